@@ -3,19 +3,34 @@ package main
 import (
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"vmcat/internal/alert"
 	"vmcat/internal/event"
+	"vmcat/internal/fence"
+	"vmcat/internal/ha"
+	"vmcat/internal/inventory"
+	"vmcat/internal/job"
+	"vmcat/internal/maintenance"
+	"vmcat/internal/metrics"
 	"vmcat/internal/monitor"
+	"vmcat/internal/placement"
+	"vmcat/internal/retention"
+	"vmcat/internal/schedule"
+	"vmcat/internal/spec"
 	internalssh "vmcat/internal/ssh"
 	"vmcat/internal/store"
 	"vmcat/internal/terminal"
@@ -34,13 +49,22 @@ type App struct {
 	monitor          *monitor.Collector
 	historyCollector *monitor.HistoryCollector
 	termSrv          *terminal.Server
-	emitter          event.Emitter // 事件发射器（桌面模式用 Wails，服务端模式用 Noop）
-	forceQuit        bool          // 真正退出标志，由托盘"退出"菜单设置
-	importMu         sync.Mutex
-	importTasks      map[string]*importTask // 活跃的导入任务
-}
-
-// importTask 镜像导入任务
+	emitter          event.Emitter        // 事件发射器（桌面模式用 Wails，服务端模式用 Hub）
+	events           *event.Hub           // 服务端模式事件广播中心（/v1/events）
+	jobs             *job.Manager         // 后台任务管理器
+	alerts           *alert.Engine        // 告警引擎
+	scheduler        *placement.Scheduler // 新 VM 调度与维护迁出计划
+	maintenance      *maintenance.Manager // 维护模式迁出与迁回
+	applier          *spec.Applier        // 声明式配置的对比与执行
+	ha               *ha.Monitor          // 高可用监控
+	fencer           *fence.Fencer        // 宿主机隔离，脚本目录由 serve --fence-script-dir 指定
+	sched            *schedule.Runner     // 定时任务
+	forceQuit        bool                 // 真正退出标志，由托盘"退出"菜单设置
+	actor            string               // 审计日志中的操作者，服务端模式按请求设置（见 withActor）
+	reqCtx           context.Context      // 当前请求或任务的 ctx（见 withContext），为 nil 时不可取消
+}
+
+// importTask 镜像导入任务状态（兼容旧接口，由后台任务转换而来）
 type importTask struct {
 	ID        string `json:"id"`
 	HostID    string `json:"hostId"`
@@ -59,6 +83,8 @@ func NewApp() *App {
 		monitor:   monitor.NewCollector(pool),
 		termSrv:   terminal.NewServer(pool),
 		emitter:   &event.NoopEmitter{}, // 默认 Noop，桌面模式在 startup 中替换
+		jobs:      job.NewManager(),
+		fencer:    &fence.Fencer{ScriptDir: fence.DefaultScriptDir()},
 	}
 }
 
@@ -71,15 +97,44 @@ func (a *App) InitForServe() error {
 	a.store = s
 	s.MigrateEncryptPasswords()
 
+	// 事件通过 /v1/events 推送给 SSE/WebSocket 客户端
+	a.events = event.NewHub(0, 0)
+	a.emitter = a.events
+
+	a.sshPool.SetEmitter(a.emitter)
+	a.jobs.SetEmitter(a.emitter)
+	a.vmManager.SetEmitter(a.emitter)
+	a.jobs.SetStore(s)
+	a.scheduler = placement.NewScheduler(a.sshPool, s, a.monitor, a.vmManager)
+	a.maintenance = maintenance.NewManager(a.sshPool, s, a.vmManager, a.jobs, a.scheduler)
+	a.maintenance.OnMoved(a.haMoved)
+	a.applier = spec.NewApplier(a.sshPool, s, a.vmManager, a.jobs, a.maintenance)
+	a.loadTimeouts()
+	a.loadSessionLimit()
+
 	// 启动资源历史采集器
 	a.historyCollector = monitor.NewHistoryCollector(a.sshPool, a.store, a.monitor, a.vmManager)
+	a.loadStatsSettings()
+	a.initAlerts()
+	a.initHA()
+	a.initSchedules()
 	a.historyCollector.Start()
 
+	// /metrics 导出宿主机、VM 与连接池状态
+	version := a.AppVersion()
+	metrics.Default.Register(func(e *metrics.Emitter) {
+		e.Gauge("vmcat_build_info", "VMCat version; value is always 1.", 1, metrics.Label{Name: "version", Value: version})
+	})
+	metrics.Default.Register(monitor.NewExporter(a.sshPool, a.store, a.historyCollector).Collect)
+
 	return nil
 }
 
 // Shutdown 清理资源（导出供服务端模式调用）
 func (a *App) Shutdown() {
+	a.stopHA()
+	a.stopSchedules()
+	a.jobs.CancelAll()
 	if a.historyCollector != nil {
 		a.historyCollector.Stop()
 	}
@@ -91,6 +146,9 @@ func (a *App) Shutdown() {
 }
 
 func (a *App) shutdown(ctx context.Context) {
+	a.stopHA()
+	a.stopSchedules()
+	a.jobs.CancelAll()
 	if a.historyCollector != nil {
 		a.historyCollector.Stop()
 	}
@@ -101,10 +159,10 @@ func (a *App) shutdown(ctx context.Context) {
 	}
 }
 
-// audit 记录审计日志（内部辅助方法）
+// audit 记录审计日志（内部辅助方法），未设置操作者时记为本地用户
 func (a *App) audit(hostID, vmName, action, detail string) {
 	if a.store != nil {
-		a.store.AuditInsert(hostID, vmName, action, detail)
+		a.store.AuditInsert(a.actorName(), hostID, vmName, action, detail)
 	}
 }
 
@@ -132,6 +190,9 @@ func (a *App) HostAdd(h store.Host) error {
 	if a.store == nil {
 		return fmt.Errorf("store not initialized")
 	}
+	if !vm.ValidBackend(h.Backend) {
+		return invalidParams("invalid backend: %s", h.Backend)
+	}
 	return a.store.HostAdd(&h)
 }
 
@@ -140,7 +201,17 @@ func (a *App) HostUpdate(h store.Host) error {
 	if a.store == nil {
 		return fmt.Errorf("store not initialized")
 	}
-	return a.store.HostUpdate(&h)
+	if !vm.ValidBackend(h.Backend) {
+		return invalidParams("invalid backend: %s", h.Backend)
+	}
+	if err := a.store.HostUpdate(&h); err != nil {
+		return err
+	}
+	// 已连接的宿主机立即切换后端
+	if a.sshPool.IsConnected(h.ID) {
+		a.vmManager.SetBackend(h.ID, h.Backend)
+	}
+	return nil
 }
 
 // HostDelete 删除宿主机
@@ -148,7 +219,14 @@ func (a *App) HostDelete(id string) error {
 	if a.store == nil {
 		return fmt.Errorf("store not initialized")
 	}
+	a.vmManager.Unwatch(id)
+	a.vmManager.CloseBackend(id)
 	a.sshPool.Disconnect(id)
+	a.store.HostInventoryDelete(id)
+	a.store.MaintenanceEnd(id)
+	a.store.VMHADeleteHost(id)
+	a.store.SnapshotPolicyDeleteHost(id)
+	a.store.AutoSnapshotDeleteVM(id, "")
 	return a.store.HostDelete(id)
 }
 
@@ -159,7 +237,7 @@ func (a *App) HostConnect(id string) error {
 	}
 	h, err := a.store.HostGet(id)
 	if err != nil {
-		return fmt.Errorf("host not found: %w", err)
+		return notFound("host", err)
 	}
 
 	cfg := &internalssh.Config{
@@ -177,6 +255,8 @@ func (a *App) HostConnect(id string) error {
 	if err != nil {
 		return err
 	}
+	a.vmManager.SetBackend(id, h.Backend)
+	a.vmManager.Watch(id)
 
 	// 首次连接（无已知密钥），存储服务端公钥
 	if h.HostKey == "" {
@@ -185,11 +265,21 @@ func (a *App) HostConnect(id string) error {
 		}
 	}
 
+	// 每次连接后在后台刷新清单，记录升级、扩容等变更
+	go func() {
+		ctx := internalssh.WithPriority(context.Background(), internalssh.PriorityBackground)
+		if _, err := a.refreshInventory(ctx, id); err != nil {
+			log.Printf("host %s inventory: %v", id, err)
+		}
+	}()
+
 	return nil
 }
 
 // HostDisconnect 断开宿主机
 func (a *App) HostDisconnect(id string) {
+	a.vmManager.Unwatch(id)
+	a.vmManager.CloseBackend(id)
 	a.sshPool.Disconnect(id)
 }
 
@@ -210,7 +300,7 @@ func (a *App) HostTest(h store.Host) (string, error) {
 		return "", err
 	}
 
-	output, err := client.Execute("hostname")
+	output, err := client.Run(a.requestContext(), internalssh.OpQuery, "hostname")
 	if err != nil {
 		client.Close()
 		return "", fmt.Errorf("execute test command: %w", err)
@@ -268,6 +358,21 @@ func (a *App) HostIsConnected(id string) bool {
 	return a.sshPool.IsConnected(id)
 }
 
+// HostHealth 获取宿主机连接健康状态（connected/degraded/down/reconnecting/disconnected）
+func (a *App) HostHealth(id string) internalssh.HostHealth {
+	return a.sshPool.Health(id)
+}
+
+// HostHealthList 获取所有已连接宿主机的健康状态
+func (a *App) HostHealthList() []internalssh.HostHealth {
+	return a.sshPool.HealthAll()
+}
+
+// HostQueueStats 获取各宿主机的 SSH 会话队列指标（并发、排队深度、等待与执行耗时）
+func (a *App) HostQueueStats() []internalssh.QueueStats {
+	return a.sshPool.QueueStats()
+}
+
 // HostResourceStats 获取宿主机资源统计
 func (a *App) HostResourceStats(hostID string) (*monitor.HostStats, error) {
 	return a.monitor.Collect(hostID)
@@ -293,17 +398,17 @@ func (a *App) HostImportJSON(jsonStr string) (int, error) {
 
 // VMList 获取虚拟机列表
 func (a *App) VMList(hostID string) ([]vm.VM, error) {
-	return a.vmManager.List(hostID)
+	return a.vmManager.List(a.requestContext(), hostID)
 }
 
 // VMGet 获取虚拟机详情
 func (a *App) VMGet(hostID, vmName string) (*vm.VMDetail, error) {
-	return a.vmManager.Get(hostID, vmName)
+	return a.vmManager.Get(a.requestContext(), hostID, vmName)
 }
 
 // VMStart 启动虚拟机
 func (a *App) VMStart(hostID, vmName string) error {
-	err := a.vmManager.Start(hostID, vmName)
+	err := a.vmManager.Start(a.requestContext(), hostID, vmName)
 	if err == nil {
 		a.audit(hostID, vmName, "vm.start", "")
 	}
@@ -312,7 +417,7 @@ func (a *App) VMStart(hostID, vmName string) error {
 
 // VMShutdown 关闭虚拟机
 func (a *App) VMShutdown(hostID, vmName string) error {
-	err := a.vmManager.Shutdown(hostID, vmName)
+	err := a.vmManager.Shutdown(a.requestContext(), hostID, vmName)
 	if err == nil {
 		a.audit(hostID, vmName, "vm.shutdown", "")
 	}
@@ -321,7 +426,7 @@ func (a *App) VMShutdown(hostID, vmName string) error {
 
 // VMDestroy 强制关闭虚拟机
 func (a *App) VMDestroy(hostID, vmName string) error {
-	err := a.vmManager.Destroy(hostID, vmName)
+	err := a.vmManager.Destroy(a.requestContext(), hostID, vmName)
 	if err == nil {
 		a.audit(hostID, vmName, "vm.destroy", "")
 	}
@@ -330,7 +435,7 @@ func (a *App) VMDestroy(hostID, vmName string) error {
 
 // VMReboot 重启虚拟机
 func (a *App) VMReboot(hostID, vmName string) error {
-	err := a.vmManager.Reboot(hostID, vmName)
+	err := a.vmManager.Reboot(a.requestContext(), hostID, vmName)
 	if err == nil {
 		a.audit(hostID, vmName, "vm.reboot", "")
 	}
@@ -339,203 +444,255 @@ func (a *App) VMReboot(hostID, vmName string) error {
 
 // VMSuspend 暂停虚拟机
 func (a *App) VMSuspend(hostID, vmName string) error {
-	return a.vmManager.Suspend(hostID, vmName)
+	return a.vmManager.Suspend(a.requestContext(), hostID, vmName)
 }
 
 // VMResume 恢复虚拟机
 func (a *App) VMResume(hostID, vmName string) error {
-	return a.vmManager.Resume(hostID, vmName)
+	return a.vmManager.Resume(a.requestContext(), hostID, vmName)
 }
 
 // VMDelete 删除虚拟机
 func (a *App) VMDelete(hostID, vmName string, removeStorage bool) error {
-	err := a.vmManager.Delete(hostID, vmName, removeStorage)
+	err := a.vmManager.Delete(a.requestContext(), hostID, vmName, removeStorage)
 	if err == nil {
 		detail := ""
 		if removeStorage {
 			detail = "含存储"
 		}
 		a.audit(hostID, vmName, "vm.delete", detail)
+		if a.store != nil {
+			a.store.PlacementGroupRemoveVM("", vmName)
+			a.store.VMHADelete(hostID, vmName)
+			a.store.SnapshotPolicyDelete(hostID, vmName)
+			a.store.AutoSnapshotDeleteVM(hostID, vmName)
+		}
 	}
 	return err
 }
 
 // VMRename 重命名虚拟机
 func (a *App) VMRename(hostID, oldName, newName string) error {
-	return a.vmManager.Rename(hostID, oldName, newName)
+	err := a.vmManager.Rename(a.requestContext(), hostID, oldName, newName)
+	if err == nil && a.store != nil {
+		a.store.PlacementGroupRenameVM(oldName, newName)
+		a.store.VMHARename(hostID, oldName, newName)
+		a.store.SnapshotPolicyRename(hostID, oldName, newName)
+		a.store.AutoSnapshotRename(hostID, oldName, newName)
+	}
+	return err
 }
 
 // VMSetVCPUs 设置 CPU 数量
 func (a *App) VMSetVCPUs(hostID, vmName string, count int) error {
-	return a.vmManager.SetVCPUs(hostID, vmName, count)
+	return a.vmManager.SetVCPUs(a.requestContext(), hostID, vmName, count)
 }
 
 // VMSetMemory 设置内存大小 (MB)
 func (a *App) VMSetMemory(hostID, vmName string, sizeMB int) error {
-	return a.vmManager.SetMemory(hostID, vmName, sizeMB)
+	return a.vmManager.SetMemory(a.requestContext(), hostID, vmName, sizeMB)
 }
 
 // VMSetAutostart 设置自动启动
 func (a *App) VMSetAutostart(hostID, vmName string, enabled bool) error {
-	return a.vmManager.SetAutostart(hostID, vmName, enabled)
+	return a.vmManager.SetAutostart(a.requestContext(), hostID, vmName, enabled)
 }
 
-// VMClone 克隆虚拟机
+// VMClone 克隆虚拟机（等待后台任务完成）
 func (a *App) VMClone(hostID, srcName, newName string) error {
-	err := a.vmManager.Clone(hostID, srcName, newName)
-	if err == nil {
+	return a.jobs.Wait(a.VMCloneJob(hostID, srcName, newName).ID)
+}
+
+// VMCloneJob 以后台任务方式克隆虚拟机，立即返回任务
+func (a *App) VMCloneJob(hostID, srcName, newName string) *store.Job {
+	return a.jobs.Start("vm.clone", hostID, newName, func(ctx context.Context, r *job.Run) error {
+		r.Log("cloning %s to %s", srcName, newName)
+		if err := a.vmManager.Clone(ctx, hostID, srcName, newName); err != nil {
+			return err
+		}
 		a.audit(hostID, newName, "vm.clone", fmt.Sprintf("from %s", srcName))
-	}
-	return err
+		return nil
+	})
 }
 
 // VMGetXML 获取 VM XML 配置
 func (a *App) VMGetXML(hostID, vmName string) (string, error) {
-	return a.vmManager.GetXML(hostID, vmName)
+	return a.vmManager.GetXML(a.requestContext(), hostID, vmName)
 }
 
 // VMDefineXML 用 XML 定义/更新 VM
 func (a *App) VMDefineXML(hostID, xmlContent string) error {
-	return a.vmManager.DefineXML(hostID, xmlContent)
+	return a.vmManager.DefineXML(a.requestContext(), hostID, xmlContent)
 }
 
-// VMCreate 创建虚拟机
+// VMCreate 创建虚拟机，hostID 为 auto 时自动选择宿主机
 func (a *App) VMCreate(hostID string, params vm.VMCreateParams) error {
-	err := a.vmManager.Create(hostID, params)
-	if err == nil {
-		a.audit(hostID, params.Name, "vm.create", "")
-	}
+	_, err := a.VMCreatePlaced(hostID, params, placement.ScheduleRequest{})
 	return err
 }
 
+// VMCreatePlaced 创建虚拟机，hostID 为 auto 时按 hints（标签、网络、策略、放置组）调度并返回决策
+// 资源、存储和镜像需求由 params 填充；创建成功后 VM 加入 hints.Groups
+func (a *App) VMCreatePlaced(hostID string, params vm.VMCreateParams, hints placement.ScheduleRequest) (*placement.Decision, error) {
+	hints.VMName = params.Name
+	hints.VCPUs = params.CPUs
+	hints.MemoryMB = int64(params.MemoryMB)
+	hints.DiskGB = int64(params.DiskSizeGB)
+	if params.DiskPath != "" {
+		hints.StoragePath = path.Dir(params.DiskPath)
+	}
+	if params.CDROM != "" {
+		hints.Images = append(hints.Images, params.CDROM)
+	}
+	netRequirement(&hints, params.NetType, params.Network, "bridge")
+
+	hostID, decision, err := a.placeVM(hostID, hints)
+	if err != nil {
+		return decision, err
+	}
+	if err := a.validateVMParams(hostID, params.CPUs, params.MemoryMB, params.Machine, params.Firmware); err != nil {
+		return decision, err
+	}
+	if err := a.vmManager.Create(a.requestContext(), hostID, params); err != nil {
+		return decision, err
+	}
+	detail := ""
+	if decision != nil {
+		detail = "auto placement: " + decision.Reason
+	}
+	a.audit(hostID, params.Name, "vm.create", detail)
+	a.scheduler.Join(hints.Groups, params.Name)
+	return decision, nil
+}
+
 // VMStats 获取 VM 实时资源统计
 func (a *App) VMStats(hostID, vmName string) (*vm.VMResourceStats, error) {
-	return a.vmManager.VMStats(hostID, vmName)
+	return a.vmManager.VMStats(a.requestContext(), hostID, vmName)
 }
 
 // === 硬件管理 ===
 
 // VMAttachDisk 添加磁盘
 func (a *App) VMAttachDisk(hostID, vmName string, params vm.DiskAttachParams) error {
-	return a.vmManager.AttachDisk(hostID, vmName, params)
+	return a.vmManager.AttachDisk(a.requestContext(), hostID, vmName, params)
 }
 
 // VMDetachDisk 移除磁盘
 func (a *App) VMDetachDisk(hostID, vmName, target string) error {
-	return a.vmManager.DetachDisk(hostID, vmName, target)
+	return a.vmManager.DetachDisk(a.requestContext(), hostID, vmName, target)
 }
 
 // VMAttachInterface 添加网卡
 func (a *App) VMAttachInterface(hostID, vmName string, params vm.NICAttachParams) error {
-	return a.vmManager.AttachInterface(hostID, vmName, params)
+	return a.vmManager.AttachInterface(a.requestContext(), hostID, vmName, params)
 }
 
 // VMDetachInterface 移除网卡
 func (a *App) VMDetachInterface(hostID, vmName, macAddr string) error {
-	return a.vmManager.DetachInterface(hostID, vmName, macAddr)
+	return a.vmManager.DetachInterface(a.requestContext(), hostID, vmName, macAddr)
 }
 
 // VMChangeMedia 挂载光驱
 func (a *App) VMChangeMedia(hostID, vmName, target, source string) error {
-	return a.vmManager.ChangeMedia(hostID, vmName, target, source)
+	return a.vmManager.ChangeMedia(a.requestContext(), hostID, vmName, target, source)
 }
 
 // VMEjectMedia 弹出光驱
 func (a *App) VMEjectMedia(hostID, vmName, target string) error {
-	return a.vmManager.EjectMedia(hostID, vmName, target)
+	return a.vmManager.EjectMedia(a.requestContext(), hostID, vmName, target)
 }
 
 // VMResizeDisk 磁盘扩容
 func (a *App) VMResizeDisk(hostID, diskPath string, newSizeGB int) error {
-	return a.vmManager.ResizeDisk(hostID, diskPath, newSizeGB)
+	return a.vmManager.ResizeDisk(a.requestContext(), hostID, diskPath, newSizeGB)
 }
 
 // VMSetGraphics 设置 VNC 显示
 func (a *App) VMSetGraphics(hostID, vmName string, enabled bool) error {
-	return a.vmManager.SetGraphics(hostID, vmName, enabled)
+	return a.vmManager.SetGraphics(a.requestContext(), hostID, vmName, enabled)
 }
 
 // === 存储管理 ===
 
 // PoolList 获取存储池列表
 func (a *App) PoolList(hostID string) ([]vm.StoragePool, error) {
-	return a.vmManager.PoolList(hostID)
+	return a.vmManager.PoolList(a.requestContext(), hostID)
 }
 
 // VolList 获取卷列表
 func (a *App) VolList(hostID, poolName string) ([]vm.Volume, error) {
-	return a.vmManager.VolList(hostID, poolName)
+	return a.vmManager.VolList(a.requestContext(), hostID, poolName)
 }
 
 // CreateVolume 创建卷
 func (a *App) CreateVolume(hostID, poolName, volName string, sizeGB int, format string) (string, error) {
-	return a.vmManager.CreateVolume(hostID, poolName, volName, sizeGB, format)
+	return a.vmManager.CreateVolume(a.requestContext(), hostID, poolName, volName, sizeGB, format)
 }
 
 // DeleteVolume 删除卷
 func (a *App) DeleteVolume(hostID, poolName, volName string) error {
-	return a.vmManager.DeleteVolume(hostID, poolName, volName)
+	return a.vmManager.DeleteVolume(a.requestContext(), hostID, poolName, volName)
 }
 
 // === 存储池管理 ===
 
 // PoolStart 启动存储池
 func (a *App) PoolStart(hostID, poolName string) error {
-	return a.vmManager.PoolStart(hostID, poolName)
+	return a.vmManager.PoolStart(a.requestContext(), hostID, poolName)
 }
 
 // PoolStop 停止存储池
 func (a *App) PoolStop(hostID, poolName string) error {
-	return a.vmManager.PoolStop(hostID, poolName)
+	return a.vmManager.PoolStop(a.requestContext(), hostID, poolName)
 }
 
 // PoolAutostart 设置存储池自动启动
 func (a *App) PoolAutostart(hostID, poolName string, enabled bool) error {
-	return a.vmManager.PoolAutostart(hostID, poolName, enabled)
+	return a.vmManager.PoolAutostart(a.requestContext(), hostID, poolName, enabled)
 }
 
 // === 网络管理 ===
 
 // NetworkList 获取网络列表
 func (a *App) NetworkList(hostID string) ([]vm.Network, error) {
-	return a.vmManager.NetworkList(hostID)
+	return a.vmManager.NetworkList(a.requestContext(), hostID)
 }
 
 // BridgeList 获取网桥列表
 func (a *App) BridgeList(hostID string) ([]string, error) {
-	return a.vmManager.BridgeList(hostID)
+	return a.vmManager.BridgeList(a.requestContext(), hostID)
 }
 
 // NetworkStart 启动虚拟网络
 func (a *App) NetworkStart(hostID, netName string) error {
-	return a.vmManager.NetworkStart(hostID, netName)
+	return a.vmManager.NetworkStart(a.requestContext(), hostID, netName)
 }
 
 // NetworkStop 停止虚拟网络
 func (a *App) NetworkStop(hostID, netName string) error {
-	return a.vmManager.NetworkStop(hostID, netName)
+	return a.vmManager.NetworkStop(a.requestContext(), hostID, netName)
 }
 
 // NetworkAutostart 设置虚拟网络自动启动
 func (a *App) NetworkAutostart(hostID, netName string, enabled bool) error {
-	return a.vmManager.NetworkAutostart(hostID, netName, enabled)
+	return a.vmManager.NetworkAutostart(a.requestContext(), hostID, netName, enabled)
 }
 
 // === NAT 端口转发 ===
 
 // NATRuleList 列出 NAT 端口转发规则
 func (a *App) NATRuleList(hostID string) ([]vm.NATRule, error) {
-	return a.vmManager.NATRuleList(hostID)
+	return a.vmManager.NATRuleList(a.requestContext(), hostID)
 }
 
 // NATRuleAdd 添加 NAT 端口转发规则
 func (a *App) NATRuleAdd(hostID, proto, hostPort, vmIP, vmPort, comment string) error {
-	return a.vmManager.NATRuleAdd(hostID, proto, hostPort, vmIP, vmPort, comment)
+	return a.vmManager.NATRuleAdd(a.requestContext(), hostID, proto, hostPort, vmIP, vmPort, comment)
 }
 
 // NATRuleDelete 删除 NAT 端口转发规则
 func (a *App) NATRuleDelete(hostID, proto, hostPort, vmIP, vmPort string) error {
-	return a.vmManager.NATRuleDelete(hostID, proto, hostPort, vmIP, vmPort)
+	return a.vmManager.NATRuleDelete(a.requestContext(), hostID, proto, hostPort, vmIP, vmPort)
 }
 
 // === ISO 管理 ===
@@ -553,34 +710,268 @@ func (a *App) ISOList(hostID string) ([]vm.ISOFile, error) {
 			}
 		}
 	}
-	return a.vmManager.ISOList(hostID, paths)
+	return a.vmManager.ISOList(a.requestContext(), hostID, paths)
 }
 
 // OSVariantList 获取 OS 变体列表
 func (a *App) OSVariantList(hostID string) ([]string, error) {
-	return a.vmManager.OSVariantList(hostID)
+	return a.vmManager.OSVariantList(a.requestContext(), hostID)
 }
 
 // === 快照管理 ===
 
 // SnapshotList 获取快照列表
 func (a *App) SnapshotList(hostID, vmName string) ([]vm.Snapshot, error) {
-	return a.vmManager.SnapshotList(hostID, vmName)
+	return a.vmManager.SnapshotList(a.requestContext(), hostID, vmName)
 }
 
 // SnapshotCreate 创建快照
 func (a *App) SnapshotCreate(hostID, vmName, snapName string) error {
-	return a.vmManager.SnapshotCreate(hostID, vmName, snapName)
+	return a.vmManager.SnapshotCreate(a.requestContext(), hostID, vmName, snapName)
+}
+
+// SnapshotCreateWith 按参数创建快照；外部磁盘快照的 overlay 默认放在 VMCat 实例目录，
+// 非模板创建的 VM 放在各磁盘原镜像所在目录
+func (a *App) SnapshotCreateWith(hostID, vmName string, params vm.SnapshotCreateParams) error {
+	if params.DiskOnly && params.OverlayDir == "" && a.store != nil {
+		if inst, err := a.store.InstanceByVMName(hostID, vmName); err == nil {
+			instanceRoot, _ := a.store.SettingGet("instance_root")
+			if instanceRoot == "" {
+				instanceRoot = "/var/lib/libvirt/instances"
+			}
+			params.OverlayDir = vm.InstanceDir(instanceRoot, inst.ID)
+		}
+	}
+	return a.vmManager.SnapshotCreateWith(a.requestContext(), hostID, vmName, params)
 }
 
 // SnapshotDelete 删除快照
 func (a *App) SnapshotDelete(hostID, vmName, snapName string) error {
-	return a.vmManager.SnapshotDelete(hostID, vmName, snapName)
+	err := a.vmManager.SnapshotDelete(a.requestContext(), hostID, vmName, snapName)
+	if err == nil && a.store != nil {
+		a.store.AutoSnapshotDelete(hostID, vmName, snapName)
+	}
+	return err
 }
 
 // SnapshotRevert 恢复快照
 func (a *App) SnapshotRevert(hostID, vmName, snapName string) error {
-	return a.vmManager.SnapshotRevert(hostID, vmName, snapName)
+	return a.vmManager.SnapshotRevert(a.requestContext(), hostID, vmName, snapName)
+}
+
+// SnapshotTree 获取快照树（深度优先顺序），含描述、是否包含内存状态和大小
+func (a *App) SnapshotTree(hostID, vmName string) ([]vm.SnapshotNode, error) {
+	return a.vmManager.SnapshotTree(a.requestContext(), hostID, vmName)
+}
+
+// SnapshotCommit 将外部快照链合并回基础镜像（等待后台任务完成）
+func (a *App) SnapshotCommit(hostID, vmName string) error {
+	return a.jobs.Wait(a.SnapshotCommitJob(hostID, vmName).ID)
+}
+
+// SnapshotCommitJob 以后台任务方式合并外部快照链，立即返回任务；合并结果写入任务结果
+func (a *App) SnapshotCommitJob(hostID, vmName string) *store.Job {
+	return a.jobs.Start("snapshot.commit", hostID, vmName, func(ctx context.Context, r *job.Run) error {
+		res, err := a.vmManager.SnapshotCommit(ctx, hostID, vmName, func(detail string) {
+			r.Log("%s", detail)
+			r.Progress(0, detail)
+		})
+		if res != nil {
+			r.SetResult(res)
+			if len(res.Disks) > 0 {
+				disks := make([]string, len(res.Disks))
+				for i, d := range res.Disks {
+					disks[i] = d.Target
+				}
+				a.audit(hostID, vmName, "snapshot.commit", strings.Join(disks, ", "))
+			}
+		}
+		return err
+	})
+}
+
+// SnapshotPruneResult 自动快照和按保留策略清理的结果
+type SnapshotPruneResult struct {
+	Created  string              `json:"created"` // snapshot.auto 创建的快照
+	Policy   string              `json:"policy"`  // 保留策略说明，未设置策略时为空（不清理）
+	DryRun   bool                `json:"dryRun"`
+	Verdicts []retention.Verdict `json:"verdicts"` // 每个自动快照的去留，从新到旧
+	Pruned   []string            `json:"pruned"`
+	Errors   []string            `json:"errors"`
+}
+
+// SnapshotPolicyList 获取快照保留策略，hostID 为空时返回全部
+func (a *App) SnapshotPolicyList(hostID string) ([]store.SnapshotPolicy, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.SnapshotPolicyList(hostID)
+}
+
+// SnapshotPolicySet 设置 VM 的快照保留策略，在下一次自动快照或手动清理时生效
+func (a *App) SnapshotPolicySet(p store.SnapshotPolicy) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if p.HostID == "" || p.VMName == "" {
+		return invalidParams("hostId and vmName are required")
+	}
+	policy := snapshotRetention(&p)
+	if err := policy.Validate(); err != nil {
+		return invalidParams("%w", err)
+	}
+	if policy.Empty() {
+		return invalidParams("at least one keep count is required (delete the policy to keep all snapshots)")
+	}
+	if err := a.store.SnapshotPolicySet(&p); err != nil {
+		return err
+	}
+	a.audit(p.HostID, p.VMName, "snapshotPolicy.set", policy.String())
+	return nil
+}
+
+// SnapshotPolicyDelete 删除 VM 的快照保留策略，之后不再清理其自动快照
+func (a *App) SnapshotPolicyDelete(hostID, vmName string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if err := a.store.SnapshotPolicyDelete(hostID, vmName); err != nil {
+		return err
+	}
+	a.audit(hostID, vmName, "snapshotPolicy.delete", "")
+	return nil
+}
+
+// SnapshotAuto 创建自动快照（vmcat-auto-<时间>），并按 VM 的保留策略清理旧的自动快照
+// 配合定时任务（如每天对 vmPattern 执行 snapshot.auto）实现周期快照。
+// 默认为内部快照，只支持 qcow2 磁盘；raw 磁盘的 VM 需使用 diskOnly（外部磁盘快照，宿主机须为 libvirt 9.0 及以上）
+func (a *App) SnapshotAuto(hostID, vmName string, diskOnly bool) (*SnapshotPruneResult, error) {
+	// 外部快照需要 libvirt 9.0 起才能直接删除，否则保留策略无法清理，overlay 会不断累积
+	if diskOnly {
+		ok, err := a.vmManager.SnapshotExternalDeletable(a.requestContext(), hostID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("libvirt on host %s cannot delete external snapshots (needs 9.0 or newer), automatic disk-only snapshots could never be pruned", hostID)
+		}
+	}
+	name := retention.Name(time.Now())
+	if err := a.SnapshotCreateWith(hostID, vmName, vm.SnapshotCreateParams{
+		Name:        name,
+		Description: "Created by VMCat; pruned by the VM's snapshot retention policy",
+		DiskOnly:    diskOnly,
+	}); err != nil {
+		return nil, err
+	}
+	if a.store != nil {
+		if err := a.store.AutoSnapshotAdd(hostID, vmName, name); err != nil {
+			log.Printf("snapshot.auto %s/%s: record %s: %v", hostID, vmName, name, err)
+		}
+	}
+	a.audit(hostID, vmName, "snapshot.auto", name)
+
+	result, err := a.SnapshotPrune(hostID, vmName, false)
+	if result != nil {
+		result.Created = name
+	}
+	return result, err
+}
+
+// SnapshotPrune 按 VM 的保留策略删除多余的自动快照；只清理 SnapshotAuto 记录过的快照，
+// 手动创建的快照即使以 vmcat-auto- 命名也不受影响；未设置策略时不删除
+// dryRun 为 true 时只返回选择结果
+func (a *App) SnapshotPrune(hostID, vmName string, dryRun bool) (*SnapshotPruneResult, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	result := &SnapshotPruneResult{DryRun: dryRun}
+	p, err := a.store.SnapshotPolicyGet(hostID, vmName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy := snapshotRetention(p)
+	result.Policy = policy.String()
+
+	snaps, err := a.vmManager.SnapshotList(a.requestContext(), hostID, vmName)
+	if err != nil {
+		return nil, err
+	}
+	auto, err := a.store.AutoSnapshotNames(hostID, vmName)
+	if err != nil {
+		return nil, err
+	}
+	var items []retention.Item
+	for _, snap := range snaps {
+		if !auto[snap.Name] {
+			continue
+		}
+		delete(auto, snap.Name)
+		if t, ok := retention.ParseName(snap.Name); ok {
+			items = append(items, retention.Item{Name: snap.Name, Time: t})
+		}
+	}
+	// 已在 VMCat 之外删除的快照不再保留记录
+	if !dryRun {
+		for name := range auto {
+			a.store.AutoSnapshotDelete(hostID, vmName, name)
+		}
+	}
+	result.Verdicts = retention.Apply(items, policy)
+	if dryRun {
+		return result, nil
+	}
+
+	// 从旧到新删除；外部快照在 libvirt 9.0 之前无法删除，只能通过 snapshot.commit 合并
+	var externalDeletable *bool
+	for i := len(result.Verdicts) - 1; i >= 0; i-- {
+		v := result.Verdicts[i]
+		if v.Keep {
+			continue
+		}
+		location, err := a.vmManager.SnapshotLocation(a.requestContext(), hostID, vmName, v.Name)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", v.Name, err))
+			continue
+		}
+		if location == "external" {
+			if externalDeletable == nil {
+				ok, err := a.vmManager.SnapshotExternalDeletable(a.requestContext(), hostID)
+				if err != nil {
+					// 查询失败不缓存，后续外部快照重新查询
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", v.Name, err))
+					continue
+				}
+				externalDeletable = &ok
+			}
+			if !*externalDeletable {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: external snapshot cannot be deleted by libvirt before 9.0, merge it with snapshot commit", v.Name))
+				continue
+			}
+		}
+		if err := a.vmManager.SnapshotDelete(a.requestContext(), hostID, vmName, v.Name); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", v.Name, err))
+			continue
+		}
+		a.store.AutoSnapshotDelete(hostID, vmName, v.Name)
+		result.Pruned = append(result.Pruned, v.Name)
+	}
+	a.store.SnapshotPolicyPruned(hostID, vmName)
+	if len(result.Pruned) > 0 {
+		a.audit(hostID, vmName, "snapshot.prune", strings.Join(result.Pruned, ", "))
+	}
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("%d snapshot(s) could not be pruned: %s", len(result.Errors), result.Errors[0])
+	}
+	return result, nil
+}
+
+// snapshotRetention 将保存的策略转换为 retention.Policy
+func snapshotRetention(p *store.SnapshotPolicy) retention.Policy {
+	return retention.Policy{KeepLast: p.KeepLast, KeepDaily: p.KeepDaily, KeepWeekly: p.KeepWeekly, KeepMonthly: p.KeepMonthly}
 }
 
 // === 终端 ===
@@ -605,7 +996,95 @@ func (a *App) SettingSet(key, value string) error {
 	if a.store == nil {
 		return fmt.Errorf("store not initialized")
 	}
-	return a.store.SettingSet(key, value)
+	op, isTimeout := timeoutSettingOp(key)
+	if isTimeout {
+		if _, err := parseTimeoutSetting(value); err != nil {
+			return invalidParams("invalid %s: %w", key, err)
+		}
+	}
+	_, isStats := statsSettingUnits[key]
+	if (isStats || key == ha.GraceSetting) && strings.TrimSpace(value) != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); err != nil || n <= 0 {
+			return invalidParams("invalid %s: must be a positive integer", key)
+		}
+	}
+	if err := a.store.SettingSet(key, value); err != nil {
+		return err
+	}
+	switch {
+	case isTimeout:
+		a.applyTimeout(op, value)
+	case key == "ssh_max_sessions" || key == "ssh_overflow":
+		a.loadSessionLimit()
+	case isStats:
+		a.loadStatsSettings()
+	}
+	return nil
+}
+
+// SettingTimeouts 获取当前生效的命令超时（秒），键为操作类别
+func (a *App) SettingTimeouts() map[string]int {
+	result := make(map[string]int)
+	for op, d := range a.sshPool.Timeouts().All() {
+		result[string(op)] = int(d / time.Second)
+	}
+	return result
+}
+
+// loadTimeouts 从设置加载各类操作的命令超时（timeout_<op>，单位秒）
+func (a *App) loadTimeouts() {
+	for _, op := range internalssh.Ops {
+		if val, _ := a.store.SettingGet(timeoutSettingPrefix + string(op)); val != "" {
+			a.applyTimeout(op, val)
+		}
+	}
+}
+
+// loadSessionLimit 从设置加载每台宿主机的 SSH 会话并发上限
+// ssh_max_sessions: 每条连接的会话数（默认 8）；ssh_overflow: "true" 时饱和后建立第二条连接
+func (a *App) loadSessionLimit() {
+	limitStr, _ := a.store.SettingGet("ssh_max_sessions")
+	overflowStr, _ := a.store.SettingGet("ssh_overflow")
+	limit, _ := strconv.Atoi(strings.TrimSpace(limitStr))
+	a.sshPool.SetSessionLimit(limit, overflowStr == "true")
+}
+
+// applyTimeout 应用单个超时设置，空值或 0 恢复默认
+func (a *App) applyTimeout(op internalssh.Op, value string) {
+	sec, err := parseTimeoutSetting(value)
+	if err != nil {
+		log.Printf("ignore invalid timeout setting %s=%q", op, value)
+		return
+	}
+	a.sshPool.Timeouts().Set(op, time.Duration(sec)*time.Second)
+}
+
+// timeoutSettingPrefix 命令超时设置项前缀
+const timeoutSettingPrefix = "timeout_"
+
+// timeoutSettingOp 判断设置项是否为命令超时，返回对应操作类别
+func timeoutSettingOp(key string) (internalssh.Op, bool) {
+	if !strings.HasPrefix(key, timeoutSettingPrefix) {
+		return "", false
+	}
+	op := internalssh.Op(strings.TrimPrefix(key, timeoutSettingPrefix))
+	if _, ok := internalssh.DefaultTimeouts[op]; !ok {
+		return "", false
+	}
+	return op, true
+}
+
+// parseTimeoutSetting 解析超时秒数，空值视为 0（默认）
+func parseTimeoutSetting(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	sec, err := strconv.Atoi(value)
+	if err != nil || sec < 0 {
+		return 0, fmt.Errorf("timeout must be a non-negative number of seconds")
+	}
+	return sec, nil
 }
 
 // === 模板管理 ===
@@ -675,19 +1154,39 @@ func (a *App) ImageDelete(id string) error {
 	return a.store.ImageDelete(id)
 }
 
-// VMCreateFromTemplate 基于模板快速创建 VM
+// VMCreateFromTemplate 基于模板快速创建 VM（等待后台任务完成）
 func (a *App) VMCreateFromTemplate(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey string) error {
+	j, err := a.VMCreateFromTemplateJob(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey)
+	if err != nil {
+		return err
+	}
+	return a.jobs.Wait(j.ID)
+}
+
+// VMCreateFromTemplateJob 以后台任务方式基于模板创建 VM，参数校验失败时直接返回错误
+func (a *App) VMCreateFromTemplateJob(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey string) (*store.Job, error) {
+	return a.VMCreateFromTemplatePlacedJob(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey, placement.ScheduleRequest{})
+}
+
+// VMCreateFromTemplatePlacedJob 同 VMCreateFromTemplateJob，hostID 为 auto 时按 flavor、镜像、网络和 hints 调度，
+// 选中的宿主机即任务的 hostId，决策写入任务日志和结果（placement 字段）
+func (a *App) VMCreateFromTemplatePlacedJob(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey string, hints placement.ScheduleRequest) (*store.Job, error) {
+	return a.templateCreateJob(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey, "", hints)
+}
+
+// templateCreateJob 同 VMCreateFromTemplatePlacedJob，userData 非空时替换生成的 cloud-init user-data
+func (a *App) templateCreateJob(hostID, vmName, flavorID, imageID, netType, netName, rootPassword, sshPubKey, userData string, hints placement.ScheduleRequest) (*store.Job, error) {
 	if a.store == nil {
-		return fmt.Errorf("store not initialized")
+		return nil, fmt.Errorf("store not initialized")
 	}
 
 	flavor, err := a.store.FlavorGet(flavorID)
 	if err != nil {
-		return fmt.Errorf("flavor not found: %w", err)
+		return nil, notFound("flavor", err)
 	}
 	image, err := a.store.ImageGet(imageID)
 	if err != nil {
-		return fmt.Errorf("image not found: %w", err)
+		return nil, notFound("image", err)
 	}
 
 	// 读取 instance_root 配置
@@ -696,41 +1195,70 @@ func (a *App) VMCreateFromTemplate(hostID, vmName, flavorID, imageID, netType, n
 		instanceRoot = "/var/lib/libvirt/instances"
 	}
 
-	// 创建 instance 记录，获取自增 ID
-	inst := &store.Instance{
-		HostID:   hostID,
-		VMName:   vmName,
-		FlavorID: flavorID,
-		ImageID:  imageID,
-	}
-	instanceID, err := a.store.InstanceCreate(inst)
+	hints.VMName = vmName
+	hints.VCPUs = flavor.CPUs
+	hints.MemoryMB = int64(flavor.MemoryMB)
+	hints.DiskGB = int64(flavor.DiskGB)
+	hints.StoragePath = instanceRoot
+	hints.Images = append(hints.Images, image.BasePath)
+	netRequirement(&hints, netType, netName, "network")
+	hostID, decision, err := a.placeVM(hostID, hints)
 	if err != nil {
-		return fmt.Errorf("create instance record: %w", err)
-	}
-
-	// 调用 VM 创建
-	params := &vm.TemplateCreateParams{
-		VMName:       vmName,
-		InstanceID:   instanceID,
-		InstanceRoot: instanceRoot,
-		CPUs:         flavor.CPUs,
-		MemoryMB:     flavor.MemoryMB,
-		DiskGB:       flavor.DiskGB,
-		BasePath:     image.BasePath,
-		OSVariant:    image.OSVariant,
-		NetType:      netType,
-		NetName:      netName,
-		RootPassword: rootPassword,
-		SSHPubKey:    sshPubKey,
-	}
-
-	if err := a.vmManager.CreateFromTemplate(hostID, params); err != nil {
-		// 创建失败，删除 instance 记录
-		a.store.InstanceDelete(instanceID)
-		return err
+		return nil, err
+	}
+	if err := a.validateVMParams(hostID, flavor.CPUs, flavor.MemoryMB, "", ""); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return a.jobs.Start("vm.createFromTemplate", hostID, vmName, func(ctx context.Context, r *job.Run) error {
+		if decision != nil {
+			r.Log("placement: %s", decision.Reason)
+			r.SetResult(map[string]interface{}{"placement": decision})
+		}
+
+		// 创建 instance 记录，获取自增 ID
+		inst := &store.Instance{
+			HostID:   hostID,
+			VMName:   vmName,
+			FlavorID: flavorID,
+			ImageID:  imageID,
+		}
+		instanceID, err := a.store.InstanceCreate(inst)
+		if err != nil {
+			return fmt.Errorf("create instance record: %w", err)
+		}
+		r.Log("instance %d: flavor=%s image=%s", instanceID, flavor.Name, image.Name)
+
+		// 调用 VM 创建
+		params := &vm.TemplateCreateParams{
+			VMName:       vmName,
+			InstanceID:   instanceID,
+			InstanceRoot: instanceRoot,
+			CPUs:         flavor.CPUs,
+			MemoryMB:     flavor.MemoryMB,
+			DiskGB:       flavor.DiskGB,
+			BasePath:     image.BasePath,
+			OSVariant:    image.OSVariant,
+			NetType:      netType,
+			NetName:      netName,
+			RootPassword: rootPassword,
+			SSHPubKey:    sshPubKey,
+			UserData:     userData,
+		}
+
+		if err := a.vmManager.CreateFromTemplate(ctx, hostID, params); err != nil {
+			// 创建失败，删除 instance 记录
+			a.store.InstanceDelete(instanceID)
+			return err
+		}
+		if decision != nil {
+			r.SetResult(map[string]interface{}{"instanceId": instanceID, "placement": decision})
+		} else {
+			r.SetResult(map[string]int{"instanceId": instanceID})
+		}
+		a.scheduler.Join(hints.Groups, vmName)
+		return nil
+	}), nil
 }
 
 // InstanceISOList 获取 instance 专属 ISO 列表
@@ -739,7 +1267,7 @@ func (a *App) InstanceISOList(hostID string, instanceID int) ([]vm.ISOFile, erro
 	if instanceRoot == "" {
 		instanceRoot = "/var/lib/libvirt/instances"
 	}
-	return a.vmManager.InstanceISOList(hostID, instanceRoot, instanceID)
+	return a.vmManager.InstanceISOList(a.requestContext(), hostID, instanceRoot, instanceID)
 }
 
 // InstanceList 获取宿主机的 instance 列表
@@ -775,7 +1303,7 @@ func (a *App) AppVersion() string {
 
 // === 资源历史 ===
 
-// HostStatsHistory 获取宿主机资源历史
+// HostStatsHistory 获取宿主机资源历史，按时间范围自动选择原始数据或 5 分钟 / 1 小时汇总
 func (a *App) HostStatsHistory(hostID string, hours int) ([]store.HostStatsRecord, error) {
 	if a.store == nil {
 		return nil, fmt.Errorf("store not initialized")
@@ -783,10 +1311,10 @@ func (a *App) HostStatsHistory(hostID string, hours int) ([]store.HostStatsRecor
 	if hours <= 0 {
 		hours = 24
 	}
-	return a.store.HostStatsHistory(hostID, hours)
+	return a.store.HostStatsHistory(hostID, hours, a.statsResolution(hours))
 }
 
-// VMStatsHistory 获取 VM 资源历史
+// VMStatsHistory 获取 VM 资源历史，精度选择同 HostStatsHistory
 func (a *App) VMStatsHistory(hostID, vmName string, hours int) ([]store.VMStatsRecord, error) {
 	if a.store == nil {
 		return nil, fmt.Errorf("store not initialized")
@@ -794,91 +1322,1061 @@ func (a *App) VMStatsHistory(hostID, vmName string, hours int) ([]store.VMStatsR
 	if hours <= 0 {
 		hours = 24
 	}
-	return a.store.VMStatsHistory(hostID, vmName, hours)
+	return a.store.VMStatsHistory(hostID, vmName, hours, a.statsResolution(hours))
 }
 
-// === 审计日志 ===
-
-// AuditList 获取指定宿主机的审计日志
-func (a *App) AuditList(hostID string, limit int) ([]store.AuditRecord, error) {
+// VMDiskHistory 获取块设备 I/O 速率历史，vmName 为空时返回宿主机上全部 VM，device 为空时返回全部设备
+func (a *App) VMDiskHistory(hostID, vmName, device string, hours int) ([]store.VMDiskStatsRecord, error) {
 	if a.store == nil {
 		return nil, fmt.Errorf("store not initialized")
 	}
-	return a.store.AuditList(hostID, limit)
+	if hours <= 0 {
+		hours = 24
+	}
+	return a.store.VMDiskStatsHistory(hostID, vmName, device, hours, a.statsResolution(hours))
 }
 
-// AuditListAll 获取全部审计日志
-func (a *App) AuditListAll(limit int) ([]store.AuditRecord, error) {
+// VMNetHistory 获取网卡流量速率历史，过滤条件同 VMDiskHistory
+func (a *App) VMNetHistory(hostID, vmName, device string, hours int) ([]store.VMNetStatsRecord, error) {
 	if a.store == nil {
 		return nil, fmt.Errorf("store not initialized")
 	}
-	return a.store.AuditListAll(limit)
+	if hours <= 0 {
+		hours = 24
+	}
+	return a.store.VMNetStatsHistory(hostID, vmName, device, hours, a.statsResolution(hours))
 }
 
-// === VM 备注 ===
+// statsResolution 按查询范围和当前保留设置选择历史数据精度
+func (a *App) statsResolution(hours int) string {
+	_, retention := statsSettings(a.store)
+	return retention.Resolution(time.Duration(hours) * time.Hour)
+}
 
-// VMNoteGet 获取 VM 备注
-func (a *App) VMNoteGet(hostID, vmName string) (string, error) {
-	if a.store == nil {
-		return "", fmt.Errorf("store not initialized")
-	}
-	key := fmt.Sprintf("vm_note:%s:%s", hostID, vmName)
-	return a.store.SettingGet(key)
+// 资源历史设置项：stats_interval 采集间隔（秒），stats_retention_raw 原始数据保留小时数，
+// stats_retention_5m / stats_retention_1h 汇总数据保留天数；空值为默认
+var statsSettingUnits = map[string]time.Duration{
+	"stats_interval":      time.Second,
+	"stats_retention_raw": time.Hour,
+	"stats_retention_5m":  24 * time.Hour,
+	"stats_retention_1h":  24 * time.Hour,
 }
 
-// VMNoteSet 设置 VM 备注
-func (a *App) VMNoteSet(hostID, vmName, note string) error {
-	if a.store == nil {
-		return fmt.Errorf("store not initialized")
+// statsSettings 读取采集间隔和保留时长，未设置或无效的项取默认值
+func statsSettings(s *store.Store) (time.Duration, store.StatsRetention) {
+	get := func(key string, def time.Duration) time.Duration {
+		val, _ := s.SettingGet(key)
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || n <= 0 {
+			return def
+		}
+		return time.Duration(n) * statsSettingUnits[key]
+	}
+	def := store.DefaultStatsRetention
+	return get("stats_interval", monitor.DefaultInterval), store.StatsRetention{
+		Raw:     get("stats_retention_raw", def.Raw),
+		FiveMin: get("stats_retention_5m", def.FiveMin),
+		Hourly:  get("stats_retention_1h", def.Hourly),
 	}
-	key := fmt.Sprintf("vm_note:%s:%s", hostID, vmName)
-	return a.store.SettingSet(key, note)
 }
 
-// === Cloud-Init ===
-
-// VMGenerateCloudInit 生成 cloud-init ISO
-func (a *App) VMGenerateCloudInit(hostID, outputPath string, cfg vm.CloudInitConfig) error {
-	err := a.vmManager.GenerateCloudInitISO(hostID, outputPath, cfg)
-	if err == nil {
-		a.audit(hostID, cfg.Hostname, "cloudinit.generate", outputPath)
+// loadStatsSettings 将资源历史设置应用到采集器
+func (a *App) loadStatsSettings() {
+	if a.historyCollector == nil {
+		return
 	}
-	return err
+	interval, retention := statsSettings(a.store)
+	a.historyCollector.SetInterval(interval)
+	a.historyCollector.SetRetention(retention)
 }
 
-// === VM 迁移 ===
+// === 维护模式 ===
 
-// VMMigrate 在线迁移 VM
-func (a *App) VMMigrate(srcHostID, vmName, dstHostID string) error {
-	err := a.vmManager.Migrate(srcHostID, vmName, dstHostID)
-	if err == nil {
-		a.audit(srcHostID, vmName, "vm.migrate", fmt.Sprintf("to %s", dstHostID))
-	}
-	return err
+// MaintenancePlan 维护迁出计划
+type MaintenancePlan struct {
+	HostID string                 `json:"hostId"`
+	Moves  []placement.Assignment `json:"moves"` // HostID 为空的 VM 无法放置，Reason 说明原因
 }
 
-// VMMigrateOffline 离线迁移 VM (通过客户端中继，适用于网络隔离场景)
-func (a *App) VMMigrateOffline(srcHostID, vmName, dstHostID string) error {
-	err := a.vmManager.MigrateOffline(srcHostID, vmName, dstHostID, func(step, detail string) {
-		a.emitter.Emit("migrate:progress", map[string]string{
-			"step":   step,
-			"detail": detail,
-		})
-	})
-	if err == nil {
-		a.audit(srcHostID, vmName, "vm.migrate_offline", fmt.Sprintf("to %s", dstHostID))
+// MaintenanceStatus 宿主机维护状态及迁出记录
+type MaintenanceStatus struct {
+	Maintenance *store.Maintenance      `json:"maintenance"` // 不在维护中时为 null
+	Moves       []store.MaintenanceMove `json:"moves"`
+}
+
+// HostMaintenanceList 获取所有处于维护中的宿主机
+func (a *App) HostMaintenanceList() ([]store.Maintenance, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
 	}
-	return err
+	return a.store.MaintenanceList()
 }
 
-// HostCheckTools 检测宿主机上的工具安装情况
-func (a *App) HostCheckTools(id string) (map[string]string, error) {
+// HostMaintenanceStatus 获取宿主机维护状态，维护结束后仍返回迁出记录以便迁回
+func (a *App) HostMaintenanceStatus(hostID string) (*MaintenanceStatus, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	st := &MaintenanceStatus{}
+	if m, err := a.store.MaintenanceGet(hostID); err == nil {
+		st.Maintenance = m
+	}
+	moves, err := a.store.MaintenanceMoveList(hostID)
+	if err != nil {
+		return nil, err
+	}
+	st.Moves = moves
+	return st, nil
+}
+
+// HostMaintenancePlan 计算宿主机上运行中 VM 的迁出计划（不执行）
+func (a *App) HostMaintenancePlan(hostID string) (*MaintenancePlan, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	moves, err := a.maintenance.Plan(a.requestContext(), hostID)
+	if err != nil {
+		return nil, err
+	}
+	return &MaintenancePlan{HostID: hostID, Moves: moves}, nil
+}
+
+// HostMaintenanceEnter 宿主机进入维护模式并迁出运行中的 VM（等待后台任务完成）
+func (a *App) HostMaintenanceEnter(hostID, reason string) error {
+	j, err := a.HostMaintenanceEnterJob(hostID, reason)
+	if err != nil {
+		return err
+	}
+	return a.jobs.Wait(j.ID)
+}
+
+// HostMaintenanceEnterJob 宿主机进入维护模式（立即不再接受新 VM），以后台任务逐台迁出运行中的 VM：
+// 优先在线迁移，不可行时关机后离线迁移并在目标宿主机上启动。已在维护中时重新迁出剩余的 VM
+func (a *App) HostMaintenanceEnterJob(hostID, reason string) (*store.Job, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if _, err := a.store.HostGet(hostID); err != nil {
+		return nil, notFound("host", err)
+	}
+	return a.maintenance.Enter(hostID, reason, a.actorName())
+}
+
+// HostMaintenanceExit 结束维护模式，宿主机重新接受新 VM；迁出的 VM 不会自动迁回（见 HostReturnVMsJob）
+func (a *App) HostMaintenanceExit(hostID string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	return a.maintenance.Exit(hostID, a.actorName())
+}
+
+// HostReturnVMs 将维护期间迁出的 VM 迁回原宿主机（等待后台任务完成）
+func (a *App) HostReturnVMs(hostID string) error {
+	j, err := a.HostReturnVMsJob(hostID)
+	if err != nil {
+		return err
+	}
+	return a.jobs.Wait(j.ID)
+}
+
+// HostReturnVMsJob 以后台任务方式将维护期间迁出的 VM 逐台迁回，须先结束维护模式
+func (a *App) HostReturnVMsJob(hostID string) (*store.Job, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.maintenance.Return(hostID, a.actorName())
+}
+
+// === 调度 ===
+
+// HostOvercommitList 获取各宿主机设置的超分比（未设置的宿主机使用默认值）
+func (a *App) HostOvercommitList() ([]store.HostOvercommit, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.HostOvercommitList()
+}
+
+// HostOvercommitSet 设置宿主机的 vCPU 与内存超分比，0 恢复默认（vCPU 4 倍，内存按实际可用量）
+func (a *App) HostOvercommitSet(hostID string, cpuRatio, memRatio float64) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if _, err := a.store.HostGet(hostID); err != nil {
+		return notFound("host", err)
+	}
+	if cpuRatio < 0 || memRatio < 0 {
+		return invalidParams("overcommit ratio must not be negative")
+	}
+	err := a.store.HostOvercommitSet(&store.HostOvercommit{HostID: hostID, CPURatio: cpuRatio, MemRatio: memRatio})
+	if err == nil {
+		a.audit(hostID, "", "host.overcommit", fmt.Sprintf("cpu=%g mem=%g", cpuRatio, memRatio))
+	}
+	return err
+}
+
+// PlacementGroupList 获取所有放置组
+func (a *App) PlacementGroupList() ([]store.PlacementGroup, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.PlacementGroupList()
+}
+
+// PlacementGroupCreate 新建放置组，policy 为 affinity 或 anti-affinity
+func (a *App) PlacementGroupCreate(name, policy string) (*store.PlacementGroup, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, invalidParams("placement group name is required")
+	}
+	if !placement.ValidPolicy(policy) {
+		return nil, invalidParams("invalid placement policy: %s (affinity | anti-affinity)", policy)
+	}
+	g := &store.PlacementGroup{Name: name, Policy: policy, Members: []string{}}
+	if err := a.store.PlacementGroupAdd(g); err != nil {
+		return nil, err
+	}
+	a.audit("", "", "placementGroup.create", name+" "+policy)
+	return g, nil
+}
+
+// PlacementGroupDelete 删除放置组（不影响组内 VM）
+func (a *App) PlacementGroupDelete(name string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	err := a.store.PlacementGroupDelete(name)
+	if err == nil {
+		a.audit("", "", "placementGroup.delete", name)
+	}
+	return err
+}
+
+// PlacementGroupAddVM 将已有 VM 加入放置组，之后的调度据此约束同组的新 VM
+func (a *App) PlacementGroupAddVM(name, vmName string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if _, err := a.store.PlacementGroupGet(name); err != nil {
+		return notFound("placement group "+name, err)
+	}
+	return a.store.PlacementGroupAddVM(name, vmName)
+}
+
+// PlacementGroupRemoveVM 将 VM 移出放置组
+func (a *App) PlacementGroupRemoveVM(name, vmName string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	return a.store.PlacementGroupRemoveVM(name, vmName)
+}
+
+// VMSchedule 计算新 VM 的调度决策（不创建），没有合格宿主机时决策的 HostID 为空
+func (a *App) VMSchedule(req placement.ScheduleRequest) (*placement.Decision, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.scheduler.Schedule(a.requestContext(), req)
+}
+
+// placeVM hostID 为 auto 时调度并返回选中的宿主机和决策，否则原样返回（只检查放置组是否存在）
+func (a *App) placeVM(hostID string, req placement.ScheduleRequest) (string, *placement.Decision, error) {
+	if a.store == nil {
+		if hostID != placement.Auto {
+			return hostID, nil, nil
+		}
+		return "", nil, fmt.Errorf("store not initialized")
+	}
+	return a.scheduler.Place(a.requestContext(), hostID, req)
+}
+
+// netRequirement 将 VM 的网络参数转换为调度条件，netType 为空时按 defaultType 处理
+func netRequirement(req *placement.ScheduleRequest, netType, netName, defaultType string) {
+	if netName == "" {
+		req.Networks = append(req.Networks, "default")
+		return
+	}
+	if netType == "" {
+		netType = defaultType
+	}
+	if netType == "bridge" {
+		req.Bridges = append(req.Bridges, netName)
+	} else {
+		req.Networks = append(req.Networks, netName)
+	}
+}
+
+// === 高可用 ===
+
+// HAHost 宿主机的高可用状态
+type HAHost struct {
+	HostID      string `json:"hostId"`
+	HostName    string `json:"hostName"`
+	State       string `json:"state"`       // SSH 连接状态
+	Since       string `json:"since"`       // 进入当前状态的时间
+	FenceMethod string `json:"fenceMethod"` // 未配置隔离时为空，此时不会故障转移
+	HAVMs       int    `json:"haVms"`
+	FailoverJob string `json:"failoverJob"` // 本次故障的故障转移任务
+	FailoverAt  string `json:"failoverAt"`
+	Fenced      bool   `json:"fenced"`
+}
+
+// HAStatus 高可用总体状态
+type HAStatus struct {
+	GraceSeconds int      `json:"graceSeconds"`
+	Hosts        []HAHost `json:"hosts"`
+}
+
+// initHA 启动高可用监控：宿主机持续不可达超过宽限期后隔离并在其他宿主机上重启其高可用 VM
+func (a *App) initHA() {
+	a.ha = ha.NewMonitor(a.sshPool, a.store, a.vmManager, a.jobs, a.scheduler, a.fencer)
+	a.ha.Start()
+}
+
+// stopHA 停止高可用监控
+func (a *App) stopHA() {
+	if a.ha != nil {
+		a.ha.Stop()
+	}
+}
+
+// VMHAList 获取标记为高可用的 VM
+func (a *App) VMHAList() ([]store.VMHA, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.VMHAList("", false)
+}
+
+// VMHASet 标记或取消 VM 的高可用，标记时缓存当前定义 XML
+// VM 磁盘须位于所有宿主机都能访问的共享存储，否则故障转移后无法启动
+func (a *App) VMHASet(hostID, vmName string, enabled bool) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if !enabled {
+		err := a.store.VMHADelete(hostID, vmName)
+		if err == nil {
+			a.audit(hostID, vmName, "vm.ha_disable", "")
+		}
+		return err
+	}
+	xmlContent, err := a.vmManager.GetXML(a.requestContext(), hostID, vmName)
+	if err != nil {
+		return err
+	}
+	if err := a.store.VMHAAdd(hostID, vmName, xmlContent); err != nil {
+		return err
+	}
+	detail := ""
+	if _, err := a.store.HostFenceGet(hostID); err != nil {
+		detail = "host has no fencing configured"
+	}
+	a.audit(hostID, vmName, "vm.ha_enable", detail)
+	return nil
+}
+
+// HostFenceList 获取各宿主机的隔离配置（不含密码）
+func (a *App) HostFenceList() ([]store.HostFence, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.HostFenceList()
+}
+
+// HostFenceSet 设置宿主机的隔离方式，method 为空时删除配置（该宿主机故障时不再故障转移）
+func (a *App) HostFenceSet(f store.HostFence) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if _, err := a.store.HostGet(f.HostID); err != nil {
+		return notFound("host", err)
+	}
+	if f.Method == "" {
+		err := a.store.HostFenceDelete(f.HostID)
+		if err == nil {
+			a.audit(f.HostID, "", "host.fence", "removed")
+		}
+		return err
+	}
+	if err := a.fencer.Validate(&f); err != nil {
+		return err
+	}
+	err := a.store.HostFenceSet(&f)
+	if err == nil {
+		a.audit(f.HostID, "", "host.fence", f.Method)
+	}
+	return err
+}
+
+// HostFenceStatus 通过隔离配置查询宿主机电源状态，用于验证配置是否可用
+func (a *App) HostFenceStatus(hostID string) (string, error) {
+	if a.store == nil {
+		return "", fmt.Errorf("store not initialized")
+	}
+	h, err := a.store.HostGet(hostID)
+	if err != nil {
+		return "", notFound("host", err)
+	}
+	f, err := a.store.HostFenceGet(hostID)
+	if err != nil {
+		return "", fmt.Errorf("no fencing configured for host %s", h.Name)
+	}
+	return a.fencer.Status(a.requestContext(), f, h)
+}
+
+// HAStatusGet 获取宽限期及各宿主机的高可用状态
+func (a *App) HAStatusGet() (*HAStatus, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	hosts, err := a.store.HostList()
+	if err != nil {
+		return nil, err
+	}
+	records, err := a.store.VMHAList("", false)
+	if err != nil {
+		return nil, err
+	}
+	fences, err := a.store.HostFenceList()
+	if err != nil {
+		return nil, err
+	}
+	count := make(map[string]int)
+	for _, rec := range records {
+		count[rec.HostID]++
+	}
+	methods := make(map[string]string)
+	for _, f := range fences {
+		methods[f.HostID] = f.Method
+	}
+
+	status := &HAStatus{GraceSeconds: int(a.ha.Grace() / time.Second), Hosts: []HAHost{}}
+	for _, h := range hosts {
+		health := a.sshPool.Health(h.ID)
+		hh := HAHost{
+			HostID:      h.ID,
+			HostName:    h.Name,
+			State:       health.State,
+			Since:       health.Since,
+			FenceMethod: methods[h.ID],
+			HAVMs:       count[h.ID],
+		}
+		if f := a.ha.Failover(h.ID); f != nil {
+			hh.FailoverJob = f.JobID
+			hh.FailoverAt = f.At.Format("2006-01-02 15:04:05")
+			hh.Fenced = f.Fenced
+		}
+		status.Hosts = append(status.Hosts, hh)
+	}
+	return status, nil
+}
+
+// haMoved VM 迁移成功后更新高可用记录
+func (a *App) haMoved(srcHostID, vmName, dstHostID string) {
+	if a.ha != nil {
+		a.ha.Moved(srcHostID, vmName, dstHostID)
+	}
+}
+
+// === 声明式配置 ===
+
+// SpecPlan 解析 spec（YAML 或 JSON）并与实际状态对比，返回变更计划，不做任何修改（也不连接宿主机）
+// 未连接的宿主机上的资源不参与对比，计划中给出警告
+func (a *App) SpecPlan(text string, prune bool) (*spec.Plan, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	s, err := spec.Parse([]byte(text))
+	if err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	return a.applier.Plan(a.requestContext(), s, prune)
+}
+
+// SpecApply 按 spec 执行变更（等待后台任务完成）
+func (a *App) SpecApply(text string, prune bool) error {
+	j, err := a.SpecApplyJob(text, prune)
+	if err != nil {
+		return err
+	}
+	return a.jobs.Wait(j.ID)
+}
+
+// SpecApplyJob 以后台任务方式按 spec 执行变更：先添加/更新并连接宿主机，重新采集后按计划顺序逐项执行，
+// 遇到错误即停止（已完成的变更保留，重新执行会从剩余的差异继续）。计划写入任务结果
+func (a *App) SpecApplyJob(text string, prune bool) (*store.Job, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	s, err := spec.Parse([]byte(text))
+	if err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	return a.applier.Apply(s, prune, a.actorName(), specOps{a}), nil
+}
+
+// specOps 以 App 方法实现 spec.Ops，在任务的 ctx 下执行（随任务取消而终止），审计操作者为提交者
+type specOps struct{ a *App }
+
+func (o specOps) HostConnect(ctx context.Context, id string) error {
+	return o.a.withContext(ctx).HostConnect(id)
+}
+
+func (o specOps) HostDelete(ctx context.Context, id string) error {
+	return o.a.withContext(ctx).HostDelete(id)
+}
+
+func (o specOps) VMCreate(ctx context.Context, hostID string, v *spec.VM, flavorID, imageID string) (*store.Job, error) {
+	return o.a.withContext(ctx).templateCreateJob(hostID, v.Name, flavorID, imageID, v.NetType, v.Network,
+		v.CloudInit.RootPassword, v.CloudInit.SSHKey, v.CloudInit.UserData, placement.ScheduleRequest{Groups: v.Groups})
+}
+
+func (o specOps) VMDelete(ctx context.Context, hostID, vmName string) error {
+	return o.a.withContext(ctx).VMDelete(hostID, vmName, false)
+}
+
+func (o specOps) VMHASet(ctx context.Context, hostID, vmName string, enabled bool) error {
+	return o.a.withContext(ctx).VMHASet(hostID, vmName, enabled)
+}
+
+// === 清单导出 ===
+
+// InventoryAnsible 导出 Ansible 动态清单（ansible-inventory --list 格式），user 非空时作为 VM 的 ansible_user
+// 未连接宿主机上的 VM 不在清单中
+func (a *App) InventoryAnsible(user string) (inventory.Ansible, error) {
+	d, err := a.inventoryData()
+	if err != nil {
+		return nil, err
+	}
+	return inventory.Build(d, user), nil
+}
+
+// InventorySSHConfig 导出 ssh_config，VM 经所在宿主机 ProxyJump 访问
+func (a *App) InventorySSHConfig(user string) (string, error) {
+	inv, err := a.InventoryAnsible(user)
+	if err != nil {
+		return "", err
+	}
+	return inventory.SSHConfig(inv), nil
+}
+
+// inventoryData 采集宿主机、VM 及其 IP，基于模板创建的 VM 补充 flavor 和镜像名称
+func (a *App) inventoryData() (*inventory.Data, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	hosts, err := a.store.HostList()
+	if err != nil {
+		return nil, err
+	}
+	flavors := make(map[string]string)
+	if list, err := a.store.FlavorList(); err == nil {
+		for _, f := range list {
+			flavors[f.ID] = f.Name
+		}
+	}
+
+	d := &inventory.Data{}
+	for _, h := range hosts {
+		d.Hosts = append(d.Hosts, inventory.Host{
+			ID:      h.ID,
+			Name:    h.Name,
+			Address: h.Host,
+			Port:    h.Port,
+			User:    h.User,
+			Tags:    splitList(h.Tags),
+		})
+		if !a.sshPool.IsConnected(h.ID) {
+			continue
+		}
+		vms, err := a.vmManager.List(a.requestContext(), h.ID)
+		if err != nil {
+			log.Printf("inventory: host %s: %v", h.ID, err)
+			continue
+		}
+
+		images := make(map[string]string)
+		if list, err := a.store.ImageList(h.ID); err == nil {
+			for _, img := range list {
+				images[img.ID] = img.Name
+			}
+		}
+		instances := make(map[string]store.Instance)
+		if list, err := a.store.InstanceList(h.ID); err == nil {
+			for _, inst := range list {
+				instances[inst.VMName] = inst
+			}
+		}
+
+		for _, v := range vms {
+			iv := inventory.VM{
+				HostID:   h.ID,
+				Name:     v.Name,
+				State:    v.State,
+				CPUs:     v.CPUs,
+				MemoryMB: v.MemoryMB,
+			}
+			if inst, ok := instances[v.Name]; ok {
+				iv.Flavor = flavors[inst.FlavorID]
+				iv.Image = images[inst.ImageID]
+			}
+			// 只有运行中的 VM 能查到地址
+			if v.State == "running" {
+				if detail, err := a.vmManager.Get(a.requestContext(), h.ID, v.Name); err == nil {
+					for _, n := range detail.NICs {
+						if n.IP != "" {
+							iv.IPs = append(iv.IPs, n.IP)
+						}
+					}
+				}
+			}
+			d.VMs = append(d.VMs, iv)
+		}
+	}
+	return d, nil
+}
+
+// === 定时任务 ===
+
+// initSchedules 启动定时任务检查，到期的任务在后台执行
+func (a *App) initSchedules() {
+	a.sched = schedule.NewRunner(a.sshPool, a.store, a.vmManager, scheduleActions{a})
+	a.sched.Start()
+}
+
+// stopSchedules 停止定时任务检查，执行中的任务不会中断
+func (a *App) stopSchedules() {
+	if a.sched != nil {
+		a.sched.Stop()
+	}
+}
+
+// scheduleActions 以 action 表实现 schedule.Actions
+type scheduleActions struct{ a *App }
+
+func (s scheduleActions) Fields(action string) (map[string]bool, bool) {
+	def, ok := actionIndex[action]
+	if !ok || def.call == nil {
+		return nil, false
+	}
+	return actionParamFields(action), true
+}
+
+func (s scheduleActions) Dispatch(ctx context.Context, actor, action string, params json.RawMessage) (interface{}, error) {
+	return s.a.withActor(actor).dispatch(ctx, action, params)
+}
+
+// ScheduleList 获取定时任务
+func (a *App) ScheduleList() ([]store.Schedule, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.ScheduleList()
+}
+
+// ScheduleGet 获取单个定时任务
+func (a *App) ScheduleGet(id string) (*store.Schedule, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	sc, err := a.store.ScheduleGet(id)
+	if err != nil {
+		return nil, notFound("schedule", err)
+	}
+	return sc, nil
+}
+
+// ScheduleAdd 添加定时任务
+func (a *App) ScheduleAdd(sc store.Schedule) (*store.Schedule, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if err := a.sched.Validate(&sc); err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	sc.NextRunAt = ""
+	if sc.Enabled {
+		next, err := schedule.Next(&sc, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		sc.NextRunAt = schedule.FormatRunAt(next)
+	}
+	sc.LastRunAt, sc.LastState = "", ""
+	sc.CreatedBy = a.actorName()
+	if err := a.store.ScheduleAdd(&sc); err != nil {
+		return nil, err
+	}
+	a.audit(sc.HostID, "", "schedule.add", fmt.Sprintf("%s (%s %s)", sc.Name, sc.Cron, sc.Action))
+	return &sc, nil
+}
+
+// ScheduleUpdate 更新定时任务，下次执行时间按新配置从当前时间起重新计算
+func (a *App) ScheduleUpdate(sc store.Schedule) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if _, err := a.store.ScheduleGet(sc.ID); err != nil {
+		return notFound("schedule", err)
+	}
+	if err := a.sched.Validate(&sc); err != nil {
+		return invalidParams("%w", err)
+	}
+	sc.NextRunAt = ""
+	if sc.Enabled {
+		next, err := schedule.Next(&sc, time.Now())
+		if err != nil {
+			return err
+		}
+		sc.NextRunAt = schedule.FormatRunAt(next)
+	}
+	if err := a.store.ScheduleUpdate(&sc); err != nil {
+		return err
+	}
+	a.audit(sc.HostID, "", "schedule.update", fmt.Sprintf("%s (%s %s)", sc.Name, sc.Cron, sc.Action))
+	return nil
+}
+
+// ScheduleDelete 删除定时任务及其执行记录，执行中的本次执行不会中断
+func (a *App) ScheduleDelete(id string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	sc, err := a.store.ScheduleGet(id)
+	if err != nil {
+		return notFound("schedule", err)
+	}
+	if err := a.store.ScheduleDelete(id); err != nil {
+		return err
+	}
+	a.audit(sc.HostID, "", "schedule.delete", sc.Name)
+	return nil
+}
+
+// ScheduleRunNow 立即执行一次定时任务（不影响下次执行时间），等待执行结束后返回执行记录
+func (a *App) ScheduleRunNow(id string) (*store.ScheduleRun, error) {
+	if a.store == nil || a.sched == nil {
+		return nil, fmt.Errorf("scheduler not started")
+	}
+	sc, err := a.store.ScheduleGet(id)
+	if err != nil {
+		return nil, notFound("schedule", err)
+	}
+	return a.sched.RunNow(a.requestContext(), sc, a.actorName())
+}
+
+// ScheduleRunList 获取定时任务的执行记录，最新的在前
+func (a *App) ScheduleRunList(id string, limit int) ([]store.ScheduleRun, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.ScheduleRunList(id, limit)
+}
+
+// SchedulePreview 预览 cron 表达式接下来 count 次（默认 5，最多 100）的触发时间，按时区格式化并带 UTC 偏移
+func (a *App) SchedulePreview(expr, timezone string, count int) ([]string, error) {
+	if count <= 0 {
+		count = 5
+	}
+	if count > 100 {
+		count = 100
+	}
+	return schedule.Preview(expr, timezone, count)
+}
+
+// === 告警 ===
+
+// initAlerts 创建告警引擎并挂接到资源采集和 VM 事件，须在 historyCollector 启动前调用
+func (a *App) initAlerts() {
+	a.alerts = alert.NewEngine(a.sshPool, a.store)
+	a.alerts.SetEmitter(a.emitter)
+	a.vmManager.SetEmitter(event.Multi{a.emitter, a.alerts})
+	a.historyCollector.OnCollect(a.alerts.Evaluate)
+}
+
+// AlertList 获取触发中的告警
+func (a *App) AlertList() ([]store.AlertEvent, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AlertEventList("", "", alert.StateFiring, 1000)
+}
+
+// AlertHistory 获取告警记录，hostID/ruleID/state 为空则不过滤
+func (a *App) AlertHistory(hostID, ruleID, state string, limit int) ([]store.AlertEvent, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AlertEventList(hostID, ruleID, state, limit)
+}
+
+// AlertRuleList 获取告警规则
+func (a *App) AlertRuleList() ([]store.AlertRule, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AlertRuleList()
+}
+
+// AlertRuleAdd 添加告警规则
+func (a *App) AlertRuleAdd(r store.AlertRule) (*store.AlertRule, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if err := alert.Validate(&r); err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	if err := a.store.AlertRuleAdd(&r); err != nil {
+		return nil, err
+	}
+	a.audit(r.HostID, "", "alertRule.add", fmt.Sprintf("%s (%s)", r.Name, r.Metric))
+	return &r, nil
+}
+
+// AlertRuleUpdate 更新告警规则，新条件从下一轮采集开始生效
+func (a *App) AlertRuleUpdate(r store.AlertRule) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if err := alert.Validate(&r); err != nil {
+		return invalidParams("%w", err)
+	}
+	if err := a.store.AlertRuleUpdate(&r); err != nil {
+		return err
+	}
+	a.audit(r.HostID, "", "alertRule.update", fmt.Sprintf("%s (%s)", r.Name, r.Metric))
+	return nil
+}
+
+// AlertRuleDelete 删除告警规则，其触发中的告警在下一轮采集时恢复
+func (a *App) AlertRuleDelete(id string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	r, err := a.store.AlertRuleGet(id)
+	if err != nil {
+		return notFound("alert rule", err)
+	}
+	if err := a.store.AlertRuleDelete(id); err != nil {
+		return err
+	}
+	a.audit(r.HostID, "", "alertRule.delete", r.Name)
+	return nil
+}
+
+// AlertChannelList 获取通知渠道（不含密码等敏感项）
+func (a *App) AlertChannelList() ([]store.AlertChannel, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AlertChannelList(false)
+}
+
+// AlertChannelAdd 添加通知渠道
+func (a *App) AlertChannelAdd(c store.AlertChannel) (*store.AlertChannel, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if err := alert.ValidateChannel(&c); err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	if err := a.store.AlertChannelAdd(&c); err != nil {
+		return nil, err
+	}
+	a.audit("", "", "alertChannel.add", fmt.Sprintf("%s (%s)", c.Name, c.Type))
+	c.Config = nil
+	return &c, nil
+}
+
+// AlertChannelUpdate 更新通知渠道，密码等敏感项留空则保留原值
+func (a *App) AlertChannelUpdate(c store.AlertChannel) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if err := alert.ValidateChannel(&c); err != nil {
+		return invalidParams("%w", err)
+	}
+	if err := a.store.AlertChannelUpdate(&c); err != nil {
+		return err
+	}
+	a.audit("", "", "alertChannel.update", fmt.Sprintf("%s (%s)", c.Name, c.Type))
+	return nil
+}
+
+// AlertChannelDelete 删除通知渠道
+func (a *App) AlertChannelDelete(id string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	c, err := a.store.AlertChannelGet(id)
+	if err != nil {
+		return notFound("alert channel", err)
+	}
+	if err := a.store.AlertChannelDelete(id); err != nil {
+		return err
+	}
+	a.audit("", "", "alertChannel.delete", c.Name)
+	return nil
+}
+
+// AlertChannelTest 向通知渠道发送测试通知
+func (a *App) AlertChannelTest(id string) error {
+	if a.store == nil || a.alerts == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	c, err := a.store.AlertChannelGet(id)
+	if err != nil {
+		return notFound("alert channel", err)
+	}
+	return a.alerts.Test(a.requestContext(), c)
+}
+
+// AlertSilenceList 获取静默，all 为 false 时仅返回未过期的
+func (a *App) AlertSilenceList(all bool) ([]store.AlertSilence, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AlertSilenceList(!all)
+}
+
+// AlertSilenceAdd 添加静默，duration 如 "2h"，与 EndsAt 二选一
+func (a *App) AlertSilenceAdd(s store.AlertSilence, duration string) (*store.AlertSilence, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if err := alert.ValidateSilence(&s, duration, time.Now()); err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	s.CreatedBy = a.actorName()
+	if err := a.store.AlertSilenceAdd(&s); err != nil {
+		return nil, err
+	}
+	a.audit(s.HostID, "", "alertSilence.add", fmt.Sprintf("until %s %s", s.EndsAt, s.Comment))
+	return &s, nil
+}
+
+// AlertSilenceDelete 删除静默
+func (a *App) AlertSilenceDelete(id string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if err := a.store.AlertSilenceDelete(id); err != nil {
+		return err
+	}
+	a.audit("", "", "alertSilence.delete", id)
+	return nil
+}
+
+// === 审计日志 ===
+
+// AuditList 获取指定宿主机的审计日志
+func (a *App) AuditList(hostID string, limit int) ([]store.AuditRecord, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AuditList(hostID, limit)
+}
+
+// AuditListAll 获取全部审计日志
+func (a *App) AuditListAll(limit int) ([]store.AuditRecord, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.AuditListAll(limit)
+}
+
+// === VM 备注 ===
+
+// VMNoteGet 获取 VM 备注
+func (a *App) VMNoteGet(hostID, vmName string) (string, error) {
+	if a.store == nil {
+		return "", fmt.Errorf("store not initialized")
+	}
+	key := fmt.Sprintf("vm_note:%s:%s", hostID, vmName)
+	return a.store.SettingGet(key)
+}
+
+// VMNoteSet 设置 VM 备注
+func (a *App) VMNoteSet(hostID, vmName, note string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	key := fmt.Sprintf("vm_note:%s:%s", hostID, vmName)
+	return a.store.SettingSet(key, note)
+}
+
+// === Cloud-Init ===
+
+// VMGenerateCloudInit 生成 cloud-init ISO
+func (a *App) VMGenerateCloudInit(hostID, outputPath string, cfg vm.CloudInitConfig) error {
+	err := a.vmManager.GenerateCloudInitISO(a.requestContext(), hostID, outputPath, cfg)
+	if err == nil {
+		a.audit(hostID, cfg.Hostname, "cloudinit.generate", outputPath)
+	}
+	return err
+}
+
+// === VM 迁移 ===
+
+// VMMigrate 在线迁移 VM（等待后台任务完成）
+func (a *App) VMMigrate(srcHostID, vmName, dstHostID string) error {
+	return a.jobs.Wait(a.VMMigrateJob(srcHostID, vmName, dstHostID).ID)
+}
+
+// VMMigrateJob 以后台任务方式在线迁移 VM，立即返回任务
+func (a *App) VMMigrateJob(srcHostID, vmName, dstHostID string) *store.Job {
+	return a.jobs.Start("vm.migrate", srcHostID, vmName, func(ctx context.Context, r *job.Run) error {
+		r.Log("live migrating %s to %s", vmName, dstHostID)
+		if err := a.vmManager.Migrate(ctx, srcHostID, vmName, dstHostID); err != nil {
+			return err
+		}
+		a.audit(srcHostID, vmName, "vm.migrate", fmt.Sprintf("to %s", dstHostID))
+		a.haMoved(srcHostID, vmName, dstHostID)
+		return nil
+	})
+}
+
+// VMMigrateOffline 离线迁移 VM (通过客户端中继，适用于网络隔离场景，等待后台任务完成)
+func (a *App) VMMigrateOffline(srcHostID, vmName, dstHostID string) error {
+	return a.jobs.Wait(a.VMMigrateOfflineJob(srcHostID, vmName, dstHostID).ID)
+}
+
+// VMMigrateOfflineJob 以后台任务方式离线迁移 VM，立即返回任务
+func (a *App) VMMigrateOfflineJob(srcHostID, vmName, dstHostID string) *store.Job {
+	// 各步骤对应的大致进度
+	stepPercent := map[string]int{"check": 5, "xml": 10, "copy": 15, "define": 90, "cleanup": 95, "done": 100}
+
+	return a.jobs.Start("vm.migrateOffline", srcHostID, vmName, func(ctx context.Context, r *job.Run) error {
+		err := a.vmManager.MigrateOffline(ctx, srcHostID, vmName, dstHostID, func(step, detail string) {
+			r.Log("%s: %s", step, detail)
+			r.Progress(stepPercent[step], detail)
+			a.emitter.Emit("migrate:progress", map[string]string{
+				"jobId":  r.ID(),
+				"step":   step,
+				"detail": detail,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		a.audit(srcHostID, vmName, "vm.migrate_offline", fmt.Sprintf("to %s", dstHostID))
+		a.haMoved(srcHostID, vmName, dstHostID)
+		return nil
+	})
+}
+
+// HostCheckTools 检测宿主机上的工具安装情况
+func (a *App) HostCheckTools(id string) (map[string]string, error) {
 	client, err := a.sshPool.Get(id)
 	if err != nil {
 		return nil, err
 	}
 	result := map[string]string{}
-	out, err := client.Execute("virsh --version 2>/dev/null")
+	out, err := client.Run(a.requestContext(), internalssh.OpQuery, "virsh --version 2>/dev/null")
 	if err != nil {
 		result["virsh"] = ""
 	} else {
@@ -887,6 +2385,70 @@ func (a *App) HostCheckTools(id string) (map[string]string, error) {
 	return result, nil
 }
 
+// HostInventory 获取宿主机清单，refresh 为 false 时优先返回缓存
+func (a *App) HostInventory(id string, refresh bool) (*monitor.Inventory, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if !refresh {
+		if inv := monitor.CachedInventory(a.store, id); inv != nil {
+			return inv, nil
+		}
+	}
+	return a.refreshInventory(a.requestContext(), id)
+}
+
+// HostInventoryChanges 获取宿主机清单变更记录
+func (a *App) HostInventoryChanges(id string, limit int) ([]store.HostInventoryChange, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.HostInventoryChanges(id, limit)
+}
+
+// refreshInventory 重新采集清单，与缓存比较后保存，有变更时发出 host:inventory 事件
+func (a *App) refreshInventory(ctx context.Context, id string) (*monitor.Inventory, error) {
+	inv, err := a.monitor.Inventory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return nil, err
+	}
+	changes := monitor.DiffInventory(monitor.CachedInventory(a.store, id), inv)
+	if err := a.store.HostInventorySave(id, string(data), changes); err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		a.emitter.Emit("host:inventory", map[string]interface{}{"hostId": id, "changes": changes})
+	}
+	return inv, nil
+}
+
+// validateVMParams 检查宿主机可接受新 VM（不在维护中），并按宿主机清单校验参数
+// 无缓存时现场采集；采集失败不阻止创建，由 virt-install 自行报错
+func (a *App) validateVMParams(hostID string, cpus, memoryMB int, machine, firmware string) error {
+	if a.store == nil {
+		return nil
+	}
+	if a.store.InMaintenance(hostID) {
+		return fmt.Errorf("host %s is in maintenance and does not accept new VMs", hostID)
+	}
+	inv := monitor.CachedInventory(a.store, hostID)
+	if inv == nil {
+		var err error
+		if inv, err = a.refreshInventory(a.requestContext(), hostID); err != nil {
+			log.Printf("host %s inventory: %v", hostID, err)
+			return nil
+		}
+	}
+	if err := inv.ValidateVM(cpus, memoryMB, machine, firmware); err != nil {
+		return invalidParams("%w", err)
+	}
+	return nil
+}
+
 // === 宿主机镜像文件管理 ===
 
 // HostImageFile 宿主机上的镜像文件
@@ -904,7 +2466,7 @@ func (a *App) HostImageScan(hostID string) ([]HostImageFile, error) {
 	}
 	// 扫描常见镜像目录
 	cmd := `find /var/lib/libvirt/images /root /home /opt -maxdepth 3 \( -name '*.qcow2' -o -name '*.img' -o -name '*.raw' -o -name '*.vmdk' \) -type f -printf '%s %p\n' 2>/dev/null | head -200`
-	output, err := client.Execute(cmd)
+	output, err := client.Run(a.requestContext(), internalssh.OpQuery, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("scan images: %w", err)
 	}
@@ -946,13 +2508,13 @@ func (a *App) HostImageScan(hostID string) ([]HostImageFile, error) {
 // HostImageDelete 删除宿主机上的镜像文件
 func (a *App) HostImageDelete(hostID, path string) error {
 	if path == "" || path == "/" {
-		return fmt.Errorf("invalid path")
+		return invalidParams("invalid path")
 	}
 	client, err := a.sshPool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("rm -f %s", path))
+	output, err := client.Run(a.requestContext(), internalssh.OpDisk, fmt.Sprintf("rm -f %s", path))
 	if err != nil {
 		return fmt.Errorf("delete failed: %s", output)
 	}
@@ -976,7 +2538,7 @@ func (a *App) HostDetectDistro(hostID string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	out, err := client.Execute("cat /etc/os-release 2>/dev/null | grep ^ID= | head -1 | cut -d= -f2 | tr -d '\"'")
+	out, err := client.Run(a.requestContext(), internalssh.OpQuery, "cat /etc/os-release 2>/dev/null | grep ^ID= | head -1 | cut -d= -f2 | tr -d '\"'")
 	if err != nil {
 		return "unknown", nil
 	}
@@ -1141,7 +2703,8 @@ func (a *App) HostRunScript(hostID, script string) (string, error) {
 	}
 	// 通过 heredoc 传递脚本执行
 	cmd := fmt.Sprintf("bash << 'VMCAT_SCRIPT_EOF'\n%s\nVMCAT_SCRIPT_EOF", script)
-	output, err := client.Execute(cmd)
+	res, err := client.ExecuteContext(a.requestContext(), cmd)
+	output := res.Combined
 	if err != nil {
 		return output, fmt.Errorf("script failed: %w\nOutput: %s", err, output)
 	}
@@ -1184,71 +2747,67 @@ func (a *App) ImageSourceDelete(id string) error {
 
 // === 镜像导入 ===
 
-// ImageImport 从 URL 下载镜像到宿主机（后台异步，通过 Events 推送进度）
+// ImageImport 从 URL 下载镜像到宿主机（后台任务，通过 Events 推送进度），返回任务 ID
 func (a *App) ImageImport(hostID, url, destPath, name, osVariant string) (string, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "ftp://") {
+		return "", invalidParams("url must be an http, https or ftp URL")
+	}
+	if err := validateImagePath(destPath); err != nil {
+		return "", err
+	}
 	client, err := a.sshPool.Get(hostID)
 	if err != nil {
 		return "", fmt.Errorf("host not connected: %w", err)
 	}
 
-	taskID := fmt.Sprintf("import-%d", time.Now().UnixNano())
-
-	a.importMu.Lock()
-	if a.importTasks == nil {
-		a.importTasks = make(map[string]*importTask)
-	}
-	task := &importTask{ID: taskID, HostID: hostID, Status: "downloading"}
-	a.importTasks[taskID] = task
-	a.importMu.Unlock()
-
-	go func() {
-		defer func() {
-			time.Sleep(30 * time.Second)
-			a.importMu.Lock()
-			delete(a.importTasks, taskID)
-			a.importMu.Unlock()
-		}()
+	j := a.jobs.Start("image.import", hostID, "", func(ctx context.Context, r *job.Run) error {
+		fail := func(msg string) error {
+			a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": r.ID(), "hostId": hostID, "error": msg})
+			return fmt.Errorf("%s", msg)
+		}
 
 		// 获取文件总大小
-		sizeOut, _ := client.Execute(fmt.Sprintf(`curl -sIL '%s' | grep -i content-length | tail -1 | awk '{print $2}' | tr -d '\r\n'`, url))
+		sizeOut, _ := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf(`curl -sIL %s | grep -i content-length | tail -1 | awk '{print $2}' | tr -d '\r\n'`, internalssh.ShellQuote(url)))
 		totalSize, _ := strconv.ParseInt(strings.TrimSpace(sizeOut), 10, 64)
-		task.TotalSize = totalSize
+		r.Log("downloading %s (%d bytes) to %s", url, totalSize, destPath)
 
 		// 确保目标目录存在
-		dir := destPath[:strings.LastIndex(destPath, "/")]
-		client.Execute(fmt.Sprintf("mkdir -p '%s'", dir))
+		client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("mkdir -p %s", internalssh.ShellQuote(path.Dir(destPath))))
 
 		// 后台下载并获取 PID
-		pidOut, err := client.Execute(fmt.Sprintf(`nohup wget -q -O '%s' '%s' >/dev/null 2>&1 & echo $!`, destPath, url))
+		pidOut, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf(`nohup wget -q -O %s %s >/dev/null 2>&1 & echo $!`, internalssh.ShellQuote(destPath), internalssh.ShellQuote(url)))
 		if err != nil {
-			task.Status = "error"
-			task.Error = "启动下载失败: " + err.Error()
-			a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": taskID, "error": task.Error})
-			return
+			return fail("启动下载失败: " + err.Error())
 		}
 		pid := strings.TrimSpace(pidOut)
 
 		// 轮询进度
 		for {
-			time.Sleep(2 * time.Second)
+			select {
+			case <-time.After(2 * time.Second):
+			case <-ctx.Done():
+				// 任务取消: 结束远端下载进程并删除残留文件，清理命令不随已取消的 ctx 中断
+				client.Run(context.WithoutCancel(ctx), internalssh.OpConfig, fmt.Sprintf("kill %s 2>/dev/null; rm -f %s", internalssh.ShellQuote(pid), internalssh.ShellQuote(destPath)))
+				a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": r.ID(), "hostId": hostID, "error": "canceled"})
+				return ctx.Err()
+			}
 
 			// 检查进程是否存活
-			_, aliveErr := client.Execute(fmt.Sprintf("kill -0 %s 2>/dev/null", pid))
+			_, aliveErr := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("kill -0 %s 2>/dev/null", internalssh.ShellQuote(pid)))
 			alive := aliveErr == nil
 
 			// 获取当前文件大小
-			curOut, _ := client.Execute(fmt.Sprintf("stat -c %%s '%s' 2>/dev/null || echo 0", destPath))
+			curOut, _ := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("stat -c %%s %s 2>/dev/null || echo 0", internalssh.ShellQuote(destPath)))
 			curSize, _ := strconv.ParseInt(strings.TrimSpace(curOut), 10, 64)
-			task.Current = curSize
 
 			pct := 0
 			if totalSize > 0 {
 				pct = int(curSize * 100 / totalSize)
 			}
-			task.Percent = pct
+			r.ProgressBytes(curSize, totalSize, fmt.Sprintf("%d/%d bytes", curSize, totalSize))
 
 			a.emitter.Emit("image:import:progress", map[string]interface{}{
-				"taskId":    taskID,
+				"taskId":    r.ID(),
 				"percent":   pct,
 				"current":   curSize,
 				"totalSize": totalSize,
@@ -1258,18 +2817,13 @@ func (a *App) ImageImport(hostID, url, destPath, name, osVariant string) (string
 			if !alive {
 				// 验证文件是否完整
 				if totalSize > 0 && curSize < totalSize*95/100 {
-					task.Status = "error"
-					task.Error = "下载中断或不完整"
-					a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": taskID, "error": task.Error})
-					return
+					return fail("下载中断或不完整")
 				}
 				break
 			}
 		}
 
 		// 下载完成，注册为 Image
-		task.Status = "done"
-		task.Percent = 100
 		if a.store != nil {
 			img := &store.Image{
 				HostID:    hostID,
@@ -1278,21 +2832,37 @@ func (a *App) ImageImport(hostID, url, destPath, name, osVariant string) (string
 				OSVariant: osVariant,
 			}
 			a.store.ImageAdd(img)
+			r.SetResult(img)
 		}
 		a.audit(hostID, "", "image.import", fmt.Sprintf("url=%s dest=%s", url, destPath))
 		a.emitter.Emit("image:import:done", map[string]interface{}{
-			"taskId":   taskID,
+			"taskId":   r.ID(),
 			"hostId":   hostID,
 			"destPath": destPath,
 			"name":     name,
 		})
-	}()
+		return nil
+	})
+
+	return j.ID, nil
+}
 
-	return taskID, nil
+// validateImagePath 校验镜像在宿主机上的目标路径：须为绝对文件路径且不含 ..
+func validateImagePath(p string) error {
+	if !path.IsAbs(p) || strings.HasSuffix(p, "/") {
+		return invalidParams("destPath must be an absolute file path")
+	}
+	if slices.Contains(strings.Split(p, "/"), "..") {
+		return invalidParams("destPath must not contain '..'")
+	}
+	return nil
 }
 
-// ImageUpload 从本地文件上传镜像到宿主机（后台异步）
+// ImageUpload 从本地文件上传镜像到宿主机（后台任务），返回任务 ID
 func (a *App) ImageUpload(hostID, localPath, destPath, name, osVariant string) (string, error) {
+	if err := validateImagePath(destPath); err != nil {
+		return "", err
+	}
 	client, err := a.sshPool.Get(hostID)
 	if err != nil {
 		return "", fmt.Errorf("host not connected: %w", err)
@@ -1304,43 +2874,31 @@ func (a *App) ImageUpload(hostID, localPath, destPath, name, osVariant string) (
 	}
 	totalSize := fi.Size()
 
-	taskID := fmt.Sprintf("upload-%d", time.Now().UnixNano())
-
-	a.importMu.Lock()
-	if a.importTasks == nil {
-		a.importTasks = make(map[string]*importTask)
-	}
-	task := &importTask{ID: taskID, HostID: hostID, Status: "uploading", TotalSize: totalSize}
-	a.importTasks[taskID] = task
-	a.importMu.Unlock()
-
-	go func() {
-		defer func() {
-			time.Sleep(30 * time.Second)
-			a.importMu.Lock()
-			delete(a.importTasks, taskID)
-			a.importMu.Unlock()
-		}()
+	j := a.jobs.Start("image.upload", hostID, "", func(ctx context.Context, r *job.Run) error {
+		fail := func(msg string) error {
+			a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": r.ID(), "hostId": hostID, "error": msg})
+			return fmt.Errorf("%s", msg)
+		}
 
 		f, err := os.Open(localPath)
 		if err != nil {
-			task.Status = "error"
-			task.Error = "打开本地文件失败: " + err.Error()
-			a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": taskID, "error": task.Error})
-			return
+			return fail("打开本地文件失败: " + err.Error())
 		}
 		defer f.Close()
+		r.Log("uploading %s (%d bytes) to %s", localPath, totalSize, destPath)
 
 		lastEmit := time.Now()
-		err = client.WriteFile(destPath, f, totalSize, func(written int64) {
-			task.Current = written
-			pct := int(written * 100 / totalSize)
-			task.Percent = pct
+		err = client.WriteFile(ctx, destPath, &ctxReader{ctx: ctx, r: f}, totalSize, func(written int64) {
+			pct := 0
+			if totalSize > 0 {
+				pct = int(written * 100 / totalSize)
+			}
+			r.ProgressBytes(written, totalSize, fmt.Sprintf("%d/%d bytes", written, totalSize))
 			// 限制事件频率，每 500ms 推一次
 			if time.Since(lastEmit) > 500*time.Millisecond {
 				lastEmit = time.Now()
 				a.emitter.Emit("image:import:progress", map[string]interface{}{
-					"taskId":    taskID,
+					"taskId":    r.ID(),
 					"percent":   pct,
 					"current":   written,
 					"totalSize": totalSize,
@@ -1350,15 +2908,15 @@ func (a *App) ImageUpload(hostID, localPath, destPath, name, osVariant string) (
 		})
 
 		if err != nil {
-			task.Status = "error"
-			task.Error = "上传失败: " + err.Error()
-			a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": taskID, "error": task.Error})
-			return
+			if ctx.Err() != nil {
+				client.Run(context.WithoutCancel(ctx), internalssh.OpConfig, "rm -f "+internalssh.ShellQuote(destPath))
+				a.emitter.Emit("image:import:error", map[string]interface{}{"taskId": r.ID(), "hostId": hostID, "error": "canceled"})
+				return ctx.Err()
+			}
+			return fail("上传失败: " + err.Error())
 		}
 
 		// 上传完成，注册为 Image
-		task.Status = "done"
-		task.Percent = 100
 		if a.store != nil {
 			img := &store.Image{
 				HostID:    hostID,
@@ -1367,27 +2925,191 @@ func (a *App) ImageUpload(hostID, localPath, destPath, name, osVariant string) (
 				OSVariant: osVariant,
 			}
 			a.store.ImageAdd(img)
+			r.SetResult(img)
 		}
 		a.audit(hostID, "", "image.upload", fmt.Sprintf("local=%s dest=%s", localPath, destPath))
 		a.emitter.Emit("image:import:done", map[string]interface{}{
-			"taskId":   taskID,
+			"taskId":   r.ID(),
 			"hostId":   hostID,
 			"destPath": destPath,
 			"name":     name,
 		})
-	}()
+		return nil
+	})
+
+	return j.ID, nil
+}
+
+// ctxReader 在 ctx 取消后让读取返回错误，用于中断上传
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-	return taskID, nil
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
 }
 
 // ImageImportStatus 获取所有活跃的导入任务状态
 func (a *App) ImageImportStatus() []importTask {
-	a.importMu.Lock()
-	defer a.importMu.Unlock()
+	jobs, _ := a.jobs.List("", "", 50)
 
 	var tasks []importTask
-	for _, t := range a.importTasks {
-		tasks = append(tasks, *t)
+	for _, j := range jobs {
+		if j.Type != "image.import" && j.Type != "image.upload" {
+			continue
+		}
+		// 旧接口仅返回进行中和最近结束的任务
+		status := "done"
+		switch j.State {
+		case job.StatePending, job.StateRunning:
+			status = "downloading"
+			if j.Type == "image.upload" {
+				status = "uploading"
+			}
+		case job.StateSucceeded:
+		default:
+			status = "error"
+		}
+		if j.FinishedAt != "" {
+			if t, err := time.ParseInLocation("2006-01-02 15:04:05", j.FinishedAt, time.Local); err == nil && time.Since(t) > 30*time.Second {
+				continue
+			}
+		}
+		t := importTask{
+			ID:        j.ID,
+			HostID:    j.HostID,
+			Status:    status,
+			Percent:   j.Progress,
+			TotalSize: j.Total,
+			Current:   j.Current,
+			Error:     j.Error,
+		}
+		tasks = append(tasks, t)
 	}
 	return tasks
 }
+
+// === 后台任务 ===
+
+// JobList 获取后台任务列表，hostID/state 为空则不过滤
+func (a *App) JobList(hostID, state string, limit int) ([]store.Job, error) {
+	return a.jobs.List(hostID, state, limit)
+}
+
+// JobGet 获取后台任务详情
+func (a *App) JobGet(id string) (*store.Job, error) {
+	return a.jobs.Get(id)
+}
+
+// JobCancel 取消后台任务
+func (a *App) JobCancel(id string) error {
+	return a.jobs.Cancel(id)
+}
+
+// === 用户与令牌 ===
+
+// UserList 获取 API 用户列表
+func (a *App) UserList() ([]store.User, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.UserList()
+}
+
+// UserAdd 添加 API 用户
+func (a *App) UserAdd(u store.User) (*store.User, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if err := validateUser(&u); err != nil {
+		return nil, invalidParams("%w", err)
+	}
+	if err := a.store.UserAdd(&u); err != nil {
+		return nil, err
+	}
+	a.audit("", "", "user.add", fmt.Sprintf("%s (%s)", u.Name, u.Role))
+	return &u, nil
+}
+
+// UserUpdate 更新 API 用户角色与访问范围
+func (a *App) UserUpdate(u store.User) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if err := validateUser(&u); err != nil {
+		return invalidParams("%w", err)
+	}
+	if err := a.store.UserUpdate(&u); err != nil {
+		return err
+	}
+	a.audit("", "", "user.update", fmt.Sprintf("%s (%s)", u.Name, u.Role))
+	return nil
+}
+
+// UserDelete 删除 API 用户及其令牌
+func (a *App) UserDelete(id string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	u, err := a.store.UserGet(id)
+	if err != nil {
+		return notFound("user", err)
+	}
+	if err := a.store.UserDelete(id); err != nil {
+		return err
+	}
+	a.audit("", "", "user.delete", u.Name)
+	return nil
+}
+
+// TokenList 获取令牌列表（不含明文），userID 为空返回全部
+func (a *App) TokenList(userID string) ([]store.APIToken, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	return a.store.TokenList(userID)
+}
+
+// TokenCreateResult 新建令牌结果，明文令牌仅返回这一次
+type TokenCreateResult struct {
+	Token string          `json:"token"`
+	Info  *store.APIToken `json:"info"`
+}
+
+// TokenCreate 为用户创建 API 令牌，expiresAt 格式为 "2006-01-02 15:04:05"，空为永不过期
+func (a *App) TokenCreate(userID, name, expiresAt string) (*TokenCreateResult, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	u, err := a.store.UserGet(userID)
+	if err != nil {
+		return nil, notFound("user", err)
+	}
+	if expiresAt != "" {
+		if _, err := time.ParseInLocation("2006-01-02 15:04:05", expiresAt, time.Local); err != nil {
+			return nil, invalidParams("invalid expiresAt: %s", expiresAt)
+		}
+	}
+	plain, t, err := a.store.TokenCreate(userID, name, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	a.audit("", "", "token.create", fmt.Sprintf("%s for %s", t.Prefix, u.Name))
+	return &TokenCreateResult{Token: plain, Info: t}, nil
+}
+
+// TokenRevoke 吊销 API 令牌
+func (a *App) TokenRevoke(id string) error {
+	if a.store == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if err := a.store.TokenRevoke(id); err != nil {
+		return err
+	}
+	a.audit("", "", "token.revoke", id)
+	return nil
+}
@@ -6,8 +6,13 @@ import (
 	"context"
 	"log"
 
+	"vmcat/internal/alert"
+	"vmcat/internal/maintenance"
 	"vmcat/internal/monitor"
+	"vmcat/internal/placement"
+	"vmcat/internal/spec"
 	"vmcat/internal/store"
+	"vmcat/internal/tray"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -35,6 +40,17 @@ func (a *App) startup(ctx context.Context) {
 	// 迁移旧的明文密码为加密格式
 	s.MigrateEncryptPasswords()
 
+	a.sshPool.SetEmitter(a.emitter)
+	a.jobs.SetEmitter(a.emitter)
+	a.vmManager.SetEmitter(a.emitter)
+	a.jobs.SetStore(s)
+	a.scheduler = placement.NewScheduler(a.sshPool, s, a.monitor, a.vmManager)
+	a.maintenance = maintenance.NewManager(a.sshPool, s, a.vmManager, a.jobs, a.scheduler)
+	a.maintenance.OnMoved(a.haMoved)
+	a.applier = spec.NewApplier(a.sshPool, s, a.vmManager, a.jobs, a.maintenance)
+	a.loadTimeouts()
+	a.loadSessionLimit()
+
 	// 启动终端 WebSocket 服务
 	if err := a.termSrv.Start(); err != nil {
 		log.Printf("start terminal server: %v", err)
@@ -42,6 +58,13 @@ func (a *App) startup(ctx context.Context) {
 
 	// 启动资源历史采集器
 	a.historyCollector = monitor.NewHistoryCollector(a.sshPool, a.store, a.monitor, a.vmManager)
+	a.loadStatsSettings()
+	a.initAlerts()
+	a.initHA()
+	a.initSchedules()
+	a.alerts.SetSender(alert.ChannelDesktop, func(ctx context.Context, _ map[string]string, ev *store.AlertEvent) error {
+		return tray.Notify(ctx, alert.Title(ev), ev.Message)
+	})
 	a.historyCollector.Start()
 }
 
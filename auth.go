@@ -0,0 +1,561 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"vmcat/internal/api"
+	"vmcat/internal/event"
+	"vmcat/internal/inventory"
+	"vmcat/internal/placement"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// localActor 桌面模式及未启用认证时审计日志中的操作者
+const localActor = "local"
+
+// rootKeyActor --api-key 对应的内置管理员名称
+const rootKeyActor = "api-key"
+
+// authenticator 服务端模式的令牌与客户端证书认证
+// rootKey 非空时作为内置管理员令牌（--api-key / VMCAT_API_KEY），其余令牌在数据库中校验
+type authenticator struct {
+	store   *store.Store
+	rootKey string
+}
+
+func (au *authenticator) Authenticate(token string) (*api.Principal, error) {
+	if au.rootKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(au.rootKey)) == 1 {
+		return &api.Principal{Name: rootKeyActor, Role: api.RoleAdmin}, nil
+	}
+	u, err := au.store.TokenAuthenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	return userPrincipal(u), nil
+}
+
+// AuthenticateCert 未携带令牌时，以客户端证书 CN 对应的同名用户认证
+func (au *authenticator) AuthenticateCert(cert *x509.Certificate) (*api.Principal, error) {
+	u, err := au.store.UserGetByName(api.CertSubject(cert))
+	if err != nil {
+		return nil, fmt.Errorf("no user for certificate %s", api.CertSubject(cert))
+	}
+	if u.Disabled {
+		return nil, fmt.Errorf("user %s disabled", u.Name)
+	}
+	return userPrincipal(u), nil
+}
+
+func userPrincipal(u *store.User) *api.Principal {
+	return &api.Principal{
+		UserID:     u.ID,
+		Name:       u.Name,
+		Role:       u.Role,
+		HostTags:   splitList(u.HostTags),
+		VMPatterns: splitList(u.VMPattern),
+	}
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// validateUser 校验用户名、角色和 VM 通配符
+func validateUser(u *store.User) error {
+	u.Name = strings.TrimSpace(u.Name)
+	if u.Name == "" {
+		return fmt.Errorf("user name is required")
+	}
+	if u.Name == rootKeyActor || u.Name == localActor {
+		return fmt.Errorf("user name %q is reserved", u.Name)
+	}
+	if !api.ValidRole(u.Role) {
+		return fmt.Errorf("invalid role: %s", u.Role)
+	}
+	for _, pattern := range splitList(u.VMPattern) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid vm pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// withActor 返回以 name 作为审计操作者的 App 副本，用于按请求记录操作者
+// App 的字段仅在初始化时写入，浅拷贝后与原实例共享全部资源
+func (a *App) withActor(name string) *App {
+	c := *a
+	c.actor = name
+	return &c
+}
+
+// withContext 返回在 ctx 下执行的 App 副本：同步调用中的远程命令随请求结束（客户端断开）或任务取消而终止
+func (a *App) withContext(ctx context.Context) *App {
+	c := *a
+	c.reqCtx = ctx
+	return &c
+}
+
+// requestContext 当前调用的 ctx，桌面端直接调用和后台循环中为 context.Background()
+func (a *App) requestContext() context.Context {
+	if a.reqCtx != nil {
+		return a.reqCtx
+	}
+	return context.Background()
+}
+
+// actorName 审计日志和记录创建者使用的操作者名称，未设置时为本地用户
+func (a *App) actorName() string {
+	if a.actor != "" {
+		return a.actor
+	}
+	return localActor
+}
+
+// serveAPI 服务端模式 API 入口：校验角色与访问范围后分发，并按范围过滤列表结果
+func (a *App) serveAPI(ctx context.Context, action string, data json.RawMessage) (interface{}, error) {
+	p := api.PrincipalFrom(ctx)
+	if p == nil {
+		// 未启用认证
+		return a.dispatch(ctx, action, data)
+	}
+	if action == "auth.whoami" {
+		return p, nil
+	}
+	if err := a.authorize(p, action, data); err != nil {
+		return nil, err
+	}
+	result, err := a.withActor(p.Actor()).dispatch(ctx, action, data)
+	if err != nil {
+		return nil, err
+	}
+	return a.filterResult(p, result), nil
+}
+
+// authorize 检查调用方角色，并检查请求涉及的宿主机和 VM 是否在其访问范围内
+func (a *App) authorize(p *api.Principal, action string, data json.RawMessage) error {
+	need := api.RoleAdmin
+	if def, ok := actionIndex[action]; ok {
+		need = def.Role
+	}
+	if !api.RoleAllows(p.Role, need) {
+		return fmt.Errorf("%w: %s requires %s role", api.ErrForbidden, action, need)
+	}
+	if !scoped(p) {
+		return nil
+	}
+	// 静默可能覆盖范围外的告警，限定了访问范围的用户不可管理
+	if action == "alertSilence.add" || action == "alertSilence.delete" {
+		return fmt.Errorf("%w: alert silences are not available to scoped users", api.ErrForbidden)
+	}
+	// spec 描述并修改全部宿主机上的资源
+	if strings.HasPrefix(action, "spec.") {
+		return fmt.Errorf("%w: declarative specs are not available to scoped users", api.ErrForbidden)
+	}
+	// 定时任务按执行时的选择器作用于任意宿主机和 VM
+	if strings.HasPrefix(action, "schedule.") {
+		return fmt.Errorf("%w: schedules are not available to scoped users", api.ErrForbidden)
+	}
+	// 用户和令牌管理可创建或放宽任意用户（包括自身）的访问范围
+	if strings.HasPrefix(action, "user.") || strings.HasPrefix(action, "token.") {
+		return fmt.Errorf("%w: user and token management is not available to scoped users", api.ErrForbidden)
+	}
+	// 全局设置和新增宿主机不属于任何已有宿主机，无法按范围检查
+	if action == "setting.set" || action == "host.add" || action == "host.importJSON" {
+		return fmt.Errorf("%w: %s is not available to scoped users", api.ErrForbidden, action)
+	}
+
+	// 宿主机级操作不对应某台 VM（卷、存储池、网络、NAT、任意磁盘路径），限定了 VM 范围的用户不可执行
+	if len(p.VMPatterns) > 0 && hostWideActions[action] {
+		return fmt.Errorf("%w: %s is not available to vm-scoped users", api.ErrForbidden, action)
+	}
+
+	hostIDs, vmNames := requestTargets(action, data)
+	// 自动调度会在全部宿主机中选择并返回各宿主机的情况，限定了宿主机范围的用户须指定宿主机
+	if len(p.HostTags) > 0 && (action == "vm.schedule" || slices.Contains(hostIDs, placement.Auto)) {
+		return fmt.Errorf("%w: automatic placement is not available to host-scoped users", api.ErrForbidden)
+	}
+	if action == "job.get" || action == "job.cancel" {
+		var req struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(data, &req)
+		if j, err := a.jobs.Get(req.ID); err == nil {
+			hostIDs = append(hostIDs, j.HostID)
+			vmNames = append(vmNames, j.VMName)
+		}
+	}
+
+	// 放置组的删除和加入影响组内全部 VM 的调度，组内成员均须在访问范围内
+	if action == "placementGroup.delete" || action == "placementGroup.addVM" {
+		var req struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(data, &req)
+		if g, err := a.store.PlacementGroupGet(req.Name); err == nil {
+			vmNames = append(vmNames, g.Members...)
+		}
+	}
+
+	for _, id := range hostIDs {
+		if id != "" && id != placement.Auto && !a.hostAllowed(p, id) {
+			return fmt.Errorf("%w: host %s is out of scope", api.ErrForbidden, id)
+		}
+	}
+	for _, name := range vmNames {
+		if name != "" && !vmAllowed(p, name) {
+			return fmt.Errorf("%w: vm %s is out of scope", api.ErrForbidden, name)
+		}
+	}
+	return nil
+}
+
+// hostWideActions 作用于整台宿主机、不对应某台 VM 的操作，或可指定任意宿主机路径的操作
+var hostWideActions = map[string]bool{
+	"vol.create":           true,
+	"vol.delete":           true,
+	"vm.resizeDisk":        true,
+	"vm.attachDisk":        true,
+	"vm.generateCloudInit": true,
+	"pool.start":           true,
+	"pool.stop":            true,
+	"pool.autostart":       true,
+	"network.start":        true,
+	"network.stop":         true,
+	"network.autostart":    true,
+	"nat.add":              true,
+	"nat.delete":           true,
+	"host.runScript":       true,
+	"host.imageDelete":     true,
+	"image.import":         true,
+	"terminal.open":        true,
+	"vm.defineXML":         true, // 域名称取自 XML，另见 requestTargets
+}
+
+// requestTargets 从请求参数中提取涉及的宿主机 ID 和 VM 名称
+func requestTargets(action string, data json.RawMessage) (hostIDs, vmNames []string) {
+	var req struct {
+		ID        string `json:"id"`
+		HostID    string `json:"hostId"`
+		SrcHostID string `json:"srcHostId"`
+		DstHostID string `json:"dstHostId"`
+		VMName    string `json:"vmName"`
+		SrcName   string `json:"srcName"`
+		NewName   string `json:"newName"`
+		OldName   string `json:"oldName"`
+		Params    struct {
+			Name string `json:"name"`
+		} `json:"params"`
+		XMLContent string `json:"xmlContent"`
+	}
+	json.Unmarshal(data, &req)
+
+	hostIDs = []string{req.HostID, req.SrcHostID, req.DstHostID}
+	if strings.HasPrefix(action, "host.") {
+		hostIDs = append(hostIDs, req.ID)
+	}
+	vmNames = []string{req.VMName, req.SrcName, req.NewName, req.OldName, req.Params.Name}
+	if action == "vm.defineXML" {
+		var domain struct {
+			Name string `xml:"name"`
+		}
+		xml.Unmarshal([]byte(req.XMLContent), &domain)
+		vmNames = append(vmNames, strings.TrimSpace(domain.Name))
+	}
+	return hostIDs, vmNames
+}
+
+// scoped 调用方是否限定了访问范围
+func scoped(p *api.Principal) bool {
+	return len(p.HostTags) > 0 || len(p.VMPatterns) > 0
+}
+
+// hostAllowed 宿主机是否带有调用方允许的任一标签，未限定标签时均允许
+func (a *App) hostAllowed(p *api.Principal, hostID string) bool {
+	if len(p.HostTags) == 0 {
+		return true
+	}
+	h, err := a.store.HostGet(hostID)
+	if err != nil {
+		return false
+	}
+	return hostTagsMatch(p, h)
+}
+
+func hostTagsMatch(p *api.Principal, h *store.Host) bool {
+	if len(p.HostTags) == 0 {
+		return true
+	}
+	for _, tag := range splitList(h.Tags) {
+		for _, allowed := range p.HostTags {
+			if tag == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// vmAllowed VM 名称是否匹配调用方的任一通配符，未限定时均允许
+func vmAllowed(p *api.Principal, name string) bool {
+	if len(p.VMPatterns) == 0 {
+		return true
+	}
+	for _, pattern := range p.VMPatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterResult 按调用方访问范围过滤列表结果
+func (a *App) filterResult(p *api.Principal, result interface{}) interface{} {
+	if !scoped(p) {
+		return result
+	}
+	switch list := result.(type) {
+	case inventory.Ansible:
+		return list.Filter(func(hostID, vmName string) bool {
+			return a.targetAllowed(p, hostID, vmName)
+		})
+	case []internalssh.HostHealth:
+		out := make([]internalssh.HostHealth, 0, len(list))
+		for _, h := range list {
+			if a.hostAllowed(p, h.HostID) {
+				out = append(out, h)
+			}
+		}
+		return out
+	case []internalssh.QueueStats:
+		out := make([]internalssh.QueueStats, 0, len(list))
+		for _, q := range list {
+			if a.hostAllowed(p, q.HostID) {
+				out = append(out, q)
+			}
+		}
+		return out
+	case []store.Maintenance:
+		out := make([]store.Maintenance, 0, len(list))
+		for _, m := range list {
+			if a.hostAllowed(p, m.HostID) {
+				out = append(out, m)
+			}
+		}
+		return out
+	case []store.AlertRule:
+		out := make([]store.AlertRule, 0, len(list))
+		for _, r := range list {
+			if r.HostID == "" || a.hostAllowed(p, r.HostID) {
+				out = append(out, r)
+			}
+		}
+		return out
+	case []store.AlertSilence:
+		out := make([]store.AlertSilence, 0, len(list))
+		for _, sl := range list {
+			if sl.HostID == "" || a.hostAllowed(p, sl.HostID) {
+				out = append(out, sl)
+			}
+		}
+		return out
+	case []importTask:
+		out := make([]importTask, 0, len(list))
+		for _, t := range list {
+			if a.hostAllowed(p, t.HostID) {
+				out = append(out, t)
+			}
+		}
+		return out
+	case []store.Host:
+		out := make([]store.Host, 0, len(list))
+		for i := range list {
+			if hostTagsMatch(p, &list[i]) {
+				out = append(out, list[i])
+			}
+		}
+		return out
+	case []vm.VM:
+		out := make([]vm.VM, 0, len(list))
+		for _, v := range list {
+			if vmAllowed(p, v.Name) {
+				out = append(out, v)
+			}
+		}
+		return out
+	case []store.Job:
+		out := make([]store.Job, 0, len(list))
+		for _, j := range list {
+			if a.targetAllowed(p, j.HostID, j.VMName) {
+				out = append(out, j)
+			}
+		}
+		return out
+	case []store.SnapshotPolicy:
+		out := make([]store.SnapshotPolicy, 0, len(list))
+		for _, sp := range list {
+			if a.targetAllowed(p, sp.HostID, sp.VMName) {
+				out = append(out, sp)
+			}
+		}
+		return out
+	case []store.AlertEvent:
+		out := make([]store.AlertEvent, 0, len(list))
+		for _, ev := range list {
+			if a.targetAllowed(p, ev.HostID, ev.VMName) {
+				out = append(out, ev)
+			}
+		}
+		return out
+	case []store.VMDiskStatsRecord:
+		out := make([]store.VMDiskStatsRecord, 0, len(list))
+		for _, r := range list {
+			if vmAllowed(p, r.VMName) {
+				out = append(out, r)
+			}
+		}
+		return out
+	case []store.VMNetStatsRecord:
+		out := make([]store.VMNetStatsRecord, 0, len(list))
+		for _, r := range list {
+			if vmAllowed(p, r.VMName) {
+				out = append(out, r)
+			}
+		}
+		return out
+	case []store.HostOvercommit:
+		out := make([]store.HostOvercommit, 0, len(list))
+		for _, o := range list {
+			if a.hostAllowed(p, o.HostID) {
+				out = append(out, o)
+			}
+		}
+		return out
+	case []store.PlacementGroup:
+		out := make([]store.PlacementGroup, 0, len(list))
+		for _, g := range list {
+			members := make([]string, 0, len(g.Members))
+			for _, name := range g.Members {
+				if vmAllowed(p, name) {
+					members = append(members, name)
+				}
+			}
+			g.Members = members
+			out = append(out, g)
+		}
+		return out
+	case []store.VMHA:
+		out := make([]store.VMHA, 0, len(list))
+		for _, v := range list {
+			if a.targetAllowed(p, v.HostID, v.VMName) {
+				out = append(out, v)
+			}
+		}
+		return out
+	case []store.HostFence:
+		out := make([]store.HostFence, 0, len(list))
+		for _, f := range list {
+			if a.hostAllowed(p, f.HostID) {
+				out = append(out, f)
+			}
+		}
+		return out
+	case *HAStatus:
+		out := &HAStatus{GraceSeconds: list.GraceSeconds, Hosts: make([]HAHost, 0, len(list.Hosts))}
+		for _, h := range list.Hosts {
+			if a.hostAllowed(p, h.HostID) {
+				out.Hosts = append(out.Hosts, h)
+			}
+		}
+		return out
+	case []store.Instance:
+		out := make([]store.Instance, 0, len(list))
+		for _, inst := range list {
+			if a.targetAllowed(p, inst.HostID, inst.VMName) {
+				out = append(out, inst)
+			}
+		}
+		return out
+	}
+	return result
+}
+
+// targetAllowed 宿主机和 VM 均在访问范围内（空值视为不限）
+func (a *App) targetAllowed(p *api.Principal, hostID, vmName string) bool {
+	return (hostID == "" || a.hostAllowed(p, hostID)) && (vmName == "" || vmAllowed(p, vmName))
+}
+
+// eventVisible 事件是否对调用方可见，按事件数据中的 hostId / vmName 判断
+func (a *App) eventVisible(p *api.Principal, ev event.Event) bool {
+	if p == nil || !scoped(p) {
+		return true
+	}
+	raw, err := json.Marshal(ev.Data)
+	if err != nil {
+		return false
+	}
+	var target struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Name   string `json:"name"`
+	}
+	json.Unmarshal(raw, &target)
+	name := target.VMName
+	if name == "" && strings.HasPrefix(ev.Topic, "vm:") {
+		name = target.Name
+	}
+	return a.targetAllowed(p, target.HostID, name)
+}
+
+// guardWS 为 WebSocket 入口（终端、VNC）做与 action 相同的权限检查，宿主机取自 query 参数 host
+func (a *App) guardWS(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p := api.PrincipalFrom(r.Context()); p != nil {
+			// VNC 按端口连接，无法对应到 VM 名称，限定 VM 范围的用户不可使用
+			if action == "vnc.open" && len(p.VMPatterns) > 0 {
+				http.Error(w, "forbidden: vnc is not available to vm-scoped users", http.StatusForbidden)
+				return
+			}
+			data, _ := json.Marshal(map[string]string{"hostId": r.URL.Query().Get("host")})
+			if err := a.authorize(p, action, data); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// guardMetrics /metrics 的权限检查：指标覆盖全部宿主机和 VM，限定了访问范围的用户不可读取
+func (a *App) guardMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := api.PrincipalFrom(r.Context()); p != nil {
+			if err := a.authorize(p, "metrics.read", nil); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if scoped(p) {
+				http.Error(w, "forbidden: metrics are not available to scoped users", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,2123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"vmcat/internal/inventory"
+	"vmcat/internal/spec"
+	"vmcat/pkg/client"
+)
+
+// defaultEndpoint 未配置时连接的服务端地址
+const defaultEndpoint = "http://127.0.0.1:9600"
+
+// ctlConfig ctl 配置文件 (~/.vmcat/ctl.yaml)
+type ctlConfig struct {
+	Endpoint   string `yaml:"endpoint"`   // 如 https://vmcat.example.com:9600
+	Token      string `yaml:"token"`      // API 令牌，可被 VMCAT_API_KEY 覆盖
+	Host       string `yaml:"host"`       // 默认宿主机（ID 或名称）
+	CACert     string `yaml:"caCert"`     // 校验服务端证书的 CA（自签名证书时即服务端证书）
+	ClientCert string `yaml:"clientCert"` // mTLS 客户端证书
+	ClientKey  string `yaml:"clientKey"`
+	Insecure   bool   `yaml:"insecure"` // 跳过服务端证书校验
+}
+
+// ctlCommand ctl 子命令，如 "vms start"
+type ctlCommand struct {
+	name  string
+	args  string // 用法中的位置参数说明
+	short string
+	run   func(cx *ctlContext) error
+}
+
+// ctlContext 单次 ctl 调用的参数与连接
+type ctlContext struct {
+	ctx  context.Context
+	cmd  *ctlCommand
+	fs   *flag.FlagSet
+	args []string
+	out  io.Writer
+
+	output   string
+	endpoint string
+	token    string
+	config   string
+	host     string
+	wait     bool
+	timeout  time.Duration
+	insecure bool
+
+	cfg    ctlConfig
+	client *client.Client
+}
+
+// ctlCommands 全部子命令，按资源分组
+var ctlCommands []*ctlCommand
+
+func init() {
+	ctlCommands = append(ctlCommands, ctlHostCommands()...)
+	ctlCommands = append(ctlCommands, ctlVMCommands()...)
+	ctlCommands = append(ctlCommands, ctlSnapshotCommands()...)
+	ctlCommands = append(ctlCommands, ctlPoolCommands()...)
+	ctlCommands = append(ctlCommands, ctlNetworkCommands()...)
+	ctlCommands = append(ctlCommands, ctlNATCommands()...)
+	ctlCommands = append(ctlCommands, ctlAlertCommands()...)
+	ctlCommands = append(ctlCommands, ctlGroupCommands()...)
+	ctlCommands = append(ctlCommands, ctlHACommands()...)
+	ctlCommands = append(ctlCommands, ctlSpecCommands()...)
+	ctlCommands = append(ctlCommands, ctlInventoryCommands()...)
+	ctlCommands = append(ctlCommands, ctlScheduleCommands()...)
+	ctlCommands = append(ctlCommands, ctlMiscCommands()...)
+}
+
+// runCtl ctl 子命令入口，返回进程退出码
+func runCtl(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		ctlUsage(os.Stdout, "")
+		return 0
+	}
+
+	cmd, rest := findCtlCommand(args)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", strings.Join(args, " "))
+		ctlUsage(os.Stderr, args[0])
+		return 2
+	}
+
+	cx := newCtlContext(cmd, rest)
+	if err := cmd.run(cx); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// findCtlCommand 按最长前缀匹配子命令，资源名可用单数形式（vm / host ...）
+// 只给出资源名时执行 list
+func findCtlCommand(args []string) (*ctlCommand, []string) {
+	resource := ctlResource(args[0])
+	verb, rest := "list", args[1:]
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		verb, rest = rest[0], rest[1:]
+	}
+	for _, cmd := range ctlCommands {
+		parts := strings.Fields(cmd.name)
+		if len(parts) == 1 && parts[0] == resource {
+			return cmd, args[1:]
+		}
+		if len(parts) == 2 && parts[0] == resource && parts[1] == verb {
+			return cmd, rest
+		}
+	}
+	return nil, nil
+}
+
+// ctlResource 资源名别名
+func ctlResource(name string) string {
+	switch name {
+	case "host", "vm", "snapshot", "pool", "network", "image", "job", "group", "schedule":
+		return name + "s"
+	case "snap":
+		return "snapshots"
+	case "net":
+		return "networks"
+	}
+	return name
+}
+
+// ctlUsage 输出命令列表，resource 为已知资源时只列出该资源的命令
+func ctlUsage(w io.Writer, resource string) {
+	fmt.Fprintf(w, "Usage: vmcat ctl <resource> <command> [flags] [args]\n\nCommands:\n")
+	known := false
+	for _, cmd := range ctlCommands {
+		known = known || strings.Fields(cmd.name)[0] == ctlResource(resource)
+	}
+	tw := newTable(w)
+	for _, cmd := range ctlCommands {
+		if known && strings.Fields(cmd.name)[0] != ctlResource(resource) {
+			continue
+		}
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.short)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	cx := newCtlContext(&ctlCommand{}, nil)
+	cx.fs.SetOutput(w)
+	cx.fs.PrintDefaults()
+	fmt.Fprintf(w, "\nEndpoint and token are read from --endpoint/--token, VMCAT_ENDPOINT/VMCAT_API_KEY,\nor the config file (~/.vmcat/ctl.yaml):\n\n"+
+		"  endpoint: https://vmcat.example.com:9600\n  token: vmcat_...\n  host: kvm-01        # default --host\n  caCert: ca.pem      # optional\n")
+}
+
+func newCtlContext(cmd *ctlCommand, args []string) *ctlContext {
+	cx := &ctlContext{
+		ctx:  context.Background(),
+		cmd:  cmd,
+		fs:   flag.NewFlagSet("vmcat ctl "+cmd.name, flag.ContinueOnError),
+		args: args,
+		out:  os.Stdout,
+	}
+	fs := cx.fs
+	fs.StringVar(&cx.output, "o", "table", "output format: table, json or yaml")
+	fs.StringVar(&cx.output, "output", "table", "output format: table, json or yaml")
+	fs.StringVar(&cx.endpoint, "endpoint", "", "API endpoint (default from config, VMCAT_ENDPOINT or "+defaultEndpoint+")")
+	fs.StringVar(&cx.token, "token", "", "API token (default from VMCAT_API_KEY or config)")
+	fs.StringVar(&cx.config, "config", "", "config file (default ~/.vmcat/ctl.yaml)")
+	fs.StringVar(&cx.host, "host", "", "host ID or name (default from VMCAT_HOST or config)")
+	fs.BoolVar(&cx.wait, "wait", false, "wait for asynchronous operations to finish")
+	fs.DurationVar(&cx.timeout, "timeout", 10*time.Minute, "timeout for --wait")
+	fs.BoolVar(&cx.insecure, "insecure", false, "skip TLS certificate verification")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: vmcat ctl %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.short)
+		fs.PrintDefaults()
+	}
+	return cx
+}
+
+// parse 解析参数（参数与位置参数可交错），检查位置参数个数并连接服务端
+// n 为所需位置参数个数，-1 表示不限
+func (cx *ctlContext) parse(n int) ([]string, error) {
+	var positional []string
+	args := cx.args
+	for {
+		if err := cx.fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = cx.fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if n >= 0 && len(positional) != n {
+		cx.fs.Usage()
+		return nil, fmt.Errorf("expected %d argument(s), got %d", n, len(positional))
+	}
+	switch cx.output {
+	case "table", "json", "yaml":
+	default:
+		return nil, fmt.Errorf("unknown output format %q", cx.output)
+	}
+	return positional, cx.connect()
+}
+
+// connect 按 参数 > 环境变量 > 配置文件 的顺序确定服务端地址和令牌
+func (cx *ctlContext) connect() error {
+	path := cx.config
+	if path == "" {
+		path = os.Getenv("VMCAT_CONFIG")
+	}
+	explicit := path != ""
+	if !explicit {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".vmcat", "ctl.yaml")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && (explicit || !os.IsNotExist(err)) {
+		return fmt.Errorf("read config: %w", err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &cx.cfg); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	endpoint := firstNonEmpty(cx.endpoint, os.Getenv("VMCAT_ENDPOINT"), cx.cfg.Endpoint, defaultEndpoint)
+	token := firstNonEmpty(cx.token, os.Getenv("VMCAT_API_KEY"), cx.cfg.Token)
+	cx.host = firstNonEmpty(cx.host, os.Getenv("VMCAT_HOST"), cx.cfg.Host)
+
+	hc, err := cx.httpClient()
+	if err != nil {
+		return err
+	}
+	cx.client = client.New(endpoint, token, client.WithHTTPClient(hc))
+	return nil
+}
+
+// httpClient 按配置构造 TLS 客户端
+func (cx *ctlContext) httpClient() (*http.Client, error) {
+	cfg := &tls.Config{InsecureSkipVerify: cx.insecure || cx.cfg.Insecure}
+	if cx.cfg.CACert != "" {
+		data, err := os.ReadFile(cx.cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", cx.cfg.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+	if cx.cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cx.cfg.ClientCert, cx.cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return &http.Client{Transport: transport, Timeout: 5 * time.Minute}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// hostID 解析 --host（ID 或名称）；未指定且只有一台宿主机时使用该宿主机
+// latestDeviceRates 每个 VM 设备只保留最新一条记录，按吞吐量从高到低排序
+func latestDeviceRates(disks []client.VMDiskStatsRecord, nics []client.VMNetStatsRecord) ([]client.VMDiskStatsRecord, []client.VMNetStatsRecord) {
+	latestDisk := make(map[string]int)
+	var outDisks []client.VMDiskStatsRecord
+	for _, d := range disks { // 记录按时间升序
+		key := d.VMName + "/" + d.Device
+		if i, ok := latestDisk[key]; ok {
+			outDisks[i] = d
+			continue
+		}
+		latestDisk[key] = len(outDisks)
+		outDisks = append(outDisks, d)
+	}
+	sort.SliceStable(outDisks, func(i, j int) bool {
+		return outDisks[i].RdBps+outDisks[i].WrBps > outDisks[j].RdBps+outDisks[j].WrBps
+	})
+
+	latestNIC := make(map[string]int)
+	var outNICs []client.VMNetStatsRecord
+	for _, n := range nics {
+		key := n.VMName + "/" + n.Device
+		if i, ok := latestNIC[key]; ok {
+			outNICs[i] = n
+			continue
+		}
+		latestNIC[key] = len(outNICs)
+		outNICs = append(outNICs, n)
+	}
+	sort.SliceStable(outNICs, func(i, j int) bool {
+		return outNICs[i].RxBps+outNICs[i].TxBps > outNICs[j].RxBps+outNICs[j].TxBps
+	})
+	return outDisks, outNICs
+}
+
+func (cx *ctlContext) hostID() (string, error) {
+	hosts, err := cx.client.HostList(cx.ctx)
+	if err != nil {
+		return "", err
+	}
+	if cx.host == "" {
+		if len(hosts) == 1 {
+			return hosts[0].ID, nil
+		}
+		return "", fmt.Errorf("--host is required (%d hosts available)", len(hosts))
+	}
+	for _, h := range hosts {
+		if h.ID == cx.host || h.Name == cx.host {
+			return h.ID, nil
+		}
+	}
+	return "", fmt.Errorf("host %q not found", cx.host)
+}
+
+// done 无结果的操作在 table 格式下输出提示
+func (cx *ctlContext) done(format string, args ...interface{}) error {
+	if cx.output == "table" {
+		fmt.Fprintf(cx.out, format+"\n", args...)
+	}
+	return nil
+}
+
+// waitJob 等待任务结束，失败或取消时返回错误
+func (cx *ctlContext) waitJob(job *client.Job) (*client.Job, error) {
+	ctx, cancel := context.WithTimeout(cx.ctx, cx.timeout)
+	defer cancel()
+	last := ""
+	for {
+		switch job.State {
+		case "succeeded":
+			return job, nil
+		case "failed", "canceled", "interrupted":
+			return job, fmt.Errorf("job %s %s: %s", job.ID, job.State, firstNonEmpty(job.Error, job.Message))
+		}
+		if msg := fmt.Sprintf("%s %d%% %s", job.State, job.Progress, job.Message); msg != last && cx.output == "table" {
+			fmt.Fprintf(os.Stderr, "job %s: %s\n", job.ID, msg)
+			last = msg
+		}
+		select {
+		case <-ctx.Done():
+			return job, fmt.Errorf("timed out waiting for job %s", job.ID)
+		case <-time.After(time.Second):
+		}
+		next, err := cx.client.JobGet(ctx, client.IDParams{ID: job.ID})
+		if err != nil {
+			return job, err
+		}
+		job = next
+	}
+}
+
+// showJob 输出异步任务，--wait 时等待完成
+func (cx *ctlContext) showJob(job *client.Job) error {
+	if job == nil {
+		return cx.done("done")
+	}
+	var err error
+	if cx.wait {
+		job, err = cx.waitJob(job)
+	}
+	if showErr := cx.show(job, func(t *table) {
+		t.row("JOB", "TYPE", "STATE", "PROGRESS", "MESSAGE")
+		t.row(job.ID, job.Type, job.State, fmt.Sprintf("%d%%", job.Progress), firstNonEmpty(job.Error, job.Message))
+	}); err == nil {
+		err = showErr
+	}
+	return err
+}
+
+// waitVMState 等待 VM 进入目标状态
+func (cx *ctlContext) waitVMState(hostID, name, state string) error {
+	ctx, cancel := context.WithTimeout(cx.ctx, cx.timeout)
+	defer cancel()
+	for {
+		vm, err := cx.client.VMGet(ctx, client.VMParams{HostID: hostID, VMName: name})
+		if err != nil {
+			return err
+		}
+		if vm.State == state {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s to be %s (now %s)", name, state, vm.State)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// === hosts ===
+
+func ctlHostCommands() []*ctlCommand {
+	return []*ctlCommand{
+		{name: "hosts list", short: "List hosts", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			hosts, err := cx.client.HostList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(hosts, func(t *table) {
+				t.row("ID", "NAME", "ADDRESS", "BACKEND", "TAGS")
+				for _, h := range hosts {
+					t.row(h.ID, h.Name, fmt.Sprintf("%s@%s:%d", h.User, h.Host, h.Port), firstNonEmpty(h.Backend, "ssh"), h.Tags)
+				}
+			})
+		}},
+		{name: "hosts get", args: "<host>", short: "Show host connection state and resource usage", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			health, err := cx.client.HostHealth(cx.ctx, client.IDParams{ID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(health, nil)
+		}},
+		{name: "hosts stats", args: "<host>", short: "Show host CPU, memory and disk usage", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			st, err := cx.client.HostResourceStats(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(st, func(t *table) {
+				t.row("CPU", "MEMORY", "DISK", "LOAD", "UPTIME")
+				t.row(fmt.Sprintf("%.1f%%", st.CPUPercent),
+					fmt.Sprintf("%s / %s (%.1f%%)", humanBytes(uint64(st.MemUsed)), humanBytes(uint64(st.MemTotal)), st.MemPercent),
+					fmt.Sprintf("%s / %s (%.1f%%)", humanBytes(uint64(st.DiskUsed)), humanBytes(uint64(st.DiskTotal)), st.DiskPercent),
+					st.LoadAvg, st.Uptime)
+			})
+		}},
+		{name: "hosts inventory", args: "<host>", short: "Show host hardware and software inventory (--refresh to re-collect, --changes for the change log)", run: func(cx *ctlContext) error {
+			refresh := cx.fs.Bool("refresh", false, "re-collect instead of using the cached inventory")
+			changes := cx.fs.Bool("changes", false, "show the inventory change log")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			if *changes {
+				list, err := cx.client.HostInventoryChanges(cx.ctx, client.HostInventoryChangesRequest{ID: id})
+				if err != nil {
+					return err
+				}
+				return cx.show(list, func(t *table) {
+					t.row("CHANGED", "FIELD", "OLD", "NEW")
+					for _, c := range list {
+						t.row(c.ChangedAt, c.Field, c.OldValue, c.NewValue)
+					}
+				})
+			}
+			inv, err := cx.client.HostInventory(cx.ctx, client.HostInventoryRequest{ID: id, Refresh: *refresh})
+			if err != nil {
+				return err
+			}
+			return cx.show(inv, func(t *table) {
+				machines := make([]string, 0, len(inv.Machines))
+				for _, m := range inv.Machines {
+					machines = append(machines, m.Name)
+				}
+				t.pairs("CPU", fmt.Sprintf("%s (%s)", inv.CPU.Model, inv.CPU.LibvirtModel),
+					"Topology", fmt.Sprintf("%d sockets x %d cores x %d threads = %d CPUs", inv.CPU.Sockets, inv.CPU.CoresPerSocket, inv.CPU.ThreadsPerCore, inv.CPU.CPUs),
+					"CPU flags", strings.Join(inv.CPU.Flags, " "),
+					"Memory", humanBytes(uint64(inv.MemoryMB)<<20),
+					"NUMA nodes", len(inv.NUMA),
+					"OS", inv.OS,
+					"Kernel", inv.Kernel,
+					"libvirt", inv.Libvirt,
+					"QEMU", inv.QEMU,
+					"KVM", inv.KVM,
+					"Max vCPUs", inv.MaxVCPUs,
+					"Firmware", strings.Join(inv.Firmware, ", "),
+					"Machines", strings.Join(machines, " "))
+				for _, f := range inv.Filesystems {
+					t.pairs("FS "+f.Mount, fmt.Sprintf("%s %s, %s free of %s", f.Source, f.Type, humanBytes(uint64(f.Avail)), humanBytes(uint64(f.Size))))
+				}
+				for _, n := range inv.NICs {
+					t.pairs("NIC "+n.Name, strings.TrimSpace(fmt.Sprintf("%s %s %s %d Mbps %s", n.Kind, n.MAC, n.State, n.SpeedMbps, strings.Join(n.Addresses, " "))))
+				}
+			})
+		}},
+		{name: "hosts io", args: "<host>", short: "Show the latest per-VM disk and network rates, busiest first (--vm, --device to filter)", run: func(cx *ctlContext) error {
+			vmName := cx.fs.String("vm", "", "only this VM")
+			device := cx.fs.String("device", "", "only this device, e.g. vda or vnet0")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			disks, err := cx.client.VMDiskHistory(cx.ctx, client.VMDiskHistoryRequest{HostID: id, VMName: *vmName, Device: *device, Hours: 1})
+			if err != nil {
+				return err
+			}
+			nics, err := cx.client.VMNetHistory(cx.ctx, client.VMNetHistoryRequest{HostID: id, VMName: *vmName, Device: *device, Hours: 1})
+			if err != nil {
+				return err
+			}
+			disks, nics = latestDeviceRates(disks, nics)
+			return cx.show(map[string]interface{}{"disks": disks, "interfaces": nics}, func(t *table) {
+				t.row("VM", "DEVICE", "READ/RX", "WRITE/TX", "IOPS/PPS", "LATENCY", "AT")
+				for _, d := range disks {
+					t.row(d.VMName, d.Device, humanRate(d.RdBps), humanRate(d.WrBps),
+						fmt.Sprintf("%.0f / %.0f", d.RdIOPS, d.WrIOPS), fmt.Sprintf("%.1f / %.1f ms", d.RdLatency, d.WrLatency), d.Timestamp)
+				}
+				for _, n := range nics {
+					t.row(n.VMName, n.Device, humanRate(n.RxBps), humanRate(n.TxBps),
+						fmt.Sprintf("%.0f / %.0f", n.RxPps, n.TxPps), "-", n.Timestamp)
+				}
+			})
+		}},
+		{name: "hosts drain", args: "<host>", short: "Enter maintenance mode and evacuate running VMs (asynchronous job; --dry-run to only show the plan)", run: func(cx *ctlContext) error {
+			reason := cx.fs.String("reason", "", "reason recorded in the audit log")
+			dryRun := cx.fs.Bool("dry-run", false, "show the placement plan without migrating")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			if *dryRun {
+				plan, err := cx.client.HostMaintenancePlan(cx.ctx, client.IDParams{ID: id})
+				if err != nil {
+					return err
+				}
+				return cx.show(plan, func(t *table) {
+					t.row("VM", "TARGET", "REASON")
+					for _, mv := range plan.Moves {
+						t.row(mv.VM, firstNonEmpty(mv.HostName, "-"), mv.Reason)
+					}
+				})
+			}
+			job, err := cx.client.HostMaintenanceEnter(cx.ctx, client.HostMaintenanceEnterRequest{ID: id, Reason: *reason, Async: true})
+			if err != nil {
+				return err
+			}
+			return cx.showJob(job)
+		}},
+		{name: "hosts undrain", args: "<host>", short: "End maintenance mode (--return to migrate evacuated VMs back as an asynchronous job)", run: func(cx *ctlContext) error {
+			returnVMs := cx.fs.Bool("return", false, "migrate evacuated VMs back to this host")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			// 已结束维护时仍可单独迁回
+			st, err := cx.client.HostMaintenanceStatus(cx.ctx, client.IDParams{ID: id})
+			if err != nil {
+				return err
+			}
+			if st.Maintenance.HostID != "" {
+				if err := cx.client.HostMaintenanceExit(cx.ctx, client.IDParams{ID: id}); err != nil {
+					return err
+				}
+			}
+			if !*returnVMs {
+				return cx.done("maintenance ended on %s", args[0])
+			}
+			job, err := cx.client.HostReturnVMs(cx.ctx, client.HostReturnVMsRequest{ID: id, Async: true})
+			if err != nil {
+				return err
+			}
+			return cx.showJob(job)
+		}},
+		{name: "hosts maintenance", args: "[host]", short: "List hosts in maintenance, or show one host's evacuation record", run: func(cx *ctlContext) error {
+			args, err := cx.parse(-1)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				list, err := cx.client.HostMaintenanceList(cx.ctx)
+				if err != nil {
+					return err
+				}
+				return cx.show(list, func(t *table) {
+					t.row("HOST", "STATE", "SINCE", "BY", "JOB", "REASON")
+					for _, m := range list {
+						t.row(m.HostID, m.State, m.StartedAt, m.CreatedBy, m.JobID, m.Reason)
+					}
+				})
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			st, err := cx.client.HostMaintenanceStatus(cx.ctx, client.IDParams{ID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(st, func(t *table) {
+				state := "not in maintenance"
+				if st.Maintenance.HostID != "" {
+					state = fmt.Sprintf("%s since %s (%s)", st.Maintenance.State, st.Maintenance.StartedAt, st.Maintenance.Reason)
+				}
+				fmt.Fprintf(t.w, "Maintenance: %s\n\n", state)
+				t.row("VM", "TARGET", "METHOD", "STATE", "ERROR")
+				for _, mv := range st.Moves {
+					t.row(mv.VMName, firstNonEmpty(mv.DstHostID, "-"), firstNonEmpty(mv.Method, "-"), mv.State, mv.Error)
+				}
+			})
+		}},
+		{name: "hosts overcommit", args: "[host]", short: "List per-host overcommit ratios, or set one host's (--cpu, --mem; 0 restores the default)", run: func(cx *ctlContext) error {
+			cpu := cx.fs.Float64("cpu", -1, "vCPU overcommit ratio")
+			mem := cx.fs.Float64("mem", -1, "memory overcommit ratio")
+			args, err := cx.parse(-1)
+			if err != nil {
+				return err
+			}
+			list, err := cx.client.HostOvercommitList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				return cx.show(list, func(t *table) {
+					t.row("HOST", "CPU", "MEMORY", "UPDATED")
+					for _, o := range list {
+						t.row(o.HostID, fmt.Sprintf("%g", o.CPURatio), fmt.Sprintf("%g", o.MemRatio), o.UpdatedAt)
+					}
+				})
+			}
+			if *cpu < 0 && *mem < 0 {
+				return fmt.Errorf("--cpu or --mem is required")
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			// 只修改指定的一项
+			req := client.HostSetOvercommitRequest{ID: id}
+			for _, o := range list {
+				if o.HostID == id {
+					req.CPURatio, req.MemRatio = o.CPURatio, o.MemRatio
+				}
+			}
+			if *cpu >= 0 {
+				req.CPURatio = *cpu
+			}
+			if *mem >= 0 {
+				req.MemRatio = *mem
+			}
+			if err := cx.client.HostSetOvercommit(cx.ctx, req); err != nil {
+				return err
+			}
+			return cx.done("overcommit on %s: cpu %g, memory %g", args[0], req.CPURatio, req.MemRatio)
+		}},
+		{name: "hosts connect", args: "<host>", short: "Connect to a host", run: func(cx *ctlContext) error {
+			return cx.hostAction(func(id string) error { return cx.client.HostConnect(cx.ctx, client.IDParams{ID: id}) }, "connected")
+		}},
+		{name: "hosts disconnect", args: "<host>", short: "Disconnect from a host", run: func(cx *ctlContext) error {
+			return cx.hostAction(func(id string) error { return cx.client.HostDisconnect(cx.ctx, client.IDParams{ID: id}) }, "disconnected")
+		}},
+	}
+}
+
+func (cx *ctlContext) hostAction(fn func(id string) error, verb string) error {
+	args, err := cx.parse(1)
+	if err != nil {
+		return err
+	}
+	cx.host = args[0]
+	id, err := cx.hostID()
+	if err != nil {
+		return err
+	}
+	if err := fn(id); err != nil {
+		return err
+	}
+	return cx.done("host %s %s", args[0], verb)
+}
+
+// === vms ===
+
+// vmPowerActions 电源操作及 --wait 时等待的目标状态（空表示不等待）
+var vmPowerActions = []struct {
+	verb, short, state, done string
+	call                     func(c *client.Client, ctx context.Context, p client.VMParams) error
+}{
+	{"start", "Start a VM", "running", "started", (*client.Client).VMStart},
+	{"shutdown", "Gracefully shut down a VM", "shut off", "shutting down", (*client.Client).VMShutdown},
+	{"reboot", "Reboot a VM", "", "rebooting", (*client.Client).VMReboot},
+	{"destroy", "Force off a VM", "shut off", "destroyed", (*client.Client).VMDestroy},
+	{"suspend", "Pause a VM", "paused", "suspended", (*client.Client).VMSuspend},
+	{"resume", "Resume a paused VM", "running", "resumed", (*client.Client).VMResume},
+}
+
+func ctlVMCommands() []*ctlCommand {
+	cmds := []*ctlCommand{
+		{name: "vms list", short: "List VMs on a host", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			vms, err := cx.client.VMList(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(vms, func(t *table) {
+				t.row("NAME", "STATE", "CPUS", "MEMORY")
+				for _, v := range vms {
+					t.row(v.Name, v.State, v.CPUs, fmt.Sprintf("%d MiB", v.MemoryMB))
+				}
+			})
+		}},
+		{name: "vms get", args: "<vm>", short: "Show VM details", run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			vm, err := cx.client.VMGet(cx.ctx, client.VMParams{HostID: id, VMName: name})
+			if err != nil {
+				return err
+			}
+			return cx.show(vm, func(t *table) {
+				t.pairs("Name", vm.Name, "State", vm.State, "CPUs", vm.CPUs,
+					"Memory", fmt.Sprintf("%d MiB", vm.MemoryMB), "Autostart", vm.Autostart, "VNC port", vm.VNCPort)
+				for _, d := range vm.Disks {
+					t.pairs("Disk "+d.Device, fmt.Sprintf("%s (%s, %.1f GB)", d.Path, d.Format, d.SizeGB))
+				}
+				for _, n := range vm.NICs {
+					t.pairs("NIC "+n.MAC, strings.TrimSpace(fmt.Sprintf("%s %s", firstNonEmpty(n.Network, n.Bridge), n.IP)))
+				}
+			})
+		}},
+		{name: "vms stats", args: "<vm>", short: "Show VM resource usage", run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			st, err := cx.client.VMStats(cx.ctx, client.VMParams{HostID: id, VMName: name})
+			if err != nil {
+				return err
+			}
+			return cx.show(st, func(t *table) {
+				t.pairs("CPU", fmt.Sprintf("%.1f%% (%d vCPU)", st.CPUPercent, st.VCPUs),
+					"Memory", fmt.Sprintf("%s RSS / %s", humanBytes(st.MemRSS), humanBytes(st.MemActual)),
+					"Network", fmt.Sprintf("rx %s / tx %s", humanBytes(st.NetRxBytes), humanBytes(st.NetTxBytes)),
+					"Disk", fmt.Sprintf("read %s / write %s", humanBytes(st.BlockRdBytes), humanBytes(st.BlockWrBytes)))
+			})
+		}},
+		{name: "vms xml", args: "<vm>", short: "Print the libvirt domain XML", run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			xml, err := cx.client.VMGetXML(cx.ctx, client.VMParams{HostID: id, VMName: name})
+			if err != nil {
+				return err
+			}
+			return cx.show(xml, nil)
+		}},
+	}
+
+	for _, pa := range vmPowerActions {
+		pa := pa
+		cmds = append(cmds, &ctlCommand{name: "vms " + pa.verb, args: "<vm>", short: pa.short, run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			if err := pa.call(cx.client, cx.ctx, client.VMParams{HostID: id, VMName: name}); err != nil {
+				return err
+			}
+			if cx.wait && pa.state != "" {
+				if err := cx.waitVMState(id, name, pa.state); err != nil {
+					return err
+				}
+				return cx.done("vm %s %s", name, pa.state)
+			}
+			return cx.done("vm %s %s", name, pa.done)
+		}})
+	}
+
+	cmds = append(cmds,
+		&ctlCommand{name: "vms delete", args: "<vm>", short: "Delete a VM (--remove-storage to delete its disks)", run: func(cx *ctlContext) error {
+			removeStorage := cx.fs.Bool("remove-storage", false, "also delete the VM's disk volumes")
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			if err := cx.client.VMDelete(cx.ctx, client.VMDeleteRequest{HostID: id, VMName: name, RemoveStorage: *removeStorage}); err != nil {
+				return err
+			}
+			return cx.done("vm %s deleted", name)
+		}},
+		&ctlCommand{name: "vms clone", args: "<vm> <new-name>", short: "Clone a shut-off VM (asynchronous job)", run: func(cx *ctlContext) error {
+			args, err := cx.parse(2)
+			if err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			job, err := cx.client.VMClone(cx.ctx, client.VMCloneRequest{HostID: id, SrcName: args[0], NewName: args[1], Async: true})
+			if err != nil {
+				return err
+			}
+			return cx.showJob(job)
+		}},
+		&ctlCommand{name: "vms migrate", args: "<vm>", short: "Migrate a VM to another host (asynchronous job)", run: func(cx *ctlContext) error {
+			to := cx.fs.String("to", "", "destination host ID or name")
+			offline := cx.fs.Bool("offline", false, "copy a shut-off VM instead of live migration")
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			if *to == "" {
+				return fmt.Errorf("--to is required")
+			}
+			src := cx.host
+			cx.host = *to
+			dst, err := cx.hostID()
+			cx.host = src
+			if err != nil {
+				return err
+			}
+			var job *client.Job
+			if *offline {
+				job, err = cx.client.VMMigrateOffline(cx.ctx, client.VMMigrateOfflineRequest{SrcHostID: id, VMName: name, DstHostID: dst, Async: true})
+			} else {
+				job, err = cx.client.VMMigrate(cx.ctx, client.VMMigrateRequest{SrcHostID: id, VMName: name, DstHostID: dst, Async: true})
+			}
+			if err != nil {
+				return err
+			}
+			return cx.showJob(job)
+		}},
+		&ctlCommand{name: "vms schedule", short: "Show which host automatic placement would choose for a new VM, and why", run: func(cx *ctlContext) error {
+			var req client.ScheduleRequest
+			var tags, networks, bridges, groups, images string
+			cx.fs.IntVar(&req.VCPUs, "cpus", 1, "vCPUs")
+			cx.fs.Int64Var(&req.MemoryMB, "memory", 1024, "memory in MB")
+			cx.fs.Int64Var(&req.DiskGB, "disk", 0, "disk size in GB (0 to skip the storage check)")
+			cx.fs.StringVar(&req.StoragePath, "storage", "", "directory the disk will be created in")
+			cx.fs.StringVar(&req.Strategy, "strategy", "spread", "spread | pack")
+			cx.fs.StringVar(&tags, "tags", "", "required host tags (comma separated)")
+			cx.fs.StringVar(&networks, "networks", "", "required libvirt networks (comma separated)")
+			cx.fs.StringVar(&bridges, "bridges", "", "required bridges (comma separated)")
+			cx.fs.StringVar(&groups, "groups", "", "placement groups the VM would join (comma separated)")
+			cx.fs.StringVar(&images, "images", "", "image or ISO paths that must exist on the host (comma separated)")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			req.Tags, req.Networks, req.Bridges = splitList(tags), splitList(networks), splitList(bridges)
+			req.Groups, req.Images = splitList(groups), splitList(images)
+			d, err := cx.client.VMSchedule(cx.ctx, req)
+			if err != nil {
+				return err
+			}
+			return cx.show(d, func(t *table) {
+				fmt.Fprintf(t.w, "Host: %s\nReason: %s\n\n", firstNonEmpty(d.HostName, "-"), d.Reason)
+				t.row("HOST", "ELIGIBLE", "DETAIL")
+				for _, c := range d.Candidates {
+					t.row(c.HostName, fmt.Sprint(c.Eligible), c.Reason)
+				}
+			})
+		}},
+	)
+	return cmds
+}
+
+// targetArgs 解析 <name> 参数（VM、存储池、网络）和所在宿主机
+func (cx *ctlContext) targetArgs() (hostID, name string, err error) {
+	args, err := cx.parse(1)
+	if err != nil {
+		return "", "", err
+	}
+	hostID, err = cx.hostID()
+	return hostID, args[0], err
+}
+
+// === snapshots ===
+
+func ctlSnapshotCommands() []*ctlCommand {
+	snapAction := func(verb, short, done string, call func(c *client.Client, ctx context.Context, hostID, vmName, snap string) error) *ctlCommand {
+		return &ctlCommand{name: "snapshots " + verb, args: "<vm> <snapshot>", short: short, run: func(cx *ctlContext) error {
+			args, err := cx.parse(2)
+			if err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			if err := call(cx.client, cx.ctx, id, args[0], args[1]); err != nil {
+				return err
+			}
+			return cx.done("snapshot %s of %s %s", args[1], args[0], done)
+		}}
+	}
+	return []*ctlCommand{
+		{name: "snapshots list", args: "<vm>", short: "List snapshots of a VM", run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			snaps, err := cx.client.SnapshotList(cx.ctx, client.VMParams{HostID: id, VMName: name})
+			if err != nil {
+				return err
+			}
+			return cx.show(snaps, func(t *table) {
+				t.row("NAME", "STATE", "PARENT", "CREATED")
+				for _, s := range snaps {
+					t.row(s.Name, s.State, s.Parent, s.CreatedAt)
+				}
+			})
+		}},
+		{name: "snapshots tree", args: "<vm>", short: "Show snapshots as a tree with memory state, size and description (* marks the current one)", run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			nodes, err := cx.client.SnapshotTree(cx.ctx, client.VMParams{HostID: id, VMName: name})
+			if err != nil {
+				return err
+			}
+			return cx.show(nodes, func(t *table) {
+				t.row("NAME", "STATE", "LOCATION", "MEMORY", "SIZE", "CREATED", "DESCRIPTION")
+				for _, n := range nodes {
+					label := strings.Repeat("  ", n.Depth) + n.Name
+					if n.Current {
+						label += " *"
+					}
+					size := "-"
+					if n.SizeBytes > 0 {
+						size = humanBytes(uint64(n.SizeBytes))
+					}
+					t.row(label, n.State, n.Location, n.Memory, size, n.CreatedAt, n.Description)
+				}
+			})
+		}},
+		{name: "snapshots auto", args: "<vm>", short: "Take a vmcat-auto-<time> snapshot and prune old ones by the VM's retention policy (--disk-only for raw disks)", run: func(cx *ctlContext) error {
+			diskOnly := cx.fs.Bool("disk-only", false, "take an external disk-only snapshot (internal snapshots need qcow2 disks)")
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			res, err := cx.client.SnapshotAuto(cx.ctx, client.SnapshotAutoRequest{HostID: id, VMName: name, DiskOnly: *diskOnly})
+			if err != nil {
+				return err
+			}
+			return showPrune(cx, res)
+		}},
+		{name: "snapshots prune", args: "<vm>", short: "Delete automatic snapshots the VM's retention policy no longer keeps (--dry-run to preview)", run: func(cx *ctlContext) error {
+			dryRun := cx.fs.Bool("dry-run", false, "only show which snapshots would be deleted")
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			res, err := cx.client.SnapshotPrune(cx.ctx, client.SnapshotPruneRequest{HostID: id, VMName: name, DryRun: *dryRun})
+			if err != nil {
+				return err
+			}
+			return showPrune(cx, res)
+		}},
+		{name: "snapshots policy", args: "[vm]", short: "List retention policies, or show / set one VM's (--keep-last, --keep-daily, --keep-weekly, --keep-monthly; --delete)", run: func(cx *ctlContext) error {
+			var sp client.SnapshotPolicy
+			cx.fs.IntVar(&sp.KeepLast, "keep-last", 0, "keep the newest N automatic snapshots")
+			cx.fs.IntVar(&sp.KeepDaily, "keep-daily", 0, "keep the newest snapshot of each of the last N days")
+			cx.fs.IntVar(&sp.KeepWeekly, "keep-weekly", 0, "keep the newest snapshot of each of the last N weeks")
+			cx.fs.IntVar(&sp.KeepMonthly, "keep-monthly", 0, "keep the newest snapshot of each of the last N months")
+			del := cx.fs.Bool("delete", false, "remove the policy (automatic snapshots are no longer pruned)")
+			args, err := cx.parse(-1)
+			if err != nil {
+				return err
+			}
+			if len(args) > 1 {
+				cx.fs.Usage()
+				return fmt.Errorf("expected at most 1 argument, got %d", len(args))
+			}
+			var id string
+			if len(args) == 1 || cx.host != "" {
+				if id, err = cx.hostID(); err != nil {
+					return err
+				}
+			}
+			set := false
+			cx.fs.Visit(func(f *flag.Flag) {
+				set = set || strings.HasPrefix(f.Name, "keep-")
+			})
+
+			if len(args) == 1 && *del {
+				if err := cx.client.SnapshotPolicyDelete(cx.ctx, client.VMParams{HostID: id, VMName: args[0]}); err != nil {
+					return err
+				}
+				return cx.done("snapshot policy of %s deleted", args[0])
+			}
+			if len(args) == 1 && set {
+				sp.HostID, sp.VMName = id, args[0]
+				if err := cx.client.SnapshotPolicySet(cx.ctx, sp); err != nil {
+					return err
+				}
+				return cx.done("snapshot policy of %s set", args[0])
+			}
+			if *del || set {
+				return fmt.Errorf("a VM is required to set or delete a policy")
+			}
+
+			list, err := cx.client.SnapshotPolicyList(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				out := list[:0]
+				for _, p := range list {
+					if p.VMName == args[0] {
+						out = append(out, p)
+					}
+				}
+				if len(out) == 0 {
+					return fmt.Errorf("vm %s has no snapshot policy", args[0])
+				}
+				list = out
+			}
+			return cx.show(list, func(t *table) {
+				t.row("HOST", "VM", "LAST", "DAILY", "WEEKLY", "MONTHLY", "LAST PRUNED")
+				for _, p := range list {
+					t.row(p.HostID, p.VMName, p.KeepLast, p.KeepDaily, p.KeepWeekly, p.KeepMonthly, p.LastPruned)
+				}
+			})
+		}},
+		{name: "snapshots create", args: "<vm> <snapshot>", short: "Create a snapshot (--disk-only for an external overlay snapshot, --quiesce to freeze guest filesystems via qemu-guest-agent)", run: func(cx *ctlContext) error {
+			desc := cx.fs.String("description", "", "snapshot description")
+			diskOnly := cx.fs.Bool("disk-only", false, "external disk-only snapshot: disks switch to new qcow2 overlays, no memory state")
+			quiesce := cx.fs.Bool("quiesce", false, "freeze guest filesystems during the snapshot (requires --disk-only and qemu-guest-agent)")
+			args, err := cx.parse(2)
+			if err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			if err := cx.client.SnapshotCreate(cx.ctx, client.SnapshotCreateRequest{
+				HostID: id, VMName: args[0], SnapName: args[1], Description: *desc, DiskOnly: *diskOnly, Quiesce: *quiesce,
+			}); err != nil {
+				return err
+			}
+			return cx.done("snapshot %s of %s created", args[1], args[0])
+		}},
+		{name: "snapshots commit", args: "<vm>", short: "Merge external snapshot overlays back into the base images and drop those snapshots", run: func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			job, err := cx.client.SnapshotCommit(cx.ctx, client.SnapshotCommitRequest{HostID: id, VMName: name, Async: true})
+			if err != nil {
+				return err
+			}
+			return cx.showJob(job)
+		}},
+		snapAction("revert", "Revert a VM to a snapshot", "reverted", func(c *client.Client, ctx context.Context, hostID, vmName, snap string) error {
+			return c.SnapshotRevert(ctx, client.SnapshotRevertRequest{HostID: hostID, VMName: vmName, SnapName: snap})
+		}),
+		snapAction("delete", "Delete a snapshot", "deleted", func(c *client.Client, ctx context.Context, hostID, vmName, snap string) error {
+			return c.SnapshotDelete(ctx, client.SnapshotDeleteRequest{HostID: hostID, VMName: vmName, SnapName: snap})
+		}),
+	}
+}
+
+// showPrune 输出自动快照清理结果
+func showPrune(cx *ctlContext, res *client.SnapshotPruneResult) error {
+	return cx.show(res, func(t *table) {
+		if res.Created != "" {
+			t.pairs("Created", res.Created)
+		}
+		if res.Policy == "" {
+			t.pairs("Policy", "none (nothing pruned)")
+			return
+		}
+		t.pairs("Policy", res.Policy)
+		fmt.Fprintln(t.w)
+		t.row("SNAPSHOT", "TIME", "ACTION", "KEPT BY")
+		for _, v := range res.Verdicts {
+			action := "keep"
+			if !v.Keep {
+				action = "delete"
+				if res.DryRun {
+					action = "would delete"
+				}
+			}
+			t.row(v.Name, v.Time, action, strings.Join(v.Reasons, ","))
+		}
+		for _, e := range res.Errors {
+			fmt.Fprintf(t.w, "error: %s\n", e)
+		}
+	})
+}
+
+// === pools / networks ===
+
+func ctlPoolCommands() []*ctlCommand {
+	return []*ctlCommand{
+		{name: "pools list", short: "List storage pools on a host", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			pools, err := cx.client.PoolList(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(pools, func(t *table) {
+				t.row("NAME", "STATE", "AUTOSTART", "CAPACITY", "ALLOCATION", "AVAILABLE")
+				for _, p := range pools {
+					t.row(p.Name, p.State, p.Autostart, p.Capacity, p.Allocation, p.Available)
+				}
+			})
+		}},
+		{name: "pools start", args: "<pool>", short: "Start a storage pool", run: func(cx *ctlContext) error {
+			return cx.namedAction("pool", "started", func(id, name string) error {
+				return cx.client.PoolStart(cx.ctx, client.PoolStartRequest{HostID: id, PoolName: name})
+			})
+		}},
+		{name: "pools stop", args: "<pool>", short: "Stop a storage pool", run: func(cx *ctlContext) error {
+			return cx.namedAction("pool", "stopped", func(id, name string) error {
+				return cx.client.PoolStop(cx.ctx, client.PoolStopRequest{HostID: id, PoolName: name})
+			})
+		}},
+	}
+}
+
+func ctlNetworkCommands() []*ctlCommand {
+	return []*ctlCommand{
+		{name: "networks list", short: "List virtual networks on a host", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			nets, err := cx.client.NetworkList(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(nets, func(t *table) {
+				t.row("NAME", "STATE", "AUTOSTART", "PERSISTENT", "BRIDGE")
+				for _, n := range nets {
+					t.row(n.Name, n.State, n.Autostart, n.Persistent, n.Bridge)
+				}
+			})
+		}},
+		{name: "networks start", args: "<network>", short: "Start a virtual network", run: func(cx *ctlContext) error {
+			return cx.namedAction("network", "started", func(id, name string) error {
+				return cx.client.NetworkStart(cx.ctx, client.NetworkStartRequest{HostID: id, NetName: name})
+			})
+		}},
+		{name: "networks stop", args: "<network>", short: "Stop a virtual network", run: func(cx *ctlContext) error {
+			return cx.namedAction("network", "stopped", func(id, name string) error {
+				return cx.client.NetworkStop(cx.ctx, client.NetworkStopRequest{HostID: id, NetName: name})
+			})
+		}},
+	}
+}
+
+// namedAction 对宿主机上按名称指定的资源执行无结果操作
+func (cx *ctlContext) namedAction(kind, done string, fn func(hostID, name string) error) error {
+	id, name, err := cx.targetArgs()
+	if err != nil {
+		return err
+	}
+	if err := fn(id, name); err != nil {
+		return err
+	}
+	return cx.done("%s %s %s", kind, name, done)
+}
+
+// === nat ===
+
+func ctlNATCommands() []*ctlCommand {
+	ruleFlags := func(cx *ctlContext) *client.NATAddRequest {
+		r := &client.NATAddRequest{}
+		cx.fs.StringVar(&r.Proto, "proto", "tcp", "protocol: tcp or udp")
+		cx.fs.StringVar(&r.HostPort, "host-port", "", "port on the host")
+		cx.fs.StringVar(&r.VMIP, "vm-ip", "", "VM address")
+		cx.fs.StringVar(&r.VMPort, "vm-port", "", "port on the VM")
+		return r
+	}
+	check := func(r *client.NATAddRequest) error {
+		if r.HostPort == "" || r.VMIP == "" || r.VMPort == "" {
+			return fmt.Errorf("--host-port, --vm-ip and --vm-port are required")
+		}
+		return nil
+	}
+	return []*ctlCommand{
+		{name: "nat list", short: "List port forwarding rules on a host", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			rules, err := cx.client.NATList(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(rules, func(t *table) {
+				t.row("PROTO", "HOST PORT", "VM", "COMMENT")
+				for _, r := range rules {
+					t.row(r.Proto, r.HostPort, r.VMIP+":"+r.VMPort, r.Comment)
+				}
+			})
+		}},
+		{name: "nat add", short: "Add a port forwarding rule", run: func(cx *ctlContext) error {
+			r := ruleFlags(cx)
+			cx.fs.StringVar(&r.Comment, "comment", "", "rule comment")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			if err := check(r); err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			r.HostID = id
+			if err := cx.client.NATAdd(cx.ctx, *r); err != nil {
+				return err
+			}
+			return cx.done("forwarding %s/%s -> %s:%s", r.Proto, r.HostPort, r.VMIP, r.VMPort)
+		}},
+		{name: "nat delete", short: "Delete a port forwarding rule", run: func(cx *ctlContext) error {
+			r := ruleFlags(cx)
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			if err := check(r); err != nil {
+				return err
+			}
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			if err := cx.client.NATDelete(cx.ctx, client.NATDeleteRequest{HostID: id, Proto: r.Proto, HostPort: r.HostPort, VMIP: r.VMIP, VMPort: r.VMPort}); err != nil {
+				return err
+			}
+			return cx.done("removed %s/%s -> %s:%s", r.Proto, r.HostPort, r.VMIP, r.VMPort)
+		}},
+	}
+}
+
+// === alerts ===
+
+func ctlAlertCommands() []*ctlCommand {
+	showAlerts := func(cx *ctlContext, alerts []client.AlertEvent) error {
+		return cx.show(alerts, func(t *table) {
+			t.row("ID", "SEVERITY", "RULE", "HOST", "VM", "STATE", "STARTED", "MESSAGE")
+			for _, a := range alerts {
+				state := a.State
+				if a.Silenced {
+					state += " (silenced)"
+				}
+				t.row(a.ID, a.Severity, a.RuleName, a.HostName, a.VMName, state, a.StartedAt, a.Message)
+			}
+		})
+	}
+	return []*ctlCommand{
+		{name: "alerts list", short: "List firing alerts", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			alerts, err := cx.client.AlertList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return showAlerts(cx, alerts)
+		}},
+		{name: "alerts history", short: "Show alert history (--host, --rule, --state to filter)", run: func(cx *ctlContext) error {
+			rule := cx.fs.String("rule", "", "filter by rule ID")
+			state := cx.fs.String("state", "", "filter by state: firing or resolved")
+			limit := cx.fs.Int("limit", 50, "number of records")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			var id string
+			if cx.host != "" {
+				var err error
+				if id, err = cx.hostID(); err != nil {
+					return err
+				}
+			}
+			alerts, err := cx.client.AlertHistory(cx.ctx, client.AlertHistoryRequest{HostID: id, RuleID: *rule, State: *state, Limit: *limit})
+			if err != nil {
+				return err
+			}
+			return showAlerts(cx, alerts)
+		}},
+		{name: "alerts rules", short: "List alert rules", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			rules, err := cx.client.AlertRuleList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(rules, func(t *table) {
+				t.row("ID", "NAME", "METRIC", "CONDITION", "FOR", "SEVERITY", "ENABLED")
+				for _, r := range rules {
+					cond := fmt.Sprintf("%s %g", r.Operator, r.Threshold)
+					if r.Metric == "host.unreachable" || r.Metric == "vm.crashed" {
+						cond = "-"
+					}
+					t.row(r.ID, r.Name, r.Metric, cond, time.Duration(r.ForSeconds)*time.Second, r.Severity, r.Enabled)
+				}
+			})
+		}},
+		{name: "alerts silence", short: "Silence alerts (--host, --rule, --vm to narrow, --for to set the duration)", run: func(cx *ctlContext) error {
+			var req client.AlertSilenceAddRequest
+			cx.fs.StringVar(&req.RuleID, "rule", "", "only silence this rule ID")
+			cx.fs.StringVar(&req.VMPattern, "vm", "", "only silence VMs matching these patterns (comma separated)")
+			cx.fs.StringVar(&req.Duration, "for", "1h", "how long to silence")
+			cx.fs.StringVar(&req.Comment, "comment", "", "reason for the silence")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			if cx.host != "" {
+				var err error
+				if req.HostID, err = cx.hostID(); err != nil {
+					return err
+				}
+			}
+			s, err := cx.client.AlertSilenceAdd(cx.ctx, req)
+			if err != nil {
+				return err
+			}
+			return cx.show(s, func(t *table) {
+				t.pairs("ID", s.ID, "Ends", s.EndsAt)
+			})
+		}},
+		{name: "alerts unsilence", args: "<silence>", short: "Remove a silence", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			if err := cx.client.AlertSilenceDelete(cx.ctx, client.IDParams{ID: args[0]}); err != nil {
+				return err
+			}
+			return cx.done("silence %s removed", args[0])
+		}},
+	}
+}
+
+// === placement groups ===
+
+func ctlGroupCommands() []*ctlCommand {
+	return []*ctlCommand{
+		{name: "groups list", short: "List placement groups", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			groups, err := cx.client.PlacementGroupList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(groups, func(t *table) {
+				t.row("NAME", "POLICY", "MEMBERS")
+				for _, g := range groups {
+					t.row(g.Name, g.Policy, strings.Join(g.Members, ","))
+				}
+			})
+		}},
+		{name: "groups create", args: "<group>", short: "Create a placement group (--policy affinity | anti-affinity)", run: func(cx *ctlContext) error {
+			policy := cx.fs.String("policy", "anti-affinity", "affinity | anti-affinity")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			g, err := cx.client.PlacementGroupCreate(cx.ctx, client.PlacementGroupCreateRequest{Name: args[0], Policy: *policy})
+			if err != nil {
+				return err
+			}
+			return cx.done("placement group %s created (%s)", g.Name, g.Policy)
+		}},
+		{name: "groups delete", args: "<group>", short: "Delete a placement group", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			if err := cx.client.PlacementGroupDelete(cx.ctx, client.PlacementGroupDeleteRequest{Name: args[0]}); err != nil {
+				return err
+			}
+			return cx.done("placement group %s deleted", args[0])
+		}},
+		{name: "groups add", args: "<group> <vm>", short: "Add an existing VM to a placement group", run: func(cx *ctlContext) error {
+			args, err := cx.parse(2)
+			if err != nil {
+				return err
+			}
+			if err := cx.client.PlacementGroupAddVM(cx.ctx, client.PlacementGroupAddVMRequest{Name: args[0], VMName: args[1]}); err != nil {
+				return err
+			}
+			return cx.done("vm %s added to %s", args[1], args[0])
+		}},
+		{name: "groups remove", args: "<group> <vm>", short: "Remove a VM from a placement group", run: func(cx *ctlContext) error {
+			args, err := cx.parse(2)
+			if err != nil {
+				return err
+			}
+			if err := cx.client.PlacementGroupRemoveVM(cx.ctx, client.PlacementGroupRemoveVMRequest{Name: args[0], VMName: args[1]}); err != nil {
+				return err
+			}
+			return cx.done("vm %s removed from %s", args[1], args[0])
+		}},
+	}
+}
+
+// === high availability ===
+
+func ctlHACommands() []*ctlCommand {
+	setHA := func(enabled bool) func(cx *ctlContext) error {
+		return func(cx *ctlContext) error {
+			id, name, err := cx.targetArgs()
+			if err != nil {
+				return err
+			}
+			if err := cx.client.VMSetHA(cx.ctx, client.VMSetHARequest{HostID: id, VMName: name, Enabled: enabled}); err != nil {
+				return err
+			}
+			if enabled {
+				return cx.done("vm %s marked HA", name)
+			}
+			return cx.done("vm %s no longer HA", name)
+		}
+	}
+	return []*ctlCommand{
+		{name: "ha list", short: "List VMs marked for HA restart", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			vms, err := cx.client.VMHAList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(vms, func(t *table) {
+				t.row("HOST", "VM", "RUNNING", "XML CACHED", "FAILED FROM")
+				for _, v := range vms {
+					t.row(v.HostID, v.VMName, fmt.Sprint(v.Running), v.XMLUpdatedAt, firstNonEmpty(v.FailedFrom, "-"))
+				}
+			})
+		}},
+		{name: "ha status", short: "Show per-host HA state, fencing and failover jobs", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			st, err := cx.client.HAStatus(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(st, func(t *table) {
+				fmt.Fprintf(t.w, "Grace period: %ds\n\n", st.GraceSeconds)
+				t.row("HOST", "STATE", "SINCE", "FENCE", "HA VMS", "FAILOVER JOB")
+				for _, h := range st.Hosts {
+					t.row(h.HostName, h.State, h.Since, firstNonEmpty(h.FenceMethod, "-"), fmt.Sprint(h.HAVMs), firstNonEmpty(h.FailoverJob, "-"))
+				}
+			})
+		}},
+		{name: "ha enable", args: "<vm>", short: "Restart a VM on another host if its host goes down (disks must be on shared storage)", run: setHA(true)},
+		{name: "ha disable", args: "<vm>", short: "Stop restarting a VM on host failure", run: setHA(false)},
+		{name: "ha fence", args: "[host]", short: "List fencing configs, or set one host's (--method ipmi | script | none, empty to remove; --status to test)", run: func(cx *ctlContext) error {
+			var req client.HostSetFenceRequest
+			cx.fs.StringVar(&req.Method, "method", "", "ipmi | script | none")
+			cx.fs.StringVar(&req.Address, "address", "", "BMC address (ipmi)")
+			cx.fs.StringVar(&req.Username, "user", "", "BMC user (ipmi)")
+			cx.fs.StringVar(&req.Password, "password", "", "BMC password (ipmi; empty keeps the current one)")
+			cx.fs.StringVar(&req.Script, "script", "", "fence script in the server's --fence-script-dir (name or absolute path), called as <script> off|status <host address>")
+			status := cx.fs.Bool("status", false, "query the host's power state through its fencing config")
+			args, err := cx.parse(-1)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				list, err := cx.client.HostFenceList(cx.ctx)
+				if err != nil {
+					return err
+				}
+				return cx.show(list, func(t *table) {
+					t.row("HOST", "METHOD", "ADDRESS", "USER", "SCRIPT", "UPDATED")
+					for _, f := range list {
+						t.row(f.HostID, f.Method, f.Address, f.Username, f.Script, f.UpdatedAt)
+					}
+				})
+			}
+			cx.host = args[0]
+			id, err := cx.hostID()
+			if err != nil {
+				return err
+			}
+			if *status {
+				out, err := cx.client.HostFenceStatus(cx.ctx, client.IDParams{ID: id})
+				if err != nil {
+					return err
+				}
+				return cx.show(out, nil)
+			}
+			req.ID = id
+			if err := cx.client.HostSetFence(cx.ctx, req); err != nil {
+				return err
+			}
+			if req.Method == "" {
+				return cx.done("fencing removed from %s", args[0])
+			}
+			return cx.done("fencing on %s: %s", args[0], req.Method)
+		}},
+	}
+}
+
+// === declarative specs ===
+
+func ctlSpecCommands() []*ctlCommand {
+	// load 读取 -f 指定的 spec（- 为标准输入），替换 ${VAR} 并在本地校验后返回原文
+	load := func(cx *ctlContext, file string) (string, error) {
+		if file == "" {
+			cx.fs.Usage()
+			return "", fmt.Errorf("-f is required")
+		}
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return "", err
+		}
+		data, missing := spec.ExpandEnv(data, os.LookupEnv)
+		if len(missing) > 0 {
+			return "", fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+		}
+		if _, err := spec.Parse(data); err != nil {
+			return "", fmt.Errorf("%s: %w", file, err)
+		}
+		return string(data), nil
+	}
+	return []*ctlCommand{
+		{name: "spec plan", args: "-f <file>", short: "Show what apply would change to match a YAML/JSON spec (--prune to also delete undeclared resources)", run: func(cx *ctlContext) error {
+			file := cx.fs.String("f", "", "spec file (YAML or JSON, - for stdin)")
+			prune := cx.fs.Bool("prune", false, "delete VMs, networks, pools, images, flavors, groups and hosts not in the spec")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			text, err := load(cx, *file)
+			if err != nil {
+				return err
+			}
+			plan, err := cx.client.SpecPlan(cx.ctx, client.SpecPlanRequest{Spec: text, Prune: *prune})
+			if err != nil {
+				return err
+			}
+			return cx.show(plan, func(t *table) { renderPlan(t, plan) })
+		}},
+		{name: "spec apply", args: "-f <file>", short: "Create, update and (--prune) delete resources to match a spec, after confirmation (--yes to skip)", run: func(cx *ctlContext) error {
+			file := cx.fs.String("f", "", "spec file (YAML or JSON, - for stdin)")
+			prune := cx.fs.Bool("prune", false, "delete VMs, networks, pools, images, flavors, groups and hosts not in the spec")
+			yes := cx.fs.Bool("yes", false, "apply without asking for confirmation")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			text, err := load(cx, *file)
+			if err != nil {
+				return err
+			}
+			plan, err := cx.client.SpecPlan(cx.ctx, client.SpecPlanRequest{Spec: text, Prune: *prune})
+			if err != nil {
+				return err
+			}
+			tw := newTable(os.Stderr)
+			renderPlan(&table{w: tw}, plan)
+			tw.Flush()
+			if len(plan.Changes) == 0 {
+				return nil
+			}
+			if !*yes {
+				if *file == "-" {
+					return fmt.Errorf("--yes is required when the spec is read from stdin")
+				}
+				fmt.Fprint(os.Stderr, "\nApply these changes? Only 'yes' is accepted: ")
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if strings.TrimSpace(answer) != "yes" {
+					return fmt.Errorf("apply canceled")
+				}
+			}
+			// 计划在服务端执行时重新生成（宿主机变化后其上的资源会再次对比）
+			job, err := cx.client.SpecApply(cx.ctx, client.SpecApplyRequest{Spec: text, Prune: *prune, Async: true})
+			if err != nil {
+				return err
+			}
+			cx.wait = true
+			return cx.showJob(job)
+		}},
+	}
+}
+
+// renderPlan 按 + 创建、~ 更新、- 删除逐项输出变更计划
+func renderPlan(t *table, plan *client.Plan) {
+	symbols := map[string]string{"create": "+", "update": "~", "delete": "-"}
+	for _, c := range plan.Changes {
+		line := fmt.Sprintf("%s %s %s", symbols[c.Action], c.Kind, c.Name)
+		if c.VM != "" {
+			line += " (vm " + c.VM + ")"
+		}
+		if c.Host != "" {
+			line += " on " + c.Host
+		}
+		fmt.Fprintln(t.w, line)
+		for _, d := range c.Diffs {
+			fmt.Fprintf(t.w, "      %s\n", d)
+		}
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Fprintln(t.w, "No changes. Live state matches the spec.")
+	} else {
+		fmt.Fprintf(t.w, "\nPlan: %d to create, %d to update, %d to delete.\n", plan.Create, plan.Update, plan.Delete)
+	}
+	for _, w := range plan.Warnings {
+		fmt.Fprintf(t.w, "Warning: %s\n", w)
+	}
+}
+
+// === inventory ===
+
+func ctlInventoryCommands() []*ctlCommand {
+	// fetch 获取服务端按访问范围过滤后的清单
+	fetch := func(cx *ctlContext, user string) (inventory.Ansible, error) {
+		out, err := cx.client.InventoryAnsible(cx.ctx, client.InventoryAnsibleRequest{User: user})
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		return inventory.Parse(data)
+	}
+	return []*ctlCommand{
+		{name: "inventory list", short: "Print the Ansible dynamic inventory (--list; --host <name> for one host's vars, as Ansible calls inventory scripts)", run: func(cx *ctlContext) error {
+			user := cx.fs.String("user", "", "ansible_user for VMs")
+			cx.fs.Bool("list", true, "print the whole inventory (default; accepted for Ansible)")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			// Ansible 以 --host <清单名称> 调用清单脚本，与全局的默认宿主机无关
+			var name string
+			cx.fs.Visit(func(f *flag.Flag) {
+				if f.Name == "host" {
+					name = f.Value.String()
+				}
+			})
+			inv, err := fetch(cx, *user)
+			if err != nil {
+				return err
+			}
+			var v interface{} = inv
+			if name != "" {
+				vars := inv.HostVarsOf(name)
+				if vars == nil {
+					return fmt.Errorf("%s is not in the inventory", name)
+				}
+				v = vars
+			}
+			data, err := json.MarshalIndent(v, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cx.out, string(data))
+			return nil
+		}},
+		{name: "inventory ssh-config", short: "Print an ssh_config reaching each VM through its KVM host (ProxyJump)", run: func(cx *ctlContext) error {
+			user := cx.fs.String("user", "", "SSH user for VMs")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			inv, err := fetch(cx, *user)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cx.out, inventory.SSHConfig(inv))
+			return nil
+		}},
+	}
+}
+
+// === schedules ===
+
+func ctlScheduleCommands() []*ctlCommand {
+	// find 按 ID 或名称查找定时任务
+	find := func(cx *ctlContext, ref string) (*client.Schedule, error) {
+		list, err := cx.client.ScheduleList(cx.ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if list[i].ID == ref || list[i].Name == ref {
+				return &list[i], nil
+			}
+		}
+		return nil, fmt.Errorf("schedule %q not found", ref)
+	}
+	// bind 注册定时任务的参数，返回 --params 的值
+	bind := func(cx *ctlContext, sc *client.Schedule) *string {
+		cx.fs.StringVar(&sc.Cron, "cron", "", "cron expression: minute hour day-of-month month day-of-week, or @daily etc.")
+		cx.fs.StringVar(&sc.Timezone, "tz", "", "IANA time zone for the cron expression (default server local time)")
+		cx.fs.IntVar(&sc.JitterSeconds, "jitter", 0, "delay each run by a random 0..N seconds (at most half the interval)")
+		cx.fs.StringVar(&sc.Action, "action", "", "API action to run, e.g. vm.shutdown or snapshot.create")
+		cx.fs.StringVar(&sc.Target, "target", "vm", "run once per vm, once per host, or once (none)")
+		cx.fs.StringVar(&sc.VMPattern, "vm", "", "VM name patterns (comma separated, * for all VMs)")
+		cx.fs.StringVar(&sc.HostTags, "tags", "", "only hosts with any of these tags (comma separated)")
+		cx.fs.StringVar(&sc.Missed, "missed", "skip", "runs missed while the server was down: skip or run (catch up once)")
+		return cx.fs.String("params", "", `action parameters as a JSON object; ${date}, ${time}, ${vm} and ${host} are expanded`)
+	}
+	// apply 处理需要转换的参数：--params 和显式给出的 --host（全局默认宿主机不作为选择器）
+	apply := func(cx *ctlContext, sc *client.Schedule, params string) error {
+		if params != "" {
+			if !json.Valid([]byte(params)) {
+				return fmt.Errorf("--params is not valid JSON")
+			}
+			sc.Params = json.RawMessage(params)
+		}
+		explicit := false
+		cx.fs.Visit(func(f *flag.Flag) {
+			explicit = explicit || f.Name == "host"
+		})
+		if !explicit {
+			return nil
+		}
+		if cx.host == "" {
+			sc.HostID = ""
+			return nil
+		}
+		id, err := cx.hostID()
+		if err != nil {
+			return err
+		}
+		sc.HostID = id
+		return nil
+	}
+	setEnabled := func(enabled bool) func(cx *ctlContext) error {
+		return func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			sc, err := find(cx, args[0])
+			if err != nil {
+				return err
+			}
+			sc.Enabled = enabled
+			if err := cx.client.ScheduleUpdate(cx.ctx, *sc); err != nil {
+				return err
+			}
+			if enabled {
+				return cx.done("schedule %s enabled", sc.Name)
+			}
+			return cx.done("schedule %s disabled", sc.Name)
+		}
+	}
+	showRun := func(cx *ctlContext, run *client.ScheduleRun) error {
+		return cx.show(run, func(t *table) {
+			t.pairs("State", run.State, "Started", run.StartedAt, "Finished", run.FinishedAt, "Message", run.Message)
+			if len(run.Results) > 0 {
+				fmt.Fprintln(t.w)
+				t.row("HOST", "VM", "ERROR")
+				for _, r := range run.Results {
+					t.row(r.HostID, r.VMName, r.Error)
+				}
+			}
+		})
+	}
+
+	return []*ctlCommand{
+		{name: "schedules list", short: "List schedules", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			list, err := cx.client.ScheduleList(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(list, func(t *table) {
+				t.row("NAME", "CRON", "TZ", "ACTION", "TARGET", "ENABLED", "NEXT RUN", "LAST RUN", "LAST STATE")
+				for _, sc := range list {
+					target := sc.Target
+					if sc.Target == "vm" {
+						target += ":" + sc.VMPattern
+					}
+					t.row(sc.Name, sc.Cron, firstNonEmpty(sc.Timezone, "local"), sc.Action, target, sc.Enabled, sc.NextRunAt, sc.LastRunAt, sc.LastState)
+				}
+			})
+		}},
+		{name: "schedules get", args: "<schedule>", short: "Show a schedule", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			sc, err := find(cx, args[0])
+			if err != nil {
+				return err
+			}
+			return cx.show(sc, func(t *table) {
+				t.pairs("ID", sc.ID, "Name", sc.Name, "Cron", sc.Cron, "Timezone", firstNonEmpty(sc.Timezone, "local"),
+					"Jitter", time.Duration(sc.JitterSeconds)*time.Second, "Action", sc.Action, "Params", string(sc.Params),
+					"Target", sc.Target, "Host", sc.HostID, "Host tags", sc.HostTags, "VMs", sc.VMPattern, "Missed runs", sc.Missed,
+					"Enabled", sc.Enabled, "Next run", sc.NextRunAt, "Last run", sc.LastRunAt, "Last state", sc.LastState,
+					"Created by", sc.CreatedBy)
+			})
+		}},
+		{name: "schedules add", args: "<name>", short: "Add a schedule (--cron, --action, --vm or --target host|none; --host, --tags to narrow)", run: func(cx *ctlContext) error {
+			sc := client.Schedule{Enabled: true}
+			params := bind(cx, &sc)
+			disabled := cx.fs.Bool("disabled", false, "create the schedule disabled")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			if err := apply(cx, &sc, *params); err != nil {
+				return err
+			}
+			sc.Name = args[0]
+			sc.Enabled = !*disabled
+			out, err := cx.client.ScheduleAdd(cx.ctx, sc)
+			if err != nil {
+				return err
+			}
+			return cx.show(out, func(t *table) {
+				t.pairs("ID", out.ID, "Next run", firstNonEmpty(out.NextRunAt, "disabled"))
+			})
+		}},
+		{name: "schedules set", args: "<schedule>", short: "Change a schedule (only the flags given; --host \"\" to clear the host)", run: func(cx *ctlContext) error {
+			var patch client.Schedule
+			params := bind(cx, &patch)
+			cx.fs.StringVar(&patch.Name, "name", "", "rename the schedule")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			sc, err := find(cx, args[0])
+			if err != nil {
+				return err
+			}
+			cx.fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "name":
+					sc.Name = patch.Name
+				case "cron":
+					sc.Cron = patch.Cron
+				case "tz":
+					sc.Timezone = patch.Timezone
+				case "jitter":
+					sc.JitterSeconds = patch.JitterSeconds
+				case "action":
+					sc.Action = patch.Action
+				case "target":
+					sc.Target = patch.Target
+				case "vm":
+					sc.VMPattern = patch.VMPattern
+				case "tags":
+					sc.HostTags = patch.HostTags
+				case "missed":
+					sc.Missed = patch.Missed
+				}
+			})
+			if err := apply(cx, sc, *params); err != nil {
+				return err
+			}
+			if err := cx.client.ScheduleUpdate(cx.ctx, *sc); err != nil {
+				return err
+			}
+			return cx.done("schedule %s updated", sc.Name)
+		}},
+		{name: "schedules enable", args: "<schedule>", short: "Enable a schedule (the next run is computed from now)", run: setEnabled(true)},
+		{name: "schedules disable", args: "<schedule>", short: "Disable a schedule", run: setEnabled(false)},
+		{name: "schedules delete", args: "<schedule>", short: "Delete a schedule and its run history", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			sc, err := find(cx, args[0])
+			if err != nil {
+				return err
+			}
+			if err := cx.client.ScheduleDelete(cx.ctx, client.IDParams{ID: sc.ID}); err != nil {
+				return err
+			}
+			return cx.done("schedule %s deleted", sc.Name)
+		}},
+		{name: "schedules run", args: "<schedule>", short: "Run a schedule now and wait for it to finish", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			sc, err := find(cx, args[0])
+			if err != nil {
+				return err
+			}
+			run, err := cx.client.ScheduleRun(cx.ctx, client.IDParams{ID: sc.ID})
+			if err != nil {
+				return err
+			}
+			return showRun(cx, run)
+		}},
+		{name: "schedules runs", args: "<schedule>", short: "Show a schedule's run history", run: func(cx *ctlContext) error {
+			limit := cx.fs.Int("limit", 20, "number of runs")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			sc, err := find(cx, args[0])
+			if err != nil {
+				return err
+			}
+			runs, err := cx.client.ScheduleRuns(cx.ctx, client.ScheduleRunsRequest{ID: sc.ID, Limit: *limit})
+			if err != nil {
+				return err
+			}
+			return cx.show(runs, func(t *table) {
+				t.row("ID", "SCHEDULED", "STARTED", "STATE", "MISSED", "TARGETS", "MESSAGE")
+				for _, r := range runs {
+					t.row(r.ID, r.ScheduledFor, r.StartedAt, r.State, r.Missed, len(r.Results), r.Message)
+				}
+			})
+		}},
+		{name: "schedules preview", args: "<cron>", short: "Show the next times a cron expression fires (--tz, --count)", run: func(cx *ctlContext) error {
+			tz := cx.fs.String("tz", "", "IANA time zone (default server local time)")
+			count := cx.fs.Int("count", 5, "number of times to show")
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			times, err := cx.client.SchedulePreview(cx.ctx, client.SchedulePreviewRequest{Cron: args[0], Timezone: *tz, Count: *count})
+			if err != nil {
+				return err
+			}
+			return cx.show(times, func(t *table) {
+				for _, s := range times {
+					t.row(s)
+				}
+			})
+		}},
+	}
+}
+
+// === images / audit / jobs ===
+
+func ctlMiscCommands() []*ctlCommand {
+	return []*ctlCommand{
+		{name: "images list", short: "List OS templates (--host to filter)", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			var id string
+			if cx.host != "" {
+				var err error
+				if id, err = cx.hostID(); err != nil {
+					return err
+				}
+			}
+			images, err := cx.client.ImageList(cx.ctx, client.HostParams{HostID: id})
+			if err != nil {
+				return err
+			}
+			return cx.show(images, func(t *table) {
+				t.row("ID", "NAME", "OS VARIANT", "PATH")
+				for _, img := range images {
+					t.row(img.ID, img.Name, img.OSVariant, img.BasePath)
+				}
+			})
+		}},
+		{name: "audit list", short: "Show the audit log (--host to filter)", run: func(cx *ctlContext) error {
+			limit := cx.fs.Int("limit", 50, "number of records")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			var records []client.AuditRecord
+			var err error
+			if cx.host != "" {
+				var id string
+				if id, err = cx.hostID(); err != nil {
+					return err
+				}
+				records, err = cx.client.AuditList(cx.ctx, client.AuditListRequest{HostID: id, Limit: *limit})
+			} else {
+				records, err = cx.client.AuditListAll(cx.ctx, client.AuditListAllRequest{Limit: *limit})
+			}
+			if err != nil {
+				return err
+			}
+			return cx.show(records, func(t *table) {
+				t.row("TIME", "ACTOR", "ACTION", "VM", "DETAIL")
+				for _, r := range records {
+					t.row(r.Timestamp, r.Actor, r.Action, r.VMName, r.Detail)
+				}
+			})
+		}},
+		{name: "jobs list", short: "List background jobs", run: func(cx *ctlContext) error {
+			state := cx.fs.String("state", "", "filter by state")
+			limit := cx.fs.Int("limit", 50, "number of jobs")
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			var id string
+			if cx.host != "" {
+				var err error
+				if id, err = cx.hostID(); err != nil {
+					return err
+				}
+			}
+			jobs, err := cx.client.JobList(cx.ctx, client.JobListRequest{HostID: id, State: *state, Limit: *limit})
+			if err != nil {
+				return err
+			}
+			return cx.show(jobs, func(t *table) {
+				t.row("ID", "TYPE", "VM", "STATE", "PROGRESS", "CREATED")
+				for _, j := range jobs {
+					t.row(j.ID, j.Type, j.VMName, j.State, fmt.Sprintf("%d%%", j.Progress), j.CreatedAt)
+				}
+			})
+		}},
+		{name: "jobs get", args: "<job>", short: "Show a job (--wait to wait for it)", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			job, err := cx.client.JobGet(cx.ctx, client.IDParams{ID: args[0]})
+			if err != nil {
+				return err
+			}
+			return cx.showJob(job)
+		}},
+		{name: "jobs cancel", args: "<job>", short: "Cancel a running job", run: func(cx *ctlContext) error {
+			args, err := cx.parse(1)
+			if err != nil {
+				return err
+			}
+			if err := cx.client.JobCancel(cx.ctx, client.IDParams{ID: args[0]}); err != nil {
+				return err
+			}
+			return cx.done("job %s canceled", args[0])
+		}},
+		{name: "whoami", short: "Show the authenticated user", run: func(cx *ctlContext) error {
+			if _, err := cx.parse(0); err != nil {
+				return err
+			}
+			p, err := cx.client.AuthWhoami(cx.ctx)
+			if err != nil {
+				return err
+			}
+			return cx.show(p, func(t *table) {
+				t.pairs("Name", p.Name, "Role", p.Role,
+					"Host tags", strings.Join(p.HostTags, ","), "VM patterns", strings.Join(p.VMPatterns, ","))
+			})
+		}},
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// table ctl 的表格输出
+type table struct {
+	w *tabwriter.Writer
+}
+
+func newTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+}
+
+// row 输出一行，空值显示为 -
+func (t *table) row(cells ...interface{}) {
+	parts := make([]string, len(cells))
+	for i, c := range cells {
+		s := strings.ReplaceAll(fmt.Sprint(c), "\t", " ")
+		if s == "" {
+			s = "-"
+		}
+		parts[i] = s
+	}
+	fmt.Fprintln(t.w, strings.Join(parts, "\t"))
+}
+
+// pairs 以 "键: 值" 形式输出单个对象，参数为键值交替
+func (t *table) pairs(kv ...interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		t.row(fmt.Sprint(kv[i])+":", kv[i+1])
+	}
+}
+
+// show 按 --output 输出结果；table 格式由 render 绘制，render 为 nil 时字符串原样输出、其它结果按 YAML 输出
+func (cx *ctlContext) show(v interface{}, render func(t *table)) error {
+	// 空列表输出 [] 而不是 null
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		v = []struct{}{}
+	}
+	switch cx.output {
+	case "json":
+		enc := json.NewEncoder(cx.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		return writeYAML(cx.out, v)
+	}
+
+	if render == nil {
+		if s, ok := v.(string); ok {
+			_, err := fmt.Fprintln(cx.out, strings.TrimRight(s, "\n"))
+			return err
+		}
+		return writeYAML(cx.out, v)
+	}
+	t := &table{w: newTable(cx.out)}
+	render(t)
+	return t.w.Flush()
+}
+
+// writeYAML 以 JSON 字段名和字段顺序输出 YAML
+// 先编码为 JSON 再解析为 yaml.Node（JSON 是 YAML 的子集），并去掉流式风格
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// humanBytes 字节数转可读格式
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// humanRate 格式化每秒字节数
+func humanRate(bps float64) string {
+	if bps < 0 {
+		bps = 0
+	}
+	return humanBytes(uint64(bps)) + "/s"
+}
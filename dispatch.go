@@ -1,961 +1,1239 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-
+	"reflect"
+	"strings"
+
+	"vmcat/internal/api"
+	"vmcat/internal/inventory"
+	"vmcat/internal/monitor"
+	"vmcat/internal/placement"
+	"vmcat/internal/spec"
+	internalssh "vmcat/internal/ssh"
 	"vmcat/internal/store"
 	"vmcat/internal/vm"
 )
 
-// dispatch 将 API action 路由到对应的 App 方法
-func (a *App) dispatch(action string, data json.RawMessage) (interface{}, error) {
-	switch action {
+// actionDef API action 定义
+// 同一张表驱动 /v1/api.json 分发、REST 路由和角色检查，新增 action 只需在 actions 中登记
+type actionDef struct {
+	Name   string
+	Role   string       // 所需最低角色
+	Method string       // REST 方法，为空则仅可通过 /v1/api.json 调用
+	Path   string       // REST 路径（相对 /v1），路径参数名即参数的 json 字段名
+	Params reflect.Type // 参数类型，REST 据此转换路径和查询参数
+	Result reflect.Type // 结果类型，nil 表示无结果
+	call   func(a *App, data json.RawMessage) (interface{}, error)
+}
+
+// 常用参数结构
+type (
+	noParams struct{}
+	IDParams struct {
+		ID string `json:"id"`
+	}
+	HostParams struct {
+		HostID string `json:"hostId"`
+	}
+	VMParams struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+	}
+)
+
+// anyResult 结果类型不固定的 action（按参数返回不同结构）
+// 处理函数返回 interface{} 表示无结果（REST 返回 204）
+type anyResult interface{}
 
+// act 登记 action，route 形如 "POST /hosts/{hostId}/vms/{vmName}:start"，为空表示不提供 REST 路由
+// 参数和结果类型由处理函数签名推导，用于 REST 参数转换和 OpenAPI 文档
+func act[P, R any](name, role, route string, fn func(a *App, p P) (R, error)) actionDef {
+	def := actionDef{Name: name, Role: role, Params: reflect.TypeOf((*P)(nil)).Elem()}
+	if t := reflect.TypeOf((*R)(nil)).Elem(); t.Kind() != reflect.Interface || t.Name() != "" {
+		def.Result = t
+	}
+	if route != "" {
+		def.Method, def.Path, _ = strings.Cut(route, " ")
+	}
+	def.call = func(a *App, data json.RawMessage) (interface{}, error) {
+		var p P
+		if _, none := any(p).(noParams); !none {
+			if err := json.Unmarshal(data, &p); err != nil {
+				return nil, fmt.Errorf("%w: %v", api.ErrInvalidParams, err)
+			}
+		}
+		result, err := fn(a, p)
+		if err != nil {
+			return nil, err
+		}
+		// 空指针结果（如同步执行时的 *store.Job）视为无结果
+		if v := reflect.ValueOf(result); v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, nil
+		}
+		return result, nil
+	}
+	return def
+}
+
+// actions API action 表
+var actions = []actionDef{
 	// === 宿主机管理 ===
 
-	case "host.list":
+	act("host.list", api.RoleViewer, "GET /hosts", func(a *App, _ noParams) ([]store.Host, error) {
 		return a.HostList()
+	}),
 
-	case "host.add":
-		var p store.Host
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.add", api.RoleAdmin, "POST /hosts", func(a *App, p store.Host) (interface{}, error) {
 		return nil, a.HostAdd(p)
+	}),
 
-	case "host.update":
-		var p store.Host
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.update", api.RoleAdmin, "PUT /hosts/{id}", func(a *App, p store.Host) (interface{}, error) {
 		return nil, a.HostUpdate(p)
+	}),
 
-	case "host.delete":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.delete", api.RoleAdmin, "DELETE /hosts/{id}", func(a *App, p IDParams) (interface{}, error) {
 		return nil, a.HostDelete(p.ID)
+	}),
 
-	case "host.connect":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.connect", api.RoleOperator, "POST /hosts/{id}:connect", func(a *App, p IDParams) (interface{}, error) {
 		return nil, a.HostConnect(p.ID)
+	}),
 
-	case "host.disconnect":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.disconnect", api.RoleOperator, "POST /hosts/{id}:disconnect", func(a *App, p IDParams) (interface{}, error) {
 		a.HostDisconnect(p.ID)
 		return nil, nil
+	}),
 
-	case "host.test":
-		var p store.Host
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.test", api.RoleAdmin, "POST /hosts:test", func(a *App, p store.Host) (string, error) {
 		return a.HostTest(p)
+	}),
 
-	case "host.resetHostKey":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.resetHostKey", api.RoleAdmin, "POST /hosts/{id}:resetHostKey", func(a *App, p IDParams) (interface{}, error) {
 		return nil, a.HostResetHostKey(p.ID)
+	}),
 
-	case "host.getFingerprint":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.getFingerprint", api.RoleViewer, "GET /hosts/{id}/fingerprint", func(a *App, p IDParams) (string, error) {
 		return a.HostGetFingerprint(p.ID)
+	}),
 
-	case "host.isConnected":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.isConnected", api.RoleViewer, "GET /hosts/{id}/connected", func(a *App, p IDParams) (bool, error) {
 		return a.HostIsConnected(p.ID), nil
+	}),
 
-	case "host.resourceStats":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
+	act("host.health", api.RoleViewer, "GET /hosts/{id}/health", func(a *App, p IDParams) (anyResult, error) {
+		if p.ID == "" {
+			return a.HostHealthList(), nil
 		}
+		return a.HostHealth(p.ID), nil
+	}),
+
+	act("host.queueStats", api.RoleViewer, "GET /hosts:queueStats", func(a *App, _ noParams) ([]internalssh.QueueStats, error) {
+		return a.HostQueueStats(), nil
+	}),
+
+	act("host.resourceStats", api.RoleViewer, "GET /hosts/{hostId}/resources", func(a *App, p HostParams) (*monitor.HostStats, error) {
 		return a.HostResourceStats(p.HostID)
+	}),
 
-	case "host.exportJSON":
+	act("host.exportJSON", api.RoleAdmin, "GET /hosts:export", func(a *App, _ noParams) (string, error) {
 		return a.HostExportJSON()
+	}),
 
-	case "host.importJSON":
-		var p struct {
-			JSON string `json:"json"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.importJSON", api.RoleAdmin, "POST /hosts:import", func(a *App, p struct {
+		JSON string `json:"json"`
+	}) (int, error) {
 		return a.HostImportJSON(p.JSON)
+	}),
 
-	case "host.checkTools":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.checkTools", api.RoleViewer, "GET /hosts/{id}/tools", func(a *App, p IDParams) (map[string]string, error) {
 		return a.HostCheckTools(p.ID)
-
-	case "host.detectDistro":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	act("host.inventory", api.RoleViewer, "GET /hosts/{id}/inventory", func(a *App, p struct {
+		ID      string `json:"id"`
+		Refresh bool   `json:"refresh"` // 重新采集，否则优先返回缓存
+	}) (*monitor.Inventory, error) {
+		return a.HostInventory(p.ID, p.Refresh)
+	}),
+
+	act("host.inventoryChanges", api.RoleViewer, "GET /hosts/{id}/inventory/changes", func(a *App, p struct {
+		ID    string `json:"id"`
+		Limit int    `json:"limit"`
+	}) ([]store.HostInventoryChange, error) {
+		return a.HostInventoryChanges(p.ID, p.Limit)
+	}),
+
+	act("host.detectDistro", api.RoleViewer, "GET /hosts/{id}/distro", func(a *App, p IDParams) (string, error) {
 		return a.HostDetectDistro(p.ID)
+	}),
 
-	case "host.runScript":
-		var p struct {
-			HostID string `json:"hostId"`
-			Script string `json:"script"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.runScript", api.RoleAdmin, "POST /hosts/{hostId}:runScript", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		Script string `json:"script"`
+	}) (string, error) {
 		return a.HostRunScript(p.HostID, p.Script)
+	}),
 
-	case "host.imageScan":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.imageScan", api.RoleViewer, "GET /hosts/{hostId}/image-files", func(a *App, p HostParams) ([]HostImageFile, error) {
 		return a.HostImageScan(p.HostID)
+	}),
 
-	case "host.imageDelete":
-		var p struct {
-			HostID string `json:"hostId"`
-			Path   string `json:"path"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.imageDelete", api.RoleAdmin, "DELETE /hosts/{hostId}/image-files", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		Path   string `json:"path"`
+	}) (interface{}, error) {
 		return nil, a.HostImageDelete(p.HostID, p.Path)
+	}),
 
-	case "host.statsHistory":
-		var p struct {
-			HostID string `json:"hostId"`
-			Hours  int    `json:"hours"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("host.statsHistory", api.RoleViewer, "GET /hosts/{hostId}/stats/history", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		Hours  int    `json:"hours"`
+	}) ([]store.HostStatsRecord, error) {
 		return a.HostStatsHistory(p.HostID, p.Hours)
+	}),
 
 	// === VM 管理 ===
 
-	case "vm.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.list", api.RoleViewer, "GET /hosts/{hostId}/vms", func(a *App, p HostParams) ([]vm.VM, error) {
 		return a.VMList(p.HostID)
+	}),
 
-	case "vm.get":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.get", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}", func(a *App, p VMParams) (*vm.VMDetail, error) {
 		return a.VMGet(p.HostID, p.VMName)
+	}),
 
-	case "vm.start":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.start", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}:start", func(a *App, p VMParams) (interface{}, error) {
 		return nil, a.VMStart(p.HostID, p.VMName)
+	}),
 
-	case "vm.shutdown":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.shutdown", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}:shutdown", func(a *App, p VMParams) (interface{}, error) {
 		return nil, a.VMShutdown(p.HostID, p.VMName)
+	}),
 
-	case "vm.destroy":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.destroy", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}:destroy", func(a *App, p VMParams) (interface{}, error) {
 		return nil, a.VMDestroy(p.HostID, p.VMName)
+	}),
 
-	case "vm.reboot":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.reboot", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}:reboot", func(a *App, p VMParams) (interface{}, error) {
 		return nil, a.VMReboot(p.HostID, p.VMName)
+	}),
 
-	case "vm.suspend":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.suspend", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}:suspend", func(a *App, p VMParams) (interface{}, error) {
 		return nil, a.VMSuspend(p.HostID, p.VMName)
+	}),
 
-	case "vm.resume":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.resume", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}:resume", func(a *App, p VMParams) (interface{}, error) {
 		return nil, a.VMResume(p.HostID, p.VMName)
+	}),
 
-	case "vm.delete":
-		var p struct {
-			HostID        string `json:"hostId"`
-			VMName        string `json:"vmName"`
-			RemoveStorage bool   `json:"removeStorage"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.delete", api.RoleOperator, "DELETE /hosts/{hostId}/vms/{vmName}", func(a *App, p struct {
+		HostID        string `json:"hostId"`
+		VMName        string `json:"vmName"`
+		RemoveStorage bool   `json:"removeStorage"`
+	}) (interface{}, error) {
 		return nil, a.VMDelete(p.HostID, p.VMName, p.RemoveStorage)
+	}),
 
-	case "vm.rename":
-		var p struct {
-			HostID  string `json:"hostId"`
-			OldName string `json:"oldName"`
-			NewName string `json:"newName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.rename", api.RoleOperator, "POST /hosts/{hostId}/vms/{oldName}:rename", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		OldName string `json:"oldName"`
+		NewName string `json:"newName"`
+	}) (interface{}, error) {
 		return nil, a.VMRename(p.HostID, p.OldName, p.NewName)
+	}),
 
-	case "vm.setVCPUs":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			Count  int    `json:"count"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.setVCPUs", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/vcpus", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Count  int    `json:"count"`
+	}) (interface{}, error) {
 		return nil, a.VMSetVCPUs(p.HostID, p.VMName, p.Count)
+	}),
 
-	case "vm.setMemory":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			SizeMB int    `json:"sizeMB"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.setMemory", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/memory", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		SizeMB int    `json:"sizeMB"`
+	}) (interface{}, error) {
 		return nil, a.VMSetMemory(p.HostID, p.VMName, p.SizeMB)
+	}),
 
-	case "vm.setAutostart":
-		var p struct {
-			HostID  string `json:"hostId"`
-			VMName  string `json:"vmName"`
-			Enabled bool   `json:"enabled"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.setAutostart", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/autostart", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		VMName  string `json:"vmName"`
+		Enabled bool   `json:"enabled"`
+	}) (interface{}, error) {
 		return nil, a.VMSetAutostart(p.HostID, p.VMName, p.Enabled)
+	}),
 
-	case "vm.clone":
-		var p struct {
-			HostID  string `json:"hostId"`
-			SrcName string `json:"srcName"`
-			NewName string `json:"newName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
+	act("vm.clone", api.RoleOperator, "POST /hosts/{hostId}/vms/{srcName}:clone", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		SrcName string `json:"srcName"`
+		NewName string `json:"newName"`
+		Async   bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.VMCloneJob(p.HostID, p.SrcName, p.NewName), nil
 		}
 		return nil, a.VMClone(p.HostID, p.SrcName, p.NewName)
+	}),
 
-	case "vm.getXML":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.getXML", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/xml", func(a *App, p VMParams) (string, error) {
 		return a.VMGetXML(p.HostID, p.VMName)
+	}),
 
-	case "vm.defineXML":
-		var p struct {
-			HostID     string `json:"hostId"`
-			XMLContent string `json:"xmlContent"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.defineXML", api.RoleAdmin, "POST /hosts/{hostId}/vms:define", func(a *App, p struct {
+		HostID     string `json:"hostId"`
+		XMLContent string `json:"xmlContent"`
+	}) (interface{}, error) {
 		return nil, a.VMDefineXML(p.HostID, p.XMLContent)
-
-	case "vm.create":
-		var p struct {
-			HostID string          `json:"hostId"`
-			Params vm.VMCreateParams `json:"params"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
-		return nil, a.VMCreate(p.HostID, p.Params)
-
-	case "vm.stats":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	// hostId 为 auto 时自动选择宿主机，返回调度决策；指定宿主机时返回 null
+	act("vm.create", api.RoleOperator, "POST /hosts/{hostId}/vms", func(a *App, p struct {
+		HostID    string                    `json:"hostId"`
+		Params    vm.VMCreateParams         `json:"params"`
+		Placement placement.ScheduleRequest `json:"placement"`
+	}) (*placement.Decision, error) {
+		return a.VMCreatePlaced(p.HostID, p.Params, p.Placement)
+	}),
+
+	act("vm.stats", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/stats", func(a *App, p VMParams) (*vm.VMResourceStats, error) {
 		return a.VMStats(p.HostID, p.VMName)
-
-	case "vm.createFromTemplate":
-		var p struct {
-			HostID       string `json:"hostId"`
-			VMName       string `json:"vmName"`
-			FlavorID     string `json:"flavorId"`
-			ImageID      string `json:"imageId"`
-			NetType      string `json:"netType"`
-			NetName      string `json:"netName"`
-			RootPassword string `json:"rootPassword"`
-			SSHPubKey    string `json:"sshPubKey"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
-		return nil, a.VMCreateFromTemplate(p.HostID, p.VMName, p.FlavorID, p.ImageID, p.NetType, p.NetName, p.RootPassword, p.SSHPubKey)
-
-	case "vm.migrate":
-		var p struct {
-			SrcHostID string `json:"srcHostId"`
-			VMName    string `json:"vmName"`
-			DstHostID string `json:"dstHostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
+	}),
+
+	act("vm.createFromTemplate", api.RoleOperator, "POST /hosts/{hostId}/vms:fromTemplate", func(a *App, p struct {
+		HostID       string                    `json:"hostId"`
+		VMName       string                    `json:"vmName"`
+		FlavorID     string                    `json:"flavorId"`
+		ImageID      string                    `json:"imageId"`
+		NetType      string                    `json:"netType"`
+		NetName      string                    `json:"netName"`
+		RootPassword string                    `json:"rootPassword"`
+		SSHPubKey    string                    `json:"sshPubKey"`
+		Placement    placement.ScheduleRequest `json:"placement"`
+		Async        bool                      `json:"async"`
+	}) (*store.Job, error) {
+		// hostId 为 auto 时自动选择宿主机，调度决策见任务结果的 placement 字段
+		j, err := a.VMCreateFromTemplatePlacedJob(p.HostID, p.VMName, p.FlavorID, p.ImageID, p.NetType, p.NetName, p.RootPassword, p.SSHPubKey, p.Placement)
+		if err != nil || p.Async {
+			return j, err
+		}
+		if err := a.jobs.Wait(j.ID); err != nil {
+			return nil, err
+		}
+		return a.jobs.Get(j.ID)
+	}),
+
+	act("vm.migrate", api.RoleOperator, "POST /hosts/{srcHostId}/vms/{vmName}:migrate", func(a *App, p struct {
+		SrcHostID string `json:"srcHostId"`
+		VMName    string `json:"vmName"`
+		DstHostID string `json:"dstHostId"`
+		Async     bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.VMMigrateJob(p.SrcHostID, p.VMName, p.DstHostID), nil
 		}
 		return nil, a.VMMigrate(p.SrcHostID, p.VMName, p.DstHostID)
+	}),
 
-	case "vm.migrateOffline":
-		var p struct {
-			SrcHostID string `json:"srcHostId"`
-			VMName    string `json:"vmName"`
-			DstHostID string `json:"dstHostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
+	act("vm.migrateOffline", api.RoleOperator, "POST /hosts/{srcHostId}/vms/{vmName}:migrateOffline", func(a *App, p struct {
+		SrcHostID string `json:"srcHostId"`
+		VMName    string `json:"vmName"`
+		DstHostID string `json:"dstHostId"`
+		Async     bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.VMMigrateOfflineJob(p.SrcHostID, p.VMName, p.DstHostID), nil
 		}
 		return nil, a.VMMigrateOffline(p.SrcHostID, p.VMName, p.DstHostID)
+	}),
 
-	case "vm.noteGet":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.noteGet", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/note", func(a *App, p VMParams) (string, error) {
 		return a.VMNoteGet(p.HostID, p.VMName)
+	}),
 
-	case "vm.noteSet":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			Note   string `json:"note"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.noteSet", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/note", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Note   string `json:"note"`
+	}) (interface{}, error) {
 		return nil, a.VMNoteSet(p.HostID, p.VMName, p.Note)
+	}),
 
-	case "vm.statsHistory":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			Hours  int    `json:"hours"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.statsHistory", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/stats/history", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Hours  int    `json:"hours"`
+	}) ([]store.VMStatsRecord, error) {
 		return a.VMStatsHistory(p.HostID, p.VMName, p.Hours)
+	}),
+
+	act("vm.diskHistory", api.RoleViewer, "GET /hosts/{hostId}/stats/disks", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"` // 为空时返回全部 VM
+		Device string `json:"device"` // 为空时返回全部设备
+		Hours  int    `json:"hours"`
+	}) ([]store.VMDiskStatsRecord, error) {
+		return a.VMDiskHistory(p.HostID, p.VMName, p.Device, p.Hours)
+	}),
+
+	act("vm.netHistory", api.RoleViewer, "GET /hosts/{hostId}/stats/interfaces", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"` // 为空时返回全部 VM
+		Device string `json:"device"` // 为空时返回全部网卡
+		Hours  int    `json:"hours"`
+	}) ([]store.VMNetStatsRecord, error) {
+		return a.VMNetHistory(p.HostID, p.VMName, p.Device, p.Hours)
+	}),
 
 	// === 硬件管理 ===
 
-	case "vm.attachDisk":
-		var p struct {
-			HostID string             `json:"hostId"`
-			VMName string             `json:"vmName"`
-			Params vm.DiskAttachParams `json:"params"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.attachDisk", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/disks", func(a *App, p struct {
+		HostID string              `json:"hostId"`
+		VMName string              `json:"vmName"`
+		Params vm.DiskAttachParams `json:"params"`
+	}) (interface{}, error) {
 		return nil, a.VMAttachDisk(p.HostID, p.VMName, p.Params)
+	}),
 
-	case "vm.detachDisk":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			Target string `json:"target"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.detachDisk", api.RoleOperator, "DELETE /hosts/{hostId}/vms/{vmName}/disks/{target}", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Target string `json:"target"`
+	}) (interface{}, error) {
 		return nil, a.VMDetachDisk(p.HostID, p.VMName, p.Target)
+	}),
 
-	case "vm.resizeDisk":
-		var p struct {
-			HostID    string `json:"hostId"`
-			DiskPath  string `json:"diskPath"`
-			NewSizeGB int    `json:"newSizeGB"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.resizeDisk", api.RoleOperator, "POST /hosts/{hostId}/disks:resize", func(a *App, p struct {
+		HostID    string `json:"hostId"`
+		DiskPath  string `json:"diskPath"`
+		NewSizeGB int    `json:"newSizeGB"`
+	}) (interface{}, error) {
 		return nil, a.VMResizeDisk(p.HostID, p.DiskPath, p.NewSizeGB)
+	}),
 
-	case "vm.attachInterface":
-		var p struct {
-			HostID string              `json:"hostId"`
-			VMName string              `json:"vmName"`
-			Params vm.NICAttachParams  `json:"params"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.attachInterface", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/interfaces", func(a *App, p struct {
+		HostID string             `json:"hostId"`
+		VMName string             `json:"vmName"`
+		Params vm.NICAttachParams `json:"params"`
+	}) (interface{}, error) {
 		return nil, a.VMAttachInterface(p.HostID, p.VMName, p.Params)
+	}),
 
-	case "vm.detachInterface":
-		var p struct {
-			HostID  string `json:"hostId"`
-			VMName  string `json:"vmName"`
-			MacAddr string `json:"macAddr"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.detachInterface", api.RoleOperator, "DELETE /hosts/{hostId}/vms/{vmName}/interfaces/{macAddr}", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		VMName  string `json:"vmName"`
+		MacAddr string `json:"macAddr"`
+	}) (interface{}, error) {
 		return nil, a.VMDetachInterface(p.HostID, p.VMName, p.MacAddr)
-
-	case "vm.changeMedia":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			Target string `json:"target"`
-			Source string `json:"source"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	act("vm.changeMedia", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/media/{target}", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Target string `json:"target"`
+		Source string `json:"source"`
+	}) (interface{}, error) {
 		return nil, a.VMChangeMedia(p.HostID, p.VMName, p.Target, p.Source)
+	}),
 
-	case "vm.ejectMedia":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-			Target string `json:"target"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.ejectMedia", api.RoleOperator, "DELETE /hosts/{hostId}/vms/{vmName}/media/{target}", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Target string `json:"target"`
+	}) (interface{}, error) {
 		return nil, a.VMEjectMedia(p.HostID, p.VMName, p.Target)
+	}),
 
-	case "vm.setGraphics":
-		var p struct {
-			HostID  string `json:"hostId"`
-			VMName  string `json:"vmName"`
-			Enabled bool   `json:"enabled"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.setGraphics", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/graphics", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		VMName  string `json:"vmName"`
+		Enabled bool   `json:"enabled"`
+	}) (interface{}, error) {
 		return nil, a.VMSetGraphics(p.HostID, p.VMName, p.Enabled)
+	}),
 
-	case "vm.generateCloudInit":
-		var p struct {
-			HostID     string             `json:"hostId"`
-			OutputPath string             `json:"outputPath"`
-			Config     vm.CloudInitConfig `json:"config"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vm.generateCloudInit", api.RoleOperator, "POST /hosts/{hostId}/cloud-init", func(a *App, p struct {
+		HostID     string             `json:"hostId"`
+		OutputPath string             `json:"outputPath"`
+		Config     vm.CloudInitConfig `json:"config"`
+	}) (interface{}, error) {
 		return nil, a.VMGenerateCloudInit(p.HostID, p.OutputPath, p.Config)
+	}),
 
 	// === 快照管理 ===
 
-	case "snapshot.list":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("snapshot.list", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/snapshots", func(a *App, p VMParams) ([]vm.Snapshot, error) {
 		return a.SnapshotList(p.HostID, p.VMName)
-
-	case "snapshot.create":
-		var p struct {
-			HostID   string `json:"hostId"`
-			VMName   string `json:"vmName"`
-			SnapName string `json:"snapName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
-		return nil, a.SnapshotCreate(p.HostID, p.VMName, p.SnapName)
-
-	case "snapshot.delete":
-		var p struct {
-			HostID   string `json:"hostId"`
-			VMName   string `json:"vmName"`
-			SnapName string `json:"snapName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	// diskOnly 创建外部磁盘快照（overlay 位于实例目录），quiesce 通过 guest agent 冻结文件系统
+	act("snapshot.create", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/snapshots", func(a *App, p struct {
+		HostID      string `json:"hostId"`
+		VMName      string `json:"vmName"`
+		SnapName    string `json:"snapName"`
+		Description string `json:"description"`
+		DiskOnly    bool   `json:"diskOnly"`
+		Quiesce     bool   `json:"quiesce"`
+	}) (interface{}, error) {
+		return nil, a.SnapshotCreateWith(p.HostID, p.VMName, vm.SnapshotCreateParams{
+			Name:        p.SnapName,
+			Description: p.Description,
+			DiskOnly:    p.DiskOnly,
+			Quiesce:     p.Quiesce,
+		})
+	}),
+
+	act("snapshot.delete", api.RoleOperator, "DELETE /hosts/{hostId}/vms/{vmName}/snapshots/{snapName}", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		VMName   string `json:"vmName"`
+		SnapName string `json:"snapName"`
+	}) (interface{}, error) {
 		return nil, a.SnapshotDelete(p.HostID, p.VMName, p.SnapName)
+	}),
 
-	case "snapshot.revert":
-		var p struct {
-			HostID   string `json:"hostId"`
-			VMName   string `json:"vmName"`
-			SnapName string `json:"snapName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("snapshot.revert", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/snapshots/{snapName}:revert", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		VMName   string `json:"vmName"`
+		SnapName string `json:"snapName"`
+	}) (interface{}, error) {
 		return nil, a.SnapshotRevert(p.HostID, p.VMName, p.SnapName)
+	}),
+
+	act("snapshot.tree", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/snapshots:tree", func(a *App, p VMParams) ([]vm.SnapshotNode, error) {
+		return a.SnapshotTree(p.HostID, p.VMName)
+	}),
+
+	// 将外部快照的 overlay 合并回基础镜像，并删除这些快照
+	act("snapshot.commit", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/snapshots:commit", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		Async  bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.SnapshotCommitJob(p.HostID, p.VMName), nil
+		}
+		return nil, a.SnapshotCommit(p.HostID, p.VMName)
+	}),
+
+	// 创建 vmcat-auto-<时间> 快照并按保留策略清理，适合由定时任务执行
+	// 默认为内部快照（仅支持 qcow2 磁盘），raw 磁盘的 VM 使用 diskOnly（需 libvirt 9.0 及以上）
+	act("snapshot.auto", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/snapshots:auto", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		VMName   string `json:"vmName"`
+		DiskOnly bool   `json:"diskOnly"`
+	}) (*SnapshotPruneResult, error) {
+		return a.SnapshotAuto(p.HostID, p.VMName, p.DiskOnly)
+	}),
+
+	act("snapshot.prune", api.RoleOperator, "POST /hosts/{hostId}/vms/{vmName}/snapshots:prune", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		VMName string `json:"vmName"`
+		DryRun bool   `json:"dryRun"`
+	}) (*SnapshotPruneResult, error) {
+		return a.SnapshotPrune(p.HostID, p.VMName, p.DryRun)
+	}),
+
+	act("snapshotPolicy.list", api.RoleViewer, "GET /snapshot-policies", func(a *App, p HostParams) ([]store.SnapshotPolicy, error) {
+		return a.SnapshotPolicyList(p.HostID)
+	}),
+
+	act("snapshotPolicy.set", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/snapshot-policy", func(a *App, p store.SnapshotPolicy) (interface{}, error) {
+		return nil, a.SnapshotPolicySet(p)
+	}),
+
+	act("snapshotPolicy.delete", api.RoleOperator, "DELETE /hosts/{hostId}/vms/{vmName}/snapshot-policy", func(a *App, p VMParams) (interface{}, error) {
+		return nil, a.SnapshotPolicyDelete(p.HostID, p.VMName)
+	}),
 
 	// === 存储管理 ===
 
-	case "pool.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("pool.list", api.RoleViewer, "GET /hosts/{hostId}/pools", func(a *App, p HostParams) ([]vm.StoragePool, error) {
 		return a.PoolList(p.HostID)
+	}),
 
-	case "pool.start":
-		var p struct {
-			HostID   string `json:"hostId"`
-			PoolName string `json:"poolName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("pool.start", api.RoleOperator, "POST /hosts/{hostId}/pools/{poolName}:start", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		PoolName string `json:"poolName"`
+	}) (interface{}, error) {
 		return nil, a.PoolStart(p.HostID, p.PoolName)
+	}),
 
-	case "pool.stop":
-		var p struct {
-			HostID   string `json:"hostId"`
-			PoolName string `json:"poolName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("pool.stop", api.RoleOperator, "POST /hosts/{hostId}/pools/{poolName}:stop", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		PoolName string `json:"poolName"`
+	}) (interface{}, error) {
 		return nil, a.PoolStop(p.HostID, p.PoolName)
+	}),
 
-	case "pool.autostart":
-		var p struct {
-			HostID   string `json:"hostId"`
-			PoolName string `json:"poolName"`
-			Enabled  bool   `json:"enabled"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("pool.autostart", api.RoleOperator, "PUT /hosts/{hostId}/pools/{poolName}/autostart", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		PoolName string `json:"poolName"`
+		Enabled  bool   `json:"enabled"`
+	}) (interface{}, error) {
 		return nil, a.PoolAutostart(p.HostID, p.PoolName, p.Enabled)
+	}),
 
-	case "vol.list":
-		var p struct {
-			HostID   string `json:"hostId"`
-			PoolName string `json:"poolName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vol.list", api.RoleViewer, "GET /hosts/{hostId}/pools/{poolName}/volumes", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		PoolName string `json:"poolName"`
+	}) ([]vm.Volume, error) {
 		return a.VolList(p.HostID, p.PoolName)
-
-	case "vol.create":
-		var p struct {
-			HostID   string `json:"hostId"`
-			PoolName string `json:"poolName"`
-			VolName  string `json:"volName"`
-			SizeGB   int    `json:"sizeGB"`
-			Format   string `json:"format"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	act("vol.create", api.RoleOperator, "POST /hosts/{hostId}/pools/{poolName}/volumes", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		PoolName string `json:"poolName"`
+		VolName  string `json:"volName"`
+		SizeGB   int    `json:"sizeGB"`
+		Format   string `json:"format"`
+	}) (string, error) {
 		return a.CreateVolume(p.HostID, p.PoolName, p.VolName, p.SizeGB, p.Format)
+	}),
 
-	case "vol.delete":
-		var p struct {
-			HostID   string `json:"hostId"`
-			PoolName string `json:"poolName"`
-			VolName  string `json:"volName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("vol.delete", api.RoleOperator, "DELETE /hosts/{hostId}/pools/{poolName}/volumes/{volName}", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		PoolName string `json:"poolName"`
+		VolName  string `json:"volName"`
+	}) (interface{}, error) {
 		return nil, a.DeleteVolume(p.HostID, p.PoolName, p.VolName)
+	}),
 
 	// === 网络管理 ===
 
-	case "network.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("network.list", api.RoleViewer, "GET /hosts/{hostId}/networks", func(a *App, p HostParams) ([]vm.Network, error) {
 		return a.NetworkList(p.HostID)
+	}),
 
-	case "network.start":
-		var p struct {
-			HostID  string `json:"hostId"`
-			NetName string `json:"netName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("network.start", api.RoleOperator, "POST /hosts/{hostId}/networks/{netName}:start", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		NetName string `json:"netName"`
+	}) (interface{}, error) {
 		return nil, a.NetworkStart(p.HostID, p.NetName)
+	}),
 
-	case "network.stop":
-		var p struct {
-			HostID  string `json:"hostId"`
-			NetName string `json:"netName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("network.stop", api.RoleOperator, "POST /hosts/{hostId}/networks/{netName}:stop", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		NetName string `json:"netName"`
+	}) (interface{}, error) {
 		return nil, a.NetworkStop(p.HostID, p.NetName)
+	}),
 
-	case "network.autostart":
-		var p struct {
-			HostID  string `json:"hostId"`
-			NetName string `json:"netName"`
-			Enabled bool   `json:"enabled"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("network.autostart", api.RoleOperator, "PUT /hosts/{hostId}/networks/{netName}/autostart", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		NetName string `json:"netName"`
+		Enabled bool   `json:"enabled"`
+	}) (interface{}, error) {
 		return nil, a.NetworkAutostart(p.HostID, p.NetName, p.Enabled)
+	}),
 
-	case "bridge.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("bridge.list", api.RoleViewer, "GET /hosts/{hostId}/bridges", func(a *App, p HostParams) ([]string, error) {
 		return a.BridgeList(p.HostID)
+	}),
 
 	// === NAT 端口转发 ===
 
-	case "nat.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("nat.list", api.RoleViewer, "GET /hosts/{hostId}/nat-rules", func(a *App, p HostParams) ([]vm.NATRule, error) {
 		return a.NATRuleList(p.HostID)
-
-	case "nat.add":
-		var p struct {
-			HostID   string `json:"hostId"`
-			Proto    string `json:"proto"`
-			HostPort string `json:"hostPort"`
-			VMIP     string `json:"vmIP"`
-			VMPort   string `json:"vmPort"`
-			Comment  string `json:"comment"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	act("nat.add", api.RoleAdmin, "POST /hosts/{hostId}/nat-rules", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		Proto    string `json:"proto"`
+		HostPort string `json:"hostPort"`
+		VMIP     string `json:"vmIP"`
+		VMPort   string `json:"vmPort"`
+		Comment  string `json:"comment"`
+	}) (interface{}, error) {
 		return nil, a.NATRuleAdd(p.HostID, p.Proto, p.HostPort, p.VMIP, p.VMPort, p.Comment)
-
-	case "nat.delete":
-		var p struct {
-			HostID   string `json:"hostId"`
-			Proto    string `json:"proto"`
-			HostPort string `json:"hostPort"`
-			VMIP     string `json:"vmIP"`
-			VMPort   string `json:"vmPort"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	act("nat.delete", api.RoleAdmin, "DELETE /hosts/{hostId}/nat-rules", func(a *App, p struct {
+		HostID   string `json:"hostId"`
+		Proto    string `json:"proto"`
+		HostPort string `json:"hostPort"`
+		VMIP     string `json:"vmIP"`
+		VMPort   string `json:"vmPort"`
+	}) (interface{}, error) {
 		return nil, a.NATRuleDelete(p.HostID, p.Proto, p.HostPort, p.VMIP, p.VMPort)
+	}),
 
 	// === ISO / OS Variant ===
 
-	case "iso.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("iso.list", api.RoleViewer, "GET /hosts/{hostId}/isos", func(a *App, p HostParams) ([]vm.ISOFile, error) {
 		return a.ISOList(p.HostID)
+	}),
 
-	case "osvariant.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("osvariant.list", api.RoleViewer, "GET /hosts/{hostId}/os-variants", func(a *App, p HostParams) ([]string, error) {
 		return a.OSVariantList(p.HostID)
+	}),
 
 	// === 模板管理 ===
 
-	case "flavor.list":
+	act("flavor.list", api.RoleViewer, "GET /flavors", func(a *App, _ noParams) ([]store.Flavor, error) {
 		return a.FlavorList()
+	}),
 
-	case "flavor.add":
-		var p store.Flavor
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("flavor.add", api.RoleAdmin, "POST /flavors", func(a *App, p store.Flavor) (interface{}, error) {
 		return nil, a.FlavorAdd(p)
+	}),
 
-	case "flavor.update":
-		var p store.Flavor
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("flavor.update", api.RoleAdmin, "PUT /flavors/{id}", func(a *App, p store.Flavor) (interface{}, error) {
 		return nil, a.FlavorUpdate(p)
+	}),
 
-	case "flavor.delete":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("flavor.delete", api.RoleAdmin, "DELETE /flavors/{id}", func(a *App, p IDParams) (interface{}, error) {
 		return nil, a.FlavorDelete(p.ID)
+	}),
 
-	case "image.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("image.list", api.RoleViewer, "GET /hosts/{hostId}/images", func(a *App, p HostParams) ([]store.Image, error) {
 		return a.ImageList(p.HostID)
+	}),
 
-	case "image.add":
-		var p struct {
-			HostID string      `json:"hostId"`
-			Image  store.Image `json:"image"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("image.add", api.RoleAdmin, "POST /hosts/{hostId}/images", func(a *App, p struct {
+		HostID string      `json:"hostId"`
+		Image  store.Image `json:"image"`
+	}) (interface{}, error) {
 		return nil, a.ImageAdd(p.HostID, p.Image)
+	}),
 
-	case "image.update":
-		var p store.Image
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("image.update", api.RoleAdmin, "PUT /images/{id}", func(a *App, p store.Image) (interface{}, error) {
 		return nil, a.ImageUpdate(p)
+	}),
 
-	case "image.delete":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("image.delete", api.RoleAdmin, "DELETE /images/{id}", func(a *App, p IDParams) (interface{}, error) {
 		return nil, a.ImageDelete(p.ID)
-
-	case "image.import":
-		var p struct {
-			HostID    string `json:"hostId"`
-			URL       string `json:"url"`
-			DestPath  string `json:"destPath"`
-			Name      string `json:"name"`
-			OSVariant string `json:"osVariant"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	}),
+
+	act("image.import", api.RoleOperator, "POST /hosts/{hostId}/images:import", func(a *App, p struct {
+		HostID    string `json:"hostId"`
+		URL       string `json:"url"`
+		DestPath  string `json:"destPath"`
+		Name      string `json:"name"`
+		OSVariant string `json:"osVariant"`
+	}) (string, error) {
 		return a.ImageImport(p.HostID, p.URL, p.DestPath, p.Name, p.OSVariant)
+	}),
 
-	case "image.upload":
+	act("image.upload", api.RoleAdmin, "", func(a *App, _ noParams) (interface{}, error) {
 		// 远程模式暂不支持本地文件上传
 		return nil, fmt.Errorf("image.upload is not supported in remote mode")
+	}),
 
-	case "image.importStatus":
+	act("image.importStatus", api.RoleViewer, "GET /images:importStatus", func(a *App, _ noParams) ([]importTask, error) {
 		return a.ImageImportStatus(), nil
+	}),
 
 	// === 镜像源管理 ===
 
-	case "imageSource.list":
+	act("imageSource.list", api.RoleViewer, "GET /image-sources", func(a *App, _ noParams) ([]store.ImageSource, error) {
 		return a.ImageSourceList()
+	}),
 
-	case "imageSource.add":
-		var p store.ImageSource
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("imageSource.add", api.RoleAdmin, "POST /image-sources", func(a *App, p store.ImageSource) (interface{}, error) {
 		return nil, a.ImageSourceAdd(p)
+	}),
 
-	case "imageSource.update":
-		var p store.ImageSource
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("imageSource.update", api.RoleAdmin, "PUT /image-sources/{id}", func(a *App, p store.ImageSource) (interface{}, error) {
 		return nil, a.ImageSourceUpdate(p)
+	}),
 
-	case "imageSource.delete":
-		var p struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("imageSource.delete", api.RoleAdmin, "DELETE /image-sources/{id}", func(a *App, p IDParams) (interface{}, error) {
 		return nil, a.ImageSourceDelete(p.ID)
+	}),
 
 	// === Instance 管理 ===
 
-	case "instance.list":
-		var p struct {
-			HostID string `json:"hostId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("instance.list", api.RoleViewer, "GET /hosts/{hostId}/instances", func(a *App, p HostParams) ([]store.Instance, error) {
 		return a.InstanceList(p.HostID)
+	}),
 
-	case "instance.byVMName":
-		var p struct {
-			HostID string `json:"hostId"`
-			VMName string `json:"vmName"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("instance.byVMName", api.RoleViewer, "GET /hosts/{hostId}/vms/{vmName}/instance", func(a *App, p VMParams) (*store.Instance, error) {
 		return a.InstanceByVMName(p.HostID, p.VMName)
+	}),
 
-	case "instance.isoList":
-		var p struct {
-			HostID     string `json:"hostId"`
-			InstanceID int    `json:"instanceId"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("instance.isoList", api.RoleViewer, "GET /hosts/{hostId}/instances/{instanceId}/isos", func(a *App, p struct {
+		HostID     string `json:"hostId"`
+		InstanceID int    `json:"instanceId"`
+	}) ([]vm.ISOFile, error) {
 		return a.InstanceISOList(p.HostID, p.InstanceID)
+	}),
 
 	// === 设置 ===
 
-	case "setting.get":
-		var p struct {
-			Key string `json:"key"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("setting.get", api.RoleViewer, "GET /settings/{key}", func(a *App, p struct {
+		Key string `json:"key"`
+	}) (string, error) {
 		return a.SettingGet(p.Key)
+	}),
 
-	case "setting.set":
-		var p struct {
-			Key   string `json:"key"`
-			Value string `json:"value"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("setting.set", api.RoleAdmin, "PUT /settings/{key}", func(a *App, p struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}) (interface{}, error) {
 		return nil, a.SettingSet(p.Key, p.Value)
+	}),
+
+	act("setting.timeouts", api.RoleViewer, "GET /settings:timeouts", func(a *App, _ noParams) (map[string]int, error) {
+		return a.SettingTimeouts(), nil
+	}),
+
+	// === 维护模式 ===
+
+	act("host.maintenanceList", api.RoleViewer, "GET /hosts:maintenance", func(a *App, _ noParams) ([]store.Maintenance, error) {
+		return a.HostMaintenanceList()
+	}),
+
+	act("host.maintenanceStatus", api.RoleViewer, "GET /hosts/{id}/maintenance", func(a *App, p IDParams) (*MaintenanceStatus, error) {
+		return a.HostMaintenanceStatus(p.ID)
+	}),
+
+	act("host.maintenancePlan", api.RoleViewer, "GET /hosts/{id}/maintenance/plan", func(a *App, p IDParams) (*MaintenancePlan, error) {
+		return a.HostMaintenancePlan(p.ID)
+	}),
+
+	act("host.maintenanceEnter", api.RoleAdmin, "POST /hosts/{id}:enterMaintenance", func(a *App, p struct {
+		ID     string `json:"id"`
+		Reason string `json:"reason"`
+		Async  bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.HostMaintenanceEnterJob(p.ID, p.Reason)
+		}
+		return nil, a.HostMaintenanceEnter(p.ID, p.Reason)
+	}),
+
+	act("host.maintenanceExit", api.RoleAdmin, "POST /hosts/{id}:exitMaintenance", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.HostMaintenanceExit(p.ID)
+	}),
+
+	act("host.returnVMs", api.RoleAdmin, "POST /hosts/{id}:returnVMs", func(a *App, p struct {
+		ID    string `json:"id"`
+		Async bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.HostReturnVMsJob(p.ID)
+		}
+		return nil, a.HostReturnVMs(p.ID)
+	}),
+
+	// === 调度 ===
+
+	act("vm.schedule", api.RoleViewer, "POST /vms:schedule", func(a *App, p placement.ScheduleRequest) (*placement.Decision, error) {
+		return a.VMSchedule(p)
+	}),
+
+	act("host.overcommitList", api.RoleViewer, "GET /hosts:overcommit", func(a *App, _ noParams) ([]store.HostOvercommit, error) {
+		return a.HostOvercommitList()
+	}),
+
+	act("host.setOvercommit", api.RoleAdmin, "PUT /hosts/{id}/overcommit", func(a *App, p struct {
+		ID       string  `json:"id"`
+		CPURatio float64 `json:"cpuRatio"`
+		MemRatio float64 `json:"memRatio"`
+	}) (interface{}, error) {
+		return nil, a.HostOvercommitSet(p.ID, p.CPURatio, p.MemRatio)
+	}),
+
+	act("placementGroup.list", api.RoleViewer, "GET /placement-groups", func(a *App, _ noParams) ([]store.PlacementGroup, error) {
+		return a.PlacementGroupList()
+	}),
+
+	act("placementGroup.create", api.RoleOperator, "POST /placement-groups", func(a *App, p struct {
+		Name   string `json:"name"`
+		Policy string `json:"policy"`
+	}) (*store.PlacementGroup, error) {
+		return a.PlacementGroupCreate(p.Name, p.Policy)
+	}),
+
+	act("placementGroup.delete", api.RoleOperator, "DELETE /placement-groups/{name}", func(a *App, p struct {
+		Name string `json:"name"`
+	}) (interface{}, error) {
+		return nil, a.PlacementGroupDelete(p.Name)
+	}),
+
+	act("placementGroup.addVM", api.RoleOperator, "POST /placement-groups/{name}/vms", func(a *App, p struct {
+		Name   string `json:"name"`
+		VMName string `json:"vmName"`
+	}) (interface{}, error) {
+		return nil, a.PlacementGroupAddVM(p.Name, p.VMName)
+	}),
+
+	act("placementGroup.removeVM", api.RoleOperator, "DELETE /placement-groups/{name}/vms/{vmName}", func(a *App, p struct {
+		Name   string `json:"name"`
+		VMName string `json:"vmName"`
+	}) (interface{}, error) {
+		return nil, a.PlacementGroupRemoveVM(p.Name, p.VMName)
+	}),
+
+	// === 高可用 ===
+
+	act("ha.status", api.RoleViewer, "GET /ha", func(a *App, _ noParams) (*HAStatus, error) {
+		return a.HAStatusGet()
+	}),
+
+	act("vm.haList", api.RoleViewer, "GET /ha/vms", func(a *App, _ noParams) ([]store.VMHA, error) {
+		return a.VMHAList()
+	}),
+
+	act("vm.setHA", api.RoleOperator, "PUT /hosts/{hostId}/vms/{vmName}/ha", func(a *App, p struct {
+		HostID  string `json:"hostId"`
+		VMName  string `json:"vmName"`
+		Enabled bool   `json:"enabled"`
+	}) (interface{}, error) {
+		return nil, a.VMHASet(p.HostID, p.VMName, p.Enabled)
+	}),
+
+	act("host.fenceList", api.RoleAdmin, "GET /hosts:fence", func(a *App, _ noParams) ([]store.HostFence, error) {
+		return a.HostFenceList()
+	}),
+
+	act("host.setFence", api.RoleAdmin, "PUT /hosts/{id}/fence", func(a *App, p struct {
+		ID       string `json:"id"`
+		Method   string `json:"method"`
+		Address  string `json:"address"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Script   string `json:"script"`
+	}) (interface{}, error) {
+		return nil, a.HostFenceSet(store.HostFence{
+			HostID:   p.ID,
+			Method:   p.Method,
+			Address:  p.Address,
+			Username: p.Username,
+			Password: p.Password,
+			Script:   p.Script,
+		})
+	}),
+
+	act("host.fenceStatus", api.RoleAdmin, "POST /hosts/{id}:fenceStatus", func(a *App, p IDParams) (string, error) {
+		return a.HostFenceStatus(p.ID)
+	}),
+
+	// === 声明式配置 ===
+
+	act("spec.plan", api.RoleAdmin, "POST /spec:plan", func(a *App, p struct {
+		Spec  string `json:"spec"` // YAML 或 JSON 文本
+		Prune bool   `json:"prune"`
+	}) (*spec.Plan, error) {
+		return a.SpecPlan(p.Spec, p.Prune)
+	}),
+
+	act("spec.apply", api.RoleAdmin, "POST /spec:apply", func(a *App, p struct {
+		Spec  string `json:"spec"`
+		Prune bool   `json:"prune"`
+		Async bool   `json:"async"`
+	}) (*store.Job, error) {
+		if p.Async {
+			return a.SpecApplyJob(p.Spec, p.Prune)
+		}
+		return nil, a.SpecApply(p.Spec, p.Prune)
+	}),
+
+	// === 清单导出 ===
+
+	act("inventory.ansible", api.RoleViewer, "GET /inventory/ansible", func(a *App, p struct {
+		User string `json:"user"` // VM 的 ansible_user
+	}) (inventory.Ansible, error) {
+		return a.InventoryAnsible(p.User)
+	}),
+
+	// === 定时任务 ===
+
+	act("schedule.list", api.RoleViewer, "GET /schedules", func(a *App, _ noParams) ([]store.Schedule, error) {
+		return a.ScheduleList()
+	}),
+
+	act("schedule.get", api.RoleViewer, "GET /schedules/{id}", func(a *App, p IDParams) (*store.Schedule, error) {
+		return a.ScheduleGet(p.ID)
+	}),
+
+	act("schedule.add", api.RoleAdmin, "POST /schedules", func(a *App, p store.Schedule) (*store.Schedule, error) {
+		return a.ScheduleAdd(p)
+	}),
+
+	act("schedule.update", api.RoleAdmin, "PUT /schedules/{id}", func(a *App, p store.Schedule) (interface{}, error) {
+		return nil, a.ScheduleUpdate(p)
+	}),
+
+	act("schedule.delete", api.RoleAdmin, "DELETE /schedules/{id}", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.ScheduleDelete(p.ID)
+	}),
+
+	act("schedule.run", api.RoleAdmin, "POST /schedules/{id}:run", func(a *App, p IDParams) (*store.ScheduleRun, error) {
+		return a.ScheduleRunNow(p.ID)
+	}),
+
+	act("schedule.runs", api.RoleViewer, "GET /schedules/{id}/runs", func(a *App, p struct {
+		ID    string `json:"id"`
+		Limit int    `json:"limit"`
+	}) ([]store.ScheduleRun, error) {
+		return a.ScheduleRunList(p.ID, p.Limit)
+	}),
+
+	act("schedule.preview", api.RoleViewer, "GET /schedules:preview", func(a *App, p struct {
+		Cron     string `json:"cron"`
+		Timezone string `json:"timezone"`
+		Count    int    `json:"count"`
+	}) ([]string, error) {
+		return a.SchedulePreview(p.Cron, p.Timezone, p.Count)
+	}),
+
+	// === 告警 ===
+
+	act("alert.list", api.RoleViewer, "GET /alerts", func(a *App, _ noParams) ([]store.AlertEvent, error) {
+		return a.AlertList()
+	}),
+
+	act("alert.history", api.RoleViewer, "GET /alerts:history", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		RuleID string `json:"ruleId"`
+		State  string `json:"state"`
+		Limit  int    `json:"limit"`
+	}) ([]store.AlertEvent, error) {
+		return a.AlertHistory(p.HostID, p.RuleID, p.State, p.Limit)
+	}),
+
+	act("alertRule.list", api.RoleViewer, "GET /alert-rules", func(a *App, _ noParams) ([]store.AlertRule, error) {
+		return a.AlertRuleList()
+	}),
+
+	act("alertRule.add", api.RoleAdmin, "POST /alert-rules", func(a *App, p store.AlertRule) (*store.AlertRule, error) {
+		return a.AlertRuleAdd(p)
+	}),
+
+	act("alertRule.update", api.RoleAdmin, "PUT /alert-rules/{id}", func(a *App, p store.AlertRule) (interface{}, error) {
+		return nil, a.AlertRuleUpdate(p)
+	}),
+
+	act("alertRule.delete", api.RoleAdmin, "DELETE /alert-rules/{id}", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.AlertRuleDelete(p.ID)
+	}),
+
+	act("alertChannel.list", api.RoleAdmin, "GET /alert-channels", func(a *App, _ noParams) ([]store.AlertChannel, error) {
+		return a.AlertChannelList()
+	}),
+
+	act("alertChannel.add", api.RoleAdmin, "POST /alert-channels", func(a *App, p store.AlertChannel) (*store.AlertChannel, error) {
+		return a.AlertChannelAdd(p)
+	}),
+
+	act("alertChannel.update", api.RoleAdmin, "PUT /alert-channels/{id}", func(a *App, p store.AlertChannel) (interface{}, error) {
+		return nil, a.AlertChannelUpdate(p)
+	}),
+
+	act("alertChannel.delete", api.RoleAdmin, "DELETE /alert-channels/{id}", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.AlertChannelDelete(p.ID)
+	}),
+
+	act("alertChannel.test", api.RoleAdmin, "POST /alert-channels/{id}:test", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.AlertChannelTest(p.ID)
+	}),
+
+	act("alertSilence.list", api.RoleViewer, "GET /alert-silences", func(a *App, p struct {
+		All bool `json:"all"`
+	}) ([]store.AlertSilence, error) {
+		return a.AlertSilenceList(p.All)
+	}),
+
+	act("alertSilence.add", api.RoleOperator, "POST /alert-silences", func(a *App, p struct {
+		store.AlertSilence
+		Duration string `json:"duration"`
+	}) (*store.AlertSilence, error) {
+		return a.AlertSilenceAdd(p.AlertSilence, p.Duration)
+	}),
+
+	act("alertSilence.delete", api.RoleOperator, "DELETE /alert-silences/{id}", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.AlertSilenceDelete(p.ID)
+	}),
 
 	// === 审计日志 ===
 
-	case "audit.list":
-		var p struct {
-			HostID string `json:"hostId"`
-			Limit  int    `json:"limit"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("audit.list", api.RoleAdmin, "GET /hosts/{hostId}/audit", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		Limit  int    `json:"limit"`
+	}) ([]store.AuditRecord, error) {
 		return a.AuditList(p.HostID, p.Limit)
+	}),
 
-	case "audit.listAll":
-		var p struct {
-			Limit int `json:"limit"`
-		}
-		if err := json.Unmarshal(data, &p); err != nil {
-			return nil, fmt.Errorf("invalid params: %w", err)
-		}
+	act("audit.listAll", api.RoleAdmin, "GET /audit", func(a *App, p struct {
+		Limit int `json:"limit"`
+	}) ([]store.AuditRecord, error) {
 		return a.AuditListAll(p.Limit)
+	}),
+
+	// === 后台任务 ===
+
+	act("job.list", api.RoleViewer, "GET /jobs", func(a *App, p struct {
+		HostID string `json:"hostId"`
+		State  string `json:"state"`
+		Limit  int    `json:"limit"`
+	}) ([]store.Job, error) {
+		return a.JobList(p.HostID, p.State, p.Limit)
+	}),
+
+	act("job.get", api.RoleViewer, "GET /jobs/{id}", func(a *App, p IDParams) (*store.Job, error) {
+		return a.JobGet(p.ID)
+	}),
+
+	act("job.cancel", api.RoleOperator, "POST /jobs/{id}:cancel", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.JobCancel(p.ID)
+	}),
+
+	// === 用户与令牌 ===
+
+	act("user.list", api.RoleAdmin, "GET /users", func(a *App, _ noParams) ([]store.User, error) {
+		return a.UserList()
+	}),
+
+	act("user.add", api.RoleAdmin, "POST /users", func(a *App, p store.User) (*store.User, error) {
+		return a.UserAdd(p)
+	}),
+
+	act("user.update", api.RoleAdmin, "PUT /users/{id}", func(a *App, p store.User) (interface{}, error) {
+		return nil, a.UserUpdate(p)
+	}),
+
+	act("user.delete", api.RoleAdmin, "DELETE /users/{id}", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.UserDelete(p.ID)
+	}),
+
+	act("token.list", api.RoleAdmin, "GET /tokens", func(a *App, p struct {
+		UserID string `json:"userId"`
+	}) ([]store.APIToken, error) {
+		return a.TokenList(p.UserID)
+	}),
+
+	act("token.create", api.RoleAdmin, "POST /users/{userId}/tokens", func(a *App, p struct {
+		UserID    string `json:"userId"`
+		Name      string `json:"name"`
+		ExpiresAt string `json:"expiresAt"`
+	}) (*TokenCreateResult, error) {
+		return a.TokenCreate(p.UserID, p.Name, p.ExpiresAt)
+	}),
+
+	act("token.revoke", api.RoleAdmin, "POST /tokens/{id}:revoke", func(a *App, p IDParams) (interface{}, error) {
+		return nil, a.TokenRevoke(p.ID)
+	}),
+
+	act("auth.whoami", api.RoleViewer, "GET /whoami", func(a *App, _ noParams) (*api.Principal, error) {
+		// 启用认证时由 serveAPI 直接返回调用方，到达此处说明未启用认证
+		return &api.Principal{Name: localActor, Role: api.RoleAdmin}, nil
+	}),
 
 	// === 工具 ===
 
-	case "app.version":
+	act("app.version", api.RoleViewer, "GET /version", func(a *App, _ noParams) (string, error) {
 		return a.AppVersion(), nil
+	}),
 
-	case "terminal.port":
+	act("terminal.port", api.RoleViewer, "", func(a *App, _ noParams) (int, error) {
 		return a.TerminalPort(), nil
+	}),
 
-	case "libvirt.setupScripts":
+	act("libvirt.setupScripts", api.RoleViewer, "GET /libvirt/setup-scripts", func(a *App, _ noParams) ([]LibvirtSetupScript, error) {
 		return a.LibvirtSetupScriptList(), nil
+	}),
+
+	// === WebSocket 入口（仅用于权限检查，见 guardWS） ===
+
+	{Name: "terminal.open", Role: api.RoleAdmin},
+	{Name: "vnc.open", Role: api.RoleOperator},
+
+	// === /metrics（见 guardMetrics） ===
+
+	{Name: "metrics.read", Role: api.RoleViewer},
+}
+
+// actionIndex 按名称索引 action，在 init 中建立：定时任务按名称执行 action，直接初始化会形成初始化循环
+var actionIndex map[string]*actionDef
+
+func init() {
+	actionIndex = make(map[string]*actionDef, len(actions))
+	for i := range actions {
+		actionIndex[actions[i].Name] = &actions[i]
+	}
+}
+
+// actionParamFields 返回 action 参数结构的 json 字段名（含嵌入结构的字段）
+func actionParamFields(action string) map[string]bool {
+	fields := make(map[string]bool)
+	def, ok := actionIndex[action]
+	if !ok || def.Params == nil {
+		return fields
+	}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Anonymous && name == "" {
+				walk(f.Type)
+				continue
+			}
+			if name != "" && name != "-" {
+				fields[name] = true
+			}
+		}
+	}
+	walk(def.Params)
+	return fields
+}
+
+// dispatch 将 API action 路由到对应的 App 方法，同步执行的远程命令在 ctx 结束时终止
+func (a *App) dispatch(ctx context.Context, action string, data json.RawMessage) (interface{}, error) {
+	def, ok := actionIndex[action]
+	if !ok || def.call == nil {
+		return nil, fmt.Errorf("%w: %s", api.ErrUnknownAction, action)
+	}
+	result, err := def.call(a.withContext(ctx), data)
+	switch {
+	case errors.Is(err, internalssh.ErrUnavailable):
+		err = api.WithKind(api.ErrUnavailable, err)
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, vm.ErrNotFound):
+		err = api.WithKind(api.ErrNotFound, err)
+	}
+	return result, err
+}
+
+// invalidParams 参数校验失败，REST 接口返回 400
+func invalidParams(format string, args ...interface{}) error {
+	return api.WithKind(api.ErrInvalidParams, fmt.Errorf(format, args...))
+}
+
+// notFound 包装 store 查询错误：记录不存在时标记为 ErrNotFound（REST 接口返回 404），其他错误原样带上
+func notFound(what string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.WithKind(api.ErrNotFound, fmt.Errorf("%s not found", what))
+	}
+	return fmt.Errorf("get %s: %w", what, err)
+}
 
-	default:
-		return nil, fmt.Errorf("unknown action: %s", action)
+// apiRoutes 由 action 表生成路由，用于 REST 分发和 OpenAPI 文档
+// 未提供 REST 路由的 action 仅出现在文档的 x-actions 中
+func apiRoutes() []api.Route {
+	var routes []api.Route
+	for _, def := range actions {
+		if def.call == nil {
+			continue
+		}
+		routes = append(routes, api.Route{
+			Method: def.Method,
+			Path:   def.Path,
+			Action: def.Name,
+			Role:   def.Role,
+			Params: def.Params,
+			Result: def.Result,
+		})
 	}
+	return routes
 }
@@ -2,6 +2,7 @@
 // 所有组件通过此模块调用后端,而非直接调用 Wails 绑定
 
 import * as WailsAPI from '../../wailsjs/go/main/App'
+import { EventsOn } from '../../wailsjs/runtime/runtime'
 import { RemoteClient } from './remote'
 import type { RemoteConfig } from './types'
 
@@ -54,6 +55,19 @@ export async function getVNCWSURL(params: Record<string, string>): Promise<strin
   return `ws://127.0.0.1:${port}/ws/vnc?${qs.toString()}`
 }
 
+// --- 事件订阅 ---
+
+/**
+ * 订阅后端事件，本地模式走 Wails Events，远程模式走 /v1/events
+ * @returns 取消订阅函数
+ */
+export function onEvent(topic: string, callback: (data: any) => void): () => void {
+  if (remoteClient) {
+    return remoteClient.subscribe([topic], (_, data) => callback(data))
+  }
+  return EventsOn(topic, callback)
+}
+
 // --- 应用 API ---
 
 export async function AppVersion(): Promise<string> {
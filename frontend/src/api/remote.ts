@@ -62,4 +62,36 @@ export class RemoteClient {
     }
     return `${base}${path}?${qs.toString()}`
   }
+
+  /**
+   * 订阅服务端事件流 (SSE)
+   * @param topics 主题过滤 例: ["image:import:*"]，为空则订阅全部
+   * @param onEvent 事件回调
+   * @returns 取消订阅函数
+   */
+  subscribe(topics: string[], onEvent: (topic: string, data: any) => void): () => void {
+    const qs = new URLSearchParams()
+    if (topics.length > 0) {
+      qs.set('topics', topics.join(','))
+    }
+    if (this.config.token) {
+      qs.set('token', this.config.token)
+    }
+    const source = new EventSource(`${this.config.baseURL}/v1/events?${qs.toString()}`)
+    const handler = (e: MessageEvent) => {
+      try {
+        const ev = JSON.parse(e.data)
+        onEvent(ev.topic, ev.data)
+      } catch {
+        // 忽略无法解析的消息
+      }
+    }
+    for (const topic of topics) {
+      source.addEventListener(topic, handler as EventListener)
+    }
+    if (topics.length === 0) {
+      source.onmessage = handler
+    }
+    return () => source.close()
+  }
 }
@@ -127,6 +127,8 @@ export default {
     proxyPlaceholder: 'Direct if empty (e.g. 127.0.0.1:1080)',
     tags: 'Tags',
     tagsPlaceholder: 'Comma separated (e.g. prod,beijing)',
+    backend: 'Management Backend',
+    backendHint: 'libvirt RPC talks to libvirtd over the forwarded socket; requires access to libvirt-sock',
     testConnect: 'Test Connection',
     testSuccess: 'Connected: {info}',
   },
@@ -698,11 +700,12 @@ export default {
     records: '{count} records',
     noLogs: 'No audit logs',
     time: 'Time',
+    actor: 'User',
     host: 'Host',
     vmName: 'VM',
     action: 'Action',
     detail: 'Detail',
-    csvHeader: 'Time,Host,VM,Action,Detail',
+    csvHeader: 'Time,User,Host,VM,Action,Detail',
   },
   settings: {
     title: 'Settings',
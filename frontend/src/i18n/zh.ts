@@ -127,6 +127,8 @@ export default {
     proxyPlaceholder: '留空则直连 (例: 127.0.0.1:1080)',
     tags: '标签',
     tagsPlaceholder: '逗号分隔 (例: 生产,北京)',
+    backend: '管理后端',
+    backendHint: 'libvirt RPC 通过转发的 libvirt-sock 直接与 libvirtd 通信，需要有该 socket 的访问权限',
     testConnect: '测试连接',
     testSuccess: '连接成功: {info}',
   },
@@ -698,11 +700,12 @@ export default {
     records: '{count} 条记录',
     noLogs: '暂无审计日志',
     time: '时间',
+    actor: '操作者',
     host: '宿主机',
     vmName: 'VM',
     action: '操作',
     detail: '详情',
-    csvHeader: '时间,宿主机,VM,操作,详情',
+    csvHeader: '时间,操作者,宿主机,VM,操作,详情',
   },
   settings: {
     title: '设置',
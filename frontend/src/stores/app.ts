@@ -13,6 +13,7 @@ export interface Host {
   hostKey: string
   proxyAddr: string
   tags: string
+  backend: string
   sortOrder: number
   createdAt: string
   updatedAt: string
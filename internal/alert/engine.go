@@ -0,0 +1,544 @@
+// Package alert 阈值告警
+//
+// 规则按 HistoryCollector 每轮采集结果（默认 30 秒一轮）评估，条件持续满足 ForSeconds 后触发，
+// 回落越过回差后恢复；触发和恢复都记入告警记录并经通知渠道发送（静默期内只记录不发送）
+package alert
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vmcat/internal/event"
+	"vmcat/internal/monitor"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// 指标
+const (
+	MetricHostCPU         = "host.cpu"         // 宿主机 CPU 使用率 (%)
+	MetricHostMemory      = "host.memory"      // 宿主机内存使用率 (%)
+	MetricHostDisk        = "host.disk"        // 宿主机磁盘使用率 (%)
+	MetricHostLoad        = "host.load1"       // 宿主机 1 分钟负载
+	MetricHostUnreachable = "host.unreachable" // 宿主机 SSH 连接断开（down / reconnecting）
+	MetricVMCPU           = "vm.cpu"           // VM CPU 使用率 (%)
+	MetricVMMemory        = "vm.memory"        // VM 驻留内存占分配内存的比例 (%)
+	MetricVMCrashed       = "vm.crashed"       // VM 崩溃
+)
+
+// 告警状态
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// metricInfo 指标说明，flag 类指标只有 0/1 两个值，忽略比较符和阈值
+var metricInfo = map[string]struct {
+	label, unit string
+	vm, flag    bool
+}{
+	MetricHostCPU:         {label: "CPU usage", unit: "%"},
+	MetricHostMemory:      {label: "memory usage", unit: "%"},
+	MetricHostDisk:        {label: "disk usage", unit: "%"},
+	MetricHostLoad:        {label: "load average"},
+	MetricHostUnreachable: {label: "unreachable", flag: true},
+	MetricVMCPU:           {label: "CPU usage", unit: "%", vm: true},
+	MetricVMMemory:        {label: "memory usage", unit: "%", vm: true},
+	MetricVMCrashed:       {label: "crashed", vm: true, flag: true},
+}
+
+// cleanupInterval 清理过期告警记录的间隔，historyDays 为已恢复告警的保留天数
+const (
+	cleanupInterval = time.Hour
+	historyDays     = 30
+)
+
+// Engine 告警引擎
+type Engine struct {
+	pool    *internalssh.Pool
+	store   *store.Store
+	emitter event.Emitter
+
+	mu          sync.Mutex
+	senders     map[string]Sender
+	instances   map[string]*instance // ruleID/hostID/vmName -> 评估状态
+	crashed     map[string]bool      // hostID/vmName -> 上一轮以来收到过 crashed 事件
+	silences    []store.AlertSilence // 本轮生效的静默
+	lastCleanup time.Time
+}
+
+// instance 单条规则在单个对象（宿主机或 VM）上的评估状态
+type instance struct {
+	ruleID, hostID, vmName string
+	metric                 string
+	channels               string
+	pendingSince           time.Time         // 条件开始满足的时间，零值表示未满足
+	ev                     *store.AlertEvent // 触发中的告警，nil 表示未触发
+}
+
+// NewEngine 创建告警引擎，恢复上次运行时未恢复的告警
+func NewEngine(pool *internalssh.Pool, s *store.Store) *Engine {
+	e := &Engine{
+		pool:      pool,
+		store:     s,
+		emitter:   &event.NoopEmitter{},
+		senders:   map[string]Sender{ChannelWebhook: sendWebhook, ChannelSMTP: sendSMTP},
+		instances: make(map[string]*instance),
+		crashed:   make(map[string]bool),
+	}
+	e.restore()
+	return e
+}
+
+// SetEmitter 设置事件发射器（告警触发和恢复推送 alert:event）
+func (e *Engine) SetEmitter(em event.Emitter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emitter = em
+}
+
+// SetSender 设置通知渠道类型的实现，如桌面模式注册 desktop
+func (e *Engine) SetSender(channelType string, fn Sender) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.senders[channelType] = fn
+}
+
+// Emit 实现 event.Emitter，接收 vm:event 中的 crashed 事件
+// 默认配置下崩溃的 VM 会立即变为 shut off，仅靠周期采集无法发现
+func (e *Engine) Emit(topic string, data ...interface{}) {
+	if topic != "vm:event" || len(data) == 0 {
+		return
+	}
+	ev, ok := data[0].(vm.VMEvent)
+	if !ok || ev.Event != "crashed" {
+		return
+	}
+	e.mu.Lock()
+	e.crashed[ev.HostID+"/"+ev.Name] = true
+	e.mu.Unlock()
+}
+
+// restore 加载未恢复的告警，使其在条件解除后能正常恢复
+func (e *Engine) restore() {
+	firing, err := e.store.AlertEventList("", "", StateFiring, 10000)
+	if err != nil {
+		log.Printf("alert restore: %v", err)
+		return
+	}
+	channels := make(map[string]string)
+	if rules, err := e.store.AlertRuleList(); err == nil {
+		for _, r := range rules {
+			channels[r.ID] = r.Channels
+		}
+	}
+	for i := range firing {
+		ev := firing[i]
+		e.instances[instanceKey(ev.RuleID, ev.HostID, ev.VMName)] = &instance{
+			ruleID: ev.RuleID, hostID: ev.HostID, vmName: ev.VMName,
+			metric: ev.Metric, channels: channels[ev.RuleID], ev: &ev,
+		}
+	}
+}
+
+func instanceKey(ruleID, hostID, vmName string) string {
+	return ruleID + "/" + hostID + "/" + vmName
+}
+
+// Evaluate 按一轮采集结果评估全部规则（作为 HistoryCollector.OnCollect 回调）
+func (e *Engine) Evaluate(samples map[string]*monitor.Sample) {
+	rules, err := e.store.AlertRuleList()
+	if err != nil {
+		log.Printf("alert rules: %v", err)
+		return
+	}
+	hosts, err := e.store.HostList()
+	if err != nil {
+		log.Printf("alert hosts: %v", err)
+		return
+	}
+	silences, err := e.store.AlertSilenceList(true)
+	if err != nil {
+		log.Printf("alert silences: %v", err)
+	}
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	crashed := e.crashed
+	e.crashed = make(map[string]bool)
+	e.silences = silences
+
+	active := make(map[string]*store.AlertRule)
+	hostByID := make(map[string]*store.Host, len(hosts))
+	for i := range hosts {
+		hostByID[hosts[i].ID] = &hosts[i]
+	}
+	seen := make(map[string]bool)
+	for i := range rules {
+		r := &rules[i]
+		if !r.Enabled || metricInfo[r.Metric].label == "" {
+			continue
+		}
+		active[r.ID] = r
+		for j := range hosts {
+			h := &hosts[j]
+			if !hostMatches(r, h) {
+				continue
+			}
+			for _, o := range e.observe(r, h, samples[h.ID], crashed) {
+				key := instanceKey(r.ID, h.ID, o.vmName)
+				seen[key] = true
+				e.step(key, r, h, o, now)
+			}
+		}
+	}
+
+	// 本轮未评估到的对象：宿主机未采集到时保持原状态，规则停用、删除或对象已不存在时恢复
+	for key, in := range e.instances {
+		if seen[key] {
+			continue
+		}
+		r, h := active[in.ruleID], hostByID[in.hostID]
+		if r != nil && h != nil && hostMatches(r, h) && !sampled(in.metric, samples[in.hostID]) {
+			continue
+		}
+		if in.ev != nil {
+			reason := "rule removed or disabled"
+			if r != nil {
+				reason = "target no longer exists"
+			}
+			e.resolve(in, in.ev.Value, reason, now)
+		}
+		delete(e.instances, key)
+	}
+
+	if now.Sub(e.lastCleanup) >= cleanupInterval {
+		e.lastCleanup = now
+		if err := e.store.AlertEventCleanup(historyDays); err != nil {
+			log.Printf("alert cleanup: %v", err)
+		}
+	}
+}
+
+// observation 一个对象的当前值
+type observation struct {
+	vmName string
+	value  float64
+}
+
+// observe 取规则在宿主机上的观测值，宿主机本轮未采集到时返回空
+func (e *Engine) observe(r *store.AlertRule, h *store.Host, s *monitor.Sample, crashed map[string]bool) []observation {
+	if r.Metric == MetricHostUnreachable {
+		state := e.pool.Health(h.ID).State
+		return []observation{{value: boolValue(state == internalssh.StateDown || state == internalssh.StateReconnecting)}}
+	}
+	if !sampled(r.Metric, s) {
+		return nil
+	}
+
+	switch r.Metric {
+	case MetricHostCPU:
+		return []observation{{value: s.Host.CPUPercent}}
+	case MetricHostMemory:
+		return []observation{{value: s.Host.MemPercent}}
+	case MetricHostDisk:
+		return []observation{{value: s.Host.DiskPercent}}
+	case MetricHostLoad:
+		load, _ := strconv.ParseFloat(strings.TrimSpace(strings.Split(s.Host.LoadAvg, ",")[0]), 64)
+		return []observation{{value: load}}
+	}
+
+	var obs []observation
+	for _, v := range s.VMs {
+		if !matchAny(r.VMPattern, v.Name) {
+			continue
+		}
+		o := observation{vmName: v.Name}
+		stats := s.VMStats[v.Name]
+		switch r.Metric {
+		case MetricVMCPU:
+			if stats != nil {
+				o.value = stats.CPUPercent
+			}
+		case MetricVMMemory:
+			if stats != nil && v.MemoryMB > 0 {
+				o.value = float64(stats.MemRSS) / float64(v.MemoryMB<<20) * 100
+			}
+		case MetricVMCrashed:
+			o.value = boolValue(v.State == "crashed" || (crashed[h.ID+"/"+v.Name] && v.State != "running"))
+		}
+		obs = append(obs, o)
+	}
+	return obs
+}
+
+// sampled 宿主机本轮是否采集到了该指标所需的数据
+func sampled(metric string, s *monitor.Sample) bool {
+	if metric == MetricHostUnreachable {
+		return true
+	}
+	if s == nil {
+		return false
+	}
+	// VM 列表采集失败时 VMs 为 nil
+	return !metricInfo[metric].vm || s.VMs != nil
+}
+
+// step 推进单个对象的评估状态
+func (e *Engine) step(key string, r *store.AlertRule, h *store.Host, o observation, now time.Time) {
+	in := e.instances[key]
+	if in == nil {
+		in = &instance{ruleID: r.ID, hostID: h.ID, vmName: o.vmName}
+		e.instances[key] = in
+	}
+	in.metric, in.channels = r.Metric, r.Channels
+
+	if !breached(r, o.value, in.ev != nil) {
+		if in.ev != nil {
+			e.resolve(in, o.value, describe(r.Metric, h.Name, o.vmName, o.value, false), now)
+		}
+		delete(e.instances, key)
+		return
+	}
+	if in.ev != nil {
+		return
+	}
+	if in.pendingSince.IsZero() {
+		in.pendingSince = now
+	}
+	if now.Sub(in.pendingSince) < time.Duration(r.ForSeconds)*time.Second {
+		return
+	}
+	e.fire(in, r, h, o.value, now)
+}
+
+// breached 是否满足告警条件，已触发时按回差放宽，避免在阈值附近反复触发和恢复
+func breached(r *store.AlertRule, value float64, firing bool) bool {
+	if metricInfo[r.Metric].flag {
+		return value >= 1
+	}
+	threshold := r.Threshold
+	if r.Operator == "<" {
+		if firing {
+			threshold += r.Hysteresis
+		}
+		return value < threshold
+	}
+	if firing {
+		threshold -= r.Hysteresis
+	}
+	return value > threshold
+}
+
+func (e *Engine) fire(in *instance, r *store.AlertRule, h *store.Host, value float64, now time.Time) {
+	ev := &store.AlertEvent{
+		RuleID:    r.ID,
+		RuleName:  r.Name,
+		Metric:    r.Metric,
+		Severity:  r.Severity,
+		HostID:    h.ID,
+		HostName:  h.Name,
+		VMName:    in.vmName,
+		State:     StateFiring,
+		Value:     value,
+		Threshold: r.Threshold,
+		Message:   describe(r.Metric, h.Name, in.vmName, value, true),
+		Silenced:  e.silenced(r.ID, h.ID, in.vmName, now),
+		StartedAt: now.Format("2006-01-02 15:04:05"),
+	}
+	if metricInfo[r.Metric].flag {
+		ev.Threshold = 1
+	}
+	if err := e.store.AlertEventInsert(ev); err != nil {
+		log.Printf("alert record: %v", err)
+	}
+	in.ev = ev
+	e.publish(*ev, in.channels)
+}
+
+func (e *Engine) resolve(in *instance, value float64, message string, now time.Time) {
+	ev := *in.ev
+	ev.State = StateResolved
+	ev.Value = value
+	ev.Message = message
+	ev.ResolvedAt = now.Format("2006-01-02 15:04:05")
+	if err := e.store.AlertEventResolve(ev.ID, ev.ResolvedAt, ev.Message); err != nil {
+		log.Printf("alert resolve: %v", err)
+	}
+	in.ev = nil
+	e.publish(ev, in.channels)
+}
+
+// publish 推送 alert:event，未静默时发送通知（触发时静默的告警恢复时也不通知）
+func (e *Engine) publish(ev store.AlertEvent, channels string) {
+	e.emitter.Emit("alert:event", ev)
+	if !ev.Silenced {
+		e.notify(&ev, channels)
+	}
+}
+
+// silenced 是否有生效中的静默匹配该对象
+func (e *Engine) silenced(ruleID, hostID, vmName string, now time.Time) bool {
+	ts := now.Format("2006-01-02 15:04:05")
+	for _, s := range e.silences {
+		if s.StartsAt != "" && s.StartsAt > ts {
+			continue
+		}
+		if (s.RuleID == "" || s.RuleID == ruleID) && (s.HostID == "" || s.HostID == hostID) &&
+			(s.VMPattern == "" || matchAny(s.VMPattern, vmName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe 告警说明
+func describe(metric, hostName, vmName string, value float64, firing bool) string {
+	info := metricInfo[metric]
+	target := "host " + hostName
+	if vmName != "" {
+		target = fmt.Sprintf("vm %s on %s", vmName, hostName)
+	}
+	switch {
+	case metric == MetricHostUnreachable && firing:
+		return target + " is unreachable"
+	case metric == MetricHostUnreachable:
+		return target + " is reachable again"
+	case metric == MetricVMCrashed && firing:
+		return target + " crashed"
+	case metric == MetricVMCrashed:
+		return target + " recovered from crash"
+	case firing:
+		return fmt.Sprintf("%s %s is %.1f%s", target, info.label, value, info.unit)
+	}
+	return fmt.Sprintf("%s %s back to %.1f%s", target, info.label, value, info.unit)
+}
+
+// hostMatches 规则是否适用于该宿主机
+func hostMatches(r *store.AlertRule, h *store.Host) bool {
+	if r.HostID != "" && r.HostID != h.ID {
+		return false
+	}
+	if r.HostTags == "" {
+		return true
+	}
+	for _, tag := range splitList(h.Tags) {
+		for _, want := range splitList(r.HostTags) {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny 名称是否匹配逗号分隔的任一通配符，patterns 为空时均匹配
+func matchAny(patterns, name string) bool {
+	list := splitList(patterns)
+	if len(list) == 0 {
+		return true
+	}
+	for _, p := range list {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Validate 校验规则并补全默认值（比较符 >，级别 warning）
+func Validate(r *store.AlertRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	info, ok := metricInfo[r.Metric]
+	if !ok {
+		return fmt.Errorf("unknown metric: %s", r.Metric)
+	}
+	if r.Operator == "" {
+		r.Operator = ">"
+	}
+	if r.Operator != ">" && r.Operator != "<" {
+		return fmt.Errorf("invalid operator: %s", r.Operator)
+	}
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	switch r.Severity {
+	case "info", "warning", "critical":
+	default:
+		return fmt.Errorf("invalid severity: %s", r.Severity)
+	}
+	if r.Hysteresis < 0 || r.ForSeconds < 0 {
+		return fmt.Errorf("hysteresis and forSeconds must not be negative")
+	}
+	if !info.vm && r.VMPattern != "" {
+		return fmt.Errorf("vmPattern only applies to vm.* metrics")
+	}
+	for _, p := range splitList(r.VMPattern) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid vm pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// ValidateSilence 校验静默并按 duration（如 "2h"，从 StartsAt 或 now 起算）补全 EndsAt，duration 与 EndsAt 二选一
+func ValidateSilence(s *store.AlertSilence, duration string, now time.Time) error {
+	const layout = "2006-01-02 15:04:05"
+	if duration != "" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration: %s", duration)
+		}
+		start := now
+		if s.StartsAt != "" {
+			if start, err = time.ParseInLocation(layout, s.StartsAt, time.Local); err != nil {
+				return fmt.Errorf("invalid startsAt: %s", s.StartsAt)
+			}
+		}
+		s.EndsAt = start.Add(d).Format(layout)
+	}
+	if s.EndsAt == "" {
+		return fmt.Errorf("duration or endsAt is required")
+	}
+	for _, v := range []string{s.StartsAt, s.EndsAt} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.ParseInLocation(layout, v, time.Local); err != nil {
+			return fmt.Errorf("invalid time: %s", v)
+		}
+	}
+	for _, p := range splitList(s.VMPattern) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid vm pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"vmcat/internal/store"
+)
+
+func TestBreached(t *testing.T) {
+	above := &store.AlertRule{Metric: MetricHostCPU, Operator: ">", Threshold: 80, Hysteresis: 5}
+	below := &store.AlertRule{Metric: MetricHostDisk, Operator: "<", Threshold: 10, Hysteresis: 2}
+	noHysteresis := &store.AlertRule{Metric: MetricHostCPU, Operator: ">", Threshold: 80}
+	flag := &store.AlertRule{Metric: MetricHostUnreachable, Operator: "<", Threshold: 50, Hysteresis: 10}
+
+	tests := []struct {
+		name   string
+		rule   *store.AlertRule
+		value  float64
+		firing bool
+		want   bool
+	}{
+		{"above threshold", above, 81, false, true},
+		{"at threshold", above, 80, false, false},
+		{"inside band before firing", above, 78, false, false},
+		{"inside band while firing", above, 78, true, true},
+		{"at lower edge while firing", above, 75, true, false},
+		{"below band while firing", above, 74, true, false},
+
+		{"below threshold", below, 9, false, true},
+		{"at threshold (<)", below, 10, false, false},
+		{"inside band while firing (<)", below, 11, true, true},
+		{"at upper edge while firing (<)", below, 12, true, false},
+
+		{"no hysteresis while firing", noHysteresis, 80, true, false},
+
+		{"flag set", flag, 1, false, true},
+		{"flag clear", flag, 0, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := breached(tt.rule, tt.value, tt.firing); got != tt.want {
+				t.Errorf("breached(%v, firing=%v) = %v, want %v", tt.value, tt.firing, got, tt.want)
+			}
+		})
+	}
+}
+
+// recorder 记录推送的 alert:event
+type recorder struct {
+	events []store.AlertEvent
+}
+
+func (r *recorder) Emit(topic string, data ...interface{}) {
+	if topic == "alert:event" {
+		r.events = append(r.events, data[0].(store.AlertEvent))
+	}
+}
+
+func newTestEngine(t *testing.T) (*Engine, *recorder) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	s, err := store.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	e := NewEngine(nil, s)
+	rec := &recorder{}
+	e.SetEmitter(rec)
+	return e, rec
+}
+
+func TestStep(t *testing.T) {
+	type sample struct {
+		at    int     // 秒
+		value float64 // 观测值
+		state string  // 评估后告警状态：firing / pending / 空（无状态）
+		event string  // 本次评估推送的事件，空为无
+	}
+	tests := []struct {
+		name    string
+		rule    store.AlertRule
+		samples []sample
+	}{
+		{
+			name: "fires after duration and resolves past hysteresis",
+			rule: store.AlertRule{ID: "r1", Metric: MetricHostCPU, Operator: ">", Threshold: 80, Hysteresis: 5, ForSeconds: 60},
+			samples: []sample{
+				{0, 85, "pending", ""},
+				{30, 90, "pending", ""},
+				{60, 81, "firing", StateFiring},
+				{90, 78, "firing", ""}, // 回差内不恢复
+				{120, 76, "firing", ""},
+				{150, 75, "", StateResolved},
+				{180, 79, "", ""}, // 恢复后按原阈值判断
+				{210, 85, "pending", ""},
+			},
+		},
+		{
+			name: "dip below threshold restarts the duration",
+			rule: store.AlertRule{ID: "r2", Metric: MetricHostCPU, Operator: ">", Threshold: 80, ForSeconds: 60},
+			samples: []sample{
+				{0, 85, "pending", ""},
+				{30, 79, "", ""},
+				{60, 85, "pending", ""},
+				{90, 85, "pending", ""},
+				{120, 85, "firing", StateFiring},
+				{150, 80, "", StateResolved},
+			},
+		},
+		{
+			name: "fires immediately without duration",
+			rule: store.AlertRule{ID: "r3", Metric: MetricHostDisk, Operator: "<", Threshold: 10, Hysteresis: 2},
+			samples: []sample{
+				{0, 9, "firing", StateFiring},
+				{30, 11.5, "firing", ""},
+				{60, 9, "firing", ""},
+				{90, 12, "", StateResolved},
+			},
+		},
+		{
+			name: "flag metric ignores threshold",
+			rule: store.AlertRule{ID: "r4", Metric: MetricHostUnreachable, Operator: ">", Threshold: 5, Hysteresis: 1},
+			samples: []sample{
+				{0, 1, "firing", StateFiring},
+				{30, 1, "firing", ""},
+				{60, 0, "", StateResolved},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, rec := newTestEngine(t)
+			h := &store.Host{ID: "h1", Name: "node1"}
+			key := instanceKey(tt.rule.ID, h.ID, "")
+			start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+
+			for _, s := range tt.samples {
+				before := len(rec.events)
+				e.step(key, &tt.rule, h, observation{value: s.value}, start.Add(time.Duration(s.at)*time.Second))
+
+				state := ""
+				if in := e.instances[key]; in != nil {
+					state = "pending"
+					if in.ev != nil {
+						state = "firing"
+					}
+				}
+				if state != s.state {
+					t.Errorf("t=%ds value=%v: state = %q, want %q", s.at, s.value, state, s.state)
+				}
+				event := ""
+				if len(rec.events) > before {
+					event = rec.events[len(rec.events)-1].State
+				}
+				if event != s.event {
+					t.Errorf("t=%ds value=%v: event = %q, want %q", s.at, s.value, event, s.event)
+				}
+			}
+
+			// 每次触发都记入告警记录，恢复后状态为 resolved
+			list, err := e.store.AlertEventList("", tt.rule.ID, "", 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fired := 0
+			for _, ev := range rec.events {
+				if ev.State == StateFiring {
+					fired++
+				}
+			}
+			if len(list) != fired {
+				t.Errorf("%d alert records, want %d", len(list), fired)
+			}
+			for _, ev := range list {
+				if ev.State != StateResolved {
+					t.Errorf("record %d state = %s, want resolved", ev.ID, ev.State)
+				}
+			}
+		})
+	}
+}
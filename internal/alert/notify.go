@@ -0,0 +1,259 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"vmcat/internal/metrics"
+	"vmcat/internal/store"
+)
+
+// 通知渠道类型
+const (
+	ChannelWebhook = "webhook" // config: url, secret（可选，HMAC-SHA256 签名）
+	ChannelSMTP    = "smtp"    // config: host, port, security (starttls | tls | none), username, password, from, to
+	ChannelDesktop = "desktop" // 桌面通知，仅桌面模式可用
+)
+
+// ChannelTypes 支持的通知渠道类型
+var ChannelTypes = []string{ChannelWebhook, ChannelSMTP, ChannelDesktop}
+
+// ValidateChannel 校验通知渠道名称和类型
+func ValidateChannel(c *store.AlertChannel) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("channel name is required")
+	}
+	for _, t := range ChannelTypes {
+		if c.Type == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid channel type: %s", c.Type)
+}
+
+// Sender 通知渠道实现
+type Sender func(ctx context.Context, config map[string]string, ev *store.AlertEvent) error
+
+// sendTimeout 单次通知超时
+const sendTimeout = 30 * time.Second
+
+// notifications 通知发送次数
+var notifications = metrics.Default.NewCounterVec(
+	"vmcat_alert_notifications_total",
+	"Alert notifications sent, by channel type and result (ok, error).",
+	"type", "result",
+)
+
+// notify 异步发送到规则指定的渠道，channels 为空时发送到全部启用的渠道
+func (e *Engine) notify(ev *store.AlertEvent, channels string) {
+	list, err := e.store.AlertChannelList(true)
+	if err != nil {
+		log.Printf("alert channels: %v", err)
+		return
+	}
+	wanted := splitList(channels)
+	for _, ch := range list {
+		if !ch.Enabled || (len(wanted) > 0 && !contains(wanted, ch.ID)) {
+			continue
+		}
+		fn := e.senders[ch.Type]
+		go func(ch store.AlertChannel) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := send(ctx, fn, &ch, ev); err != nil {
+				log.Printf("alert notify %s (%s): %v", ch.Name, ch.Type, err)
+			}
+		}(ch)
+	}
+}
+
+// Test 向渠道发送一条测试通知
+func (e *Engine) Test(ctx context.Context, ch *store.AlertChannel) error {
+	e.mu.Lock()
+	fn := e.senders[ch.Type]
+	e.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	now := time.Now().Format("2006-01-02 15:04:05")
+	return send(ctx, fn, ch, &store.AlertEvent{
+		RuleName:  "Test notification",
+		Severity:  "info",
+		State:     StateFiring,
+		Message:   "This is a test notification from VMCat.",
+		StartedAt: now,
+	})
+}
+
+func send(ctx context.Context, fn Sender, ch *store.AlertChannel, ev *store.AlertEvent) error {
+	if fn == nil {
+		notifications.Inc(ch.Type, "error")
+		return fmt.Errorf("channel type %s is not available in this mode", ch.Type)
+	}
+	if err := fn(ctx, ch.Config, ev); err != nil {
+		notifications.Inc(ch.Type, "error")
+		return err
+	}
+	notifications.Inc(ch.Type, "ok")
+	return nil
+}
+
+// Title 通知标题，如 "[FIRING] Disk almost full"
+func Title(ev *store.AlertEvent) string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(ev.State), ev.RuleName)
+}
+
+// Body 通知正文
+func Body(ev *store.AlertEvent) string {
+	var b strings.Builder
+	b.WriteString(ev.Message)
+	b.WriteString("\n\n")
+	if ev.Severity != "" {
+		fmt.Fprintf(&b, "Severity: %s\n", ev.Severity)
+	}
+	if ev.HostName != "" {
+		fmt.Fprintf(&b, "Host: %s\n", ev.HostName)
+	}
+	if ev.VMName != "" {
+		fmt.Fprintf(&b, "VM: %s\n", ev.VMName)
+	}
+	fmt.Fprintf(&b, "Started: %s\n", ev.StartedAt)
+	if ev.ResolvedAt != "" {
+		fmt.Fprintf(&b, "Resolved: %s\n", ev.ResolvedAt)
+	}
+	return b.String()
+}
+
+// sendWebhook 以 JSON POST 告警记录，配置了 secret 时附带 X-VMCat-Signature: sha256=<hex>
+func sendWebhook(ctx context.Context, config map[string]string, ev *store.AlertEvent) error {
+	url := config["url"]
+	if url == "" {
+		return fmt.Errorf("webhook url is required")
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "VMCat")
+	if secret := config["secret"]; secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-VMCat-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sendSMTP 发送邮件，security 默认 starttls（端口 587），tls 为隐式 TLS（端口 465）
+func sendSMTP(ctx context.Context, config map[string]string, ev *store.AlertEvent) error {
+	host, from, to := config["host"], config["from"], splitList(config["to"])
+	if host == "" || from == "" || len(to) == 0 {
+		return fmt.Errorf("smtp host, from and to are required")
+	}
+	security := config["security"]
+	if security == "" {
+		security = "starttls"
+	}
+	port := config["port"]
+	if port == "" {
+		port = "587"
+		if security == "tls" {
+			port = "465"
+		}
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+	switch security {
+	case "tls":
+		conn = tls.Client(conn, tlsConfig)
+	case "starttls", "none":
+	default:
+		conn.Close()
+		return fmt.Errorf("invalid smtp security: %s", security)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if security == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if user := config["username"]; user != "" {
+		if err := c.Auth(smtp.PlainAuth("", user, config["password"], host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		from, strings.Join(to, ", "), mime.QEncoding.Encode("utf-8", "VMCat "+Title(ev)), time.Now().Format(time.RFC1123Z))
+	io.WriteString(w, strings.ReplaceAll(Body(ev), "\n", "\r\n"))
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+// 角色，权限依次递增
+const (
+	RoleViewer   = "viewer"   // 只读
+	RoleOperator = "operator" // 可执行 VM 生命周期等日常操作
+	RoleAdmin    = "admin"    // 全部操作，包括宿主机、用户和设置管理
+)
+
+var roleRank = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ValidRole 检查角色名是否合法
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAllows 判断 role 是否满足所需的最低角色 need
+func RoleAllows(role, need string) bool {
+	have, ok := roleRank[role]
+	return ok && have >= roleRank[need]
+}
+
+// Principal 已认证的调用方
+type Principal struct {
+	UserID     string   `json:"userId"`
+	Name       string   `json:"name"`
+	Role       string   `json:"role"`
+	HostTags   []string `json:"hostTags"`          // 可访问的宿主机标签，为空不限
+	VMPatterns []string `json:"vmPatterns"`        // 可访问的 VM 名称通配符，为空不限
+	Subject    string   `json:"subject,omitempty"` // 客户端证书身份 (mTLS)，仅在证书对应的用户即调用方时设置，非空时作为审计操作者
+}
+
+// Actor 审计日志中记录的操作者
+func (p *Principal) Actor() string {
+	if p.Subject != "" {
+		return p.Subject
+	}
+	return p.Name
+}
+
+// Authenticator 校验令牌并返回调用方
+type Authenticator interface {
+	Authenticate(token string) (*Principal, error)
+}
+
+// CertAuthenticator 可选接口，未携带令牌时凭已校验的客户端证书认证
+type CertAuthenticator interface {
+	AuthenticateCert(cert *x509.Certificate) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal 返回携带调用方的 ctx
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom 从 ctx 获取调用方，未认证时返回 nil
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
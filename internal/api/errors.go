@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+// 错误类别，REST 接口据此返回 HTTP 状态码（用 errors.Is 判断）
+var (
+	ErrForbidden     = errors.New("forbidden")      // 403 调用方无权执行该操作
+	ErrInvalidParams = errors.New("invalid params") // 400 参数错误
+	ErrUnknownAction = errors.New("unknown action") // 404 action 不存在
+	ErrNotFound      = errors.New("not found")      // 404 资源不存在
+	ErrUnavailable   = errors.New("unavailable")    // 503 宿主机未连接或连接不可用
+)
+
+// ErrorBody REST 错误响应 {"error": {...}}
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail 错误详情，Code 为稳定的机器可读类别
+type ErrorDetail struct {
+	Code    string `json:"code"` // invalid_params | forbidden | unauthorized | not_found | unknown_action | method_not_allowed | unavailable | timeout | operation_failed
+	Message string `json:"message"`
+	Action  string `json:"action,omitempty"`
+}
+
+// kindError 为错误附加类别，消息保持不变
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// WithKind 标记错误类别（如 ErrNotFound / ErrUnavailable），不改变错误消息
+func WithKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// errorStatus 错误对应的 HTTP 状态码和错误码
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrInvalidParams):
+		return http.StatusBadRequest, "invalid_params"
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden, "forbidden"
+	case errors.Is(err, ErrUnknownAction):
+		return http.StatusNotFound, "unknown_action"
+	case errors.Is(err, ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable, "unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "timeout"
+	default:
+		return http.StatusInternalServerError, "operation_failed"
+	}
+}
+
+// writeError 写入 REST 错误响应
+func writeError(w http.ResponseWriter, status int, code, msg, action string) {
+	writeJSON(w, status, ErrorBody{Error: ErrorDetail{Code: code, Message: msg, Action: action}})
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vmcat/internal/event"
+
+	"github.com/gorilla/websocket"
+)
+
+// sseHeartbeat SSE 心跳间隔，防止代理断开空闲连接
+const sseHeartbeat = 15 * time.Second
+
+// eventUpgrader 事件 WebSocket 升级器（已通过 Token 认证，允许任意来源）
+var eventUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleEvents 事件推送入口，WebSocket 握手走 WS，否则走 SSE
+// 查询参数: topics=image:import:*,migrate:progress  lastEventId=123
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, Response{Code: 405, Msg: "method not allowed"})
+		return
+	}
+
+	var topics []string
+	if t := r.URL.Query().Get("topics"); t != "" {
+		topics = strings.Split(t, ",")
+	}
+
+	// EventSource 重连时自动带 Last-Event-ID 头
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = r.URL.Query().Get("lastEventId")
+	}
+	lastID, _ := strconv.ParseUint(lastIDStr, 10, 64)
+
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveEventsWS(w, r, topics, lastID)
+		return
+	}
+	s.serveEventsSSE(w, r, topics, lastID)
+}
+
+// serveEventsSSE 以 text/event-stream 推送事件
+func (s *Server) serveEventsSSE(w http.ResponseWriter, r *http.Request, topics []string, lastID uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, Response{Code: 500, Msg: "streaming unsupported"})
+		return
+	}
+
+	sub, replay := s.events.Subscribe(topics, lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// 告知客户端重连间隔
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, ev := range replay {
+		if !s.visible(r, ev) {
+			continue
+		}
+		if err := writeSSE(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev, ok := <-sub.C():
+			if !ok {
+				// 被判定为慢消费者，通知客户端后断开
+				if sub.Dropped() {
+					fmt.Fprint(w, "event: dropped\ndata: {}\n\n")
+					flusher.Flush()
+				}
+				return
+			}
+			if !s.visible(r, ev) {
+				continue
+			}
+			if err := writeSSE(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// visible 事件是否推送给当前请求的调用方
+func (s *Server) visible(r *http.Request, ev event.Event) bool {
+	return s.eventFilter == nil || s.eventFilter(PrincipalFrom(r.Context()), ev)
+}
+
+// writeSSE 写入单条 SSE 消息
+func writeSSE(w http.ResponseWriter, ev event.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Topic, data)
+	return err
+}
+
+// serveEventsWS 以 WebSocket 文本帧推送事件（每帧一个 JSON Event）
+func (s *Server) serveEventsWS(w http.ResponseWriter, r *http.Request, topics []string, lastID uint64) {
+	conn, err := eventUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub, replay := s.events.Subscribe(topics, lastID)
+	defer sub.Close()
+
+	// 读循环仅用于感知客户端断开
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, ev := range replay {
+		if !s.visible(r, ev) {
+			continue
+		}
+		if err := conn.WriteJSON(ev); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev, ok := <-sub.C():
+			if !ok {
+				reason := "closed"
+				if sub.Dropped() {
+					reason = "dropped: consumer too slow"
+				}
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+					time.Now().Add(time.Second))
+				return
+			}
+			if !s.visible(r, ev) {
+				continue
+			}
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
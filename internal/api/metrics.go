@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"vmcat/internal/metrics"
+)
+
+var (
+	apiRequests = metrics.Default.NewCounterVec(
+		"vmcat_api_requests_total",
+		"API action calls, by action and result code (ok or the REST error code).",
+		"action", "code",
+	)
+	apiDuration = metrics.Default.NewHistogramVec(
+		"vmcat_api_request_duration_seconds",
+		"Duration of API action calls.",
+		nil,
+		"action",
+	)
+	authFailures = metrics.Default.NewCounterVec(
+		"vmcat_api_auth_failures_total",
+		"Requests rejected because of a missing or invalid token or certificate.",
+	)
+)
+
+// SetMetrics 设置 /metrics 处理函数（经过认证中间件）
+func (s *Server) SetMetrics(h http.Handler) {
+	s.metrics = h
+}
+
+// call 调用 ActionHandler 并记录请求数和耗时
+func (s *Server) call(ctx context.Context, action string, data json.RawMessage) (interface{}, error) {
+	start := time.Now()
+	result, err := s.handler(ctx, action, data)
+
+	code := "ok"
+	if err != nil {
+		_, code = errorStatus(err)
+	}
+	// 未知 action 名称由调用方任意指定，合并为一个标签值避免指标无限增长
+	label := action
+	if errors.Is(err, ErrUnknownAction) {
+		label = "unknown"
+	}
+	apiRequests.Inc(label, code)
+	apiDuration.Observe(time.Since(start).Seconds(), label)
+	return result, err
+}
@@ -1,17 +1,22 @@
 package api
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 )
 
-// APIKeyAuth API Key 认证中间件
-func APIKeyAuth(key string) func(http.Handler) http.Handler {
+// errIdentityMismatch 令牌与客户端证书属于不同用户
+var errIdentityMismatch = errors.New("token and client certificate identify different users")
+
+// Auth 令牌认证中间件，认证通过后将调用方写入请求 ctx
+func Auth(authn Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// 健康检查不需要认证
-			if r.URL.Path == "/health" {
+			// 健康检查和 API 文档不需要认证
+			if r.URL.Path == "/health" || r.URL.Path == restPrefix+"/openapi.json" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -27,14 +32,48 @@ func APIKeyAuth(key string) func(http.Handler) http.Handler {
 				token = r.URL.Query().Get("token")
 			}
 
-			if token != key {
+			// mTLS 下客户端证书已由 TLS 层校验
+			var cert *x509.Certificate
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				cert = r.TLS.PeerCertificates[0]
+			}
+
+			ca, _ := authn.(CertAuthenticator)
+			var p *Principal
+			var err error
+			fromCert := false
+			switch {
+			case token != "":
+				p, err = authn.Authenticate(token)
+				if err == nil && p != nil && ca != nil && cert != nil {
+					// 令牌和客户端证书同时出现时必须是同一用户，否则会以令牌身份授权、以证书身份审计；
+					// 证书没有对应用户时仅作为传输层凭据，以令牌身份审计
+					if cp, cerr := ca.AuthenticateCert(cert); cerr == nil {
+						if cp.UserID != p.UserID {
+							p, err = nil, errIdentityMismatch
+						} else {
+							fromCert = true
+						}
+					}
+				}
+			case ca != nil && cert != nil:
+				p, err = ca.AuthenticateCert(cert)
+				fromCert = true
+			}
+			if p == nil || err != nil {
+				authFailures.Inc()
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusUnauthorized)
 				json.NewEncoder(w).Encode(Response{Code: 401, Msg: "unauthorized"})
 				return
 			}
+			if fromCert {
+				withSubject := *p
+				withSubject.Subject = CertSubject(cert)
+				p = &withSubject
+			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
 		})
 	}
 }
@@ -43,8 +82,8 @@ func APIKeyAuth(key string) func(http.Handler) http.Handler {
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -0,0 +1,376 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenAPIDoc OpenAPI 3.0 文档（仅包含本服务用到的部分）
+// 扩展字段 x-actions 列出全部 action（含无 REST 路由的），供 pkg/client 代码生成使用
+type OpenAPIDoc struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       OpenAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security"`
+	Actions    []ActionSpec                    `json:"x-actions"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// Operation 单个 REST 操作
+type Operation struct {
+	OperationID string                  `json:"operationId"`
+	Summary     string                  `json:"summary,omitempty"`
+	Tags        []string                `json:"tags,omitempty"`
+	Parameters  []Parameter             `json:"parameters,omitempty"`
+	RequestBody *RequestBody            `json:"requestBody,omitempty"`
+	Responses   map[string]ResponseSpec `json:"responses"`
+	Action      string                  `json:"x-action,omitempty"`
+	Role        string                  `json:"x-role,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path | query
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type ResponseSpec struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// ActionSpec action 描述，Params/Result 为 nil 表示无参数/无结果
+type ActionSpec struct {
+	Name   string  `json:"name"`
+	Role   string  `json:"role"`
+	Method string  `json:"method,omitempty"`
+	Path   string  `json:"path,omitempty"`
+	Params *Schema `json:"params,omitempty"`
+	Result *Schema `json:"result,omitempty"`
+}
+
+// Schema JSON Schema 子集
+// 命名类型放入 components 并以 $ref 引用，x-go-name 记录 Go 字段名
+type Schema struct {
+	Ref                  string     `json:"$ref,omitempty"`
+	Type                 string     `json:"type,omitempty"`
+	Format               string     `json:"format,omitempty"`
+	Nullable             bool       `json:"nullable,omitempty"`
+	Items                *Schema    `json:"items,omitempty"`
+	Properties           Properties `json:"properties,omitempty"`
+	AdditionalProperties *Schema    `json:"additionalProperties,omitempty"`
+	GoName               string     `json:"x-go-name,omitempty"`
+}
+
+// Property 结构体字段
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties 有序字段列表，序列化为 JSON 对象并保持 Go 结构体中的顺序
+type Properties []Property
+
+func (ps Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, p := range ps {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(p.Name)
+		schema, err := json.Marshal(p.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(schema)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (ps *Properties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("invalid property name %v", tok)
+		}
+		var s Schema
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		*ps = append(*ps, Property{Name: name, Schema: &s})
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// BuildOpenAPI 由路由表生成 OpenAPI 文档
+func BuildOpenAPI(version string, routes []Route) *OpenAPIDoc {
+	g := &schemaGen{schemas: make(map[string]*Schema)}
+	doc := &OpenAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:   "VMCat API",
+			Version: version,
+			Description: "Every action is available via POST /v1/api.json {\"action\", \"data\"}; " +
+				"most also have a REST route below. Errors on REST routes use real HTTP status codes with an ErrorBody.",
+		},
+		Paths: make(map[string]map[string]Operation),
+		Components: Components{
+			Schemas:         g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{"bearer": {Type: "http", Scheme: "bearer"}},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}
+
+	errorBody := g.schema(reflect.TypeOf(ErrorBody{}))
+	doc.Paths[restPrefix+"/api.json"] = map[string]Operation{
+		"post": {
+			OperationID: "callAction",
+			Summary:     "Call any action by name (see x-actions)",
+			RequestBody: &RequestBody{Required: true, Content: jsonContent(g.schema(reflect.TypeOf(Request{})))},
+			Responses: map[string]ResponseSpec{
+				"200": {Description: "code 0 on success, non-zero with msg on failure", Content: jsonContent(g.schema(reflect.TypeOf(Response{})))},
+			},
+		},
+	}
+
+	for _, r := range routes {
+		spec := ActionSpec{Name: r.Action, Role: r.Role, Method: r.Method, Path: r.Path}
+		if r.Params != nil && r.Params.NumField() > 0 {
+			spec.Params = g.schema(r.Params)
+		}
+		if r.Result != nil {
+			spec.Result = g.schema(r.Result)
+		}
+		doc.Actions = append(doc.Actions, spec)
+
+		if r.Method == "" {
+			continue
+		}
+		op := Operation{
+			OperationID: r.Action,
+			Summary:     r.Action,
+			Tags:        []string{strings.SplitN(r.Action, ".", 2)[0]},
+			Action:      r.Action,
+			Role:        r.Role,
+			Responses:   map[string]ResponseSpec{"default": {Description: "error", Content: jsonContent(errorBody)}},
+		}
+		if spec.Result != nil {
+			op.Responses["200"] = ResponseSpec{Description: "success", Content: jsonContent(spec.Result)}
+		} else {
+			op.Responses["204"] = ResponseSpec{Description: "success"}
+		}
+
+		c := compileRoute(r)
+		inPath := make(map[string]bool)
+		for _, seg := range c.segs {
+			if seg.param == "" {
+				continue
+			}
+			inPath[seg.param] = true
+			op.Parameters = append(op.Parameters, Parameter{Name: seg.param, In: "path", Required: true, Schema: g.fieldSchema(r.Params, seg.param)})
+		}
+		if spec.Params != nil {
+			if r.Method == "GET" || r.Method == "DELETE" {
+				for _, f := range structFields(r.Params) {
+					if !inPath[f.name] {
+						op.Parameters = append(op.Parameters, Parameter{Name: f.name, In: "query", Schema: g.schema(f.typ)})
+					}
+				}
+			} else {
+				op.RequestBody = &RequestBody{Content: jsonContent(spec.Params)}
+			}
+		}
+
+		p := restPrefix + r.Path
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]Operation)
+		}
+		doc.Paths[p][strings.ToLower(r.Method)] = op
+	}
+
+	sort.Slice(doc.Actions, func(i, j int) bool { return doc.Actions[i].Name < doc.Actions[j].Name })
+	return doc
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// schemaGen 由 Go 类型生成 Schema，命名结构体登记到 components
+type schemaGen struct {
+	schemas map[string]*Schema
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *schemaGen) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			// $ref 不能带其它属性，保持原样
+			return s
+		}
+		c := *s
+		c.Nullable = true
+		return &c
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int:
+		return &Schema{Type: "integer"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: t.Kind().String()}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[name]; !ok {
+			// 先占位，避免递归类型死循环
+			g.schemas[name] = &Schema{Type: "object"}
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		// interface{} 等任意 JSON
+		return &Schema{}
+	}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: Properties{}}
+	for _, f := range structFields(t) {
+		fs := g.schema(f.typ)
+		if f.goName != "" {
+			if fs.Ref != "" || fs.Type == "" {
+				// 不修改共享的 $ref / 任意类型 Schema
+				c := *fs
+				fs = &c
+			}
+			fs.GoName = f.goName
+		}
+		s.Properties = append(s.Properties, Property{Name: f.name, Schema: fs})
+	}
+	return s
+}
+
+// fieldSchema 参数结构体中 json 名为 name 的字段的 Schema，未找到时视为字符串
+func (g *schemaGen) fieldSchema(t reflect.Type, name string) *Schema {
+	if ft, ok := jsonField(t, name); ok {
+		return g.schema(ft)
+	}
+	return &Schema{Type: "string"}
+}
+
+// schemaName 命名结构体的 Schema 名称，如 store.Host；main 包类型不带包名；匿名结构体返回空
+func schemaName(t reflect.Type) string {
+	if t.Name() == "" {
+		return ""
+	}
+	pkg := path.Base(t.PkgPath())
+	if pkg == "main" || pkg == "." {
+		return exportName(t.Name())
+	}
+	return pkg + "." + t.Name()
+}
+
+func exportName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+type structField struct {
+	name   string       // json 名
+	goName string       // Go 字段名
+	typ    reflect.Type // 字段类型
+}
+
+// structFields 结构体的 JSON 字段（含嵌入字段），顺序与定义一致
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		fields = append(fields, structField{name: tag, goName: f.Name, typ: f.Type})
+	}
+	return fields
+}
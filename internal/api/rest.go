@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// restPrefix REST 接口路径前缀
+const restPrefix = "/v1"
+
+// maxRESTBody REST 请求体上限
+const maxRESTBody = 16 << 20
+
+// Route REST 路由到 action 的映射
+// Path 相对 /v1，{name} 为路径参数，末段可带 ":verb" 表示自定义操作，如 /hosts/{hostId}/vms/{vmName}:start
+// 路径参数、查询参数和 JSON 请求体合并为 action 参数，名称与 action 参数的 json 字段一致
+type Route struct {
+	Method string // 为空表示仅可通过 /v1/api.json 调用
+	Path   string
+	Action string
+	Role   string       // 所需最低角色，写入 OpenAPI 文档
+	Params reflect.Type // action 参数类型，用于将字符串参数转换为对应的 JSON 类型
+	Result reflect.Type // action 结果类型，nil 表示无结果
+}
+
+// compiledRoute 预解析的路由
+type compiledRoute struct {
+	Route
+	segs  []routeSeg
+	score int // 字面量段越多越优先
+}
+
+type routeSeg struct {
+	literal string // 字面量段，与 param 二选一
+	param   string // 路径参数名
+	verb    string // 自定义操作 (":start")
+}
+
+func compileRoute(r Route) compiledRoute {
+	c := compiledRoute{Route: r}
+	for _, part := range strings.Split(strings.Trim(r.Path, "/"), "/") {
+		var seg routeSeg
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			part, seg.verb = part[:i], part[i+1:]
+			c.score++
+		}
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			seg.param = part[1 : len(part)-1]
+		} else {
+			seg.literal = part
+			c.score += 2
+		}
+		c.segs = append(c.segs, seg)
+	}
+	return c
+}
+
+// match 匹配已拆分并解码的路径段
+func (c *compiledRoute) match(parts []string) (map[string]string, bool) {
+	if len(parts) != len(c.segs) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range c.segs {
+		part := parts[i]
+		if seg.verb != "" {
+			var ok bool
+			if part, ok = strings.CutSuffix(part, ":"+seg.verb); !ok {
+				return nil, false
+			}
+		}
+		if seg.param != "" {
+			if part == "" {
+				return nil, false
+			}
+			params[seg.param] = part
+		} else if part != seg.literal {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// SetRoutes 设置 REST 路由（/v1/...），请求转换为 action 后交由同一个 ActionHandler 处理
+func (s *Server) SetRoutes(routes []Route) {
+	s.routes = s.routes[:0]
+	for _, r := range routes {
+		if r.Method != "" {
+			s.routes = append(s.routes, compileRoute(r))
+		}
+	}
+	s.openapi = BuildOpenAPI(s.version, routes)
+}
+
+// handleREST REST 入口：成功返回 200 + 结果（无结果时 204），失败返回对应状态码和 ErrorBody
+func (s *Server) handleREST(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), restPrefix+"/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint", "")
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	for i, part := range parts {
+		decoded, err := url.PathUnescape(part)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_params", "invalid path", "")
+			return
+		}
+		parts[i] = decoded
+	}
+
+	var route *compiledRoute
+	var pathParams map[string]string
+	pathMatched := false
+	for i := range s.routes {
+		c := &s.routes[i]
+		params, ok := c.match(parts)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if c.Method == r.Method && (route == nil || c.score > route.score) {
+			route, pathParams = c, params
+		}
+	}
+	if route == nil {
+		if pathMatched {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", "")
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint", "")
+		return
+	}
+
+	data, err := buildParams(r, route.Params, pathParams)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_params", err.Error(), route.Action)
+		return
+	}
+
+	result, err := s.call(r.Context(), route.Action, data)
+	if err != nil {
+		status, code := errorStatus(err)
+		writeError(w, status, code, err.Error(), route.Action)
+		return
+	}
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// 空列表返回 [] 而不是 null
+	if v := reflect.ValueOf(result); v.Kind() == reflect.Slice && v.IsNil() {
+		result = []struct{}{}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// buildParams 合并 JSON 请求体、查询参数和路径参数（后者优先）为 action 参数
+func buildParams(r *http.Request, typ reflect.Type, pathParams map[string]string) (json.RawMessage, error) {
+	obj := make(map[string]json.RawMessage)
+
+	if r.Body != nil && r.Method != http.MethodGet {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRESTBody))
+		if err != nil {
+			return nil, fmt.Errorf("read body: %w", err)
+		}
+		if body = bytes.TrimSpace(body); len(body) > 0 {
+			if err := json.Unmarshal(body, &obj); err != nil {
+				return nil, fmt.Errorf("request body must be a JSON object: %w", err)
+			}
+		}
+	}
+
+	for key, values := range r.URL.Query() {
+		// token 为 WebSocket / 浏览器场景的认证参数，不属于 action 参数
+		if key == "token" || len(values) == 0 {
+			continue
+		}
+		raw, err := paramValue(typ, key, values[0])
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = raw
+	}
+	for key, value := range pathParams {
+		raw, err := paramValue(typ, key, value)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = raw
+	}
+	return json.Marshal(obj)
+}
+
+// paramValue 按参数字段类型将字符串转换为 JSON 值，未知字段按字符串处理
+func paramValue(typ reflect.Type, key, value string) (json.RawMessage, error) {
+	field, ok := jsonField(typ, key)
+	if !ok {
+		return json.Marshal(value)
+	}
+	for field.Kind() == reflect.Pointer {
+		field = field.Elem()
+	}
+	switch field.Kind() {
+	case reflect.String:
+		return json.Marshal(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%s: expected integer, got %q", key, value)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%s: expected unsigned integer, got %q", key, value)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%s: expected number, got %q", key, value)
+		}
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected boolean, got %q", key, value)
+		}
+		return json.Marshal(b)
+	default:
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("%s: expected JSON value", key)
+		}
+	}
+	return json.RawMessage(value), nil
+}
+
+// jsonField 按 json 字段名查找结构体字段类型（含嵌入字段）
+func jsonField(typ reflect.Type, name string) (reflect.Type, bool) {
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for _, f := range reflect.VisibleFields(typ) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+
+	"vmcat/internal/event"
 )
 
 // Request API 请求
@@ -20,36 +25,62 @@ type Response struct {
 	Data interface{} `json:"data,omitempty"`
 }
 
-// ActionHandler 动作处理函数类型
-type ActionHandler func(action string, data json.RawMessage) (interface{}, error)
+// ActionHandler 动作处理函数类型，ctx 携带已认证的调用方（见 PrincipalFrom）
+type ActionHandler func(ctx context.Context, action string, data json.RawMessage) (interface{}, error)
 
 // Server HTTP API 服务器
 type Server struct {
 	handler     ActionHandler
 	termHandler http.HandlerFunc
 	vncHandler  http.HandlerFunc
+	events      *event.Hub
 	port        int
-	apiKey      string
+	authn       Authenticator
+	eventFilter EventFilter
+	tlsConfig   *tls.Config
+	routes      []compiledRoute
+	openapi     *OpenAPIDoc
+	metrics     http.Handler
 	version     string
 }
 
-// NewServer 创建 API 服务器
-func NewServer(handler ActionHandler, termHandler, vncHandler http.HandlerFunc, port int, apiKey, version string) *Server {
+// EventFilter 判断事件是否推送给调用方（p 在未启用认证时为 nil）
+type EventFilter func(p *Principal, ev event.Event) bool
+
+// NewServer 创建 API 服务器，authn 为 nil 时不做认证
+func NewServer(handler ActionHandler, termHandler, vncHandler http.HandlerFunc, events *event.Hub, port int, authn Authenticator, version string) *Server {
 	return &Server{
 		handler:     handler,
 		termHandler: termHandler,
 		vncHandler:  vncHandler,
+		events:      events,
 		port:        port,
-		apiKey:      apiKey,
+		authn:       authn,
 		version:     version,
 	}
 }
 
+// SetEventFilter 设置 /v1/events 的事件过滤（如按用户访问范围过滤）
+func (s *Server) SetEventFilter(f EventFilter) {
+	s.eventFilter = f
+}
+
+// SetTLS 启用 HTTPS（WebSocket 相应为 wss），cfg 由 LoadTLSConfig 生成
+func (s *Server) SetTLS(cfg *tls.Config) {
+	s.tlsConfig = cfg
+}
+
 // Start 启动服务（阻塞）
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/api.json", s.handleAPI)
 	mux.HandleFunc("/health", s.handleHealth)
+	if s.openapi != nil {
+		mux.HandleFunc(restPrefix+"/openapi.json", s.handleOpenAPI)
+	}
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics)
+	}
 
 	if s.termHandler != nil {
 		mux.HandleFunc("/ws/terminal", s.termHandler)
@@ -57,18 +88,31 @@ func (s *Server) Start() error {
 	if s.vncHandler != nil {
 		mux.HandleFunc("/ws/vnc", s.vncHandler)
 	}
+	if s.events != nil {
+		mux.HandleFunc("/v1/events", s.handleEvents)
+	}
+	if len(s.routes) > 0 {
+		mux.HandleFunc(restPrefix+"/", s.handleREST)
+	}
 
 	// 中间件链: CORS -> Auth -> Handler
 	var handler http.Handler = mux
-	if s.apiKey != "" {
-		handler = APIKeyAuth(s.apiKey)(handler)
+	if s.authn != nil {
+		handler = Auth(s.authn)(handler)
 	}
 	handler = CORSMiddleware(handler)
 
-	addr := fmt.Sprintf(":%d", s.port)
-	log.Printf("VMCat API server listening on %s", addr)
-	log.Printf("API Key: %s", s.apiKey)
-	return http.ListenAndServe(addr, handler)
+	srv := &http.Server{
+		Addr:      fmt.Sprintf(":%d", s.port),
+		Handler:   handler,
+		TLSConfig: s.tlsConfig,
+	}
+	if s.tlsConfig != nil {
+		log.Printf("VMCat API server listening on %s (TLS)", srv.Addr)
+		return srv.ListenAndServeTLS("", "")
+	}
+	log.Printf("VMCat API server listening on %s", srv.Addr)
+	return srv.ListenAndServe()
 }
 
 func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
@@ -83,7 +127,11 @@ func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := s.handler(req.Action, req.Data)
+	data, err := s.call(r.Context(), req.Action, req.Data)
+	if errors.Is(err, ErrForbidden) {
+		writeJSON(w, http.StatusForbidden, Response{Code: 403, Msg: err.Error()})
+		return
+	}
 	if err != nil {
 		writeJSON(w, http.StatusOK, Response{Code: 1, Msg: err.Error()})
 		return
@@ -98,6 +146,11 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleOpenAPI 返回由路由表生成的 OpenAPI 文档
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.openapi)
+}
+
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,143 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"* * * foo *",
+		"@every 5m",
+	}
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string // 空表示不会触发
+	}{
+		{"* * * * *", "2026-10-16 10:07:30", "2026-10-16 10:08:00"},
+		{"*/15 * * * *", "2026-10-16 10:07:00", "2026-10-16 10:15:00"},
+		{"*/15 * * * *", "2026-10-16 10:15:00", "2026-10-16 10:30:00"},
+		{"5/20 * * * *", "2026-10-16 10:30:00", "2026-10-16 10:45:00"},
+		{"0 0 * * *", "2026-10-16 23:59:30", "2026-10-17 00:00:00"},
+		{"30 8,20 * * *", "2026-10-16 09:00:00", "2026-10-16 20:30:00"},
+		{"0 20 * * mon-fri", "2026-10-16 21:00:00", "2026-10-19 20:00:00"},
+		{"0 0 * * 7", "2026-10-16 00:00:00", "2026-10-18 00:00:00"},
+		{"0 0 * * sun", "2026-10-16 00:00:00", "2026-10-18 00:00:00"},
+		{"0 0 1 jan *", "2026-10-16 00:00:00", "2027-01-01 00:00:00"},
+		{"@monthly", "2026-10-16 00:00:00", "2026-11-01 00:00:00"},
+		{"@weekly", "2026-10-16 00:00:00", "2026-10-18 00:00:00"},
+		{"@hourly", "2026-12-31 23:30:00", "2027-01-01 00:00:00"},
+		{"0 0 31 * *", "2026-11-01 00:00:00", "2026-12-31 00:00:00"},
+		{"0 0 29 2 *", "2026-10-16 00:00:00", "2028-02-29 00:00:00"},
+		{"0 0 30 2 *", "2026-10-16 00:00:00", ""},
+
+		// 日和周都指定时满足其一即触发（2026-10-16 为周五）
+		{"0 0 13 * 5", "2026-10-16 00:00:00", "2026-10-23 00:00:00"},
+		{"0 0 13 * 1", "2026-10-16 00:00:00", "2026-10-19 00:00:00"},
+		{"0 0 13 * 1", "2026-11-10 00:00:00", "2026-11-13 00:00:00"},
+		// 只指定其中之一时另一个为 *，须同时满足
+		{"0 0 13 * *", "2026-10-16 00:00:00", "2026-11-13 00:00:00"},
+		{"0 0 * * 5", "2026-10-16 00:00:00", "2026-10-23 00:00:00"},
+		{"0 0 ? * 5", "2026-10-16 00:00:00", "2026-10-23 00:00:00"},
+		{"0 0 1-7 * *", "2026-10-16 00:00:00", "2026-11-01 00:00:00"},
+	}
+	const layout = "2006-01-02 15:04:05"
+	for _, tt := range tests {
+		t.Run(tt.expr+" after "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, _ := time.ParseInLocation(layout, tt.from, time.UTC)
+			got := s.Next(from)
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("got %s, want never", got.Format(layout))
+				}
+				return
+			}
+			if got.Format(layout) != tt.want {
+				t.Errorf("got %s, want %s", got.Format(layout), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2026-03-08 02:00 EST 跳到 03:00 EDT，2026-11-01 02:00 EDT 回拨到 01:00 EST
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want []string
+	}{
+		{
+			name: "skipped time does not fire",
+			expr: "30 2 * * *",
+			from: time.Date(2026, 3, 8, 0, 0, 0, 0, ny),
+			want: []string{"2026-03-09 02:30 EDT", "2026-03-10 02:30 EDT"},
+		},
+		{
+			name: "every 30 minutes across spring forward",
+			expr: "*/30 * * * *",
+			from: time.Date(2026, 3, 8, 1, 0, 0, 0, ny),
+			want: []string{"2026-03-08 01:30 EST", "2026-03-08 03:00 EDT", "2026-03-08 03:30 EDT"},
+		},
+		{
+			name: "repeated time fires once per occurrence",
+			expr: "30 1 * * *",
+			from: time.Date(2026, 11, 1, 0, 0, 0, 0, ny),
+			want: []string{"2026-11-01 01:30 EDT", "2026-11-01 01:30 EST", "2026-11-02 01:30 EST"},
+		},
+		{
+			name: "hourly across fall back",
+			expr: "0 * * * *",
+			from: time.Date(2026, 11, 1, 0, 30, 0, 0, ny),
+			want: []string{"2026-11-01 01:00 EDT", "2026-11-01 01:00 EST", "2026-11-01 02:00 EST"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := tt.from
+			for _, want := range tt.want {
+				prev := next
+				next = s.Next(prev)
+				if got := next.Format("2006-01-02 15:04 MST"); got != want {
+					t.Fatalf("Next(%s) = %s, want %s", prev.Format(time.RFC3339), got, want)
+				}
+				if !next.After(prev) {
+					t.Fatalf("Next(%s) = %s is not after it", prev.Format(time.RFC3339), next.Format(time.RFC3339))
+				}
+			}
+		})
+	}
+}
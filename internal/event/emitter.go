@@ -5,7 +5,16 @@ type Emitter interface {
 	Emit(event string, data ...interface{})
 }
 
-// NoopEmitter 空实现（服务端模式使用，前端通过轮询获取状态）
+// NoopEmitter 空实现（未初始化时的默认值）
 type NoopEmitter struct{}
 
 func (e *NoopEmitter) Emit(event string, data ...interface{}) {}
+
+// Multi 依次转发给多个发射器
+type Multi []Emitter
+
+func (m Multi) Emit(event string, data ...interface{}) {
+	for _, e := range m {
+		e.Emit(event, data...)
+	}
+}
@@ -0,0 +1,212 @@
+package event
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistorySize = 256 // 回放缓冲区大小
+	defaultBufferSize  = 64  // 每个订阅者的发送缓冲区大小
+)
+
+// TopicReset 回放起点无法衔接时（ID 来自重启前的进程或已移出缓冲区）在回放前发送的事件，
+// 客户端应视为可能漏掉了事件并重新拉取状态；其 ID 为回放起点，可直接作为下次的 Last-Event-ID
+const TopicReset = "reset"
+
+// Event 推送给客户端的事件
+type Event struct {
+	ID    uint64      `json:"id"`
+	Topic string      `json:"topic"`
+	Data  interface{} `json:"data,omitempty"`
+	Time  int64       `json:"time"` // Unix 毫秒
+}
+
+// Hub 事件广播中心（服务端模式的 Emitter 实现）
+// 保存最近的事件用于断线回放，按主题过滤分发给订阅者
+// 事件 ID 从创建时的 Unix 微秒时间开始递增，重启后的 ID 大于重启前的 ID，不会与之前的事件混淆
+type Hub struct {
+	mu         sync.Mutex
+	nextID     uint64
+	history    []Event // 环形缓冲区
+	head       int     // 下一个写入位置
+	count      int     // 已写入数量（不超过容量）
+	subs       map[*Subscriber]struct{}
+	bufferSize int
+}
+
+// NewHub 创建事件广播中心，historySize/bufferSize <= 0 时使用默认值
+func NewHub(historySize, bufferSize int) *Hub {
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	return &Hub{
+		nextID:     uint64(time.Now().UnixMicro()),
+		history:    make([]Event, historySize),
+		subs:       make(map[*Subscriber]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+// Emit 实现 Emitter 接口，单个参数直接作为 data，多个参数作为数组
+func (h *Hub) Emit(topic string, data ...interface{}) {
+	var payload interface{}
+	switch len(data) {
+	case 0:
+	case 1:
+		payload = data[0]
+	default:
+		payload = data
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	ev := Event{
+		ID:    h.nextID,
+		Topic: topic,
+		Data:  payload,
+		Time:  time.Now().UnixMilli(),
+	}
+	h.history[h.head] = ev
+	h.head = (h.head + 1) % len(h.history)
+	if h.count < len(h.history) {
+		h.count++
+	}
+
+	for sub := range h.subs {
+		if !sub.match(topic) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			// 缓冲区已满，断开慢消费者，客户端可通过 Last-Event-ID 重连补齐
+			sub.dropped = true
+			h.removeLocked(sub)
+		}
+	}
+}
+
+// Subscribe 订阅事件，topics 为空表示全部主题
+// lastID > 0 时返回缓冲区中 ID 大于 lastID 的匹配事件用于回放；
+// lastID 不属于本进程或早于缓冲区时无法确定漏掉的事件，回放以 TopicReset 事件开头并包含缓冲区中全部匹配事件
+func (h *Hub) Subscribe(topics []string, lastID uint64) (*Subscriber, []Event) {
+	sub := &Subscriber{
+		ch:     make(chan Event, h.bufferSize),
+		topics: normalizeTopics(topics),
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []Event
+	if lastID > 0 {
+		start := (h.head - h.count + len(h.history)) % len(h.history)
+		// 可衔接的范围：缓冲区中最早事件的前一个 ID 到最新 ID
+		oldest := h.nextID
+		if h.count > 0 {
+			oldest = h.history[start].ID - 1
+		}
+		if lastID < oldest || lastID > h.nextID {
+			replay = append(replay, Event{
+				ID:    oldest,
+				Topic: TopicReset,
+				Data:  map[string]uint64{"lastEventId": lastID},
+				Time:  time.Now().UnixMilli(),
+			})
+			lastID = oldest
+		}
+		for i := 0; i < h.count; i++ {
+			ev := h.history[(start+i)%len(h.history)]
+			if ev.ID > lastID && sub.match(ev.Topic) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	h.subs[sub] = struct{}{}
+	return sub, replay
+}
+
+// LastID 返回最近一次事件的 ID
+func (h *Hub) LastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nextID
+}
+
+// SubscriberCount 当前订阅者数量
+func (h *Hub) SubscriberCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *Hub) removeLocked(sub *Subscriber) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.ch)
+}
+
+// Subscriber 事件订阅者
+type Subscriber struct {
+	ch      chan Event
+	topics  []string
+	hub     *Hub
+	dropped bool
+}
+
+// C 返回事件通道，订阅关闭或被断开时通道关闭
+func (s *Subscriber) C() <-chan Event {
+	return s.ch
+}
+
+// Dropped 是否因消费过慢被断开
+func (s *Subscriber) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+// Close 取消订阅
+func (s *Subscriber) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s)
+}
+
+// match 检查主题是否匹配过滤规则，支持 "*" 和 "image:import:*" 前缀通配
+func (s *Subscriber) match(topic string) bool {
+	if len(s.topics) == 0 {
+		return true
+	}
+	for _, t := range s.topics {
+		if t == "*" || t == topic {
+			return true
+		}
+		if strings.HasSuffix(t, "*") && strings.HasPrefix(topic, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTopics 去除空白和空项
+func normalizeTopics(topics []string) []string {
+	var out []string
+	for _, t := range topics {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
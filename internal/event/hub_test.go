@@ -0,0 +1,68 @@
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeReplay(t *testing.T) {
+	h := NewHub(4, 0)
+	for i := 0; i < 3; i++ {
+		h.Emit("vm:state", i)
+	}
+	last := h.LastID()
+
+	sub, replay := h.Subscribe(nil, last-1)
+	defer sub.Close()
+	if len(replay) != 1 || replay[0].ID != last || replay[0].Topic != "vm:state" {
+		t.Errorf("replay = %+v, want the last event only", replay)
+	}
+}
+
+func TestSubscribeResetAfterRestart(t *testing.T) {
+	before := NewHub(4, 0)
+	before.Emit("vm:state", "old")
+	staleID := before.LastID()
+
+	// 重启后的进程（重启至少间隔数毫秒）
+	time.Sleep(2 * time.Millisecond)
+	h := NewHub(4, 0)
+	h.Emit("vm:state", "a")
+	h.Emit("job:update", "b")
+	if first := h.LastID() - 1; first <= staleID {
+		t.Fatalf("ids after restart (%d) must be greater than before (%d)", first, staleID)
+	}
+
+	for _, lastID := range []uint64{staleID, h.LastID() + 100} {
+		sub, replay := h.Subscribe([]string{"vm:*"}, lastID)
+		sub.Close()
+		if len(replay) != 2 || replay[0].Topic != TopicReset || replay[1].Data != "a" {
+			t.Fatalf("lastID %d: replay = %+v, want reset followed by the buffered vm event", lastID, replay)
+		}
+		if replay[0].ID != replay[1].ID-1 {
+			t.Errorf("lastID %d: reset id = %d, want %d", lastID, replay[0].ID, replay[1].ID-1)
+		}
+	}
+}
+
+func TestSubscribeResetWhenEvicted(t *testing.T) {
+	h := NewHub(2, 0)
+	h.Emit("vm:state", 1)
+	evicted := h.LastID()
+	h.Emit("vm:state", 2)
+	h.Emit("vm:state", 3)
+	h.Emit("vm:state", 4)
+
+	sub, replay := h.Subscribe(nil, evicted)
+	defer sub.Close()
+	if len(replay) != 3 || replay[0].Topic != TopicReset || replay[1].Data != 3 || replay[2].Data != 4 {
+		t.Errorf("replay = %+v, want reset followed by the two buffered events", replay)
+	}
+
+	// 紧接缓冲区最早事件之前的 ID 仍可衔接
+	sub2, replay := h.Subscribe(nil, replay[0].ID)
+	defer sub2.Close()
+	if len(replay) != 2 || replay[0].Topic == TopicReset {
+		t.Errorf("replay from the reset id = %+v, want the two buffered events", replay)
+	}
+}
@@ -0,0 +1,187 @@
+// Package fence 宿主机隔离（fencing）
+//
+// HA 在其他宿主机上重启 VM 之前，必须确认故障宿主机已断电，避免同一块共享磁盘被两个 VM 实例同时写入。
+// 隔离命令在 VMCat 所在机器本地执行：ipmi 调用 ipmitool 通过 BMC 关机，script 运行自定义脚本。
+// 脚本只能位于服务端配置的脚本目录内（vmcat serve --fence-script-dir，默认 ~/.vmcat/fence），
+// 避免通过 API 让管理服务器执行任意本地程序
+package fence
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vmcat/internal/store"
+)
+
+// 隔离方式
+const (
+	MethodIPMI   = "ipmi"   // address, username, password: BMC 地址与凭据
+	MethodScript = "script" // script: 脚本目录内的脚本名称或路径
+	MethodNone   = "none"   // 不隔离，由管理员确认故障宿主机不会恢复（有双写风险）
+)
+
+// 脚本的动作参数（第一个参数，同时通过 VMCAT_FENCE_ACTION 传入）
+const (
+	ActionOff    = "off"    // 关机，退出码 0 表示已隔离
+	ActionStatus = "status" // 查询电源状态，输出 on / off
+)
+
+// timeout 单次隔离的最长时间（含等待断电确认）
+const timeout = 2 * time.Minute
+
+// DefaultScriptDir 默认的隔离脚本目录 ~/.vmcat/fence
+func DefaultScriptDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".vmcat", "fence")
+}
+
+// Fencer 执行宿主机隔离，script 方式只运行 ScriptDir 内的脚本
+type Fencer struct {
+	ScriptDir string // 为空时禁用 script 方式
+}
+
+// Validate 校验隔离配置，script 方式要求脚本位于脚本目录内且可执行
+func (fc *Fencer) Validate(f *store.HostFence) error {
+	switch f.Method {
+	case MethodIPMI:
+		if f.Address == "" || f.Username == "" {
+			return fmt.Errorf("ipmi fencing needs address and username")
+		}
+	case MethodScript:
+		if f.Script == "" {
+			return fmt.Errorf("script fencing needs a script path")
+		}
+		if _, err := fc.scriptPath(f.Script); err != nil {
+			return err
+		}
+	case MethodNone:
+	default:
+		return fmt.Errorf("invalid fence method: %s (ipmi | script | none)", f.Method)
+	}
+	return nil
+}
+
+// Off 隔离宿主机：关机并确认已断电，返回命令输出
+func (fc *Fencer) Off(ctx context.Context, f *store.HostFence, host *store.Host) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch f.Method {
+	case MethodIPMI:
+		out, err := ipmi(ctx, f, "chassis", "power", "off")
+		if err != nil {
+			return out, err
+		}
+		// power off 只下发指令，轮询直到 BMC 报告已断电
+		for {
+			status, err := ipmi(ctx, f, "chassis", "power", "status")
+			if err == nil && strings.Contains(strings.ToLower(status), "is off") {
+				return out + "\n" + status, nil
+			}
+			select {
+			case <-ctx.Done():
+				return out + "\n" + status, fmt.Errorf("host still powered on: %w", ctx.Err())
+			case <-time.After(3 * time.Second):
+			}
+		}
+	case MethodScript:
+		return fc.script(ctx, f, host, ActionOff)
+	case MethodNone:
+		return "fencing disabled", nil
+	}
+	return "", fmt.Errorf("invalid fence method: %s", f.Method)
+}
+
+// Status 查询宿主机电源状态（不执行隔离），用于检查隔离配置是否可用
+func (fc *Fencer) Status(ctx context.Context, f *store.HostFence, host *store.Host) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch f.Method {
+	case MethodIPMI:
+		return ipmi(ctx, f, "chassis", "power", "status")
+	case MethodScript:
+		return fc.script(ctx, f, host, ActionStatus)
+	case MethodNone:
+		return "fencing disabled", nil
+	}
+	return "", fmt.Errorf("invalid fence method: %s", f.Method)
+}
+
+// scriptPath 解析脚本的实际路径（相对路径相对于脚本目录），解析符号链接后必须仍位于脚本目录内
+func (fc *Fencer) scriptPath(script string) (string, error) {
+	if fc.ScriptDir == "" {
+		return "", fmt.Errorf("script fencing is disabled: no fence script directory is configured (vmcat serve --fence-script-dir)")
+	}
+	dir, err := filepath.EvalSymlinks(fc.ScriptDir)
+	if err != nil {
+		return "", fmt.Errorf("fence script directory: %w", err)
+	}
+	if !filepath.IsAbs(script) {
+		script = filepath.Join(fc.ScriptDir, script)
+	}
+	real, err := filepath.EvalSymlinks(script)
+	if err != nil {
+		return "", fmt.Errorf("fence script: %w", err)
+	}
+	if rel, err := filepath.Rel(dir, real); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("fence script %s is outside the fence script directory %s", script, fc.ScriptDir)
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return "", fmt.Errorf("fence script: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("fence script %s is not an executable file", script)
+	}
+	return real, nil
+}
+
+// ipmi 执行 ipmitool，密码通过环境变量传入（-E），不出现在进程参数中
+func ipmi(ctx context.Context, f *store.HostFence, args ...string) (string, error) {
+	full := append([]string{"-I", "lanplus", "-H", f.Address, "-U", f.Username, "-E"}, args...)
+	cmd := exec.CommandContext(ctx, "ipmitool", full...)
+	cmd.Env = append(os.Environ(), "IPMI_PASSWORD="+f.Password)
+	return output(cmd)
+}
+
+// script 运行隔离脚本：script <action> <host address>，宿主机信息同时通过环境变量传入
+// 执行前再次检查脚本目录，目录配置变更后旧的配置不会越界执行
+func (fc *Fencer) script(ctx context.Context, f *store.HostFence, host *store.Host, action string) (string, error) {
+	path, err := fc.scriptPath(f.Script)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, path, action, host.Host)
+	cmd.Env = append(os.Environ(),
+		"VMCAT_FENCE_ACTION="+action,
+		"VMCAT_HOST_ID="+host.ID,
+		"VMCAT_HOST_NAME="+host.Name,
+		"VMCAT_HOST_ADDR="+host.Host,
+	)
+	return output(cmd)
+}
+
+func output(cmd *exec.Cmd) (string, error) {
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err := cmd.Run()
+	out := strings.TrimSpace(buf.String())
+	if err != nil {
+		if out != "" {
+			return out, fmt.Errorf("%s: %w: %s", cmd.Args[0], err, out)
+		}
+		return out, fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return out, nil
+}
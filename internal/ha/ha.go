@@ -0,0 +1,398 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vmcat/internal/fence"
+	"vmcat/internal/job"
+	"vmcat/internal/placement"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// GraceSetting 宿主机不可达多少秒后判定故障（设置项）
+const GraceSetting = "ha_grace_period"
+
+const (
+	defaultGrace  = 180 * time.Second // 默认宽限期
+	checkInterval = 15 * time.Second  // 检查宿主机状态的间隔
+	xmlRefresh    = 10 * time.Minute  // 刷新缓存 XML 的间隔
+	listTimeout   = 30 * time.Second  // 查询单台宿主机 VM 列表的超时
+)
+
+// auditActor 后台监控的操作在审计日志中记为本地用户，与桌面模式一致
+const auditActor = "local"
+
+// Monitor 高可用监控：宿主机持续不可达超过宽限期后隔离并在其他宿主机上重启其高可用 VM
+type Monitor struct {
+	pool      *internalssh.Pool
+	store     *store.Store
+	vms       *vm.Manager
+	jobs      *job.Manager
+	scheduler *placement.Scheduler
+	fencer    *fence.Fencer
+
+	stop      chan struct{}
+	once      sync.Once
+	mu        sync.Mutex
+	attempts  map[string]*attempt // hostID -> 本次故障的故障转移，宿主机恢复后清除
+	conflicts map[string]bool     // 已记录过的双重运行（hostID/vmName），只在监控协程中访问
+	refreshed time.Time           // 上次刷新缓存 XML 的时间，只在监控协程中访问
+}
+
+// attempt 单台宿主机本次故障的故障转移任务
+type attempt struct {
+	jobID  string
+	at     time.Time
+	fenced bool // 已隔离成功，不再重试
+	done   bool // 任务已结束
+}
+
+// Failover 宿主机本次故障的故障转移状态
+type Failover struct {
+	JobID  string
+	At     time.Time
+	Fenced bool
+}
+
+// NewMonitor 创建高可用监控（调用 Start 后开始检查）
+func NewMonitor(pool *internalssh.Pool, s *store.Store, vms *vm.Manager, jobs *job.Manager, scheduler *placement.Scheduler, fencer *fence.Fencer) *Monitor {
+	return &Monitor{
+		pool:      pool,
+		store:     s,
+		vms:       vms,
+		jobs:      jobs,
+		scheduler: scheduler,
+		fencer:    fencer,
+		stop:      make(chan struct{}),
+		attempts:  make(map[string]*attempt),
+		conflicts: make(map[string]bool),
+	}
+}
+
+// Start 启动监控协程
+func (m *Monitor) Start() {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.check(context.Background())
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止监控
+func (m *Monitor) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// Grace 读取宽限期设置
+func (m *Monitor) Grace() time.Duration {
+	val, _ := m.store.SettingGet(GraceSetting)
+	if n, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return defaultGrace
+}
+
+// Failover 返回宿主机本次故障的故障转移状态，未发生时返回 nil
+func (m *Monitor) Failover(hostID string) *Failover {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	at := m.attempts[hostID]
+	if at == nil {
+		return nil
+	}
+	return &Failover{JobID: at.jobID, At: at.at, Fenced: at.fenced}
+}
+
+// Moved VM 迁移成功后更新高可用记录
+func (m *Monitor) Moved(srcHostID, vmName, dstHostID string) {
+	m.store.VMHAMove(srcHostID, vmName, dstHostID, "")
+}
+
+func (m *Monitor) audit(hostID, vmName, action, detail string) {
+	m.store.AuditInsert(auditActor, hostID, vmName, action, detail)
+}
+
+// check 检查一轮：不可达超过宽限期的宿主机启动故障转移，恢复的宿主机清理旧定义，
+// 可用宿主机上的高可用 VM 更新运行状态和缓存 XML
+// 主动断开或未连接的宿主机不判定为故障；所有宿主机均不可达时多半是 VMCat 自身网络故障，也不处理
+func (m *Monitor) check(ctx context.Context) {
+	records, err := m.store.VMHAList("", false)
+	if err != nil || len(records) == 0 {
+		return
+	}
+	hosts, err := m.store.HostList()
+	if err != nil {
+		return
+	}
+	byHost := make(map[string][]store.VMHA)
+	for _, rec := range records {
+		byHost[rec.HostID] = append(byHost[rec.HostID], rec)
+	}
+
+	lists := make(map[string][]vm.VM) // 可用宿主机的 VM 列表
+	for _, h := range hosts {
+		if !m.pool.IsConnected(h.ID) {
+			continue
+		}
+		listCtx, cancel := context.WithTimeout(ctx, listTimeout)
+		vms, err := m.vms.List(listCtx, h.ID)
+		cancel()
+		if err != nil {
+			log.Printf("ha: host %s: %v", h.ID, err)
+			continue
+		}
+		lists[h.ID] = vms
+	}
+
+	grace := m.Grace()
+	for _, h := range hosts {
+		health := m.pool.Health(h.ID)
+		switch health.State {
+		case internalssh.StateConnected, internalssh.StateDegraded:
+			m.mu.Lock()
+			delete(m.attempts, h.ID)
+			m.mu.Unlock()
+			if vms, ok := lists[h.ID]; ok {
+				m.cleanup(ctx, h.ID, vms, records)
+			}
+		case internalssh.StateDown, internalssh.StateReconnecting:
+			if len(byHost[h.ID]) == 0 || len(lists) == 0 || m.store.InMaintenance(h.ID) {
+				continue
+			}
+			since, err := time.ParseInLocation("2006-01-02 15:04:05", health.Since, time.Local)
+			if err != nil || time.Since(since) < grace {
+				continue
+			}
+			m.startFailover(h, health.Since, grace)
+		}
+	}
+
+	refresh := time.Since(m.refreshed) >= xmlRefresh
+	if refresh {
+		m.refreshed = time.Now()
+	}
+	for hostID, vms := range lists {
+		m.track(ctx, hostID, vms, byHost[hostID], lists, refresh)
+	}
+}
+
+// track 更新可用宿主机上高可用 VM 的运行状态，refresh 时重新缓存 XML；
+// VM 已不在该宿主机上（在 VMCat 之外迁移）时按其他宿主机的列表更新所在位置
+func (m *Monitor) track(ctx context.Context, hostID string, vms []vm.VM, records []store.VMHA, lists map[string][]vm.VM, refresh bool) {
+	state := make(map[string]string)
+	for _, v := range vms {
+		state[v.Name] = v.State
+	}
+	for _, rec := range records {
+		s, ok := state[rec.VMName]
+		if !ok {
+			for other, list := range lists {
+				if other != hostID && slices.ContainsFunc(list, func(v vm.VM) bool { return v.Name == rec.VMName }) {
+					log.Printf("ha: %s moved from %s to %s", rec.VMName, hostID, other)
+					m.store.VMHAMove(hostID, rec.VMName, other, "")
+					break
+				}
+			}
+			continue
+		}
+		if running := s == "running"; running != rec.Running {
+			m.store.VMHASetRunning(hostID, rec.VMName, running)
+		}
+		if refresh {
+			xmlContent, err := m.vms.GetXML(ctx, hostID, rec.VMName)
+			if err != nil {
+				log.Printf("ha: %s/%s: refresh XML: %v", hostID, rec.VMName, err)
+				continue
+			}
+			m.store.VMHAUpdateXML(hostID, rec.VMName, xmlContent)
+		}
+	}
+}
+
+// cleanup 故障宿主机恢复后处理其上已转移 VM 的旧定义：未运行的取消自动启动并删除定义（不删磁盘），
+// 仍在运行的（两处同时运行，共享磁盘可能损坏）记录审计日志由管理员处理
+func (m *Monitor) cleanup(ctx context.Context, hostID string, vms []vm.VM, records []store.VMHA) {
+	for _, rec := range records {
+		if rec.FailedFrom != hostID {
+			continue
+		}
+		idx := slices.IndexFunc(vms, func(v vm.VM) bool { return v.Name == rec.VMName })
+		if idx >= 0 && vms[idx].State == "running" {
+			key := hostID + "/" + rec.VMName
+			if !m.conflicts[key] {
+				m.conflicts[key] = true
+				m.audit(hostID, rec.VMName, "ha.conflict", fmt.Sprintf("old definition is running again; VM was restarted on %s", rec.HostID))
+			}
+			continue
+		}
+		if idx >= 0 {
+			m.vms.SetAutostart(ctx, hostID, rec.VMName, false)
+			if err := m.vms.Delete(ctx, hostID, rec.VMName, false); err != nil {
+				log.Printf("ha: %s/%s: remove stale definition: %v", hostID, rec.VMName, err)
+				continue
+			}
+			m.audit(hostID, rec.VMName, "ha.cleanup", fmt.Sprintf("removed stale definition; VM now on %s", rec.HostID))
+		}
+		delete(m.conflicts, hostID+"/"+rec.VMName)
+		m.store.VMHAClearFailed(rec.HostID, rec.VMName)
+	}
+}
+
+// startFailover 为故障宿主机启动故障转移任务；隔离失败的在任务结束且再过一个宽限期后重试
+func (m *Monitor) startFailover(h store.Host, since string, grace time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if at := m.attempts[h.ID]; at != nil && (at.fenced || !at.done || time.Since(at.at) < grace) {
+		return
+	}
+	at := &attempt{at: time.Now()}
+	j := m.jobs.Start("ha.failover", h.ID, "", func(ctx context.Context, r *job.Run) error {
+		err := m.failover(ctx, r, h, since, at)
+		m.mu.Lock()
+		at.done = true
+		m.mu.Unlock()
+		return err
+	})
+	at.jobID = j.ID
+	m.attempts[h.ID] = at
+}
+
+// failover 隔离故障宿主机，确认断电后在其他宿主机上按缓存的 XML 重新定义并启动其高可用 VM
+func (m *Monitor) failover(ctx context.Context, r *job.Run, h store.Host, since string, at *attempt) error {
+	m.audit(h.ID, "", "ha.host_down", "unreachable since "+since)
+	r.Log("host %s unreachable since %s", h.Name, since)
+
+	f, err := m.store.HostFenceGet(h.ID)
+	if err != nil {
+		m.audit(h.ID, "", "ha.fence_failed", "no fencing configured")
+		return fmt.Errorf("no fencing configured for host %s, HA VMs not restarted", h.Name)
+	}
+	r.Progress(5, "fencing")
+	r.Log("fencing %s via %s", h.Name, f.Method)
+	out, err := m.fencer.Off(ctx, f, &h)
+	if out != "" {
+		r.Log("%s", out)
+	}
+	if err != nil {
+		m.audit(h.ID, "", "ha.fence_failed", err.Error())
+		return fmt.Errorf("fence host %s: %w", h.Name, err)
+	}
+	m.audit(h.ID, "", "ha.fence", f.Method)
+	m.mu.Lock()
+	at.fenced = true
+	m.mu.Unlock()
+
+	// 未真正断电（method none）时宿主机可能已恢复，VM 仍在原处运行
+	if m.pool.IsConnected(h.ID) {
+		m.audit(h.ID, "", "ha.aborted", "host came back")
+		return fmt.Errorf("host %s came back, HA VMs not restarted", h.Name)
+	}
+
+	records, err := m.store.VMHAList(h.ID, true)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i, rec := range records {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r.Progress(10+85*i/len(records), rec.VMName)
+		dst, err := m.restart(ctx, r, h.ID, rec)
+		if err != nil {
+			failed++
+			r.Log("%s: %v", rec.VMName, err)
+			m.audit(h.ID, rec.VMName, "ha.restart_failed", err.Error())
+			continue
+		}
+		r.Log("%s: restarted on %s", rec.VMName, dst)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d HA VMs could not be restarted", failed, len(records))
+	}
+	return nil
+}
+
+// definedOn 返回已定义同名 VM 的可用宿主机（exclude 除外），没有时返回空
+func (m *Monitor) definedOn(ctx context.Context, vmName, exclude string) string {
+	hosts, err := m.store.HostList()
+	if err != nil {
+		return ""
+	}
+	for _, h := range hosts {
+		if h.ID == exclude || !m.pool.IsConnected(h.ID) {
+			continue
+		}
+		vms, err := m.vms.List(ctx, h.ID)
+		if err == nil && slices.ContainsFunc(vms, func(v vm.VM) bool { return v.Name == vmName }) {
+			return h.Name
+		}
+	}
+	return ""
+}
+
+// restart 调度并在选中的宿主机上定义 VM，原本运行中的再启动，返回目标宿主机名称
+func (m *Monitor) restart(ctx context.Context, r *job.Run, srcHostID string, rec store.VMHA) (string, error) {
+	if rec.XML == "" {
+		return "", fmt.Errorf("no cached domain XML")
+	}
+	domain, err := vm.ParseDomainXML(rec.XML)
+	if err != nil {
+		return "", err
+	}
+	req := placement.ScheduleRequest{VMName: rec.VMName, VCPUs: domain.VCPU, MemoryMB: int64(domain.Memory.MB())}
+	for _, iface := range domain.Devices.Interfaces {
+		switch {
+		case iface.Source.Network != "":
+			req.Networks = append(req.Networks, iface.Source.Network)
+		case iface.Source.Bridge != "":
+			req.Bridges = append(req.Bridges, iface.Source.Bridge)
+		}
+	}
+	req.Groups, _ = m.store.PlacementGroupsOf(rec.VMName)
+	if other := m.definedOn(ctx, rec.VMName, srcHostID); other != "" {
+		return "", fmt.Errorf("a VM with this name already exists on %s", other)
+	}
+
+	d, err := m.scheduler.Schedule(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if d.HostID == "" {
+		return "", fmt.Errorf("%s", d.Reason)
+	}
+	r.Log("%s: %s", rec.VMName, d.Reason)
+
+	if err := m.vms.DefineXML(ctx, d.HostID, rec.XML); err != nil {
+		return "", fmt.Errorf("define on %s: %w", d.HostName, err)
+	}
+	if err := m.store.VMHAMove(srcHostID, rec.VMName, d.HostID, srcHostID); err != nil {
+		return "", err
+	}
+	detail := fmt.Sprintf("from %s: %s", srcHostID, d.Reason)
+	if !rec.Running {
+		m.audit(d.HostID, rec.VMName, "ha.restart", detail+" (defined only, was not running)")
+		return d.HostName, nil
+	}
+	if err := m.vms.Start(ctx, d.HostID, rec.VMName); err != nil {
+		return "", fmt.Errorf("start on %s: %w", d.HostName, err)
+	}
+	m.audit(d.HostID, rec.VMName, "ha.restart", detail)
+	return d.HostName, nil
+}
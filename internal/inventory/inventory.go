@@ -0,0 +1,308 @@
+// Package inventory 将宿主机和 VM 导出为 Ansible 动态清单和 ssh_config
+//
+// 只做格式转换：调用方采集宿主机、VM 及其 IP、flavor 和镜像，Build 生成
+// ansible-inventory --list 格式的清单，SSHConfig 由清单生成经宿主机 ProxyJump 访问 VM 的 ssh_config
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 固定的组
+const (
+	GroupHosts = "vmcat_hosts" // KVM 宿主机
+	GroupVMs   = "vmcat_vms"   // 全部 VM
+)
+
+// Host 宿主机
+type Host struct {
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	User    string
+	Tags    []string
+}
+
+// VM 虚拟机，IPs 来自 domifaddr，Flavor 和 Image 只对基于模板创建的 VM 已知
+type VM struct {
+	HostID   string
+	Name     string
+	State    string
+	CPUs     int
+	MemoryMB int
+	IPs      []string
+	Flavor   string
+	Image    string
+}
+
+// Data 采集结果，VMs 只含已连接宿主机上的 VM
+type Data struct {
+	Hosts []Host
+	VMs   []VM
+}
+
+// Group 清单中的组
+type Group struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+// HostVars 清单主机的变量，ansible_* 供 Ansible 连接，vmcat_* 供 playbook 和 ssh_config 使用
+type HostVars struct {
+	AnsibleHost   string   `json:"ansible_host,omitempty"`
+	AnsiblePort   int      `json:"ansible_port,omitempty"`
+	AnsibleUser   string   `json:"ansible_user,omitempty"`
+	SSHCommonArgs string   `json:"ansible_ssh_common_args,omitempty"` // VM: 经宿主机 ProxyJump
+	HostID        string   `json:"vmcat_host_id"`
+	Host          string   `json:"vmcat_host"`                 // 宿主机（VM 所在宿主机）的清单名称
+	ProxyJump     string   `json:"vmcat_proxy_jump,omitempty"` // VM: user@address:port
+	VM            string   `json:"vmcat_vm,omitempty"`         // VM: libvirt 中的名称
+	State         string   `json:"vmcat_state,omitempty"`
+	CPUs          int      `json:"vmcat_cpus,omitempty"`
+	MemoryMB      int      `json:"vmcat_memory_mb,omitempty"`
+	Flavor        string   `json:"vmcat_flavor,omitempty"`
+	Image         string   `json:"vmcat_image,omitempty"`
+	IPs           []string `json:"vmcat_ips,omitempty"`
+	Tags          []string `json:"vmcat_tags,omitempty"`
+}
+
+// Meta 清单的 _meta 部分，Ansible 据此不再逐台调用 --host
+type Meta struct {
+	HostVars map[string]*HostVars `json:"hostvars"`
+}
+
+// Ansible ansible-inventory --list 格式的清单：组名 -> *Group，"_meta" -> *Meta
+type Ansible map[string]interface{}
+
+// Build 生成清单。VM 以名称为清单名称，与其它 VM 或宿主机重名时使用 <宿主机>-<VM>；
+// user 非空时作为 VM 的 ansible_user
+// 分组：vmcat_hosts、vmcat_vms、host_<宿主机>、tag_<宿主机标签>、state_<状态>、flavor_<flavor>、image_<镜像>
+func Build(d *Data, user string) Ansible {
+	meta := &Meta{HostVars: make(map[string]*HostVars)}
+	groups := make(map[string]*Group)
+	add := func(group, name string) {
+		group = groupName(group)
+		g := groups[group]
+		if g == nil {
+			g = &Group{}
+			groups[group] = g
+		}
+		g.Hosts = append(g.Hosts, name)
+	}
+
+	hosts := make(map[string]Host)
+	taken := make(map[string]int)
+	for _, h := range d.Hosts {
+		hosts[h.ID] = h
+		taken[h.Name]++
+	}
+	for _, v := range d.VMs {
+		taken[v.Name]++
+	}
+
+	for _, h := range d.Hosts {
+		meta.HostVars[h.Name] = &HostVars{
+			AnsibleHost: h.Address,
+			AnsiblePort: h.Port,
+			AnsibleUser: h.User,
+			HostID:      h.ID,
+			Host:        h.Name,
+			Tags:        h.Tags,
+		}
+		add(GroupHosts, h.Name)
+	}
+
+	for _, v := range d.VMs {
+		h, ok := hosts[v.HostID]
+		if !ok {
+			continue
+		}
+		name := v.Name
+		if taken[name] > 1 {
+			name = h.Name + "-" + v.Name
+		}
+		jump := fmt.Sprintf("%s@%s:%d", h.User, h.Address, h.Port)
+		hv := &HostVars{
+			AnsibleUser:   user,
+			SSHCommonArgs: "-o ProxyJump=" + jump,
+			HostID:        h.ID,
+			Host:          h.Name,
+			ProxyJump:     jump,
+			VM:            v.Name,
+			State:         v.State,
+			CPUs:          v.CPUs,
+			MemoryMB:      v.MemoryMB,
+			Flavor:        v.Flavor,
+			Image:         v.Image,
+			IPs:           v.IPs,
+			Tags:          h.Tags,
+		}
+		if len(v.IPs) > 0 {
+			hv.AnsibleHost = v.IPs[0]
+		}
+		meta.HostVars[name] = hv
+
+		add(GroupVMs, name)
+		add("host_"+h.Name, name)
+		for _, tag := range h.Tags {
+			add("tag_"+tag, name)
+		}
+		if v.State != "" {
+			add("state_"+v.State, name)
+		}
+		if v.Flavor != "" {
+			add("flavor_"+v.Flavor, name)
+		}
+		if v.Image != "" {
+			add("image_"+v.Image, name)
+		}
+	}
+
+	return assemble(groups, meta)
+}
+
+// assemble 组装清单，组内主机排序，all 以全部组为子组
+func assemble(groups map[string]*Group, meta *Meta) Ansible {
+	inv := Ansible{"_meta": meta}
+	names := make([]string, 0, len(groups))
+	for name, g := range groups {
+		sort.Strings(g.Hosts)
+		inv[name] = g
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	inv["all"] = &Group{Children: names}
+	return inv
+}
+
+// groupName Ansible 组名只允许字母、数字和下划线
+func groupName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// Filter 只保留 keep 返回 true 的主机（宿主机的 vmName 为空），并去掉因此变空的组
+func (inv Ansible) Filter(keep func(hostID, vmName string) bool) Ansible {
+	meta, ok := inv["_meta"].(*Meta)
+	if !ok {
+		return inv
+	}
+	kept := &Meta{HostVars: make(map[string]*HostVars)}
+	for name, hv := range meta.HostVars {
+		if keep(hv.HostID, hv.VM) {
+			kept.HostVars[name] = hv
+		}
+	}
+	groups := make(map[string]*Group)
+	for name, v := range inv {
+		g, ok := v.(*Group)
+		if !ok || name == "all" {
+			continue
+		}
+		var hosts []string
+		for _, h := range g.Hosts {
+			if kept.HostVars[h] != nil {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(hosts) > 0 {
+			groups[name] = &Group{Hosts: hosts}
+		}
+	}
+	return assemble(groups, kept)
+}
+
+// Parse 解析 Build 生成的清单 JSON（如客户端收到的结果）
+func Parse(data []byte) (Ansible, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	inv := Ansible{}
+	for name, v := range raw {
+		if name == "_meta" {
+			meta := &Meta{}
+			if err := json.Unmarshal(v, meta); err != nil {
+				return nil, fmt.Errorf("_meta: %w", err)
+			}
+			inv[name] = meta
+			continue
+		}
+		g := &Group{}
+		if err := json.Unmarshal(v, g); err != nil {
+			return nil, fmt.Errorf("group %s: %w", name, err)
+		}
+		inv[name] = g
+	}
+	if _, ok := inv["_meta"]; !ok {
+		inv["_meta"] = &Meta{HostVars: map[string]*HostVars{}}
+	}
+	return inv, nil
+}
+
+// HostVarsOf 返回清单主机的变量（ansible --host 的输出），不存在时返回 nil
+func (inv Ansible) HostVarsOf(name string) *HostVars {
+	if meta, ok := inv["_meta"].(*Meta); ok {
+		return meta.HostVars[name]
+	}
+	return nil
+}
+
+// SSHConfig 生成 ssh_config：每台宿主机一个 Host 段，VM 经所在宿主机的 Host 段 ProxyJump，
+// 宿主机不在清单中时直接使用 user@address:port；没有 IP 的 VM 只输出注释
+func SSHConfig(inv Ansible) string {
+	meta, _ := inv["_meta"].(*Meta)
+	if meta == nil {
+		return ""
+	}
+	var hostNames, vmNames []string
+	for name, hv := range meta.HostVars {
+		if hv.VM == "" {
+			hostNames = append(hostNames, name)
+		} else {
+			vmNames = append(vmNames, name)
+		}
+	}
+	sort.Strings(hostNames)
+	sort.Strings(vmNames)
+
+	var b strings.Builder
+	b.WriteString("# Generated by vmcat: KVM hosts and the VMs on them, reached through their host (ProxyJump)\n")
+	for _, name := range hostNames {
+		hv := meta.HostVars[name]
+		fmt.Fprintf(&b, "\nHost %s\n    HostName %s\n", name, hv.AnsibleHost)
+		if hv.AnsiblePort != 0 {
+			fmt.Fprintf(&b, "    Port %d\n", hv.AnsiblePort)
+		}
+		if hv.AnsibleUser != "" {
+			fmt.Fprintf(&b, "    User %s\n", hv.AnsibleUser)
+		}
+	}
+	for _, name := range vmNames {
+		hv := meta.HostVars[name]
+		if hv.AnsibleHost == "" {
+			fmt.Fprintf(&b, "\n# %s (on %s): no IP address\n", name, hv.Host)
+			continue
+		}
+		jump := hv.ProxyJump
+		if h := meta.HostVars[hv.Host]; h != nil && h.VM == "" {
+			jump = hv.Host
+		}
+		fmt.Fprintf(&b, "\nHost %s\n    HostName %s\n    ProxyJump %s\n", name, hv.AnsibleHost, jump)
+		if hv.AnsibleUser != "" {
+			fmt.Fprintf(&b, "    User %s\n", hv.AnsibleUser)
+		}
+	}
+	return b.String()
+}
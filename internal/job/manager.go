@@ -0,0 +1,387 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"vmcat/internal/event"
+	"vmcat/internal/store"
+)
+
+// 任务状态
+const (
+	StatePending     = "pending"
+	StateRunning     = "running"
+	StateSucceeded   = "succeeded"
+	StateFailed      = "failed"
+	StateCanceled    = "canceled"
+	StateInterrupted = "interrupted"
+)
+
+const (
+	maxLogLines   = 200             // 每个任务保留的最大日志行数
+	flushInterval = time.Second     // 进度持久化与推送的最小间隔
+	retainDays    = 7               // 已结束任务的保留天数
+	evictAfter    = 5 * time.Minute // 已结束任务在内存中的保留时间
+)
+
+// Func 任务执行函数，ctx 在任务被取消时结束
+type Func func(ctx context.Context, r *Run) error
+
+// Manager 后台任务管理器
+type Manager struct {
+	mu      sync.Mutex
+	store   *store.Store
+	emitter event.Emitter
+	active  map[string]*Run
+}
+
+// NewManager 创建任务管理器（store/emitter 在初始化完成后注入）
+func NewManager() *Manager {
+	return &Manager{
+		emitter: &event.NoopEmitter{},
+		active:  make(map[string]*Run),
+	}
+}
+
+// SetStore 设置持久化存储，并将上次进程遗留的未完成任务标记为中断
+func (m *Manager) SetStore(s *store.Store) {
+	m.mu.Lock()
+	m.store = s
+	m.mu.Unlock()
+
+	if s == nil {
+		return
+	}
+	if n, err := s.JobMarkInterrupted(); err != nil {
+		log.Printf("job: mark interrupted: %v", err)
+	} else if n > 0 {
+		log.Printf("job: %d unfinished jobs marked as interrupted", n)
+	}
+	s.JobCleanup(retainDays)
+}
+
+// SetEmitter 设置事件发射器
+func (m *Manager) SetEmitter(e event.Emitter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emitter = e
+}
+
+// Start 创建并异步执行任务，立即返回任务快照
+func (m *Manager) Start(typ, hostID, vmName string, fn Func) *store.Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now().Format("2006-01-02 15:04:05")
+	r := &Run{
+		manager: m,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		job: store.Job{
+			ID:        uuid.New().String(),
+			Type:      typ,
+			HostID:    hostID,
+			VMName:    vmName,
+			State:     StatePending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+
+	m.mu.Lock()
+	m.active[r.job.ID] = r
+	m.mu.Unlock()
+	r.flush(true)
+
+	go r.execute(ctx, fn)
+	return r.Snapshot()
+}
+
+// Wait 等待任务结束，返回任务错误
+func (m *Manager) Wait(id string) error {
+	m.mu.Lock()
+	r, ok := m.active[id]
+	m.mu.Unlock()
+	if !ok {
+		j, err := m.Get(id)
+		if err != nil {
+			return err
+		}
+		return jobErr(j)
+	}
+	<-r.done
+	return jobErr(r.Snapshot())
+}
+
+// Get 获取任务详情（内存中的活跃任务优先）
+func (m *Manager) Get(id string) (*store.Job, error) {
+	m.mu.Lock()
+	r, ok := m.active[id]
+	s := m.store
+	m.mu.Unlock()
+	if ok {
+		return r.Snapshot(), nil
+	}
+	if s == nil {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	j, err := s.JobGet(id)
+	if err != nil {
+		return nil, fmt.Errorf("job %s not found: %w", id, err)
+	}
+	return j, nil
+}
+
+// List 获取任务列表，hostID/state 为空则不过滤
+func (m *Manager) List(hostID, state string, limit int) ([]store.Job, error) {
+	m.mu.Lock()
+	s := m.store
+	runs := make([]*Run, 0, len(m.active))
+	for _, r := range m.active {
+		runs = append(runs, r)
+	}
+	m.mu.Unlock()
+
+	// 无持久化时仅返回内存中的任务
+	if s == nil {
+		var list []store.Job
+		for _, r := range runs {
+			j := r.Snapshot()
+			if (hostID == "" || j.HostID == hostID) && (state == "" || j.State == state) {
+				list = append(list, *j)
+			}
+		}
+		return list, nil
+	}
+
+	list, err := s.JobList(hostID, state, limit)
+	if err != nil {
+		return nil, err
+	}
+	// 用内存中的最新进度覆盖数据库快照
+	latest := make(map[string]*store.Job, len(runs))
+	for _, r := range runs {
+		latest[r.job.ID] = r.Snapshot()
+	}
+	for i := range list {
+		if j, ok := latest[list[i].ID]; ok {
+			list[i] = *j
+		}
+	}
+	return list, nil
+}
+
+// Cancel 取消任务
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	r, ok := m.active[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("job %s is not running", id)
+	}
+	r.Log("cancel requested")
+	r.cancel()
+	return nil
+}
+
+// CancelAll 取消所有活跃任务（退出时调用）
+func (m *Manager) CancelAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.active {
+		r.cancel()
+	}
+}
+
+func (m *Manager) persist(j *store.Job) {
+	m.mu.Lock()
+	s := m.store
+	emitter := m.emitter
+	m.mu.Unlock()
+
+	if s != nil {
+		if err := s.JobSave(j); err != nil {
+			log.Printf("job: save %s: %v", j.ID, err)
+		}
+	}
+	emitter.Emit("job:update", j)
+}
+
+func (m *Manager) evict(id string) {
+	time.AfterFunc(evictAfter, func() {
+		m.mu.Lock()
+		delete(m.active, id)
+		m.mu.Unlock()
+	})
+}
+
+// jobErr 将已结束任务的状态转换为 error
+func jobErr(j *store.Job) error {
+	switch j.State {
+	case StateSucceeded:
+		return nil
+	case StateCanceled:
+		return context.Canceled
+	default:
+		if j.Error != "" {
+			return errors.New(j.Error)
+		}
+		return fmt.Errorf("job %s", j.State)
+	}
+}
+
+// Run 运行中的任务句柄，供任务函数上报进度
+type Run struct {
+	manager   *Manager
+	cancel    context.CancelFunc
+	done      chan struct{}
+	mu        sync.Mutex
+	job       store.Job
+	lastFlush time.Time
+	version   uint64 // 每次 flush 取的快照序号，由 mu 保护
+
+	pmu       sync.Mutex // 串行化同一任务的持久化
+	persisted uint64     // 已写入的最新快照序号，由 pmu 保护
+}
+
+// ID 任务 ID
+func (r *Run) ID() string {
+	return r.job.ID
+}
+
+// Progress 更新进度 (0-100) 和当前步骤描述
+func (r *Run) Progress(percent int, message string) {
+	r.mu.Lock()
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	r.job.Progress = percent
+	if message != "" {
+		r.job.Message = message
+	}
+	r.mu.Unlock()
+	r.flush(false)
+}
+
+// ProgressBytes 按已处理/总数据量（字节）更新进度，total 为 0 时只记录已处理量
+func (r *Run) ProgressBytes(current, total int64, message string) {
+	r.mu.Lock()
+	r.job.Current = current
+	r.job.Total = total
+	percent := r.job.Progress
+	if total > 0 {
+		percent = int(current * 100 / total)
+	}
+	r.mu.Unlock()
+	r.Progress(percent, message)
+}
+
+// Log 追加任务日志
+func (r *Run) Log(format string, args ...interface{}) {
+	line := time.Now().Format("15:04:05") + " " + fmt.Sprintf(format, args...)
+	r.mu.Lock()
+	r.job.Logs = append(r.job.Logs, line)
+	if len(r.job.Logs) > maxLogLines {
+		r.job.Logs = r.job.Logs[len(r.job.Logs)-maxLogLines:]
+	}
+	r.mu.Unlock()
+	r.flush(false)
+}
+
+// SetResult 设置任务结果（JSON 序列化后持久化）
+func (r *Run) SetResult(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	r.job.Result = data
+	r.mu.Unlock()
+}
+
+// Snapshot 返回任务当前状态的副本
+func (r *Run) Snapshot() *store.Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j := r.job
+	j.Logs = append([]string(nil), r.job.Logs...)
+	return &j
+}
+
+func (r *Run) execute(ctx context.Context, fn Func) {
+	defer close(r.done)
+	defer r.cancel()
+
+	r.setState(StateRunning, "")
+
+	var err error
+	func() {
+		// 任务 panic 不应拖垮整个进程
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		err = fn(ctx, r)
+	}()
+
+	switch {
+	case err == nil:
+		r.mu.Lock()
+		r.job.Progress = 100
+		r.mu.Unlock()
+		r.setState(StateSucceeded, "")
+	case ctx.Err() != nil:
+		r.setState(StateCanceled, err.Error())
+	default:
+		r.setState(StateFailed, err.Error())
+	}
+	r.manager.evict(r.job.ID)
+}
+
+func (r *Run) setState(state, errMsg string) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	r.mu.Lock()
+	r.job.State = state
+	r.job.Error = errMsg
+	switch state {
+	case StateSucceeded, StateFailed, StateCanceled:
+		r.job.FinishedAt = now
+	}
+	r.mu.Unlock()
+	r.flush(true)
+}
+
+// flush 持久化并推送 job:update，非强制时按 flushInterval 节流
+// 并发的 flush（如取消时的日志与任务结束时的状态）按快照先后写入，较旧的快照不会覆盖已写入的新状态
+func (r *Run) flush(force bool) {
+	r.mu.Lock()
+	if !force && time.Since(r.lastFlush) < flushInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastFlush = time.Now()
+	r.job.UpdatedAt = r.lastFlush.Format("2006-01-02 15:04:05")
+	r.version++
+	version := r.version
+	j := r.job
+	j.Logs = append([]string(nil), r.job.Logs...)
+	r.mu.Unlock()
+
+	r.pmu.Lock()
+	defer r.pmu.Unlock()
+	if version < r.persisted {
+		return
+	}
+	r.persisted = version
+	r.manager.persist(&j)
+}
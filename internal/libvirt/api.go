@@ -0,0 +1,297 @@
+package libvirt
+
+import (
+	"context"
+)
+
+// Domain 域引用 (remote_nonnull_domain)，ID 为 -1 表示未运行
+type Domain struct {
+	Name string
+	UUID [16]byte
+	ID   int32
+}
+
+// StoragePool 存储池引用 (remote_nonnull_storage_pool)
+type StoragePool struct {
+	Name string
+	UUID [16]byte
+}
+
+// DomainInfo 域基本信息，内存单位 KiB
+type DomainInfo struct {
+	State     uint8
+	MaxMem    uint64
+	Memory    uint64
+	NrVirtCPU uint16
+	CPUTime   uint64
+}
+
+// StoragePoolInfo 存储池信息，容量单位字节
+type StoragePoolInfo struct {
+	State      uint8
+	Capacity   uint64
+	Allocation uint64
+	Available  uint64
+}
+
+// DomainInterface 域网卡地址信息
+type DomainInterface struct {
+	Name   string
+	HWAddr string
+	Addrs  []DomainIPAddr
+}
+
+// DomainIPAddr 网卡 IP 地址
+type DomainIPAddr struct {
+	Type   int32 // 0: IPv4, 1: IPv6
+	Addr   string
+	Prefix uint32
+}
+
+// 域状态 (virDomainState)
+const (
+	DomainNoState     = 0
+	DomainRunning     = 1
+	DomainBlocked     = 2
+	DomainPaused      = 3
+	DomainShutdown    = 4
+	DomainShutoff     = 5
+	DomainCrashed     = 6
+	DomainPMSuspended = 7
+)
+
+// ConnectListAllDomains 标志 (virConnectListAllDomainsFlags)
+const (
+	ListDomainsActive     = 1 << 0
+	ListDomainsInactive   = 1 << 1
+	ListDomainsPersistent = 1 << 2
+	ListDomainsAutostart  = 1 << 10
+)
+
+// ConnectListAllStoragePools 标志 (virConnectListAllStoragePoolsFlags)
+const (
+	ListPoolsInactive   = 1 << 0
+	ListPoolsActive     = 1 << 1
+	ListPoolsPersistent = 1 << 2
+	ListPoolsAutostart  = 1 << 4
+)
+
+// 存储池状态 (virStoragePoolState)
+const (
+	PoolInactive     = 0
+	PoolBuilding     = 1
+	PoolRunning      = 2
+	PoolDegraded     = 3
+	PoolInaccessible = 4
+)
+
+// ListAllDomains 列出域，flags 为 0 时返回全部
+func (c *Conn) ListAllDomains(ctx context.Context, flags uint32) ([]Domain, error) {
+	var e encoder
+	e.int32(1) // need_results
+	e.uint32(flags)
+	body, err := c.call(ctx, procConnectListAllDomains, e.bytes())
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(body)
+	n := int(d.uint32())
+	doms := make([]Domain, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		doms = append(doms, d.domain())
+	}
+	return doms, d.err
+}
+
+// DomainLookupByName 按名称查找域
+func (c *Conn) DomainLookupByName(ctx context.Context, name string) (Domain, error) {
+	var e encoder
+	e.string(name)
+	body, err := c.call(ctx, procDomainLookupByName, e.bytes())
+	if err != nil {
+		return Domain{}, err
+	}
+	d := newDecoder(body)
+	dom := d.domain()
+	return dom, d.err
+}
+
+// DomainGetInfo 获取域状态、内存和 vCPU 数
+func (c *Conn) DomainGetInfo(ctx context.Context, dom Domain) (DomainInfo, error) {
+	var e encoder
+	e.domain(dom)
+	body, err := c.call(ctx, procDomainGetInfo, e.bytes())
+	if err != nil {
+		return DomainInfo{}, err
+	}
+	d := newDecoder(body)
+	info := DomainInfo{
+		State:     uint8(d.uint32()),
+		MaxMem:    d.uint64(),
+		Memory:    d.uint64(),
+		NrVirtCPU: uint16(d.uint32()),
+		CPUTime:   d.uint64(),
+	}
+	return info, d.err
+}
+
+// DomainGetXMLDesc 获取域 XML
+func (c *Conn) DomainGetXMLDesc(ctx context.Context, dom Domain, flags uint32) (string, error) {
+	var e encoder
+	e.domain(dom)
+	e.uint32(flags)
+	body, err := c.call(ctx, procDomainGetXMLDesc, e.bytes())
+	if err != nil {
+		return "", err
+	}
+	d := newDecoder(body)
+	xml := d.string()
+	return xml, d.err
+}
+
+// DomainGetAutostart 获取域是否自动启动
+func (c *Conn) DomainGetAutostart(ctx context.Context, dom Domain) (bool, error) {
+	var e encoder
+	e.domain(dom)
+	body, err := c.call(ctx, procDomainGetAutostart, e.bytes())
+	if err != nil {
+		return false, err
+	}
+	d := newDecoder(body)
+	autostart := d.int32() != 0
+	return autostart, d.err
+}
+
+// DomainSetAutostart 设置域自动启动
+func (c *Conn) DomainSetAutostart(ctx context.Context, dom Domain, autostart bool) error {
+	var e encoder
+	e.domain(dom)
+	if autostart {
+		e.int32(1)
+	} else {
+		e.int32(0)
+	}
+	_, err := c.call(ctx, procDomainSetAutostart, e.bytes())
+	return err
+}
+
+// DomainDefineXML 定义或更新域
+func (c *Conn) DomainDefineXML(ctx context.Context, xml string) (Domain, error) {
+	var e encoder
+	e.string(xml)
+	body, err := c.call(ctx, procDomainDefineXML, e.bytes())
+	if err != nil {
+		return Domain{}, err
+	}
+	d := newDecoder(body)
+	dom := d.domain()
+	return dom, d.err
+}
+
+// DomainCreate 启动已定义的域
+func (c *Conn) DomainCreate(ctx context.Context, dom Domain) error {
+	return c.domainCall(ctx, procDomainCreate, dom)
+}
+
+// DomainShutdown 发送 ACPI 关机请求
+func (c *Conn) DomainShutdown(ctx context.Context, dom Domain) error {
+	return c.domainCall(ctx, procDomainShutdown, dom)
+}
+
+// DomainDestroy 强制关闭域
+func (c *Conn) DomainDestroy(ctx context.Context, dom Domain) error {
+	return c.domainCall(ctx, procDomainDestroy, dom)
+}
+
+// DomainSuspend 暂停域
+func (c *Conn) DomainSuspend(ctx context.Context, dom Domain) error {
+	return c.domainCall(ctx, procDomainSuspend, dom)
+}
+
+// DomainResume 恢复域
+func (c *Conn) DomainResume(ctx context.Context, dom Domain) error {
+	return c.domainCall(ctx, procDomainResume, dom)
+}
+
+// DomainReboot 重启域
+func (c *Conn) DomainReboot(ctx context.Context, dom Domain, flags uint32) error {
+	var e encoder
+	e.domain(dom)
+	e.uint32(flags)
+	_, err := c.call(ctx, procDomainReboot, e.bytes())
+	return err
+}
+
+// DomainInterfaceAddresses 获取域网卡地址，source 0 为 DHCP 租约
+func (c *Conn) DomainInterfaceAddresses(ctx context.Context, dom Domain, source uint32) ([]DomainInterface, error) {
+	var e encoder
+	e.domain(dom)
+	e.uint32(source)
+	e.uint32(0) // flags
+	body, err := c.call(ctx, procDomainInterfaceAddresses, e.bytes())
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(body)
+	n := int(d.uint32())
+	ifaces := make([]DomainInterface, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		iface := DomainInterface{
+			Name:   d.string(),
+			HWAddr: d.optString(),
+		}
+		m := int(d.uint32())
+		for j := 0; j < m && d.err == nil; j++ {
+			iface.Addrs = append(iface.Addrs, DomainIPAddr{
+				Type:   d.int32(),
+				Addr:   d.string(),
+				Prefix: d.uint32(),
+			})
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, d.err
+}
+
+// ListAllStoragePools 列出存储池，flags 为 0 时返回全部
+func (c *Conn) ListAllStoragePools(ctx context.Context, flags uint32) ([]StoragePool, error) {
+	var e encoder
+	e.int32(1) // need_results
+	e.uint32(flags)
+	body, err := c.call(ctx, procConnectListAllStoragePools, e.bytes())
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(body)
+	n := int(d.uint32())
+	pools := make([]StoragePool, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		pools = append(pools, d.pool())
+	}
+	return pools, d.err
+}
+
+// StoragePoolGetInfo 获取存储池状态和容量
+func (c *Conn) StoragePoolGetInfo(ctx context.Context, pool StoragePool) (StoragePoolInfo, error) {
+	var e encoder
+	e.pool(pool)
+	body, err := c.call(ctx, procStoragePoolGetInfo, e.bytes())
+	if err != nil {
+		return StoragePoolInfo{}, err
+	}
+	d := newDecoder(body)
+	info := StoragePoolInfo{
+		State:      uint8(d.uint32()),
+		Capacity:   d.uint64(),
+		Allocation: d.uint64(),
+		Available:  d.uint64(),
+	}
+	return info, d.err
+}
+
+func (c *Conn) domainCall(ctx context.Context, proc uint32, dom Domain) error {
+	var e encoder
+	e.domain(dom)
+	_, err := c.call(ctx, proc, e.bytes())
+	return err
+}
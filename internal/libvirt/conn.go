@@ -0,0 +1,257 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// libvirt 远程协议 (remote_protocol.x) 的最小 Go 实现
+// 通过任意 net.Conn（如 SSH 转发的 /var/run/libvirt/libvirt-sock）与 libvirtd 通信
+
+const (
+	remoteProgram   = 0x20008086
+	protocolVersion = 1
+	headerSize      = 28 // 含 4 字节长度字段
+	maxPacketSize   = 32 << 20
+)
+
+// 消息类型与状态
+const (
+	typeCall    = 0
+	typeReply   = 1
+	statusOK    = 0
+	statusError = 1
+)
+
+// 过程号 (remote_procedure)
+const (
+	procConnectOpen                = 1
+	procConnectClose               = 2
+	procDomainCreate               = 9
+	procDomainDefineXML            = 11
+	procDomainDestroy              = 12
+	procDomainGetXMLDesc           = 14
+	procDomainGetAutostart         = 15
+	procDomainGetInfo              = 16
+	procDomainLookupByName         = 23
+	procDomainReboot               = 27
+	procDomainResume               = 28
+	procDomainSetAutostart         = 29
+	procDomainShutdown             = 33
+	procDomainSuspend              = 34
+	procStoragePoolGetInfo         = 87
+	procConnectListAllDomains      = 273
+	procConnectListAllStoragePools = 281
+	procDomainInterfaceAddresses   = 353
+)
+
+// 错误码 (virErrorNumber)
+const (
+	ErrNoDomain = 42 // VIR_ERR_NO_DOMAIN 域不存在
+)
+
+// Error libvirtd 返回的错误 (remote_error)
+type Error struct {
+	Code    int32
+	Domain  int32
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("libvirt error code %d (domain %d)", e.Code, e.Domain)
+}
+
+// closeTimeout Close 等待 procConnectClose 应答的上限，libvirtd 无响应时直接关闭传输
+const closeTimeout = 5 * time.Second
+
+// Conn libvirt 远程协议连接，可并发调用：后台读循环按 serial 将应答分发给等待中的调用
+type Conn struct {
+	mu      sync.Mutex // 保护 serial、pending、broken
+	wmu     sync.Mutex // 串行写入报文
+	conn    net.Conn
+	serial  uint32
+	pending map[uint32]chan reply
+	broken  bool
+}
+
+// reply 读循环交给调用方的应答，err 非空表示传输已断开
+type reply struct {
+	hdr  header
+	body []byte
+	err  error
+}
+
+// Open 在已建立的传输连接上打开 libvirt 连接，uri 如 qemu:///system
+func Open(ctx context.Context, conn net.Conn, uri string) (*Conn, error) {
+	c := &Conn{conn: conn, pending: make(map[uint32]chan reply)}
+	go c.readLoop()
+	var e encoder
+	e.optString(&uri)
+	e.uint32(0) // flags
+	if _, err := c.call(ctx, procConnectOpen, e.bytes()); err != nil {
+		c.shutdown(err)
+		return nil, fmt.Errorf("connect open %s: %w", uri, err)
+	}
+	return c, nil
+}
+
+// Close 关闭 libvirt 连接和底层传输，procConnectClose 最多等待 closeTimeout
+func (c *Conn) Close() error {
+	if c.Alive() {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		c.call(ctx, procConnectClose, nil)
+		cancel()
+	}
+	c.shutdown(fmt.Errorf("libvirt connection closed"))
+	return nil
+}
+
+// Alive 连接是否仍可用（传输层出错或已关闭后为 false）
+func (c *Conn) Alive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.broken
+}
+
+// call 发送调用并等待对应 serial 的应答
+// ctx 结束时仅放弃本次等待，连接继续供其他调用使用，迟到的应答由读循环按 serial 丢弃
+func (c *Conn) call(ctx context.Context, proc uint32, args []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.broken {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("libvirt connection closed")
+	}
+	c.serial++
+	serial := c.serial
+	ch := make(chan reply, 1)
+	c.pending[serial] = ch
+	c.mu.Unlock()
+
+	// header: len | program | version | procedure | type | serial | status
+	pkt := make([]byte, headerSize, headerSize+len(args))
+	binary.BigEndian.PutUint32(pkt[4:], remoteProgram)
+	binary.BigEndian.PutUint32(pkt[8:], protocolVersion)
+	binary.BigEndian.PutUint32(pkt[12:], proc)
+	binary.BigEndian.PutUint32(pkt[16:], typeCall)
+	binary.BigEndian.PutUint32(pkt[20:], serial)
+	binary.BigEndian.PutUint32(pkt[24:], statusOK)
+	pkt = append(pkt, args...)
+	binary.BigEndian.PutUint32(pkt[0:], uint32(len(pkt)))
+
+	c.wmu.Lock()
+	_, err := c.conn.Write(pkt)
+	c.wmu.Unlock()
+	if err != nil {
+		err = fmt.Errorf("write: %w", err)
+		c.shutdown(err)
+		return nil, err
+	}
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, r.err
+		}
+		if r.hdr.status == statusError {
+			return nil, decodeError(r.body)
+		}
+		return r.body, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, serial)
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// readLoop 持续读取报文并交给对应 serial 的调用，传输出错时标记连接不可用
+func (c *Conn) readLoop() {
+	for {
+		hdr, body, err := c.readPacket()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		// 忽略非本程序的消息和非应答消息（如 keepalive、事件）
+		if hdr.program != remoteProgram || hdr.typ != typeReply {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[hdr.serial]
+		delete(c.pending, hdr.serial)
+		c.mu.Unlock()
+		// 调用方已放弃等待的应答直接丢弃
+		if ok {
+			ch <- reply{hdr: hdr, body: body}
+		}
+	}
+}
+
+// shutdown 标记连接不可用、关闭传输，并以 err 结束全部等待中的调用
+func (c *Conn) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken {
+		return
+	}
+	c.broken = true
+	c.conn.Close()
+	for serial, ch := range c.pending {
+		ch <- reply{err: err}
+		delete(c.pending, serial)
+	}
+}
+
+type header struct {
+	program, version, proc, typ, serial, status uint32
+}
+
+func (c *Conn) readPacket() (header, []byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(c.conn, lenBuf[:]); err != nil {
+		return header{}, nil, fmt.Errorf("read: %w", err)
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n < headerSize || n > maxPacketSize {
+		return header{}, nil, fmt.Errorf("invalid packet length %d", n)
+	}
+	buf := make([]byte, n-4)
+	if _, err := io.ReadFull(c.conn, buf); err != nil {
+		return header{}, nil, fmt.Errorf("read: %w", err)
+	}
+	h := header{
+		program: binary.BigEndian.Uint32(buf[0:]),
+		version: binary.BigEndian.Uint32(buf[4:]),
+		proc:    binary.BigEndian.Uint32(buf[8:]),
+		typ:     binary.BigEndian.Uint32(buf[12:]),
+		serial:  binary.BigEndian.Uint32(buf[16:]),
+		status:  binary.BigEndian.Uint32(buf[20:]),
+	}
+	return h, buf[24:], nil
+}
+
+// decodeError 解析 remote_error，仅取 code/domain/message
+func decodeError(body []byte) error {
+	d := newDecoder(body)
+	e := &Error{
+		Code:   d.int32(),
+		Domain: d.int32(),
+	}
+	e.Message = d.optString()
+	if d.err != nil {
+		return fmt.Errorf("libvirt error (undecodable: %v)", d.err)
+	}
+	return e
+}
@@ -0,0 +1,220 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// libvirtd 应答报文（remote_protocol.x 线上格式，REMOTE_PROC_CONNECT_LIST_ALL_DOMAINS，serial 2）：
+// 运行中的 web01 (id 1) 和未运行的 db (id -1)，末尾为 ret 字段
+const listAllDomainsReply = "" +
+	"00000060200080860000000100000111" +
+	"00000001000000020000000000000002" +
+	"0000000577656230310000006f1c2e4a" +
+	"9b3d4c8ea1f23d4e5f60718200000001" +
+	"00000002646200000a1b2c3d4e5f4061" +
+	"82738495a6b7c8d9ffffffff00000002"
+
+// libvirtd 错误应答报文（remote_protocol.x 线上格式，REMOTE_PROC_DOMAIN_LOOKUP_BY_NAME，serial 2，VIR_ERR_NO_DOMAIN）
+const lookupErrorReply = "" +
+	"00000084200080860000000100000017" +
+	"0000000100000002000000010000002a" +
+	"0000000a0000000100000036446f6d61" +
+	"696e206e6f7420666f756e643a206e6f" +
+	"20646f6d61696e2077697468206d6174" +
+	"6368696e67206e616d65202767686f73" +
+	"74270000000000020000000000000000" +
+	"0000000000000000ffffffffffffffff" +
+	"00000000"
+
+// libvirtd 应答报文（remote_protocol.x 线上格式，REMOTE_PROC_DOMAIN_GET_INFO，serial 2）：运行中，2 GiB 内存，2 个 vCPU
+const getInfoReply = "" +
+	"0000003c200080860000000100000010" +
+	"00000001000000020000000000000001" +
+	"00000000002000000000000000200000" +
+	"000000020000001cbe991a08"
+
+// keepalive PING，调用方应忽略
+const keepalivePing = "" +
+	"0000001c6b6565700000000100000001" +
+	"000000020000000000000000"
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// okReply 构造无返回值的成功应答
+func okReply(proc, serial uint32) []byte {
+	pkt := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(pkt[0:], headerSize)
+	binary.BigEndian.PutUint32(pkt[4:], remoteProgram)
+	binary.BigEndian.PutUint32(pkt[8:], protocolVersion)
+	binary.BigEndian.PutUint32(pkt[12:], proc)
+	binary.BigEndian.PutUint32(pkt[16:], typeReply)
+	binary.BigEndian.PutUint32(pkt[20:], serial)
+	binary.BigEndian.PutUint32(pkt[24:], statusOK)
+	return pkt
+}
+
+type received struct {
+	hdr  header
+	args []byte
+}
+
+// fakeDaemon 模拟 libvirtd：每收到一个调用依次写出 replies 中对应的报文（可包含多个报文），
+// replies 用完后对其余调用（如 ConnectClose）返回成功应答，返回已完成 ConnectOpen 的连接和收到的调用
+func fakeDaemon(t *testing.T, replies ...[]byte) (*Conn, <-chan received) {
+	t.Helper()
+	client, server := net.Pipe()
+	calls := make(chan received, len(replies)+1)
+	go func() {
+		defer server.Close()
+		srv := &Conn{conn: server}
+		replies = append([][]byte{okReply(procConnectOpen, 1)}, replies...)
+		for _, r := range replies {
+			hdr, args, err := srv.readPacket()
+			if err != nil {
+				return
+			}
+			calls <- received{hdr, args}
+			if _, err := server.Write(r); err != nil {
+				return
+			}
+		}
+		for {
+			hdr, _, err := srv.readPacket()
+			if err != nil {
+				return
+			}
+			if _, err := server.Write(okReply(hdr.proc, hdr.serial)); err != nil {
+				return
+			}
+		}
+	}()
+	c, err := Open(context.Background(), client, "qemu:///system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	open := <-calls
+	if open.hdr.proc != procConnectOpen || open.hdr.serial != 1 {
+		t.Fatalf("open call header = %+v", open.hdr)
+	}
+	d := newDecoder(open.args)
+	if uri := d.optString(); uri != "qemu:///system" {
+		t.Errorf("open uri = %q", uri)
+	}
+	return c, calls
+}
+
+func TestListAllDomainsReply(t *testing.T) {
+	reply := append(mustHex(t, keepalivePing), mustHex(t, listAllDomainsReply)...)
+	c, calls := fakeDaemon(t, reply)
+
+	doms, err := c.ListAllDomains(context.Background(), ListDomainsActive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := <-calls
+	want := header{program: remoteProgram, version: protocolVersion, proc: procConnectListAllDomains, typ: typeCall, serial: 2, status: statusOK}
+	if call.hdr != want {
+		t.Errorf("call header = %+v, want %+v", call.hdr, want)
+	}
+	if got := hex.EncodeToString(call.args); got != "0000000100000001" {
+		t.Errorf("call args = %s", got)
+	}
+
+	if len(doms) != 2 {
+		t.Fatalf("got %d domains", len(doms))
+	}
+	if doms[0].Name != "web01" || doms[0].ID != 1 || hex.EncodeToString(doms[0].UUID[:]) != "6f1c2e4a9b3d4c8ea1f23d4e5f607182" {
+		t.Errorf("domain 0 = %+v", doms[0])
+	}
+	if doms[1].Name != "db" || doms[1].ID != -1 || hex.EncodeToString(doms[1].UUID[:]) != "0a1b2c3d4e5f406182738495a6b7c8d9" {
+		t.Errorf("domain 1 = %+v", doms[1])
+	}
+}
+
+func TestDomainGetInfoReply(t *testing.T) {
+	c, _ := fakeDaemon(t, mustHex(t, getInfoReply))
+
+	info, err := c.DomainGetInfo(context.Background(), Domain{Name: "web01", ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DomainInfo{State: DomainRunning, MaxMem: 2 << 20, Memory: 2 << 20, NrVirtCPU: 2, CPUTime: 123456789000}
+	if info != want {
+		t.Errorf("info = %+v, want %+v", info, want)
+	}
+}
+
+func TestErrorReply(t *testing.T) {
+	c, _ := fakeDaemon(t, mustHex(t, lookupErrorReply))
+
+	_, err := c.DomainLookupByName(context.Background(), "ghost")
+	var lerr *Error
+	if !errors.As(err, &lerr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if lerr.Code != ErrNoDomain || lerr.Domain != 10 || !strings.Contains(lerr.Message, "'ghost'") {
+		t.Errorf("err = %+v", lerr)
+	}
+	if !c.Alive() {
+		t.Error("error reply should not break the connection")
+	}
+}
+
+func TestTruncatedReply(t *testing.T) {
+	pkt := mustHex(t, listAllDomainsReply)
+	// 声明的 domain 数量大于实际数据
+	binary.BigEndian.PutUint32(pkt[headerSize:], 3)
+	c, _ := fakeDaemon(t, pkt)
+
+	if _, err := c.ListAllDomains(context.Background(), 0); err == nil || !strings.Contains(err.Error(), "short buffer") {
+		t.Errorf("err = %v, want short buffer", err)
+	}
+}
+
+func TestInvalidPacketLength(t *testing.T) {
+	c, _ := fakeDaemon(t, []byte{0, 0, 0, 4})
+
+	if _, err := c.ListAllDomains(context.Background(), 0); err == nil {
+		t.Fatal("expected error")
+	}
+	if c.Alive() {
+		t.Error("connection should be marked broken after a malformed packet")
+	}
+}
+
+func TestCanceledCallKeepsConnection(t *testing.T) {
+	// 第一次调用只收到 keepalive，超时放弃；第二次调用前先收到第一次调用迟到的应答
+	late := append(mustHex(t, getInfoReply), okReply(procDomainCreate, 3)...)
+	c, _ := fakeDaemon(t, mustHex(t, keepalivePing), late)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.DomainGetInfo(ctx, Domain{Name: "web01", ID: 1}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if !c.Alive() {
+		t.Fatal("a canceled call should not break the connection")
+	}
+	if err := c.DomainCreate(context.Background(), Domain{Name: "web01"}); err != nil {
+		t.Fatalf("call after cancel: %v", err)
+	}
+	if !c.Alive() {
+		t.Error("connection should stay usable after a late reply")
+	}
+}
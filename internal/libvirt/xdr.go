@@ -0,0 +1,154 @@
+package libvirt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// XDR (RFC 4506) 编解码，仅实现 libvirt 远程协议用到的类型
+
+// encoder XDR 编码器
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) int32(v int32) {
+	e.uint32(uint32(v))
+}
+
+// string 变长字符串: 长度 + 内容 + 4 字节对齐填充
+func (e *encoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf.WriteString(s)
+	e.pad(len(s))
+}
+
+// optString 可选字符串 (remote_string): 是否存在标志 + 字符串
+func (e *encoder) optString(s *string) {
+	if s == nil {
+		e.uint32(0)
+		return
+	}
+	e.uint32(1)
+	e.string(*s)
+}
+
+// fixed 定长不透明数据
+func (e *encoder) fixed(b []byte) {
+	e.buf.Write(b)
+	e.pad(len(b))
+}
+
+func (e *encoder) pad(n int) {
+	if r := n % 4; r != 0 {
+		e.buf.Write(make([]byte, 4-r))
+	}
+}
+
+// domain remote_nonnull_domain
+func (e *encoder) domain(d Domain) {
+	e.string(d.Name)
+	e.fixed(d.UUID[:])
+	e.int32(d.ID)
+}
+
+// pool remote_nonnull_storage_pool
+func (e *encoder) pool(p StoragePool) {
+	e.string(p.Name)
+	e.fixed(p.UUID[:])
+}
+
+func (e *encoder) bytes() []byte {
+	return e.buf.Bytes()
+}
+
+// decoder XDR 解码器，出错后后续读取均返回零值，最终通过 err 检查
+type decoder struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func newDecoder(data []byte) *decoder {
+	return &decoder{data: data}
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.off+n > len(d.data) {
+		d.err = fmt.Errorf("xdr: short buffer (need %d at offset %d, have %d)", n, d.off, len(d.data))
+		return nil
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *decoder) int32() int32 {
+	return int32(d.uint32())
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.take(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *decoder) string() string {
+	n := int(d.uint32())
+	b := d.take(n)
+	d.skipPad(n)
+	return string(b)
+}
+
+func (d *decoder) optString() string {
+	if d.uint32() == 0 {
+		return ""
+	}
+	return d.string()
+}
+
+func (d *decoder) fixed(dst []byte) {
+	copy(dst, d.take(len(dst)))
+	d.skipPad(len(dst))
+}
+
+func (d *decoder) skipPad(n int) {
+	if r := n % 4; r != 0 {
+		d.take(4 - r)
+	}
+}
+
+func (d *decoder) domain() Domain {
+	var dom Domain
+	dom.Name = d.string()
+	d.fixed(dom.UUID[:])
+	dom.ID = d.int32()
+	return dom
+}
+
+func (d *decoder) pool() StoragePool {
+	var p StoragePool
+	p.Name = d.string()
+	d.fixed(p.UUID[:])
+	return p
+}
@@ -0,0 +1,117 @@
+package libvirt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestXDRRoundTrip(t *testing.T) {
+	uri := "qemu:///system"
+	dom := Domain{Name: "web01", UUID: [16]byte{0x6f, 0x1c, 0x2e, 0x4a, 15: 0x82}, ID: -1}
+	pool := StoragePool{Name: "default", UUID: [16]byte{1, 2, 3, 15: 4}}
+
+	var e encoder
+	e.uint32(0xdeadbeef)
+	e.int32(-2)
+	e.string("")
+	e.string("a")
+	e.string("abcd")
+	e.string("中文")
+	e.optString(nil)
+	e.optString(&uri)
+	e.fixed([]byte{1, 2, 3})
+	e.domain(dom)
+	e.pool(pool)
+
+	b := e.bytes()
+	if len(b)%4 != 0 {
+		t.Fatalf("encoded length %d is not 4-byte aligned", len(b))
+	}
+
+	d := newDecoder(b)
+	if v := d.uint32(); v != 0xdeadbeef {
+		t.Errorf("uint32 = %#x", v)
+	}
+	if v := d.int32(); v != -2 {
+		t.Errorf("int32 = %d", v)
+	}
+	for _, want := range []string{"", "a", "abcd", "中文"} {
+		if v := d.string(); v != want {
+			t.Errorf("string = %q, want %q", v, want)
+		}
+	}
+	if v := d.optString(); v != "" {
+		t.Errorf("nil optString = %q", v)
+	}
+	if v := d.optString(); v != uri {
+		t.Errorf("optString = %q, want %q", v, uri)
+	}
+	var fixed [3]byte
+	d.fixed(fixed[:])
+	if fixed != [3]byte{1, 2, 3} {
+		t.Errorf("fixed = %v", fixed)
+	}
+	if v := d.domain(); v != dom {
+		t.Errorf("domain = %+v, want %+v", v, dom)
+	}
+	if v := d.pool(); v != pool {
+		t.Errorf("pool = %+v, want %+v", v, pool)
+	}
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	if d.off != len(b) {
+		t.Errorf("decoded %d of %d bytes", d.off, len(b))
+	}
+}
+
+func TestXDREncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  func(e *encoder)
+		want []byte
+	}{
+		{"uint32", func(e *encoder) { e.uint32(1) }, []byte{0, 0, 0, 1}},
+		{"int32", func(e *encoder) { e.int32(-1) }, []byte{0xff, 0xff, 0xff, 0xff}},
+		{"string padded", func(e *encoder) { e.string("abcde") }, []byte{0, 0, 0, 5, 'a', 'b', 'c', 'd', 'e', 0, 0, 0}},
+		{"string aligned", func(e *encoder) { e.string("abcd") }, []byte{0, 0, 0, 4, 'a', 'b', 'c', 'd'}},
+		{"nil optString", func(e *encoder) { e.optString(nil) }, []byte{0, 0, 0, 0}},
+		{"fixed padded", func(e *encoder) { e.fixed([]byte{9}) }, []byte{9, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e encoder
+			tt.enc(&e)
+			if got := e.bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("got % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXDRShortBuffer(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		dec  func(d *decoder)
+	}{
+		{"uint32", []byte{0, 0, 1}, func(d *decoder) { d.uint32() }},
+		{"uint64", []byte{0, 0, 0, 0, 0, 0, 1}, func(d *decoder) { d.uint64() }},
+		{"string body", []byte{0, 0, 0, 8, 'a', 'b'}, func(d *decoder) { d.string() }},
+		{"string padding", []byte{0, 0, 0, 1, 'a'}, func(d *decoder) { d.string() }},
+		{"domain uuid", []byte{0, 0, 0, 0, 1, 2, 3}, func(d *decoder) { d.domain() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDecoder(tt.data)
+			tt.dec(d)
+			if d.err == nil {
+				t.Fatal("expected short buffer error")
+			}
+			// 出错后后续读取返回零值
+			if v := d.uint32(); v != 0 {
+				t.Errorf("read after error = %d", v)
+			}
+		})
+	}
+}
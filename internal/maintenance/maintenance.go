@@ -0,0 +1,271 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vmcat/internal/job"
+	"vmcat/internal/placement"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// shutdownTimeout 离线迁移前等待 VM 关机的时间
+const shutdownTimeout = 3 * time.Minute
+
+// Manager 宿主机维护模式：迁出运行中的 VM，维护结束后按迁出记录迁回
+type Manager struct {
+	pool      *internalssh.Pool
+	store     *store.Store
+	vms       *vm.Manager
+	jobs      *job.Manager
+	scheduler *placement.Scheduler
+	onMoved   func(srcHostID, vmName, dstHostID string) // VM 迁移成功后回调（更新高可用记录）
+}
+
+// NewManager 创建维护模式管理器
+func NewManager(pool *internalssh.Pool, s *store.Store, vms *vm.Manager, jobs *job.Manager, scheduler *placement.Scheduler) *Manager {
+	return &Manager{pool: pool, store: s, vms: vms, jobs: jobs, scheduler: scheduler}
+}
+
+// OnMoved 设置 VM 迁移成功后的回调，须在开始迁移之前设置
+func (m *Manager) OnMoved(fn func(srcHostID, vmName, dstHostID string)) {
+	m.onMoved = fn
+}
+
+func (m *Manager) moved(srcHostID, vmName, dstHostID string) {
+	if m.onMoved != nil {
+		m.onMoved(srcHostID, vmName, dstHostID)
+	}
+}
+
+// Plan 计算宿主机上运行中 VM 的迁出计划（不执行），HostID 为空的 VM 无法放置，Reason 说明原因
+func (m *Manager) Plan(ctx context.Context, hostID string) ([]placement.Assignment, error) {
+	vms, err := m.vms.List(ctx, hostID)
+	if err != nil {
+		return nil, fmt.Errorf("list VMs: %w", err)
+	}
+	var running []placement.VM
+	for _, v := range vms {
+		if v.State == "running" {
+			running = append(running, placement.VM{Name: v.Name, VCPUs: v.CPUs, MemoryMB: int64(v.MemoryMB)})
+		}
+	}
+	if len(running) == 0 {
+		return []placement.Assignment{}, nil
+	}
+	hosts := m.scheduler.Hosts(ctx, hostID)
+	return placement.Plan(running, hosts, placement.DefaultOptions), nil
+}
+
+// Enter 宿主机进入维护模式（立即不再接受新 VM），以后台任务逐台迁出运行中的 VM：
+// 优先在线迁移，不可行时关机后离线迁移并在目标宿主机上启动。已在维护中时重新迁出剩余的 VM
+// actor 记入维护记录和审计日志
+func (m *Manager) Enter(hostID, reason, actor string) (*store.Job, error) {
+	if !m.pool.IsConnected(hostID) {
+		return nil, fmt.Errorf("host %s is not connected", hostID)
+	}
+	if cur, err := m.store.MaintenanceGet(hostID); err == nil {
+		if m.evacuating(cur) {
+			return nil, fmt.Errorf("evacuation is already in progress (job %s)", cur.JobID)
+		}
+		m.store.MaintenanceUpdate(hostID, store.MaintenanceEvacuating, "")
+	} else {
+		rec := &store.Maintenance{HostID: hostID, State: store.MaintenanceEvacuating, Reason: reason, CreatedBy: actor}
+		if err := m.store.MaintenanceStart(rec); err != nil {
+			return nil, err
+		}
+		m.store.AuditInsert(actor, hostID, "", "host.maintenance_enter", reason)
+	}
+
+	return m.jobs.Start("host.maintenance", hostID, "", func(ctx context.Context, r *job.Run) error {
+		m.store.MaintenanceUpdate(hostID, store.MaintenanceEvacuating, r.ID())
+		defer m.store.MaintenanceUpdate(hostID, store.MaintenanceActive, r.ID())
+		return m.evacuate(ctx, r, hostID, actor)
+	}), nil
+}
+
+// evacuating 迁出任务是否仍在进行（进程重启后遗留的 evacuating 状态视为已结束）
+func (m *Manager) evacuating(rec *store.Maintenance) bool {
+	if rec.State != store.MaintenanceEvacuating {
+		return false
+	}
+	if rec.JobID == "" {
+		return true // 任务刚创建
+	}
+	j, err := m.jobs.Get(rec.JobID)
+	return err == nil && (j.State == job.StatePending || j.State == job.StateRunning)
+}
+
+// evacuate 按迁出计划逐台迁移，单台失败不影响其余 VM
+func (m *Manager) evacuate(ctx context.Context, r *job.Run, hostID, actor string) error {
+	r.Progress(0, "computing placement plan")
+	moves, err := m.Plan(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		r.Log("no running VMs to evacuate")
+		return nil
+	}
+
+	failed := 0
+	for i, mv := range moves {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.Progress(i*100/len(moves), fmt.Sprintf("%d/%d: %s", i+1, len(moves), mv.VM))
+		rec := &store.MaintenanceMove{HostID: hostID, VMName: mv.VM, DstHostID: mv.HostID, WasRunning: true, State: store.MoveStatePlanned}
+		if mv.HostID == "" {
+			rec.State, rec.Error = store.MoveStateFailed, mv.Reason
+			m.store.MaintenanceMoveSave(rec)
+			r.Log("%s: cannot be placed: %s", mv.VM, mv.Reason)
+			failed++
+			continue
+		}
+		m.store.MaintenanceMoveSave(rec)
+
+		r.Log("%s -> %s (%s)", mv.VM, mv.HostName, mv.Reason)
+		method, err := m.Move(ctx, r, actor, hostID, mv.VM, mv.HostID)
+		rec.Method = method
+		if err != nil {
+			rec.State, rec.Error = store.MoveStateFailed, err.Error()
+			r.Log("%s: %v", mv.VM, err)
+			failed++
+		} else {
+			rec.State = store.MoveStateMoved
+		}
+		m.store.MaintenanceMoveSave(rec)
+	}
+	r.Progress(100, "evacuation finished")
+	if failed > 0 {
+		return fmt.Errorf("%d of %d VMs could not be evacuated", failed, len(moves))
+	}
+	return nil
+}
+
+// Move 迁移单台 VM：运行中的先尝试在线迁移，失败后关机走离线迁移并在目标上重新启动；
+// 未运行的直接离线迁移。返回实际使用的方式 live / offline
+func (m *Manager) Move(ctx context.Context, r *job.Run, actor, srcHostID, vmName, dstHostID string) (string, error) {
+	detail, err := m.vms.Get(ctx, srcHostID, vmName)
+	if err != nil {
+		return "", err
+	}
+	running := detail.State == "running"
+	if running {
+		r.Log("%s: live migrating", vmName)
+		err := m.vms.Migrate(ctx, srcHostID, vmName, dstHostID)
+		if err == nil {
+			m.store.AuditInsert(actor, srcHostID, vmName, "vm.migrate", fmt.Sprintf("to %s", dstHostID))
+			m.moved(srcHostID, vmName, dstHostID)
+			return "live", nil
+		}
+		if ctx.Err() != nil {
+			return "live", ctx.Err()
+		}
+		r.Log("%s: live migration failed (%v), falling back to shutdown and offline migration", vmName, err)
+		if err := m.shutdownAndWait(ctx, actor, srcHostID, vmName); err != nil {
+			return "offline", err
+		}
+	}
+
+	err = m.vms.MigrateOffline(ctx, srcHostID, vmName, dstHostID, func(step, detail string) {
+		r.Log("%s: %s: %s", vmName, step, detail)
+	})
+	if err != nil {
+		return "offline", err
+	}
+	m.store.AuditInsert(actor, srcHostID, vmName, "vm.migrate_offline", fmt.Sprintf("to %s", dstHostID))
+	m.moved(srcHostID, vmName, dstHostID)
+	if running {
+		if err := m.vms.Start(ctx, dstHostID, vmName); err != nil {
+			return "offline", fmt.Errorf("start on target: %w", err)
+		}
+	}
+	return "offline", nil
+}
+
+// shutdownAndWait 正常关机并等待 VM 停止，超时不强制断电
+func (m *Manager) shutdownAndWait(ctx context.Context, actor, hostID, vmName string) error {
+	if err := m.vms.Shutdown(ctx, hostID, vmName); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	m.store.AuditInsert(actor, hostID, vmName, "vm.shutdown", "maintenance")
+	deadline := time.Now().Add(shutdownTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(3 * time.Second):
+		}
+		if d, err := m.vms.Get(ctx, hostID, vmName); err == nil && d.State == "shut off" {
+			return nil
+		}
+	}
+	return fmt.Errorf("VM did not shut down within %s", shutdownTimeout)
+}
+
+// Exit 结束维护模式，宿主机重新接受新 VM；迁出的 VM 不会自动迁回（见 Return）
+func (m *Manager) Exit(hostID, actor string) error {
+	rec, err := m.store.MaintenanceGet(hostID)
+	if err != nil {
+		return fmt.Errorf("host %s is not in maintenance", hostID)
+	}
+	if m.evacuating(rec) {
+		return fmt.Errorf("evacuation is in progress, cancel job %s first", rec.JobID)
+	}
+	if err := m.store.MaintenanceEnd(hostID); err != nil {
+		return err
+	}
+	m.store.AuditInsert(actor, hostID, "", "host.maintenance_exit", "")
+	return nil
+}
+
+// Return 以后台任务方式将维护期间迁出的 VM 逐台迁回，须先结束维护模式
+func (m *Manager) Return(hostID, actor string) (*store.Job, error) {
+	if m.store.InMaintenance(hostID) {
+		return nil, fmt.Errorf("host %s is still in maintenance", hostID)
+	}
+	if !m.pool.IsConnected(hostID) {
+		return nil, fmt.Errorf("host %s is not connected", hostID)
+	}
+	moves, err := m.store.MaintenanceMoveList(hostID)
+	if err != nil {
+		return nil, err
+	}
+	var pending []store.MaintenanceMove
+	for _, mv := range moves {
+		if mv.State == store.MoveStateMoved {
+			pending = append(pending, mv)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("no evacuated VMs to return")
+	}
+
+	return m.jobs.Start("host.returnVMs", hostID, "", func(ctx context.Context, r *job.Run) error {
+		failed := 0
+		for i, mv := range pending {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			r.Progress(i*100/len(pending), fmt.Sprintf("%d/%d: %s", i+1, len(pending), mv.VMName))
+			method, err := m.Move(ctx, r, actor, mv.DstHostID, mv.VMName, hostID)
+			if err != nil {
+				mv.Error = fmt.Sprintf("return (%s): %v", method, err)
+				r.Log("%s: %v", mv.VMName, err)
+				failed++
+			} else {
+				mv.State, mv.Error = store.MoveStateReturned, ""
+			}
+			m.store.MaintenanceMoveSave(&mv)
+		}
+		r.Progress(100, "return finished")
+		if failed > 0 {
+			return fmt.Errorf("%d of %d VMs could not be returned", failed, len(pending))
+		}
+		return nil
+	}), nil
+}
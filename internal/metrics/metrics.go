@@ -0,0 +1,293 @@
+// Package metrics 以 Prometheus 文本格式 (0.0.4) 导出指标
+//
+// 计数器和直方图在代码中直接累加；宿主机、VM 等状态类指标在抓取时由注册的 CollectFunc 生成
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets 默认直方图区间（秒）
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Default 进程级指标注册表
+var Default = NewRegistry()
+
+// CollectFunc 抓取时生成指标
+type CollectFunc func(e *Emitter)
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	vecs       []vec
+	collectors []CollectFunc
+}
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// vec 带标签的计数器或直方图
+type vec interface {
+	emit(e *Emitter)
+}
+
+// Register 注册抓取时调用的指标生成函数
+func (r *Registry) Register(fn CollectFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec 创建并注册计数器，name 应以 _total 结尾
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+	r.mu.Lock()
+	r.vecs = append(r.vecs, c)
+	r.mu.Unlock()
+	return c
+}
+
+// Add 按标签值累加，标签值顺序与创建时的标签名一致
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv := c.values[key]
+	if cv == nil {
+		cv = &counterValue{labels: labelValues}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+// Inc 按标签值加 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) emit(e *Emitter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.family(c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		e.sample(c.name, labelPairs(c.labels, cv.labels), cv.value)
+	}
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	values     map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // 各区间累计（非累积）计数，最后一个为 +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 创建并注册直方图，buckets 为 nil 时使用 DefBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+	r.mu.Lock()
+	r.vecs = append(r.vecs, h)
+	r.mu.Unlock()
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv := h.values[key]
+	if hv == nil {
+		hv = &histogramValue{labels: labelValues, counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = hv
+	}
+	i := sort.SearchFloat64s(h.buckets, v)
+	hv.counts[i]++
+	hv.sum += v
+	hv.count++
+}
+
+func (h *HistogramVec) emit(e *Emitter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e.family(h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		base := labelPairs(h.labels, hv.labels)
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += hv.counts[i]
+			e.sample(h.name+"_bucket", append(base, Label{"le", formatFloat(le)}), float64(cum))
+		}
+		e.sample(h.name+"_bucket", append(base, Label{"le", "+Inf"}), float64(hv.count))
+		e.sample(h.name+"_sum", base, hv.sum)
+		e.sample(h.name+"_count", base, float64(hv.count))
+	}
+}
+
+// Label 标签
+type Label struct {
+	Name, Value string
+}
+
+// Emitter 抓取时的指标输出，样本按指标名归组后统一写出，调用顺序不限
+type Emitter struct {
+	families map[string]*family
+	order    []string
+	cur      *family
+}
+
+type family struct {
+	name, help, typ string
+	buf             strings.Builder
+}
+
+// Gauge 输出一个 gauge 样本
+func (e *Emitter) Gauge(name, help string, value float64, labels ...Label) {
+	e.family(name, help, "gauge")
+	e.sample(name, labels, value)
+}
+
+// Counter 输出一个 counter 样本（值由调用方累计，如宿主机上报的累计字节数）
+func (e *Emitter) Counter(name, help string, value float64, labels ...Label) {
+	e.family(name, help, "counter")
+	e.sample(name, labels, value)
+}
+
+// family 切换当前指标族，首次出现时记录 HELP 和 TYPE
+func (e *Emitter) family(name, help, typ string) {
+	f := e.families[name]
+	if f == nil {
+		f = &family{name: name, help: help, typ: typ}
+		e.families[name] = f
+		e.order = append(e.order, name)
+	}
+	e.cur = f
+}
+
+func (e *Emitter) sample(name string, labels []Label, value float64) {
+	b := &e.cur.buf
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.Name)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(l.Value))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+// Handler 返回 /metrics 处理函数
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		r.Write(bw)
+		bw.Flush()
+	})
+}
+
+// Write 输出全部指标
+func (r *Registry) Write(w *bufio.Writer) {
+	r.mu.Lock()
+	vecs := append([]vec(nil), r.vecs...)
+	collectors := append([]CollectFunc(nil), r.collectors...)
+	r.mu.Unlock()
+
+	e := &Emitter{families: make(map[string]*family)}
+	for _, v := range vecs {
+		v.emit(e)
+	}
+	for _, fn := range collectors {
+		fn(e)
+	}
+	for _, name := range e.order {
+		f := e.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.typ)
+		w.WriteString(f.buf.String())
+	}
+}
+
+func labelPairs(names, values []string) []Label {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			labels[i] = Label{name, values[i]}
+		} else {
+			labels[i] = Label{Name: name}
+		}
+	}
+	return labels
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
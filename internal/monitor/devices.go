@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// deviceRates 由相邻两轮的累计计数计算各块设备和网卡的速率，elapsed 为间隔秒数
+// 计数回退（VM 重启、设备热插拔）的设备本轮跳过
+func deviceRates(hostID, vmName string, prev, cur *vm.VMResourceStats, elapsed float64) ([]store.VMDiskStatsRecord, []store.VMNetStatsRecord) {
+	if prev == nil || cur == nil || elapsed <= 0 {
+		return nil, nil
+	}
+
+	prevDisks := make(map[string]vm.DiskStats, len(prev.Disks))
+	for _, d := range prev.Disks {
+		prevDisks[d.Name] = d
+	}
+	var disks []store.VMDiskStatsRecord
+	for _, d := range cur.Disks {
+		p, ok := prevDisks[d.Name]
+		if !ok || d.RdBytes < p.RdBytes || d.WrBytes < p.WrBytes || d.RdReqs < p.RdReqs || d.WrReqs < p.WrReqs {
+			continue
+		}
+		rdReqs, wrReqs := d.RdReqs-p.RdReqs, d.WrReqs-p.WrReqs
+		disks = append(disks, store.VMDiskStatsRecord{
+			HostID:    hostID,
+			VMName:    vmName,
+			Device:    d.Name,
+			RdBps:     float64(d.RdBytes-p.RdBytes) / elapsed,
+			WrBps:     float64(d.WrBytes-p.WrBytes) / elapsed,
+			RdIOPS:    float64(rdReqs) / elapsed,
+			WrIOPS:    float64(wrReqs) / elapsed,
+			RdLatency: latencyMs(d.RdTimeNs, p.RdTimeNs, rdReqs),
+			WrLatency: latencyMs(d.WrTimeNs, p.WrTimeNs, wrReqs),
+		})
+	}
+
+	prevNICs := make(map[string]vm.InterfaceStats, len(prev.Interfaces))
+	for _, n := range prev.Interfaces {
+		prevNICs[n.Name] = n
+	}
+	var nics []store.VMNetStatsRecord
+	for _, n := range cur.Interfaces {
+		p, ok := prevNICs[n.Name]
+		if !ok || n.RxBytes < p.RxBytes || n.TxBytes < p.TxBytes || n.RxPkts < p.RxPkts || n.TxPkts < p.TxPkts ||
+			n.RxDrop < p.RxDrop || n.TxDrop < p.TxDrop {
+			continue
+		}
+		nics = append(nics, store.VMNetStatsRecord{
+			HostID:  hostID,
+			VMName:  vmName,
+			Device:  n.Name,
+			RxBps:   float64(n.RxBytes-p.RxBytes) / elapsed,
+			TxBps:   float64(n.TxBytes-p.TxBytes) / elapsed,
+			RxPps:   float64(n.RxPkts-p.RxPkts) / elapsed,
+			TxPps:   float64(n.TxPkts-p.TxPkts) / elapsed,
+			RxDrops: float64(n.RxDrop-p.RxDrop) / elapsed,
+			TxDrops: float64(n.TxDrop-p.TxDrop) / elapsed,
+		})
+	}
+	return disks, nics
+}
+
+// latencyMs 区间内平均每次请求耗时 (ms)，无请求时为 0
+func latencyMs(cur, prev, reqs uint64) float64 {
+	if reqs == 0 || cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / float64(reqs) / 1e6
+}
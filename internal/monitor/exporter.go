@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"strconv"
+	"strings"
+
+	"vmcat/internal/metrics"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// healthStates SSH 连接健康状态，逐一导出便于按状态告警
+var healthStates = []string{
+	internalssh.StateConnected,
+	internalssh.StateDegraded,
+	internalssh.StateDown,
+	internalssh.StateReconnecting,
+	internalssh.StateDisconnected,
+}
+
+// Exporter 将宿主机、VM 和 SSH 连接池状态导出为 Prometheus 指标
+// 资源数据取自 HistoryCollector 最近一轮采集结果（默认 30 秒一轮），抓取时不访问宿主机
+type Exporter struct {
+	pool    *internalssh.Pool
+	store   *store.Store
+	history *HistoryCollector
+}
+
+// NewExporter 创建指标导出器
+func NewExporter(pool *internalssh.Pool, s *store.Store, history *HistoryCollector) *Exporter {
+	return &Exporter{pool: pool, store: s, history: history}
+}
+
+// Collect 实现 metrics.CollectFunc
+func (x *Exporter) Collect(e *metrics.Emitter) {
+	hosts, err := x.store.HostList()
+	if err != nil {
+		collectorErrors.Inc("", "store")
+		return
+	}
+	latest, lastRun, duration := x.history.Latest()
+	if !lastRun.IsZero() {
+		e.Gauge("vmcat_collector_last_run_timestamp_seconds", "Start time of the last background collection round.", float64(lastRun.Unix()))
+		e.Gauge("vmcat_collector_last_run_duration_seconds", "Duration of the last background collection round.", duration.Seconds())
+	}
+
+	hostLabels := make(map[string][]metrics.Label, len(hosts))
+	for _, h := range hosts {
+		labels := []metrics.Label{{Name: "host_id", Value: h.ID}, {Name: "host", Value: h.Name}, {Name: "tags", Value: h.Tags}}
+		hostLabels[h.ID] = labels
+
+		backend := h.Backend
+		if backend == "" {
+			backend = vm.BackendVirsh
+		}
+		e.Gauge("vmcat_host_info", "Host metadata; value is always 1.", 1,
+			append(labels, metrics.Label{Name: "address", Value: h.Host}, metrics.Label{Name: "backend", Value: backend})...)
+
+		health := x.pool.Health(h.ID)
+		up := 0.0
+		if x.pool.IsConnected(h.ID) {
+			up = 1
+		}
+		e.Gauge("vmcat_host_up", "Whether the SSH connection to the host is usable.", up, labels...)
+		for _, state := range healthStates {
+			v := 0.0
+			if health.State == state {
+				v = 1
+			}
+			e.Gauge("vmcat_ssh_connection_state", "SSH connection state of the host; 1 for the current state.", v,
+				append(labels, metrics.Label{Name: "state", Value: state})...)
+		}
+		e.Gauge("vmcat_ssh_keepalive_failures", "Consecutive failed keepalive probes.", float64(health.Failures), labels...)
+
+		if sample := latest[h.ID]; sample != nil {
+			x.collectHost(e, labels, sample)
+		}
+	}
+
+	for _, q := range x.pool.QueueStats() {
+		labels, ok := hostLabels[q.HostID]
+		if !ok {
+			continue
+		}
+		e.Gauge("vmcat_ssh_sessions_limit", "Maximum concurrent SSH sessions per connection.", float64(q.Limit), labels...)
+		e.Gauge("vmcat_ssh_sessions_in_use", "SSH sessions currently in use.", float64(q.InUse),
+			append(labels, metrics.Label{Name: "conn", Value: "primary"})...)
+		e.Gauge("vmcat_ssh_sessions_in_use", "SSH sessions currently in use.", float64(q.OverflowInUse),
+			append(labels, metrics.Label{Name: "conn", Value: "overflow"})...)
+		e.Gauge("vmcat_ssh_sessions_queued", "Requests waiting for an SSH session.", float64(q.QueuedInteractive),
+			append(labels, metrics.Label{Name: "priority", Value: "interactive"})...)
+		e.Gauge("vmcat_ssh_sessions_queued", "Requests waiting for an SSH session.", float64(q.QueuedBackground),
+			append(labels, metrics.Label{Name: "priority", Value: "background"})...)
+		e.Counter("vmcat_ssh_sessions_completed_total", "SSH sessions completed.", float64(q.Completed), labels...)
+		e.Counter("vmcat_ssh_sessions_waited_total", "SSH sessions that had to queue.", float64(q.Waited), labels...)
+		e.Gauge("vmcat_ssh_session_wait_max_seconds", "Longest time a request waited for an SSH session.", q.MaxWaitMs/1000, labels...)
+	}
+}
+
+// collectHost 导出单台宿主机的资源采集结果
+func (x *Exporter) collectHost(e *metrics.Emitter, labels []metrics.Label, s *Sample) {
+	const mb, gb = 1 << 20, 1 << 30
+	e.Gauge("vmcat_host_last_collected_timestamp_seconds", "Time the host's resources were last collected.", float64(s.At.Unix()), labels...)
+
+	st := s.Host
+	e.Gauge("vmcat_host_cpu_usage_percent", "Host CPU usage.", st.CPUPercent, labels...)
+	e.Gauge("vmcat_host_memory_total_bytes", "Host memory size.", float64(st.MemTotal*mb), labels...)
+	e.Gauge("vmcat_host_memory_used_bytes", "Host memory in use.", float64(st.MemUsed*mb), labels...)
+	e.Gauge("vmcat_host_disk_total_bytes", "Total size of host filesystems.", float64(st.DiskTotal*gb), labels...)
+	e.Gauge("vmcat_host_disk_used_bytes", "Used space of host filesystems.", float64(st.DiskUsed*gb), labels...)
+	for i, load := range parseLoadAvg(st.LoadAvg) {
+		period := []string{"1m", "5m", "15m"}[i]
+		e.Gauge("vmcat_host_load", "Host load average.", load, append(labels, metrics.Label{Name: "period", Value: period})...)
+	}
+
+	for _, v := range s.VMs {
+		vmLabels := append(labels[:len(labels):len(labels)], metrics.Label{Name: "vm", Value: v.Name})
+		running := 0.0
+		if v.State == "running" {
+			running = 1
+		}
+		e.Gauge("vmcat_vm_info", "VM metadata; value is always 1.", 1, append(vmLabels, metrics.Label{Name: "state", Value: v.State})...)
+		e.Gauge("vmcat_vm_running", "Whether the VM is running.", running, vmLabels...)
+		e.Gauge("vmcat_vm_vcpus", "vCPUs assigned to the VM.", float64(v.CPUs), vmLabels...)
+		e.Gauge("vmcat_vm_memory_bytes", "Memory assigned to the VM.", float64(v.MemoryMB)*mb, vmLabels...)
+
+		vs := s.VMStats[v.Name]
+		if vs == nil {
+			continue
+		}
+		e.Counter("vmcat_vm_cpu_seconds_total", "CPU time consumed by the VM.", float64(vs.CPUTime)/1e9, vmLabels...)
+		e.Gauge("vmcat_vm_cpu_usage_percent", "VM CPU usage, normalised to its vCPU count.", vs.CPUPercent, vmLabels...)
+		e.Gauge("vmcat_vm_memory_rss_bytes", "Resident memory of the VM process on the host.", float64(vs.MemRSS), vmLabels...)
+		e.Gauge("vmcat_vm_memory_balloon_bytes", "Current balloon size of the VM.", float64(vs.MemActual), vmLabels...)
+		e.Counter("vmcat_vm_network_receive_bytes_total", "Bytes received on all VM interfaces.", float64(vs.NetRxBytes), vmLabels...)
+		e.Counter("vmcat_vm_network_transmit_bytes_total", "Bytes transmitted on all VM interfaces.", float64(vs.NetTxBytes), vmLabels...)
+		e.Counter("vmcat_vm_disk_read_bytes_total", "Bytes read from all VM block devices.", float64(vs.BlockRdBytes), vmLabels...)
+		e.Counter("vmcat_vm_disk_written_bytes_total", "Bytes written to all VM block devices.", float64(vs.BlockWrBytes), vmLabels...)
+	}
+}
+
+// parseLoadAvg 解析 "0.15, 0.10, 0.05"
+func parseLoadAvg(s string) []float64 {
+	var loads []float64
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || len(loads) == 3 {
+			break
+		}
+		loads = append(loads, v)
+	}
+	return loads
+}
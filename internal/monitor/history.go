@@ -1,23 +1,51 @@
 package monitor
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
 
+	"vmcat/internal/metrics"
 	internalssh "vmcat/internal/ssh"
 	"vmcat/internal/store"
 	"vmcat/internal/vm"
 )
 
+// collectorErrors 后台采集失败次数
+var collectorErrors = metrics.Default.NewCounterVec(
+	"vmcat_collector_errors_total",
+	"Failed background collections, by stage (host, vm_list, vm).",
+	"host_id", "stage",
+)
+
 // HistoryCollector 资源历史采集器
+// 每轮采集结果同时保留在内存中，供 /metrics 导出而无需在抓取时访问宿主机
 type HistoryCollector struct {
 	pool      *internalssh.Pool
 	store     *store.Store
 	monitor   *Collector
 	vmManager *vm.Manager
 	stopCh    chan struct{}
+	resetCh   chan struct{}
 	once      sync.Once
+
+	mu        sync.RWMutex
+	latest    map[string]*Sample // hostID -> 最近一次采集结果
+	lastRun   time.Time
+	duration  time.Duration
+	interval  time.Duration
+	retention store.StatsRetention
+
+	onCollect func(latest map[string]*Sample) // 每轮采集完成后回调（告警评估）
+}
+
+// Sample 单台宿主机最近一次采集结果
+type Sample struct {
+	Host    *HostStats
+	VMs     []vm.VM                        // 全部 VM（含未运行的），VM 列表采集失败时为 nil
+	VMStats map[string]*vm.VMResourceStats // 运行中 VM 的统计，按名称索引
+	At      time.Time
 }
 
 // NewHistoryCollector 创建历史采集器
@@ -28,24 +56,84 @@ func NewHistoryCollector(pool *internalssh.Pool, s *store.Store, monitor *Collec
 		monitor:   monitor,
 		vmManager: vmManager,
 		stopCh:    make(chan struct{}),
+		resetCh:   make(chan struct{}, 1),
+		latest:    make(map[string]*Sample),
+		interval:  DefaultInterval,
+		retention: store.DefaultStatsRetention,
+	}
+}
+
+// 采集间隔
+const (
+	DefaultInterval = 30 * time.Second
+	MinInterval     = 5 * time.Second
+)
+
+// SetInterval 设置采集间隔（不低于 MinInterval），运行中修改立即生效
+func (h *HistoryCollector) SetInterval(d time.Duration) {
+	if d <= 0 {
+		d = DefaultInterval
+	}
+	if d < MinInterval {
+		d = MinInterval
 	}
+	h.mu.Lock()
+	h.interval = d
+	h.mu.Unlock()
+	select {
+	case h.resetCh <- struct{}{}:
+	default:
+	}
+}
+
+// SetRetention 设置各精度历史数据的保留时长，下一轮清理时生效
+func (h *HistoryCollector) SetRetention(r store.StatsRetention) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retention = r
 }
 
-// Start 启动定时采集（每 30 秒）
+func (h *HistoryCollector) settings() (time.Duration, store.StatsRetention) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.interval, h.retention
+}
+
+// maintain 汇总并清理历史数据
+func (h *HistoryCollector) maintain() {
+	_, retention := h.settings()
+	if err := h.store.StatsRollup(); err != nil {
+		log.Printf("history rollup: %v", err)
+	}
+	if err := h.store.StatsCleanup(retention); err != nil {
+		log.Printf("history cleanup: %v", err)
+	}
+}
+
+// OnCollect 设置每轮采集完成后的回调，在采集协程中同步调用，须在 Start 之前设置
+func (h *HistoryCollector) OnCollect(fn func(latest map[string]*Sample)) {
+	h.onCollect = fn
+}
+
+// Start 启动定时采集（默认每 30 秒，见 SetInterval）
 func (h *HistoryCollector) Start() {
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
+		interval, _ := h.settings()
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
-		// 启动后清理旧数据
-		h.store.StatsCleanup(24)
+		// 启动后补齐停机期间未完成的汇总并清理旧数据
+		h.maintain()
 
 		for {
 			select {
 			case <-ticker.C:
 				h.collectAll()
-				// 每次采集后清理超过 24 小时的数据
-				h.store.StatsCleanup(24)
+				// 每次采集后汇总已结束的区间并按保留时长清理
+				h.maintain()
+			case <-h.resetCh:
+				interval, _ := h.settings()
+				ticker.Reset(interval)
 			case <-h.stopCh:
 				return
 			}
@@ -66,6 +154,23 @@ func (h *HistoryCollector) collectAll() {
 	if err != nil {
 		return
 	}
+	start := time.Now()
+	latest := make(map[string]*Sample)
+	// 上一轮结果，用于计算块设备和网卡速率
+	h.mu.RLock()
+	prev := h.latest
+	h.mu.RUnlock()
+	defer func() {
+		h.mu.Lock()
+		h.latest, h.lastRun, h.duration = latest, start, time.Since(start)
+		h.mu.Unlock()
+		if h.onCollect != nil {
+			h.onCollect(latest)
+		}
+	}()
+
+	// 后台采集以低优先级排队，不挤占用户操作的会话
+	ctx := internalssh.WithPriority(context.Background(), internalssh.PriorityBackground)
 
 	for _, host := range hosts {
 		if !h.pool.IsConnected(host.ID) {
@@ -73,30 +178,42 @@ func (h *HistoryCollector) collectAll() {
 		}
 
 		// 采集宿主机资源
-		stats, err := h.monitor.Collect(host.ID)
+		stats, err := h.monitor.CollectContext(ctx, host.ID)
 		if err != nil {
+			collectorErrors.Inc(host.ID, "host")
 			log.Printf("history collect host %s: %v", host.ID, err)
 			continue
 		}
+		sample := &Sample{Host: stats, VMStats: make(map[string]*vm.VMResourceStats), At: time.Now()}
+		latest[host.ID] = sample
 
 		if err := h.store.HostStatsInsert(host.ID, stats.CPUPercent, stats.MemPercent, stats.DiskPercent); err != nil {
 			log.Printf("history insert host stats: %v", err)
 		}
 
 		// 采集运行中的 VM 资源
-		vms, err := h.vmManager.List(host.ID)
+		vms, err := h.vmManager.List(ctx, host.ID)
 		if err != nil {
+			collectorErrors.Inc(host.ID, "vm_list")
 			continue
 		}
+		if vms == nil {
+			vms = []vm.VM{}
+		}
+		sample.VMs = vms
 
+		var disks []store.VMDiskStatsRecord
+		var nics []store.VMNetStatsRecord
 		for _, v := range vms {
 			if v.State != "running" {
 				continue
 			}
-			vmStats, err := h.vmManager.VMStats(host.ID, v.Name)
+			vmStats, err := h.vmManager.VMStats(ctx, host.ID, v.Name)
 			if err != nil {
+				collectorErrors.Inc(host.ID, "vm")
 				continue
 			}
+			sample.VMStats[v.Name] = vmStats
 			h.store.VMStatsInsert(
 				host.ID, v.Name,
 				vmStats.CPUPercent,
@@ -104,6 +221,21 @@ func (h *HistoryCollector) collectAll() {
 				int64(vmStats.NetRxBytes),
 				int64(vmStats.NetTxBytes),
 			)
+			if p := prev[host.ID]; p != nil {
+				d, n := deviceRates(host.ID, v.Name, p.VMStats[v.Name], vmStats, sample.At.Sub(p.At).Seconds())
+				disks = append(disks, d...)
+				nics = append(nics, n...)
+			}
+		}
+		if err := h.store.VMDeviceStatsInsert(disks, nics); err != nil {
+			log.Printf("history insert device stats: %v", err)
 		}
 	}
 }
+
+// Latest 最近一轮采集结果（未连接或采集失败的宿主机不在其中）及本轮开始时间和耗时
+func (h *HistoryCollector) Latest() (map[string]*Sample, time.Time, time.Duration) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.latest, h.lastRun, h.duration
+}
@@ -0,0 +1,477 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+)
+
+// Inventory 宿主机硬件与软件清单，变化不频繁，缓存在本地数据库中
+type Inventory struct {
+	CPU         CPUInfo      `json:"cpu"`
+	MemoryMB    int64        `json:"memoryMB"`
+	NUMA        []NUMANode   `json:"numa"`
+	Arch        string       `json:"arch"`
+	Libvirt     string       `json:"libvirtVersion"`
+	QEMU        string       `json:"qemuVersion"`
+	KVM         bool         `json:"kvm"`      // 可用 KVM 硬件加速
+	MaxVCPUs    int          `json:"maxVcpus"` // domcapabilities 报告的单 VM vCPU 上限
+	Machines    []Machine    `json:"machines"`
+	Firmware    []string     `json:"firmware"` // bios / efi
+	Loaders     []string     `json:"loaders"`  // UEFI 固件路径
+	Kernel      string       `json:"kernel"`
+	OS          string       `json:"os"`
+	Filesystems []Filesystem `json:"filesystems"`
+	NICs        []HostNIC    `json:"nics"`
+	CollectedAt string       `json:"collectedAt"`
+}
+
+// CPUInfo CPU 型号与拓扑
+type CPUInfo struct {
+	Model          string   `json:"model"`          // 如 Intel(R) Xeon(R) Gold 6230
+	LibvirtModel   string   `json:"libvirtModel"`   // libvirt 识别的型号，如 Cascadelake-Server
+	Sockets        int      `json:"sockets"`        // 全部 NUMA 节点的插槽总数
+	CoresPerSocket int      `json:"coresPerSocket"` // 每插槽核心数
+	ThreadsPerCore int      `json:"threadsPerCore"` // 每核心线程数
+	CPUs           int      `json:"cpus"`           // 逻辑 CPU 数
+	Flags          []string `json:"flags"`          // 虚拟化相关特性，如 vmx / svm / ept / npt
+}
+
+// NUMANode NUMA 节点
+type NUMANode struct {
+	ID       int   `json:"id"`
+	MemoryMB int64 `json:"memoryMB"`
+	CPUs     []int `json:"cpus"`
+}
+
+// Machine QEMU 机器类型，别名（如 pc / q35）的 Canonical 为实际版本
+type Machine struct {
+	Name      string `json:"name"`
+	Canonical string `json:"canonical,omitempty"`
+	MaxCPUs   int    `json:"maxCpus"`
+}
+
+// Filesystem 已挂载文件系统，容量单位为字节
+type Filesystem struct {
+	Source string `json:"source"`
+	Type   string `json:"type"`
+	Mount  string `json:"mount"`
+	Size   int64  `json:"size"`
+	Used   int64  `json:"used"`
+	Avail  int64  `json:"avail"`
+}
+
+// HostNIC 宿主机网卡（不含回环和 VM 的 tap 设备）
+type HostNIC struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac"`
+	Kind      string   `json:"kind"`  // physical | bridge | bond | virtual
+	State     string   `json:"state"` // up | down | unknown
+	SpeedMbps int      `json:"speedMbps"`
+	MTU       int      `json:"mtu"`
+	Addresses []string `json:"addresses"`
+}
+
+// virtFlags 记录的 CPU 特性
+var virtFlags = map[string]bool{
+	"vmx": true, "svm": true, "ept": true, "vpid": true, "npt": true, "nrip_save": true,
+	"flexpriority": true, "hypervisor": true, "pdpe1gb": true, "x2apic": true,
+	"aes": true, "avx": true, "avx2": true, "avx512f": true,
+}
+
+// inventoryCmd 一次会话采集全部清单信息
+const inventoryCmd = `echo "===LSCPU===" && LC_ALL=C lscpu 2>/dev/null
+echo "===NODEINFO===" && virsh nodeinfo 2>/dev/null
+echo "===CAPS===" && virsh capabilities 2>/dev/null
+echo "===DOMCAPS===" && (virsh domcapabilities --virttype kvm 2>/dev/null || virsh domcapabilities 2>/dev/null)
+echo "===VERSION===" && virsh version 2>/dev/null
+echo "===KERNEL===" && uname -r
+echo "===OS===" && cat /etc/os-release 2>/dev/null
+echo "===FS===" && df -PT -B1 -x tmpfs -x devtmpfs -x squashfs -x overlay 2>/dev/null
+echo "===NIC===" && for n in /sys/class/net/*; do k=virtual; [ -e $n/device ] && k=physical; [ -d $n/bridge ] && k=bridge; [ -d $n/bonding ] && k=bond; echo "${n##*/}|$(cat $n/address 2>/dev/null)|$k|$(cat $n/operstate 2>/dev/null)|$(cat $n/speed 2>/dev/null)|$(cat $n/mtu 2>/dev/null)"; done
+echo "===ADDR===" && ip -o addr show scope global 2>/dev/null
+true`
+
+// Inventory 采集宿主机清单，ctx 可携带命令优先级
+func (c *Collector) Inventory(ctx context.Context, hostID string) (*Inventory, error) {
+	client, err := c.pool.Get(hostID)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.Run(ctx, internalssh.OpQuery, inventoryCmd)
+	if err != nil {
+		return nil, fmt.Errorf("collect inventory: %w", err)
+	}
+	inv := parseInventory(output)
+	inv.CollectedAt = time.Now().Format("2006-01-02 15:04:05")
+	return inv, nil
+}
+
+// parseInventory 解析清单采集输出
+func parseInventory(output string) *Inventory {
+	inv := &Inventory{}
+	sections := splitSections(output)
+
+	lscpu := parseColonPairs(sections["LSCPU"])
+	inv.CPU.Model = lscpu["Model name"]
+	inv.Arch = lscpu["Architecture"]
+	for _, f := range strings.Fields(lscpu["Flags"]) {
+		if virtFlags[f] {
+			inv.CPU.Flags = append(inv.CPU.Flags, f)
+		}
+	}
+
+	node := parseColonPairs(sections["NODEINFO"])
+	inv.CPU.CPUs = atoi(node["CPU(s)"])
+	inv.CPU.CoresPerSocket = atoi(node["Core(s) per socket"])
+	inv.CPU.ThreadsPerCore = atoi(node["Thread(s) per core"])
+	inv.CPU.Sockets = atoi(node["CPU socket(s)"]) * max(atoi(node["NUMA cell(s)"]), 1)
+	inv.MemoryMB = int64(atoi(strings.TrimSuffix(node["Memory size"], " KiB"))) / 1024
+	if inv.CPU.CPUs == 0 {
+		inv.CPU.CPUs = atoi(lscpu["CPU(s)"])
+	}
+
+	parseCapabilities(inv, sections["CAPS"])
+	parseDomCapabilities(inv, sections["DOMCAPS"])
+
+	for _, line := range strings.Split(sections["VERSION"], "\n") {
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "Using library: libvirt "); ok {
+			inv.Libvirt = v
+		} else if v, ok := strings.CutPrefix(line, "Running hypervisor: QEMU "); ok {
+			inv.QEMU = v
+		}
+	}
+
+	inv.Kernel = strings.TrimSpace(sections["KERNEL"])
+	for _, line := range strings.Split(sections["OS"], "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "PRETTY_NAME="); ok {
+			inv.OS = strings.Trim(v, `"`)
+		}
+	}
+
+	inv.Filesystems = parseFilesystems(sections["FS"])
+	inv.NICs = parseNICs(sections["NIC"], sections["ADDR"])
+	return inv
+}
+
+// parseColonPairs 解析 "Key:   value" 格式的输出
+func parseColonPairs(s string) map[string]string {
+	kv := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			kv[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return kv
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// capabilitiesXML virsh capabilities 中用到的部分
+type capabilitiesXML struct {
+	Host struct {
+		CPU struct {
+			Arch  string `xml:"arch"`
+			Model string `xml:"model"`
+		} `xml:"cpu"`
+		Cells []struct {
+			ID     int   `xml:"id,attr"`
+			Memory int64 `xml:"memory"` // KiB
+			CPUs   []struct {
+				ID int `xml:"id,attr"`
+			} `xml:"cpus>cpu"`
+		} `xml:"topology>cells>cell"`
+	} `xml:"host"`
+	Guests []struct {
+		Arch struct {
+			Name     string `xml:"name,attr"`
+			Machines []struct {
+				Name      string `xml:",chardata"`
+				Canonical string `xml:"canonical,attr"`
+				MaxCPUs   int    `xml:"maxCpus,attr"`
+			} `xml:"machine"`
+			Domains []struct {
+				Type string `xml:"type,attr"`
+			} `xml:"domain"`
+		} `xml:"arch"`
+	} `xml:"guest"`
+}
+
+// parseCapabilities 从 virsh capabilities 提取 NUMA 拓扑、机器类型和 KVM 支持
+func parseCapabilities(inv *Inventory, s string) {
+	var caps capabilitiesXML
+	if strings.TrimSpace(s) == "" || xml.Unmarshal([]byte(s), &caps) != nil {
+		return
+	}
+	inv.CPU.LibvirtModel = caps.Host.CPU.Model
+	if caps.Host.CPU.Arch != "" {
+		inv.Arch = caps.Host.CPU.Arch
+	}
+	for _, cell := range caps.Host.Cells {
+		n := NUMANode{ID: cell.ID, MemoryMB: cell.Memory / 1024}
+		for _, cpu := range cell.CPUs {
+			n.CPUs = append(n.CPUs, cpu.ID)
+		}
+		inv.NUMA = append(inv.NUMA, n)
+	}
+
+	seen := make(map[string]bool)
+	for _, g := range caps.Guests {
+		if g.Arch.Name != inv.Arch {
+			continue
+		}
+		for _, d := range g.Arch.Domains {
+			if d.Type == "kvm" {
+				inv.KVM = true
+			}
+		}
+		for _, m := range g.Arch.Machines {
+			name := strings.TrimSpace(m.Name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			inv.Machines = append(inv.Machines, Machine{Name: name, Canonical: m.Canonical, MaxCPUs: m.MaxCPUs})
+		}
+	}
+	sort.Slice(inv.Machines, func(i, j int) bool { return inv.Machines[i].Name < inv.Machines[j].Name })
+}
+
+// domCapabilitiesXML virsh domcapabilities 中用到的部分
+type domCapabilitiesXML struct {
+	VCPU struct {
+		Max int `xml:"max,attr"`
+	} `xml:"vcpu"`
+	OS struct {
+		Enums []struct {
+			Name   string   `xml:"name,attr"`
+			Values []string `xml:"value"`
+		} `xml:"enum"`
+		Loader struct {
+			Supported string   `xml:"supported,attr"`
+			Values    []string `xml:"value"`
+		} `xml:"loader"`
+	} `xml:"os"`
+}
+
+// parseDomCapabilities 从 virsh domcapabilities 提取 vCPU 上限和可用固件
+func parseDomCapabilities(inv *Inventory, s string) {
+	var caps domCapabilitiesXML
+	if strings.TrimSpace(s) == "" || xml.Unmarshal([]byte(s), &caps) != nil {
+		return
+	}
+	inv.MaxVCPUs = caps.VCPU.Max
+	if caps.OS.Loader.Supported == "yes" {
+		inv.Loaders = caps.OS.Loader.Values
+	}
+	has := make(map[string]bool)
+	for _, e := range caps.OS.Enums {
+		if e.Name == "firmware" {
+			for _, v := range e.Values {
+				has[v] = true
+			}
+		}
+	}
+	// firmware 枚举只列出基于描述文件的固件（通常仅 efi），x86 上 BIOS 总是可用；
+	// 较旧的 libvirt 不报告该枚举，有 UEFI 固件路径即视为支持 efi
+	if inv.Arch == "x86_64" || inv.Arch == "i686" {
+		inv.Firmware = append(inv.Firmware, "bios")
+	}
+	if has["efi"] || len(inv.Loaders) > 0 {
+		inv.Firmware = append(inv.Firmware, "efi")
+	}
+}
+
+// parseFilesystems 解析 df -PT -B1 输出
+func parseFilesystems(s string) []Filesystem {
+	var list []Filesystem
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || fields[0] == "Filesystem" {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		used, _ := strconv.ParseInt(fields[3], 10, 64)
+		avail, _ := strconv.ParseInt(fields[4], 10, 64)
+		list = append(list, Filesystem{
+			Source: fields[0],
+			Type:   fields[1],
+			Size:   size,
+			Used:   used,
+			Avail:  avail,
+			Mount:  strings.Join(fields[6:], " "),
+		})
+	}
+	return list
+}
+
+// parseNICs 解析网卡列表（name|mac|kind|state|speed|mtu）和 ip -o addr 输出
+func parseNICs(links, addrs string) []HostNIC {
+	byName := make(map[string][]string)
+	for _, line := range strings.Split(addrs, "\n") {
+		fields := strings.Fields(line)
+		// 2: eth0    inet 10.0.0.5/24 brd 10.0.0.255 scope global eth0
+		if len(fields) >= 4 && (fields[2] == "inet" || fields[2] == "inet6") {
+			name := strings.TrimSuffix(fields[1], ":")
+			byName[name] = append(byName[name], fields[3])
+		}
+	}
+
+	var list []HostNIC
+	for _, line := range strings.Split(links, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "|")
+		if len(parts) != 6 || skipNIC(parts[0]) {
+			continue
+		}
+		speed := atoi(parts[4])
+		if speed < 0 {
+			speed = 0 // 未连接的网卡报告 -1
+		}
+		list = append(list, HostNIC{
+			Name:      parts[0],
+			MAC:       parts[1],
+			Kind:      parts[2],
+			State:     parts[3],
+			SpeedMbps: speed,
+			MTU:       atoi(parts[5]),
+			Addresses: byName[parts[0]],
+		})
+	}
+	return list
+}
+
+// skipNIC 回环和 VM 的 tap 设备随 VM 启停变化，不计入清单
+func skipNIC(name string) bool {
+	if name == "lo" {
+		return true
+	}
+	for _, prefix := range []string{"vnet", "tap", "macvtap"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint 用于变更比较的稳定字段，不含文件系统用量等随时变化的值
+func (inv *Inventory) fingerprint() map[string]string {
+	fp := map[string]string{
+		"cpu.model":    inv.CPU.Model,
+		"cpu.topology": fmt.Sprintf("%d sockets x %d cores x %d threads (%d CPUs)", inv.CPU.Sockets, inv.CPU.CoresPerSocket, inv.CPU.ThreadsPerCore, inv.CPU.CPUs),
+		"cpu.flags":    strings.Join(inv.CPU.Flags, " "),
+		"memory":       fmt.Sprintf("%d MB", inv.MemoryMB),
+		"libvirt":      inv.Libvirt,
+		"qemu":         inv.QEMU,
+		"kvm":          strconv.FormatBool(inv.KVM),
+		"firmware":     strings.Join(inv.Firmware, " "),
+		"kernel":       inv.Kernel,
+		"os":           inv.OS,
+	}
+	var machines []string
+	for _, m := range inv.Machines {
+		machines = append(machines, m.Name)
+	}
+	fp["machines"] = strings.Join(machines, " ")
+	for _, n := range inv.NUMA {
+		fp[fmt.Sprintf("numa.%d", n.ID)] = fmt.Sprintf("%d MB, %d CPUs", n.MemoryMB, len(n.CPUs))
+	}
+	for _, f := range inv.Filesystems {
+		fp["fs."+f.Mount] = fmt.Sprintf("%s %s %d", f.Source, f.Type, f.Size)
+	}
+	for _, n := range inv.NICs {
+		fp["nic."+n.Name] = fmt.Sprintf("%s %s %d Mbps mtu %d %s", n.MAC, n.Kind, n.SpeedMbps, n.MTU, strings.Join(n.Addresses, " "))
+	}
+	return fp
+}
+
+// CachedInventory 读取 store 中缓存的清单，未采集过时返回 nil
+func CachedInventory(s *store.Store, hostID string) *Inventory {
+	data, err := s.HostInventoryGet(hostID)
+	if err != nil {
+		return nil
+	}
+	var inv Inventory
+	if json.Unmarshal([]byte(data), &inv) != nil {
+		return nil
+	}
+	return &inv
+}
+
+// DiffInventory 比较两次清单，old 为 nil（首次采集）时不产生变更
+func DiffInventory(old, cur *Inventory) []store.HostInventoryChange {
+	if old == nil || cur == nil {
+		return nil
+	}
+	before, after := old.fingerprint(), cur.fingerprint()
+	keys := make([]string, 0, len(after))
+	for k := range after {
+		keys = append(keys, k)
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []store.HostInventoryChange
+	for _, k := range keys {
+		if before[k] != after[k] {
+			changes = append(changes, store.HostInventoryChange{Field: k, OldValue: before[k], NewValue: after[k]})
+		}
+	}
+	return changes
+}
+
+// Machine 按名称查找机器类型
+func (inv *Inventory) Machine(name string) (Machine, bool) {
+	for _, m := range inv.Machines {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Machine{}, false
+}
+
+// ValidateVM 按清单校验新建 VM 的参数，machine / firmware 为空时不校验
+func (inv *Inventory) ValidateVM(cpus, memoryMB int, machine, firmware string) error {
+	if inv.CPU.CPUs > 0 && cpus > inv.CPU.CPUs {
+		return fmt.Errorf("%d vCPUs exceeds the host's %d logical CPUs", cpus, inv.CPU.CPUs)
+	}
+	if inv.MaxVCPUs > 0 && cpus > inv.MaxVCPUs {
+		return fmt.Errorf("%d vCPUs exceeds the hypervisor limit of %d", cpus, inv.MaxVCPUs)
+	}
+	if inv.MemoryMB > 0 && int64(memoryMB) > inv.MemoryMB {
+		return fmt.Errorf("%d MB memory exceeds the host's %d MB", memoryMB, inv.MemoryMB)
+	}
+	if machine != "" && len(inv.Machines) > 0 {
+		m, ok := inv.Machine(machine)
+		if !ok {
+			return fmt.Errorf("machine type %q is not supported by this host", machine)
+		}
+		if m.MaxCPUs > 0 && cpus > m.MaxCPUs {
+			return fmt.Errorf("machine type %s supports at most %d vCPUs", machine, m.MaxCPUs)
+		}
+	}
+	if firmware != "" && len(inv.Firmware) > 0 {
+		for _, f := range inv.Firmware {
+			if f == firmware {
+				return nil
+			}
+		}
+		return fmt.Errorf("firmware %q is not available on this host (available: %s)", firmware, strings.Join(inv.Firmware, ", "))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -33,6 +34,11 @@ func NewCollector(pool *internalssh.Pool) *Collector {
 
 // Collect 采集宿主机资源信息
 func (c *Collector) Collect(hostID string) (*HostStats, error) {
+	return c.CollectContext(context.Background(), hostID)
+}
+
+// CollectContext 采集宿主机资源信息，ctx 可携带命令优先级
+func (c *Collector) CollectContext(ctx context.Context, hostID string) (*HostStats, error) {
 	client, err := c.pool.Get(hostID)
 	if err != nil {
 		return nil, err
@@ -40,7 +46,7 @@ func (c *Collector) Collect(hostID string) (*HostStats, error) {
 
 	// 合并多条命令减少 SSH 会话开销
 	cmd := `echo "===MEM===" && free -m && echo "===DISK===" && df -BG --total 2>/dev/null | grep '^total' && echo "===CPU===" && top -bn1 | grep '%Cpu' | head -1 && echo "===UPTIME===" && uptime`
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpStats, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("collect stats: %w", err)
 	}
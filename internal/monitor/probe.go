@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	internalssh "vmcat/internal/ssh"
+)
+
+// PlacementProbe 调度新 VM 时需要的宿主机实时信息
+type PlacementProbe struct {
+	Networks  []string `json:"networks"`  // 活动的 libvirt 网络
+	Bridges   []string `json:"bridges"`   // 网桥
+	FreeBytes int64    `json:"freeBytes"` // 存储路径所在文件系统的可用空间，未指定路径时为 0
+	Missing   []string `json:"missing"`   // 不存在的文件
+}
+
+// Probe 一次会话探测宿主机的网络、网桥、path 所在文件系统的可用空间以及 files 是否存在
+// path 不存在时按最近的已存在上级目录计算（实例目录创建 VM 时才建立）
+func (c *Collector) Probe(ctx context.Context, hostID, path string, files []string) (*PlacementProbe, error) {
+	client, err := c.pool.Get(hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd strings.Builder
+	cmd.WriteString(`echo "===NETS===" && virsh net-list --name 2>/dev/null
+echo "===BRIDGES===" && ip -o link show type bridge 2>/dev/null | awk -F': ' '{print $2}'
+`)
+	if path != "" {
+		fmt.Fprintf(&cmd, `echo "===DF===" && p=%s; while [ ! -e "$p" ] && [ "$p" != / ]; do p=$(dirname "$p"); done; df -P -B1 "$p" 2>/dev/null | tail -1
+`, internalssh.ShellQuote(path))
+	}
+	cmd.WriteString(`echo "===FILES==="
+`)
+	for _, f := range files {
+		fmt.Fprintf(&cmd, "[ -e %s ] && echo %s\n", internalssh.ShellQuote(f), internalssh.ShellQuote(f))
+	}
+	cmd.WriteString("true")
+
+	output, err := client.Run(ctx, internalssh.OpQuery, cmd.String())
+	if err != nil {
+		return nil, fmt.Errorf("probe host: %w", err)
+	}
+	sections := splitSections(output)
+
+	p := &PlacementProbe{
+		Networks: probeLines(sections["NETS"]),
+		Bridges:  probeLines(sections["BRIDGES"]),
+	}
+	if path != "" {
+		// Filesystem 1-blocks Used Available Capacity Mounted-on
+		fields := strings.Fields(sections["DF"])
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("probe host: cannot read free space of %s", path)
+		}
+		p.FreeBytes, _ = strconv.ParseInt(fields[3], 10, 64)
+	}
+	present := make(map[string]bool)
+	for _, f := range probeLines(sections["FILES"]) {
+		present[f] = true
+	}
+	for _, f := range files {
+		if !present[f] {
+			p.Missing = append(p.Missing, f)
+		}
+	}
+	return p, nil
+}
+
+func probeLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		// 网桥名可能带 @ 后缀（如 br0@eth0）
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, strings.SplitN(line, "@", 2)[0])
+		}
+	}
+	return lines
+}
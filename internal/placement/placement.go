@@ -0,0 +1,166 @@
+// Package placement 为 VM 选择目标宿主机
+//
+// 只做纯计算：调用方采集各宿主机的容量和已分配资源，Plan 按内存余量和 vCPU 分配率
+// 依次放置 VM，每放置一台即扣减目标宿主机的余量，保证整体计划不会超额；
+// Schedule 为单台新 VM 按过滤条件、放置策略和放置组选择宿主机并说明原因
+package placement
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Host 候选宿主机的容量与当前负载
+type Host struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	MemTotalMB     int64   `json:"memTotalMB"`
+	MemUsedMB      int64   `json:"memUsedMB"`
+	CPUs           int     `json:"cpus"` // 逻辑 CPU 数，0 为未知（不检查 vCPU 分配率）
+	CPUPercent     float64 `json:"cpuPercent"`
+	VCPUsAllocated int     `json:"vcpusAllocated"` // 运行中 VM 的 vCPU 合计
+	MemAllocatedMB int64   `json:"memAllocatedMB"` // 运行中 VM 的内存合计
+	CPURatio       float64 `json:"cpuRatio"`       // 宿主机的 vCPU 超分比，0 使用 Options
+	MemRatio       float64 `json:"memRatio"`       // 宿主机的内存超分比，0 使用 Options
+	Unavailable    string  `json:"unavailable"`    // 非空时不参与放置（未连接、维护中、采集失败）
+
+	// 以下仅 Schedule 使用，由调用方按请求探测
+	Tags          []string `json:"tags"`
+	Networks      []string `json:"networks"`      // 活动的 libvirt 网络
+	Bridges       []string `json:"bridges"`       // 宿主机网桥
+	DiskFreeGB    int64    `json:"diskFreeGB"`    // 存储路径所在文件系统的可用空间
+	MissingImages []string `json:"missingImages"` // 请求需要但宿主机上不存在的镜像 / ISO
+	VMs           []string `json:"vms"`           // 宿主机上的全部 VM（用于放置组）
+}
+
+// MemFreeMB 扣除预留后的可用内存
+func (h *Host) MemFreeMB(reserveMB int64) int64 {
+	return h.MemTotalMB - h.MemUsedMB - reserveMB
+}
+
+// cpuRatio 生效的 vCPU 超分比
+func (h *Host) cpuRatio(opt Options) float64 {
+	if h.CPURatio > 0 {
+		return h.CPURatio
+	}
+	return opt.CPURatio
+}
+
+// memRatio 生效的内存超分比
+func (h *Host) memRatio(opt Options) float64 {
+	if h.MemRatio > 0 {
+		return h.MemRatio
+	}
+	return opt.MemRatio
+}
+
+// VM 待放置的 VM
+type VM struct {
+	Name     string `json:"name"`
+	VCPUs    int    `json:"vcpus"`
+	MemoryMB int64  `json:"memoryMB"`
+}
+
+// Assignment 放置结果，HostID 为空表示无法放置，Reason 说明原因
+type Assignment struct {
+	VM       string `json:"vm"`
+	HostID   string `json:"hostId"`
+	HostName string `json:"hostName"`
+	Reason   string `json:"reason"`
+}
+
+// Options 放置约束
+type Options struct {
+	MemReserveMB int64   // 每台宿主机保留给系统的内存
+	CPURatio     float64 // vCPU 超分比（已分配 vCPU / 逻辑 CPU 的上限）
+	MemRatio     float64 // 内存超分比（已分配内存 / 物理内存的上限），0 只检查实际可用内存
+}
+
+// DefaultOptions 默认保留 1 GB 内存，vCPU 最多超分 4 倍，内存按实际用量放置
+var DefaultOptions = Options{MemReserveMB: 1024, CPURatio: 4}
+
+// Plan 为一组 VM 计算放置方案，按内存从大到小依次放到可用内存最多的宿主机上
+// 内存相同时选 CPU 使用率低的；返回顺序与放置顺序一致
+func Plan(vms []VM, hosts []Host, opt Options) []Assignment {
+	pool := make([]Host, len(hosts))
+	copy(pool, hosts)
+
+	order := make([]VM, len(vms))
+	copy(order, vms)
+	sort.SliceStable(order, func(i, j int) bool { return order[i].MemoryMB > order[j].MemoryMB })
+
+	result := make([]Assignment, 0, len(order))
+	for _, v := range order {
+		best := -1
+		for i := range pool {
+			if fits(&pool[i], v, opt) != "" {
+				continue
+			}
+			if best < 0 || better(&pool[i], &pool[best], opt) {
+				best = i
+			}
+		}
+		if best < 0 {
+			result = append(result, Assignment{VM: v.Name, Reason: rejectReason(pool, v, opt)})
+			continue
+		}
+		h := &pool[best]
+		reason := fmt.Sprintf("most free memory: %d MB left after placement", h.MemFreeMB(opt.MemReserveMB)-v.MemoryMB)
+		if h.CPUs > 0 {
+			reason += fmt.Sprintf(", %d vCPUs on %d CPUs", h.VCPUsAllocated+v.VCPUs, h.CPUs)
+		}
+		result = append(result, Assignment{VM: v.Name, HostID: h.ID, HostName: h.Name, Reason: reason})
+		h.MemUsedMB += v.MemoryMB
+		h.MemAllocatedMB += v.MemoryMB
+		h.VCPUsAllocated += v.VCPUs
+	}
+	return result
+}
+
+// fits 宿主机能否容纳 VM，不能时返回原因
+func fits(h *Host, v VM, opt Options) string {
+	if h.Unavailable != "" {
+		return fmt.Sprintf("%s: %s", h.Name, h.Unavailable)
+	}
+	if free := h.MemFreeMB(opt.MemReserveMB); free < v.MemoryMB {
+		return fmt.Sprintf("%s: only %d MB free", h.Name, max(free, 0))
+	}
+	if ratio := h.memRatio(opt); ratio > 0 {
+		limit := int64(float64(h.MemTotalMB)*ratio) - opt.MemReserveMB
+		if h.MemAllocatedMB+v.MemoryMB > limit {
+			return fmt.Sprintf("%s: %d/%d MB already allocated", h.Name, h.MemAllocatedMB, max(limit, 0))
+		}
+	}
+	if limit := h.vcpuLimit(opt); limit > 0 && h.VCPUsAllocated+v.VCPUs > limit {
+		return fmt.Sprintf("%s: %d/%d vCPUs already allocated", h.Name, h.VCPUsAllocated, limit)
+	}
+	return ""
+}
+
+// vcpuLimit 按超分比可分配的 vCPU 上限，CPU 数未知时为 0（不检查）
+func (h *Host) vcpuLimit(opt Options) int {
+	ratio := h.cpuRatio(opt)
+	if h.CPUs <= 0 || ratio <= 0 {
+		return 0
+	}
+	return int(float64(h.CPUs) * ratio)
+}
+
+func better(a, b *Host, opt Options) bool {
+	fa, fb := a.MemFreeMB(opt.MemReserveMB), b.MemFreeMB(opt.MemReserveMB)
+	if fa != fb {
+		return fa > fb
+	}
+	return a.CPUPercent < b.CPUPercent
+}
+
+func rejectReason(hosts []Host, v VM, opt Options) string {
+	if len(hosts) == 0 {
+		return "no eligible host"
+	}
+	reason := fmt.Sprintf("needs %d MB / %d vCPUs;", v.MemoryMB, v.VCPUs)
+	for i := range hosts {
+		reason += " " + fits(&hosts[i], v, opt) + ";"
+	}
+	return reason[:len(reason)-1]
+}
@@ -0,0 +1,236 @@
+package placement
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Auto 创建 VM 时以此作为宿主机 ID 表示由调度器选择
+const Auto = "auto"
+
+// 放置策略
+const (
+	StrategySpread = "spread" // 选放置后剩余内存最多的宿主机，负载分散
+	StrategyPack   = "pack"   // 选放置后剩余内存最少但仍可容纳的宿主机，集中使用
+)
+
+// 放置组策略
+const (
+	PolicyAffinity     = "affinity"      // 组内 VM 放在同一宿主机
+	PolicyAntiAffinity = "anti-affinity" // 组内 VM 分散到不同宿主机
+)
+
+// ScheduleRequest 单台新 VM 的调度请求
+type ScheduleRequest struct {
+	VMName   string   `json:"vmName"`
+	VCPUs    int      `json:"vcpus"`
+	MemoryMB int64    `json:"memoryMB"`
+	DiskGB   int64    `json:"diskGB"`   // 需要的存储空间，0 不检查
+	Tags     []string `json:"tags"`     // 宿主机须带有全部标签
+	Networks []string `json:"networks"` // 须存在且处于活动状态的 libvirt 网络
+	Bridges  []string `json:"bridges"`  // 须存在的宿主机网桥
+	Strategy string   `json:"strategy"` // spread（默认）| pack
+	Groups   []string `json:"groups"`   // 加入的放置组
+
+	// 由调用方探测，结果填入 Host.DiskFreeGB 和 Host.MissingImages
+	StoragePath string   `json:"storagePath"` // 检查可用空间的路径
+	Images      []string `json:"images"`      // 须存在于宿主机上的镜像 / ISO 路径
+}
+
+// Validate 校验调度请求，空策略按 spread 处理
+func (r *ScheduleRequest) Validate() error {
+	switch r.Strategy {
+	case "":
+		r.Strategy = StrategySpread
+	case StrategySpread, StrategyPack:
+	default:
+		return fmt.Errorf("invalid placement strategy: %s (spread | pack)", r.Strategy)
+	}
+	if r.VCPUs <= 0 || r.MemoryMB <= 0 {
+		return fmt.Errorf("placement needs vcpus and memory")
+	}
+	return nil
+}
+
+// ValidPolicy 是否为支持的放置组策略
+func ValidPolicy(policy string) bool {
+	return policy == PolicyAffinity || policy == PolicyAntiAffinity
+}
+
+// Group 放置组及其成员 VM 名称
+type Group struct {
+	Name    string   `json:"name"`
+	Policy  string   `json:"policy"`
+	Members []string `json:"members"`
+}
+
+// Candidate 单台宿主机的调度结果
+type Candidate struct {
+	HostID    string `json:"hostId"`
+	HostName  string `json:"hostName"`
+	Eligible  bool   `json:"eligible"`
+	Reason    string `json:"reason"`    // 不合格的原因，或合格宿主机放置后的余量
+	MemFreeMB int64  `json:"memFreeMB"` // 放置后剩余内存（仅合格时）
+}
+
+// Decision 调度决策，Candidates 按优先顺序排列，合格的在前
+type Decision struct {
+	HostID     string      `json:"hostId"` // 为空表示没有合格的宿主机
+	HostName   string      `json:"hostName"`
+	Strategy   string      `json:"strategy"`
+	Reason     string      `json:"reason"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Schedule 为新 VM 选择宿主机：依次按标签、网络、网桥、镜像、存储、放置组和容量过滤，
+// 再按策略在合格的宿主机中排序。req 需已通过 Validate
+func Schedule(req ScheduleRequest, hosts []Host, groups []Group, opt Options) *Decision {
+	d := &Decision{Strategy: req.Strategy, Candidates: []Candidate{}}
+	v := VM{Name: req.VMName, VCPUs: req.VCPUs, MemoryMB: req.MemoryMB}
+	affinity, anti, notes := groupHosts(hosts, groups)
+
+	var eligible []*Host
+	for i := range hosts {
+		h := &hosts[i]
+		reason := filter(h, req, affinity, anti)
+		if reason == "" {
+			reason = fits(h, v, opt)
+		}
+		if reason != "" {
+			d.Candidates = append(d.Candidates, Candidate{HostID: h.ID, HostName: h.Name, Reason: strings.TrimPrefix(reason, h.Name+": ")})
+			continue
+		}
+		eligible = append(eligible, h)
+	}
+
+	free := func(h *Host) int64 { return h.MemFreeMB(opt.MemReserveMB) - v.MemoryMB }
+	sort.SliceStable(eligible, func(i, j int) bool {
+		a, b := eligible[i], eligible[j]
+		if fa, fb := free(a), free(b); fa != fb {
+			if req.Strategy == StrategyPack {
+				return fa < fb
+			}
+			return fa > fb
+		}
+		if a.CPUPercent != b.CPUPercent {
+			return a.CPUPercent < b.CPUPercent
+		}
+		return a.Name < b.Name
+	})
+
+	ranked := make([]Candidate, 0, len(hosts))
+	for _, h := range eligible {
+		reason := fmt.Sprintf("%d MB free after placement", free(h))
+		if limit := h.vcpuLimit(opt); limit > 0 {
+			reason += fmt.Sprintf(", %d of %d vCPUs allocated", h.VCPUsAllocated+v.VCPUs, limit)
+		}
+		reason += fmt.Sprintf(", CPU %.0f%%", h.CPUPercent)
+		ranked = append(ranked, Candidate{HostID: h.ID, HostName: h.Name, Eligible: true, Reason: reason, MemFreeMB: free(h)})
+	}
+	d.Candidates = append(ranked, d.Candidates...)
+
+	if len(eligible) == 0 {
+		var reasons []string
+		for _, c := range d.Candidates {
+			reasons = append(reasons, c.HostName+": "+c.Reason)
+		}
+		if len(reasons) == 0 {
+			reasons = append(reasons, "no hosts")
+		}
+		d.Reason = "no eligible host: " + strings.Join(reasons, "; ")
+		return d
+	}
+
+	best := eligible[0]
+	d.HostID, d.HostName = best.ID, best.Name
+	if req.Strategy == StrategyPack {
+		d.Reason = fmt.Sprintf("pack: %s has the least free memory that still fits (%d MB after placement)", best.Name, free(best))
+	} else {
+		d.Reason = fmt.Sprintf("spread: %s has the most free memory (%d MB after placement)", best.Name, free(best))
+	}
+	d.Reason += fmt.Sprintf("; %d of %d hosts eligible", len(eligible), len(hosts))
+	for _, n := range notes {
+		d.Reason += "; " + n
+	}
+	return d
+}
+
+// filter 检查宿主机是否满足请求的非容量条件，不满足时返回原因
+func filter(h *Host, req ScheduleRequest, affinity, anti map[string]string) string {
+	if h.Unavailable != "" {
+		return h.Unavailable
+	}
+	for _, tag := range req.Tags {
+		if !slices.Contains(h.Tags, tag) {
+			return fmt.Sprintf("missing tag %s", tag)
+		}
+	}
+	for _, n := range req.Networks {
+		if !slices.Contains(h.Networks, n) {
+			return fmt.Sprintf("network %s not active", n)
+		}
+	}
+	for _, br := range req.Bridges {
+		if !slices.Contains(h.Bridges, br) {
+			return fmt.Sprintf("bridge %s not found", br)
+		}
+	}
+	if len(h.MissingImages) > 0 {
+		return fmt.Sprintf("image %s not available", h.MissingImages[0])
+	}
+	if req.DiskGB > 0 && h.DiskFreeGB < req.DiskGB {
+		return fmt.Sprintf("only %d GB storage free, needs %d GB", max(h.DiskFreeGB, 0), req.DiskGB)
+	}
+	if affinity != nil {
+		if _, ok := affinity[h.ID]; !ok {
+			return "affinity: group members run elsewhere"
+		}
+	}
+	if reason, ok := anti[h.ID]; ok {
+		return reason
+	}
+	return ""
+}
+
+// groupHosts 按放置组成员所在的宿主机计算约束：affinity 为允许的宿主机（nil 表示不限），
+// anti 为排除的宿主机及原因；notes 说明生效的亲和约束
+func groupHosts(hosts []Host, groups []Group) (affinity, anti map[string]string, notes []string) {
+	anti = make(map[string]string)
+	for _, g := range groups {
+		holders := make(map[string]string) // hostID -> 首个成员
+		for _, h := range hosts {
+			for _, m := range g.Members {
+				if slices.Contains(h.VMs, m) {
+					holders[h.ID] = m
+					break
+				}
+			}
+		}
+		if len(holders) == 0 {
+			continue
+		}
+		switch g.Policy {
+		case PolicyAffinity:
+			if affinity == nil {
+				affinity = holders
+			} else {
+				for id := range affinity {
+					if _, ok := holders[id]; !ok {
+						delete(affinity, id)
+					}
+				}
+			}
+			notes = append(notes, fmt.Sprintf("affinity group %s: co-located with existing members", g.Name))
+		case PolicyAntiAffinity:
+			for id, m := range holders {
+				if _, ok := anti[id]; !ok {
+					anti[id] = fmt.Sprintf("anti-affinity group %s: %s already runs here", g.Name, m)
+				}
+			}
+			notes = append(notes, fmt.Sprintf("anti-affinity group %s: avoided %d host(s)", g.Name, len(holders)))
+		}
+	}
+	return affinity, anti, notes
+}
@@ -0,0 +1,204 @@
+package placement
+
+import (
+	"strings"
+	"testing"
+)
+
+// testHosts a 剩余 11264 MB，b 剩余 15360 MB 但 vCPU 已接近上限（15/16），c 未连接
+func testHosts() []Host {
+	return []Host{
+		{ID: "a", Name: "a", MemTotalMB: 16384, MemUsedMB: 4096, CPUs: 8, CPUPercent: 30,
+			Tags: []string{"ssd"}, Networks: []string{"default"}, Bridges: []string{"br0"}, DiskFreeGB: 100, VMs: []string{"web1"}},
+		{ID: "b", Name: "b", MemTotalMB: 32768, MemUsedMB: 16384, CPUs: 4, VCPUsAllocated: 15, CPUPercent: 10,
+			Tags: []string{"ssd", "gpu"}, Bridges: []string{"br0"}, DiskFreeGB: 10, VMs: []string{"db1"}},
+		{ID: "c", Name: "c", Unavailable: "not connected"},
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      ScheduleRequest
+		groups   []Group
+		hosts    func(hosts []Host)
+		want     string            // 选中的宿主机，空为无合格宿主机
+		reason   string            // Decision.Reason 须包含
+		rejected map[string]string // 不合格宿主机及原因须包含
+	}{
+		{
+			name:     "spread picks most free memory",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			want:     "b",
+			reason:   "spread: b has the most free memory (13312 MB after placement); 2 of 3 hosts eligible",
+			rejected: map[string]string{"c": "not connected"},
+		},
+		{
+			name:   "pack picks least free memory",
+			req:    ScheduleRequest{VCPUs: 1, MemoryMB: 2048, Strategy: StrategyPack},
+			want:   "a",
+			reason: "pack: a has the least free memory",
+		},
+		{
+			name:     "tags",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048, Tags: []string{"gpu"}},
+			want:     "b",
+			rejected: map[string]string{"a": "missing tag gpu"},
+		},
+		{
+			name:     "vcpu overcommit",
+			req:      ScheduleRequest{VCPUs: 2, MemoryMB: 2048},
+			want:     "a",
+			rejected: map[string]string{"b": "15/16 vCPUs already allocated"},
+		},
+		{
+			name:     "host cpu ratio overrides default",
+			req:      ScheduleRequest{VCPUs: 2, MemoryMB: 2048},
+			hosts:    func(h []Host) { h[1].CPURatio = 8 },
+			want:     "b",
+			rejected: map[string]string{"c": "not connected"},
+		},
+		{
+			name:     "memory",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 12000},
+			want:     "b",
+			rejected: map[string]string{"a": "only 11264 MB free"},
+		},
+		{
+			name:     "memory overcommit ratio",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			hosts:    func(h []Host) { h[1].MemRatio = 1; h[1].MemAllocatedMB = 31000 },
+			want:     "a",
+			rejected: map[string]string{"b": "31000/31744 MB already allocated"},
+		},
+		{
+			name:   "no eligible host",
+			req:    ScheduleRequest{VCPUs: 1, MemoryMB: 20000},
+			want:   "",
+			reason: "no eligible host: a: only 11264 MB free; b: only 15360 MB free; c: not connected",
+		},
+		{
+			name:     "network",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048, Networks: []string{"default"}},
+			want:     "a",
+			rejected: map[string]string{"b": "network default not active"},
+		},
+		{
+			name:     "bridge",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048, Bridges: []string{"br1"}},
+			want:     "",
+			rejected: map[string]string{"a": "bridge br1 not found", "b": "bridge br1 not found"},
+		},
+		{
+			name:     "disk",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048, DiskGB: 50},
+			want:     "a",
+			rejected: map[string]string{"b": "only 10 GB storage free, needs 50 GB"},
+		},
+		{
+			name:     "missing image",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			hosts:    func(h []Host) { h[1].MissingImages = []string{"/iso/debian.iso"} },
+			want:     "a",
+			rejected: map[string]string{"b": "image /iso/debian.iso not available"},
+		},
+		{
+			name:     "anti-affinity avoids members",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			groups:   []Group{{Name: "db", Policy: PolicyAntiAffinity, Members: []string{"db1"}}},
+			want:     "a",
+			reason:   "anti-affinity group db: avoided 1 host(s)",
+			rejected: map[string]string{"b": "anti-affinity group db: db1 already runs here"},
+		},
+		{
+			name:     "affinity follows members",
+			req:      ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			groups:   []Group{{Name: "web", Policy: PolicyAffinity, Members: []string{"web1"}}},
+			want:     "a",
+			reason:   "affinity group web: co-located with existing members",
+			rejected: map[string]string{"b": "affinity: group members run elsewhere"},
+		},
+		{
+			name:   "affinity without placed members is unconstrained",
+			req:    ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			groups: []Group{{Name: "new", Policy: PolicyAffinity, Members: []string{"web9"}}},
+			want:   "b",
+		},
+		{
+			name: "conflicting affinity groups",
+			req:  ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			groups: []Group{
+				{Name: "web", Policy: PolicyAffinity, Members: []string{"web1"}},
+				{Name: "db", Policy: PolicyAffinity, Members: []string{"db1"}},
+			},
+			want: "",
+		},
+		{
+			name:  "equal free memory prefers lower CPU",
+			req:   ScheduleRequest{VCPUs: 1, MemoryMB: 2048},
+			hosts: func(h []Host) { h[1].MemUsedMB = 20480 },
+			want:  "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts := testHosts()
+			if tt.hosts != nil {
+				tt.hosts(hosts)
+			}
+			if err := tt.req.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			d := Schedule(tt.req, hosts, tt.groups, DefaultOptions)
+
+			if d.HostID != tt.want {
+				t.Fatalf("HostID = %q, want %q (%s)", d.HostID, tt.want, d.Reason)
+			}
+			if tt.reason != "" && !strings.Contains(d.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want it to contain %q", d.Reason, tt.reason)
+			}
+			if len(d.Candidates) != len(hosts) {
+				t.Fatalf("got %d candidates, want %d", len(d.Candidates), len(hosts))
+			}
+			if tt.want != "" && (d.Candidates[0].HostID != tt.want || !d.Candidates[0].Eligible) {
+				t.Errorf("first candidate = %+v, want eligible %s", d.Candidates[0], tt.want)
+			}
+			seenRejected := false
+			for _, c := range d.Candidates {
+				if c.Eligible && seenRejected {
+					t.Errorf("eligible candidate %s listed after a rejected one", c.HostID)
+				}
+				seenRejected = seenRejected || !c.Eligible
+				want, ok := tt.rejected[c.HostID]
+				if !ok {
+					continue
+				}
+				if c.Eligible || !strings.Contains(c.Reason, want) {
+					t.Errorf("candidate %s = %+v, want rejected with %q", c.HostID, c, want)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleRequestValidate(t *testing.T) {
+	tests := []struct {
+		req     ScheduleRequest
+		wantErr bool
+	}{
+		{ScheduleRequest{VCPUs: 1, MemoryMB: 512}, false},
+		{ScheduleRequest{VCPUs: 1, MemoryMB: 512, Strategy: StrategyPack}, false},
+		{ScheduleRequest{VCPUs: 1, MemoryMB: 512, Strategy: "random"}, true},
+		{ScheduleRequest{MemoryMB: 512}, true},
+		{ScheduleRequest{VCPUs: 1}, true},
+	}
+	for _, tt := range tests {
+		err := tt.req.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, wantErr %v", tt.req, err, tt.wantErr)
+		}
+		if err == nil && tt.req.Strategy == "" {
+			t.Errorf("Validate(%+v) left strategy empty", tt.req)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package placement
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"vmcat/internal/monitor"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// DefaultStorageDir 未指定磁盘路径时 virt-install 默认存储池所在目录
+const DefaultStorageDir = "/var/lib/libvirt/images"
+
+// Scheduler 采集各宿主机的容量与已分配资源，供 Plan / Schedule 使用
+type Scheduler struct {
+	pool    *internalssh.Pool
+	store   *store.Store
+	monitor *monitor.Collector
+	vms     *vm.Manager
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(pool *internalssh.Pool, s *store.Store, mon *monitor.Collector, vms *vm.Manager) *Scheduler {
+	return &Scheduler{pool: pool, store: s, monitor: mon, vms: vms}
+}
+
+// Hosts 采集除 exclude 外各宿主机的容量和已分配资源
+// 未连接、维护中或采集失败的宿主机标记 Unavailable，不参与放置但保留在结果中以便说明原因
+func (s *Scheduler) Hosts(ctx context.Context, exclude string) []Host {
+	list, err := s.store.HostList()
+	if err != nil {
+		return nil
+	}
+	ratios := make(map[string]store.HostOvercommit)
+	if oc, err := s.store.HostOvercommitList(); err == nil {
+		for _, o := range oc {
+			ratios[o.HostID] = o
+		}
+	}
+	var hosts []Host
+	for _, h := range list {
+		if h.ID == exclude {
+			continue
+		}
+		ph := Host{
+			ID:       h.ID,
+			Name:     h.Name,
+			CPURatio: ratios[h.ID].CPURatio,
+			MemRatio: ratios[h.ID].MemRatio,
+			Tags:     splitTags(h.Tags),
+		}
+		switch {
+		case !s.pool.IsConnected(h.ID):
+			ph.Unavailable = "not connected"
+		case s.store.InMaintenance(h.ID):
+			ph.Unavailable = "in maintenance"
+		}
+		if ph.Unavailable != "" {
+			hosts = append(hosts, ph)
+			continue
+		}
+		stats, err := s.monitor.CollectContext(ctx, h.ID)
+		if err != nil {
+			log.Printf("placement: host %s: %v", h.ID, err)
+			ph.Unavailable = "stats unavailable"
+			hosts = append(hosts, ph)
+			continue
+		}
+		vms, err := s.vms.List(ctx, h.ID)
+		if err != nil {
+			log.Printf("placement: host %s: %v", h.ID, err)
+			ph.Unavailable = "VM list unavailable"
+			hosts = append(hosts, ph)
+			continue
+		}
+		ph.MemTotalMB = stats.MemTotal
+		ph.MemUsedMB = stats.MemUsed
+		ph.CPUPercent = stats.CPUPercent
+		for _, v := range vms {
+			ph.VMs = append(ph.VMs, v.Name)
+			if v.State == "running" {
+				ph.VCPUsAllocated += v.CPUs
+				ph.MemAllocatedMB += int64(v.MemoryMB)
+			}
+		}
+		if inv := monitor.CachedInventory(s.store, h.ID); inv != nil {
+			ph.CPUs = inv.CPU.CPUs
+		}
+		hosts = append(hosts, ph)
+	}
+	return hosts
+}
+
+// Groups 按名称加载放置组，不存在时返回的错误包装 sql.ErrNoRows
+func (s *Scheduler) Groups(names []string) ([]Group, error) {
+	var groups []Group
+	for _, name := range names {
+		g, err := s.store.PlacementGroupGet(name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("placement group %s not found: %w", name, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get placement group %s: %w", name, err)
+		}
+		groups = append(groups, Group{Name: g.Name, Policy: g.Policy, Members: g.Members})
+	}
+	return groups, nil
+}
+
+// Schedule 采集并探测各宿主机后按请求选择宿主机
+func (s *Scheduler) Schedule(ctx context.Context, req ScheduleRequest) (*Decision, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	groups, err := s.Groups(req.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := s.Hosts(ctx, "")
+	storage := ""
+	if req.DiskGB > 0 {
+		storage = req.StoragePath
+		if storage == "" {
+			storage = DefaultStorageDir
+		}
+	}
+	if storage != "" || len(req.Networks) > 0 || len(req.Bridges) > 0 || len(req.Images) > 0 {
+		for i := range hosts {
+			h := &hosts[i]
+			if h.Unavailable != "" {
+				continue
+			}
+			p, err := s.monitor.Probe(ctx, h.ID, storage, req.Images)
+			if err != nil {
+				log.Printf("placement: host %s: %v", h.ID, err)
+				h.Unavailable = "probe failed"
+				continue
+			}
+			h.Networks = p.Networks
+			h.Bridges = p.Bridges
+			h.DiskFreeGB = p.FreeBytes >> 30
+			h.MissingImages = p.Missing
+		}
+	}
+	return Schedule(req, hosts, groups, DefaultOptions), nil
+}
+
+// Place hostID 为 Auto 时调度并返回选中的宿主机和决策，否则原样返回（只检查放置组是否存在）
+// 调度失败的错误中包含各宿主机不合格的原因
+func (s *Scheduler) Place(ctx context.Context, hostID string, req ScheduleRequest) (string, *Decision, error) {
+	if hostID != Auto {
+		if _, err := s.Groups(req.Groups); err != nil {
+			return "", nil, err
+		}
+		return hostID, nil, nil
+	}
+	d, err := s.Schedule(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	if d.HostID == "" {
+		return "", d, fmt.Errorf("%s", d.Reason)
+	}
+	return d.HostID, d, nil
+}
+
+// Join 新 VM 创建成功后加入放置组
+func (s *Scheduler) Join(groups []string, vmName string) {
+	for _, name := range groups {
+		if err := s.store.PlacementGroupAddVM(name, vmName); err != nil {
+			log.Printf("placement group %s: add %s: %v", name, vmName, err)
+		}
+	}
+}
+
+// splitTags 拆分宿主机的逗号分隔标签
+func splitTags(tags string) []string {
+	var list []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			list = append(list, t)
+		}
+	}
+	return list
+}
@@ -0,0 +1,135 @@
+// Package retention 按保留策略选择要清理的自动快照
+//
+// 只处理 VMCat 自动创建的快照（名称为 vmcat-auto-<时间>），手动命名的快照不参与选择。
+// 策略与 restic / borg 的 --keep-* 一致：保留最新 N 个，以及最近 N 天 / 周 / 月中每天 / 周 / 月最新的一个，
+// 被任一规则保留的快照都保留
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prefix 自动快照的名称前缀
+const Prefix = "vmcat-auto-"
+
+const nameLayout = "20060102-150405"
+
+// Policy 保留策略，全部为 0 时不清理
+type Policy struct {
+	KeepLast    int `json:"keepLast"`
+	KeepDaily   int `json:"keepDaily"`
+	KeepWeekly  int `json:"keepWeekly"`
+	KeepMonthly int `json:"keepMonthly"`
+}
+
+// Empty 策略未设置任何保留规则
+func (p Policy) Empty() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 && p.KeepMonthly <= 0
+}
+
+// Validate 检查保留数量
+func (p Policy) Validate() error {
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 || p.KeepMonthly < 0 {
+		return fmt.Errorf("keep counts must not be negative")
+	}
+	return nil
+}
+
+// String 策略说明，如 "last 3, daily 7"
+func (p Policy) String() string {
+	var parts []string
+	for _, r := range []struct {
+		name string
+		n    int
+	}{{"last", p.KeepLast}, {"daily", p.KeepDaily}, {"weekly", p.KeepWeekly}, {"monthly", p.KeepMonthly}} {
+		if r.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", r.name, r.n))
+		}
+	}
+	if len(parts) == 0 {
+		return "keep all"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Name 生成 t 时刻的自动快照名称
+func Name(t time.Time) string {
+	return Prefix + t.Format(nameLayout)
+}
+
+// ParseName 解析自动快照名称中的时间（本地时区），手动命名的快照返回 false
+func ParseName(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(name, Prefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(nameLayout, rest, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Item 参与选择的快照
+type Item struct {
+	Name string
+	Time time.Time
+}
+
+// Verdict 单个快照的选择结果，Reasons 为保留它的规则（last / daily / weekly / monthly）
+type Verdict struct {
+	Name    string   `json:"name"`
+	Time    string   `json:"time"`
+	Keep    bool     `json:"keep"`
+	Reasons []string `json:"reasons"`
+}
+
+// Apply 按策略逐个决定保留或清理，结果按时间从新到旧排列；策略为空时全部保留
+func Apply(items []Item, p Policy) []Verdict {
+	sorted := append([]Item(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+
+	out := make([]Verdict, len(sorted))
+	for i, it := range sorted {
+		out[i] = Verdict{Name: it.Name, Time: it.Time.Format("2006-01-02 15:04:05"), Keep: p.Empty()}
+	}
+	if p.Empty() {
+		return out
+	}
+
+	keep := func(i int, reason string) {
+		out[i].Keep = true
+		out[i].Reasons = append(out[i].Reasons, reason)
+	}
+	for i := 0; i < len(sorted) && i < p.KeepLast; i++ {
+		keep(i, "last")
+	}
+
+	buckets := []struct {
+		reason string
+		n      int
+		key    func(t time.Time) string
+	}{
+		{"daily", p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", p.KeepWeekly, func(t time.Time) string {
+			y, w := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", y, w)
+		}},
+		{"monthly", p.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, b := range buckets {
+		last, taken := "", 0
+		for i := 0; i < len(sorted) && taken < b.n; i++ {
+			// 从新到旧遍历，每个时间段的第一个即该段最新的快照
+			if key := b.key(sorted[i].Time); key != last {
+				keep(i, b.reason)
+				last = key
+				taken++
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package retention
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func items(times ...string) []Item {
+	var list []Item
+	for _, s := range times {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			panic(err)
+		}
+		list = append(list, Item{Name: Name(t), Time: t})
+	}
+	return list
+}
+
+// snapshots 从新到旧：2026-10-16 为周五（ISO 第 42 周），10-11 为第 41 周，10-04 和 09-30 为第 40 周
+var snapshots = items(
+	"2026-10-16 20:00",
+	"2026-10-16 08:00",
+	"2026-10-15 20:00",
+	"2026-10-14 20:00",
+	"2026-10-11 20:00",
+	"2026-10-04 20:00",
+	"2026-09-30 20:00",
+	"2026-08-31 20:00",
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		items  []Item
+		policy Policy
+		want   []string // 按从新到旧，保留的为规则列表，清理的为 "-"
+	}{
+		{
+			name:  "empty policy keeps all",
+			items: snapshots,
+			want:  []string{"", "", "", "", "", "", "", ""},
+		},
+		{
+			name:   "last",
+			items:  snapshots,
+			policy: Policy{KeepLast: 2},
+			want:   []string{"last", "last", "-", "-", "-", "-", "-", "-"},
+		},
+		{
+			name:   "daily keeps the newest of each day",
+			items:  snapshots,
+			policy: Policy{KeepDaily: 3},
+			want:   []string{"daily", "-", "daily", "daily", "-", "-", "-", "-"},
+		},
+		{
+			name:   "weekly uses ISO weeks",
+			items:  snapshots,
+			policy: Policy{KeepWeekly: 3},
+			want:   []string{"weekly", "-", "-", "-", "weekly", "weekly", "-", "-"},
+		},
+		{
+			name:   "monthly",
+			items:  snapshots,
+			policy: Policy{KeepMonthly: 2},
+			want:   []string{"monthly", "-", "-", "-", "-", "-", "monthly", "-"},
+		},
+		{
+			name:   "rules combine",
+			items:  snapshots,
+			policy: Policy{KeepLast: 1, KeepDaily: 2, KeepMonthly: 3},
+			want:   []string{"last,daily,monthly", "-", "daily", "-", "-", "-", "monthly", "monthly"},
+		},
+		{
+			name:   "keep counts larger than the number of snapshots",
+			items:  snapshots[:3],
+			policy: Policy{KeepLast: 10, KeepWeekly: 10},
+			want:   []string{"last,weekly", "last", "last"},
+		},
+		{
+			name:   "input order does not matter",
+			items:  []Item{snapshots[2], snapshots[0], snapshots[1]},
+			policy: Policy{KeepDaily: 1},
+			want:   []string{"daily", "-", "-"},
+		},
+		{
+			name:   "ISO week spans the year boundary",
+			items:  items("2027-01-04 00:00", "2027-01-01 00:00", "2026-12-31 00:00", "2026-12-27 00:00"),
+			policy: Policy{KeepWeekly: 3},
+			want:   []string{"weekly", "weekly", "-", "weekly"},
+		},
+		{
+			name:   "no snapshots",
+			policy: Policy{KeepLast: 1},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Apply(tt.items, tt.policy)
+			if len(out) != len(tt.want) {
+				t.Fatalf("got %d verdicts, want %d", len(out), len(tt.want))
+			}
+			for i, v := range out {
+				if i > 0 && v.Time > out[i-1].Time {
+					t.Errorf("verdict %d (%s) is newer than %d (%s)", i, v.Time, i-1, out[i-1].Time)
+				}
+				got := strings.Join(v.Reasons, ",")
+				if !v.Keep {
+					got = "-"
+				}
+				if got != tt.want[i] {
+					t.Errorf("%s: got %q, want %q", v.Time, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	at := time.Date(2026, 10, 16, 20, 5, 9, 0, time.Local)
+	name := Name(at)
+	if name != "vmcat-auto-20261016-200509" {
+		t.Errorf("Name = %s", name)
+	}
+	if got, ok := ParseName(name); !ok || !got.Equal(at) {
+		t.Errorf("ParseName(%s) = %v, %v", name, got, ok)
+	}
+	for _, name := range []string{"before-upgrade", "vmcat-auto-", "vmcat-auto-2026-10-16", "vmcat-auto-20261016-200509-x"} {
+		if _, ok := ParseName(name); ok {
+			t.Errorf("ParseName(%s) succeeded", name)
+		}
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	tests := []struct {
+		p    Policy
+		want string
+	}{
+		{Policy{}, "keep all"},
+		{Policy{KeepLast: 3, KeepDaily: 7}, "last 3, daily 7"},
+		{Policy{KeepWeekly: 4, KeepMonthly: 6}, "weekly 4, monthly 6"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+	if err := (Policy{KeepDaily: -1}).Validate(); err == nil {
+		t.Error("negative keep count should be rejected")
+	}
+}
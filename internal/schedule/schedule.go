@@ -0,0 +1,579 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"vmcat/internal/cron"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+const (
+	checkInterval  = 20 * time.Second // 检查到期定时任务的间隔
+	missedGrace    = 2 * time.Minute  // 超过计划时间多久视为错过执行（服务停止期间到期）
+	listTimeout    = 30 * time.Second // 查询单台宿主机 VM 列表的超时
+	resultLimit    = 4096             // 执行记录中保存的 action 返回值上限（字节）
+	maxJitter      = 3600             // 随机推迟上限（秒）
+	jitterFraction = 50               // 随机推迟占执行间隔的百分比上限
+	actorPrefix    = "schedule:"      // 定时执行时审计日志中的操作者前缀
+)
+
+// forbidden 不允许定时执行的 action 前缀
+var forbidden = []string{"schedule.", "user.", "token.", "auth."}
+
+// Actions 定时任务执行的 action，由调用方按 API 的 action 表实现
+type Actions interface {
+	// Fields 返回 action 参数的 JSON 字段名，action 不存在或不可调用时 ok 为 false
+	Fields(action string) (fields map[string]bool, ok bool)
+	// Dispatch 以 actor 作为审计操作者执行 action，不经过权限检查（定时任务只能由管理员创建）
+	Dispatch(ctx context.Context, actor, action string, params json.RawMessage) (interface{}, error)
+}
+
+// Runner 定时任务调度：到期的任务在后台对选中的宿主机或 VM 执行 action 并记录结果
+type Runner struct {
+	pool    *internalssh.Pool
+	store   *store.Store
+	vms     *vm.Manager
+	actions Actions
+
+	stop    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	running map[string]bool // 正在执行的定时任务 ID
+}
+
+// target 一次执行的对象，VMName 为空表示宿主机或不指定对象
+type target struct {
+	HostID   string
+	HostName string
+	VMName   string
+	Err      error // 无法确定对象（如宿主机未连接）时直接记为失败
+}
+
+// NewRunner 创建定时任务调度器（调用 Start 后开始检查）
+func NewRunner(pool *internalssh.Pool, s *store.Store, vms *vm.Manager, actions Actions) *Runner {
+	return &Runner{
+		pool:    pool,
+		store:   s,
+		vms:     vms,
+		actions: actions,
+		stop:    make(chan struct{}),
+		running: make(map[string]bool),
+	}
+}
+
+// Start 启动定时检查
+func (r *Runner) Start() {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.check()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时检查，执行中的任务不会中断
+func (r *Runner) Stop() {
+	r.once.Do(func() { close(r.stop) })
+}
+
+// RunNow 立即执行一次定时任务（不影响下次执行时间），等待执行结束后返回执行记录；actor 为手动触发者
+func (r *Runner) RunNow(ctx context.Context, sc *store.Schedule, actor string) (*store.ScheduleRun, error) {
+	if !r.begin(sc.ID) {
+		return nil, fmt.Errorf("schedule %s is already running", sc.Name)
+	}
+	defer r.end(sc.ID)
+	r.store.AuditInsert(actor, sc.HostID, "", "schedule.run", sc.Name)
+	return r.execute(ctx, sc, "", 0, "run manually"), nil
+}
+
+// check 执行到期的定时任务
+// 超过计划时间 missedGrace 仍未执行的视为错过：按任务配置跳过或补执行一次，并记录错过的次数
+func (r *Runner) check() {
+	list, err := r.store.ScheduleList()
+	if err != nil {
+		log.Printf("schedule: %v", err)
+		return
+	}
+	now := time.Now()
+	for i := range list {
+		sc := &list[i]
+		if !sc.Enabled {
+			continue
+		}
+		if sc.NextRunAt == "" {
+			r.advance(sc, now)
+			continue
+		}
+		due, err := ParseRunAt(sc.NextRunAt)
+		if err != nil {
+			r.advance(sc, now)
+			continue
+		}
+		if now.Before(due) {
+			continue
+		}
+
+		missed := 0
+		if now.Sub(due) > missedGrace {
+			missed = countMissed(sc, due, now)
+		}
+		scheduledFor := sc.NextRunAt
+		r.advance(sc, now)
+
+		if missed > 0 && sc.Missed != store.ScheduleMissedRun {
+			r.recordSkipped(sc, scheduledFor, missed, fmt.Sprintf("missed %d run(s) while the server was down", missed))
+			continue
+		}
+		message := ""
+		if missed > 0 {
+			message = fmt.Sprintf("catching up after %d missed run(s)", missed)
+		}
+		if !r.begin(sc.ID) {
+			r.recordSkipped(sc, scheduledFor, missed, "previous run is still in progress")
+			continue
+		}
+		go func(sc store.Schedule) {
+			defer r.end(sc.ID)
+			r.execute(context.Background(), &sc, scheduledFor, missed, message)
+		}(*sc)
+	}
+}
+
+// advance 计算并保存下次执行时间
+func (r *Runner) advance(sc *store.Schedule, after time.Time) {
+	next, err := Next(sc, after)
+	if err != nil {
+		log.Printf("schedule %s: %v", sc.Name, err)
+		sc.NextRunAt = ""
+	} else {
+		sc.NextRunAt = FormatRunAt(next)
+	}
+	if err := r.store.ScheduleSetNextRun(sc.ID, sc.NextRunAt); err != nil {
+		log.Printf("schedule %s: %v", sc.Name, err)
+	}
+}
+
+// Next 按任务时区计算 after 之后的下次执行时间，并加上 0 ~ jitterSeconds 秒的随机推迟
+// 推迟不超过到再下一次执行间隔的一半，避免越过下一次执行
+func Next(sc *store.Schedule, after time.Time) (time.Time, error) {
+	expr, err := cron.Parse(sc.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := location(sc.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := expr.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron %q never fires", sc.Cron)
+	}
+	if jitter := time.Duration(sc.JitterSeconds) * time.Second; jitter > 0 {
+		if following := expr.Next(next); !following.IsZero() {
+			jitter = min(jitter, following.Sub(next)*jitterFraction/100)
+		}
+		next = next.Add(time.Duration(rand.Int64N(int64(jitter/time.Second)+1)) * time.Second)
+	}
+	return next.Local(), nil
+}
+
+// FormatRunAt 格式化保存的下次执行时间：RFC 3339 带 UTC 偏移，夏令时结束时重复的一小时也能区分
+func FormatRunAt(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// ParseRunAt 解析 FormatRunAt 保存的时间，兼容旧版本保存的不带偏移的服务端本地时间
+func ParseRunAt(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+}
+
+// countMissed 统计 due 到 now 之间应执行的次数（含 due 本身），最多统计 1000 次
+func countMissed(sc *store.Schedule, due, now time.Time) int {
+	expr, err := cron.Parse(sc.Cron)
+	if err != nil {
+		return 1
+	}
+	loc, err := location(sc.Timezone)
+	if err != nil {
+		return 1
+	}
+	n := 1
+	for t := expr.Next(due.In(loc)); !t.IsZero() && !t.After(now) && n < 1000; t = expr.Next(t) {
+		n++
+	}
+	return n
+}
+
+// location 解析时区，空为服务端本地时区
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Preview 预览 cron 表达式接下来 count 次的触发时间，按时区格式化并带 UTC 偏移
+func Preview(expr, timezone string, count int) ([]string, error) {
+	s, err := cron.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := location(timezone)
+	if err != nil {
+		return nil, err
+	}
+	var times []string
+	t := time.Now().In(loc)
+	for i := 0; i < count; i++ {
+		if t = s.Next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t.Format("2006-01-02 15:04:05 -07:00"))
+	}
+	return times, nil
+}
+
+// begin 标记任务开始执行，已在执行中时返回 false
+func (r *Runner) begin(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running[id] {
+		return false
+	}
+	r.running[id] = true
+	return true
+}
+
+func (r *Runner) end(id string) {
+	r.mu.Lock()
+	delete(r.running, id)
+	r.mu.Unlock()
+}
+
+// recordSkipped 记录一次跳过的执行
+func (r *Runner) recordSkipped(sc *store.Schedule, scheduledFor string, missed int, message string) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	run := &store.ScheduleRun{
+		ScheduleID:   sc.ID,
+		ScheduledFor: scheduledFor,
+		StartedAt:    now,
+		FinishedAt:   now,
+		State:        store.ScheduleRunSkipped,
+		Missed:       missed,
+		Message:      message,
+	}
+	if err := r.store.ScheduleRunInsert(run); err != nil {
+		log.Printf("schedule %s: %v", sc.Name, err)
+	}
+}
+
+// execute 对任务选中的每个对象执行一次 action 并记录结果，action 以 schedule:<任务名称> 为操作者执行
+func (r *Runner) execute(ctx context.Context, sc *store.Schedule, scheduledFor string, missed int, message string) *store.ScheduleRun {
+	run := &store.ScheduleRun{
+		ScheduleID:   sc.ID,
+		ScheduledFor: scheduledFor,
+		StartedAt:    time.Now().Format("2006-01-02 15:04:05"),
+		Missed:       missed,
+	}
+	var notes []string
+	if message != "" {
+		notes = append(notes, message)
+	}
+
+	failed := 0
+	targets, err := r.targets(ctx, sc)
+	if err != nil {
+		failed = 1
+		notes = append(notes, err.Error())
+	}
+	if err == nil && len(targets) == 0 {
+		notes = append(notes, "no matching targets")
+	}
+	for _, t := range targets {
+		res := store.ScheduleTargetResult{HostID: t.HostID, VMName: t.VMName}
+		err := t.Err
+		if err == nil {
+			var data json.RawMessage
+			if data, err = r.paramsFor(sc, t); err == nil {
+				var result interface{}
+				if result, err = r.actions.Dispatch(ctx, actorPrefix+sc.Name, sc.Action, data); err == nil && result != nil {
+					if b, merr := json.Marshal(result); merr == nil && len(b) <= resultLimit {
+						res.Result = b
+					}
+				}
+			}
+		}
+		if err != nil {
+			res.Error = err.Error()
+			failed++
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	switch {
+	case failed == 0:
+		run.State = store.ScheduleRunSucceeded
+	case failed < len(targets):
+		run.State = store.ScheduleRunPartial
+		notes = append(notes, fmt.Sprintf("%d of %d target(s) failed", failed, len(targets)))
+	default:
+		run.State = store.ScheduleRunFailed
+	}
+	run.Message = strings.Join(notes, "; ")
+	run.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+	if err := r.store.ScheduleRunInsert(run); err != nil {
+		log.Printf("schedule %s: %v", sc.Name, err)
+	}
+	return run
+}
+
+// targets 在执行时解析任务选中的对象：按宿主机 ID 和标签筛选宿主机，target 为 vm 时再按名称通配符筛选其上的 VM
+// 未连接的宿主机在 target 为 vm 时记为失败对象
+func (r *Runner) targets(ctx context.Context, sc *store.Schedule) ([]target, error) {
+	if sc.Target == store.ScheduleTargetNone {
+		return []target{{}}, nil
+	}
+	hosts, err := r.store.HostList()
+	if err != nil {
+		return nil, err
+	}
+	var targets []target
+	for _, h := range hosts {
+		if sc.HostID != "" && h.ID != sc.HostID {
+			continue
+		}
+		if !hostTagsMatch(sc.HostTags, h.Tags) {
+			continue
+		}
+		if sc.Target == store.ScheduleTargetHost {
+			targets = append(targets, target{HostID: h.ID, HostName: h.Name})
+			continue
+		}
+		if !r.pool.IsConnected(h.ID) {
+			targets = append(targets, target{HostID: h.ID, HostName: h.Name, Err: fmt.Errorf("host %s is not connected", h.Name)})
+			continue
+		}
+		listCtx, cancel := context.WithTimeout(ctx, listTimeout)
+		vms, err := r.vms.List(listCtx, h.ID)
+		cancel()
+		if err != nil {
+			targets = append(targets, target{HostID: h.ID, HostName: h.Name, Err: err})
+			continue
+		}
+		for _, v := range vms {
+			for _, pattern := range splitList(sc.VMPattern) {
+				if ok, _ := path.Match(pattern, v.Name); ok {
+					targets = append(targets, target{HostID: h.ID, HostName: h.Name, VMName: v.Name})
+					break
+				}
+			}
+		}
+	}
+	return targets, nil
+}
+
+// hostTagsMatch 宿主机带有任一指定标签，未指定时均匹配
+func hostTagsMatch(want, tags string) bool {
+	if want == "" {
+		return true
+	}
+	for _, tag := range splitList(tags) {
+		for _, w := range splitList(want) {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paramsFor 生成对单个对象执行时的 action 参数：展开字符串中的 ${date}、${time}、${vm}、${host}，
+// 并按 action 的参数字段填入宿主机 ID（hostId / srcHostId / id）和 VM 名称（vmName / srcName / name）
+func (r *Runner) paramsFor(sc *store.Schedule, t target) (json.RawMessage, error) {
+	params := make(map[string]interface{})
+	if len(sc.Params) > 0 {
+		if err := json.Unmarshal(sc.Params, &params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	loc, err := location(sc.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(loc)
+	rep := strings.NewReplacer(
+		"${date}", now.Format("20060102"),
+		"${time}", now.Format("150405"),
+		"${vm}", t.VMName,
+		"${host}", t.HostName,
+	)
+	for k, v := range params {
+		params[k] = expand(v, rep)
+	}
+
+	fields, _ := r.actions.Fields(sc.Action)
+	hostKey, vmKey := targetKeys(fields, sc.Action, sc.Target)
+	if sc.Target != store.ScheduleTargetNone {
+		params[hostKey] = t.HostID
+	}
+	if sc.Target == store.ScheduleTargetVM {
+		params[vmKey] = t.VMName
+	}
+	return json.Marshal(params)
+}
+
+// expand 展开参数值（含嵌套对象和数组）中的变量
+func expand(v interface{}, r *strings.Replacer) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Replace(v)
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = expand(item, r)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expand(item, r)
+		}
+	}
+	return v
+}
+
+// targetKeys 返回 action 接收宿主机 ID 和 VM 名称的参数字段，不接收时返回空
+func targetKeys(fields map[string]bool, action, target string) (hostKey, vmKey string) {
+	hostKeys := []string{"hostId", "srcHostId"}
+	if target == store.ScheduleTargetHost {
+		hostKeys = append(hostKeys, "id")
+	}
+	for _, k := range hostKeys {
+		if fields[k] {
+			hostKey = k
+			break
+		}
+	}
+	vmKeys := []string{"vmName", "srcName"}
+	if vmAction(action) {
+		// 只有 VM 类 action 的 name 字段才是 VM 名称，placementGroup.* 等的 name 是其他资源的名称
+		vmKeys = append(vmKeys, "name")
+	}
+	for _, k := range vmKeys {
+		if fields[k] {
+			vmKey = k
+			break
+		}
+	}
+	return hostKey, vmKey
+}
+
+// vmAction action 的参数是否为 VM 参数（vm.* / snapshot.*）
+func vmAction(action string) bool {
+	return strings.HasPrefix(action, "vm.") || strings.HasPrefix(action, "snapshot.")
+}
+
+// Validate 校验定时任务并补全默认值
+func (r *Runner) Validate(sc *store.Schedule) error {
+	sc.Name = strings.TrimSpace(sc.Name)
+	if sc.Name == "" {
+		return fmt.Errorf("schedule name is required")
+	}
+	if _, err := cron.Parse(sc.Cron); err != nil {
+		return err
+	}
+	if _, err := location(sc.Timezone); err != nil {
+		return err
+	}
+	if sc.JitterSeconds < 0 || sc.JitterSeconds > maxJitter {
+		return fmt.Errorf("jitterSeconds must be between 0 and %d", maxJitter)
+	}
+
+	fields, ok := r.actions.Fields(sc.Action)
+	if !ok {
+		return fmt.Errorf("unknown action: %s", sc.Action)
+	}
+	for _, prefix := range forbidden {
+		if strings.HasPrefix(sc.Action, prefix) {
+			return fmt.Errorf("action %s cannot be scheduled", sc.Action)
+		}
+	}
+	if len(sc.Params) == 0 || string(sc.Params) == "null" {
+		sc.Params = json.RawMessage("{}")
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(sc.Params, &params); err != nil {
+		return fmt.Errorf("params must be a JSON object: %w", err)
+	}
+
+	if sc.Target == "" {
+		sc.Target = store.ScheduleTargetVM
+	}
+	hostKey, vmKey := targetKeys(fields, sc.Action, sc.Target)
+	switch sc.Target {
+	case store.ScheduleTargetVM:
+		if hostKey == "" || vmKey == "" {
+			return fmt.Errorf("action %s does not take a VM", sc.Action)
+		}
+		if sc.VMPattern == "" {
+			return fmt.Errorf("vmPattern is required for VM schedules (use * for all VMs)")
+		}
+		for _, pattern := range splitList(sc.VMPattern) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid vm pattern %q: %w", pattern, err)
+			}
+		}
+	case store.ScheduleTargetHost:
+		if hostKey == "" {
+			return fmt.Errorf("action %s does not take a host", sc.Action)
+		}
+		if sc.VMPattern != "" {
+			return fmt.Errorf("vmPattern only applies to VM schedules")
+		}
+	case store.ScheduleTargetNone:
+		if sc.HostID != "" || sc.HostTags != "" || sc.VMPattern != "" {
+			return fmt.Errorf("hostId, hostTags and vmPattern do not apply when target is none")
+		}
+	default:
+		return fmt.Errorf("invalid target: %s (vm, host or none)", sc.Target)
+	}
+
+	if sc.Missed == "" {
+		sc.Missed = store.ScheduleMissedSkip
+	}
+	if sc.Missed != store.ScheduleMissedSkip && sc.Missed != store.ScheduleMissedRun {
+		return fmt.Errorf("invalid missed: %s (skip or run)", sc.Missed)
+	}
+	return nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,52 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"vmcat/internal/store"
+)
+
+func TestRunAtRoundTripAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available:", err)
+	}
+	local := time.Local
+	time.Local = ny
+	defer func() { time.Local = local }()
+
+	sc := &store.Schedule{Cron: "30 1 * * *"}
+	// 2024-11-03 01:00 EDT 之后：01:30 EDT 先于 01:30 EST 触发，再下一次为 11-04 01:30 EST
+	after := time.Date(2024, 11, 3, 5, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		next, err := Next(sc, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !next.After(after) {
+			t.Fatalf("Next(%s) = %s, not after", after, next)
+		}
+		got, err := ParseRunAt(FormatRunAt(next))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(next) {
+			t.Fatalf("round trip of %s = %s", next, got)
+		}
+		if !got.After(after) {
+			t.Fatalf("stored next run %s is not after %s", got, after)
+		}
+		after = got
+	}
+}
+
+func TestParseRunAtLegacy(t *testing.T) {
+	got, err := ParseRunAt("2024-05-01 10:00:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("ParseRunAt = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,724 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"vmcat/internal/job"
+	"vmcat/internal/maintenance"
+	internalssh "vmcat/internal/ssh"
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+const (
+	ipTimeout   = 2 * time.Minute // 为新 VM 添加 NAT 规则前等待其获取 IP 的时间
+	ipInterval  = 5 * time.Second
+	readTimeout = 60 * time.Second // 读取单台宿主机实际状态的超时
+)
+
+// Ops apply 中由调用方完成的操作：宿主机的连接与删除、VM 的创建与删除以及高可用标记
+// 涉及连接池、后端、清单和多张表的联动，与手动操作共用同一实现
+type Ops interface {
+	HostConnect(ctx context.Context, id string) error
+	HostDelete(ctx context.Context, id string) error
+	VMCreate(ctx context.Context, hostID string, v *VM, flavorID, imageID string) (*store.Job, error) // hostID 为 Auto 时调度
+	VMDelete(ctx context.Context, hostID, vmName string) error                                        // 保留磁盘
+	VMHASet(ctx context.Context, hostID, vmName string, enabled bool) error
+}
+
+// Applier 采集实际状态并按变更计划执行
+type Applier struct {
+	pool        *internalssh.Pool
+	store       *store.Store
+	vms         *vm.Manager
+	jobs        *job.Manager
+	maintenance *maintenance.Manager
+}
+
+// NewApplier 创建 spec 执行器
+func NewApplier(pool *internalssh.Pool, s *store.Store, vms *vm.Manager, jobs *job.Manager, maint *maintenance.Manager) *Applier {
+	return &Applier{pool: pool, store: s, vms: vms, jobs: jobs, maintenance: maint}
+}
+
+// Plan 与实际状态对比，返回变更计划，不做任何修改（也不连接宿主机）
+// 未连接的宿主机上的资源不参与对比，计划中给出警告
+func (ap *Applier) Plan(ctx context.Context, s *Spec, prune bool) (*Plan, error) {
+	st, err := ap.state(ctx, s, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(s, st, prune), nil
+}
+
+// Apply 以后台任务方式按 spec 执行变更：先添加/更新并连接宿主机，重新采集后按计划顺序逐项执行，
+// 遇到错误即停止（已完成的变更保留，重新执行会从剩余的差异继续）。计划写入任务结果，actor 记入审计日志
+func (ap *Applier) Apply(s *Spec, prune bool, actor string, ops Ops) *store.Job {
+	return ap.jobs.Start("spec.apply", "", "", func(ctx context.Context, r *job.Run) error {
+		a := &applyRun{Applier: ap, ctx: ctx, r: r, s: s, ops: ops, actor: actor}
+		st, err := ap.state(ctx, s, ops)
+		if err != nil {
+			return err
+		}
+		plan := Diff(s, st, prune)
+
+		// 宿主机的变化影响其上资源的对比结果，先执行后重新生成计划
+		hostsChanged := false
+		for _, c := range plan.Changes {
+			if c.Kind != KindHost || c.Action == ActionDelete {
+				continue
+			}
+			if err := a.change(st, c); err != nil {
+				return fmt.Errorf("%s host %s: %w", c.Action, c.Name, err)
+			}
+			hostsChanged = true
+		}
+		if hostsChanged {
+			if st, err = ap.state(ctx, s, ops); err != nil {
+				return err
+			}
+			plan = Diff(s, st, prune)
+		}
+		r.SetResult(plan)
+		for _, w := range plan.Warnings {
+			r.Log("warning: %s", w)
+		}
+
+		var pending []Change
+		for _, c := range plan.Changes {
+			if c.Kind != KindHost || c.Action == ActionDelete {
+				pending = append(pending, c)
+			}
+		}
+		if len(pending) == 0 {
+			r.Log("no changes")
+			return nil
+		}
+		for i, c := range pending {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			label := fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+			if c.VM != "" {
+				label += " (" + c.VM + ")"
+			}
+			r.Progress(i*100/len(pending), label)
+			r.Log("%s", label)
+			if err := a.change(st, c); err != nil {
+				return fmt.Errorf("%s: %w", label, err)
+			}
+		}
+		r.Log("applied %d changes", len(pending))
+		return nil
+	})
+}
+
+// state 采集对比所需的实际状态；ops 不为 nil 时（apply）已添加但未连接的 spec 宿主机先尝试连接，
+// 未连接或读取失败的宿主机不计入 Live
+func (ap *Applier) state(ctx context.Context, s *Spec, ops Ops) (*State, error) {
+	hosts, err := ap.store.HostList()
+	if err != nil {
+		return nil, err
+	}
+	st := &State{
+		Images: make(map[string][]store.Image),
+		Live:   make(map[string]*HostState),
+	}
+	if st.Flavors, err = ap.store.FlavorList(); err != nil {
+		return nil, err
+	}
+	if st.Groups, err = ap.store.PlacementGroupList(); err != nil {
+		return nil, err
+	}
+	if st.HA, err = ap.store.VMHAList("", false); err != nil {
+		return nil, err
+	}
+
+	for _, h := range hosts {
+		// 列表中的密码已清空，对比需要解密后的值
+		full, err := ap.store.HostGet(h.ID)
+		if err != nil {
+			return nil, err
+		}
+		st.Hosts = append(st.Hosts, *full)
+		if st.Images[h.ID], err = ap.store.ImageList(h.ID); err != nil {
+			return nil, err
+		}
+
+		if !ap.pool.IsConnected(h.ID) {
+			if ops == nil || s.HostByName(h.Name) == nil {
+				continue
+			}
+			if err := ops.HostConnect(ctx, h.ID); err != nil {
+				log.Printf("spec: connect host %s: %v", h.Name, err)
+				continue
+			}
+		}
+		live, err := ap.hostState(ctx, s, h.ID)
+		if err != nil {
+			log.Printf("spec: read host %s: %v", h.Name, err)
+			continue
+		}
+		st.Live[h.ID] = live
+	}
+	return st, nil
+}
+
+// hostState 读取单台宿主机的 VM、网络、存储池和 NAT 规则；spec 中声明的 VM 额外读取自动启动和 IP
+func (ap *Applier) hostState(ctx context.Context, s *Spec, hostID string) (*HostState, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+	vms, err := ap.vms.List(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	live := &HostState{}
+	if live.Networks, err = ap.vms.NetworkList(ctx, hostID); err != nil {
+		return nil, err
+	}
+	if live.Pools, err = ap.vms.PoolList(ctx, hostID); err != nil {
+		return nil, err
+	}
+	if live.NAT, err = ap.vms.NATRuleList(ctx, hostID); err != nil {
+		return nil, err
+	}
+	for _, v := range vms {
+		lv := LiveVM{Name: v.Name, State: v.State, CPUs: v.CPUs, MemoryMB: v.MemoryMB}
+		if s.VMByName(v.Name) != nil {
+			detail, err := ap.vms.Get(ctx, hostID, v.Name)
+			if err != nil {
+				return nil, err
+			}
+			lv.Autostart = detail.Autostart
+			lv.IP = nicIP(detail.NICs)
+		}
+		live.VMs = append(live.VMs, lv)
+	}
+	return live, nil
+}
+
+// nicIP 返回第一个已获取到地址的网卡 IP
+func nicIP(nics []vm.NIC) string {
+	for _, n := range nics {
+		if n.IP != "" {
+			return n.IP
+		}
+	}
+	return ""
+}
+
+// applyRun 单次 apply 任务的上下文
+type applyRun struct {
+	*Applier
+	ctx   context.Context
+	r     *job.Run
+	s     *Spec
+	ops   Ops
+	actor string
+}
+
+func (a *applyRun) audit(hostID, vmName, action, detail string) {
+	a.store.AuditInsert(a.actor, hostID, vmName, action, detail)
+}
+
+// change 执行单项变更并记录审计日志
+func (a *applyRun) change(st *State, c Change) error {
+	hostID := st.HostID(c.Host)
+	var err error
+	switch c.Kind {
+	case KindHost:
+		hostID, err = a.applyHost(st, c)
+	case KindGroup:
+		err = a.applyGroup(c)
+	case KindFlavor:
+		err = a.applyFlavor(st, c)
+	case KindImage:
+		err = a.applyImage(st, hostID, c)
+	case KindPool:
+		err = a.applyPool(st, hostID, c)
+	case KindNetwork:
+		err = a.applyNetwork(st, hostID, c)
+	case KindVM:
+		hostID, err = a.applyVM(st, hostID, c)
+	case KindNAT:
+		hostID, err = a.applyNAT(st, hostID, c)
+	default:
+		err = fmt.Errorf("unknown kind: %s", c.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	vmName := c.VM
+	if c.Kind == KindVM {
+		vmName = c.Name
+	}
+	detail := c.Kind + " " + c.Name
+	if len(c.Diffs) > 0 {
+		detail += ": " + strings.Join(c.Diffs, "; ")
+	}
+	a.audit(hostID, vmName, "spec."+c.Action, detail)
+	return nil
+}
+
+// applyHost 添加、更新或删除宿主机，添加和更新后连接，返回宿主机 ID
+func (a *applyRun) applyHost(st *State, c Change) (string, error) {
+	id := st.HostID(c.Name)
+	if c.Action == ActionDelete {
+		return id, a.ops.HostDelete(a.ctx, id)
+	}
+
+	h := a.s.HostByName(c.Name)
+	if !vm.ValidBackend(h.Backend) {
+		return id, fmt.Errorf("invalid backend: %s", h.Backend)
+	}
+	sh := store.Host{
+		ID:        id,
+		Name:      h.Name,
+		Host:      h.Host,
+		Port:      h.Port,
+		User:      h.User,
+		AuthType:  h.AuthType,
+		KeyPath:   h.KeyPath,
+		Password:  h.Password,
+		ProxyAddr: h.ProxyAddr,
+		Tags:      strings.Join(h.Tags, ","),
+		Backend:   h.Backend,
+	}
+	if c.Action == ActionCreate {
+		if err := a.store.HostAdd(&sh); err != nil {
+			return "", err
+		}
+		id = sh.ID
+	} else {
+		for _, existing := range st.Hosts {
+			if existing.ID == id {
+				sh.SortOrder = existing.SortOrder
+			}
+		}
+		if err := a.store.HostUpdate(&sh); err != nil {
+			return id, err
+		}
+		// 已连接的宿主机立即切换后端
+		if a.pool.IsConnected(id) {
+			a.vms.SetBackend(id, sh.Backend)
+		}
+	}
+	if !a.pool.IsConnected(id) {
+		if err := a.ops.HostConnect(a.ctx, id); err != nil {
+			return id, fmt.Errorf("connect: %w", err)
+		}
+	}
+	return id, nil
+}
+
+// applyGroup 放置组不能修改策略，策略变化时重建并保留成员
+func (a *applyRun) applyGroup(c Change) error {
+	if c.Action == ActionDelete {
+		return a.deleteGroup(c.Name)
+	}
+	var policy string
+	for _, g := range a.s.Groups {
+		if g.Name == c.Name {
+			policy = g.Policy
+		}
+	}
+	if c.Action == ActionCreate {
+		return a.createGroup(c.Name, policy)
+	}
+
+	old, err := a.store.PlacementGroupGet(c.Name)
+	if err != nil {
+		return err
+	}
+	if err := a.deleteGroup(c.Name); err != nil {
+		return err
+	}
+	if err := a.createGroup(c.Name, policy); err != nil {
+		return err
+	}
+	for _, member := range old.Members {
+		if err := a.store.PlacementGroupAddVM(c.Name, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *applyRun) createGroup(name, policy string) error {
+	if err := a.store.PlacementGroupAdd(&store.PlacementGroup{Name: name, Policy: policy, Members: []string{}}); err != nil {
+		return err
+	}
+	a.audit("", "", "placementGroup.create", name+" "+policy)
+	return nil
+}
+
+func (a *applyRun) deleteGroup(name string) error {
+	if err := a.store.PlacementGroupDelete(name); err != nil {
+		return err
+	}
+	a.audit("", "", "placementGroup.delete", name)
+	return nil
+}
+
+func (a *applyRun) applyFlavor(st *State, c Change) error {
+	var id string
+	for _, f := range st.Flavors {
+		if f.Name == c.Name {
+			id = f.ID
+		}
+	}
+	if c.Action == ActionDelete {
+		return a.store.FlavorDelete(id)
+	}
+	for _, f := range a.s.Flavors {
+		if f.Name != c.Name {
+			continue
+		}
+		sf := store.Flavor{ID: id, Name: f.Name, CPUs: f.CPUs, MemoryMB: f.MemoryMB, DiskGB: f.DiskGB}
+		if c.Action == ActionCreate {
+			return a.store.FlavorAdd(&sf)
+		}
+		return a.store.FlavorUpdate(&sf)
+	}
+	return nil
+}
+
+func (a *applyRun) applyImage(st *State, hostID string, c Change) error {
+	var existing store.Image
+	for _, img := range st.Images[hostID] {
+		if img.Name == c.Name {
+			existing = img
+		}
+	}
+	if c.Action == ActionDelete {
+		return a.store.ImageDelete(existing.ID)
+	}
+	for _, img := range a.s.Images {
+		if img.Host != c.Host || img.Name != c.Name {
+			continue
+		}
+		existing.Name = img.Name
+		existing.BasePath = img.BasePath
+		existing.OSVariant = img.OSVariant
+		if c.Action == ActionCreate {
+			existing.HostID = hostID
+			return a.store.ImageAdd(&existing)
+		}
+		return a.store.ImageUpdate(&existing)
+	}
+	return nil
+}
+
+// applyPool 创建或删除存储池，已有的存储池只调整启动状态和自动启动
+func (a *applyRun) applyPool(st *State, hostID string, c Change) error {
+	if c.Action == ActionDelete {
+		return a.vms.PoolDelete(a.ctx, hostID, c.Name)
+	}
+	var p Pool
+	for _, sp := range a.s.Pools {
+		if sp.Host == c.Host && sp.Name == c.Name {
+			p = sp
+		}
+	}
+	autostart := p.Autostart == nil || *p.Autostart
+	if c.Action == ActionCreate {
+		if err := a.vms.PoolCreate(a.ctx, hostID, p.Name, p.Path); err != nil {
+			return err
+		}
+		return a.vms.PoolAutostart(a.ctx, hostID, p.Name, autostart)
+	}
+	for _, lp := range st.Live[hostID].Pools {
+		if lp.Name != p.Name {
+			continue
+		}
+		if lp.State != "running" {
+			if err := a.vms.PoolStart(a.ctx, hostID, p.Name); err != nil {
+				return err
+			}
+		}
+		if (lp.Autostart == "yes") != autostart {
+			return a.vms.PoolAutostart(a.ctx, hostID, p.Name, autostart)
+		}
+	}
+	return nil
+}
+
+// applyNetwork 创建或删除虚拟网络，已有的网络只调整启动状态和自动启动
+func (a *applyRun) applyNetwork(st *State, hostID string, c Change) error {
+	if c.Action == ActionDelete {
+		return a.vms.NetworkDelete(a.ctx, hostID, c.Name)
+	}
+	var n Network
+	for _, sn := range a.s.Networks {
+		if sn.Host == c.Host && sn.Name == c.Name {
+			n = sn
+		}
+	}
+	autostart := n.Autostart == nil || *n.Autostart
+	if c.Action == ActionCreate {
+		err := a.vms.NetworkCreate(a.ctx, hostID, vm.NetworkCreateParams{
+			Name:      n.Name,
+			Mode:      n.Mode,
+			Bridge:    n.Bridge,
+			Address:   n.Address,
+			DHCPStart: n.DHCPStart,
+			DHCPEnd:   n.DHCPEnd,
+		})
+		if err != nil {
+			return err
+		}
+		return a.vms.NetworkAutostart(a.ctx, hostID, n.Name, autostart)
+	}
+	for _, ln := range st.Live[hostID].Networks {
+		if ln.Name != n.Name {
+			continue
+		}
+		if ln.State != "active" {
+			if err := a.vms.NetworkStart(a.ctx, hostID, n.Name); err != nil {
+				return err
+			}
+		}
+		if (ln.Autostart == "yes") != autostart {
+			return a.vms.NetworkAutostart(a.ctx, hostID, n.Name, autostart)
+		}
+	}
+	return nil
+}
+
+// applyVM 创建、调整或删除 VM（删除时保留磁盘），返回 VM 所在的宿主机 ID
+func (a *applyRun) applyVM(st *State, hostID string, c Change) (string, error) {
+	if c.Action == ActionDelete {
+		return hostID, a.ops.VMDelete(a.ctx, hostID, c.Name)
+	}
+	v := a.s.VMByName(c.Name)
+	if c.Action == ActionCreate {
+		return a.createVM(v)
+	}
+
+	lv, current := st.FindVM(v.Name)
+	if lv == nil {
+		return "", fmt.Errorf("VM %s not found", v.Name)
+	}
+	if v.Host != Auto {
+		if target := st.HostID(v.Host); target != current {
+			mode, err := a.maintenance.Move(a.ctx, a.r, a.actor, current, v.Name, target)
+			if err != nil {
+				return current, fmt.Errorf("move to %s (%s): %w", v.Host, mode, err)
+			}
+			current = target
+		}
+	}
+
+	for _, f := range a.s.Flavors {
+		if f.Name != v.Flavor {
+			continue
+		}
+		if lv.CPUs != f.CPUs {
+			if err := a.vms.SetVCPUs(a.ctx, current, v.Name, f.CPUs); err != nil {
+				return current, err
+			}
+		}
+		if lv.MemoryMB != f.MemoryMB {
+			if err := a.vms.SetMemory(a.ctx, current, v.Name, f.MemoryMB); err != nil {
+				return current, err
+			}
+		}
+		if (lv.CPUs != f.CPUs || lv.MemoryMB != f.MemoryMB) && lv.State == "running" && v.State == StateRunning {
+			a.r.Log("%s: CPU and memory changes take effect after the next restart", v.Name)
+		}
+	}
+
+	running := lv.State == "running"
+	if v.State == StateRunning && !running {
+		if err := a.vms.Start(a.ctx, current, v.Name); err != nil {
+			return current, err
+		}
+		a.audit(current, v.Name, "vm.start", "")
+	}
+	if v.State == StateStopped && running {
+		if err := a.shutdown(current, v.Name); err != nil {
+			return current, err
+		}
+	}
+	if lv.Autostart != v.Autostart {
+		if err := a.vms.SetAutostart(a.ctx, current, v.Name, v.Autostart); err != nil {
+			return current, err
+		}
+	}
+
+	// 只调整 spec 中声明的放置组
+	for _, g := range st.Groups {
+		if !slices.ContainsFunc(a.s.Groups, func(sg Group) bool { return sg.Name == g.Name }) {
+			continue
+		}
+		member := slices.Contains(g.Members, v.Name)
+		want := slices.Contains(v.Groups, g.Name)
+		if want && !member {
+			if err := a.store.PlacementGroupAddVM(g.Name, v.Name); err != nil {
+				return current, err
+			}
+		}
+		if !want && member {
+			if err := a.store.PlacementGroupRemoveVM(g.Name, v.Name); err != nil {
+				return current, err
+			}
+		}
+	}
+	// 本次新建的放置组尚无成员
+	for _, name := range v.Groups {
+		if !slices.ContainsFunc(st.Groups, func(g store.PlacementGroup) bool { return g.Name == name }) {
+			if err := a.store.PlacementGroupAddVM(name, v.Name); err != nil {
+				return current, err
+			}
+		}
+	}
+
+	ha := slices.ContainsFunc(st.HA, func(rec store.VMHA) bool { return rec.VMName == v.Name })
+	if ha != v.HA {
+		if err := a.ops.VMHASet(a.ctx, current, v.Name, v.HA); err != nil {
+			return current, err
+		}
+	}
+	return current, nil
+}
+
+func (a *applyRun) shutdown(hostID, vmName string) error {
+	if err := a.vms.Shutdown(a.ctx, hostID, vmName); err != nil {
+		return err
+	}
+	a.audit(hostID, vmName, "vm.shutdown", "")
+	return nil
+}
+
+// createVM 基于模板创建 VM 并等待完成，host 为 auto 时按 flavor、镜像、网络和放置组调度
+func (a *applyRun) createVM(v *VM) (string, error) {
+	hosts, err := a.store.HostList()
+	if err != nil {
+		return "", err
+	}
+	hostID := Auto
+	var hostIDs []string
+	for _, h := range hosts {
+		if v.Host == Auto {
+			hostIDs = append(hostIDs, h.ID)
+		} else if h.Name == v.Host {
+			hostID = h.ID
+			hostIDs = []string{h.ID}
+		}
+	}
+	if v.Host != Auto && hostID == Auto {
+		return "", fmt.Errorf("host %s not found", v.Host)
+	}
+
+	// 镜像按宿主机登记，调度时只用其 basePath，取任一同名记录即可
+	var imageID string
+	for _, id := range hostIDs {
+		images, err := a.store.ImageList(id)
+		if err != nil {
+			return "", err
+		}
+		for _, img := range images {
+			if img.Name == v.Image && imageID == "" {
+				imageID = img.ID
+			}
+		}
+	}
+	if imageID == "" {
+		return "", fmt.Errorf("image %s not found", v.Image)
+	}
+	flavors, err := a.store.FlavorList()
+	if err != nil {
+		return "", err
+	}
+	var flavorID string
+	for _, f := range flavors {
+		if f.Name == v.Flavor {
+			flavorID = f.ID
+		}
+	}
+	if flavorID == "" {
+		return "", fmt.Errorf("flavor %s not found", v.Flavor)
+	}
+
+	j, err := a.ops.VMCreate(a.ctx, hostID, v, flavorID, imageID)
+	if err != nil {
+		return "", err
+	}
+	hostID = j.HostID
+	a.r.Log("%s: creating on %s (job %s)", v.Name, hostID, j.ID)
+	if err := a.jobs.Wait(j.ID); err != nil {
+		return hostID, err
+	}
+
+	if v.Autostart {
+		if err := a.vms.SetAutostart(a.ctx, hostID, v.Name, true); err != nil {
+			return hostID, err
+		}
+	}
+	if v.State == StateStopped {
+		if err := a.shutdown(hostID, v.Name); err != nil {
+			return hostID, err
+		}
+	}
+	if v.HA {
+		if err := a.ops.VMHASet(a.ctx, hostID, v.Name, true); err != nil {
+			return hostID, err
+		}
+	}
+	return hostID, nil
+}
+
+// applyNAT 添加或删除转发规则；VM 的 IP 未知时（本次新建）先定位 VM 并等待其获取地址
+func (a *applyRun) applyNAT(st *State, hostID string, c Change) (string, error) {
+	rule := *c.NAT
+	if c.Action == ActionDelete {
+		return hostID, a.vms.NATRuleDelete(a.ctx, hostID, rule.Proto, rule.HostPort, rule.VMIP, rule.VMPort)
+	}
+	if rule.VMIP == "" {
+		var err error
+		if hostID, rule.VMIP, err = a.waitIP(st, c.VM); err != nil {
+			return hostID, err
+		}
+	}
+	return hostID, a.vms.NATRuleAdd(a.ctx, hostID, rule.Proto, rule.HostPort, rule.VMIP, rule.VMPort, rule.Comment)
+}
+
+// waitIP 在已连接的宿主机中定位 VM，等待其获取 IP
+func (a *applyRun) waitIP(st *State, vmName string) (string, string, error) {
+	var hostID string
+	for _, h := range st.Hosts {
+		if !a.pool.IsConnected(h.ID) {
+			continue
+		}
+		if vms, err := a.vms.List(a.ctx, h.ID); err == nil && slices.ContainsFunc(vms, func(v vm.VM) bool { return v.Name == vmName }) {
+			hostID = h.ID
+			break
+		}
+	}
+	if hostID == "" {
+		return "", "", fmt.Errorf("VM %s not found", vmName)
+	}
+
+	a.r.Log("%s: waiting for an IP address", vmName)
+	deadline := time.Now().Add(ipTimeout)
+	for {
+		if detail, err := a.vms.Get(a.ctx, hostID, vmName); err == nil {
+			if ip := nicIP(detail.NICs); ip != "" {
+				return hostID, ip, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return hostID, "", fmt.Errorf("VM %s has no IP address after %s", vmName, ipTimeout)
+		}
+		select {
+		case <-a.ctx.Done():
+			return hostID, "", a.ctx.Err()
+		case <-time.After(ipInterval):
+		}
+	}
+}
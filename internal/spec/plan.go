@@ -0,0 +1,574 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+// 资源类型，按依赖顺序排列：创建和更新按此顺序执行，删除按相反顺序先于创建执行
+const (
+	KindHost    = "host"
+	KindGroup   = "group"
+	KindFlavor  = "flavor"
+	KindImage   = "image"
+	KindPool    = "pool"
+	KindNetwork = "network"
+	KindVM      = "vm"
+	KindNAT     = "nat"
+)
+
+var kindOrder = []string{KindHost, KindGroup, KindFlavor, KindImage, KindPool, KindNetwork, KindVM, KindNAT}
+
+// 变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change 单项变更
+type Change struct {
+	Action string      `json:"action"`
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Host   string      `json:"host"`          // 宿主机级资源所在宿主机的名称
+	VM     string      `json:"vm"`            // nat: 转发目标 VM
+	Diffs  []string    `json:"diffs"`         // 变化的字段，如 "cpus: 2 -> 4"
+	NAT    *vm.NATRule `json:"nat,omitempty"` // nat: 规则（创建时 VMIP 可能在 VM 启动后才确定）
+}
+
+// Plan 变更计划，Changes 已按执行顺序排列
+type Plan struct {
+	Changes  []Change `json:"changes"`
+	Warnings []string `json:"warnings"`
+	Create   int      `json:"create"`
+	Update   int      `json:"update"`
+	Delete   int      `json:"delete"`
+}
+
+// State 实际状态，由调用方从 store 和各宿主机采集
+type State struct {
+	Hosts   []store.Host // 密码为解密后的值，只用于对比
+	Flavors []store.Flavor
+	Groups  []store.PlacementGroup
+	HA      []store.VMHA
+	Images  map[string][]store.Image // 按宿主机 ID
+	Live    map[string]*HostState    // 按宿主机 ID，只含已连接且读取成功的宿主机
+}
+
+// HostState 单台宿主机的实际状态
+type HostState struct {
+	VMs      []LiveVM
+	Networks []vm.Network
+	Pools    []vm.StoragePool
+	NAT      []vm.NATRule
+}
+
+// LiveVM VM 的实际状态，Autostart 和 IP 只对 spec 中声明的 VM 采集
+type LiveVM struct {
+	Name      string
+	State     string
+	CPUs      int
+	MemoryMB  int
+	Autostart bool
+	IP        string
+}
+
+// differ 单次对比的上下文
+type differ struct {
+	s       *Spec
+	st      *State
+	prune   bool
+	plan    *Plan
+	changes map[string][]Change // 按资源类型
+	deletes map[string][]Change
+}
+
+// Diff 对比 spec 与实际状态生成变更计划；prune 为 true 时删除 spec 中未声明的资源：
+// 未声明的宿主机全部移除（宿主机上的 VM 不受影响），其余资源的删除范围限于 spec 中声明的宿主机，
+// 名为 default 的网络和存储池不会被删除，删除 VM 时保留磁盘
+func Diff(s *Spec, st *State, prune bool) *Plan {
+	d := &differ{
+		s:       s,
+		st:      st,
+		prune:   prune,
+		plan:    &Plan{Changes: []Change{}, Warnings: []string{}},
+		changes: make(map[string][]Change),
+		deletes: make(map[string][]Change),
+	}
+	d.hosts()
+	d.groups()
+	d.flavors()
+	d.images()
+	d.pools()
+	d.networks()
+	d.vms()
+
+	for i := len(kindOrder) - 1; i >= 0; i-- {
+		d.plan.Changes = append(d.plan.Changes, d.deletes[kindOrder[i]]...)
+	}
+	for _, kind := range kindOrder {
+		d.plan.Changes = append(d.plan.Changes, d.changes[kind]...)
+	}
+	for _, c := range d.plan.Changes {
+		switch c.Action {
+		case ActionCreate:
+			d.plan.Create++
+		case ActionUpdate:
+			d.plan.Update++
+		case ActionDelete:
+			d.plan.Delete++
+		}
+	}
+	return d.plan
+}
+
+func (d *differ) add(c Change) {
+	if c.Diffs == nil {
+		c.Diffs = []string{}
+	}
+	if c.Action == ActionDelete {
+		d.deletes[c.Kind] = append(d.deletes[c.Kind], c)
+		return
+	}
+	d.changes[c.Kind] = append(d.changes[c.Kind], c)
+}
+
+func (d *differ) warn(format string, args ...interface{}) {
+	d.plan.Warnings = append(d.plan.Warnings, fmt.Sprintf(format, args...))
+}
+
+// storeHost 按名称查找已添加的宿主机
+func (d *differ) storeHost(name string) *store.Host {
+	for i := range d.st.Hosts {
+		if d.st.Hosts[i].Name == name {
+			return &d.st.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// live 返回宿主机的实际状态；ok 为 false 表示无法对比（宿主机未连接），
+// 宿主机尚未添加时 live 为 nil 且 ok 为 true，其上的资源全部创建
+func (d *differ) live(hostName string) (live *HostState, ok bool) {
+	h := d.storeHost(hostName)
+	if h == nil {
+		return nil, true
+	}
+	live, ok = d.st.Live[h.ID]
+	return live, ok
+}
+
+// diffField 值不同时追加 "name: old -> new"
+func diffField(diffs *[]string, name string, old, new interface{}) {
+	if fmt.Sprint(old) != fmt.Sprint(new) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: %v -> %v", name, old, new))
+	}
+}
+
+func (d *differ) hosts() {
+	for _, h := range d.s.Hosts {
+		sh := d.storeHost(h.Name)
+		if sh == nil {
+			d.add(Change{Action: ActionCreate, Kind: KindHost, Name: h.Name, Diffs: []string{fmt.Sprintf("address: %s@%s:%d", h.User, h.Host, h.Port)}})
+			d.warn("host %s will be added; its pools, networks and VMs are compared again after it is connected", h.Name)
+			continue
+		}
+		var diffs []string
+		diffField(&diffs, "host", sh.Host, h.Host)
+		diffField(&diffs, "port", sh.Port, h.Port)
+		diffField(&diffs, "user", sh.User, h.User)
+		diffField(&diffs, "authType", sh.AuthType, h.AuthType)
+		diffField(&diffs, "keyPath", sh.KeyPath, h.KeyPath)
+		diffField(&diffs, "proxyAddr", sh.ProxyAddr, h.ProxyAddr)
+		diffField(&diffs, "tags", normTags(sh.Tags), strings.Join(h.Tags, ","))
+		diffField(&diffs, "backend", firstNonEmpty(sh.Backend, "virsh"), h.Backend)
+		if h.Password != "" && h.Password != sh.Password {
+			diffs = append(diffs, "password: (changed)")
+		}
+		if len(diffs) > 0 {
+			d.add(Change{Action: ActionUpdate, Kind: KindHost, Name: h.Name, Diffs: diffs})
+		}
+		if _, ok := d.st.Live[sh.ID]; !ok {
+			d.warn("host %s is not connected or could not be read; its pools, networks and VMs were not compared", h.Name)
+		}
+	}
+	if d.prune {
+		for _, sh := range d.st.Hosts {
+			if d.s.HostByName(sh.Name) == nil {
+				d.add(Change{Action: ActionDelete, Kind: KindHost, Name: sh.Name})
+			}
+		}
+	}
+}
+
+func (d *differ) groups() {
+	for _, g := range d.s.Groups {
+		i := slices.IndexFunc(d.st.Groups, func(sg store.PlacementGroup) bool { return sg.Name == g.Name })
+		if i < 0 {
+			d.add(Change{Action: ActionCreate, Kind: KindGroup, Name: g.Name, Diffs: []string{"policy: " + g.Policy}})
+			continue
+		}
+		if sg := d.st.Groups[i]; sg.Policy != g.Policy {
+			d.add(Change{Action: ActionUpdate, Kind: KindGroup, Name: g.Name, Diffs: []string{fmt.Sprintf("policy: %s -> %s", sg.Policy, g.Policy)}})
+		}
+	}
+	if d.prune {
+		for _, sg := range d.st.Groups {
+			if !slices.ContainsFunc(d.s.Groups, func(g Group) bool { return g.Name == sg.Name }) {
+				d.add(Change{Action: ActionDelete, Kind: KindGroup, Name: sg.Name})
+			}
+		}
+	}
+}
+
+func (d *differ) flavors() {
+	for _, f := range d.s.Flavors {
+		i := slices.IndexFunc(d.st.Flavors, func(sf store.Flavor) bool { return sf.Name == f.Name })
+		if i < 0 {
+			d.add(Change{Action: ActionCreate, Kind: KindFlavor, Name: f.Name,
+				Diffs: []string{fmt.Sprintf("%d vCPU, %d MB, %d GB", f.CPUs, f.MemoryMB, f.DiskGB)}})
+			continue
+		}
+		sf := d.st.Flavors[i]
+		var diffs []string
+		diffField(&diffs, "cpus", sf.CPUs, f.CPUs)
+		diffField(&diffs, "memoryMB", sf.MemoryMB, f.MemoryMB)
+		diffField(&diffs, "diskGB", sf.DiskGB, f.DiskGB)
+		if len(diffs) > 0 {
+			d.add(Change{Action: ActionUpdate, Kind: KindFlavor, Name: f.Name, Diffs: diffs})
+		}
+	}
+	if d.prune {
+		for _, sf := range d.st.Flavors {
+			if !slices.ContainsFunc(d.s.Flavors, func(f Flavor) bool { return f.Name == sf.Name }) {
+				d.add(Change{Action: ActionDelete, Kind: KindFlavor, Name: sf.Name})
+			}
+		}
+	}
+}
+
+func (d *differ) images() {
+	for _, img := range d.s.Images {
+		sh := d.storeHost(img.Host)
+		var existing *store.Image
+		if sh != nil {
+			for _, si := range d.st.Images[sh.ID] {
+				if si.Name == img.Name {
+					existing = &si
+					break
+				}
+			}
+		}
+		if existing == nil {
+			d.add(Change{Action: ActionCreate, Kind: KindImage, Name: img.Name, Host: img.Host, Diffs: []string{"basePath: " + img.BasePath}})
+			continue
+		}
+		var diffs []string
+		diffField(&diffs, "basePath", existing.BasePath, img.BasePath)
+		diffField(&diffs, "osVariant", existing.OSVariant, img.OSVariant)
+		if len(diffs) > 0 {
+			d.add(Change{Action: ActionUpdate, Kind: KindImage, Name: img.Name, Host: img.Host, Diffs: diffs})
+		}
+	}
+	if d.prune {
+		for _, h := range d.s.Hosts {
+			sh := d.storeHost(h.Name)
+			if sh == nil {
+				continue
+			}
+			for _, si := range d.st.Images[sh.ID] {
+				if !slices.ContainsFunc(d.s.Images, func(img Image) bool { return img.Host == h.Name && img.Name == si.Name }) {
+					d.add(Change{Action: ActionDelete, Kind: KindImage, Name: si.Name, Host: h.Name})
+				}
+			}
+		}
+	}
+}
+
+func (d *differ) pools() {
+	for _, p := range d.s.Pools {
+		live, ok := d.live(p.Host)
+		if !ok {
+			continue
+		}
+		var existing *vm.StoragePool
+		if live != nil {
+			if i := slices.IndexFunc(live.Pools, func(lp vm.StoragePool) bool { return lp.Name == p.Name }); i >= 0 {
+				existing = &live.Pools[i]
+			}
+		}
+		if existing == nil {
+			d.add(Change{Action: ActionCreate, Kind: KindPool, Name: p.Name, Host: p.Host, Diffs: []string{"path: " + p.Path}})
+			continue
+		}
+		var diffs []string
+		diffField(&diffs, "active", existing.State == "running", true)
+		diffField(&diffs, "autostart", existing.Autostart == "yes", autostart(p.Autostart))
+		if len(diffs) > 0 {
+			d.add(Change{Action: ActionUpdate, Kind: KindPool, Name: p.Name, Host: p.Host, Diffs: diffs})
+		}
+	}
+	if d.prune {
+		for _, h := range d.s.Hosts {
+			live, _ := d.live(h.Name)
+			if live == nil {
+				continue
+			}
+			for _, lp := range live.Pools {
+				if lp.Name != "default" && !slices.ContainsFunc(d.s.Pools, func(p Pool) bool { return p.Host == h.Name && p.Name == lp.Name }) {
+					d.add(Change{Action: ActionDelete, Kind: KindPool, Name: lp.Name, Host: h.Name})
+				}
+			}
+		}
+	}
+}
+
+func (d *differ) networks() {
+	for _, n := range d.s.Networks {
+		live, ok := d.live(n.Host)
+		if !ok {
+			continue
+		}
+		var existing *vm.Network
+		if live != nil {
+			if i := slices.IndexFunc(live.Networks, func(ln vm.Network) bool { return ln.Name == n.Name }); i >= 0 {
+				existing = &live.Networks[i]
+			}
+		}
+		if existing == nil {
+			desc := "mode: " + firstNonEmpty(n.Mode, "nat")
+			if n.Address != "" {
+				desc += ", address: " + n.Address
+			}
+			d.add(Change{Action: ActionCreate, Kind: KindNetwork, Name: n.Name, Host: n.Host, Diffs: []string{desc}})
+			continue
+		}
+		var diffs []string
+		diffField(&diffs, "active", existing.State == "active", true)
+		diffField(&diffs, "autostart", existing.Autostart == "yes", autostart(n.Autostart))
+		if len(diffs) > 0 {
+			d.add(Change{Action: ActionUpdate, Kind: KindNetwork, Name: n.Name, Host: n.Host, Diffs: diffs})
+		}
+	}
+	if d.prune {
+		for _, h := range d.s.Hosts {
+			live, _ := d.live(h.Name)
+			if live == nil {
+				continue
+			}
+			for _, ln := range live.Networks {
+				if ln.Name != "default" && !slices.ContainsFunc(d.s.Networks, func(n Network) bool { return n.Host == h.Name && n.Name == ln.Name }) {
+					d.add(Change{Action: ActionDelete, Kind: KindNetwork, Name: ln.Name, Host: h.Name})
+				}
+			}
+		}
+	}
+}
+
+// HostID 按名称查找已添加的宿主机 ID，未添加时返回空
+func (st *State) HostID(name string) string {
+	for _, h := range st.Hosts {
+		if h.Name == name {
+			return h.ID
+		}
+	}
+	return ""
+}
+
+// FindVM 在已采集的宿主机中查找 VM，返回所在宿主机 ID
+func (st *State) FindVM(name string) (*LiveVM, string) {
+	for _, h := range st.Hosts {
+		live, ok := st.Live[h.ID]
+		if !ok {
+			continue
+		}
+		for i := range live.VMs {
+			if live.VMs[i].Name == name {
+				return &live.VMs[i], h.ID
+			}
+		}
+	}
+	return nil, ""
+}
+
+// unreadHosts 已添加但未连接或读取失败的宿主机名称
+func (d *differ) unreadHosts() []string {
+	var names []string
+	for _, h := range d.st.Hosts {
+		if _, ok := d.st.Live[h.ID]; !ok {
+			names = append(names, h.Name)
+		}
+	}
+	return names
+}
+
+// findVM 同 FindVM，返回宿主机名称
+func (d *differ) findVM(name string) (*LiveVM, string) {
+	lv, hostID := d.st.FindVM(name)
+	for _, h := range d.st.Hosts {
+		if h.ID == hostID {
+			return lv, h.Name
+		}
+	}
+	return nil, ""
+}
+
+func (d *differ) vms() {
+	flavors := make(map[string]Flavor)
+	for _, f := range d.s.Flavors {
+		flavors[f.Name] = f
+	}
+	declared := make(map[string]bool)
+	for _, g := range d.s.Groups {
+		declared[g.Name] = true
+	}
+
+	for _, v := range d.s.VMs {
+		flavor := flavors[v.Flavor]
+		lv, host := d.findVM(v.Name)
+		if lv == nil {
+			if v.Host != Auto {
+				if _, ok := d.live(v.Host); !ok {
+					continue
+				}
+			} else if unread := d.unreadHosts(); len(unread) > 0 {
+				// VM 可能位于无法读取的宿主机上，此时创建会在其他宿主机上产生同名 VM
+				d.warn("vm %s was not found, but hosts %s could not be read; it is not created", v.Name, strings.Join(unread, ", "))
+				continue
+			}
+			d.add(Change{Action: ActionCreate, Kind: KindVM, Name: v.Name, Host: v.Host,
+				Diffs: []string{fmt.Sprintf("flavor: %s, image: %s, network: %s", v.Flavor, v.Image, v.Network)}})
+			for _, r := range v.NAT {
+				d.add(Change{Action: ActionCreate, Kind: KindNAT, Name: natName(r), Host: v.Host, VM: v.Name,
+					Diffs: []string{"VM address is resolved after the VM is created"},
+					NAT:   &vm.NATRule{Proto: r.Proto, HostPort: r.HostPort, VMPort: r.VMPort, Comment: r.Comment}})
+			}
+			continue
+		}
+
+		var diffs []string
+		if v.Host != Auto {
+			diffField(&diffs, "host", host, v.Host)
+		}
+		diffField(&diffs, "cpus", lv.CPUs, flavor.CPUs)
+		diffField(&diffs, "memoryMB", lv.MemoryMB, flavor.MemoryMB)
+		running := lv.State == "running"
+		if v.State == StateRunning && !running {
+			diffs = append(diffs, fmt.Sprintf("state: %s -> running", lv.State))
+		}
+		if v.State == StateStopped && running {
+			diffs = append(diffs, "state: running -> stopped")
+		}
+		diffField(&diffs, "autostart", lv.Autostart, v.Autostart)
+		diffField(&diffs, "groups", d.vmGroups(v.Name, declared), normList(v.Groups))
+		diffField(&diffs, "ha", slices.ContainsFunc(d.st.HA, func(r store.VMHA) bool { return r.VMName == v.Name }), v.HA)
+		if len(diffs) > 0 {
+			d.add(Change{Action: ActionUpdate, Kind: KindVM, Name: v.Name, Host: host, Diffs: diffs})
+		}
+		d.nat(v, lv, host)
+	}
+
+	if d.prune {
+		for _, h := range d.s.Hosts {
+			live, _ := d.live(h.Name)
+			if live == nil {
+				continue
+			}
+			for _, lv := range live.VMs {
+				if d.s.VMByName(lv.Name) == nil {
+					d.add(Change{Action: ActionDelete, Kind: KindVM, Name: lv.Name, Host: h.Name, Diffs: []string{"disks are kept"}})
+				}
+			}
+		}
+	}
+}
+
+// nat 对比转发到 VM 的端口规则，按 VM 当前的 IP 匹配
+func (d *differ) nat(v VM, lv *LiveVM, host string) {
+	if len(v.NAT) == 0 && !d.prune {
+		return
+	}
+	if lv.IP == "" {
+		if len(v.NAT) > 0 {
+			d.warn("vm %s has no IP address; its NAT rules were not compared", v.Name)
+		}
+		return
+	}
+	sh := d.storeHost(host)
+	live := d.st.Live[sh.ID]
+	var current []vm.NATRule
+	for _, r := range live.NAT {
+		if r.VMIP == lv.IP {
+			current = append(current, r)
+		}
+	}
+	for _, r := range v.NAT {
+		rule := vm.NATRule{Proto: r.Proto, HostPort: r.HostPort, VMIP: lv.IP, VMPort: r.VMPort, Comment: r.Comment}
+		if !slices.ContainsFunc(current, func(c vm.NATRule) bool { return sameRule(c, rule) }) {
+			d.add(Change{Action: ActionCreate, Kind: KindNAT, Name: natName(r), Host: host, VM: v.Name,
+				Diffs: []string{"to " + lv.IP + ":" + r.VMPort}, NAT: &rule})
+		}
+	}
+	if d.prune {
+		for _, c := range current {
+			if !slices.ContainsFunc(v.NAT, func(r NATRule) bool {
+				return sameRule(c, vm.NATRule{Proto: r.Proto, HostPort: r.HostPort, VMIP: c.VMIP, VMPort: r.VMPort})
+			}) {
+				c := c
+				d.add(Change{Action: ActionDelete, Kind: KindNAT, Name: fmt.Sprintf("%s %s -> %s", c.Proto, c.HostPort, c.VMPort),
+					Host: host, VM: v.Name, NAT: &c})
+			}
+		}
+	}
+}
+
+// vmGroups VM 当前所在的、spec 中声明的放置组
+func (d *differ) vmGroups(vmName string, declared map[string]bool) string {
+	var names []string
+	for _, g := range d.st.Groups {
+		if declared[g.Name] && slices.Contains(g.Members, vmName) {
+			names = append(names, g.Name)
+		}
+	}
+	return normList(names)
+}
+
+func sameRule(a, b vm.NATRule) bool {
+	return a.Proto == b.Proto && a.HostPort == b.HostPort && a.VMIP == b.VMIP && a.VMPort == b.VMPort
+}
+
+func natName(r NATRule) string {
+	return fmt.Sprintf("%s %s -> %s", r.Proto, r.HostPort, r.VMPort)
+}
+
+// normList 排序后以逗号连接，用于比较无序列表
+func normList(list []string) string {
+	list = slices.Clone(list)
+	slices.Sort(list)
+	return "[" + strings.Join(list, ",") + "]"
+}
+
+// normTags 去掉标签两侧空白，与 spec 中的列表写法一致
+func normTags(tags string) string {
+	var list []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			list = append(list, t)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
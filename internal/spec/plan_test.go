@@ -0,0 +1,281 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"vmcat/internal/store"
+	"vmcat/internal/vm"
+)
+
+const testSpec = `
+hosts:
+  - name: h1
+    host: 10.0.0.1
+    tags: [ssd]
+flavors:
+  - name: small
+    cpus: 2
+    memoryMB: 2048
+    diskGB: 20
+pools:
+  - name: data
+    host: h1
+    path: /data
+vms:
+  - name: web
+    host: h1
+    flavor: small
+    image: debian
+    autostart: true
+    nat:
+      - hostPort: "8080"
+        vmPort: "80"
+images:
+  - name: debian
+    host: h1
+    basePath: /images/debian.qcow2
+`
+
+// testState 与 testSpec 一致的实际状态，另有一台未连接的宿主机 h2
+func testState() *State {
+	return &State{
+		Hosts: []store.Host{
+			{ID: "1", Name: "h1", Host: "10.0.0.1", Port: 22, User: "root", AuthType: "key", Tags: "ssd"},
+			{ID: "2", Name: "h2", Host: "10.0.0.2", Port: 22, User: "root", AuthType: "key"},
+		},
+		Flavors: []store.Flavor{{Name: "small", CPUs: 2, MemoryMB: 2048, DiskGB: 20}},
+		Images:  map[string][]store.Image{"1": {{Name: "debian", BasePath: "/images/debian.qcow2"}}},
+		Live: map[string]*HostState{
+			"1": {
+				VMs:      []LiveVM{{Name: "web", State: "running", CPUs: 2, MemoryMB: 2048, Autostart: true, IP: "192.168.122.10"}},
+				Networks: []vm.Network{{Name: "default", State: "active", Autostart: "yes"}},
+				Pools: []vm.StoragePool{
+					{Name: "default", State: "running", Autostart: "yes"},
+					{Name: "data", State: "running", Autostart: "yes"},
+				},
+				NAT: []vm.NATRule{{Proto: "tcp", HostPort: "8080", VMIP: "192.168.122.10", VMPort: "80"}},
+			},
+		},
+	}
+}
+
+// summary 变更概要 "action kind name@host: diffs"
+func summary(c Change) string {
+	s := fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+	if c.Host != "" {
+		s += "@" + c.Host
+	}
+	if len(c.Diffs) > 0 {
+		s += ": " + strings.Join(c.Diffs, ", ")
+	}
+	return s
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     func(s *Spec)
+		state    func(st *State)
+		prune    bool
+		want     []string
+		warnings []string // 须包含的警告
+	}{
+		{
+			name: "in sync",
+			want: []string{},
+		},
+		{
+			name: "in sync with prune keeps default pool and network",
+			// h2 未在 spec 中声明
+			prune: true,
+			want:  []string{"delete host h2"},
+		},
+		{
+			name: "flavor change updates VM",
+			spec: func(s *Spec) { s.Flavors[0].CPUs = 4 },
+			want: []string{
+				"update flavor small: cpus: 2 -> 4",
+				"update vm web@h1: cpus: 2 -> 4",
+			},
+		},
+		{
+			name: "host fields",
+			spec: func(s *Spec) {
+				s.Hosts[0].Port = 2222
+				s.Hosts[0].Tags = []string{"ssd", "gpu"}
+				s.Hosts[0].Password = "secret"
+			},
+			want: []string{"update host h1: port: 22 -> 2222, tags: ssd -> ssd,gpu, password: (changed)"},
+		},
+		{
+			name:  "unchanged password is not reported",
+			spec:  func(s *Spec) { s.Hosts[0].Password = "secret" },
+			state: func(st *State) { st.Hosts[0].Password = "secret" },
+			want:  []string{},
+		},
+		{
+			name: "new VM with NAT",
+			spec: func(s *Spec) {
+				s.VMs = append(s.VMs, VM{Name: "db", Host: "h1", Flavor: "small", Image: "debian", Network: "default",
+					NAT: []NATRule{{Proto: "tcp", HostPort: "5432", VMPort: "5432"}}})
+			},
+			want: []string{
+				"create vm db@h1: flavor: small, image: debian, network: default",
+				"create nat tcp 5432 -> 5432@h1: VM address is resolved after the VM is created",
+			},
+		},
+		{
+			name: "VM state and autostart",
+			spec: func(s *Spec) { s.VMs[0].State = StateStopped; s.VMs[0].Autostart = false },
+			want: []string{"update vm web@h1: state: running -> stopped, autostart: true -> false"},
+		},
+		{
+			name:  "stopped VM is started",
+			state: func(st *State) { st.Live["1"].VMs[0].State = "shut off" },
+			want:  []string{"update vm web@h1: state: shut off -> running"},
+		},
+		{
+			name: "auto VM is not created while a host is unreadable",
+			spec: func(s *Spec) {
+				s.VMs = append(s.VMs, VM{Name: "db", Host: Auto, Flavor: "small", Image: "debian", Network: "default"})
+			},
+			want:     []string{},
+			warnings: []string{"vm db was not found, but hosts h2 could not be read"},
+		},
+		{
+			name:     "resources on an unreadable host are skipped",
+			state:    func(st *State) { delete(st.Live, "1") },
+			want:     []string{},
+			warnings: []string{"host h1 is not connected or could not be read"},
+		},
+		{
+			name: "new host creates its resources",
+			spec: func(s *Spec) {
+				s.Hosts = append(s.Hosts, Host{Name: "h3", Host: "10.0.0.3", Port: 22, User: "root", AuthType: "key", Backend: "virsh"})
+				s.Pools = append(s.Pools, Pool{Name: "data", Host: "h3", Path: "/data"})
+				s.VMs = append(s.VMs, VM{Name: "db", Host: "h3", Flavor: "small", Image: "debian", Network: "default"})
+			},
+			want: []string{
+				"create host h3: address: root@10.0.0.3:22",
+				"create pool data@h3: path: /data",
+				"create vm db@h3: flavor: small, image: debian, network: default",
+			},
+			warnings: []string{"host h3 will be added"},
+		},
+		{
+			name: "pool and network state",
+			spec: func(s *Spec) {
+				off := false
+				s.Pools[0].Autostart = &off
+				s.Networks = append(s.Networks, Network{Name: "default", Host: "h1", Mode: "nat"})
+			},
+			state: func(st *State) { st.Live["1"].Networks[0].State = "inactive" },
+			want: []string{
+				"update pool data@h1: autostart: true -> false",
+				"update network default@h1: active: false -> true",
+			},
+		},
+		{
+			name: "placement group and HA",
+			spec: func(s *Spec) {
+				s.Groups = []Group{{Name: "web", Policy: "anti-affinity"}}
+				s.VMs[0].Groups = []string{"web"}
+				s.VMs[0].HA = true
+			},
+			want: []string{
+				"create group web: policy: anti-affinity",
+				"update vm web@h1: groups: [] -> [web], ha: false -> true",
+			},
+		},
+		{
+			name: "undeclared group members are ignored",
+			spec: func(s *Spec) {
+				s.Groups = []Group{{Name: "web", Policy: "anti-affinity"}}
+				s.VMs[0].Groups = []string{"web"}
+			},
+			state: func(st *State) {
+				st.Groups = []store.PlacementGroup{
+					{Name: "web", Policy: "affinity", Members: []string{"web"}},
+					{Name: "other", Policy: "affinity", Members: []string{"web"}},
+				}
+			},
+			want: []string{"update group web: policy: affinity -> anti-affinity"},
+		},
+		{
+			name: "NAT rules",
+			spec: func(s *Spec) {
+				s.VMs[0].NAT = append(s.VMs[0].NAT, NATRule{Proto: "udp", HostPort: "5353", VMPort: "53"})
+			},
+			want: []string{"create nat udp 5353 -> 53@h1: to 192.168.122.10:53"},
+		},
+		{
+			name:     "NAT without IP is not compared",
+			state:    func(st *State) { st.Live["1"].VMs[0].IP = "" },
+			want:     []string{},
+			warnings: []string{"vm web has no IP address"},
+		},
+		{
+			name: "prune deletes in reverse dependency order before creates",
+			spec: func(s *Spec) {
+				s.Flavors = append(s.Flavors, Flavor{Name: "large", CPUs: 8, MemoryMB: 16384, DiskGB: 100})
+			},
+			state: func(st *State) {
+				live := st.Live["1"]
+				live.VMs = append(live.VMs, LiveVM{Name: "old", State: "shut off"})
+				live.Pools = append(live.Pools, vm.StoragePool{Name: "scratch", State: "running", Autostart: "no"})
+				live.Networks = append(live.Networks, vm.Network{Name: "lab", State: "active"})
+				live.NAT = append(live.NAT, vm.NATRule{Proto: "tcp", HostPort: "2222", VMIP: "192.168.122.10", VMPort: "22"})
+				st.Flavors = append(st.Flavors, store.Flavor{Name: "tiny", CPUs: 1, MemoryMB: 512})
+				st.Images["1"] = append(st.Images["1"], store.Image{Name: "centos"})
+			},
+			prune: true,
+			want: []string{
+				"delete nat tcp 2222 -> 22@h1",
+				"delete vm old@h1: disks are kept",
+				"delete network lab@h1",
+				"delete pool scratch@h1",
+				"delete image centos@h1",
+				"delete flavor tiny",
+				"delete host h2",
+				"create flavor large: 8 vCPU, 16384 MB, 100 GB",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(testSpec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.spec != nil {
+				tt.spec(s)
+			}
+			st := testState()
+			if tt.state != nil {
+				tt.state(st)
+			}
+			plan := Diff(s, st, tt.prune)
+
+			got := make([]string, 0, len(plan.Changes))
+			counts := map[string]int{}
+			for _, c := range plan.Changes {
+				got = append(got, summary(c))
+				counts[c.Action]++
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("changes:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+			if plan.Create != counts[ActionCreate] || plan.Update != counts[ActionUpdate] || plan.Delete != counts[ActionDelete] {
+				t.Errorf("counts = %d/%d/%d, want %v", plan.Create, plan.Update, plan.Delete, counts)
+			}
+			for _, w := range tt.warnings {
+				if !slices.ContainsFunc(plan.Warnings, func(pw string) bool { return strings.Contains(pw, w) }) {
+					t.Errorf("warnings %q, want one containing %q", plan.Warnings, w)
+				}
+			}
+		})
+	}
+}
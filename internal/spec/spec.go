@@ -0,0 +1,375 @@
+// Package spec 声明式基础设施描述
+//
+// 一份 spec（YAML 或 JSON）描述宿主机、规格、镜像、存储池、虚拟网络、放置组和 VM（含 cloud-init 与 NAT 规则）。
+// Diff 将 spec 与实际状态对比生成变更计划，调用方按计划顺序执行
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec 声明的全部资源，未列出的资源只有在 prune 时才会被删除
+type Spec struct {
+	Hosts    []Host    `json:"hosts"`
+	Groups   []Group   `json:"groups"`
+	Flavors  []Flavor  `json:"flavors"`
+	Images   []Image   `json:"images"`
+	Pools    []Pool    `json:"pools"`
+	Networks []Network `json:"networks"`
+	VMs      []VM      `json:"vms"`
+}
+
+// Host 宿主机，按名称对应
+type Host struct {
+	Name      string   `json:"name"`
+	Host      string   `json:"host"`     // SSH 地址
+	Port      int      `json:"port"`     // 默认 22
+	User      string   `json:"user"`     // 默认 root
+	AuthType  string   `json:"authType"` // key（默认）| password
+	KeyPath   string   `json:"keyPath"`
+	Password  string   `json:"password"` // 建议写成 ${ENV} 由命令行从环境变量替换，不提交到 git
+	ProxyAddr string   `json:"proxyAddr"`
+	Tags      []string `json:"tags"`
+	Backend   string   `json:"backend"` // virsh（默认）| libvirt
+}
+
+// Group 放置组
+type Group struct {
+	Name   string `json:"name"`
+	Policy string `json:"policy"` // affinity | anti-affinity
+}
+
+// Flavor 硬件规格，按名称对应
+type Flavor struct {
+	Name     string `json:"name"`
+	CPUs     int    `json:"cpus"`
+	MemoryMB int    `json:"memoryMB"`
+	DiskGB   int    `json:"diskGB"`
+}
+
+// Image 宿主机上的 OS 模板，按宿主机和名称对应
+type Image struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	BasePath  string `json:"basePath"`
+	OSVariant string `json:"osVariant"`
+}
+
+// Pool 目录型存储池
+type Pool struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	Autostart *bool  `json:"autostart"` // 默认 true
+}
+
+// Network libvirt 虚拟网络，已存在的网络只对比运行状态和自动启动，不对比 XML
+type Network struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Mode      string `json:"mode"`    // nat（默认）| isolated | bridge
+	Bridge    string `json:"bridge"`  // nat/isolated 为 libvirt 网桥名（可空），bridge 为宿主机已有网桥
+	Address   string `json:"address"` // 网关 CIDR，如 192.168.100.1/24
+	DHCPStart string `json:"dhcpStart"`
+	DHCPEnd   string `json:"dhcpEnd"`
+	Autostart *bool  `json:"autostart"` // 默认 true
+}
+
+// VM 基于规格和镜像创建的 VM，规格的 CPU 和内存变化会同步到已有 VM（下次启动生效）
+type VM struct {
+	Name      string    `json:"name"`
+	Host      string    `json:"host"` // 宿主机名称，auto 表示创建时由调度器选择
+	Flavor    string    `json:"flavor"`
+	Image     string    `json:"image"`
+	Network   string    `json:"network"` // 默认 default
+	NetType   string    `json:"netType"` // network（默认）| bridge
+	State     string    `json:"state"`   // running（默认）| stopped
+	Autostart bool      `json:"autostart"`
+	Groups    []string  `json:"groups"` // 放置组，spec 中列出的组内成员以此为准
+	HA        bool      `json:"ha"`     // 宿主机故障时在其他宿主机上重启
+	CloudInit CloudInit `json:"cloudInit"`
+	NAT       []NATRule `json:"nat"` // 转发到该 VM 第一个 IP 的端口，列出时以此为准
+}
+
+// CloudInit 只在创建 VM 时使用
+type CloudInit struct {
+	RootPassword string `json:"rootPassword"`
+	SSHKey       string `json:"sshKey"`
+	UserData     string `json:"userData"` // 自定义 user-data，非空时替代按密码和公钥生成的内容
+}
+
+// NATRule 宿主机端口转发到 VM
+type NATRule struct {
+	Proto    string `json:"proto"`    // tcp（默认）| udp
+	HostPort string `json:"hostPort"` // 端口或范围，如 8080 或 8080:8090
+	VMPort   string `json:"vmPort"`
+	Comment  string `json:"comment"`
+}
+
+// Auto VM 的 host 为 auto 时由调度器选择宿主机
+const Auto = "auto"
+
+// VM 状态
+const (
+	StateRunning = "running"
+	StateStopped = "stopped"
+)
+
+// Parse 解析 YAML 或 JSON 格式的 spec，拒绝未知字段，并补齐默认值后校验
+func Parse(data []byte) (*Spec, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	// YAML 先转为 JSON，再按 json 标签严格解码，两种格式使用同一套字段名
+	js, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	var s Spec
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// envRef ${NAME} 形式的环境变量引用
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv 替换 ${NAME} 形式的环境变量引用（不处理 $NAME，避免改动 user-data 中的脚本），
+// 返回引用了但未设置的变量
+func ExpandEnv(data []byte, lookup func(string) (string, bool)) ([]byte, []string) {
+	var missing []string
+	out := envRef.ReplaceAllFunc(data, func(m []byte) []byte {
+		name := string(envRef.FindSubmatch(m)[1])
+		val, ok := lookup(name)
+		if !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return []byte(val)
+	})
+	return out, missing
+}
+
+// Validate 补齐默认值并检查名称唯一、引用存在
+func (s *Spec) Validate() error {
+	hosts := make(map[string]bool)
+	for i := range s.Hosts {
+		h := &s.Hosts[i]
+		if h.Name == "" || h.Host == "" {
+			return fmt.Errorf("hosts[%d]: name and host are required", i)
+		}
+		if hosts[h.Name] {
+			return fmt.Errorf("host %s is declared twice", h.Name)
+		}
+		hosts[h.Name] = true
+		if h.Port == 0 {
+			h.Port = 22
+		}
+		if h.User == "" {
+			h.User = "root"
+		}
+		if h.AuthType == "" {
+			h.AuthType = "key"
+		}
+		if h.Backend == "" {
+			h.Backend = "virsh"
+		}
+	}
+	// 宿主机级资源须引用 spec 中声明的宿主机
+	hostRef := func(kind, name, host string) error {
+		if !hosts[host] {
+			return fmt.Errorf("%s %s: host %q is not declared in hosts", kind, name, host)
+		}
+		return nil
+	}
+	unique := func(kind string, keys []string) error {
+		seen := make(map[string]bool)
+		for _, k := range keys {
+			if seen[k] {
+				return fmt.Errorf("%s %s is declared twice", kind, k)
+			}
+			seen[k] = true
+		}
+		return nil
+	}
+
+	var keys []string
+	groups := make(map[string]bool)
+	for _, g := range s.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("groups: name is required")
+		}
+		if g.Policy != "affinity" && g.Policy != "anti-affinity" {
+			return fmt.Errorf("group %s: invalid policy %q (affinity | anti-affinity)", g.Name, g.Policy)
+		}
+		keys = append(keys, g.Name)
+		groups[g.Name] = true
+	}
+	if err := unique("group", keys); err != nil {
+		return err
+	}
+
+	keys = nil
+	flavors := make(map[string]bool)
+	for _, f := range s.Flavors {
+		if f.Name == "" || f.CPUs <= 0 || f.MemoryMB <= 0 {
+			return fmt.Errorf("flavor %q: name, cpus and memoryMB are required", f.Name)
+		}
+		keys = append(keys, f.Name)
+		flavors[f.Name] = true
+	}
+	if err := unique("flavor", keys); err != nil {
+		return err
+	}
+
+	keys = nil
+	for _, img := range s.Images {
+		if img.Name == "" || img.BasePath == "" {
+			return fmt.Errorf("image %q: name and basePath are required", img.Name)
+		}
+		if err := hostRef("image", img.Name, img.Host); err != nil {
+			return err
+		}
+		keys = append(keys, img.Host+"/"+img.Name)
+	}
+	if err := unique("image", keys); err != nil {
+		return err
+	}
+
+	keys = nil
+	for _, p := range s.Pools {
+		if p.Name == "" || p.Path == "" {
+			return fmt.Errorf("pool %q: name and path are required", p.Name)
+		}
+		if err := hostRef("pool", p.Name, p.Host); err != nil {
+			return err
+		}
+		keys = append(keys, p.Host+"/"+p.Name)
+	}
+	if err := unique("pool", keys); err != nil {
+		return err
+	}
+
+	keys = nil
+	for _, n := range s.Networks {
+		if n.Name == "" {
+			return fmt.Errorf("networks: name is required")
+		}
+		if err := hostRef("network", n.Name, n.Host); err != nil {
+			return err
+		}
+		switch n.Mode {
+		case "", "nat", "isolated":
+		case "bridge":
+			if n.Bridge == "" {
+				return fmt.Errorf("network %s: bridge mode needs a host bridge", n.Name)
+			}
+		default:
+			return fmt.Errorf("network %s: invalid mode %q (nat | isolated | bridge)", n.Name, n.Mode)
+		}
+		keys = append(keys, n.Host+"/"+n.Name)
+	}
+	if err := unique("network", keys); err != nil {
+		return err
+	}
+
+	keys = nil
+	for i := range s.VMs {
+		v := &s.VMs[i]
+		if v.Name == "" || v.Flavor == "" || v.Image == "" {
+			return fmt.Errorf("vm %q: name, flavor and image are required", v.Name)
+		}
+		if v.Host == "" {
+			v.Host = Auto
+		}
+		if v.Host != Auto {
+			if err := hostRef("vm", v.Name, v.Host); err != nil {
+				return err
+			}
+			if !s.hasImage(v.Host, v.Image) {
+				return fmt.Errorf("vm %s: image %s is not declared for host %s", v.Name, v.Image, v.Host)
+			}
+		} else if !s.hasImage("", v.Image) {
+			return fmt.Errorf("vm %s: image %s is not declared", v.Name, v.Image)
+		}
+		if !flavors[v.Flavor] {
+			return fmt.Errorf("vm %s: flavor %s is not declared", v.Name, v.Flavor)
+		}
+		for _, g := range v.Groups {
+			if !groups[g] {
+				return fmt.Errorf("vm %s: group %s is not declared", v.Name, g)
+			}
+		}
+		switch v.State {
+		case "":
+			v.State = StateRunning
+		case StateRunning, StateStopped:
+		default:
+			return fmt.Errorf("vm %s: invalid state %q (running | stopped)", v.Name, v.State)
+		}
+		if v.NetType == "" {
+			v.NetType = "network"
+		}
+		if v.Network == "" {
+			v.Network = "default"
+		}
+		for j := range v.NAT {
+			r := &v.NAT[j]
+			if r.HostPort == "" || r.VMPort == "" {
+				return fmt.Errorf("vm %s: nat[%d]: hostPort and vmPort are required", v.Name, j)
+			}
+			if r.Proto == "" {
+				r.Proto = "tcp"
+			}
+		}
+		keys = append(keys, v.Name)
+	}
+	return unique("vm", keys)
+}
+
+// hasImage host 为空时检查任一宿主机是否声明了该镜像
+func (s *Spec) hasImage(host, name string) bool {
+	for _, img := range s.Images {
+		if img.Name == name && (host == "" || img.Host == host) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostByName 按名称查找声明的宿主机
+func (s *Spec) HostByName(name string) *Host {
+	for i := range s.Hosts {
+		if s.Hosts[i].Name == name {
+			return &s.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// VMByName 按名称查找声明的 VM
+func (s *Spec) VMByName(name string) *VM {
+	for i := range s.VMs {
+		if s.VMs[i].Name == name {
+			return &s.VMs[i]
+		}
+	}
+	return nil
+}
+
+// autostart 未指定时默认开启
+func autostart(b *bool) bool {
+	return b == nil || *b
+}
@@ -1,15 +1,20 @@
 package ssh
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -19,10 +24,24 @@ import (
 // Client SSH 客户端封装
 type Client struct {
 	config       *Config
+	hostID       string // 所属宿主机，用于指标标签
 	client       *ssh.Client
 	connectedKey ssh.PublicKey // 连接后获取到的服务端公钥
+	timeouts     *Timeouts     // 各类操作的超时配置，nil 时使用默认值
+	limiter      *limiter      // 会话并发限制
+	overflow     *ssh.Client   // 会话饱和时按需建立的第二条连接
+	omu          sync.Mutex    // 保护 overflow 的建立与关闭
+	rmu          sync.Mutex    // 串行化重连，避免并发重连互相关闭对方刚建立的连接
 	mu           sync.Mutex
-	closed       bool
+	closed       atomic.Bool // 已被 Close 关闭，在 mu 内写入，overflowSession 在 omu 内读取
+	ping         *pingCall   // 进行中的 keepalive 请求，超时后仍未返回的请求被后续 Ping 复用
+}
+
+// pingCall 一次 keepalive 请求，done 关闭后 err 可读
+type pingCall struct {
+	client *ssh.Client
+	done   chan struct{}
+	err    error
 }
 
 // Config SSH 连接配置
@@ -39,21 +58,40 @@ type Config struct {
 
 // NewClient 创建 SSH 客户端
 func NewClient(cfg *Config) *Client {
-	return &Client{config: cfg}
+	return &Client{config: cfg, limiter: newLimiter(DefaultMaxSessions, false)}
 }
 
-// Connect 建立 SSH 连接
+// Connect 建立 SSH 连接，已被 Close 关闭的客户端不再重新连接
 func (c *Client) Connect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.closed.Load() {
+		return c.closedErr()
+	}
 	if c.client != nil {
 		c.client.Close()
 	}
+	c.closeOverflow()
 
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.client = client
+
+	// 固定首次连接接受的主机密钥，后续自动重连时必须一致
+	if c.config.HostKey == "" && c.connectedKey != nil {
+		c.config.HostKey = base64.StdEncoding.EncodeToString(c.connectedKey.Marshal())
+	}
+	return nil
+}
+
+// dial 按配置建立一条新的 SSH 连接
+func (c *Client) dial() (*ssh.Client, error) {
 	authMethods, err := c.buildAuth()
 	if err != nil {
-		return fmt.Errorf("build auth: %w", err)
+		return nil, fmt.Errorf("build auth: %w", err)
 	}
 
 	sshConfig := &ssh.ClientConfig{
@@ -69,37 +107,222 @@ func (c *Client) Connect() error {
 		// 通过 SOCKS5 代理建立 TCP 连接
 		dialer, err := proxy.SOCKS5("tcp", c.config.ProxyAddr, nil, proxy.Direct)
 		if err != nil {
-			return fmt.Errorf("socks5 proxy: %w", err)
+			return nil, fmt.Errorf("socks5 proxy: %w", err)
 		}
 		conn, err := dialer.Dial("tcp", addr)
 		if err != nil {
-			return fmt.Errorf("proxy dial %s: %w", addr, err)
+			return nil, fmt.Errorf("proxy dial %s: %w", addr, err)
 		}
 		// 在代理连接上建立 SSH 握手
 		ncc, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
 		if err != nil {
 			conn.Close()
-			return fmt.Errorf("ssh via proxy: %w", err)
+			return nil, fmt.Errorf("ssh via proxy: %w", err)
 		}
-		c.client = ssh.NewClient(ncc, chans, reqs)
-	} else {
-		client, err := ssh.Dial("tcp", addr, sshConfig)
+		return ssh.NewClient(ncc, chans, reqs), nil
+	}
+
+	client, err := ssh.Dial("tcp", addr, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("ssh dial %s: %w", addr, err)
+	}
+	return client, nil
+}
+
+// ExecResult 远程命令执行结果
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	Combined string // stdout/stderr 按写入顺序合并的输出
+	ExitCode int    // 远端退出码，未正常退出（被取消/连接断开）时为 -1
+}
+
+// Execute 执行远程命令，返回合并后的输出
+func (c *Client) Execute(cmd string) (string, error) {
+	res, err := c.ExecuteContext(context.Background(), cmd)
+	return res.Combined, err
+}
+
+// ExecuteContext 执行远程命令，ctx 结束时向远端进程发送 KILL 信号并关闭会话
+// 返回的 ExecResult 始终非 nil，命令以非零码退出时 err 为 *ssh.ExitError
+func (c *Client) ExecuteContext(ctx context.Context, cmd string) (*ExecResult, error) {
+	res := &ExecResult{ExitCode: -1}
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	session, sl, err := c.openSession(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer sl.release()
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	var combined syncBuffer
+	session.Stdout = io.MultiWriter(&stdout, &combined)
+	session.Stderr = io.MultiWriter(&stderr, &combined)
+	if err := session.Start(cmd); err != nil {
+		return res, fmt.Errorf("start: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err = <-done:
+		var exitErr *ssh.ExitError
+		switch {
+		case err == nil:
+			res.ExitCode = 0
+		case errors.As(err, &exitErr):
+			res.ExitCode = exitErr.ExitStatus()
+		}
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		<-done
+		err = ctx.Err()
+	}
+
+	res.Stdout = strings.TrimSpace(stdout.String())
+	res.Stderr = strings.TrimSpace(stderr.String())
+	res.Combined = strings.TrimSpace(combined.String())
+	return res, err
+}
+
+// Run 在 op 对应的超时内执行命令（ctx 取消时同样终止远端进程）
+// 成功返回 stdout；失败返回 stderr（为空时依次取 stdout、错误描述），便于调用方直接拼接错误信息
+func (c *Client) Run(ctx context.Context, op Op, cmd string) (string, error) {
+	start := time.Now()
+	timeout := c.timeouts.Get(op)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	used := c.GetSSHClient()
+	res, err := c.ExecuteContext(ctx, cmd)
+	if err != nil && op.Idempotent() && isConnErr(ctx, err) {
+		// 只读操作在连接异常时重连后重试一次
+		if c.Ping(keepaliveTimeout) != nil {
+			if reconnErr := c.reconnect(used); reconnErr != nil {
+				observeCommand(c.hostID, op, start, reconnErr)
+				return reconnErr.Error(), fmt.Errorf("reconnect: %w", reconnErr)
+			}
+		}
+		res, err = c.ExecuteContext(ctx, cmd)
+	}
+	observeCommand(c.hostID, op, start, err)
+	if err == nil {
+		return res.Stdout, nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%s timed out after %s: %w", op, timeout, err)
+	}
+	switch {
+	case res.Stderr != "":
+		return res.Stderr, err
+	case res.Stdout != "":
+		return res.Stdout, err
+	default:
+		return err.Error(), err
+	}
+}
+
+// Stream 执行常驻命令并逐行回调 stdout，直到命令退出、连接断开或 ctx 结束
+// 命令正常退出时返回 io.EOF；与终端会话一样不占用会话槽位，避免长期占满并发上限
+func (c *Client) Stream(ctx context.Context, cmd string, onLine func(line string)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr syncBuffer
+	session.Stderr = &stderr
+	if err := session.Start(cmd); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+	})
+	defer stop()
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		onLine(scanner.Text())
+	}
+	err = session.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err == nil {
+		return io.EOF
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// isConnErr 判断是否为连接层错误（非命令退出码、非 ctx 结束）
+func isConnErr(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var exitErr *ssh.ExitError
+	return !errors.As(err, &exitErr)
+}
+
+// openSession 获取会话槽位后打开会话，槽位已满时按 ctx 中的优先级排队
+// 会话结束后必须调用 slot.release 归还槽位
+func (c *Client) openSession(ctx context.Context) (*ssh.Session, *slot, error) {
+	sl, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var session *ssh.Session
+	if sl.overflow {
+		session, err = c.overflowSession()
 		if err != nil {
-			return fmt.Errorf("ssh dial %s: %w", addr, err)
+			// 溢出连接不可用时退回主连接
+			log.Printf("ssh: overflow connection to %s: %v", c.config.Host, err)
+			session, err = c.newSession()
 		}
-		c.client = client
+	} else {
+		session, err = c.newSession()
 	}
-	c.closed = false
-	return nil
+	if err != nil {
+		sl.release()
+		return nil, nil, err
+	}
+	return session, sl, nil
 }
 
-// Execute 执行远程命令
-func (c *Client) Execute(cmd string) (string, error) {
+// newSession 在主连接上打开新会话，连接断开时自动重连一次
+func (c *Client) newSession() (*ssh.Session, error) {
 	c.mu.Lock()
-	if c.client == nil || c.closed {
+	if c.closed.Load() {
+		c.mu.Unlock()
+		return nil, c.closedErr()
+	}
+	if c.client == nil {
 		c.mu.Unlock()
-		if err := c.Connect(); err != nil {
-			return "", err
+		if err := c.reconnect(nil); err != nil {
+			return nil, err
 		}
 		c.mu.Lock()
 	}
@@ -109,21 +332,112 @@ func (c *Client) Execute(cmd string) (string, error) {
 	session, err := client.NewSession()
 	if err != nil {
 		// 连接可能已断开，尝试重连
-		if reconnErr := c.Connect(); reconnErr != nil {
-			return "", fmt.Errorf("reconnect: %w", reconnErr)
+		if reconnErr := c.reconnect(client); reconnErr != nil {
+			return nil, fmt.Errorf("reconnect: %w", reconnErr)
 		}
 		c.mu.Lock()
 		client = c.client
 		c.mu.Unlock()
 		session, err = client.NewSession()
 		if err != nil {
-			return "", fmt.Errorf("new session: %w", err)
+			return nil, fmt.Errorf("new session: %w", err)
 		}
 	}
-	defer session.Close()
+	return session, nil
+}
+
+// reconnect 重新建立主连接，failed 为调用方发现异常的连接
+// 重连串行执行：failed 已被其他调用方替换为可用的新连接时不再重连，避免关闭正在使用的新连接
+func (c *Client) reconnect(failed *ssh.Client) error {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
 
-	output, err := session.CombinedOutput(cmd)
-	return strings.TrimSpace(string(output)), err
+	c.mu.Lock()
+	replaced := c.client != nil && !c.closed.Load() && c.client != failed
+	c.mu.Unlock()
+	if replaced {
+		return nil
+	}
+	return c.Connect()
+}
+
+// overflowSession 在溢出连接上打开会话，连接不存在或已断开时重新建立
+func (c *Client) overflowSession() (*ssh.Session, error) {
+	c.omu.Lock()
+	defer c.omu.Unlock()
+
+	if c.closed.Load() {
+		return nil, c.closedErr()
+	}
+	if c.overflow != nil {
+		if session, err := c.overflow.NewSession(); err == nil {
+			return session, nil
+		}
+		c.overflow.Close()
+		c.overflow = nil
+	}
+
+	client, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	// 拨号期间客户端被关闭：Close 已清理过溢出连接，新连接由这里关闭
+	if c.closed.Load() {
+		client.Close()
+		return nil, c.closedErr()
+	}
+	c.overflow = client
+	return client.NewSession()
+}
+
+// closeOverflow 关闭溢出连接
+func (c *Client) closeOverflow() {
+	c.omu.Lock()
+	defer c.omu.Unlock()
+	if c.overflow != nil {
+		c.overflow.Close()
+		c.overflow = nil
+	}
+}
+
+// SetSessionLimit 设置每条连接的并发会话上限，overflow 为 true 时允许饱和后建立第二条连接
+func (c *Client) SetSessionLimit(limit int, overflow bool) {
+	c.limiter.setLimit(limit, overflow)
+	if !overflow {
+		c.omu.Lock()
+		idle := c.overflow != nil && c.limiter.stats().OverflowInUse == 0
+		c.omu.Unlock()
+		if idle {
+			c.closeOverflow()
+		}
+	}
+}
+
+// QueueStats 返回会话队列指标
+func (c *Client) QueueStats() QueueStats {
+	st := c.limiter.stats()
+	c.omu.Lock()
+	st.OverflowActive = c.overflow != nil
+	c.omu.Unlock()
+	return st
+}
+
+// syncBuffer 并发安全的输出缓冲（stdout/stderr 合并写入）
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
 }
 
 // GetSSHClient 获取底层 SSH 客户端（用于高级操作如管道流式传输）
@@ -135,24 +449,61 @@ func (c *Client) GetSSHClient() *ssh.Client {
 
 // IsAlive 检查连接是否存活
 func (c *Client) IsAlive() bool {
+	return c.Ping(keepaliveTimeout) == nil
+}
+
+// Ping 发送 keepalive 请求检测连接，超时视为失败
+// 请求在锁外发送，半开连接上的阻塞不会影响其他会话；同一连接上最多只有一个请求在等待应答，
+// 超时的请求在连接关闭（重连或断开）时返回，不会随探测次数累积 goroutine
+func (c *Client) Ping(timeout time.Duration) error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	client := c.client
+	if client == nil || c.closed.Load() {
+		c.mu.Unlock()
+		return fmt.Errorf("not connected")
+	}
+	call := c.ping
+	if call == nil || call.client != client {
+		call = &pingCall{client: client, done: make(chan struct{})}
+		c.ping = call
+		go c.sendPing(call)
+	}
+	c.mu.Unlock()
 
-	if c.client == nil || c.closed {
-		return false
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-call.done:
+		return call.err
+	case <-timer.C:
+		return fmt.Errorf("keepalive timeout after %s", timeout)
+	}
+}
+
+// sendPing 发送 keepalive 请求，返回后清除进行中的记录
+func (c *Client) sendPing(call *pingCall) {
+	_, _, call.err = call.client.SendRequest("keepalive@vmcat", true, nil)
+	close(call.done)
+
+	c.mu.Lock()
+	if c.ping == call {
+		c.ping = nil
 	}
+	c.mu.Unlock()
+}
 
-	// 发送 keepalive 检测
-	_, _, err := c.client.SendRequest("keepalive@vmcat", true, nil)
-	return err == nil
+// closedErr 客户端已被 Close（断开或移除宿主机）后的错误，仍持有该客户端的调用方不会重新建立连接
+func (c *Client) closedErr() error {
+	return &unavailableError{msg: fmt.Sprintf("host %s disconnected", c.config.Host)}
 }
 
-// Close 关闭连接
+// Close 关闭连接，之后的会话和重连均返回 ErrUnavailable
 func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.closed = true
+	c.closed.Store(true)
+	c.closeOverflow()
 	if c.client != nil {
 		err := c.client.Close()
 		c.client = nil
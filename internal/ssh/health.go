@@ -0,0 +1,192 @@
+package ssh
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 宿主机连接状态
+const (
+	StateConnected    = "connected"    // keepalive 正常
+	StateDegraded     = "degraded"     // keepalive 偶发失败，连接仍可用
+	StateDown         = "down"         // 连续 keepalive 失败，判定断开
+	StateReconnecting = "reconnecting" // 正在按退避策略重连
+	StateDisconnected = "disconnected" // 未连接或已主动断开
+)
+
+const (
+	keepaliveInterval = 10 * time.Second // keepalive 探测间隔
+	keepaliveTimeout  = 5 * time.Second  // 单次探测超时
+	downAfter         = 2                // 连续失败多少次判定为 down
+	backoffMin        = time.Second      // 重连初始退避
+	backoffMax        = time.Minute      // 重连最大退避
+)
+
+// HostHealth 宿主机连接健康状态
+type HostHealth struct {
+	HostID    string `json:"hostId"`
+	State     string `json:"state"`
+	Failures  int    `json:"failures"`  // 连续探测失败次数
+	Attempts  int    `json:"attempts"`  // 当前轮重连尝试次数
+	LastError string `json:"lastError"` // 最近一次失败原因
+	LastCheck string `json:"lastCheck"` // 最近一次探测时间
+	Since     string `json:"since"`     // 进入当前状态的时间
+}
+
+// supervisor 单台宿主机的健康监控，定期 keepalive 并在断开后自动重连
+type supervisor struct {
+	pool   *Pool
+	client *Client
+	stop   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+	health HostHealth
+}
+
+func newSupervisor(p *Pool, hostID string, client *Client) *supervisor {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	return &supervisor{
+		pool:   p,
+		client: client,
+		stop:   make(chan struct{}),
+		health: HostHealth{HostID: hostID, State: StateConnected, LastCheck: now, Since: now},
+	}
+}
+
+// run 监控循环，直到 Stop 被调用
+func (s *supervisor) run() {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+
+		used := s.client.GetSSHClient()
+		err := s.client.Ping(keepaliveTimeout)
+		if err == nil {
+			s.update(func(h *HostHealth) {
+				h.Failures = 0
+				h.LastError = ""
+			}, StateConnected)
+			continue
+		}
+
+		failures := 0
+		s.update(func(h *HostHealth) {
+			h.Failures++
+			h.LastError = err.Error()
+			failures = h.Failures
+		}, "")
+		if failures < downAfter {
+			s.update(nil, StateDegraded)
+			continue
+		}
+
+		s.update(nil, StateDown)
+		if !s.reconnect(used) {
+			return
+		}
+	}
+}
+
+// reconnect 按指数退避重连 failed 连接（已被其他调用方替换时直接视为成功），成功返回 true，被停止返回 false
+func (s *supervisor) reconnect(failed *ssh.Client) bool {
+	backoff := backoffMin
+	for attempt := 1; ; attempt++ {
+		s.update(func(h *HostHealth) { h.Attempts = attempt }, StateReconnecting)
+
+		err := s.client.reconnect(failed)
+		if err == nil {
+			select {
+			case <-s.stop:
+				// 重连期间已被断开，丢弃新连接
+				s.client.Close()
+				return false
+			default:
+			}
+			log.Printf("ssh: host %s reconnected after %d attempts", s.health.HostID, attempt)
+			s.update(func(h *HostHealth) {
+				h.Failures = 0
+				h.Attempts = 0
+				h.LastError = ""
+			}, StateConnected)
+			return true
+		}
+
+		s.update(func(h *HostHealth) { h.LastError = err.Error() }, "")
+
+		// 退避时间加 ±20% 抖动，避免多台宿主机同时重连
+		wait := backoff + time.Duration((rand.Float64()*0.4-0.2)*float64(backoff))
+		select {
+		case <-time.After(wait):
+		case <-s.stop:
+			return false
+		}
+		backoff *= 2
+		if backoff > backoffMax {
+			backoff = backoffMax
+		}
+	}
+}
+
+// update 修改健康状态，state 非空且发生变化时推送 host:state 事件
+func (s *supervisor) update(fn func(h *HostHealth), state string) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	s.mu.Lock()
+	if fn != nil {
+		fn(&s.health)
+	}
+	s.health.LastCheck = now
+	prev := s.health.State
+	changed := state != "" && state != prev
+	if changed {
+		s.health.State = state
+		s.health.Since = now
+	}
+	h := s.health
+	s.mu.Unlock()
+
+	if changed {
+		s.pool.emit("host:state", map[string]interface{}{
+			"hostId":    h.HostID,
+			"state":     h.State,
+			"prevState": prev,
+			"error":     h.LastError,
+			"attempts":  h.Attempts,
+		})
+	}
+}
+
+// snapshot 返回当前健康状态副本
+func (s *supervisor) snapshot() HostHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.health
+}
+
+// Stop 停止监控
+func (s *supervisor) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// usable 连接是否可用（connected 或 degraded）
+func (h HostHealth) usable() bool {
+	return h.State == StateConnected || h.State == StateDegraded
+}
+
+// unavailableErr 连接不可用时返回给调用方的错误
+func (h HostHealth) unavailableErr() error {
+	if h.LastError != "" {
+		return &unavailableError{msg: fmt.Sprintf("host %s is %s: %s", h.HostID, h.State, h.LastError)}
+	}
+	return &unavailableError{msg: fmt.Sprintf("host %s is %s", h.HostID, h.State)}
+}
@@ -0,0 +1,257 @@
+package ssh
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Priority 命令优先级
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota // 用户操作（UI/API 调用）
+	PriorityBackground                  // 周期性后台任务（历史采集等）
+)
+
+const (
+	// DefaultMaxSessions 每条 SSH 连接的默认并发会话上限
+	// 低于 OpenSSH 默认 MaxSessions(10)，为终端和 VNC 隧道预留余量
+	DefaultMaxSessions = 8
+	// backgroundShare 后台请求排队时，交互请求连续获得槽位的最大次数
+	backgroundShare = 4
+)
+
+type priorityKey struct{}
+
+// WithPriority 返回携带命令优先级的 ctx，未设置时视为交互优先级
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// QueueStats 单台宿主机的会话队列指标
+type QueueStats struct {
+	HostID            string  `json:"hostId"`
+	Limit             int     `json:"limit"`             // 每条连接的会话上限
+	Overflow          bool    `json:"overflow"`          // 是否允许溢出到第二条连接
+	OverflowActive    bool    `json:"overflowActive"`    // 第二条连接是否已建立
+	InUse             int     `json:"inUse"`             // 主连接占用的会话数
+	OverflowInUse     int     `json:"overflowInUse"`     // 溢出连接占用的会话数
+	QueuedInteractive int     `json:"queuedInteractive"` // 排队中的交互请求
+	QueuedBackground  int     `json:"queuedBackground"`  // 排队中的后台请求
+	Completed         uint64  `json:"completed"`         // 已完成的会话数
+	Waited            uint64  `json:"waited"`            // 曾经排队等待的会话数
+	AvgWaitMs         float64 `json:"avgWaitMs"`
+	MaxWaitMs         float64 `json:"maxWaitMs"`
+	AvgExecMs         float64 `json:"avgExecMs"`
+}
+
+// waiter 排队中的会话请求
+type waiter struct {
+	ch       chan struct{}
+	prio     Priority
+	granted  bool
+	overflow bool
+}
+
+// limiter 每台宿主机的会话并发限制器
+// 交互请求优先，但后台请求排队时每 backgroundShare 次授予至少让出一次，避免饿死
+type limiter struct {
+	mu            sync.Mutex
+	limit         int
+	overflow      bool
+	inUse         int
+	overflowInUse int
+	queues        [2][]*waiter
+	streak        int // 后台排队期间交互请求连续获得槽位的次数
+
+	completed uint64
+	waited    uint64
+	waitTotal time.Duration
+	waitMax   time.Duration
+	execTotal time.Duration
+}
+
+func newLimiter(limit int, overflow bool) *limiter {
+	if limit <= 0 {
+		limit = DefaultMaxSessions
+	}
+	return &limiter{limit: limit, overflow: overflow}
+}
+
+// slot 已获得的会话槽位
+type slot struct {
+	l        *limiter
+	overflow bool // 是否分配在溢出连接上
+	granted  time.Time
+	once     sync.Once
+}
+
+// release 归还槽位并记录执行耗时
+func (s *slot) release() {
+	s.once.Do(func() {
+		l := s.l
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.completed++
+		l.execTotal += time.Since(s.granted)
+		l.freeLocked(s.overflow)
+		l.grantLocked()
+	})
+}
+
+// acquire 获取会话槽位，槽位已满时按优先级排队，ctx 结束则放弃
+func (l *limiter) acquire(ctx context.Context) (*slot, error) {
+	prio := priorityFrom(ctx)
+	start := time.Now()
+
+	l.mu.Lock()
+	if len(l.queues[PriorityInteractive])+len(l.queues[PriorityBackground]) == 0 {
+		if overflow, ok := l.takeLocked(); ok {
+			l.mu.Unlock()
+			return &slot{l: l, overflow: overflow, granted: time.Now()}, nil
+		}
+	}
+	w := &waiter{ch: make(chan struct{}), prio: prio}
+	l.queues[prio] = append(l.queues[prio], w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ch:
+	case <-ctx.Done():
+		l.mu.Lock()
+		if !w.granted {
+			l.removeLocked(w)
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		// 取消与授予同时发生，归还刚拿到的槽位
+		l.freeLocked(w.overflow)
+		l.grantLocked()
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	wait := time.Since(start)
+	l.mu.Lock()
+	l.waited++
+	l.waitTotal += wait
+	if wait > l.waitMax {
+		l.waitMax = wait
+	}
+	l.mu.Unlock()
+	return &slot{l: l, overflow: w.overflow, granted: time.Now()}, nil
+}
+
+// takeLocked 占用一个空闲槽位，优先主连接，返回是否分配在溢出连接
+func (l *limiter) takeLocked() (overflow bool, ok bool) {
+	if l.inUse < l.limit {
+		l.inUse++
+		return false, true
+	}
+	if l.overflow && l.overflowInUse < l.limit {
+		l.overflowInUse++
+		return true, true
+	}
+	return false, false
+}
+
+func (l *limiter) hasFreeLocked() bool {
+	return l.inUse < l.limit || (l.overflow && l.overflowInUse < l.limit)
+}
+
+func (l *limiter) freeLocked(overflow bool) {
+	if overflow {
+		l.overflowInUse--
+	} else {
+		l.inUse--
+	}
+}
+
+// grantLocked 将空闲槽位分配给排队者
+func (l *limiter) grantLocked() {
+	for l.hasFreeLocked() {
+		w := l.nextLocked()
+		if w == nil {
+			return
+		}
+		overflow, _ := l.takeLocked()
+		l.removeLocked(w)
+		w.granted = true
+		w.overflow = overflow
+		close(w.ch)
+	}
+}
+
+// nextLocked 按公平策略选出下一个排队者（不出队）
+func (l *limiter) nextLocked() *waiter {
+	inter := l.queues[PriorityInteractive]
+	bg := l.queues[PriorityBackground]
+	if len(bg) == 0 {
+		l.streak = 0
+	}
+	if len(bg) > 0 && (len(inter) == 0 || l.streak >= backgroundShare) {
+		l.streak = 0
+		return bg[0]
+	}
+	if len(inter) > 0 {
+		if len(bg) > 0 {
+			l.streak++
+		}
+		return inter[0]
+	}
+	return nil
+}
+
+func (l *limiter) removeLocked(w *waiter) {
+	q := l.queues[w.prio]
+	for i, x := range q {
+		if x == w {
+			l.queues[w.prio] = append(q[:i:i], q[i+1:]...)
+			return
+		}
+	}
+}
+
+// setLimit 调整会话上限，扩容时立即唤醒排队者
+func (l *limiter) setLimit(limit int, overflow bool) {
+	if limit <= 0 {
+		limit = DefaultMaxSessions
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.overflow = overflow
+	l.grantLocked()
+}
+
+// stats 返回当前队列指标
+func (l *limiter) stats() QueueStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := QueueStats{
+		Limit:             l.limit,
+		Overflow:          l.overflow,
+		InUse:             l.inUse,
+		OverflowInUse:     l.overflowInUse,
+		QueuedInteractive: len(l.queues[PriorityInteractive]),
+		QueuedBackground:  len(l.queues[PriorityBackground]),
+		Completed:         l.completed,
+		Waited:            l.waited,
+		MaxWaitMs:         float64(l.waitMax) / float64(time.Millisecond),
+	}
+	if l.waited > 0 {
+		s.AvgWaitMs = float64(l.waitTotal) / float64(l.waited) / float64(time.Millisecond)
+	}
+	if l.completed > 0 {
+		s.AvgExecMs = float64(l.execTotal) / float64(l.completed) / float64(time.Millisecond)
+	}
+	return s
+}
@@ -0,0 +1,29 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"vmcat/internal/metrics"
+)
+
+// commandDuration 远程命令耗时（含排队等待会话的时间）
+var commandDuration = metrics.Default.NewHistogramVec(
+	"vmcat_ssh_command_duration_seconds",
+	"Duration of remote commands run over SSH, including time queued for a session.",
+	[]float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	"host_id", "op", "result",
+)
+
+// observeCommand 记录命令耗时，result 为 ok / timeout / error
+func observeCommand(hostID string, op Op, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		result = "timeout"
+	case err != nil:
+		result = "error"
+	}
+	commandDuration.Observe(time.Since(start).Seconds(), hostID, string(op), result)
+}
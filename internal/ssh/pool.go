@@ -1,31 +1,108 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+
+	"vmcat/internal/event"
 )
 
+// ErrUnavailable 宿主机未连接或连接暂不可用（用 errors.Is 判断）
+var ErrUnavailable = errors.New("host unavailable")
+
+// unavailableError 宿主机不可用错误，保留具体原因作为消息
+type unavailableError struct {
+	msg string
+}
+
+func (e *unavailableError) Error() string        { return e.msg }
+func (e *unavailableError) Is(target error) bool { return target == ErrUnavailable }
+
 // Pool SSH 连接池，每台宿主机一个连接
+// 每个连接由 supervisor 定期 keepalive，断开后自动重连
 type Pool struct {
-	clients map[string]*Client
-	mu      sync.RWMutex
+	clients     map[string]*Client
+	supervisors map[string]*supervisor
+	timeouts    *Timeouts
+	emitter     event.Emitter
+	maxSessions int  // 每条连接的会话上限
+	overflow    bool // 会话饱和时是否建立第二条连接
+	mu          sync.RWMutex
 }
 
 // NewPool 创建连接池
 func NewPool() *Pool {
 	return &Pool{
-		clients: make(map[string]*Client),
+		clients:     make(map[string]*Client),
+		supervisors: make(map[string]*supervisor),
+		timeouts:    NewTimeouts(),
+		emitter:     &event.NoopEmitter{},
+		maxSessions: DefaultMaxSessions,
+	}
+}
+
+// SetEmitter 设置事件发射器（连接状态变化推送 host:state）
+func (p *Pool) SetEmitter(e event.Emitter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emitter = e
+}
+
+// Timeouts 返回连接池共享的命令超时配置
+func (p *Pool) Timeouts() *Timeouts {
+	return p.timeouts
+}
+
+// SetSessionLimit 设置每台宿主机的并发会话上限，对已有连接立即生效
+func (p *Pool) SetSessionLimit(limit int, overflow bool) {
+	if limit <= 0 {
+		limit = DefaultMaxSessions
+	}
+	p.mu.Lock()
+	p.maxSessions = limit
+	p.overflow = overflow
+	clients := make([]*Client, 0, len(p.clients))
+	for _, c := range p.clients {
+		clients = append(clients, c)
+	}
+	p.mu.Unlock()
+
+	for _, c := range clients {
+		c.SetSessionLimit(limit, overflow)
 	}
 }
 
-// Get 获取指定宿主机的 SSH 客户端，不存在则返回错误
+// QueueStats 获取所有已连接宿主机的会话队列指标
+func (p *Pool) QueueStats() []QueueStats {
+	p.mu.RLock()
+	list := make([]QueueStats, 0, len(p.clients))
+	for id, c := range p.clients {
+		st := c.QueueStats()
+		st.HostID = id
+		list = append(list, st)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].HostID < list[j].HostID })
+	return list
+}
+
+// Get 获取指定宿主机的 SSH 客户端，不存在或正在重连则返回错误
 func (p *Pool) Get(hostID string) (*Client, error) {
 	p.mu.RLock()
 	client, ok := p.clients[hostID]
+	sup := p.supervisors[hostID]
 	p.mu.RUnlock()
 
 	if !ok {
-		return nil, fmt.Errorf("host %s not connected", hostID)
+		return nil, &unavailableError{msg: fmt.Sprintf("host %s not connected", hostID)}
+	}
+	if sup != nil {
+		if h := sup.snapshot(); !h.usable() {
+			return nil, h.unavailableErr()
+		}
 	}
 	return client, nil
 }
@@ -34,45 +111,82 @@ func (p *Pool) Get(hostID string) (*Client, error) {
 func (p *Pool) Connect(hostID string, cfg *Config) (*Client, error) {
 	p.mu.Lock()
 	// 已有连接则先关闭
-	if old, ok := p.clients[hostID]; ok {
-		old.Close()
-		delete(p.clients, hostID)
-	}
+	p.removeLocked(hostID)
+	maxSessions, overflow := p.maxSessions, p.overflow
 	p.mu.Unlock()
 
 	client := NewClient(cfg)
+	client.hostID = hostID
+	client.timeouts = p.timeouts
+	client.SetSessionLimit(maxSessions, overflow)
 	if err := client.Connect(); err != nil {
 		return nil, err
 	}
 
+	sup := newSupervisor(p, hostID, client)
 	p.mu.Lock()
+	// 拨号期间并发的 Connect 可能已存入连接，替换并关闭它，避免客户端和 supervisor 泄漏
+	p.removeLocked(hostID)
 	p.clients[hostID] = client
+	p.supervisors[hostID] = sup
 	p.mu.Unlock()
 
+	go sup.run()
+	p.emit("host:state", map[string]interface{}{
+		"hostId":    hostID,
+		"state":     StateConnected,
+		"prevState": StateDisconnected,
+	})
+
 	return client, nil
 }
 
 // Disconnect 断开宿主机连接
 func (p *Pool) Disconnect(hostID string) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	removed := p.removeLocked(hostID)
+	p.mu.Unlock()
 
-	if client, ok := p.clients[hostID]; ok {
-		client.Close()
-		delete(p.clients, hostID)
+	if removed {
+		p.emit("host:state", map[string]interface{}{
+			"hostId": hostID,
+			"state":  StateDisconnected,
+		})
 	}
 }
 
-// IsConnected 检查宿主机是否已连接
+// IsConnected 检查宿主机连接是否可用（基于 keepalive 状态，不发起网络请求）
 func (p *Pool) IsConnected(hostID string) bool {
+	return p.Health(hostID).usable()
+}
+
+// Health 获取宿主机连接健康状态
+func (p *Pool) Health(hostID string) HostHealth {
 	p.mu.RLock()
-	client, ok := p.clients[hostID]
+	sup, ok := p.supervisors[hostID]
 	p.mu.RUnlock()
 
 	if !ok {
-		return false
+		return HostHealth{HostID: hostID, State: StateDisconnected}
+	}
+	return sup.snapshot()
+}
+
+// HealthAll 获取所有已加入连接池的宿主机健康状态
+func (p *Pool) HealthAll() []HostHealth {
+	p.mu.RLock()
+	sups := make([]*supervisor, 0, len(p.supervisors))
+	for _, sup := range p.supervisors {
+		sups = append(sups, sup)
+	}
+	p.mu.RUnlock()
+
+	list := make([]HostHealth, 0, len(sups))
+	for _, sup := range sups {
+		list = append(list, sup.snapshot())
 	}
-	return client.IsAlive()
+	sort.Slice(list, func(i, j int) bool { return list[i].HostID < list[j].HostID })
+	return list
 }
 
 // CloseAll 关闭所有连接
@@ -80,8 +194,29 @@ func (p *Pool) CloseAll() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for id, client := range p.clients {
-		client.Close()
-		delete(p.clients, id)
+	for id := range p.clients {
+		p.removeLocked(id)
 	}
 }
+
+// removeLocked 停止监控并关闭连接，调用方需持有写锁
+func (p *Pool) removeLocked(hostID string) bool {
+	if sup, ok := p.supervisors[hostID]; ok {
+		sup.Stop()
+		delete(p.supervisors, hostID)
+	}
+	client, ok := p.clients[hostID]
+	if !ok {
+		return false
+	}
+	client.Close()
+	delete(p.clients, hostID)
+	return true
+}
+
+func (p *Pool) emit(topic string, data interface{}) {
+	p.mu.RLock()
+	emitter := p.emitter
+	p.mu.RUnlock()
+	emitter.Emit(topic, data)
+}
@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"sync"
+	"time"
+)
+
+// Op 远程命令的操作类别，不同类别使用不同的超时
+type Op string
+
+const (
+	OpQuery    Op = "query"    // 只读查询: list/dumpxml/dominfo 等
+	OpStats    Op = "stats"    // 资源采集: domstats/free/top 等
+	OpPower    Op = "power"    // 电源操作: start/shutdown/reboot 等
+	OpConfig   Op = "config"   // 配置变更: define/setvcpus/attach 等
+	OpSnapshot Op = "snapshot" // 快照创建/恢复/删除
+	OpDisk     Op = "disk"     // 磁盘操作: qemu-img create/resize、virt-install
+	OpClone    Op = "clone"    // virt-clone 全量复制
+	OpMigrate  Op = "migrate"  // 在线迁移
+)
+
+// Ops 全部操作类别（用于设置项遍历）
+var Ops = []Op{OpQuery, OpStats, OpPower, OpConfig, OpSnapshot, OpDisk, OpClone, OpMigrate}
+
+// Idempotent 是否为可安全重试的只读操作
+func (op Op) Idempotent() bool {
+	return op == OpQuery || op == OpStats
+}
+
+// DefaultTimeouts 各类操作的默认超时
+var DefaultTimeouts = map[Op]time.Duration{
+	OpQuery:    30 * time.Second,
+	OpStats:    20 * time.Second,
+	OpPower:    2 * time.Minute,
+	OpConfig:   time.Minute,
+	OpSnapshot: 10 * time.Minute,
+	OpDisk:     30 * time.Minute,
+	OpClone:    2 * time.Hour,
+	OpMigrate:  4 * time.Hour,
+}
+
+// Timeouts 各类操作的超时配置（并发安全）
+type Timeouts struct {
+	mu sync.RWMutex
+	m  map[Op]time.Duration
+}
+
+// NewTimeouts 创建超时配置，初始为默认值
+func NewTimeouts() *Timeouts {
+	m := make(map[Op]time.Duration, len(DefaultTimeouts))
+	for op, d := range DefaultTimeouts {
+		m[op] = d
+	}
+	return &Timeouts{m: m}
+}
+
+// Get 获取操作超时，t 为 nil 时返回默认值，未知类别返回 0（不限时）
+func (t *Timeouts) Get(op Op) time.Duration {
+	if t == nil {
+		return DefaultTimeouts[op]
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.m[op]
+}
+
+// Set 设置操作超时，d <= 0 时恢复默认值
+func (t *Timeouts) Set(op Op, d time.Duration) {
+	if d <= 0 {
+		d = DefaultTimeouts[op]
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.m[op] = d
+}
+
+// All 返回当前全部超时配置的副本
+func (t *Timeouts) All() map[Op]time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	m := make(map[Op]time.Duration, len(t.m))
+	for op, d := range t.m {
+		m[op] = d
+	}
+	return m
+}
@@ -1,38 +1,23 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"strings"
+	"path"
 )
 
-// WriteFile 通过 SSH 将数据写入远程文件，支持进度回调
-func (c *Client) WriteFile(remotePath string, reader io.Reader, size int64, onProgress func(written int64)) error {
-	c.mu.Lock()
-	if c.client == nil || c.closed {
-		c.mu.Unlock()
-		if err := c.Connect(); err != nil {
-			return err
-		}
-		c.mu.Lock()
-	}
-	client := c.client
-	c.mu.Unlock()
-
-	session, err := client.NewSession()
+// WriteFile 通过 SSH 将数据写入远程文件，支持进度回调；ctx 取消时中断传输
+func (c *Client) WriteFile(ctx context.Context, remotePath string, reader io.Reader, size int64, onProgress func(written int64)) error {
+	session, sl, err := c.openSession(ctx)
 	if err != nil {
-		if reconnErr := c.Connect(); reconnErr != nil {
-			return fmt.Errorf("reconnect: %w", reconnErr)
-		}
-		c.mu.Lock()
-		client = c.client
-		c.mu.Unlock()
-		session, err = client.NewSession()
-		if err != nil {
-			return fmt.Errorf("new session: %w", err)
-		}
+		return err
 	}
+	defer sl.release()
 	defer session.Close()
+	// 远端停止读取时 stdin.Write 会阻塞，取消时关闭会话使其返回
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
 
 	stdin, err := session.StdinPipe()
 	if err != nil {
@@ -40,8 +25,8 @@ func (c *Client) WriteFile(remotePath string, reader io.Reader, size int64, onPr
 	}
 
 	// 确保目标目录存在
-	dir := remotePath[:strings.LastIndex(remotePath, "/")]
-	if err := session.Start(fmt.Sprintf("mkdir -p '%s' && cat > '%s'", dir, remotePath)); err != nil {
+	cmd := fmt.Sprintf("mkdir -p %s && cat > %s", ShellQuote(path.Dir(remotePath)), ShellQuote(remotePath))
+	if err := session.Start(cmd); err != nil {
 		return fmt.Errorf("start: %w", err)
 	}
 
@@ -51,6 +36,9 @@ func (c *Client) WriteFile(remotePath string, reader io.Reader, size int64, onPr
 		n, readErr := reader.Read(buf)
 		if n > 0 {
 			if _, writeErr := stdin.Write(buf[:n]); writeErr != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				return fmt.Errorf("write: %w", writeErr)
 			}
 			written += int64(n)
@@ -0,0 +1,435 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AlertRule 告警规则
+type AlertRule struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Metric     string  `json:"metric"`     // host.cpu | host.memory | host.disk | host.load1 | host.unreachable | vm.cpu | vm.memory | vm.crashed
+	HostID     string  `json:"hostId"`     // 仅评估该宿主机，空为全部
+	HostTags   string  `json:"hostTags"`   // 逗号分隔，仅评估带这些标签的宿主机，空为不限
+	VMPattern  string  `json:"vmPattern"`  // 逗号分隔的 VM 名称通配符，仅对 vm.* 指标生效，空为不限
+	Operator   string  `json:"operator"`   // > | <
+	Threshold  float64 `json:"threshold"`  // 触发阈值
+	Hysteresis float64 `json:"hysteresis"` // 恢复回差：阈值 90、回差 5 时需回落到 85 以下才恢复
+	ForSeconds int     `json:"forSeconds"` // 条件持续满足多久后触发，0 为立即触发
+	Severity   string  `json:"severity"`   // info | warning | critical
+	Channels   string  `json:"channels"`   // 逗号分隔的通知渠道 ID，空为全部启用的渠道
+	Enabled    bool    `json:"enabled"`
+	CreatedAt  string  `json:"createdAt"`
+	UpdatedAt  string  `json:"updatedAt"`
+}
+
+// AlertChannel 告警通知渠道
+type AlertChannel struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`   // webhook | smtp | desktop
+	Config    map[string]string `json:"config"` // 按类型不同，如 webhook 的 url/secret，smtp 的 host/port/username/password/from/to
+	Enabled   bool              `json:"enabled"`
+	CreatedAt string            `json:"createdAt"`
+	UpdatedAt string            `json:"updatedAt"`
+}
+
+// alertSecretKeys 渠道配置中的敏感项，加密保存且不在列表中返回
+var alertSecretKeys = []string{"password", "secret"}
+
+// AlertSilence 告警静默，匹配的告警照常记录但不发送通知
+type AlertSilence struct {
+	ID        string `json:"id"`
+	RuleID    string `json:"ruleId"`    // 空为全部规则
+	HostID    string `json:"hostId"`    // 空为全部宿主机
+	VMPattern string `json:"vmPattern"` // 逗号分隔的 VM 名称通配符，空为不限
+	StartsAt  string `json:"startsAt"`  // 空为立即生效
+	EndsAt    string `json:"endsAt"`
+	Comment   string `json:"comment"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// AlertEvent 告警记录，每次触发一条，恢复时更新
+type AlertEvent struct {
+	ID         int64   `json:"id"`
+	RuleID     string  `json:"ruleId"`
+	RuleName   string  `json:"ruleName"`
+	Metric     string  `json:"metric"`
+	Severity   string  `json:"severity"`
+	HostID     string  `json:"hostId"`
+	HostName   string  `json:"hostName"`
+	VMName     string  `json:"vmName"`
+	State      string  `json:"state"` // firing | resolved
+	Value      float64 `json:"value"` // 触发时的值
+	Threshold  float64 `json:"threshold"`
+	Message    string  `json:"message"`
+	Silenced   bool    `json:"silenced"` // 触发时处于静默期，未发送通知
+	StartedAt  string  `json:"startedAt"`
+	ResolvedAt string  `json:"resolvedAt"`
+}
+
+// migrateAlerts 创建告警规则、通知渠道、静默和告警记录表
+func (s *Store) migrateAlerts() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id          TEXT PRIMARY KEY,
+		name        TEXT NOT NULL,
+		metric      TEXT NOT NULL,
+		host_id     TEXT DEFAULT '',
+		host_tags   TEXT DEFAULT '',
+		vm_pattern  TEXT DEFAULT '',
+		operator    TEXT DEFAULT '>',
+		threshold   REAL DEFAULT 0,
+		hysteresis  REAL DEFAULT 0,
+		for_seconds INTEGER DEFAULT 0,
+		severity    TEXT DEFAULT 'warning',
+		channels    TEXT DEFAULT '',
+		enabled     INTEGER DEFAULT 1,
+		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_channels (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		config     TEXT DEFAULT '{}',
+		enabled    INTEGER DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_silences (
+		id         TEXT PRIMARY KEY,
+		rule_id    TEXT DEFAULT '',
+		host_id    TEXT DEFAULT '',
+		vm_pattern TEXT DEFAULT '',
+		starts_at  TEXT DEFAULT '',
+		ends_at    TEXT NOT NULL,
+		comment    TEXT DEFAULT '',
+		created_by TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_events (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule_id     TEXT NOT NULL,
+		rule_name   TEXT DEFAULT '',
+		metric      TEXT DEFAULT '',
+		severity    TEXT DEFAULT '',
+		host_id     TEXT DEFAULT '',
+		host_name   TEXT DEFAULT '',
+		vm_name     TEXT DEFAULT '',
+		state       TEXT NOT NULL,
+		value       REAL DEFAULT 0,
+		threshold   REAL DEFAULT 0,
+		message     TEXT DEFAULT '',
+		silenced    INTEGER DEFAULT 0,
+		started_at  TEXT NOT NULL,
+		resolved_at TEXT DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alert_events_state ON alert_events(state, started_at);
+	CREATE INDEX IF NOT EXISTS idx_alert_events_host ON alert_events(host_id, started_at);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// === 告警规则 ===
+
+const alertRuleColumns = `id, name, metric, host_id, host_tags, vm_pattern, operator, threshold, hysteresis, for_seconds, severity, channels, enabled, created_at, updated_at`
+
+func scanAlertRule(row rowScanner) (*AlertRule, error) {
+	var r AlertRule
+	if err := row.Scan(&r.ID, &r.Name, &r.Metric, &r.HostID, &r.HostTags, &r.VMPattern, &r.Operator, &r.Threshold,
+		&r.Hysteresis, &r.ForSeconds, &r.Severity, &r.Channels, &r.Enabled, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// AlertRuleList 获取全部告警规则
+func (s *Store) AlertRuleList() ([]AlertRule, error) {
+	rows, err := s.db.Query(`SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []AlertRule
+	for rows.Next() {
+		r, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *r)
+	}
+	return list, nil
+}
+
+// AlertRuleGet 获取单条告警规则
+func (s *Store) AlertRuleGet(id string) (*AlertRule, error) {
+	return scanAlertRule(s.db.QueryRow(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = ?`, id))
+}
+
+// AlertRuleAdd 添加告警规则
+func (s *Store) AlertRuleAdd(r *AlertRule) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	r.CreatedAt, r.UpdatedAt = now, now
+	_, err := s.db.Exec(`
+		INSERT INTO alert_rules (`+alertRuleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ID, r.Name, r.Metric, r.HostID, r.HostTags, r.VMPattern, r.Operator, r.Threshold,
+		r.Hysteresis, r.ForSeconds, r.Severity, r.Channels, r.Enabled, now, now)
+	return err
+}
+
+// AlertRuleUpdate 更新告警规则
+func (s *Store) AlertRuleUpdate(r *AlertRule) error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	r.UpdatedAt = now
+	_, err := s.db.Exec(`
+		UPDATE alert_rules SET name=?, metric=?, host_id=?, host_tags=?, vm_pattern=?, operator=?, threshold=?,
+			hysteresis=?, for_seconds=?, severity=?, channels=?, enabled=?, updated_at=?
+		WHERE id=?
+	`, r.Name, r.Metric, r.HostID, r.HostTags, r.VMPattern, r.Operator, r.Threshold,
+		r.Hysteresis, r.ForSeconds, r.Severity, r.Channels, r.Enabled, now, r.ID)
+	return err
+}
+
+// AlertRuleDelete 删除告警规则
+func (s *Store) AlertRuleDelete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	return err
+}
+
+// === 通知渠道 ===
+
+func scanAlertChannel(row rowScanner) (*AlertChannel, error) {
+	var c AlertChannel
+	var config string
+	if err := row.Scan(&c.ID, &c.Name, &c.Type, &config, &c.Enabled, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(config), &c.Config)
+	if c.Config == nil {
+		c.Config = make(map[string]string)
+	}
+	for _, key := range alertSecretKeys {
+		if v := c.Config[key]; v != "" {
+			if dec, err := Decrypt(v); err == nil {
+				c.Config[key] = dec
+			}
+		}
+	}
+	return &c, nil
+}
+
+// encodeAlertConfig 序列化渠道配置，敏感项加密
+func encodeAlertConfig(config map[string]string) string {
+	out := make(map[string]string, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	for _, key := range alertSecretKeys {
+		if v := out[key]; v != "" && !IsEncrypted(v) {
+			if enc, err := Encrypt(v); err == nil {
+				out[key] = enc
+			}
+		}
+	}
+	data, _ := json.Marshal(out)
+	return string(data)
+}
+
+// AlertChannelList 获取全部通知渠道，withSecrets 为 false 时不返回密码等敏感项
+func (s *Store) AlertChannelList(withSecrets bool) ([]AlertChannel, error) {
+	rows, err := s.db.Query(`SELECT id, name, type, config, enabled, created_at, updated_at FROM alert_channels ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []AlertChannel
+	for rows.Next() {
+		c, err := scanAlertChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		if !withSecrets {
+			for _, key := range alertSecretKeys {
+				if c.Config[key] != "" {
+					c.Config[key] = ""
+				}
+			}
+		}
+		list = append(list, *c)
+	}
+	return list, nil
+}
+
+// AlertChannelGet 获取单个通知渠道（含敏感项）
+func (s *Store) AlertChannelGet(id string) (*AlertChannel, error) {
+	return scanAlertChannel(s.db.QueryRow(`SELECT id, name, type, config, enabled, created_at, updated_at FROM alert_channels WHERE id = ?`, id))
+}
+
+// AlertChannelAdd 添加通知渠道
+func (s *Store) AlertChannelAdd(c *AlertChannel) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	c.CreatedAt, c.UpdatedAt = now, now
+	_, err := s.db.Exec(`
+		INSERT INTO alert_channels (id, name, type, config, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.Name, c.Type, encodeAlertConfig(c.Config), c.Enabled, now, now)
+	return err
+}
+
+// AlertChannelUpdate 更新通知渠道，敏感项为空时保留原值
+func (s *Store) AlertChannelUpdate(c *AlertChannel) error {
+	old, err := s.AlertChannelGet(c.ID)
+	if err != nil {
+		return err
+	}
+	config := make(map[string]string, len(c.Config))
+	for k, v := range c.Config {
+		config[k] = v
+	}
+	for _, key := range alertSecretKeys {
+		if config[key] == "" && old.Config[key] != "" {
+			config[key] = old.Config[key]
+		}
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	c.UpdatedAt = now
+	_, err = s.db.Exec(`
+		UPDATE alert_channels SET name=?, type=?, config=?, enabled=?, updated_at=?
+		WHERE id=?
+	`, c.Name, c.Type, encodeAlertConfig(config), c.Enabled, now, c.ID)
+	return err
+}
+
+// AlertChannelDelete 删除通知渠道
+func (s *Store) AlertChannelDelete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM alert_channels WHERE id = ?`, id)
+	return err
+}
+
+// === 静默 ===
+
+// AlertSilenceList 获取静默列表，activeOnly 为 true 时仅返回未过期的
+func (s *Store) AlertSilenceList(activeOnly bool) ([]AlertSilence, error) {
+	query := `SELECT id, rule_id, host_id, vm_pattern, starts_at, ends_at, comment, created_by, created_at FROM alert_silences`
+	var args []interface{}
+	if activeOnly {
+		query += ` WHERE ends_at > ?`
+		args = append(args, time.Now().Format("2006-01-02 15:04:05"))
+	}
+	query += ` ORDER BY ends_at DESC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []AlertSilence
+	for rows.Next() {
+		var sl AlertSilence
+		if err := rows.Scan(&sl.ID, &sl.RuleID, &sl.HostID, &sl.VMPattern, &sl.StartsAt, &sl.EndsAt,
+			&sl.Comment, &sl.CreatedBy, &sl.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, sl)
+	}
+	return list, nil
+}
+
+// AlertSilenceAdd 添加静默
+func (s *Store) AlertSilenceAdd(sl *AlertSilence) error {
+	if sl.ID == "" {
+		sl.ID = uuid.New().String()
+	}
+	sl.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`
+		INSERT INTO alert_silences (id, rule_id, host_id, vm_pattern, starts_at, ends_at, comment, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, sl.ID, sl.RuleID, sl.HostID, sl.VMPattern, sl.StartsAt, sl.EndsAt, sl.Comment, sl.CreatedBy, sl.CreatedAt)
+	return err
+}
+
+// AlertSilenceDelete 删除静默（提前结束）
+func (s *Store) AlertSilenceDelete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM alert_silences WHERE id = ?`, id)
+	return err
+}
+
+// === 告警记录 ===
+
+const alertEventColumns = `id, rule_id, rule_name, metric, severity, host_id, host_name, vm_name, state, value, threshold, message, silenced, started_at, resolved_at`
+
+// AlertEventInsert 记录一次告警触发
+func (s *Store) AlertEventInsert(ev *AlertEvent) error {
+	result, err := s.db.Exec(`
+		INSERT INTO alert_events (rule_id, rule_name, metric, severity, host_id, host_name, vm_name, state, value, threshold, message, silenced, started_at, resolved_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ev.RuleID, ev.RuleName, ev.Metric, ev.Severity, ev.HostID, ev.HostName, ev.VMName, ev.State,
+		ev.Value, ev.Threshold, ev.Message, ev.Silenced, ev.StartedAt, ev.ResolvedAt)
+	if err != nil {
+		return err
+	}
+	ev.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// AlertEventResolve 将告警标记为已恢复
+func (s *Store) AlertEventResolve(id int64, resolvedAt, message string) error {
+	_, err := s.db.Exec(`UPDATE alert_events SET state='resolved', resolved_at=?, message=? WHERE id=?`, resolvedAt, message, id)
+	return err
+}
+
+// AlertEventList 获取告警记录，hostID/ruleID/state 为空则不过滤
+func (s *Store) AlertEventList(hostID, ruleID, state string, limit int) ([]AlertEvent, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.Query(`
+		SELECT `+alertEventColumns+`
+		FROM alert_events
+		WHERE (? = '' OR host_id = ?) AND (? = '' OR rule_id = ?) AND (? = '' OR state = ?)
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, hostID, hostID, ruleID, ruleID, state, state, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []AlertEvent
+	for rows.Next() {
+		var ev AlertEvent
+		if err := rows.Scan(&ev.ID, &ev.RuleID, &ev.RuleName, &ev.Metric, &ev.Severity, &ev.HostID, &ev.HostName,
+			&ev.VMName, &ev.State, &ev.Value, &ev.Threshold, &ev.Message, &ev.Silenced, &ev.StartedAt, &ev.ResolvedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, ev)
+	}
+	return list, nil
+}
+
+// AlertEventCleanup 清理超过指定天数的已恢复告警记录
+func (s *Store) AlertEventCleanup(days int) error {
+	cutoff := time.Now().AddDate(0, 0, -days).Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`DELETE FROM alert_events WHERE state = 'resolved' AND resolved_at < ?`, cutoff)
+	return err
+}
@@ -3,6 +3,7 @@ package store
 // AuditRecord 审计日志记录
 type AuditRecord struct {
 	ID        int    `json:"id"`
+	Actor     string `json:"actor"` // 操作者: 用户名，桌面模式为 local
 	HostID    string `json:"hostId"`
 	VMName    string `json:"vmName"`
 	Action    string `json:"action"`
@@ -25,16 +26,21 @@ func (s *Store) migrateAudit() error {
 	CREATE INDEX IF NOT EXISTS idx_audit_host ON audit_log(host_id, timestamp);
 	CREATE INDEX IF NOT EXISTS idx_audit_action ON audit_log(action, timestamp);
 	`
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// 兼容旧库: 添加 actor 列（已存在则忽略）
+	s.db.Exec(`ALTER TABLE audit_log ADD COLUMN actor TEXT DEFAULT ''`)
+	return nil
 }
 
-// AuditInsert 插入审计日志
-func (s *Store) AuditInsert(hostID, vmName, action, detail string) error {
+// AuditInsert 插入审计日志，actor 为执行操作的用户
+func (s *Store) AuditInsert(actor, hostID, vmName, action, detail string) error {
 	_, err := s.db.Exec(`
-		INSERT INTO audit_log (host_id, vm_name, action, detail)
-		VALUES (?, ?, ?, ?)
-	`, hostID, vmName, action, detail)
+		INSERT INTO audit_log (actor, host_id, vm_name, action, detail)
+		VALUES (?, ?, ?, ?, ?)
+	`, actor, hostID, vmName, action, detail)
 	return err
 }
 
@@ -44,7 +50,7 @@ func (s *Store) AuditList(hostID string, limit int) ([]AuditRecord, error) {
 		limit = 100
 	}
 	rows, err := s.db.Query(`
-		SELECT id, host_id, vm_name, action, detail, timestamp
+		SELECT id, actor, host_id, vm_name, action, detail, timestamp
 		FROM audit_log
 		WHERE host_id = ?
 		ORDER BY timestamp DESC
@@ -58,7 +64,7 @@ func (s *Store) AuditList(hostID string, limit int) ([]AuditRecord, error) {
 	var records []AuditRecord
 	for rows.Next() {
 		var r AuditRecord
-		if err := rows.Scan(&r.ID, &r.HostID, &r.VMName, &r.Action, &r.Detail, &r.Timestamp); err != nil {
+		if err := rows.Scan(&r.ID, &r.Actor, &r.HostID, &r.VMName, &r.Action, &r.Detail, &r.Timestamp); err != nil {
 			return nil, err
 		}
 		records = append(records, r)
@@ -72,7 +78,7 @@ func (s *Store) AuditListAll(limit int) ([]AuditRecord, error) {
 		limit = 200
 	}
 	rows, err := s.db.Query(`
-		SELECT id, host_id, vm_name, action, detail, timestamp
+		SELECT id, actor, host_id, vm_name, action, detail, timestamp
 		FROM audit_log
 		ORDER BY timestamp DESC
 		LIMIT ?
@@ -85,7 +91,7 @@ func (s *Store) AuditListAll(limit int) ([]AuditRecord, error) {
 	var records []AuditRecord
 	for rows.Next() {
 		var r AuditRecord
-		if err := rows.Scan(&r.ID, &r.HostID, &r.VMName, &r.Action, &r.Detail, &r.Timestamp); err != nil {
+		if err := rows.Scan(&r.ID, &r.Actor, &r.HostID, &r.VMName, &r.Action, &r.Detail, &r.Timestamp); err != nil {
 			return nil, err
 		}
 		records = append(records, r)
@@ -0,0 +1,301 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// VMDiskStatsRecord VM 块设备 I/O 历史记录，数值为采样区间内的速率
+// 汇总精度下速率为区间平均值（延迟按 IOPS 加权），xxxMax 为区间最大值；原始数据两者相同
+type VMDiskStatsRecord struct {
+	HostID       string  `json:"hostId"`
+	VMName       string  `json:"vmName"`
+	Device       string  `json:"device"`    // 目标设备名，如 vda
+	RdBps        float64 `json:"rdBps"`     // 读 bytes/s
+	WrBps        float64 `json:"wrBps"`     // 写 bytes/s
+	RdIOPS       float64 `json:"rdIops"`    // 读请求/s
+	WrIOPS       float64 `json:"wrIops"`    // 写请求/s
+	RdLatency    float64 `json:"rdLatency"` // 平均每次读耗时 (ms)
+	WrLatency    float64 `json:"wrLatency"` // 平均每次写耗时 (ms)
+	RdBpsMax     float64 `json:"rdBpsMax"`
+	WrBpsMax     float64 `json:"wrBpsMax"`
+	RdIOPSMax    float64 `json:"rdIopsMax"`
+	WrIOPSMax    float64 `json:"wrIopsMax"`
+	RdLatencyMax float64 `json:"rdLatencyMax"`
+	WrLatencyMax float64 `json:"wrLatencyMax"`
+	Resolution   string  `json:"resolution"` // raw | 5m | 1h
+	Timestamp    string  `json:"timestamp"`
+}
+
+// VMNetStatsRecord VM 网卡流量历史记录，数值为采样区间内的速率，汇总精度下的含义同 VMDiskStatsRecord
+type VMNetStatsRecord struct {
+	HostID     string  `json:"hostId"`
+	VMName     string  `json:"vmName"`
+	Device     string  `json:"device"`  // 宿主机侧设备名，如 vnet0
+	RxBps      float64 `json:"rxBps"`   // 接收 bytes/s
+	TxBps      float64 `json:"txBps"`   // 发送 bytes/s
+	RxPps      float64 `json:"rxPps"`   // 接收包/s
+	TxPps      float64 `json:"txPps"`   // 发送包/s
+	RxDrops    float64 `json:"rxDrops"` // 接收丢包/s
+	TxDrops    float64 `json:"txDrops"` // 发送丢包/s
+	RxBpsMax   float64 `json:"rxBpsMax"`
+	TxBpsMax   float64 `json:"txBpsMax"`
+	Resolution string  `json:"resolution"` // raw | 5m | 1h
+	Timestamp  string  `json:"timestamp"`
+}
+
+// migrateDeviceHistory 创建 VM 块设备和网卡历史表（原始数据及各级汇总）
+func (s *Store) migrateDeviceHistory() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS vm_disk_stats_history (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		host_id    TEXT NOT NULL,
+		vm_name    TEXT NOT NULL,
+		device     TEXT NOT NULL,
+		rd_bps     REAL,
+		wr_bps     REAL,
+		rd_iops    REAL,
+		wr_iops    REAL,
+		rd_latency REAL,
+		wr_latency REAL,
+		timestamp  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS vm_net_stats_history (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		host_id   TEXT NOT NULL,
+		vm_name   TEXT NOT NULL,
+		device    TEXT NOT NULL,
+		rx_bps    REAL,
+		tx_bps    REAL,
+		rx_pps    REAL,
+		tx_pps    REAL,
+		rx_drops  REAL,
+		tx_drops  REAL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_vm_disk_stats_host_time ON vm_disk_stats_history(host_id, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_vm_net_stats_host_time ON vm_net_stats_history(host_id, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_vm_disk_stats_time ON vm_disk_stats_history(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_vm_net_stats_time ON vm_net_stats_history(timestamp);
+	`
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	for _, t := range []rollupTier{tier5m, tierHourly} {
+		schema := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			host_id        TEXT NOT NULL,
+			vm_name        TEXT NOT NULL,
+			device         TEXT NOT NULL,
+			timestamp      DATETIME NOT NULL,
+			samples        INTEGER DEFAULT 0,
+			rd_bps         REAL,
+			wr_bps         REAL,
+			rd_iops        REAL,
+			wr_iops        REAL,
+			rd_latency     REAL,
+			wr_latency     REAL,
+			rd_bps_max     REAL,
+			wr_bps_max     REAL,
+			rd_iops_max    REAL,
+			wr_iops_max    REAL,
+			rd_latency_max REAL,
+			wr_latency_max REAL,
+			PRIMARY KEY (host_id, vm_name, device, timestamp)
+		);
+
+		CREATE TABLE IF NOT EXISTS %[2]s (
+			host_id    TEXT NOT NULL,
+			vm_name    TEXT NOT NULL,
+			device     TEXT NOT NULL,
+			timestamp  DATETIME NOT NULL,
+			samples    INTEGER DEFAULT 0,
+			rx_bps     REAL,
+			tx_bps     REAL,
+			rx_pps     REAL,
+			tx_pps     REAL,
+			rx_drops   REAL,
+			tx_drops   REAL,
+			rx_bps_max REAL,
+			tx_bps_max REAL,
+			PRIMARY KEY (host_id, vm_name, device, timestamp)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_time ON %[1]s(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_%[2]s_time ON %[2]s(timestamp);
+		`, t.disk, t.net)
+		if _, err := s.db.Exec(schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 设备汇总语句，见 StatsRollup；延迟按 IOPS 加权平均
+const (
+	diskRollup5m = `
+		INSERT OR REPLACE INTO vm_disk_stats_5m (host_id, vm_name, device, timestamp, samples,
+			rd_bps, wr_bps, rd_iops, wr_iops, rd_latency, wr_latency,
+			rd_bps_max, wr_bps_max, rd_iops_max, wr_iops_max, rd_latency_max, wr_latency_max)
+		SELECT host_id, vm_name, device, %[1]s, COUNT(*),
+			AVG(rd_bps), AVG(wr_bps), AVG(rd_iops), AVG(wr_iops),
+			COALESCE(SUM(rd_latency * rd_iops) / NULLIF(SUM(rd_iops), 0), 0),
+			COALESCE(SUM(wr_latency * wr_iops) / NULLIF(SUM(wr_iops), 0), 0),
+			MAX(rd_bps), MAX(wr_bps), MAX(rd_iops), MAX(wr_iops), MAX(rd_latency), MAX(wr_latency)
+		FROM vm_disk_stats_history
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, vm_name, device, %[1]s`
+	netRollup5m = `
+		INSERT OR REPLACE INTO vm_net_stats_5m (host_id, vm_name, device, timestamp, samples,
+			rx_bps, tx_bps, rx_pps, tx_pps, rx_drops, tx_drops, rx_bps_max, tx_bps_max)
+		SELECT host_id, vm_name, device, %[1]s, COUNT(*),
+			AVG(rx_bps), AVG(tx_bps), AVG(rx_pps), AVG(tx_pps), AVG(rx_drops), AVG(tx_drops), MAX(rx_bps), MAX(tx_bps)
+		FROM vm_net_stats_history
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, vm_name, device, %[1]s`
+	diskRollup1h = `
+		INSERT OR REPLACE INTO vm_disk_stats_1h (host_id, vm_name, device, timestamp, samples,
+			rd_bps, wr_bps, rd_iops, wr_iops, rd_latency, wr_latency,
+			rd_bps_max, wr_bps_max, rd_iops_max, wr_iops_max, rd_latency_max, wr_latency_max)
+		SELECT host_id, vm_name, device, %[1]s, SUM(samples),
+			SUM(rd_bps * samples) / SUM(samples), SUM(wr_bps * samples) / SUM(samples),
+			SUM(rd_iops * samples) / SUM(samples), SUM(wr_iops * samples) / SUM(samples),
+			COALESCE(SUM(rd_latency * rd_iops * samples) / NULLIF(SUM(rd_iops * samples), 0), 0),
+			COALESCE(SUM(wr_latency * wr_iops * samples) / NULLIF(SUM(wr_iops * samples), 0), 0),
+			MAX(rd_bps_max), MAX(wr_bps_max), MAX(rd_iops_max), MAX(wr_iops_max), MAX(rd_latency_max), MAX(wr_latency_max)
+		FROM vm_disk_stats_5m
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, vm_name, device, %[1]s`
+	netRollup1h = `
+		INSERT OR REPLACE INTO vm_net_stats_1h (host_id, vm_name, device, timestamp, samples,
+			rx_bps, tx_bps, rx_pps, tx_pps, rx_drops, tx_drops, rx_bps_max, tx_bps_max)
+		SELECT host_id, vm_name, device, %[1]s, SUM(samples),
+			SUM(rx_bps * samples) / SUM(samples), SUM(tx_bps * samples) / SUM(samples),
+			SUM(rx_pps * samples) / SUM(samples), SUM(tx_pps * samples) / SUM(samples),
+			SUM(rx_drops * samples) / SUM(samples), SUM(tx_drops * samples) / SUM(samples),
+			MAX(rx_bps_max), MAX(tx_bps_max)
+		FROM vm_net_stats_5m
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, vm_name, device, %[1]s`
+)
+
+// VMDeviceStatsInsert 批量插入一轮采集的块设备和网卡速率
+func (s *Store) VMDeviceStatsInsert(disks []VMDiskStatsRecord, nics []VMNetStatsRecord) error {
+	if len(disks) == 0 && len(nics) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range disks {
+		if _, err := tx.Exec(`
+			INSERT INTO vm_disk_stats_history (host_id, vm_name, device, rd_bps, wr_bps, rd_iops, wr_iops, rd_latency, wr_latency)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, d.HostID, d.VMName, d.Device, d.RdBps, d.WrBps, d.RdIOPS, d.WrIOPS, d.RdLatency, d.WrLatency); err != nil {
+			return err
+		}
+	}
+	for _, n := range nics {
+		if _, err := tx.Exec(`
+			INSERT INTO vm_net_stats_history (host_id, vm_name, device, rx_bps, tx_bps, rx_pps, tx_pps, rx_drops, tx_drops)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, n.HostID, n.VMName, n.Device, n.RxBps, n.TxBps, n.RxPps, n.TxPps, n.RxDrops, n.TxDrops); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// deviceFilter 设备历史查询条件，vmName / device 为空时不限
+func deviceFilter(hostID, vmName, device string, hours int) (string, []interface{}) {
+	where := "host_id = ? AND timestamp > ?"
+	args := []interface{}{hostID, statsCutoff(time.Duration(hours) * time.Hour)}
+	if vmName != "" {
+		where += " AND vm_name = ?"
+		args = append(args, vmName)
+	}
+	if device != "" {
+		where += " AND device = ?"
+		args = append(args, device)
+	}
+	return where, args
+}
+
+// VMDiskStatsHistory 获取块设备 I/O 历史，vmName 为空时返回宿主机上全部 VM，device 为空时返回全部设备
+func (s *Store) VMDiskStatsHistory(hostID, vmName, device string, hours int, resolution string) ([]VMDiskStatsRecord, error) {
+	where, args := deviceFilter(hostID, vmName, device, hours)
+	query := `
+		SELECT host_id, vm_name, device, rd_bps, wr_bps, rd_iops, wr_iops, rd_latency, wr_latency,
+			rd_bps, wr_bps, rd_iops, wr_iops, rd_latency, wr_latency, timestamp
+		FROM vm_disk_stats_history
+		WHERE ` + where + `
+		ORDER BY timestamp, vm_name, device`
+	if t, ok := rollupTierOf(resolution); ok {
+		query = fmt.Sprintf(`
+		SELECT host_id, vm_name, device, rd_bps, wr_bps, rd_iops, wr_iops, rd_latency, wr_latency,
+			rd_bps_max, wr_bps_max, rd_iops_max, wr_iops_max, rd_latency_max, wr_latency_max, timestamp
+		FROM %s
+		WHERE %s
+		ORDER BY timestamp, vm_name, device`, t.disk, where)
+	} else {
+		resolution = ResolutionRaw
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []VMDiskStatsRecord
+	for rows.Next() {
+		r := VMDiskStatsRecord{Resolution: resolution}
+		if err := rows.Scan(&r.HostID, &r.VMName, &r.Device, &r.RdBps, &r.WrBps, &r.RdIOPS, &r.WrIOPS,
+			&r.RdLatency, &r.WrLatency, &r.RdBpsMax, &r.WrBpsMax, &r.RdIOPSMax, &r.WrIOPSMax,
+			&r.RdLatencyMax, &r.WrLatencyMax, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// VMNetStatsHistory 获取网卡流量历史，过滤条件同 VMDiskStatsHistory
+func (s *Store) VMNetStatsHistory(hostID, vmName, device string, hours int, resolution string) ([]VMNetStatsRecord, error) {
+	where, args := deviceFilter(hostID, vmName, device, hours)
+	query := `
+		SELECT host_id, vm_name, device, rx_bps, tx_bps, rx_pps, tx_pps, rx_drops, tx_drops, rx_bps, tx_bps, timestamp
+		FROM vm_net_stats_history
+		WHERE ` + where + `
+		ORDER BY timestamp, vm_name, device`
+	if t, ok := rollupTierOf(resolution); ok {
+		query = fmt.Sprintf(`
+		SELECT host_id, vm_name, device, rx_bps, tx_bps, rx_pps, tx_pps, rx_drops, tx_drops, rx_bps_max, tx_bps_max, timestamp
+		FROM %s
+		WHERE %s
+		ORDER BY timestamp, vm_name, device`, t.net, where)
+	} else {
+		resolution = ResolutionRaw
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []VMNetStatsRecord
+	for rows.Next() {
+		r := VMNetStatsRecord{Resolution: resolution}
+		if err := rows.Scan(&r.HostID, &r.VMName, &r.Device, &r.RxBps, &r.TxBps, &r.RxPps, &r.TxPps,
+			&r.RxDrops, &r.TxDrops, &r.RxBpsMax, &r.TxBpsMax, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
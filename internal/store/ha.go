@@ -0,0 +1,220 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// HostFence 宿主机隔离配置，密码加密保存且不在列表中返回
+type HostFence struct {
+	HostID    string `json:"hostId"`
+	Method    string `json:"method"`   // ipmi | script | none
+	Address   string `json:"address"`  // BMC 地址（ipmi）
+	Username  string `json:"username"` // BMC 用户（ipmi）
+	Password  string `json:"password"` // BMC 密码（ipmi），更新时为空表示不修改
+	Script    string `json:"script"`   // 隔离脚本，须位于服务端的隔离脚本目录内（script）
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// VMHA 标记为高可用的 VM 及其最近一次缓存的定义 XML
+// 宿主机故障时按缓存的 XML 在其他宿主机上重新定义并启动，VM 磁盘须位于共享存储
+type VMHA struct {
+	HostID       string `json:"hostId"` // 当前所在宿主机（迁移、故障转移后更新）
+	VMName       string `json:"vmName"`
+	XML          string `json:"-"`
+	XMLUpdatedAt string `json:"xmlUpdatedAt"`
+	Running      bool   `json:"running"`    // 最近一次观察到的运行状态，故障转移后只启动原本运行中的 VM
+	FailedFrom   string `json:"failedFrom"` // 故障转移前所在的宿主机，该宿主机恢复并清理旧定义后清空
+	FailedAt     string `json:"failedAt"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// migrateHA 创建隔离配置和高可用 VM 表
+func (s *Store) migrateHA() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS host_fence (
+		host_id    TEXT PRIMARY KEY,
+		method     TEXT NOT NULL,
+		address    TEXT DEFAULT '',
+		username   TEXT DEFAULT '',
+		password   TEXT DEFAULT '',
+		script     TEXT DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS vm_ha (
+		host_id        TEXT NOT NULL,
+		vm_name        TEXT NOT NULL,
+		xml            TEXT DEFAULT '',
+		xml_updated_at TEXT DEFAULT '',
+		running        INTEGER DEFAULT 1,
+		failed_from    TEXT DEFAULT '',
+		failed_at      TEXT DEFAULT '',
+		created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (host_id, vm_name)
+	);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// HostFenceGet 获取宿主机隔离配置（含密码），未配置时返回 sql.ErrNoRows
+func (s *Store) HostFenceGet(hostID string) (*HostFence, error) {
+	var f HostFence
+	err := s.db.QueryRow(`
+		SELECT host_id, method, address, username, password, script, updated_at FROM host_fence WHERE host_id = ?
+	`, hostID).Scan(&f.HostID, &f.Method, &f.Address, &f.Username, &f.Password, &f.Script, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if f.Password != "" {
+		if dec, err := Decrypt(f.Password); err == nil {
+			f.Password = dec
+		}
+	}
+	return &f, nil
+}
+
+// HostFenceList 获取所有宿主机的隔离配置（不含密码）
+func (s *Store) HostFenceList() ([]HostFence, error) {
+	rows, err := s.db.Query(`SELECT host_id, method, address, username, script, updated_at FROM host_fence`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []HostFence
+	for rows.Next() {
+		var f HostFence
+		if err := rows.Scan(&f.HostID, &f.Method, &f.Address, &f.Username, &f.Script, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, f)
+	}
+	return list, nil
+}
+
+// HostFenceSet 保存宿主机隔离配置，密码为空时保留原值
+func (s *Store) HostFenceSet(f *HostFence) error {
+	pwd := f.Password
+	if pwd == "" {
+		if old, err := s.HostFenceGet(f.HostID); err == nil {
+			pwd = old.Password
+		}
+	}
+	if pwd != "" && !IsEncrypted(pwd) {
+		if enc, err := Encrypt(pwd); err == nil {
+			pwd = enc
+		}
+	}
+	f.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`
+		INSERT INTO host_fence (host_id, method, address, username, password, script, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(host_id) DO UPDATE SET method = excluded.method, address = excluded.address, username = excluded.username,
+			password = excluded.password, script = excluded.script, updated_at = excluded.updated_at
+	`, f.HostID, f.Method, f.Address, f.Username, pwd, f.Script, f.UpdatedAt)
+	return err
+}
+
+// HostFenceDelete 删除宿主机隔离配置
+func (s *Store) HostFenceDelete(hostID string) error {
+	_, err := s.db.Exec(`DELETE FROM host_fence WHERE host_id = ?`, hostID)
+	return err
+}
+
+// VMHAList 获取高可用 VM，hostID 为空时返回全部，withXML 为 false 时不读取 XML
+func (s *Store) VMHAList(hostID string, withXML bool) ([]VMHA, error) {
+	xmlCol := "''"
+	if withXML {
+		xmlCol = "xml"
+	}
+	query := `SELECT host_id, vm_name, ` + xmlCol + `, xml_updated_at, running, failed_from, failed_at, created_at FROM vm_ha`
+	var args []interface{}
+	if hostID != "" {
+		query += ` WHERE host_id = ?`
+		args = append(args, hostID)
+	}
+	rows, err := s.db.Query(query+` ORDER BY host_id, vm_name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []VMHA
+	for rows.Next() {
+		var v VMHA
+		if err := rows.Scan(&v.HostID, &v.VMName, &v.XML, &v.XMLUpdatedAt, &v.Running, &v.FailedFrom, &v.FailedAt, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// VMHAAdd 标记 VM 为高可用并缓存定义 XML（已标记时只更新 XML）
+func (s *Store) VMHAAdd(hostID, vmName, xml string) error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`
+		INSERT INTO vm_ha (host_id, vm_name, xml, xml_updated_at, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(host_id, vm_name) DO UPDATE SET xml = excluded.xml, xml_updated_at = excluded.xml_updated_at
+	`, hostID, vmName, xml, now, now)
+	return err
+}
+
+// VMHADelete 取消 VM 的高可用标记
+func (s *Store) VMHADelete(hostID, vmName string) error {
+	_, err := s.db.Exec(`DELETE FROM vm_ha WHERE host_id = ? AND vm_name = ?`, hostID, vmName)
+	return err
+}
+
+// VMHAUpdateXML 更新缓存的定义 XML，未标记时返回 sql.ErrNoRows
+func (s *Store) VMHAUpdateXML(hostID, vmName, xml string) error {
+	res, err := s.db.Exec(`UPDATE vm_ha SET xml = ?, xml_updated_at = ? WHERE host_id = ? AND vm_name = ?`,
+		xml, time.Now().Format("2006-01-02 15:04:05"), hostID, vmName)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// VMHASetRunning 记录 VM 最近一次观察到的运行状态
+func (s *Store) VMHASetRunning(hostID, vmName string, running bool) error {
+	_, err := s.db.Exec(`UPDATE vm_ha SET running = ? WHERE host_id = ? AND vm_name = ?`, running, hostID, vmName)
+	return err
+}
+
+// VMHAMove VM 迁移或故障转移到 dstHostID 后更新记录
+// failedFrom 非空表示故障转移并记录原宿主机；为空（普通迁移）时保留已有的故障转移标记
+func (s *Store) VMHAMove(hostID, vmName, dstHostID, failedFrom string) error {
+	if failedFrom == "" {
+		_, err := s.db.Exec(`UPDATE OR REPLACE vm_ha SET host_id = ? WHERE host_id = ? AND vm_name = ?`, dstHostID, hostID, vmName)
+		return err
+	}
+	_, err := s.db.Exec(`
+		UPDATE OR REPLACE vm_ha SET host_id = ?, failed_from = ?, failed_at = ? WHERE host_id = ? AND vm_name = ?
+	`, dstHostID, failedFrom, time.Now().Format("2006-01-02 15:04:05"), hostID, vmName)
+	return err
+}
+
+// VMHAClearFailed 故障宿主机上的旧定义处理完毕后清除故障转移标记
+func (s *Store) VMHAClearFailed(hostID, vmName string) error {
+	_, err := s.db.Exec(`UPDATE vm_ha SET failed_from = '', failed_at = '' WHERE host_id = ? AND vm_name = ?`, hostID, vmName)
+	return err
+}
+
+// VMHARename VM 重命名后同步记录
+func (s *Store) VMHARename(hostID, oldName, newName string) error {
+	_, err := s.db.Exec(`UPDATE OR IGNORE vm_ha SET vm_name = ? WHERE host_id = ? AND vm_name = ?`, newName, hostID, oldName)
+	return err
+}
+
+// VMHADeleteHost 删除宿主机的隔离配置和高可用记录
+func (s *Store) VMHADeleteHost(hostID string) error {
+	if _, err := s.db.Exec(`DELETE FROM vm_ha WHERE host_id = ?`, hostID); err != nil {
+		return err
+	}
+	return s.HostFenceDelete(hostID)
+}
@@ -1,26 +1,83 @@
 package store
 
-import "time"
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 历史数据精度
+const (
+	ResolutionRaw    = "raw" // 原始采样
+	Resolution5m     = "5m"  // 5 分钟汇总（平均值和最大值）
+	ResolutionHourly = "1h"  // 1 小时汇总
+)
+
+// StatsRetention 各精度历史数据的保留时长
+type StatsRetention struct {
+	Raw     time.Duration
+	FiveMin time.Duration
+	Hourly  time.Duration
+}
+
+// DefaultStatsRetention 默认保留：原始数据 1 天，5 分钟汇总 30 天，1 小时汇总 1 年
+var DefaultStatsRetention = StatsRetention{
+	Raw:     24 * time.Hour,
+	FiveMin: 30 * 24 * time.Hour,
+	Hourly:  365 * 24 * time.Hour,
+}
+
+// Resolution 按查询时间范围选择精度：取保留时长能覆盖该范围的最细精度
+func (r StatsRetention) Resolution(span time.Duration) string {
+	switch {
+	case span <= r.Raw:
+		return ResolutionRaw
+	case span <= r.FiveMin:
+		return Resolution5m
+	}
+	return ResolutionHourly
+}
+
+// rollupTier 汇总表
+type rollupTier struct {
+	resolution string
+	step       int    // 区间长度（秒）
+	host, vm   string // 表名
+	disk, net  string // VM 块设备 / 网卡表名
+}
+
+var (
+	tier5m     = rollupTier{Resolution5m, 300, "host_stats_5m", "vm_stats_5m", "vm_disk_stats_5m", "vm_net_stats_5m"}
+	tierHourly = rollupTier{ResolutionHourly, 3600, "host_stats_1h", "vm_stats_1h", "vm_disk_stats_1h", "vm_net_stats_1h"}
+)
 
 // HostStatsRecord 宿主机资源历史记录
+// 汇总精度下 xxxPercent 为区间平均值，xxxMax 为区间最大值，Timestamp 为区间起点；原始数据两者相同
 type HostStatsRecord struct {
-	ID          int     `json:"id"`
+	ID          int     `json:"id"` // 汇总记录为 0
 	HostID      string  `json:"hostId"`
 	CPUPercent  float64 `json:"cpuPercent"`
 	MemPercent  float64 `json:"memPercent"`
 	DiskPercent float64 `json:"diskPercent"`
+	CPUMax      float64 `json:"cpuMax"`
+	MemMax      float64 `json:"memMax"`
+	DiskMax     float64 `json:"diskMax"`
+	Resolution  string  `json:"resolution"` // raw | 5m | 1h
 	Timestamp   string  `json:"timestamp"`
 }
 
-// VMStatsRecord VM 资源历史记录
+// VMStatsRecord VM 资源历史记录，汇总精度下的含义同 HostStatsRecord
 type VMStatsRecord struct {
-	ID         int     `json:"id"`
+	ID         int     `json:"id"` // 汇总记录为 0
 	HostID     string  `json:"hostId"`
 	VMName     string  `json:"vmName"`
 	CPUPercent float64 `json:"cpuPercent"`
 	MemUsed    int64   `json:"memUsed"` // bytes
-	NetRx      int64   `json:"netRx"`   // bytes
-	NetTx      int64   `json:"netTx"`   // bytes
+	CPUMax     float64 `json:"cpuMax"`
+	MemMax     int64   `json:"memMax"`     // bytes
+	NetRx      int64   `json:"netRx"`      // bytes，累计值（汇总精度下为区间末的值）
+	NetTx      int64   `json:"netTx"`      // bytes
+	Resolution string  `json:"resolution"` // raw | 5m | 1h
 	Timestamp  string  `json:"timestamp"`
 }
 
@@ -49,9 +106,51 @@ func (s *Store) migrateHistory() error {
 
 	CREATE INDEX IF NOT EXISTS idx_host_stats_host_time ON host_stats_history(host_id, timestamp);
 	CREATE INDEX IF NOT EXISTS idx_vm_stats_host_vm_time ON vm_stats_history(host_id, vm_name, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_host_stats_time ON host_stats_history(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_vm_stats_time ON vm_stats_history(timestamp);
 	`
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// 汇总表，timestamp 为区间起点 (UTC)，samples 为区间内原始采样数，用于逐级汇总时加权平均
+	for _, t := range []rollupTier{tier5m, tierHourly} {
+		schema := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			host_id      TEXT NOT NULL,
+			timestamp    DATETIME NOT NULL,
+			samples      INTEGER DEFAULT 0,
+			cpu_avg      REAL,
+			cpu_max      REAL,
+			mem_avg      REAL,
+			mem_max      REAL,
+			disk_avg     REAL,
+			disk_max     REAL,
+			PRIMARY KEY (host_id, timestamp)
+		);
+
+		CREATE TABLE IF NOT EXISTS %[2]s (
+			host_id   TEXT NOT NULL,
+			vm_name   TEXT NOT NULL,
+			timestamp DATETIME NOT NULL,
+			samples   INTEGER DEFAULT 0,
+			cpu_avg   REAL,
+			cpu_max   REAL,
+			mem_avg   INTEGER,
+			mem_max   INTEGER,
+			net_rx    INTEGER,
+			net_tx    INTEGER,
+			PRIMARY KEY (host_id, vm_name, timestamp)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_time ON %[1]s(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_%[2]s_time ON %[2]s(timestamp);
+		`, t.host, t.vm)
+		if _, err := s.db.Exec(schema); err != nil {
+			return err
+		}
+	}
+	return s.migrateDeviceHistory()
 }
 
 // HostStatsInsert 插入宿主机资源记录
@@ -72,15 +171,29 @@ func (s *Store) VMStatsInsert(hostID, vmName string, cpu float64, memUsed, netRx
 	return err
 }
 
-// HostStatsHistory 获取宿主机资源历史
-func (s *Store) HostStatsHistory(hostID string, hours int) ([]HostStatsRecord, error) {
-	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour).Format("2006-01-02 15:04:05")
-	rows, err := s.db.Query(`
-		SELECT id, host_id, cpu_percent, mem_percent, disk_percent, timestamp
+// statsCutoff 历史表时间以 UTC 保存（CURRENT_TIMESTAMP），比较时统一转换
+func statsCutoff(d time.Duration) string {
+	return time.Now().UTC().Add(-d).Format("2006-01-02 15:04:05")
+}
+
+// HostStatsHistory 获取宿主机资源历史，resolution 为 raw / 5m / 1h
+func (s *Store) HostStatsHistory(hostID string, hours int, resolution string) ([]HostStatsRecord, error) {
+	cutoff := statsCutoff(time.Duration(hours) * time.Hour)
+	query := `
+		SELECT id, host_id, cpu_percent, mem_percent, disk_percent, cpu_percent, mem_percent, disk_percent, timestamp
 		FROM host_stats_history
 		WHERE host_id = ? AND timestamp > ?
-		ORDER BY timestamp
-	`, hostID, cutoff)
+		ORDER BY timestamp`
+	if t, ok := rollupTierOf(resolution); ok {
+		query = fmt.Sprintf(`
+		SELECT 0, host_id, cpu_avg, mem_avg, disk_avg, cpu_max, mem_max, disk_max, timestamp
+		FROM %s
+		WHERE host_id = ? AND timestamp > ?
+		ORDER BY timestamp`, t.host)
+	} else {
+		resolution = ResolutionRaw
+	}
+	rows, err := s.db.Query(query, hostID, cutoff)
 	if err != nil {
 		return nil, err
 	}
@@ -88,8 +201,9 @@ func (s *Store) HostStatsHistory(hostID string, hours int) ([]HostStatsRecord, e
 
 	var records []HostStatsRecord
 	for rows.Next() {
-		var r HostStatsRecord
-		if err := rows.Scan(&r.ID, &r.HostID, &r.CPUPercent, &r.MemPercent, &r.DiskPercent, &r.Timestamp); err != nil {
+		r := HostStatsRecord{Resolution: resolution}
+		if err := rows.Scan(&r.ID, &r.HostID, &r.CPUPercent, &r.MemPercent, &r.DiskPercent,
+			&r.CPUMax, &r.MemMax, &r.DiskMax, &r.Timestamp); err != nil {
 			return nil, err
 		}
 		records = append(records, r)
@@ -97,15 +211,24 @@ func (s *Store) HostStatsHistory(hostID string, hours int) ([]HostStatsRecord, e
 	return records, nil
 }
 
-// VMStatsHistory 获取 VM 资源历史
-func (s *Store) VMStatsHistory(hostID, vmName string, hours int) ([]VMStatsRecord, error) {
-	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour).Format("2006-01-02 15:04:05")
-	rows, err := s.db.Query(`
-		SELECT id, host_id, vm_name, cpu_percent, mem_used, net_rx, net_tx, timestamp
+// VMStatsHistory 获取 VM 资源历史，resolution 为 raw / 5m / 1h
+func (s *Store) VMStatsHistory(hostID, vmName string, hours int, resolution string) ([]VMStatsRecord, error) {
+	cutoff := statsCutoff(time.Duration(hours) * time.Hour)
+	query := `
+		SELECT id, host_id, vm_name, cpu_percent, mem_used, cpu_percent, mem_used, net_rx, net_tx, timestamp
 		FROM vm_stats_history
 		WHERE host_id = ? AND vm_name = ? AND timestamp > ?
-		ORDER BY timestamp
-	`, hostID, vmName, cutoff)
+		ORDER BY timestamp`
+	if t, ok := rollupTierOf(resolution); ok {
+		query = fmt.Sprintf(`
+		SELECT 0, host_id, vm_name, cpu_avg, mem_avg, cpu_max, mem_max, net_rx, net_tx, timestamp
+		FROM %s
+		WHERE host_id = ? AND vm_name = ? AND timestamp > ?
+		ORDER BY timestamp`, t.vm)
+	} else {
+		resolution = ResolutionRaw
+	}
+	rows, err := s.db.Query(query, hostID, vmName, cutoff)
 	if err != nil {
 		return nil, err
 	}
@@ -113,8 +236,9 @@ func (s *Store) VMStatsHistory(hostID, vmName string, hours int) ([]VMStatsRecor
 
 	var records []VMStatsRecord
 	for rows.Next() {
-		var r VMStatsRecord
-		if err := rows.Scan(&r.ID, &r.HostID, &r.VMName, &r.CPUPercent, &r.MemUsed, &r.NetRx, &r.NetTx, &r.Timestamp); err != nil {
+		r := VMStatsRecord{Resolution: resolution}
+		if err := rows.Scan(&r.ID, &r.HostID, &r.VMName, &r.CPUPercent, &r.MemUsed, &r.CPUMax, &r.MemMax,
+			&r.NetRx, &r.NetTx, &r.Timestamp); err != nil {
 			return nil, err
 		}
 		records = append(records, r)
@@ -122,10 +246,102 @@ func (s *Store) VMStatsHistory(hostID, vmName string, hours int) ([]VMStatsRecor
 	return records, nil
 }
 
-// StatsCleanup 清理超过指定小时的历史数据
-func (s *Store) StatsCleanup(hours int) error {
-	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour).Format("2006-01-02 15:04:05")
-	s.db.Exec(`DELETE FROM host_stats_history WHERE timestamp < ?`, cutoff)
-	s.db.Exec(`DELETE FROM vm_stats_history WHERE timestamp < ?`, cutoff)
+func rollupTierOf(resolution string) (rollupTier, bool) {
+	switch resolution {
+	case Resolution5m:
+		return tier5m, true
+	case ResolutionHourly:
+		return tierHourly, true
+	}
+	return rollupTier{}, false
+}
+
+// StatsRollup 将已结束的区间汇总到 5 分钟和 1 小时表
+// 从各表已有的最新区间之后开始，只处理完整的区间，可重复调用
+func (s *Store) StatsRollup() error {
+	now := time.Now().UTC().Unix()
+	// 原始数据 -> 5 分钟
+	if err := s.rollup(tier5m, now, "host_stats_history", `
+		INSERT OR REPLACE INTO host_stats_5m (host_id, timestamp, samples, cpu_avg, cpu_max, mem_avg, mem_max, disk_avg, disk_max)
+		SELECT host_id, %[1]s, COUNT(*), AVG(cpu_percent), MAX(cpu_percent), AVG(mem_percent), MAX(mem_percent), AVG(disk_percent), MAX(disk_percent)
+		FROM host_stats_history
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, %[1]s`, `
+		INSERT OR REPLACE INTO vm_stats_5m (host_id, vm_name, timestamp, samples, cpu_avg, cpu_max, mem_avg, mem_max, net_rx, net_tx)
+		SELECT host_id, vm_name, %[1]s, COUNT(*), AVG(cpu_percent), MAX(cpu_percent), CAST(AVG(mem_used) AS INTEGER), MAX(mem_used), MAX(net_rx), MAX(net_tx)
+		FROM vm_stats_history
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, vm_name, %[1]s`, diskRollup5m, netRollup5m); err != nil {
+		return err
+	}
+	// 5 分钟 -> 1 小时（按采样数加权平均）
+	return s.rollup(tierHourly, now, "host_stats_5m", `
+		INSERT OR REPLACE INTO host_stats_1h (host_id, timestamp, samples, cpu_avg, cpu_max, mem_avg, mem_max, disk_avg, disk_max)
+		SELECT host_id, %[1]s, SUM(samples), SUM(cpu_avg * samples) / SUM(samples), MAX(cpu_max),
+			SUM(mem_avg * samples) / SUM(samples), MAX(mem_max), SUM(disk_avg * samples) / SUM(samples), MAX(disk_max)
+		FROM host_stats_5m
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, %[1]s`, `
+		INSERT OR REPLACE INTO vm_stats_1h (host_id, vm_name, timestamp, samples, cpu_avg, cpu_max, mem_avg, mem_max, net_rx, net_tx)
+		SELECT host_id, vm_name, %[1]s, SUM(samples), SUM(cpu_avg * samples) / SUM(samples), MAX(cpu_max),
+			CAST(SUM(mem_avg * samples) / SUM(samples) AS INTEGER), MAX(mem_max), MAX(net_rx), MAX(net_tx)
+		FROM vm_stats_5m
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY host_id, vm_name, %[1]s`, diskRollup1h, netRollup1h)
+}
+
+// rollup 汇总 [上次汇总的下一区间, 当前区间起点) 内的数据，source 为宿主机源表，
+// stmts 依次写入宿主机、VM 及设备汇总表，其中 %[1]s 为区间起点表达式
+func (s *Store) rollup(t rollupTier, now int64, source string, stmts ...string) error {
+	const layout = "2006-01-02 15:04:05"
+	end := now / int64(t.step) * int64(t.step)
+
+	// 起点：汇总表最新区间之后；尚无汇总时取源表最早数据
+	var from int64
+	var last, first sql.NullString
+	s.db.QueryRow(fmt.Sprintf(`SELECT MAX(strftime('%%s', timestamp)) FROM %s`, t.host)).Scan(&last)
+	if last.Valid {
+		fmt.Sscan(last.String, &from)
+		from += int64(t.step)
+	} else {
+		s.db.QueryRow(fmt.Sprintf(`SELECT MIN(strftime('%%s', timestamp)) FROM %s`, source)).Scan(&first)
+		if !first.Valid {
+			return nil
+		}
+		fmt.Sscan(first.String, &from)
+		from = from / int64(t.step) * int64(t.step)
+	}
+	if from >= end {
+		return nil
+	}
+
+	bucket := fmt.Sprintf(`datetime(CAST(strftime('%%s', timestamp) AS INTEGER) / %d * %d, 'unixepoch')`, t.step, t.step)
+	fromTS := time.Unix(from, 0).UTC().Format(layout)
+	endTS := time.Unix(end, 0).UTC().Format(layout)
+	for i, stmt := range stmts {
+		if _, err := s.db.Exec(fmt.Sprintf(stmt, bucket), fromTS, endTS); err != nil {
+			return fmt.Errorf("rollup %s (%d): %w", t.resolution, i, err)
+		}
+	}
+	return nil
+}
+
+// StatsCleanup 按各精度的保留时长清理历史数据
+func (s *Store) StatsCleanup(r StatsRetention) error {
+	for _, c := range []struct {
+		keep   time.Duration
+		tables []string
+	}{
+		{r.Raw, []string{"host_stats_history", "vm_stats_history", "vm_disk_stats_history", "vm_net_stats_history"}},
+		{r.FiveMin, []string{tier5m.host, tier5m.vm, tier5m.disk, tier5m.net}},
+		{r.Hourly, []string{tierHourly.host, tierHourly.vm, tierHourly.disk, tierHourly.net}},
+	} {
+		cutoff := statsCutoff(c.keep)
+		for _, table := range c.tables {
+			if _, err := s.db.Exec(`DELETE FROM `+table+` WHERE timestamp < ?`, cutoff); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,103 @@
+package store
+
+// HostInventoryChange 宿主机清单变更记录
+type HostInventoryChange struct {
+	ID        int64  `json:"id"`
+	HostID    string `json:"hostId"`
+	Field     string `json:"field"` // 如 kernel、qemu、fs./var/lib/libvirt、nic.eth0
+	OldValue  string `json:"oldValue"`
+	NewValue  string `json:"newValue"`
+	ChangedAt string `json:"changedAt"`
+}
+
+// migrateInventory 创建宿主机清单缓存和变更记录表
+func (s *Store) migrateInventory() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS host_inventory (
+		host_id      TEXT PRIMARY KEY,
+		data         TEXT NOT NULL, -- JSON
+		collected_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS host_inventory_changes (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		host_id    TEXT NOT NULL,
+		field      TEXT NOT NULL,
+		old_value  TEXT DEFAULT '',
+		new_value  TEXT DEFAULT '',
+		changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_host_inventory_changes_host ON host_inventory_changes(host_id, id);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// HostInventoryGet 获取缓存的宿主机清单 JSON，未采集过时返回 sql.ErrNoRows
+func (s *Store) HostInventoryGet(hostID string) (string, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM host_inventory WHERE host_id = ?`, hostID).Scan(&data)
+	return data, err
+}
+
+// HostInventorySave 保存宿主机清单并记录变更
+func (s *Store) HostInventorySave(hostID, data string, changes []HostInventoryChange) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO host_inventory (host_id, data, collected_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(host_id) DO UPDATE SET data = excluded.data, collected_at = excluded.collected_at
+	`, hostID, data); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if _, err := tx.Exec(`
+			INSERT INTO host_inventory_changes (host_id, field, old_value, new_value) VALUES (?, ?, ?, ?)
+		`, hostID, c.Field, c.OldValue, c.NewValue); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// HostInventoryChanges 获取宿主机清单变更记录，最新的在前
+func (s *Store) HostInventoryChanges(hostID string, limit int) ([]HostInventoryChange, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.Query(`
+		SELECT id, host_id, field, old_value, new_value, changed_at
+		FROM host_inventory_changes
+		WHERE host_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, hostID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []HostInventoryChange
+	for rows.Next() {
+		var c HostInventoryChange
+		if err := rows.Scan(&c.ID, &c.HostID, &c.Field, &c.OldValue, &c.NewValue, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// HostInventoryDelete 删除宿主机清单及变更记录
+func (s *Store) HostInventoryDelete(hostID string) error {
+	if _, err := s.db.Exec(`DELETE FROM host_inventory WHERE host_id = ?`, hostID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM host_inventory_changes WHERE host_id = ?`, hostID)
+	return err
+}
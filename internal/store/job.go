@@ -0,0 +1,145 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job 后台任务记录
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"` // image.import | vm.migrate | vm.clone | ...
+	HostID     string          `json:"hostId"`
+	VMName     string          `json:"vmName"`
+	State      string          `json:"state"` // pending | running | succeeded | failed | canceled | interrupted
+	Progress   int             `json:"progress"`
+	Current    int64           `json:"current"` // 按数据量计的进度（如已传输字节数），无此类进度时为 0
+	Total      int64           `json:"total"`   // 数据总量，未知时为 0
+	Message    string          `json:"message"`
+	Error      string          `json:"error"`
+	Logs       []string        `json:"logs"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	CreatedAt  string          `json:"createdAt"`
+	UpdatedAt  string          `json:"updatedAt"`
+	FinishedAt string          `json:"finishedAt"`
+}
+
+// migrateJobs 创建任务表
+func (s *Store) migrateJobs() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS jobs (
+		id          TEXT PRIMARY KEY,
+		type        TEXT NOT NULL,
+		host_id     TEXT DEFAULT '',
+		vm_name     TEXT DEFAULT '',
+		state       TEXT NOT NULL,
+		progress    INTEGER DEFAULT 0,
+		current     INTEGER DEFAULT 0,
+		total       INTEGER DEFAULT 0,
+		message     TEXT DEFAULT '',
+		error       TEXT DEFAULT '',
+		logs        TEXT DEFAULT '[]',
+		result      TEXT DEFAULT '',
+		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at TEXT DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_state ON jobs(state, created_at);
+	CREATE INDEX IF NOT EXISTS idx_jobs_host ON jobs(host_id, created_at);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// JobSave 插入或更新任务
+func (s *Store) JobSave(j *Job) error {
+	logs, _ := json.Marshal(j.Logs)
+	_, err := s.db.Exec(`
+		INSERT INTO jobs (id, type, host_id, vm_name, state, progress, current, total, message, error, logs, result, created_at, updated_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			state=excluded.state, progress=excluded.progress, current=excluded.current, total=excluded.total,
+			message=excluded.message, error=excluded.error,
+			logs=excluded.logs, result=excluded.result, updated_at=excluded.updated_at, finished_at=excluded.finished_at
+	`, j.ID, j.Type, j.HostID, j.VMName, j.State, j.Progress, j.Current, j.Total, j.Message, j.Error,
+		string(logs), string(j.Result), j.CreatedAt, j.UpdatedAt, j.FinishedAt)
+	return err
+}
+
+// JobGet 获取单个任务
+func (s *Store) JobGet(id string) (*Job, error) {
+	row := s.db.QueryRow(`
+		SELECT id, type, host_id, vm_name, state, progress, current, total, message, error, logs, result, created_at, updated_at, finished_at
+		FROM jobs WHERE id = ?
+	`, id)
+	return scanJob(row)
+}
+
+// JobList 获取任务列表，hostID/state 为空则不过滤
+func (s *Store) JobList(hostID, state string, limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.Query(`
+		SELECT id, type, host_id, vm_name, state, progress, current, total, message, error, logs, result, created_at, updated_at, finished_at
+		FROM jobs
+		WHERE (? = '' OR host_id = ?) AND (? = '' OR state = ?)
+		ORDER BY created_at DESC
+		LIMIT ?
+	`, hostID, hostID, state, state, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *j)
+	}
+	return list, nil
+}
+
+// JobMarkInterrupted 将上次运行遗留的未完成任务标记为中断
+func (s *Store) JobMarkInterrupted() (int, error) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	result, err := s.db.Exec(`
+		UPDATE jobs SET state='interrupted', error='process restarted', updated_at=?, finished_at=?
+		WHERE state IN ('pending', 'running')
+	`, now, now)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// JobCleanup 清理超过指定天数的已结束任务
+func (s *Store) JobCleanup(days int) error {
+	cutoff := time.Now().AddDate(0, 0, -days).Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`DELETE FROM jobs WHERE finished_at != '' AND finished_at < ?`, cutoff)
+	return err
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var j Job
+	var logs, result string
+	if err := row.Scan(&j.ID, &j.Type, &j.HostID, &j.VMName, &j.State, &j.Progress, &j.Current, &j.Total, &j.Message, &j.Error,
+		&logs, &result, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(logs), &j.Logs)
+	if result != "" {
+		j.Result = json.RawMessage(result)
+	}
+	return &j, nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 维护状态
+const (
+	MaintenanceEvacuating = "evacuating" // 正在迁出 VM
+	MaintenanceActive     = "active"     // 迁出结束，处于维护中
+)
+
+// 迁出记录状态
+const (
+	MoveStatePlanned  = "planned"
+	MoveStateMoved    = "moved"
+	MoveStateFailed   = "failed"
+	MoveStateReturned = "returned"
+)
+
+// Maintenance 宿主机维护状态，处于维护中的宿主机不接受新 VM
+type Maintenance struct {
+	HostID    string `json:"hostId"`
+	State     string `json:"state"` // evacuating | active
+	Reason    string `json:"reason"`
+	JobID     string `json:"jobId"` // 最近一次迁出 / 迁回任务
+	CreatedBy string `json:"createdBy"`
+	StartedAt string `json:"startedAt"`
+}
+
+// MaintenanceMove 维护期间的 VM 迁出记录，用于维护结束后迁回
+type MaintenanceMove struct {
+	ID         int64  `json:"id"`
+	HostID     string `json:"hostId"` // 原宿主机
+	VMName     string `json:"vmName"`
+	DstHostID  string `json:"dstHostId"`
+	Method     string `json:"method"` // live | offline
+	WasRunning bool   `json:"wasRunning"`
+	State      string `json:"state"` // planned | moved | failed | returned
+	Error      string `json:"error"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+// migrateMaintenance 创建维护状态与迁出记录表
+func (s *Store) migrateMaintenance() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS host_maintenance (
+		host_id    TEXT PRIMARY KEY,
+		state      TEXT NOT NULL,
+		reason     TEXT DEFAULT '',
+		job_id     TEXT DEFAULT '',
+		created_by TEXT DEFAULT '',
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS maintenance_moves (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		host_id     TEXT NOT NULL,
+		vm_name     TEXT NOT NULL,
+		dst_host_id TEXT DEFAULT '',
+		method      TEXT DEFAULT '',
+		was_running INTEGER DEFAULT 0,
+		state       TEXT NOT NULL,
+		error       TEXT DEFAULT '',
+		updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_maintenance_moves_host ON maintenance_moves(host_id);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// MaintenanceGet 获取宿主机维护状态，不在维护中时返回 sql.ErrNoRows
+func (s *Store) MaintenanceGet(hostID string) (*Maintenance, error) {
+	var m Maintenance
+	err := s.db.QueryRow(`
+		SELECT host_id, state, reason, job_id, created_by, started_at FROM host_maintenance WHERE host_id = ?
+	`, hostID).Scan(&m.HostID, &m.State, &m.Reason, &m.JobID, &m.CreatedBy, &m.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// MaintenanceList 获取所有处于维护中的宿主机
+func (s *Store) MaintenanceList() ([]Maintenance, error) {
+	rows, err := s.db.Query(`SELECT host_id, state, reason, job_id, created_by, started_at FROM host_maintenance ORDER BY started_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Maintenance
+	for rows.Next() {
+		var m Maintenance
+		if err := rows.Scan(&m.HostID, &m.State, &m.Reason, &m.JobID, &m.CreatedBy, &m.StartedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+// InMaintenance 宿主机是否处于维护中
+func (s *Store) InMaintenance(hostID string) bool {
+	_, err := s.MaintenanceGet(hostID)
+	return err == nil
+}
+
+// MaintenanceStart 标记宿主机进入维护并清除上次的迁出记录
+func (s *Store) MaintenanceStart(m *Maintenance) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m.StartedAt = time.Now().Format("2006-01-02 15:04:05")
+	if _, err := tx.Exec(`
+		INSERT INTO host_maintenance (host_id, state, reason, job_id, created_by, started_at) VALUES (?, ?, ?, ?, ?, ?)
+	`, m.HostID, m.State, m.Reason, m.JobID, m.CreatedBy, m.StartedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM maintenance_moves WHERE host_id = ?`, m.HostID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MaintenanceUpdate 更新维护状态和关联任务
+func (s *Store) MaintenanceUpdate(hostID, state, jobID string) error {
+	res, err := s.db.Exec(`UPDATE host_maintenance SET state = ?, job_id = ? WHERE host_id = ?`, state, jobID, hostID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MaintenanceEnd 结束维护（保留迁出记录以便迁回）
+func (s *Store) MaintenanceEnd(hostID string) error {
+	_, err := s.db.Exec(`DELETE FROM host_maintenance WHERE host_id = ?`, hostID)
+	return err
+}
+
+// MaintenanceMoveList 获取宿主机的迁出记录
+func (s *Store) MaintenanceMoveList(hostID string) ([]MaintenanceMove, error) {
+	rows, err := s.db.Query(`
+		SELECT id, host_id, vm_name, dst_host_id, method, was_running, state, error, updated_at
+		FROM maintenance_moves WHERE host_id = ? ORDER BY id
+	`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []MaintenanceMove
+	for rows.Next() {
+		var m MaintenanceMove
+		if err := rows.Scan(&m.ID, &m.HostID, &m.VMName, &m.DstHostID, &m.Method, &m.WasRunning,
+			&m.State, &m.Error, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+// MaintenanceMoveSave 新增（ID 为 0）或更新迁出记录
+func (s *Store) MaintenanceMoveSave(m *MaintenanceMove) error {
+	m.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	if m.ID == 0 {
+		res, err := s.db.Exec(`
+			INSERT INTO maintenance_moves (host_id, vm_name, dst_host_id, method, was_running, state, error, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, m.HostID, m.VMName, m.DstHostID, m.Method, m.WasRunning, m.State, m.Error, m.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		m.ID, _ = res.LastInsertId()
+		return nil
+	}
+	_, err := s.db.Exec(`
+		UPDATE maintenance_moves SET dst_host_id = ?, method = ?, was_running = ?, state = ?, error = ?, updated_at = ?
+		WHERE id = ?
+	`, m.DstHostID, m.Method, m.WasRunning, m.State, m.Error, m.UpdatedAt, m.ID)
+	return err
+}
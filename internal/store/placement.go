@@ -0,0 +1,211 @@
+package store
+
+import "time"
+
+// HostOvercommit 宿主机超分比，0 表示使用默认值
+type HostOvercommit struct {
+	HostID    string  `json:"hostId"`
+	CPURatio  float64 `json:"cpuRatio"` // 已分配 vCPU / 逻辑 CPU 的上限
+	MemRatio  float64 `json:"memRatio"` // 已分配内存 / 物理内存的上限
+	UpdatedAt string  `json:"updatedAt"`
+}
+
+// PlacementGroup 放置组，组内 VM 按策略放在同一宿主机（affinity）或不同宿主机（anti-affinity）
+type PlacementGroup struct {
+	Name      string   `json:"name"`
+	Policy    string   `json:"policy"`  // affinity | anti-affinity
+	Members   []string `json:"members"` // VM 名称，所在宿主机以调度时的实际位置为准
+	CreatedAt string   `json:"createdAt"`
+}
+
+// migratePlacement 创建超分比和放置组表
+func (s *Store) migratePlacement() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS host_overcommit (
+		host_id    TEXT PRIMARY KEY,
+		cpu_ratio  REAL DEFAULT 0,
+		mem_ratio  REAL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS placement_groups (
+		name       TEXT PRIMARY KEY,
+		policy     TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS placement_group_members (
+		group_name TEXT NOT NULL,
+		vm_name    TEXT NOT NULL,
+		PRIMARY KEY (group_name, vm_name)
+	);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// HostOvercommitList 获取所有设置了超分比的宿主机
+func (s *Store) HostOvercommitList() ([]HostOvercommit, error) {
+	rows, err := s.db.Query(`SELECT host_id, cpu_ratio, mem_ratio, updated_at FROM host_overcommit`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []HostOvercommit
+	for rows.Next() {
+		var o HostOvercommit
+		if err := rows.Scan(&o.HostID, &o.CPURatio, &o.MemRatio, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+// HostOvercommitGet 获取宿主机超分比，未设置时返回 sql.ErrNoRows
+func (s *Store) HostOvercommitGet(hostID string) (*HostOvercommit, error) {
+	var o HostOvercommit
+	err := s.db.QueryRow(`
+		SELECT host_id, cpu_ratio, mem_ratio, updated_at FROM host_overcommit WHERE host_id = ?
+	`, hostID).Scan(&o.HostID, &o.CPURatio, &o.MemRatio, &o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// HostOvercommitSet 设置宿主机超分比，两项均为 0 时删除记录
+func (s *Store) HostOvercommitSet(o *HostOvercommit) error {
+	if o.CPURatio == 0 && o.MemRatio == 0 {
+		return s.HostOvercommitDelete(o.HostID)
+	}
+	o.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`
+		INSERT INTO host_overcommit (host_id, cpu_ratio, mem_ratio, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(host_id) DO UPDATE SET cpu_ratio = excluded.cpu_ratio, mem_ratio = excluded.mem_ratio, updated_at = excluded.updated_at
+	`, o.HostID, o.CPURatio, o.MemRatio, o.UpdatedAt)
+	return err
+}
+
+// HostOvercommitDelete 删除宿主机超分比（恢复默认值）
+func (s *Store) HostOvercommitDelete(hostID string) error {
+	_, err := s.db.Exec(`DELETE FROM host_overcommit WHERE host_id = ?`, hostID)
+	return err
+}
+
+// PlacementGroupList 获取所有放置组及成员
+func (s *Store) PlacementGroupList() ([]PlacementGroup, error) {
+	rows, err := s.db.Query(`SELECT name, policy, created_at FROM placement_groups ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var list []PlacementGroup
+	for rows.Next() {
+		var g PlacementGroup
+		if err := rows.Scan(&g.Name, &g.Policy, &g.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, g)
+	}
+	rows.Close()
+
+	for i := range list {
+		members, err := s.placementGroupMembers(list[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Members = members
+	}
+	return list, nil
+}
+
+// PlacementGroupGet 获取放置组及成员，不存在时返回 sql.ErrNoRows
+func (s *Store) PlacementGroupGet(name string) (*PlacementGroup, error) {
+	var g PlacementGroup
+	err := s.db.QueryRow(`SELECT name, policy, created_at FROM placement_groups WHERE name = ?`, name).
+		Scan(&g.Name, &g.Policy, &g.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if g.Members, err = s.placementGroupMembers(name); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s *Store) placementGroupMembers(name string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT vm_name FROM placement_group_members WHERE group_name = ? ORDER BY vm_name`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []string{}
+	for rows.Next() {
+		var vm string
+		if err := rows.Scan(&vm); err != nil {
+			return nil, err
+		}
+		members = append(members, vm)
+	}
+	return members, nil
+}
+
+// PlacementGroupsOf 获取 VM 所在的放置组名称
+func (s *Store) PlacementGroupsOf(vmName string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT group_name FROM placement_group_members WHERE vm_name = ? ORDER BY group_name`, vmName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// PlacementGroupAdd 新建放置组
+func (s *Store) PlacementGroupAdd(g *PlacementGroup) error {
+	g.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`INSERT INTO placement_groups (name, policy, created_at) VALUES (?, ?, ?)`, g.Name, g.Policy, g.CreatedAt)
+	return err
+}
+
+// PlacementGroupDelete 删除放置组及成员
+func (s *Store) PlacementGroupDelete(name string) error {
+	if _, err := s.db.Exec(`DELETE FROM placement_group_members WHERE group_name = ?`, name); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM placement_groups WHERE name = ?`, name)
+	return err
+}
+
+// PlacementGroupAddVM 将 VM 加入放置组（已在组内时忽略）
+func (s *Store) PlacementGroupAddVM(name, vmName string) error {
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO placement_group_members (group_name, vm_name) VALUES (?, ?)`, name, vmName)
+	return err
+}
+
+// PlacementGroupRemoveVM 将 VM 移出放置组，name 为空时移出所有放置组
+func (s *Store) PlacementGroupRemoveVM(name, vmName string) error {
+	if name == "" {
+		_, err := s.db.Exec(`DELETE FROM placement_group_members WHERE vm_name = ?`, vmName)
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM placement_group_members WHERE group_name = ? AND vm_name = ?`, name, vmName)
+	return err
+}
+
+// PlacementGroupRenameVM VM 重命名后同步放置组成员
+func (s *Store) PlacementGroupRenameVM(oldName, newName string) error {
+	_, err := s.db.Exec(`UPDATE OR IGNORE placement_group_members SET vm_name = ? WHERE vm_name = ?`, newName, oldName)
+	return err
+}
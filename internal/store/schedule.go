@@ -0,0 +1,275 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 定时任务执行对象
+const (
+	ScheduleTargetVM   = "vm"   // 对每台匹配的 VM 执行一次
+	ScheduleTargetHost = "host" // 对每台匹配的宿主机执行一次
+	ScheduleTargetNone = "none" // 只执行一次，参数原样传入
+)
+
+// 错过执行（服务停止期间到期）的处理方式
+const (
+	ScheduleMissedSkip = "skip" // 跳过并记录
+	ScheduleMissedRun  = "run"  // 恢复后补执行一次
+)
+
+// 定时任务执行结果
+const (
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+	ScheduleRunPartial   = "partial" // 部分对象执行失败
+	ScheduleRunSkipped   = "skipped" // 错过执行或上一次仍在执行
+)
+
+// scheduleRunKeep 每个定时任务保留的执行记录条数
+const scheduleRunKeep = 200
+
+// Schedule 定时任务：按 cron 表达式对选中的 VM 或宿主机执行一个 API action
+type Schedule struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	Cron          string          `json:"cron"`          // 5 段 cron 表达式或 @daily 等
+	Timezone      string          `json:"timezone"`      // IANA 时区，如 Asia/Shanghai，空为服务端本地时区
+	JitterSeconds int             `json:"jitterSeconds"` // 每次触发随机推迟 0 ~ jitterSeconds 秒（不超过执行间隔的一半），避免同时触发
+	Action        string          `json:"action"`        // API action，如 vm.shutdown、snapshot.create
+	Params        json.RawMessage `json:"params"`        // action 参数（JSON 对象），hostId/vmName 由执行对象填入
+	Target        string          `json:"target"`        // vm | host | none
+	HostID        string          `json:"hostId"`        // 仅选中该宿主机，空为全部
+	HostTags      string          `json:"hostTags"`      // 逗号分隔，仅选中带这些标签的宿主机，空为不限
+	VMPattern     string          `json:"vmPattern"`     // 逗号分隔的 VM 名称通配符，target 为 vm 时必填，* 为全部
+	Missed        string          `json:"missed"`        // skip | run
+	Enabled       bool            `json:"enabled"`
+	NextRunAt     string          `json:"nextRunAt"` // RFC 3339 带 UTC 偏移，已含随机推迟
+	LastRunAt     string          `json:"lastRunAt"`
+	LastState     string          `json:"lastState"`
+	CreatedBy     string          `json:"createdBy"`
+	CreatedAt     string          `json:"createdAt"`
+	UpdatedAt     string          `json:"updatedAt"`
+}
+
+// ScheduleRun 定时任务的一次执行记录
+type ScheduleRun struct {
+	ID           int64                  `json:"id"`
+	ScheduleID   string                 `json:"scheduleId"`
+	ScheduledFor string                 `json:"scheduledFor"` // 计划执行时间，手动执行时为空
+	StartedAt    string                 `json:"startedAt"`
+	FinishedAt   string                 `json:"finishedAt"`
+	State        string                 `json:"state"`  // succeeded | failed | partial | skipped
+	Missed       int                    `json:"missed"` // 本次之前错过的执行次数
+	Message      string                 `json:"message"`
+	Results      []ScheduleTargetResult `json:"results"`
+}
+
+// ScheduleTargetResult 对单个执行对象的结果
+type ScheduleTargetResult struct {
+	HostID string          `json:"hostId"`
+	VMName string          `json:"vmName"`
+	Error  string          `json:"error"`
+	Result json.RawMessage `json:"result,omitempty"` // action 的返回值，过大时省略
+}
+
+// migrateSchedules 创建定时任务与执行记录表
+func (s *Store) migrateSchedules() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS schedules (
+		id             TEXT PRIMARY KEY,
+		name           TEXT NOT NULL,
+		cron           TEXT NOT NULL,
+		timezone       TEXT DEFAULT '',
+		jitter_seconds INTEGER DEFAULT 0,
+		action         TEXT NOT NULL,
+		params         TEXT DEFAULT '{}',
+		target         TEXT DEFAULT 'vm',
+		host_id        TEXT DEFAULT '',
+		host_tags      TEXT DEFAULT '',
+		vm_pattern     TEXT DEFAULT '',
+		missed         TEXT DEFAULT 'skip',
+		enabled        INTEGER DEFAULT 1,
+		next_run_at    TEXT DEFAULT '',
+		last_run_at    TEXT DEFAULT '',
+		last_state     TEXT DEFAULT '',
+		created_by     TEXT DEFAULT '',
+		created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at     DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS schedule_runs (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		schedule_id   TEXT NOT NULL,
+		scheduled_for TEXT DEFAULT '',
+		started_at    TEXT NOT NULL,
+		finished_at   TEXT DEFAULT '',
+		state         TEXT NOT NULL,
+		missed        INTEGER DEFAULT 0,
+		message       TEXT DEFAULT '',
+		results       TEXT DEFAULT '[]'
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_schedule_runs ON schedule_runs(schedule_id, id);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// === 定时任务 ===
+
+const scheduleColumns = `id, name, cron, timezone, jitter_seconds, action, params, target, host_id, host_tags, vm_pattern, missed, enabled, next_run_at, last_run_at, last_state, created_by, created_at, updated_at`
+
+func scanSchedule(row rowScanner) (*Schedule, error) {
+	var sc Schedule
+	var params string
+	if err := row.Scan(&sc.ID, &sc.Name, &sc.Cron, &sc.Timezone, &sc.JitterSeconds, &sc.Action, &params, &sc.Target,
+		&sc.HostID, &sc.HostTags, &sc.VMPattern, &sc.Missed, &sc.Enabled, &sc.NextRunAt, &sc.LastRunAt, &sc.LastState,
+		&sc.CreatedBy, &sc.CreatedAt, &sc.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if params == "" {
+		params = "{}"
+	}
+	sc.Params = json.RawMessage(params)
+	return &sc, nil
+}
+
+// scheduleParams 参数为空时保存为空对象
+func scheduleParams(params json.RawMessage) string {
+	if len(params) == 0 || string(params) == "null" {
+		return "{}"
+	}
+	return string(params)
+}
+
+// ScheduleList 获取全部定时任务
+func (s *Store) ScheduleList() ([]Schedule, error) {
+	rows, err := s.db.Query(`SELECT ` + scheduleColumns + ` FROM schedules ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Schedule
+	for rows.Next() {
+		sc, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *sc)
+	}
+	return list, nil
+}
+
+// ScheduleGet 获取单个定时任务
+func (s *Store) ScheduleGet(id string) (*Schedule, error) {
+	return scanSchedule(s.db.QueryRow(`SELECT `+scheduleColumns+` FROM schedules WHERE id = ?`, id))
+}
+
+// ScheduleAdd 添加定时任务
+func (s *Store) ScheduleAdd(sc *Schedule) error {
+	if sc.ID == "" {
+		sc.ID = uuid.New().String()
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	sc.CreatedAt, sc.UpdatedAt = now, now
+	_, err := s.db.Exec(`
+		INSERT INTO schedules (`+scheduleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, sc.ID, sc.Name, sc.Cron, sc.Timezone, sc.JitterSeconds, sc.Action, scheduleParams(sc.Params), sc.Target,
+		sc.HostID, sc.HostTags, sc.VMPattern, sc.Missed, sc.Enabled, sc.NextRunAt, sc.LastRunAt, sc.LastState,
+		sc.CreatedBy, now, now)
+	return err
+}
+
+// ScheduleUpdate 更新定时任务的配置和下次执行时间，不修改最近执行状态
+func (s *Store) ScheduleUpdate(sc *Schedule) error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	sc.UpdatedAt = now
+	_, err := s.db.Exec(`
+		UPDATE schedules SET name=?, cron=?, timezone=?, jitter_seconds=?, action=?, params=?, target=?, host_id=?,
+			host_tags=?, vm_pattern=?, missed=?, enabled=?, next_run_at=?, updated_at=?
+		WHERE id=?
+	`, sc.Name, sc.Cron, sc.Timezone, sc.JitterSeconds, sc.Action, scheduleParams(sc.Params), sc.Target, sc.HostID,
+		sc.HostTags, sc.VMPattern, sc.Missed, sc.Enabled, sc.NextRunAt, now, sc.ID)
+	return err
+}
+
+// ScheduleSetNextRun 更新下次执行时间
+func (s *Store) ScheduleSetNextRun(id, nextRunAt string) error {
+	_, err := s.db.Exec(`UPDATE schedules SET next_run_at=? WHERE id=?`, nextRunAt, id)
+	return err
+}
+
+// ScheduleDelete 删除定时任务及其执行记录
+func (s *Store) ScheduleDelete(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM schedule_runs WHERE schedule_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM schedules WHERE id = ?`, id)
+	return err
+}
+
+// === 执行记录 ===
+
+const scheduleRunColumns = `id, schedule_id, scheduled_for, started_at, finished_at, state, missed, message, results`
+
+// ScheduleRunInsert 记录一次执行，同时更新定时任务的最近执行状态，并清理超出保留条数的旧记录
+func (s *Store) ScheduleRunInsert(r *ScheduleRun) error {
+	results, _ := json.Marshal(r.Results)
+	if r.Results == nil {
+		results = []byte("[]")
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO schedule_runs (schedule_id, scheduled_for, started_at, finished_at, state, missed, message, results)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ScheduleID, r.ScheduledFor, r.StartedAt, r.FinishedAt, r.State, r.Missed, r.Message, string(results))
+	if err != nil {
+		return err
+	}
+	r.ID, _ = result.LastInsertId()
+
+	if _, err := s.db.Exec(`UPDATE schedules SET last_run_at=?, last_state=? WHERE id=?`, r.StartedAt, r.State, r.ScheduleID); err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		DELETE FROM schedule_runs WHERE schedule_id = ? AND id <= (
+			SELECT id FROM schedule_runs WHERE schedule_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+		)
+	`, r.ScheduleID, r.ScheduleID, scheduleRunKeep)
+	return err
+}
+
+// ScheduleRunList 获取定时任务的执行记录，最新的在前
+func (s *Store) ScheduleRunList(scheduleID string, limit int) ([]ScheduleRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT `+scheduleRunColumns+`
+		FROM schedule_runs
+		WHERE schedule_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ScheduleRun
+	for rows.Next() {
+		var r ScheduleRun
+		var results string
+		if err := rows.Scan(&r.ID, &r.ScheduleID, &r.ScheduledFor, &r.StartedAt, &r.FinishedAt, &r.State,
+			&r.Missed, &r.Message, &results); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(results), &r.Results)
+		list = append(list, r)
+	}
+	return list, nil
+}
@@ -0,0 +1,161 @@
+package store
+
+import "time"
+
+// SnapshotPolicy VM 自动快照的保留策略，只清理 VMCat 自动创建的快照
+type SnapshotPolicy struct {
+	HostID      string `json:"hostId"`
+	VMName      string `json:"vmName"`
+	KeepLast    int    `json:"keepLast"`    // 保留最新的 N 个
+	KeepDaily   int    `json:"keepDaily"`   // 保留最近 N 天每天最新的一个
+	KeepWeekly  int    `json:"keepWeekly"`  // 保留最近 N 周每周最新的一个
+	KeepMonthly int    `json:"keepMonthly"` // 保留最近 N 个月每月最新的一个
+	LastPruned  string `json:"lastPruned"`  // 最近一次清理的时间
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// migrateSnapshotPolicies 创建快照保留策略表和自动快照记录表
+func (s *Store) migrateSnapshotPolicies() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS snapshot_policies (
+		host_id      TEXT NOT NULL,
+		vm_name      TEXT NOT NULL,
+		keep_last    INTEGER DEFAULT 0,
+		keep_daily   INTEGER DEFAULT 0,
+		keep_weekly  INTEGER DEFAULT 0,
+		keep_monthly INTEGER DEFAULT 0,
+		last_pruned  TEXT DEFAULT '',
+		updated_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (host_id, vm_name)
+	);
+	CREATE TABLE IF NOT EXISTS auto_snapshots (
+		host_id    TEXT NOT NULL,
+		vm_name    TEXT NOT NULL,
+		name       TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (host_id, vm_name, name)
+	);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+const snapshotPolicyColumns = `host_id, vm_name, keep_last, keep_daily, keep_weekly, keep_monthly, last_pruned, updated_at`
+
+func scanSnapshotPolicy(row rowScanner) (*SnapshotPolicy, error) {
+	var p SnapshotPolicy
+	if err := row.Scan(&p.HostID, &p.VMName, &p.KeepLast, &p.KeepDaily, &p.KeepWeekly, &p.KeepMonthly,
+		&p.LastPruned, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SnapshotPolicyList 获取快照保留策略，hostID 为空时返回全部
+func (s *Store) SnapshotPolicyList(hostID string) ([]SnapshotPolicy, error) {
+	rows, err := s.db.Query(`
+		SELECT `+snapshotPolicyColumns+` FROM snapshot_policies
+		WHERE (? = '' OR host_id = ?)
+		ORDER BY host_id, vm_name
+	`, hostID, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []SnapshotPolicy
+	for rows.Next() {
+		p, err := scanSnapshotPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *p)
+	}
+	return list, nil
+}
+
+// SnapshotPolicyGet 获取 VM 的快照保留策略，未设置时返回 sql.ErrNoRows
+func (s *Store) SnapshotPolicyGet(hostID, vmName string) (*SnapshotPolicy, error) {
+	return scanSnapshotPolicy(s.db.QueryRow(`SELECT `+snapshotPolicyColumns+` FROM snapshot_policies WHERE host_id = ? AND vm_name = ?`, hostID, vmName))
+}
+
+// SnapshotPolicySet 保存 VM 的快照保留策略
+func (s *Store) SnapshotPolicySet(p *SnapshotPolicy) error {
+	p.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`
+		INSERT INTO snapshot_policies (host_id, vm_name, keep_last, keep_daily, keep_weekly, keep_monthly, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(host_id, vm_name) DO UPDATE SET keep_last = excluded.keep_last, keep_daily = excluded.keep_daily,
+			keep_weekly = excluded.keep_weekly, keep_monthly = excluded.keep_monthly, updated_at = excluded.updated_at
+	`, p.HostID, p.VMName, p.KeepLast, p.KeepDaily, p.KeepWeekly, p.KeepMonthly, p.UpdatedAt)
+	return err
+}
+
+// SnapshotPolicyPruned 记录最近一次清理的时间
+func (s *Store) SnapshotPolicyPruned(hostID, vmName string) error {
+	_, err := s.db.Exec(`UPDATE snapshot_policies SET last_pruned = ? WHERE host_id = ? AND vm_name = ?`,
+		time.Now().Format("2006-01-02 15:04:05"), hostID, vmName)
+	return err
+}
+
+// SnapshotPolicyDelete 删除 VM 的快照保留策略
+func (s *Store) SnapshotPolicyDelete(hostID, vmName string) error {
+	_, err := s.db.Exec(`DELETE FROM snapshot_policies WHERE host_id = ? AND vm_name = ?`, hostID, vmName)
+	return err
+}
+
+// SnapshotPolicyDeleteHost 删除宿主机上全部 VM 的快照保留策略
+func (s *Store) SnapshotPolicyDeleteHost(hostID string) error {
+	_, err := s.db.Exec(`DELETE FROM snapshot_policies WHERE host_id = ?`, hostID)
+	return err
+}
+
+// SnapshotPolicyRename VM 重命名后更新快照保留策略
+func (s *Store) SnapshotPolicyRename(hostID, oldName, newName string) error {
+	_, err := s.db.Exec(`UPDATE OR IGNORE snapshot_policies SET vm_name = ? WHERE host_id = ? AND vm_name = ?`, newName, hostID, oldName)
+	return err
+}
+
+// AutoSnapshotAdd 记录 VMCat 自动创建的快照，保留策略只清理有记录的快照
+func (s *Store) AutoSnapshotAdd(hostID, vmName, name string) error {
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO auto_snapshots (host_id, vm_name, name, created_at) VALUES (?, ?, ?, ?)`,
+		hostID, vmName, name, time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// AutoSnapshotNames 获取 VM 的自动快照名称
+func (s *Store) AutoSnapshotNames(hostID, vmName string) (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT name FROM auto_snapshots WHERE host_id = ? AND vm_name = ?`, hostID, vmName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
+
+// AutoSnapshotDelete 删除自动快照记录（快照已删除）
+func (s *Store) AutoSnapshotDelete(hostID, vmName, name string) error {
+	_, err := s.db.Exec(`DELETE FROM auto_snapshots WHERE host_id = ? AND vm_name = ? AND name = ?`, hostID, vmName, name)
+	return err
+}
+
+// AutoSnapshotDeleteVM 删除 VM 的全部自动快照记录，vmName 为空时删除宿主机上的全部记录
+func (s *Store) AutoSnapshotDeleteVM(hostID, vmName string) error {
+	_, err := s.db.Exec(`DELETE FROM auto_snapshots WHERE host_id = ? AND (? = '' OR vm_name = ?)`, hostID, vmName, vmName)
+	return err
+}
+
+// AutoSnapshotRename VM 重命名后更新自动快照记录
+func (s *Store) AutoSnapshotRename(hostID, oldName, newName string) error {
+	_, err := s.db.Exec(`UPDATE OR IGNORE auto_snapshots SET vm_name = ? WHERE host_id = ? AND vm_name = ?`, newName, hostID, oldName)
+	return err
+}
@@ -89,6 +89,9 @@ func (s *Store) migrate() error {
 	// 兼容旧库: 添加 tags 列（已存在则忽略）
 	s.db.Exec(`ALTER TABLE hosts ADD COLUMN tags TEXT DEFAULT ''`)
 
+	// 兼容旧库: 添加 backend 列（已存在则忽略）
+	s.db.Exec(`ALTER TABLE hosts ADD COLUMN backend TEXT DEFAULT 'virsh'`)
+
 	// 资源历史统计表
 	if err := s.migrateHistory(); err != nil {
 		return err
@@ -99,5 +102,50 @@ func (s *Store) migrate() error {
 		return err
 	}
 
+	// 后台任务表
+	if err := s.migrateJobs(); err != nil {
+		return err
+	}
+
+	// 用户与 API 令牌表
+	if err := s.migrateUsers(); err != nil {
+		return err
+	}
+
+	// 告警规则、通知渠道与告警记录表
+	if err := s.migrateAlerts(); err != nil {
+		return err
+	}
+
+	// 宿主机清单缓存与变更记录表
+	if err := s.migrateInventory(); err != nil {
+		return err
+	}
+
+	// 宿主机维护状态与迁出记录表
+	if err := s.migrateMaintenance(); err != nil {
+		return err
+	}
+
+	// 超分比与放置组表
+	if err := s.migratePlacement(); err != nil {
+		return err
+	}
+
+	// 隔离配置与高可用 VM 表
+	if err := s.migrateHA(); err != nil {
+		return err
+	}
+
+	// 定时任务与执行记录表
+	if err := s.migrateSchedules(); err != nil {
+		return err
+	}
+
+	// 快照保留策略与自动快照记录表
+	if err := s.migrateSnapshotPolicies(); err != nil {
+		return err
+	}
+
 	return nil
 }
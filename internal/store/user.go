@@ -0,0 +1,268 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User 服务端模式的 API 用户
+type User struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`      // viewer | operator | admin
+	HostTags  string `json:"hostTags"`  // 逗号分隔，仅可访问带这些标签的宿主机，空为不限
+	VMPattern string `json:"vmPattern"` // 逗号分隔的 VM 名称通配符 (如 web-*)，空为不限
+	Disabled  bool   `json:"disabled"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// APIToken API 令牌，数据库仅保存 SHA-256 哈希
+type APIToken struct {
+	ID         string `json:"id"`
+	UserID     string `json:"userId"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`    // 令牌前若干位，便于识别
+	ExpiresAt  string `json:"expiresAt"` // 空为永不过期
+	LastUsedAt string `json:"lastUsedAt"`
+	Revoked    bool   `json:"revoked"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+const (
+	tokenPrefix    = "vmcat_"
+	tokenPrefixLen = 14 // 展示用前缀长度（含 vmcat_）
+)
+
+// migrateUsers 创建用户和令牌表
+func (s *Store) migrateUsers() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS users (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL UNIQUE,
+		role       TEXT NOT NULL DEFAULT 'viewer',
+		host_tags  TEXT DEFAULT '',
+		vm_pattern TEXT DEFAULT '',
+		disabled   INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		name         TEXT DEFAULT '',
+		token_hash   TEXT NOT NULL UNIQUE,
+		prefix       TEXT DEFAULT '',
+		expires_at   TEXT DEFAULT '',
+		last_used_at TEXT DEFAULT '',
+		revoked      INTEGER DEFAULT 0,
+		created_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tokens_user ON api_tokens(user_id);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// UserList 获取所有用户
+func (s *Store) UserList() ([]User, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, role, host_tags, vm_pattern, disabled, created_at, updated_at
+		FROM users ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Role, &u.HostTags, &u.VMPattern, &u.Disabled, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// UserGet 获取单个用户
+func (s *Store) UserGet(id string) (*User, error) {
+	var u User
+	err := s.db.QueryRow(`
+		SELECT id, name, role, host_tags, vm_pattern, disabled, created_at, updated_at
+		FROM users WHERE id = ?
+	`, id).Scan(&u.ID, &u.Name, &u.Role, &u.HostTags, &u.VMPattern, &u.Disabled, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UserGetByName 按用户名获取用户
+func (s *Store) UserGetByName(name string) (*User, error) {
+	var u User
+	err := s.db.QueryRow(`
+		SELECT id, name, role, host_tags, vm_pattern, disabled, created_at, updated_at
+		FROM users WHERE name = ?
+	`, name).Scan(&u.ID, &u.Name, &u.Role, &u.HostTags, &u.VMPattern, &u.Disabled, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UserCount 获取用户数量
+func (s *Store) UserCount() (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+// UserAdd 添加用户
+func (s *Store) UserAdd(u *User) error {
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	u.CreatedAt, u.UpdatedAt = now, now
+	_, err := s.db.Exec(`
+		INSERT INTO users (id, name, role, host_tags, vm_pattern, disabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, u.ID, u.Name, u.Role, u.HostTags, u.VMPattern, u.Disabled, now, now)
+	return err
+}
+
+// UserUpdate 更新用户角色与访问范围
+func (s *Store) UserUpdate(u *User) error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	_, err := s.db.Exec(`
+		UPDATE users SET name=?, role=?, host_tags=?, vm_pattern=?, disabled=?, updated_at=?
+		WHERE id=?
+	`, u.Name, u.Role, u.HostTags, u.VMPattern, u.Disabled, now, u.ID)
+	return err
+}
+
+// UserDelete 删除用户及其全部令牌
+func (s *Store) UserDelete(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM api_tokens WHERE user_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	return err
+}
+
+// TokenCreate 为用户生成新令牌，明文仅在此处返回一次
+func (s *Store) TokenCreate(userID, name, expiresAt string) (string, *APIToken, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	plain := tokenPrefix + hex.EncodeToString(b)
+
+	t := &APIToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:tokenPrefixLen],
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO api_tokens (id, user_id, name, token_hash, prefix, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.UserID, t.Name, hashToken(plain), t.Prefix, t.ExpiresAt, t.CreatedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, t, nil
+}
+
+// TokenList 获取用户的令牌列表（userID 为空返回全部）
+func (s *Store) TokenList(userID string) ([]APIToken, error) {
+	query := `
+		SELECT id, user_id, name, prefix, expires_at, last_used_at, revoked, created_at
+		FROM api_tokens`
+	var args []interface{}
+	if userID != "" {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var t APIToken
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.ExpiresAt, &t.LastUsedAt, &t.Revoked, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// TokenRevoke 吊销令牌
+func (s *Store) TokenRevoke(id string) error {
+	res, err := s.db.Exec(`UPDATE api_tokens SET revoked = 1 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("token %s not found", id)
+	}
+	return nil
+}
+
+// TokenAuthenticate 校验令牌明文，返回所属用户并记录最近使用时间
+// 令牌不存在、已吊销、已过期或用户被禁用时返回错误
+func (s *Store) TokenAuthenticate(plain string) (*User, error) {
+	var tokenID, userID, expiresAt string
+	var revoked bool
+	err := s.db.QueryRow(`
+		SELECT id, user_id, expires_at, revoked FROM api_tokens WHERE token_hash = ?
+	`, hashToken(plain)).Scan(&tokenID, &userID, &expiresAt, &revoked)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, fmt.Errorf("token revoked")
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if expiresAt != "" && expiresAt < now {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	u, err := s.UserGet(userID)
+	if err != nil {
+		return nil, fmt.Errorf("token owner not found")
+	}
+	if u.Disabled {
+		return nil, fmt.Errorf("user %s disabled", u.Name)
+	}
+
+	s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now, tokenID)
+	return u, nil
+}
+
+// hashToken 令牌哈希（令牌为高熵随机串，无需加盐）
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,45 @@
+package tray
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// windowsNotifyScript 通过托盘气泡显示通知，标题和内容经环境变量传入以免转义
+const windowsNotifyScript = `Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(10000, $env:VMCAT_NOTIFY_TITLE, $env:VMCAT_NOTIFY_MESSAGE, 'Info')
+Start-Sleep -Seconds 10
+$n.Dispose()`
+
+// Notify 显示系统桌面通知（Linux: notify-send，macOS: osascript，Windows: PowerShell）
+func Notify(ctx context.Context, title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd":
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=VMCat", title, message)
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleQuote(message), appleQuote(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsNotifyScript)
+		cmd.Env = append(os.Environ(), "VMCAT_NOTIFY_TITLE="+title, "VMCAT_NOTIFY_MESSAGE="+message)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", cmd.Args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// appleQuote AppleScript 字符串字面量
+func appleQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,216 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	internalssh "vmcat/internal/ssh"
+)
+
+// VM 管理后端类型（按宿主机配置）
+const (
+	BackendVirsh   = "virsh"   // 通过 SSH 执行 virsh 并解析输出（默认）
+	BackendLibvirt = "libvirt" // 通过 SSH 转发 libvirt-sock，使用 libvirt 远程协议
+)
+
+// ValidBackend 检查后端类型是否合法，空值视为 virsh
+func ValidBackend(kind string) bool {
+	return kind == "" || kind == BackendVirsh || kind == BackendLibvirt
+}
+
+// ErrNotFound 虚拟机不存在（virsh 报 failed to get domain，libvirt 返回 VIR_ERR_NO_DOMAIN），用 errors.Is 判断
+var ErrNotFound = errors.New("domain not found")
+
+// notFoundError 虚拟机不存在错误，消息保持不变
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string        { return e.err.Error() }
+func (e *notFoundError) Unwrap() error        { return e.err }
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// virshErr virsh 输出表明域不存在时将 err 标记为 ErrNotFound
+func virshErr(output string, err error) error {
+	if err != nil && strings.Contains(output, "failed to get domain '") {
+		return &notFoundError{err: err}
+	}
+	return err
+}
+
+// Backend VM 管理后端
+// 两种实现返回相同的 VM / VMDetail / StoragePool 结果，HostID 由 Manager 填充
+// 未列出的操作（快照、磁盘、网络等）仍统一通过 virsh 执行
+type Backend interface {
+	List(ctx context.Context) ([]VM, error)
+	Get(ctx context.Context, vmName string) (*VMDetail, error)
+	GetXML(ctx context.Context, vmName string) (string, error)
+	DefineXML(ctx context.Context, xmlContent string) error
+	Start(ctx context.Context, vmName string) error
+	Shutdown(ctx context.Context, vmName string) error
+	Destroy(ctx context.Context, vmName string) error
+	Reboot(ctx context.Context, vmName string) error
+	Suspend(ctx context.Context, vmName string) error
+	Resume(ctx context.Context, vmName string) error
+	SetAutostart(ctx context.Context, vmName string, enabled bool) error
+	PoolList(ctx context.Context) ([]StoragePool, error)
+}
+
+// sortVMs 按 virsh list --all 的顺序排列: 运行中按 ID，其余按名称（不区分大小写）
+func sortVMs(vms []VM) {
+	sort.SliceStable(vms, func(i, j int) bool {
+		x, y := vms[i], vms[j]
+		if x.ID > 0 && y.ID > 0 {
+			return x.ID < y.ID
+		}
+		if x.ID > 0 || y.ID > 0 {
+			return x.ID > 0
+		}
+		return strings.ToLower(x.Name) < strings.ToLower(y.Name)
+	})
+}
+
+// virshBackend 通过 SSH 执行 virsh 命令
+type virshBackend struct {
+	client *internalssh.Client
+}
+
+func (b *virshBackend) List(ctx context.Context) ([]VM, error) {
+	output, err := b.client.Run(ctx, internalssh.OpQuery, "virsh list --all")
+	if err != nil {
+		return nil, fmt.Errorf("virsh list: %w", err)
+	}
+
+	vms := parseVMList(output)
+
+	// 补充 CPU 和内存信息
+	for i, vm := range vms {
+		xmlOutput, err := b.client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vm.Name)))
+		if err != nil {
+			continue
+		}
+		domain, err := parseDumpXML(xmlOutput)
+		if err != nil {
+			continue
+		}
+		vms[i].CPUs = domain.VCPU
+		vms[i].MemoryMB = domain.Memory.MB()
+	}
+
+	return vms, nil
+}
+
+func (b *virshBackend) Get(ctx context.Context, vmName string) (*VMDetail, error) {
+	q := internalssh.ShellQuote(vmName)
+
+	// 获取 XML 配置
+	xmlOutput, err := b.client.Run(ctx, internalssh.OpQuery, "virsh dumpxml "+q)
+	if err != nil {
+		return nil, virshErr(xmlOutput, fmt.Errorf("virsh dumpxml: %w", err))
+	}
+
+	domain, err := parseDumpXML(xmlOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := domainToDetail(domain, "")
+
+	// 获取运行状态
+	infoOutput, err := b.client.Run(ctx, internalssh.OpQuery, "virsh dominfo "+q)
+	if err == nil {
+		info := parseDominfo(infoOutput)
+		detail.State = info["State"]
+		if info["Autostart"] == "enable" {
+			detail.Autostart = true
+		}
+	}
+
+	// 获取 IP 地址
+	ifOutput, err := b.client.Run(ctx, internalssh.OpQuery, "virsh domifaddr "+q)
+	if err == nil {
+		ips := parseDomifaddr(ifOutput)
+		for i, nic := range detail.NICs {
+			mac := strings.ToLower(nic.MAC)
+			if ip, ok := ips[mac]; ok {
+				detail.NICs[i].IP = ip
+			}
+		}
+	}
+
+	return detail, nil
+}
+
+func (b *virshBackend) GetXML(ctx context.Context, vmName string) (string, error) {
+	output, err := b.client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vmName)))
+	if err != nil {
+		return "", virshErr(output, fmt.Errorf("virsh dumpxml: %w", err))
+	}
+	return output, nil
+}
+
+func (b *virshBackend) DefineXML(ctx context.Context, xmlContent string) error {
+	// 通过 stdin 传递 XML（ShellQuote 安全转义）
+	cmd := fmt.Sprintf("echo %s | virsh define /dev/stdin", internalssh.ShellQuote(xmlContent))
+	output, err := b.client.Run(ctx, internalssh.OpConfig, cmd)
+	if err != nil {
+		return fmt.Errorf("virsh define: %s", output)
+	}
+	return nil
+}
+
+func (b *virshBackend) Start(ctx context.Context, vmName string) error {
+	return b.power(ctx, "start", vmName)
+}
+
+func (b *virshBackend) Shutdown(ctx context.Context, vmName string) error {
+	return b.power(ctx, "shutdown", vmName)
+}
+
+func (b *virshBackend) Destroy(ctx context.Context, vmName string) error {
+	return b.power(ctx, "destroy", vmName)
+}
+
+func (b *virshBackend) Reboot(ctx context.Context, vmName string) error {
+	return b.power(ctx, "reboot", vmName)
+}
+
+func (b *virshBackend) Suspend(ctx context.Context, vmName string) error {
+	return b.power(ctx, "suspend", vmName)
+}
+
+func (b *virshBackend) Resume(ctx context.Context, vmName string) error {
+	return b.power(ctx, "resume", vmName)
+}
+
+// power 执行 virsh 电源操作子命令
+func (b *virshBackend) power(ctx context.Context, verb, vmName string) error {
+	output, err := b.client.Run(ctx, internalssh.OpPower, fmt.Sprintf("virsh %s %s", verb, internalssh.ShellQuote(vmName)))
+	if err != nil {
+		return virshErr(output, fmt.Errorf("virsh %s: %s", verb, output))
+	}
+	return nil
+}
+
+func (b *virshBackend) SetAutostart(ctx context.Context, vmName string, enabled bool) error {
+	flag := "--autostart"
+	if !enabled {
+		flag = "--autostart --disable"
+	}
+	output, err := b.client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh autostart %s %s", flag, internalssh.ShellQuote(vmName)))
+	if err != nil {
+		return virshErr(output, fmt.Errorf("virsh autostart: %s", output))
+	}
+	return nil
+}
+
+func (b *virshBackend) PoolList(ctx context.Context) ([]StoragePool, error) {
+	output, err := b.client.Run(ctx, internalssh.OpQuery, "virsh pool-list --all --details")
+	if err != nil {
+		return nil, fmt.Errorf("pool-list: %w", err)
+	}
+	return parsePoolList(output), nil
+}
@@ -0,0 +1,335 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vmcat/internal/libvirt"
+	internalssh "vmcat/internal/ssh"
+)
+
+const (
+	libvirtSocket = "/var/run/libvirt/libvirt-sock"
+	libvirtURI    = "qemu:///system"
+)
+
+// libvirtBackend 通过 SSH 转发的 libvirt-sock 使用远程协议管理 VM
+// 结果与 virsh 输出解析一致；调用超时或取消只结束该次调用，传输出错时连接失效，下次调用时重建
+type libvirtBackend struct {
+	client   *internalssh.Client
+	timeouts *internalssh.Timeouts
+	conn     *libvirt.Conn
+}
+
+// dialLibvirt 通过 SSH 连接宿主机 libvirtd
+func dialLibvirt(ctx context.Context, client *internalssh.Client, timeouts *internalssh.Timeouts) (*libvirtBackend, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeouts.Get(internalssh.OpQuery))
+	defer cancel()
+
+	nc, err := client.Dial("unix", libvirtSocket)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", libvirtSocket, err)
+	}
+	conn, err := libvirt.Open(ctx, nc, libvirtURI)
+	if err != nil {
+		return nil, err
+	}
+	return &libvirtBackend{client: client, timeouts: timeouts, conn: conn}, nil
+}
+
+func (b *libvirtBackend) alive() bool {
+	return b.conn.Alive()
+}
+
+func (b *libvirtBackend) close() {
+	b.conn.Close()
+}
+
+func (b *libvirtBackend) withTimeout(ctx context.Context, op internalssh.Op) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, b.timeouts.Get(op))
+}
+
+func (b *libvirtBackend) List(ctx context.Context) ([]VM, error) {
+	ctx, cancel := b.withTimeout(ctx, internalssh.OpQuery)
+	defer cancel()
+
+	doms, err := b.conn.ListAllDomains(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list domains: %w", err)
+	}
+
+	vms := make([]VM, 0, len(doms))
+	for _, dom := range doms {
+		info, err := b.conn.DomainGetInfo(ctx, dom)
+		if err != nil {
+			// 列出后被删除的域直接跳过
+			continue
+		}
+		vm := VM{
+			Name:  dom.Name,
+			State: domainStateName(info.State),
+		}
+		if dom.ID > 0 {
+			vm.ID = int(dom.ID)
+		}
+		if xmlDesc, err := b.conn.DomainGetXMLDesc(ctx, dom, 0); err == nil {
+			if domain, err := parseDumpXML(xmlDesc); err == nil {
+				vm.CPUs = domain.VCPU
+				vm.MemoryMB = domain.Memory.MB()
+			}
+		}
+		vms = append(vms, vm)
+	}
+
+	sortVMs(vms)
+	return vms, nil
+}
+
+func (b *libvirtBackend) Get(ctx context.Context, vmName string) (*VMDetail, error) {
+	ctx, cancel := b.withTimeout(ctx, internalssh.OpQuery)
+	defer cancel()
+
+	dom, err := b.conn.DomainLookupByName(ctx, vmName)
+	if err != nil {
+		return nil, lookupErr(fmt.Errorf("lookup %s: %w", vmName, err))
+	}
+	xmlDesc, err := b.conn.DomainGetXMLDesc(ctx, dom, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get xml: %w", err)
+	}
+	domain, err := parseDumpXML(xmlDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := domainToDetail(domain, "")
+
+	if info, err := b.conn.DomainGetInfo(ctx, dom); err == nil {
+		detail.State = domainStateName(info.State)
+	}
+	if autostart, err := b.conn.DomainGetAutostart(ctx, dom); err == nil {
+		detail.Autostart = autostart
+	}
+
+	// 与 virsh domifaddr 一致，取 DHCP 租约中每个 MAC 的首个地址
+	if ifaces, err := b.conn.DomainInterfaceAddresses(ctx, dom, 0); err == nil {
+		ips := make(map[string]string)
+		for _, iface := range ifaces {
+			mac := strings.ToLower(iface.HWAddr)
+			if _, ok := ips[mac]; ok || len(iface.Addrs) == 0 {
+				continue
+			}
+			ips[mac] = iface.Addrs[0].Addr
+		}
+		for i, nic := range detail.NICs {
+			if ip, ok := ips[strings.ToLower(nic.MAC)]; ok {
+				detail.NICs[i].IP = ip
+			}
+		}
+	}
+
+	return detail, nil
+}
+
+func (b *libvirtBackend) GetXML(ctx context.Context, vmName string) (string, error) {
+	ctx, cancel := b.withTimeout(ctx, internalssh.OpQuery)
+	defer cancel()
+
+	dom, err := b.conn.DomainLookupByName(ctx, vmName)
+	if err != nil {
+		return "", lookupErr(fmt.Errorf("lookup %s: %w", vmName, err))
+	}
+	xmlDesc, err := b.conn.DomainGetXMLDesc(ctx, dom, 0)
+	if err != nil {
+		return "", fmt.Errorf("get xml: %w", err)
+	}
+	return xmlDesc, nil
+}
+
+func (b *libvirtBackend) DefineXML(ctx context.Context, xmlContent string) error {
+	ctx, cancel := b.withTimeout(ctx, internalssh.OpConfig)
+	defer cancel()
+
+	if _, err := b.conn.DomainDefineXML(ctx, xmlContent); err != nil {
+		return fmt.Errorf("define: %w", err)
+	}
+	return nil
+}
+
+func (b *libvirtBackend) Start(ctx context.Context, vmName string) error {
+	return b.domainOp(ctx, internalssh.OpPower, "start", vmName, b.conn.DomainCreate)
+}
+
+func (b *libvirtBackend) Shutdown(ctx context.Context, vmName string) error {
+	return b.domainOp(ctx, internalssh.OpPower, "shutdown", vmName, b.conn.DomainShutdown)
+}
+
+func (b *libvirtBackend) Destroy(ctx context.Context, vmName string) error {
+	return b.domainOp(ctx, internalssh.OpPower, "destroy", vmName, b.conn.DomainDestroy)
+}
+
+func (b *libvirtBackend) Reboot(ctx context.Context, vmName string) error {
+	return b.domainOp(ctx, internalssh.OpPower, "reboot", vmName, func(ctx context.Context, dom libvirt.Domain) error {
+		return b.conn.DomainReboot(ctx, dom, 0)
+	})
+}
+
+func (b *libvirtBackend) Suspend(ctx context.Context, vmName string) error {
+	return b.domainOp(ctx, internalssh.OpPower, "suspend", vmName, b.conn.DomainSuspend)
+}
+
+func (b *libvirtBackend) Resume(ctx context.Context, vmName string) error {
+	return b.domainOp(ctx, internalssh.OpPower, "resume", vmName, b.conn.DomainResume)
+}
+
+func (b *libvirtBackend) SetAutostart(ctx context.Context, vmName string, enabled bool) error {
+	return b.domainOp(ctx, internalssh.OpConfig, "autostart", vmName, func(ctx context.Context, dom libvirt.Domain) error {
+		return b.conn.DomainSetAutostart(ctx, dom, enabled)
+	})
+}
+
+// domainOp 按名称查找域后执行操作
+func (b *libvirtBackend) domainOp(ctx context.Context, op internalssh.Op, verb, vmName string, fn func(context.Context, libvirt.Domain) error) error {
+	ctx, cancel := b.withTimeout(ctx, op)
+	defer cancel()
+
+	dom, err := b.conn.DomainLookupByName(ctx, vmName)
+	if err != nil {
+		return lookupErr(fmt.Errorf("%s: %w", verb, err))
+	}
+	if err := fn(ctx, dom); err != nil {
+		return fmt.Errorf("%s: %w", verb, err)
+	}
+	return nil
+}
+
+// lookupErr libvirtd 返回 VIR_ERR_NO_DOMAIN 时将 err 标记为 ErrNotFound
+func lookupErr(err error) error {
+	var lerr *libvirt.Error
+	if errors.As(err, &lerr) && lerr.Code == libvirt.ErrNoDomain {
+		return &notFoundError{err: err}
+	}
+	return err
+}
+
+func (b *libvirtBackend) PoolList(ctx context.Context) ([]StoragePool, error) {
+	ctx, cancel := b.withTimeout(ctx, internalssh.OpQuery)
+	defer cancel()
+
+	pools, err := b.conn.ListAllStoragePools(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("pool-list: %w", err)
+	}
+	// 按标志过滤得到自动启动和持久化集合，避免逐个查询
+	autostart, err := b.poolNames(ctx, libvirt.ListPoolsAutostart)
+	if err != nil {
+		return nil, err
+	}
+	persistent, err := b.poolNames(ctx, libvirt.ListPoolsPersistent)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]StoragePool, 0, len(pools))
+	for _, pool := range pools {
+		info, err := b.conn.StoragePoolGetInfo(ctx, pool)
+		if err != nil {
+			continue
+		}
+		p := StoragePool{
+			Name:       pool.Name,
+			State:      poolStateName(info.State),
+			Autostart:  yesNo(autostart[pool.Name]),
+			Persistent: yesNo(persistent[pool.Name]),
+			Capacity:   "-",
+			Allocation: "-",
+			Available:  "-",
+		}
+		// virsh 仅对活动存储池显示容量
+		if info.State == libvirt.PoolRunning || info.State == libvirt.PoolDegraded {
+			p.Capacity = prettyCapacity(info.Capacity)
+			p.Allocation = prettyCapacity(info.Allocation)
+			p.Available = prettyCapacity(info.Available)
+		}
+		list = append(list, p)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list, nil
+}
+
+func (b *libvirtBackend) poolNames(ctx context.Context, flags uint32) (map[string]bool, error) {
+	pools, err := b.conn.ListAllStoragePools(ctx, flags)
+	if err != nil {
+		return nil, fmt.Errorf("pool-list: %w", err)
+	}
+	names := make(map[string]bool, len(pools))
+	for _, p := range pools {
+		names[p.Name] = true
+	}
+	return names, nil
+}
+
+// domainStateName 域状态转为 virsh 显示的状态名
+func domainStateName(state uint8) string {
+	switch state {
+	case libvirt.DomainRunning:
+		return "running"
+	case libvirt.DomainBlocked:
+		return "idle"
+	case libvirt.DomainPaused:
+		return "paused"
+	case libvirt.DomainShutdown:
+		return "in shutdown"
+	case libvirt.DomainShutoff:
+		return "shut off"
+	case libvirt.DomainCrashed:
+		return "crashed"
+	case libvirt.DomainPMSuspended:
+		return "pmsuspended"
+	default:
+		return "no state"
+	}
+}
+
+// poolStateName 存储池状态转为 virsh 显示的状态名
+func poolStateName(state uint8) string {
+	switch state {
+	case libvirt.PoolInactive:
+		return "inactive"
+	case libvirt.PoolBuilding:
+		return "building"
+	case libvirt.PoolRunning:
+		return "running"
+	case libvirt.PoolDegraded:
+		return "degraded"
+	case libvirt.PoolInaccessible:
+		return "inaccessible"
+	default:
+		return "unknown"
+	}
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+// prettyCapacity 按 virsh 格式显示容量，如 "49.09 GiB"
+func prettyCapacity(n uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", v, units[i])
+}
@@ -0,0 +1,36 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"vmcat/internal/libvirt"
+)
+
+func TestNotFound(t *testing.T) {
+	exit := errors.New("Process exited with status 1")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"virsh missing domain", virshErr("error: failed to get domain 'ghost'", fmt.Errorf("virsh start: %w", exit)), true},
+		{"virsh missing snapshot", virshErr("error: failed to get domain snapshot 'snap1'", exit), false},
+		{"virsh other error", virshErr("error: Requested operation is not valid: domain is already running", exit), false},
+		{"virsh success", virshErr("error: failed to get domain 'ghost'", nil), false},
+		{"libvirt no domain", lookupErr(fmt.Errorf("lookup ghost: %w", &libvirt.Error{Code: libvirt.ErrNoDomain})), true},
+		{"libvirt other error", lookupErr(fmt.Errorf("lookup web: %w", &libvirt.Error{Code: 1})), false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, ErrNotFound); got != tt.want {
+			t.Errorf("%s: errors.Is(%v, ErrNotFound) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+
+	// 消息不变，原始错误仍可取出
+	err := virshErr("error: failed to get domain 'ghost'", fmt.Errorf("virsh start: %w", exit))
+	if err.Error() != "virsh start: Process exited with status 1" || !errors.Is(err, exit) {
+		t.Errorf("err = %v", err)
+	}
+}
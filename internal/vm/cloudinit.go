@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -17,7 +18,7 @@ type CloudInitConfig struct {
 }
 
 // GenerateCloudInitISO 在宿主机上生成 cloud-init seed ISO
-func (m *Manager) GenerateCloudInitISO(hostID string, outputPath string, cfg CloudInitConfig) error {
+func (m *Manager) GenerateCloudInitISO(ctx context.Context, hostID string, outputPath string, cfg CloudInitConfig) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -65,7 +66,7 @@ func (m *Manager) GenerateCloudInitISO(hostID string, outputPath string, cfg Clo
 	}
 
 	for _, cmd := range cmds {
-		if _, err := client.Execute(cmd); err != nil {
+		if _, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
 			return fmt.Errorf("write cloud-init files: %w", err)
 		}
 	}
@@ -79,7 +80,7 @@ func (m *Manager) GenerateCloudInitISO(hostID string, outputPath string, cfg Clo
 		tmpDir, tmpDir,
 	)
 
-	output, err := client.Execute(genCmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, genCmd)
 	if err != nil {
 		return fmt.Errorf("generate cloud-init ISO: %w (output: %s)", err, output)
 	}
@@ -88,7 +89,7 @@ func (m *Manager) GenerateCloudInitISO(hostID string, outputPath string, cfg Clo
 	}
 
 	// 清理临时文件
-	client.Execute(fmt.Sprintf("rm -rf %s", internalssh.ShellQuote(tmpDir)))
+	client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("rm -rf %s", internalssh.ShellQuote(tmpDir)))
 
 	return nil
 }
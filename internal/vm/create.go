@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -9,7 +10,7 @@ import (
 )
 
 // Create 创建虚拟机 (virt-install)
-func (m *Manager) Create(hostID string, params VMCreateParams) error {
+func (m *Manager) Create(ctx context.Context, hostID string, params VMCreateParams) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -24,6 +25,9 @@ func (m *Manager) Create(hostID string, params VMCreateParams) error {
 	if params.MemoryMB <= 0 {
 		params.MemoryMB = 1024
 	}
+	if params.Firmware != "" && params.Firmware != "bios" && params.Firmware != "efi" {
+		return fmt.Errorf("invalid firmware: %s", params.Firmware)
+	}
 
 	cmd := fmt.Sprintf("virt-install --name %s --vcpus %d --memory %d",
 		internalssh.ShellQuote(params.Name), params.CPUs, params.MemoryMB)
@@ -39,11 +43,23 @@ func (m *Manager) Create(hostID string, params VMCreateParams) error {
 		cmd += fmt.Sprintf(" --disk size=%d", params.DiskSizeGB)
 	}
 
-	// 光驱
+	// 光驱与引导
+	var boot []string
+	if params.Firmware == "efi" {
+		boot = append(boot, "uefi")
+	}
 	if params.CDROM != "" {
 		cmd += fmt.Sprintf(" --cdrom %s", internalssh.ShellQuote(params.CDROM))
 	} else {
-		cmd += " --boot hd"
+		boot = append(boot, "hd")
+	}
+	if len(boot) > 0 {
+		cmd += " --boot " + strings.Join(boot, ",")
+	}
+
+	// 机器类型
+	if params.Machine != "" {
+		cmd += fmt.Sprintf(" --machine %s", internalssh.ShellQuote(params.Machine))
 	}
 
 	// 网络
@@ -76,7 +92,7 @@ func (m *Manager) Create(hostID string, params VMCreateParams) error {
 	// 不进入交互式控制台
 	cmd += " --noautoconsole"
 
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpDisk, cmd)
 	if err != nil {
 		return fmt.Errorf("virt-install: %s", output)
 	}
@@ -84,7 +100,7 @@ func (m *Manager) Create(hostID string, params VMCreateParams) error {
 }
 
 // ISOList 列出宿主机上的 ISO 文件
-func (m *Manager) ISOList(hostID string, searchPaths []string) ([]ISOFile, error) {
+func (m *Manager) ISOList(ctx context.Context, hostID string, searchPaths []string) ([]ISOFile, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
@@ -100,7 +116,7 @@ func (m *Manager) ISOList(hostID string, searchPaths []string) ([]ISOFile, error
 	}
 	pathStr := strings.Join(quoted, " ")
 	cmd := fmt.Sprintf("find %s -maxdepth 3 -name '*.iso' -type f -printf '%%s %%p\\n' 2>/dev/null | head -100", pathStr)
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpQuery, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("find iso: %w", err)
 	}
@@ -125,12 +141,12 @@ func (m *Manager) ISOList(hostID string, searchPaths []string) ([]ISOFile, error
 }
 
 // OSVariantList 获取支持的 OS 变体列表
-func (m *Manager) OSVariantList(hostID string) ([]string, error) {
+func (m *Manager) OSVariantList(ctx context.Context, hostID string) ([]string, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
-	output, err := client.Execute("osinfo-query os -f short-id | tail -n +3 | awk '{print $1}' | head -200")
+	output, err := client.Run(ctx, internalssh.OpQuery, "osinfo-query os -f short-id | tail -n +3 | awk '{print $1}' | head -200")
 	if err != nil {
 		// osinfo-query 可能不存在，返回常用列表
 		return defaultOSVariants(), nil
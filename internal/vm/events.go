@@ -0,0 +1,331 @@
+package vm
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VMEvent 虚拟机生命周期事件（推送主题 vm:event）
+type VMEvent struct {
+	HostID string `json:"hostId"`
+	Name   string `json:"name"`
+	Event  string `json:"event"`  // started | stopped | crashed | suspended | resumed | pmsuspended | shutdown | defined | undefined | migrated
+	Detail string `json:"detail"` // libvirt 事件详情，如 Booted / Destroyed / Migrated
+	State  string `json:"state"`  // 事件发生后的状态（与 VM.State 一致），无法确定时为空
+	Time   string `json:"time"`
+}
+
+const (
+	eventCommand    = "virsh event --all --loop --event lifecycle"
+	eventBackoffMin = time.Second
+	eventBackoffMax = time.Minute
+	resyncDelay     = time.Second // 合并连续事件触发的全量刷新
+)
+
+// 兼容新旧 virsh 输出: event 'lifecycle' for domain 'vm1': Started Booted
+var eventLineRe = regexp.MustCompile(`^event 'lifecycle' for domain '?(.+?)'?: (\S+)\s*(.*)$`)
+
+// parseEventLine 解析 virsh event 输出行
+func parseEventLine(line string) (VMEvent, bool) {
+	m := eventLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return VMEvent{}, false
+	}
+	ev := VMEvent{Name: m[1], Detail: strings.TrimSpace(m[3])}
+	switch m[2] {
+	case "Started":
+		ev.Event, ev.State = "started", "running"
+	case "Resumed":
+		ev.Event, ev.State = "resumed", "running"
+	case "Suspended":
+		ev.Event, ev.State = "suspended", "paused"
+	case "PMSuspended":
+		ev.Event, ev.State = "pmsuspended", "pmsuspended"
+	case "Stopped":
+		ev.Event, ev.State = "stopped", "shut off"
+		if ev.Detail == "Crashed" {
+			ev.Event = "crashed"
+		}
+	case "Crashed":
+		ev.Event, ev.State = "crashed", "crashed"
+	case "Shutdown":
+		ev.Event = "shutdown"
+	case "Defined":
+		ev.Event = "defined"
+	case "Undefined":
+		ev.Event = "undefined"
+	default:
+		return VMEvent{}, false
+	}
+	// 迁移入 (Started Migrated) / 迁移出 (Stopped Migrated)
+	if ev.Detail == "Migrated" && (ev.Event == "started" || ev.Event == "stopped") {
+		ev.Event = "migrated"
+	}
+	return ev, true
+}
+
+// === 状态缓存 ===
+
+// stateCache 按宿主机缓存 VM 列表，仅在事件流运行且完成全量同步后作为 List 结果
+type stateCache struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	owner     *eventWatcher
+	streaming bool          // 事件流是否在运行
+	fresh     bool          // 缓存是否已与宿主机同步
+	gen       uint64        // 每次事件递增，用于丢弃过期的全量刷新结果
+	vms       map[string]VM // name -> VM
+}
+
+func newStateCache() *stateCache {
+	return &stateCache{hosts: make(map[string]*hostState)}
+}
+
+// list 返回缓存的 VM 列表，未同步时 ok 为 false
+func (c *stateCache) list(hostID string) ([]VM, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[hostID]
+	if h == nil || !h.streaming || !h.fresh {
+		return nil, false
+	}
+	vms := make([]VM, 0, len(h.vms))
+	for _, vm := range h.vms {
+		vms = append(vms, vm)
+	}
+	sortVMs(vms)
+	return vms, true
+}
+
+// generation 返回当前事件代数，全量刷新前获取
+func (c *stateCache) generation(hostID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h := c.hosts[hostID]; h != nil {
+		return h.gen
+	}
+	return 0
+}
+
+// store 写入全量刷新结果，期间有新事件或事件流未运行时丢弃并返回 false
+func (c *stateCache) store(hostID string, gen uint64, vms []VM) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[hostID]
+	if h == nil || !h.streaming || h.gen != gen {
+		return false
+	}
+	h.vms = make(map[string]VM, len(vms))
+	for _, vm := range vms {
+		h.vms[vm.Name] = vm
+	}
+	h.fresh = true
+	return true
+}
+
+// apply 将事件应用到缓存，结构性变化（定义/删除/迁移）由随后的全量刷新修正
+func (c *stateCache) apply(ev VMEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[ev.HostID]
+	if h == nil || !h.streaming {
+		return
+	}
+	h.gen++
+
+	vm, ok := h.vms[ev.Name]
+	switch {
+	case ev.Event == "defined" && !ok:
+		h.vms[ev.Name] = VM{Name: ev.Name, State: "shut off", HostID: ev.HostID}
+	case ok && ev.State != "":
+		vm.State = ev.State
+		if ev.State == "shut off" {
+			vm.ID = 0
+		}
+		h.vms[ev.Name] = vm
+	}
+}
+
+// setStreaming 标记事件流状态，断开后缓存失效直到重新同步
+// owner 防止已停止的旧订阅覆盖新订阅的状态
+func (c *stateCache) setStreaming(hostID string, owner *eventWatcher, on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[hostID]
+	if h == nil || h.owner != owner {
+		if !on {
+			return
+		}
+		h = &hostState{owner: owner, vms: make(map[string]VM)}
+		c.hosts[hostID] = h
+	}
+	h.streaming = on
+	h.fresh = false
+	h.gen++
+}
+
+func (c *stateCache) isStreaming(hostID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[hostID]
+	return h != nil && h.streaming
+}
+
+// invalidate 使缓存失效（事件不覆盖的配置变更后调用）
+func (c *stateCache) invalidate(hostID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h := c.hosts[hostID]; h != nil {
+		h.fresh = false
+		h.gen++
+	}
+}
+
+func (c *stateCache) drop(hostID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hosts, hostID)
+}
+
+// === 事件订阅 ===
+
+// eventWatcher 单台宿主机的 libvirt 事件订阅，通过常驻 SSH 会话运行 virsh event
+type eventWatcher struct {
+	m      *Manager
+	hostID string
+	cancel context.CancelFunc
+	resync chan struct{}
+}
+
+// Watch 开始订阅宿主机的 VM 生命周期事件，事件流断开后自动重连并重新同步
+func (m *Manager) Watch(hostID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.watchers[hostID]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &eventWatcher{
+		m:      m,
+		hostID: hostID,
+		cancel: cancel,
+		resync: make(chan struct{}, 1),
+	}
+	m.watchers[hostID] = w
+	go w.run(ctx)
+	go w.resyncLoop(ctx)
+}
+
+// Unwatch 停止订阅并清除缓存
+func (m *Manager) Unwatch(hostID string) {
+	m.mu.Lock()
+	w, ok := m.watchers[hostID]
+	delete(m.watchers, hostID)
+	m.mu.Unlock()
+
+	if ok {
+		w.cancel()
+	}
+	m.cache.drop(hostID)
+}
+
+// Invalidate 使宿主机的 VM 列表缓存失效，下次 List 直接查询宿主机
+func (m *Manager) Invalidate(hostID string) {
+	m.cache.invalidate(hostID)
+}
+
+// run 维持事件流，断开后按指数退避重连
+func (w *eventWatcher) run(ctx context.Context) {
+	backoff := eventBackoffMin
+	for {
+		client, err := w.m.pool.Get(w.hostID)
+		if err == nil {
+			start := time.Now()
+			w.m.cache.setStreaming(w.hostID, w, true)
+			w.requestResync()
+			err = client.Stream(ctx, eventCommand, w.handle)
+			w.m.cache.setStreaming(w.hostID, w, false)
+			if ctx.Err() == nil {
+				log.Printf("vm: event stream for host %s ended: %v", w.hostID, err)
+			}
+			if time.Since(start) > eventBackoffMax {
+				backoff = eventBackoffMin
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > eventBackoffMax {
+			backoff = eventBackoffMax
+		}
+	}
+}
+
+// handle 处理一行事件输出：更新缓存并推送 vm:event
+func (w *eventWatcher) handle(line string) {
+	ev, ok := parseEventLine(line)
+	if !ok {
+		return
+	}
+	ev.HostID = w.hostID
+	ev.Time = time.Now().Format("2006-01-02 15:04:05")
+
+	w.m.cache.apply(ev)
+	w.m.emit("vm:event", ev)
+
+	switch ev.Event {
+	case "started", "defined", "undefined", "migrated":
+		// 新的域 ID、CPU/内存等信息需要全量刷新获取
+		w.requestResync()
+	}
+}
+
+func (w *eventWatcher) requestResync() {
+	select {
+	case w.resync <- struct{}{}:
+	default:
+	}
+}
+
+// resyncLoop 合并刷新请求并执行全量同步
+func (w *eventWatcher) resyncLoop(ctx context.Context) {
+	for {
+		select {
+		case <-w.resync:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-time.After(resyncDelay):
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-w.resync:
+		default:
+		}
+
+		_, stored, err := w.m.fetch(ctx, w.hostID)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("vm: resync host %s: %v", w.hostID, err)
+			}
+			continue
+		}
+		// 刷新期间有新事件导致结果被丢弃，稍后重试
+		if !stored && w.m.cache.isStreaming(w.hostID) {
+			w.requestResync()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package vm
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	internalssh "vmcat/internal/ssh"
+)
+
+// fakeReply fakeHost 对一条命令的应答；block 为 true 时命令一直运行到客户端关闭会话
+type fakeReply struct {
+	output string
+	status uint32
+	block  bool
+}
+
+// fakeHost 进程内的 SSH 服务端，按 handle 应答 exec 请求并记录收到的命令
+type fakeHost struct {
+	handle func(cmd string) fakeReply
+
+	mu   sync.Mutex
+	cmds []string
+}
+
+// newFakeHost 启动 fakeHost，并以 hostIDs 连接到同一服务端
+func newFakeHost(t *testing.T, handle func(cmd string) fakeReply, hostIDs ...string) (*internalssh.Pool, *fakeHost) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	cfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	h := &fakeHost{handle: handle}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go h.serve(nc, cfg)
+		}
+	}()
+
+	pool := internalssh.NewPool()
+	t.Cleanup(pool.CloseAll)
+	port := ln.Addr().(*net.TCPAddr).Port
+	for _, id := range hostIDs {
+		if _, err := pool.Connect(id, &internalssh.Config{Host: "127.0.0.1", Port: port, User: "root", AuthType: "password", Password: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return pool, h
+}
+
+func (h *fakeHost) serve(nc net.Conn, cfg *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(nc, cfg)
+	if err != nil {
+		nc.Close()
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+	for nch := range chans {
+		if nch.ChannelType() != "session" {
+			nch.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		ch, chReqs, err := nch.Accept()
+		if err != nil {
+			continue
+		}
+		go h.session(ch, chReqs)
+	}
+}
+
+func (h *fakeHost) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	closed := make(chan struct{})
+	for req := range reqs {
+		if req.Type != "exec" {
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+			continue
+		}
+		var payload struct{ Command string }
+		ssh.Unmarshal(req.Payload, &payload)
+		req.Reply(true, nil)
+
+		h.mu.Lock()
+		h.cmds = append(h.cmds, payload.Command)
+		h.mu.Unlock()
+
+		r := h.handle(payload.Command)
+		go func() {
+			if r.block {
+				// 直到客户端发送 KILL 并关闭会话
+				<-closed
+				return
+			}
+			ch.Write([]byte(r.output))
+			ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{r.status}))
+			ch.Close()
+		}()
+	}
+	close(closed)
+	ch.Close()
+}
+
+// waitFor 等待以 prefix 开头的命令开始执行
+func (h *fakeHost) waitFor(t *testing.T, prefix string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		for _, cmd := range h.cmds {
+			if strings.HasPrefix(cmd, prefix) {
+				h.mu.Unlock()
+				return
+			}
+		}
+		h.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("command %q was never run", prefix)
+}
+
+// ran 是否收到过与 cmd 完全相同的命令
+func (h *fakeHost) ran(cmd string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.cmds {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,14 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 
 	internalssh "vmcat/internal/ssh"
 )
 
 // AttachDisk 添加磁盘
-func (m *Manager) AttachDisk(hostID, vmName string, params DiskAttachParams) error {
+func (m *Manager) AttachDisk(ctx context.Context, hostID, vmName string, params DiskAttachParams) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -25,29 +26,29 @@ func (m *Manager) AttachDisk(hostID, vmName string, params DiskAttachParams) err
 	if params.DevType != "" {
 		cmd += " --type " + internalssh.ShellQuote(params.DevType)
 	}
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 	if err != nil {
-		return fmt.Errorf("attach-disk: %s", output)
+		return virshErr(output, fmt.Errorf("attach-disk: %s", output))
 	}
 	return nil
 }
 
 // DetachDisk 移除磁盘
-func (m *Manager) DetachDisk(hostID, vmName, target string) error {
+func (m *Manager) DetachDisk(ctx context.Context, hostID, vmName, target string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh detach-disk %s %s --persistent",
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh detach-disk %s %s --persistent",
 		internalssh.ShellQuote(vmName), internalssh.ShellQuote(target)))
 	if err != nil {
-		return fmt.Errorf("detach-disk: %s", output)
+		return virshErr(output, fmt.Errorf("detach-disk: %s", output))
 	}
 	return nil
 }
 
 // AttachInterface 添加网卡
-func (m *Manager) AttachInterface(hostID, vmName string, params NICAttachParams) error {
+func (m *Manager) AttachInterface(ctx context.Context, hostID, vmName string, params NICAttachParams) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -63,15 +64,15 @@ func (m *Manager) AttachInterface(hostID, vmName string, params NICAttachParams)
 	cmd := fmt.Sprintf("virsh attach-interface %s %s %s --model %s --persistent",
 		internalssh.ShellQuote(vmName), internalssh.ShellQuote(nicType),
 		internalssh.ShellQuote(params.Source), internalssh.ShellQuote(model))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 	if err != nil {
-		return fmt.Errorf("attach-interface: %s", output)
+		return virshErr(output, fmt.Errorf("attach-interface: %s", output))
 	}
 	return nil
 }
 
 // DetachInterface 移除网卡
-func (m *Manager) DetachInterface(hostID, vmName, macAddr string) error {
+func (m *Manager) DetachInterface(ctx context.Context, hostID, vmName, macAddr string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -80,54 +81,54 @@ func (m *Manager) DetachInterface(hostID, vmName, macAddr string) error {
 	mac := internalssh.ShellQuote(macAddr)
 	// 先尝试 bridge 类型
 	cmd := fmt.Sprintf("virsh detach-interface %s bridge --mac %s --persistent", q, mac)
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 	if err != nil {
 		// 再尝试 network 类型
 		cmd = fmt.Sprintf("virsh detach-interface %s network --mac %s --persistent", q, mac)
-		output, err = client.Execute(cmd)
+		output, err = client.Run(ctx, internalssh.OpConfig, cmd)
 		if err != nil {
-			return fmt.Errorf("detach-interface: %s", output)
+			return virshErr(output, fmt.Errorf("detach-interface: %s", output))
 		}
 	}
 	return nil
 }
 
 // ChangeMedia 挂载光驱 ISO
-func (m *Manager) ChangeMedia(hostID, vmName, target, source string) error {
+func (m *Manager) ChangeMedia(ctx context.Context, hostID, vmName, target, source string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
 	cmd := fmt.Sprintf("virsh change-media %s %s %s --insert",
 		internalssh.ShellQuote(vmName), internalssh.ShellQuote(target), internalssh.ShellQuote(source))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 	if err != nil {
-		return fmt.Errorf("change-media: %s", output)
+		return virshErr(output, fmt.Errorf("change-media: %s", output))
 	}
 	return nil
 }
 
 // EjectMedia 弹出光驱
-func (m *Manager) EjectMedia(hostID, vmName, target string) error {
+func (m *Manager) EjectMedia(ctx context.Context, hostID, vmName, target string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh change-media %s %s --eject",
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh change-media %s %s --eject",
 		internalssh.ShellQuote(vmName), internalssh.ShellQuote(target)))
 	if err != nil {
-		return fmt.Errorf("eject-media: %s", output)
+		return virshErr(output, fmt.Errorf("eject-media: %s", output))
 	}
 	return nil
 }
 
 // ResizeDisk 磁盘扩容 (qemu-img resize)
-func (m *Manager) ResizeDisk(hostID, diskPath string, newSizeGB int) error {
+func (m *Manager) ResizeDisk(ctx context.Context, hostID, diskPath string, newSizeGB int) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("qemu-img resize %s %dG",
+	output, err := client.Run(ctx, internalssh.OpDisk, fmt.Sprintf("qemu-img resize %s %dG",
 		internalssh.ShellQuote(diskPath), newSizeGB))
 	if err != nil {
 		return fmt.Errorf("qemu-img resize: %s", output)
@@ -136,8 +137,8 @@ func (m *Manager) ResizeDisk(hostID, diskPath string, newSizeGB int) error {
 }
 
 // SetGraphics 设置 VNC 显示 (通过编辑 XML)
-func (m *Manager) SetGraphics(hostID, vmName string, enabled bool) error {
-	xmlStr, err := m.GetXML(hostID, vmName)
+func (m *Manager) SetGraphics(ctx context.Context, hostID, vmName string, enabled bool) error {
+	xmlStr, err := m.GetXML(ctx, hostID, vmName)
 	if err != nil {
 		return err
 	}
@@ -168,14 +169,14 @@ func (m *Manager) SetGraphics(hostID, vmName string, enabled bool) error {
 		}
 		cmd := fmt.Sprintf("virt-xml %s --remove-device --graphics type=vnc",
 			internalssh.ShellQuote(vmName))
-		output, err := client.Execute(cmd)
+		output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 		if err != nil {
 			return fmt.Errorf("remove vnc: %s", output)
 		}
 		return nil
 	}
 
-	return m.DefineXML(hostID, xmlStr)
+	return m.DefineXML(ctx, hostID, xmlStr)
 }
 
 // replaceFirst 替换第一个匹配
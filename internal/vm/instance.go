@@ -1,9 +1,12 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
+
+	internalssh "vmcat/internal/ssh"
 )
 
 const defaultInstanceRoot = "/var/lib/libvirt/instances"
@@ -17,7 +20,7 @@ func InstanceDir(instanceRoot string, instanceID int) string {
 }
 
 // InitInstanceDir 在远程宿主机上创建 instance 目录结构
-func (m *Manager) InitInstanceDir(hostID string, instanceRoot string, instanceID int) error {
+func (m *Manager) InitInstanceDir(ctx context.Context, hostID string, instanceRoot string, instanceID int) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -25,7 +28,7 @@ func (m *Manager) InitInstanceDir(hostID string, instanceRoot string, instanceID
 
 	dir := InstanceDir(instanceRoot, instanceID)
 	cmd := fmt.Sprintf("mkdir -p %s/iso", dir)
-	if _, err := client.Execute(cmd); err != nil {
+	if _, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
 		return fmt.Errorf("mkdir instance dir: %w", err)
 	}
 	return nil
@@ -42,7 +45,7 @@ func ValidateInstancePath(instanceRoot string, instanceID int, path string) erro
 }
 
 // InstanceISOList 列出 instance 专属 ISO 目录中的文件
-func (m *Manager) InstanceISOList(hostID string, instanceRoot string, instanceID int) ([]ISOFile, error) {
+func (m *Manager) InstanceISOList(ctx context.Context, hostID string, instanceRoot string, instanceID int) ([]ISOFile, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
@@ -50,7 +53,7 @@ func (m *Manager) InstanceISOList(hostID string, instanceRoot string, instanceID
 
 	isoDir := filepath.Join(InstanceDir(instanceRoot, instanceID), "iso")
 	cmd := fmt.Sprintf("find %s -maxdepth 1 -type f -name '*.iso' 2>/dev/null | sort", isoDir)
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpQuery, cmd)
 	if err != nil || strings.TrimSpace(output) == "" {
 		return nil, nil
 	}
@@ -1,191 +1,218 @@
 package vm
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"sync"
 
+	"vmcat/internal/event"
 	internalssh "vmcat/internal/ssh"
 )
 
 // Manager VM 管理器
 type Manager struct {
-	pool *internalssh.Pool
+	pool    *internalssh.Pool
+	emitter event.Emitter
+	cache   *stateCache
+
+	mu       sync.Mutex
+	backends map[string]string          // hostID -> 后端类型，未设置为 virsh
+	rpc      map[string]*libvirtBackend // hostID -> 已建立的 libvirt 连接
+	watchers map[string]*eventWatcher   // hostID -> 事件订阅
 }
 
 // NewManager 创建 VM 管理器
 func NewManager(pool *internalssh.Pool) *Manager {
-	return &Manager{pool: pool}
+	return &Manager{
+		pool:     pool,
+		emitter:  &event.NoopEmitter{},
+		cache:    newStateCache(),
+		backends: make(map[string]string),
+		rpc:      make(map[string]*libvirtBackend),
+		watchers: make(map[string]*eventWatcher),
+	}
 }
 
-// List 获取宿主机上所有虚拟机列表
-func (m *Manager) List(hostID string) ([]VM, error) {
-	client, err := m.pool.Get(hostID)
-	if err != nil {
-		return nil, err
-	}
+// SetEmitter 设置事件发射器（VM 生命周期变化推送 vm:event）
+func (m *Manager) SetEmitter(e event.Emitter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emitter = e
+}
 
-	output, err := client.Execute("virsh list --all")
-	if err != nil {
-		return nil, fmt.Errorf("virsh list: %w", err)
-	}
+func (m *Manager) emit(topic string, data interface{}) {
+	m.mu.Lock()
+	emitter := m.emitter
+	m.mu.Unlock()
+	emitter.Emit(topic, data)
+}
 
-	vms := parseVMList(output)
-	for i := range vms {
-		vms[i].HostID = hostID
+// SetBackend 设置宿主机使用的管理后端，切换时关闭已建立的 libvirt 连接
+func (m *Manager) SetBackend(hostID, kind string) {
+	if kind == "" {
+		kind = BackendVirsh
+	}
+	m.mu.Lock()
+	m.backends[hostID] = kind
+	b := m.takeRPC(hostID)
+	m.mu.Unlock()
+	if b != nil {
+		b.close()
 	}
+}
 
-	// 补充 CPU 和内存信息
-	for i, vm := range vms {
-		xmlOutput, err := client.Execute(fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vm.Name)))
-		if err != nil {
-			continue
-		}
-		domain, err := parseDumpXML(xmlOutput)
-		if err != nil {
-			continue
-		}
-		vms[i].CPUs = domain.VCPU
-		mem := domain.Memory.Value
-		switch domain.Memory.Unit {
-		case "KiB":
-			vms[i].MemoryMB = mem / 1024
-		case "GiB":
-			vms[i].MemoryMB = mem * 1024
-		case "bytes":
-			vms[i].MemoryMB = mem / 1024 / 1024
-		default:
-			vms[i].MemoryMB = mem
-		}
+// CloseBackend 释放宿主机的后端资源（断开连接时调用）
+func (m *Manager) CloseBackend(hostID string) {
+	m.mu.Lock()
+	delete(m.backends, hostID)
+	b := m.takeRPC(hostID)
+	m.mu.Unlock()
+	if b != nil {
+		b.close()
 	}
+}
 
-	return vms, nil
+// takeRPC 从缓存中取出宿主机的 libvirt 连接，调用方持有 m.mu
+// 关闭连接可能等待 libvirtd 应答，须在释放 m.mu 后进行，避免阻塞其他宿主机的操作和事件推送
+func (m *Manager) takeRPC(hostID string) *libvirtBackend {
+	b := m.rpc[hostID]
+	delete(m.rpc, hostID)
+	return b
 }
 
-// Get 获取虚拟机详情
-func (m *Manager) Get(hostID, vmName string) (*VMDetail, error) {
+// backend 获取宿主机的管理后端，libvirt 连接断开或 SSH 重连后自动重建
+func (m *Manager) backend(ctx context.Context, hostID string) (Backend, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取 XML 配置
-	xmlOutput, err := client.Execute(fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return nil, fmt.Errorf("virsh dumpxml: %w", err)
-	}
+	m.mu.Lock()
+	kind := m.backends[hostID]
+	cached := m.rpc[hostID]
+	m.mu.Unlock()
 
-	domain, err := parseDumpXML(xmlOutput)
-	if err != nil {
-		return nil, err
+	if kind != BackendLibvirt {
+		return &virshBackend{client: client}, nil
 	}
-
-	detail := domainToDetail(domain, hostID)
-
-	// 获取运行状态
-	infoOutput, err := client.Execute(fmt.Sprintf("virsh dominfo %s", internalssh.ShellQuote(vmName)))
-	if err == nil {
-		info := parseDominfo(infoOutput)
-		detail.State = info["State"]
-		if info["Autostart"] == "enable" {
-			detail.Autostart = true
-		}
+	if cached != nil && cached.client == client && cached.alive() {
+		return cached, nil
 	}
 
-	// 获取 IP 地址
-	ifOutput, err := client.Execute(fmt.Sprintf("virsh domifaddr %s", internalssh.ShellQuote(vmName)))
-	if err == nil {
-		ips := parseDomifaddr(ifOutput)
-		for i, nic := range detail.NICs {
-			mac := strings.ToLower(nic.MAC)
-			if ip, ok := ips[mac]; ok {
-				detail.NICs[i].IP = ip
-			}
-		}
+	b, err := dialLibvirt(ctx, client, m.pool.Timeouts())
+	if err != nil {
+		return nil, fmt.Errorf("libvirt backend: %w", err)
 	}
 
-	return detail, nil
+	m.mu.Lock()
+	// 并发建立连接时保留先完成的一个
+	if cur := m.rpc[hostID]; cur != nil && cur != cached && cur.client == client && cur.alive() {
+		m.mu.Unlock()
+		b.close()
+		return cur, nil
+	}
+	m.rpc[hostID] = b
+	m.mu.Unlock()
+	if cached != nil {
+		cached.close()
+	}
+	return b, nil
 }
 
-// Start 启动虚拟机
-func (m *Manager) Start(hostID, vmName string) error {
-	client, err := m.pool.Get(hostID)
+// List 获取宿主机上所有虚拟机列表，ctx 可携带命令优先级（后台采集使用低优先级）
+// 已订阅事件且完成同步的宿主机直接返回缓存
+func (m *Manager) List(ctx context.Context, hostID string) ([]VM, error) {
+	if vms, ok := m.cache.list(hostID); ok {
+		return vms, nil
+	}
+	vms, _, err := m.fetch(ctx, hostID)
+	return vms, err
+}
+
+// fetch 从宿主机查询 VM 列表并写入缓存，返回是否写入成功
+func (m *Manager) fetch(ctx context.Context, hostID string) ([]VM, bool, error) {
+	gen := m.cache.generation(hostID)
+	b, err := m.backend(ctx, hostID)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh start %s", internalssh.ShellQuote(vmName)))
+	vms, err := b.List(ctx)
 	if err != nil {
-		return fmt.Errorf("virsh start: %s", output)
+		return nil, false, err
 	}
-	return nil
+	for i := range vms {
+		vms[i].HostID = hostID
+	}
+	return vms, m.cache.store(hostID, gen, vms), nil
 }
 
-// Shutdown 优雅关闭虚拟机 (ACPI)
-func (m *Manager) Shutdown(hostID, vmName string) error {
-	client, err := m.pool.Get(hostID)
+// Get 获取虚拟机详情
+func (m *Manager) Get(ctx context.Context, hostID, vmName string) (*VMDetail, error) {
+	b, err := m.backend(ctx, hostID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh shutdown %s", internalssh.ShellQuote(vmName)))
+	detail, err := b.Get(ctx, vmName)
 	if err != nil {
-		return fmt.Errorf("virsh shutdown: %s", output)
+		return nil, err
 	}
-	return nil
+	detail.HostID = hostID
+	return detail, nil
+}
+
+// Start 启动虚拟机
+func (m *Manager) Start(ctx context.Context, hostID, vmName string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.Start(ctx, vmName)
+	})
+}
+
+// Shutdown 优雅关闭虚拟机 (ACPI)
+func (m *Manager) Shutdown(ctx context.Context, hostID, vmName string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.Shutdown(ctx, vmName)
+	})
 }
 
 // Destroy 强制关闭虚拟机
-func (m *Manager) Destroy(hostID, vmName string) error {
-	client, err := m.pool.Get(hostID)
-	if err != nil {
-		return err
-	}
-	output, err := client.Execute(fmt.Sprintf("virsh destroy %s", internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return fmt.Errorf("virsh destroy: %s", output)
-	}
-	return nil
+func (m *Manager) Destroy(ctx context.Context, hostID, vmName string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.Destroy(ctx, vmName)
+	})
 }
 
 // Reboot 重启虚拟机
-func (m *Manager) Reboot(hostID, vmName string) error {
-	client, err := m.pool.Get(hostID)
-	if err != nil {
-		return err
-	}
-	output, err := client.Execute(fmt.Sprintf("virsh reboot %s", internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return fmt.Errorf("virsh reboot: %s", output)
-	}
-	return nil
+func (m *Manager) Reboot(ctx context.Context, hostID, vmName string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.Reboot(ctx, vmName)
+	})
 }
 
 // Suspend 暂停虚拟机
-func (m *Manager) Suspend(hostID, vmName string) error {
-	client, err := m.pool.Get(hostID)
-	if err != nil {
-		return err
-	}
-	output, err := client.Execute(fmt.Sprintf("virsh suspend %s", internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return fmt.Errorf("virsh suspend: %s", output)
-	}
-	return nil
+func (m *Manager) Suspend(ctx context.Context, hostID, vmName string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.Suspend(ctx, vmName)
+	})
 }
 
 // Resume 恢复虚拟机
-func (m *Manager) Resume(hostID, vmName string) error {
-	client, err := m.pool.Get(hostID)
+func (m *Manager) Resume(ctx context.Context, hostID, vmName string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.Resume(ctx, vmName)
+	})
+}
+
+// withBackend 获取宿主机后端并执行操作
+func (m *Manager) withBackend(ctx context.Context, hostID string, fn func(Backend) error) error {
+	b, err := m.backend(ctx, hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh resume %s", internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return fmt.Errorf("virsh resume: %s", output)
-	}
-	return nil
+	return fn(b)
 }
 
 // Delete 删除虚拟机 (undefine)
-func (m *Manager) Delete(hostID, vmName string, removeStorage bool) error {
+func (m *Manager) Delete(ctx context.Context, hostID, vmName string, removeStorage bool) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -194,29 +221,29 @@ func (m *Manager) Delete(hostID, vmName string, removeStorage bool) error {
 	if removeStorage {
 		cmd += " --remove-all-storage"
 	}
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 	if err != nil {
-		return fmt.Errorf("virsh undefine: %s", output)
+		return virshErr(output, fmt.Errorf("virsh undefine: %s", output))
 	}
 	return nil
 }
 
 // Rename 重命名虚拟机 (需关机状态)
-func (m *Manager) Rename(hostID, oldName, newName string) error {
+func (m *Manager) Rename(ctx context.Context, hostID, oldName, newName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh domrename %s %s",
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh domrename %s %s",
 		internalssh.ShellQuote(oldName), internalssh.ShellQuote(newName)))
 	if err != nil {
-		return fmt.Errorf("virsh domrename: %s", output)
+		return virshErr(output, fmt.Errorf("virsh domrename: %s", output))
 	}
 	return nil
 }
 
 // SetVCPUs 设置 CPU 数量
-func (m *Manager) SetVCPUs(hostID, vmName string, count int) error {
+func (m *Manager) SetVCPUs(ctx context.Context, hostID, vmName string, count int) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -224,19 +251,21 @@ func (m *Manager) SetVCPUs(hostID, vmName string, count int) error {
 	q := internalssh.ShellQuote(vmName)
 	// 先设置最大值 (--config)
 	cmd := fmt.Sprintf("virsh setvcpus %s %d --config --maximum", q, count)
-	if output, err := client.Execute(cmd); err != nil {
-		return fmt.Errorf("setvcpus max: %s", output)
+	if output, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
+		return virshErr(output, fmt.Errorf("setvcpus max: %s", output))
 	}
 	// 再设置当前值 (--config)
 	cmd = fmt.Sprintf("virsh setvcpus %s %d --config", q, count)
-	if output, err := client.Execute(cmd); err != nil {
-		return fmt.Errorf("setvcpus config: %s", output)
+	if output, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
+		return virshErr(output, fmt.Errorf("setvcpus config: %s", output))
 	}
+	// CPU/内存配置变更不产生生命周期事件，需刷新缓存
+	m.Invalidate(hostID)
 	return nil
 }
 
 // SetMemory 设置内存大小 (MB)
-func (m *Manager) SetMemory(hostID, vmName string, sizeMB int) error {
+func (m *Manager) SetMemory(ctx context.Context, hostID, vmName string, sizeMB int) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -244,54 +273,49 @@ func (m *Manager) SetMemory(hostID, vmName string, sizeMB int) error {
 	q := internalssh.ShellQuote(vmName)
 	// 先设置最大内存 (--config)
 	cmd := fmt.Sprintf("virsh setmaxmem %s %dM --config", q, sizeMB)
-	if output, err := client.Execute(cmd); err != nil {
-		return fmt.Errorf("setmaxmem: %s", output)
+	if output, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
+		return virshErr(output, fmt.Errorf("setmaxmem: %s", output))
 	}
 	// 再设置当前内存 (--config)
 	cmd = fmt.Sprintf("virsh setmem %s %dM --config", q, sizeMB)
-	if output, err := client.Execute(cmd); err != nil {
-		return fmt.Errorf("setmem: %s", output)
+	if output, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
+		return virshErr(output, fmt.Errorf("setmem: %s", output))
 	}
+	// CPU/内存配置变更不产生生命周期事件，需刷新缓存
+	m.Invalidate(hostID)
 	return nil
 }
 
 // GetXML 获取 VM 的 XML 配置
-func (m *Manager) GetXML(hostID, vmName string) (string, error) {
-	client, err := m.pool.Get(hostID)
+func (m *Manager) GetXML(ctx context.Context, hostID, vmName string) (string, error) {
+	b, err := m.backend(ctx, hostID)
 	if err != nil {
 		return "", err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return "", fmt.Errorf("virsh dumpxml: %w", err)
-	}
-	return output, nil
+	return b.GetXML(ctx, vmName)
 }
 
 // DefineXML 用 XML 定义/更新 VM
-func (m *Manager) DefineXML(hostID, xmlContent string) error {
-	client, err := m.pool.Get(hostID)
-	if err != nil {
-		return err
-	}
-	// 通过 stdin 传递 XML（ShellQuote 安全转义）
-	cmd := fmt.Sprintf("echo %s | virsh define /dev/stdin", internalssh.ShellQuote(xmlContent))
-	output, err := client.Execute(cmd)
-	if err != nil {
-		return fmt.Errorf("virsh define: %s", output)
-	}
-	return nil
+func (m *Manager) DefineXML(ctx context.Context, hostID, xmlContent string) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.DefineXML(ctx, xmlContent)
+	})
+}
+
+// ParseDomainXML 解析 VM 定义 XML（如缓存的 dumpxml 输出）
+func ParseDomainXML(xmlContent string) (*DomainXML, error) {
+	return parseDumpXML(xmlContent)
 }
 
 // Clone 克隆虚拟机
-func (m *Manager) Clone(hostID, srcName, newName string) error {
+func (m *Manager) Clone(ctx context.Context, hostID, srcName, newName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
 	cmd := fmt.Sprintf("virt-clone --original %s --name %s --auto-clone",
 		internalssh.ShellQuote(srcName), internalssh.ShellQuote(newName))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpClone, cmd)
 	if err != nil {
 		return fmt.Errorf("virt-clone: %s", output)
 	}
@@ -299,18 +323,8 @@ func (m *Manager) Clone(hostID, srcName, newName string) error {
 }
 
 // SetAutostart 设置自动启动
-func (m *Manager) SetAutostart(hostID, vmName string, enabled bool) error {
-	client, err := m.pool.Get(hostID)
-	if err != nil {
-		return err
-	}
-	flag := "--autostart"
-	if !enabled {
-		flag = "--autostart --disable"
-	}
-	output, err := client.Execute(fmt.Sprintf("virsh autostart %s %s", flag, internalssh.ShellQuote(vmName)))
-	if err != nil {
-		return fmt.Errorf("virsh autostart: %s", output)
-	}
-	return nil
+func (m *Manager) SetAutostart(ctx context.Context, hostID, vmName string, enabled bool) error {
+	return m.withBackend(ctx, hostID, func(b Backend) error {
+		return b.SetAutostart(ctx, vmName, enabled)
+	})
 }
@@ -1,6 +1,8 @@
 package vm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -8,7 +10,7 @@ import (
 )
 
 // Migrate 在线迁移 VM 到目标宿主机
-func (m *Manager) Migrate(srcHostID, vmName, dstHostID string) error {
+func (m *Manager) Migrate(ctx context.Context, srcHostID, vmName, dstHostID string) error {
 	srcClient, err := m.pool.Get(srcHostID)
 	if err != nil {
 		return fmt.Errorf("源宿主机未连接: %w", err)
@@ -20,7 +22,7 @@ func (m *Manager) Migrate(srcHostID, vmName, dstHostID string) error {
 	}
 
 	// 获取目标宿主机地址
-	dstHost, err := dstClient.Execute("hostname -f 2>/dev/null || hostname")
+	dstHost, err := dstClient.Run(ctx, internalssh.OpQuery, "hostname -f 2>/dev/null || hostname")
 	if err != nil {
 		return fmt.Errorf("获取目标主机名: %w", err)
 	}
@@ -28,7 +30,7 @@ func (m *Manager) Migrate(srcHostID, vmName, dstHostID string) error {
 
 	// 预检查: 目标宿主机连通性
 	checkCmd := fmt.Sprintf("virsh -c qemu+ssh://%s/system list 2>&1 | head -3", internalssh.ShellQuote(dstHost))
-	output, err := srcClient.Execute(checkCmd)
+	output, err := srcClient.Run(ctx, internalssh.OpQuery, checkCmd)
 	if err != nil {
 		return fmt.Errorf("无法从源宿主机连接到目标: %s (输出: %s)", err, output)
 	}
@@ -40,8 +42,15 @@ func (m *Manager) Migrate(srcHostID, vmName, dstHostID string) error {
 		internalssh.ShellQuote(dstHost),
 	)
 
-	output, err = srcClient.Execute(migrateCmd)
+	output, err = srcClient.Run(ctx, internalssh.OpMigrate, migrateCmd)
 	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+			// 任务被取消或超时，中止 libvirt 侧仍在进行的迁移作业，清理命令不随已取消的 ctx 中断
+			srcClient.Run(context.WithoutCancel(ctx), internalssh.OpConfig, fmt.Sprintf("virsh domjobabort %s", internalssh.ShellQuote(vmName)))
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
 		return fmt.Errorf("迁移失败: %w (输出: %s)", err, output)
 	}
 
@@ -50,7 +59,7 @@ func (m *Manager) Migrate(srcHostID, vmName, dstHostID string) error {
 
 // MigrateOffline 离线迁移 VM (通过客户端中继，适用于网络隔离场景)
 // 流程: 关机 -> 导出XML -> 流式复制磁盘 -> 在目标定义VM -> 在源删除
-func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress func(step, detail string)) error {
+func (m *Manager) MigrateOffline(ctx context.Context, srcHostID, vmName, dstHostID string, onProgress func(step, detail string)) error {
 	srcClient, err := m.pool.Get(srcHostID)
 	if err != nil {
 		return fmt.Errorf("source host not connected: %w", err)
@@ -68,9 +77,9 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 
 	// 1. 检查 VM 是否关机
 	progress("check", "checking VM state")
-	infoOut, err := srcClient.Execute(fmt.Sprintf("virsh dominfo %s", internalssh.ShellQuote(vmName)))
+	infoOut, err := srcClient.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh dominfo %s", internalssh.ShellQuote(vmName)))
 	if err != nil {
-		return fmt.Errorf("get VM info: %w", err)
+		return virshErr(infoOut, fmt.Errorf("get VM info: %w", err))
 	}
 	info := parseDominfo(infoOut)
 	if info["State"] != "shut off" {
@@ -79,9 +88,9 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 
 	// 2. 导出 XML
 	progress("xml", "exporting VM definition")
-	xmlOut, err := srcClient.Execute(fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vmName)))
+	xmlOut, err := srcClient.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh dumpxml %s", internalssh.ShellQuote(vmName)))
 	if err != nil {
-		return fmt.Errorf("dump XML: %w", err)
+		return virshErr(xmlOut, fmt.Errorf("dump XML: %w", err))
 	}
 
 	// 3. 解析磁盘路径
@@ -115,7 +124,7 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 		progress("copy", fmt.Sprintf("copying disk %d/%d: %s", i+1, len(disks), disk.srcPath))
 
 		// 获取源文件大小
-		sizeOut, err := srcClient.Execute(fmt.Sprintf("stat -c %%s %s", internalssh.ShellQuote(disk.srcPath)))
+		sizeOut, err := srcClient.Run(ctx, internalssh.OpQuery, fmt.Sprintf("stat -c %%s %s", internalssh.ShellQuote(disk.srcPath)))
 		if err != nil {
 			return fmt.Errorf("get disk size %s: %w", disk.srcPath, err)
 		}
@@ -140,7 +149,7 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 		}
 
 		// 确保目标目录存在
-		dstClient.Execute(fmt.Sprintf("mkdir -p %s", internalssh.ShellQuote(dstBase)))
+		dstClient.Run(ctx, internalssh.OpConfig, fmt.Sprintf("mkdir -p %s", internalssh.ShellQuote(dstBase)))
 
 		dstSession, err := dstClient.GetSSHClient().NewSession()
 		if err != nil {
@@ -161,13 +170,23 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 			return fmt.Errorf("dst cat start: %w", err)
 		}
 
+		// 任务取消时关闭会话，中断阻塞中的读写
+		stopWatch := context.AfterFunc(ctx, func() {
+			srcSession.Close()
+			dstSession.Close()
+		})
+
 		// 流式复制
 		buf := make([]byte, 256*1024) // 256KB buffer
 		var copied int64
 		for {
+			if ctx.Err() != nil {
+				break
+			}
 			n, readErr := srcStdout.Read(buf)
 			if n > 0 {
 				if _, writeErr := dstStdin.Write(buf[:n]); writeErr != nil {
+					stopWatch()
 					srcSession.Close()
 					dstSession.Close()
 					return fmt.Errorf("write to dst: %w", writeErr)
@@ -184,12 +203,19 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 			}
 		}
 
+		stopWatch()
 		dstStdin.Close()
 		srcSession.Wait()
 		srcSession.Close()
 		dstSession.Wait()
 		dstSession.Close()
 
+		if err := ctx.Err(); err != nil {
+			// 清理目标上不完整的磁盘文件，清理命令不随已取消的 ctx 中断
+			dstClient.Run(context.WithoutCancel(ctx), internalssh.OpConfig, fmt.Sprintf("rm -f %s", internalssh.ShellQuote(disk.dstPath)))
+			return err
+		}
+
 		progress("copy", fmt.Sprintf("disk %d/%d done: %d MB", i+1, len(disks), copied/(1024*1024)))
 	}
 
@@ -202,18 +228,18 @@ func (m *Manager) MigrateOffline(srcHostID, vmName, dstHostID string, onProgress
 
 	// 写入临时 XML 并定义
 	tmpXML := fmt.Sprintf("/tmp/vmcat_migrate_%s.xml", vmName)
-	if _, err := dstClient.Execute(fmt.Sprintf("cat > %s << 'VMCAT_EOF'\n%s\nVMCAT_EOF", tmpXML, modifiedXML)); err != nil {
+	if _, err := dstClient.Run(ctx, internalssh.OpConfig, fmt.Sprintf("cat > %s << 'VMCAT_EOF'\n%s\nVMCAT_EOF", tmpXML, modifiedXML)); err != nil {
 		return fmt.Errorf("write XML to target: %w", err)
 	}
-	output, err := dstClient.Execute(fmt.Sprintf("virsh define %s", tmpXML))
+	output, err := dstClient.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh define %s", tmpXML))
 	if err != nil {
 		return fmt.Errorf("define VM on target: %s", output)
 	}
-	dstClient.Execute(fmt.Sprintf("rm -f %s", tmpXML))
+	dstClient.Run(ctx, internalssh.OpConfig, fmt.Sprintf("rm -f %s", tmpXML))
 
 	// 6. 在源删除
 	progress("cleanup", "removing VM from source")
-	srcClient.Execute(fmt.Sprintf("virsh undefine %s", internalssh.ShellQuote(vmName)))
+	srcClient.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh undefine %s", internalssh.ShellQuote(vmName)))
 
 	progress("done", "migration completed")
 	return nil
@@ -0,0 +1,31 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMigrateCancelAbortsJob(t *testing.T) {
+	pool, host := newFakeHost(t, func(cmd string) fakeReply {
+		if strings.HasPrefix(cmd, "virsh migrate ") {
+			return fakeReply{block: true}
+		}
+		return fakeReply{output: "dst\n"}
+	}, "src", "dst")
+	m := NewManager(pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Migrate(ctx, "src", "web01", "dst") }()
+	host.waitFor(t, "virsh migrate ")
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want canceled", err)
+	}
+	if !host.ran("virsh domjobabort 'web01'") {
+		t.Error("canceled migration was not aborted")
+	}
+}
@@ -44,6 +44,44 @@ type Snapshot struct {
 	Parent    string `json:"parent"`
 }
 
+// SnapshotNode 快照树中的快照，SnapshotTree 按深度优先顺序返回，Depth 为层级（根快照为 0）
+type SnapshotNode struct {
+	Name        string         `json:"name"`
+	Parent      string         `json:"parent"`
+	Children    []string       `json:"children"`
+	Depth       int            `json:"depth"`
+	Description string         `json:"description"`
+	State       string         `json:"state"` // 创建时 VM 的状态：running | shutoff | disk-snapshot ...
+	CreatedAt   string         `json:"createdAt"`
+	Current     bool           `json:"current"`
+	Location    string         `json:"location"`   // internal | external
+	Memory      bool           `json:"memory"`     // 是否包含内存状态（恢复后 VM 从快照时刻继续运行）
+	MemoryFile  string         `json:"memoryFile"` // 外部快照的内存文件
+	SizeBytes   int64          `json:"sizeBytes"`  // 内部快照为内存状态大小，外部快照为内存文件与 overlay 文件之和，无法获取时为 0
+	Disks       []SnapshotDisk `json:"disks"`
+}
+
+// SnapshotDisk 快照中的磁盘
+type SnapshotDisk struct {
+	Name     string `json:"name"`     // 目标设备，如 vda
+	Snapshot string `json:"snapshot"` // internal | external | no
+	File     string `json:"file"`     // 外部快照的 overlay 文件，内部快照为磁盘镜像
+}
+
+// SnapshotCommitResult 合并外部快照链的结果
+type SnapshotCommitResult struct {
+	Disks     []SnapshotCommitDisk `json:"disks"`
+	Snapshots []string             `json:"snapshots"` // 已删除元数据的外部快照（overlay 合并后无法再恢复）
+	Removed   []string             `json:"removed"`   // 已删除的 overlay 文件
+}
+
+// SnapshotCommitDisk 单块磁盘的合并结果
+type SnapshotCommitDisk struct {
+	Target   string   `json:"target"`   // 目标设备，如 vda
+	Base     string   `json:"base"`     // 合并到的镜像，即第一次外部快照前的磁盘
+	Overlays []string `json:"overlays"` // 合并进 Base 的 overlay，从新到旧
+}
+
 // StoragePool 存储池
 type StoragePool struct {
 	Name       string `json:"name"`
@@ -73,6 +111,16 @@ type Network struct {
 	Bridge     string `json:"bridge"`
 }
 
+// NetworkCreateParams 新建虚拟网络参数
+type NetworkCreateParams struct {
+	Name      string `json:"name"`
+	Mode      string `json:"mode"`      // nat（默认）| isolated | bridge（桥接到宿主机已有网桥）
+	Bridge    string `json:"bridge"`    // nat/isolated 为 libvirt 创建的网桥名（空则自动分配），bridge 为宿主机网桥
+	Address   string `json:"address"`   // 网关地址，CIDR 格式如 192.168.100.1/24（bridge 模式不用）
+	DHCPStart string `json:"dhcpStart"` // DHCP 地址池，为空不启用 DHCP
+	DHCPEnd   string `json:"dhcpEnd"`
+}
+
 // ISOFile ISO 镜像文件
 type ISOFile struct {
 	Name string `json:"name"`
@@ -93,6 +141,8 @@ type VMCreateParams struct {
 	OSVariant  string `json:"osVariant"`
 	VNC        bool   `json:"vnc"`
 	BootDev    string `json:"bootDev"`
+	Machine    string `json:"machine"`  // QEMU 机器类型，如 q35，空为默认
+	Firmware   string `json:"firmware"` // bios | efi，空为默认
 }
 
 // DiskAttachParams 添加磁盘参数
@@ -131,4 +181,29 @@ type VMResourceStats struct {
 	NetTxBytes   uint64  `json:"netTxBytes"`
 	BlockRdBytes uint64  `json:"blockRdBytes"`
 	BlockWrBytes uint64  `json:"blockWrBytes"`
+
+	Disks      []DiskStats      `json:"disks"`      // 各块设备累计计数
+	Interfaces []InterfaceStats `json:"interfaces"` // 各网卡累计计数
+}
+
+// DiskStats 块设备累计 I/O 计数（自 VM 启动起）
+type DiskStats struct {
+	Name     string `json:"name"` // 目标设备名，如 vda
+	RdBytes  uint64 `json:"rdBytes"`
+	WrBytes  uint64 `json:"wrBytes"`
+	RdReqs   uint64 `json:"rdReqs"`
+	WrReqs   uint64 `json:"wrReqs"`
+	RdTimeNs uint64 `json:"rdTimeNs"` // 读请求累计耗时
+	WrTimeNs uint64 `json:"wrTimeNs"` // 写请求累计耗时
+}
+
+// InterfaceStats 网卡累计流量计数（自 VM 启动起）
+type InterfaceStats struct {
+	Name    string `json:"name"` // 宿主机侧设备名，如 vnet0
+	RxBytes uint64 `json:"rxBytes"`
+	RxPkts  uint64 `json:"rxPkts"`
+	RxDrop  uint64 `json:"rxDrop"`
+	TxBytes uint64 `json:"txBytes"`
+	TxPkts  uint64 `json:"txPkts"`
+	TxDrop  uint64 `json:"txDrop"`
 }
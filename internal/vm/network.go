@@ -1,26 +1,29 @@
 package vm
 
 import (
+	"context"
+	"encoding/xml"
 	"fmt"
+	"net"
 	"strings"
 
 	internalssh "vmcat/internal/ssh"
 )
 
 // NetworkList 获取虚拟网络列表
-func (m *Manager) NetworkList(hostID string) ([]Network, error) {
+func (m *Manager) NetworkList(ctx context.Context, hostID string) ([]Network, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
-	output, err := client.Execute("virsh net-list --all")
+	output, err := client.Run(ctx, internalssh.OpQuery, "virsh net-list --all")
 	if err != nil {
 		return nil, fmt.Errorf("net-list: %w", err)
 	}
 	nets := parseNetList(output)
 	// 补充 bridge 信息
 	for i, n := range nets {
-		infoOut, err := client.Execute(fmt.Sprintf("virsh net-info %s", internalssh.ShellQuote(n.Name)))
+		infoOut, err := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh net-info %s", internalssh.ShellQuote(n.Name)))
 		if err == nil {
 			info := parseDominfo(infoOut)
 			nets[i].Bridge = info["Bridge"]
@@ -30,12 +33,12 @@ func (m *Manager) NetworkList(hostID string) ([]Network, error) {
 }
 
 // NetworkStart 启动虚拟网络
-func (m *Manager) NetworkStart(hostID, netName string) error {
+func (m *Manager) NetworkStart(ctx context.Context, hostID, netName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh net-start %s", internalssh.ShellQuote(netName)))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh net-start %s", internalssh.ShellQuote(netName)))
 	if err != nil {
 		return fmt.Errorf("net-start: %s", output)
 	}
@@ -43,12 +46,12 @@ func (m *Manager) NetworkStart(hostID, netName string) error {
 }
 
 // NetworkStop 停止虚拟网络
-func (m *Manager) NetworkStop(hostID, netName string) error {
+func (m *Manager) NetworkStop(ctx context.Context, hostID, netName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh net-destroy %s", internalssh.ShellQuote(netName)))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh net-destroy %s", internalssh.ShellQuote(netName)))
 	if err != nil {
 		return fmt.Errorf("net-stop: %s", output)
 	}
@@ -56,7 +59,7 @@ func (m *Manager) NetworkStop(hostID, netName string) error {
 }
 
 // NetworkAutostart 设置虚拟网络自动启动
-func (m *Manager) NetworkAutostart(hostID, netName string, enabled bool) error {
+func (m *Manager) NetworkAutostart(ctx context.Context, hostID, netName string, enabled bool) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -65,24 +68,115 @@ func (m *Manager) NetworkAutostart(hostID, netName string, enabled bool) error {
 	if !enabled {
 		flag = "--no-autostart"
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh net-autostart %s %s", internalssh.ShellQuote(netName), flag))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh net-autostart %s %s", internalssh.ShellQuote(netName), flag))
 	if err != nil {
 		return fmt.Errorf("net-autostart: %s", output)
 	}
 	return nil
 }
 
+// NetworkCreate 定义并启动虚拟网络
+func (m *Manager) NetworkCreate(ctx context.Context, hostID string, params NetworkCreateParams) error {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return err
+	}
+	xmlContent, err := networkXML(params)
+	if err != nil {
+		return err
+	}
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("echo %s | virsh net-define /dev/stdin", internalssh.ShellQuote(xmlContent)))
+	if err != nil {
+		return fmt.Errorf("net-define: %s", output)
+	}
+	output, err = client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh net-start %s", internalssh.ShellQuote(params.Name)))
+	if err != nil {
+		return fmt.Errorf("net-start: %s", output)
+	}
+	return nil
+}
+
+// NetworkDelete 停止并删除虚拟网络定义
+func (m *Manager) NetworkDelete(ctx context.Context, hostID, netName string) error {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return err
+	}
+	// 未启动的网络 net-destroy 会失败，忽略
+	client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh net-destroy %s", internalssh.ShellQuote(netName)))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh net-undefine %s", internalssh.ShellQuote(netName)))
+	if err != nil {
+		return fmt.Errorf("net-undefine: %s", output)
+	}
+	return nil
+}
+
+// networkXML 生成 virsh net-define 使用的网络 XML
+func networkXML(params NetworkCreateParams) (string, error) {
+	type attr struct {
+		Name string `xml:"name,attr,omitempty"`
+		Mode string `xml:"mode,attr,omitempty"`
+	}
+	type dhcpRange struct {
+		Start string `xml:"start,attr"`
+		End   string `xml:"end,attr"`
+	}
+	type ip struct {
+		Address string     `xml:"address,attr"`
+		Netmask string     `xml:"netmask,attr"`
+		Range   *dhcpRange `xml:"dhcp>range"`
+	}
+	doc := struct {
+		XMLName xml.Name `xml:"network"`
+		Name    string   `xml:"name"`
+		Forward *attr    `xml:"forward"`
+		Bridge  *attr    `xml:"bridge"`
+		IP      *ip      `xml:"ip"`
+	}{Name: params.Name}
+
+	switch params.Mode {
+	case "", "nat":
+		doc.Forward = &attr{Mode: "nat"}
+	case "isolated":
+	case "bridge":
+		if params.Bridge == "" {
+			return "", fmt.Errorf("bridge network %s needs a host bridge", params.Name)
+		}
+		doc.Forward = &attr{Mode: "bridge"}
+		doc.Bridge = &attr{Name: params.Bridge}
+		out, err := xml.Marshal(doc)
+		return string(out), err
+	default:
+		return "", fmt.Errorf("invalid network mode: %s (nat | isolated | bridge)", params.Mode)
+	}
+	if params.Bridge != "" {
+		doc.Bridge = &attr{Name: params.Bridge}
+	}
+	if params.Address != "" {
+		addr, ipnet, err := net.ParseCIDR(params.Address)
+		if err != nil {
+			return "", fmt.Errorf("invalid network address %s: %w", params.Address, err)
+		}
+		doc.IP = &ip{Address: addr.String(), Netmask: net.IP(ipnet.Mask).String()}
+		if params.DHCPStart != "" && params.DHCPEnd != "" {
+			doc.IP.Range = &dhcpRange{Start: params.DHCPStart, End: params.DHCPEnd}
+		}
+	}
+	out, err := xml.Marshal(doc)
+	return string(out), err
+}
+
 // BridgeList 获取宿主机网桥列表
-func (m *Manager) BridgeList(hostID string) ([]string, error) {
+func (m *Manager) BridgeList(ctx context.Context, hostID string) ([]string, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
 	// 用 ip 命令获取网桥
-	output, err := client.Execute("ip -o link show type bridge | awk -F': ' '{print $2}'")
+	output, err := client.Run(ctx, internalssh.OpQuery, "ip -o link show type bridge | awk -F': ' '{print $2}'")
 	if err != nil {
 		// 兜底: brctl show
-		output, err = client.Execute("brctl show | tail -n +2 | awk '{print $1}'")
+		output, err = client.Run(ctx, internalssh.OpQuery, "brctl show | tail -n +2 | awk '{print $1}'")
 		if err != nil {
 			return nil, fmt.Errorf("bridge-list: %w", err)
 		}
@@ -98,13 +192,13 @@ func (m *Manager) BridgeList(hostID string) ([]string, error) {
 }
 
 // NATRuleList 列出当前 iptables DNAT 规则
-func (m *Manager) NATRuleList(hostID string) ([]NATRule, error) {
+func (m *Manager) NATRuleList(ctx context.Context, hostID string) ([]NATRule, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
 	// 列出 nat 表 PREROUTING 链的 DNAT 规则
-	output, err := client.Execute("sudo iptables -t nat -L PREROUTING -n --line-numbers 2>/dev/null")
+	output, err := client.Run(ctx, internalssh.OpQuery, "sudo iptables -t nat -L PREROUTING -n --line-numbers 2>/dev/null")
 	if err != nil {
 		return nil, fmt.Errorf("iptables list: %w", err)
 	}
@@ -123,7 +217,7 @@ func (m *Manager) NATRuleList(hostID string) ([]NATRule, error) {
 }
 
 // NATRuleAdd 添加 DNAT 端口转发规则
-func (m *Manager) NATRuleAdd(hostID, proto, hostPort, vmIP, vmPort, comment string) error {
+func (m *Manager) NATRuleAdd(ctx context.Context, hostID, proto, hostPort, vmIP, vmPort, comment string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -139,7 +233,7 @@ func (m *Manager) NATRuleAdd(hostID, proto, hostPort, vmIP, vmPort, comment stri
 	if comment != "" {
 		cmd += fmt.Sprintf(" -m comment --comment %s", internalssh.ShellQuote(comment))
 	}
-	if output, err := client.Execute(cmd); err != nil {
+	if output, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
 		return fmt.Errorf("iptables add: %s", output)
 	}
 	// 添加 FORWARD 规则允许转发
@@ -147,12 +241,12 @@ func (m *Manager) NATRuleAdd(hostID, proto, hostPort, vmIP, vmPort, comment stri
 		"sudo iptables -C FORWARD -p %s -d %s --dport %s -j ACCEPT 2>/dev/null || sudo iptables -A FORWARD -p %s -d %s --dport %s -j ACCEPT",
 		proto, vmIP, vmPort, proto, vmIP, vmPort,
 	)
-	client.Execute(fwdCmd)
+	client.Run(ctx, internalssh.OpConfig, fwdCmd)
 	return nil
 }
 
 // NATRuleDelete 删除 DNAT 端口转发规则
-func (m *Manager) NATRuleDelete(hostID, proto, hostPort, vmIP, vmPort string) error {
+func (m *Manager) NATRuleDelete(ctx context.Context, hostID, proto, hostPort, vmIP, vmPort string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -164,7 +258,7 @@ func (m *Manager) NATRuleDelete(hostID, proto, hostPort, vmIP, vmPort string) er
 		"sudo iptables -t nat -D PREROUTING -p %s --dport %s -j DNAT --to-destination %s:%s",
 		proto, hostPort, vmIP, vmPort,
 	)
-	if output, err := client.Execute(cmd); err != nil {
+	if output, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
 		return fmt.Errorf("iptables delete: %s", output)
 	}
 	// 尝试删除 FORWARD 规则
@@ -172,7 +266,7 @@ func (m *Manager) NATRuleDelete(hostID, proto, hostPort, vmIP, vmPort string) er
 		"sudo iptables -D FORWARD -p %s -d %s --dport %s -j ACCEPT 2>/dev/null",
 		proto, vmIP, vmPort,
 	)
-	client.Execute(fwdCmd)
+	client.Run(ctx, internalssh.OpConfig, fwdCmd)
 	return nil
 }
 
@@ -1,45 +1,133 @@
 package vm
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	internalssh "vmcat/internal/ssh"
 )
 
 // SnapshotList 获取快照列表
-func (m *Manager) SnapshotList(hostID, vmName string) ([]Snapshot, error) {
+func (m *Manager) SnapshotList(ctx context.Context, hostID, vmName string) ([]Snapshot, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := client.Execute(fmt.Sprintf("virsh snapshot-list %s", internalssh.ShellQuote(vmName)))
+	output, err := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh snapshot-list %s", internalssh.ShellQuote(vmName)))
 	if err != nil {
-		return nil, fmt.Errorf("snapshot-list: %w", err)
+		return nil, virshErr(output, fmt.Errorf("snapshot-list: %w", err))
 	}
 
 	return parseSnapshotList(output), nil
 }
 
+// SnapshotCreateParams 创建快照的参数
+type SnapshotCreateParams struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// DiskOnly 创建外部磁盘快照（--disk-only --atomic）：不保存内存状态，
+	// 每块磁盘改写到新的 qcow2 overlay，原镜像（含 raw 盘）只读保留，大内存 VM 也不会长时间停顿
+	DiskOnly bool `json:"diskOnly"`
+	// Quiesce 通过 qemu-guest-agent 冻结文件系统后再快照，需要 DiskOnly 且 VM 内运行 guest agent
+	Quiesce bool `json:"quiesce"`
+	// OverlayDir overlay 文件所在目录，为空时与各磁盘原镜像同目录
+	OverlayDir string `json:"overlayDir"`
+}
+
 // SnapshotCreate 创建快照
-func (m *Manager) SnapshotCreate(hostID, vmName, snapName string) error {
+func (m *Manager) SnapshotCreate(ctx context.Context, hostID, vmName, snapName string) error {
+	return m.SnapshotCreateWith(ctx, hostID, vmName, SnapshotCreateParams{Name: snapName})
+}
+
+// SnapshotCreateWith 按参数创建快照
+func (m *Manager) SnapshotCreateWith(ctx context.Context, hostID, vmName string, params SnapshotCreateParams) error {
+	if params.Quiesce && !params.DiskOnly {
+		return fmt.Errorf("quiesce requires a disk-only snapshot")
+	}
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
 
-	cmd := fmt.Sprintf("virsh snapshot-create-as %s --name %s",
-		internalssh.ShellQuote(vmName), internalssh.ShellQuote(snapName))
-	output, err := client.Execute(cmd)
+	q := internalssh.ShellQuote(vmName)
+	cmd := fmt.Sprintf("virsh snapshot-create-as %s --name %s", q, internalssh.ShellQuote(params.Name))
+	if params.Description != "" {
+		cmd += " --description " + internalssh.ShellQuote(params.Description)
+	}
+	if params.DiskOnly {
+		if strings.Contains(params.Name, "/") {
+			return fmt.Errorf("disk-only snapshot name must not contain '/'")
+		}
+		xmlOutput, err := client.Run(ctx, internalssh.OpQuery, "virsh dumpxml "+q)
+		if err != nil {
+			return virshErr(xmlOutput, fmt.Errorf("virsh dumpxml: %s", xmlOutput))
+		}
+		domain, err := parseDumpXML(xmlOutput)
+		if err != nil {
+			return err
+		}
+		specs, err := snapshotDiskSpecs(domain, params)
+		if err != nil {
+			return err
+		}
+		cmd += " --disk-only --atomic" + specs
+	}
+	if params.Quiesce {
+		// 预先检查 guest agent，给出比 libvirt 更明确的错误
+		ping := fmt.Sprintf(`virsh qemu-agent-command %s '{"execute":"guest-ping"}'`, q)
+		if output, err := client.Run(ctx, internalssh.OpQuery, ping); err != nil {
+			return fmt.Errorf("quiesce needs qemu-guest-agent running in the VM: %s", strings.TrimSpace(output))
+		}
+		cmd += " --quiesce"
+	}
+	output, err := client.Run(ctx, internalssh.OpSnapshot, cmd)
 	if err != nil {
-		return fmt.Errorf("snapshot-create: %s", output)
+		return virshErr(output, fmt.Errorf("snapshot-create: %s", output))
 	}
 	return nil
 }
 
+// snapshotDiskSpecs 生成外部快照的 --diskspec 参数：磁盘写入 <目录>/<VM>.<设备>.<快照>.qcow2，光驱等设备不参与快照
+func snapshotDiskSpecs(domain *DomainXML, params SnapshotCreateParams) (string, error) {
+	var b strings.Builder
+	external := 0
+	for _, d := range domain.Devices.Disks {
+		if d.Target.Dev == "" {
+			continue
+		}
+		if d.Device != "disk" || d.Source.File == "" && d.Source.Dev == "" {
+			b.WriteString(" --diskspec " + internalssh.ShellQuote(d.Target.Dev+",snapshot=no"))
+			continue
+		}
+		dir := params.OverlayDir
+		if dir == "" {
+			if d.Source.File == "" {
+				return "", fmt.Errorf("disk %s is a block device, an overlay directory is required", d.Target.Dev)
+			}
+			dir = path.Dir(d.Source.File)
+		}
+		file := path.Join(dir, fmt.Sprintf("%s.%s.%s.qcow2", domain.Name, d.Target.Dev, params.Name))
+		// diskspec 以逗号分隔，文件名中的逗号需写成两个
+		spec := fmt.Sprintf("%s,snapshot=external,driver=qcow2,file=%s", d.Target.Dev, strings.ReplaceAll(file, ",", ",,"))
+		b.WriteString(" --diskspec " + internalssh.ShellQuote(spec))
+		external++
+	}
+	if external == 0 {
+		return "", fmt.Errorf("vm %s has no disk to snapshot", domain.Name)
+	}
+	return b.String(), nil
+}
+
 // SnapshotDelete 删除快照
-func (m *Manager) SnapshotDelete(hostID, vmName, snapName string) error {
+func (m *Manager) SnapshotDelete(ctx context.Context, hostID, vmName, snapName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -47,15 +135,69 @@ func (m *Manager) SnapshotDelete(hostID, vmName, snapName string) error {
 
 	cmd := fmt.Sprintf("virsh snapshot-delete %s %s",
 		internalssh.ShellQuote(vmName), internalssh.ShellQuote(snapName))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpSnapshot, cmd)
 	if err != nil {
-		return fmt.Errorf("snapshot-delete: %s", output)
+		return virshErr(output, fmt.Errorf("snapshot-delete: %s", output))
 	}
 	return nil
 }
 
+// SnapshotLocation 获取快照位置：internal（磁盘内部快照）或 external（overlay 文件）
+func (m *Manager) SnapshotLocation(ctx context.Context, hostID, vmName, snapName string) (string, error) {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := fmt.Sprintf("virsh snapshot-info %s %s",
+		internalssh.ShellQuote(vmName), internalssh.ShellQuote(snapName))
+	output, err := client.Run(ctx, internalssh.OpQuery, cmd)
+	if err != nil {
+		return "", virshErr(output, fmt.Errorf("snapshot-info: %s", output))
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "Location" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", fmt.Errorf("snapshot-info %s: no location", snapName)
+}
+
+// SnapshotExternalDeletable 宿主机 libvirtd 能否直接删除外部快照（snapshot-delete 自 libvirt 9.0 起支持外部快照）
+// 不支持时外部快照只能通过 SnapshotCommit 合并后删除
+func (m *Manager) SnapshotExternalDeletable(ctx context.Context, hostID string) (bool, error) {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return false, err
+	}
+
+	output, err := client.Run(ctx, internalssh.OpQuery, "virsh version --daemon")
+	if err != nil {
+		return false, virshErr(output, fmt.Errorf("virsh version: %s", output))
+	}
+	major, ok := parseDaemonVersion(output)
+	if !ok {
+		return false, fmt.Errorf("cannot determine the libvirtd version: %s", strings.TrimSpace(output))
+	}
+	return major >= 9, nil
+}
+
+// parseDaemonVersion 从 virsh version --daemon 输出中取 libvirtd 主版本号
+func parseDaemonVersion(output string) (int, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		v, ok := strings.CutPrefix(strings.TrimSpace(line), "Running against daemon: ")
+		if !ok {
+			continue
+		}
+		major, _, _ := strings.Cut(strings.TrimSpace(v), ".")
+		n, err := strconv.Atoi(major)
+		return n, err == nil
+	}
+	return 0, false
+}
+
 // SnapshotRevert 恢复到指定快照
-func (m *Manager) SnapshotRevert(hostID, vmName, snapName string) error {
+func (m *Manager) SnapshotRevert(ctx context.Context, hostID, vmName, snapName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -63,13 +205,156 @@ func (m *Manager) SnapshotRevert(hostID, vmName, snapName string) error {
 
 	cmd := fmt.Sprintf("virsh snapshot-revert %s %s",
 		internalssh.ShellQuote(vmName), internalssh.ShellQuote(snapName))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpSnapshot, cmd)
 	if err != nil {
-		return fmt.Errorf("snapshot-revert: %s", output)
+		return virshErr(output, fmt.Errorf("snapshot-revert: %s", output))
 	}
 	return nil
 }
 
+// SnapshotCommit 将外部快照链合并回基础镜像（blockcommit），之后删除外部快照的元数据和 overlay 文件
+// 只合并外部快照记录的 overlay：从磁盘当前镜像沿 backing chain 向下，遇到第一个不属于快照的镜像即为 Base，
+// 因此模板创建的 VM 不会把数据写入共享的模板镜像。运行中的 VM 使用 virsh blockcommit --active --pivot，
+// 关机的 VM 使用 qemu-img commit 并把磁盘改回 Base
+func (m *Manager) SnapshotCommit(ctx context.Context, hostID, vmName string, progress func(detail string)) (*SnapshotCommitResult, error) {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return nil, err
+	}
+	q := internalssh.ShellQuote(vmName)
+
+	nodes, _, _, err := snapshotDump(ctx, client, vmName)
+	if err != nil {
+		return nil, err
+	}
+	overlays := make(map[string]bool)
+	for _, n := range nodes {
+		for _, d := range n.Disks {
+			if d.Snapshot == "external" && d.File != "" {
+				overlays[d.File] = true
+			}
+		}
+	}
+	if len(overlays) == 0 {
+		return nil, fmt.Errorf("vm %s has no external snapshots to commit", vmName)
+	}
+
+	state, err := client.Run(ctx, internalssh.OpQuery, "virsh domstate "+q)
+	if err != nil {
+		return nil, virshErr(state, fmt.Errorf("domstate: %s", state))
+	}
+	state = strings.TrimSpace(state)
+	active := state == "running" || state == "paused"
+
+	xmlOutput, err := client.Run(ctx, internalssh.OpQuery, "virsh dumpxml "+q)
+	if err != nil {
+		return nil, virshErr(xmlOutput, fmt.Errorf("virsh dumpxml: %s", xmlOutput))
+	}
+	domain, err := parseDumpXML(xmlOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SnapshotCommitResult{}
+	committed := make(map[string]bool)
+	for _, d := range domain.Devices.Disks {
+		if d.Source.File == "" || !overlays[d.Source.File] {
+			continue
+		}
+		out, err := client.Run(ctx, internalssh.OpQuery, "qemu-img info --backing-chain -U --output=json "+internalssh.ShellQuote(d.Source.File))
+		if err != nil {
+			return result, fmt.Errorf("qemu-img info %s: %s", d.Source.File, out)
+		}
+		var chain []struct {
+			Filename string `json:"filename"`
+			Format   string `json:"format"`
+		}
+		if err := json.Unmarshal([]byte(out), &chain); err != nil {
+			return result, fmt.Errorf("parse backing chain of %s: %w", d.Source.File, err)
+		}
+		i := 0
+		for i < len(chain) && overlays[chain[i].Filename] {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		if i == len(chain) {
+			return result, fmt.Errorf("disk %s has no base image below its snapshot overlays", d.Target.Dev)
+		}
+		disk := SnapshotCommitDisk{Target: d.Target.Dev, Base: chain[i].Filename}
+		for _, img := range chain[:i] {
+			disk.Overlays = append(disk.Overlays, img.Filename)
+		}
+		progress(fmt.Sprintf("committing %d overlay(s) of %s into %s", i, disk.Target, disk.Base))
+
+		dev, base := internalssh.ShellQuote(disk.Target), internalssh.ShellQuote(disk.Base)
+		if active {
+			cmd := fmt.Sprintf("virsh blockcommit %s %s --active --base %s --pivot --wait --verbose", q, dev, base)
+			if out, err := client.Run(ctx, internalssh.OpDisk, cmd); err != nil {
+				// 任务取消、操作超时或连接中断时 libvirt 侧的块作业可能仍在进行，一律中止，
+				// 磁盘停留在 overlay 上，否则下一次合并会因 "block job already active" 失败；
+				// 中止命令不随已取消的 ctx 中断
+				client.Run(context.WithoutCancel(ctx), internalssh.OpConfig, fmt.Sprintf("virsh blockjob %s %s --abort", q, dev))
+				if ctx.Err() != nil {
+					return result, ctx.Err()
+				}
+				return result, fmt.Errorf("blockcommit %s: %s", disk.Target, out)
+			}
+		} else {
+			cmd := fmt.Sprintf("qemu-img commit -b %s %s", base, internalssh.ShellQuote(d.Source.File))
+			if out, err := client.Run(ctx, internalssh.OpDisk, cmd); err != nil {
+				return result, fmt.Errorf("qemu-img commit %s: %s", disk.Target, out)
+			}
+			cmd = fmt.Sprintf("virt-xml %s --edit target=%s --disk %s", q, dev,
+				internalssh.ShellQuote(fmt.Sprintf("path=%s,driver.type=%s", disk.Base, chain[i].Format)))
+			if out, err := client.Run(ctx, internalssh.OpConfig, cmd); err != nil {
+				return result, fmt.Errorf("switch %s to base image: %s", disk.Target, out)
+			}
+		}
+		for _, f := range disk.Overlays {
+			committed[f] = true
+		}
+		result.Disks = append(result.Disks, disk)
+	}
+	if len(result.Disks) == 0 {
+		return result, fmt.Errorf("no disk of %s is running on a snapshot overlay", vmName)
+	}
+	m.Invalidate(hostID)
+
+	// overlay 均已合并的外部快照只保留了元数据，先删子快照再删父快照
+	sorted := sortSnapshotTree(nodes)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		n := sorted[i]
+		external, merged := false, true
+		for _, d := range n.Disks {
+			if d.Snapshot == "external" {
+				external = true
+				merged = merged && committed[d.File]
+			}
+		}
+		if !external || !merged {
+			continue
+		}
+		cmd := fmt.Sprintf("virsh snapshot-delete %s %s --metadata", q, internalssh.ShellQuote(n.Name))
+		if out, err := client.Run(ctx, internalssh.OpSnapshot, cmd); err != nil {
+			return result, fmt.Errorf("snapshot-delete %s: %s", n.Name, out)
+		}
+		result.Snapshots = append(result.Snapshots, n.Name)
+	}
+
+	progress("removing merged overlay files")
+	for _, disk := range result.Disks {
+		for _, f := range disk.Overlays {
+			if out, err := client.Run(ctx, internalssh.OpDisk, "rm -f "+internalssh.ShellQuote(f)); err != nil {
+				return result, fmt.Errorf("remove %s: %s", f, out)
+			}
+			result.Removed = append(result.Removed, f)
+		}
+	}
+	return result, nil
+}
+
 // parseSnapshotList 解析 virsh snapshot-list 输出
 func parseSnapshotList(output string) []Snapshot {
 	var snapshots []Snapshot
@@ -96,3 +381,282 @@ func parseSnapshotList(output string) []Snapshot {
 	}
 	return snapshots
 }
+
+// SnapshotTree 获取快照树：逐个读取 snapshot-info 和 snapshot-dumpxml，
+// 再通过 qemu-img snapshot -l（内部快照的内存状态）和 stat（外部快照文件）统计大小
+func (m *Manager) SnapshotTree(ctx context.Context, hostID, vmName string) ([]SnapshotNode, error) {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, images, files, err := snapshotDump(ctx, client, vmName)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	// 统计大小失败不影响快照树
+	if len(images) > 0 || len(files) > 0 {
+		var b strings.Builder
+		for _, img := range images {
+			fmt.Fprintf(&b, "echo %s; qemu-img snapshot -l -U %s 2>/dev/null; ",
+				internalssh.ShellQuote("@@IMAGE "+img), internalssh.ShellQuote(img))
+		}
+		if len(files) > 0 {
+			b.WriteString("stat -c '@@STAT %s %n'")
+			for _, f := range files {
+				b.WriteString(" " + internalssh.ShellQuote(f))
+			}
+			b.WriteString(" 2>/dev/null; ")
+		}
+		b.WriteString("true")
+		if out, err := client.Run(ctx, internalssh.OpQuery, b.String()); err == nil {
+			applySnapshotSizes(nodes, out)
+		}
+	}
+	return sortSnapshotTree(nodes), nil
+}
+
+// snapshotDump 逐个读取快照的 snapshot-info 和 snapshot-dumpxml 并解析，结果为 snapshot-list 的顺序
+func snapshotDump(ctx context.Context, client *internalssh.Client, vmName string) (nodes []*SnapshotNode, images, files []string, err error) {
+	vq := internalssh.ShellQuote(vmName)
+	script := fmt.Sprintf(`virsh snapshot-list --name %s | while IFS= read -r s; do
+	[ -n "$s" ] || continue
+	echo "@@SNAPSHOT $s"
+	virsh snapshot-info %s "$s"
+	echo "@@XML"
+	virsh snapshot-dumpxml %s "$s"
+done`, vq, vq, vq)
+	output, err := client.Run(ctx, internalssh.OpQuery, script)
+	if err != nil {
+		return nil, nil, nil, virshErr(output, fmt.Errorf("snapshot-list: %s", output))
+	}
+	nodes, images, files = parseSnapshotDump(output)
+	return nodes, images, files, nil
+}
+
+// snapshotXML virsh snapshot-dumpxml 的 XML 结构
+type snapshotXML struct {
+	XMLName      xml.Name `xml:"domainsnapshot"`
+	Name         string   `xml:"name"`
+	Description  string   `xml:"description"`
+	State        string   `xml:"state"`
+	Parent       string   `xml:"parent>name"`
+	CreationTime int64    `xml:"creationTime"`
+	Memory       struct {
+		Snapshot string `xml:"snapshot,attr"`
+		File     string `xml:"file,attr"`
+	} `xml:"memory"`
+	Disks []struct {
+		Name     string           `xml:"name,attr"`
+		Snapshot string           `xml:"snapshot,attr"`
+		Source   DomainDiskSource `xml:"source"`
+	} `xml:"disks>disk"`
+	Domain DomainXML `xml:"domain"`
+}
+
+// parseSnapshotDump 解析 SnapshotTree 脚本输出，返回快照以及需要统计大小的 qcow2 镜像（内部快照）和文件（外部快照）
+func parseSnapshotDump(output string) (nodes []*SnapshotNode, images, files []string) {
+	seenImage := make(map[string]bool)
+	seenFile := make(map[string]bool)
+	addFile := func(f string) {
+		if f != "" && !seenFile[f] {
+			seenFile[f] = true
+			files = append(files, f)
+		}
+	}
+
+	for _, block := range strings.Split(output, "@@SNAPSHOT ")[1:] {
+		head, body, _ := strings.Cut(block, "\n")
+		info, dump, _ := strings.Cut(body, "@@XML\n")
+		node := &SnapshotNode{Name: strings.TrimSpace(head)}
+		for _, line := range strings.Split(info, "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(key) {
+			case "Current":
+				node.Current = value == "yes"
+			case "Location":
+				node.Location = value
+			case "State":
+				node.State = value
+			case "Parent":
+				if value != "-" {
+					node.Parent = value
+				}
+			}
+		}
+
+		var doc snapshotXML
+		if err := xml.Unmarshal([]byte(dump), &doc); err == nil {
+			node.Description = doc.Description
+			if doc.State != "" {
+				node.State = doc.State
+			}
+			if doc.Parent != "" {
+				node.Parent = doc.Parent
+			}
+			if doc.CreationTime > 0 {
+				node.CreatedAt = time.Unix(doc.CreationTime, 0).Format("2006-01-02 15:04:05")
+			}
+			switch doc.Memory.Snapshot {
+			case "internal":
+				node.Memory = true
+			case "external":
+				node.Memory = true
+				node.MemoryFile = doc.Memory.File
+				addFile(doc.Memory.File)
+			}
+
+			// 内部快照的磁盘镜像来自快照中保存的 VM 定义
+			sources := make(map[string]DomainDisk)
+			for _, d := range doc.Domain.Devices.Disks {
+				sources[d.Target.Dev] = d
+			}
+			for _, d := range doc.Disks {
+				disk := SnapshotDisk{Name: d.Name, Snapshot: d.Snapshot, File: d.Source.File}
+				switch d.Snapshot {
+				case "internal":
+					src := sources[d.Name]
+					disk.File = src.Source.File
+					if src.Driver.Type == "qcow2" && disk.File != "" && !seenImage[disk.File] {
+						seenImage[disk.File] = true
+						images = append(images, disk.File)
+					}
+				case "external":
+					addFile(disk.File)
+				}
+				node.Disks = append(node.Disks, disk)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, images, files
+}
+
+// applySnapshotSizes 按 qemu-img snapshot -l 和 stat 的输出填写快照大小
+// 内部快照取各镜像中同名快照内存状态（VM SIZE）的最大值，外部快照累加内存文件和 overlay 文件
+func applySnapshotSizes(nodes []*SnapshotNode, output string) {
+	vmSize := make(map[string]int64)   // 快照名称 -> 内存状态大小
+	fileSize := make(map[string]int64) // 文件 -> 大小
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "@@STAT "); ok {
+			size, name, _ := strings.Cut(rest, " ")
+			if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+				fileSize[name] = n
+			}
+			continue
+		}
+		if tag, size, ok := parseQemuImgSnapshot(line); ok && size > vmSize[tag] {
+			vmSize[tag] = size
+		}
+	}
+
+	for _, node := range nodes {
+		if node.Location != "external" {
+			node.SizeBytes = vmSize[node.Name]
+			continue
+		}
+		node.SizeBytes = fileSize[node.MemoryFile]
+		for _, d := range node.Disks {
+			if d.Snapshot == "external" {
+				node.SizeBytes += fileSize[d.File]
+			}
+		}
+	}
+}
+
+// parseQemuImgSnapshot 解析 qemu-img snapshot -l 的一行，返回快照名称和内存状态大小
+// 新版本形如 "1  snap1  285 MiB 2024-05-01 10:00:00 00:01:02.123  0"，旧版本大小形如 "285M" 或 "0"
+func parseQemuImgSnapshot(line string) (string, int64, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return "", 0, false
+	}
+	if _, err := strconv.Atoi(fields[0]); err != nil {
+		return "", 0, false
+	}
+	date := -1
+	for i := 2; i < len(fields); i++ {
+		if _, err := time.Parse("2006-01-02", fields[i]); err == nil {
+			date = i
+			break
+		}
+	}
+	if date < 3 {
+		return "", 0, false
+	}
+	size, ok := parseQemuSize(strings.Join(fields[2:date], ""))
+	return fields[1], size, ok
+}
+
+// parseQemuSize 解析 qemu-img 输出的大小，如 "285MiB"、"285M"、"1.5G"、"0B"、"0"
+func parseQemuSize(s string) (int64, bool) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, false
+	}
+	unit := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s[i:]), "IB"), "B")
+	mult := map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}[unit]
+	if mult == 0 {
+		return 0, false
+	}
+	return int64(n * mult), true
+}
+
+// sortSnapshotTree 按父子关系整理为深度优先顺序，同级按创建时间排序；父快照不存在的视为根
+func sortSnapshotTree(nodes []*SnapshotNode) []SnapshotNode {
+	byName := make(map[string]*SnapshotNode, len(nodes))
+	for _, n := range nodes {
+		byName[n.Name] = n
+	}
+	children := make(map[string][]*SnapshotNode)
+	var roots []*SnapshotNode
+	for _, n := range nodes {
+		if n.Parent != "" && byName[n.Parent] != nil {
+			children[n.Parent] = append(children[n.Parent], n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	byTime := func(list []*SnapshotNode) {
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].CreatedAt != list[j].CreatedAt {
+				return list[i].CreatedAt < list[j].CreatedAt
+			}
+			return list[i].Name < list[j].Name
+		})
+	}
+
+	out := make([]SnapshotNode, 0, len(nodes))
+	var walk func(n *SnapshotNode, depth int)
+	walk = func(n *SnapshotNode, depth int) {
+		kids := children[n.Name]
+		byTime(kids)
+		n.Depth = depth
+		n.Children = make([]string, 0, len(kids))
+		for _, k := range kids {
+			n.Children = append(n.Children, k.Name)
+		}
+		out = append(out, *n)
+		for _, k := range kids {
+			walk(k, depth+1)
+		}
+	}
+	byTime(roots)
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	return out
+}
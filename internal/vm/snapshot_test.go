@@ -0,0 +1,278 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQemuImgSnapshot(t *testing.T) {
+	tests := []struct {
+		line string
+		tag  string
+		size int64
+		ok   bool
+	}{
+		// qemu-img 5.0 之后
+		{"1         snap1             285 MiB 2024-05-01 10:00:00 00:01:02.123          0", "snap1", 285 << 20, true},
+		{"2         disk-only           0 B 2024-05-02 11:00:00 00:00:00.000", "disk-only", 0, true},
+		{"3         big               1.5 GiB 2024-05-03 12:00:00 00:10:00.000          0", "big", 3 << 29, true},
+		{"4         vmcat-auto-20240504-010000  512 KiB 2024-05-04 01:00:00 00:00:01.000  0", "vmcat-auto-20240504-010000", 512 << 10, true},
+		// 旧版本
+		{"1         snap1                  285M 2024-05-01 10:00:00   00:01:02.123", "snap1", 285 << 20, true},
+		{"2         disk-only                 0 2024-05-02 11:00:00   00:00:00.000", "disk-only", 0, true},
+		// 表头和无关行
+		{"Snapshot list:", "", 0, false},
+		{"ID        TAG               VM SIZE                DATE     VM CLOCK     ICOUNT", "", 0, false},
+		{"ID        TAG                 VM SIZE                DATE       VM CLOCK", "", 0, false},
+		{"@@IMAGE /var/lib/libvirt/images/web.qcow2", "", 0, false},
+		{"", "", 0, false},
+		// 大小无法解析
+		{"1         snap1             285 XB 2024-05-01 10:00:00 00:01:02.123          0", "snap1", 0, false},
+	}
+	for _, tt := range tests {
+		tag, size, ok := parseQemuImgSnapshot(tt.line)
+		if ok != tt.ok || (ok && (tag != tt.tag || size != tt.size)) {
+			t.Errorf("parseQemuImgSnapshot(%q) = %q, %d, %v; want %q, %d, %v", tt.line, tag, size, ok, tt.tag, tt.size, tt.ok)
+		}
+	}
+}
+
+func TestParseDaemonVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		major  int
+		ok     bool
+	}{
+		{"Compiled against library: libvirt 8.0.0\nUsing library: libvirt 8.0.0\nUsing API: QEMU 8.0.0\nRunning hypervisor: QEMU 6.2.0\nRunning against daemon: 8.0.0\n", 8, true},
+		{"Running against daemon: 9.0.0", 9, true},
+		{"Running against daemon: 10.10.0\n", 10, true},
+		{"Compiled against library: libvirt 9.0.0\nUsing library: libvirt 9.0.0\n", 0, false},
+		{"Running against daemon: unknown", 0, false},
+	}
+	for _, tt := range tests {
+		major, ok := parseDaemonVersion(tt.output)
+		if ok != tt.ok || major != tt.major {
+			t.Errorf("parseDaemonVersion(%q) = %d, %v; want %d, %v", tt.output, major, ok, tt.major, tt.ok)
+		}
+	}
+}
+
+func TestParseQemuSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"0B", 0, true},
+		{"512", 512, true},
+		{"4KiB", 4 << 10, true},
+		{"285M", 285 << 20, true},
+		{"285MiB", 285 << 20, true},
+		{"1.5G", 3 << 29, true},
+		{"2TiB", 2 << 40, true},
+		{"", 0, false},
+		{"MiB", 0, false},
+		{"10X", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseQemuSize(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseQemuSize(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// snapshotDumpOutput SnapshotTree 脚本的输出：内部快照 snap1（含内存）、外部快照 snap2（内存文件 + overlay）、
+// 外部快照 snap3（snapshot-dumpxml 失败）
+const snapshotDumpOutput = `@@SNAPSHOT snap1
+Name:           snap1
+Domain:         web
+Current:        no
+State:          running
+Location:       internal
+Parent:         -
+Children:       1
+Descendants:    2
+Metadata:       yes
+@@XML
+<domainsnapshot>
+  <name>snap1</name>
+  <description>before upgrade</description>
+  <state>running</state>
+  <creationTime>1714557600</creationTime>
+  <memory snapshot='internal'/>
+  <disks>
+    <disk name='vda' snapshot='internal'/>
+    <disk name='sda' snapshot='no'/>
+  </disks>
+  <domain type='kvm'>
+    <name>web</name>
+    <devices>
+      <disk type='file' device='disk'>
+        <driver name='qemu' type='qcow2'/>
+        <source file='/var/lib/libvirt/images/web.qcow2'/>
+        <target dev='vda' bus='virtio'/>
+      </disk>
+      <disk type='file' device='cdrom'>
+        <driver name='qemu' type='raw'/>
+        <source file='/iso/debian.iso'/>
+        <target dev='sda' bus='sata'/>
+      </disk>
+    </devices>
+  </domain>
+</domainsnapshot>
+@@SNAPSHOT snap2
+Name:           snap2
+Domain:         web
+Current:        no
+State:          running
+Location:       external
+Parent:         snap1
+Children:       1
+Descendants:    1
+Metadata:       yes
+@@XML
+<domainsnapshot>
+  <name>snap2</name>
+  <state>running</state>
+  <parent>
+    <name>snap1</name>
+  </parent>
+  <creationTime>1714644000</creationTime>
+  <memory snapshot='external' file='/var/lib/libvirt/images/web.snap2.mem'/>
+  <disks>
+    <disk name='vda' snapshot='external' type='file'>
+      <driver type='qcow2'/>
+      <source file='/var/lib/libvirt/images/web.snap2'/>
+    </disk>
+  </disks>
+  <domain type='kvm'>
+    <name>web</name>
+  </domain>
+</domainsnapshot>
+@@SNAPSHOT snap3
+Name:           snap3
+Domain:         web
+Current:        yes
+State:          disk-snapshot
+Location:       external
+Parent:         snap2
+Children:       0
+Descendants:    0
+Metadata:       yes
+@@XML
+error: failed to get domain snapshot 'snap3'
+`
+
+func TestParseSnapshotDump(t *testing.T) {
+	nodes, images, files := parseSnapshotDump(snapshotDumpOutput)
+
+	created := func(unix int64) string { return time.Unix(unix, 0).Format("2006-01-02 15:04:05") }
+	want := []SnapshotNode{
+		{
+			Name: "snap1", Description: "before upgrade", State: "running", CreatedAt: created(1714557600),
+			Location: "internal", Memory: true,
+			Disks: []SnapshotDisk{
+				{Name: "vda", Snapshot: "internal", File: "/var/lib/libvirt/images/web.qcow2"},
+				{Name: "sda", Snapshot: "no"},
+			},
+		},
+		{
+			Name: "snap2", Parent: "snap1", State: "running", CreatedAt: created(1714644000),
+			Location: "external", Memory: true, MemoryFile: "/var/lib/libvirt/images/web.snap2.mem",
+			Disks: []SnapshotDisk{{Name: "vda", Snapshot: "external", File: "/var/lib/libvirt/images/web.snap2"}},
+		},
+		// XML 读取失败时保留 snapshot-info 中的信息
+		{Name: "snap3", Parent: "snap2", State: "disk-snapshot", Current: true, Location: "external"},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d", len(nodes), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(*nodes[i], want[i]) {
+			t.Errorf("node %d:\n got %+v\nwant %+v", i, *nodes[i], want[i])
+		}
+	}
+
+	// 只有 qcow2 镜像能保存内部快照，cdrom 不参与统计
+	if !reflect.DeepEqual(images, []string{"/var/lib/libvirt/images/web.qcow2"}) {
+		t.Errorf("images = %q", images)
+	}
+	if !reflect.DeepEqual(files, []string{"/var/lib/libvirt/images/web.snap2.mem", "/var/lib/libvirt/images/web.snap2"}) {
+		t.Errorf("files = %q", files)
+	}
+
+	if nodes, _, _ := parseSnapshotDump(""); len(nodes) != 0 {
+		t.Errorf("empty output gave %d nodes", len(nodes))
+	}
+}
+
+func TestApplySnapshotSizes(t *testing.T) {
+	nodes, _, _ := parseSnapshotDump(snapshotDumpOutput)
+	output := `@@IMAGE /var/lib/libvirt/images/web.qcow2
+Snapshot list:
+ID        TAG               VM SIZE                DATE     VM CLOCK     ICOUNT
+1         snap1             285 MiB 2024-05-01 10:00:00 00:01:02.123          0
+@@IMAGE /var/lib/libvirt/images/data.qcow2
+Snapshot list:
+ID        TAG               VM SIZE                DATE     VM CLOCK     ICOUNT
+1         snap1             0 B 2024-05-01 10:00:00 00:01:02.123          0
+@@STAT 1073741824 /var/lib/libvirt/images/web.snap2.mem
+@@STAT 52428800 /var/lib/libvirt/images/web.snap2
+`
+	applySnapshotSizes(nodes, output)
+
+	want := map[string]int64{
+		"snap1": 285 << 20,             // 各镜像中同名快照取最大值
+		"snap2": 1073741824 + 52428800, // 内存文件 + overlay
+		"snap3": 0,                     // 无法统计
+	}
+	for _, n := range nodes {
+		if n.SizeBytes != want[n.Name] {
+			t.Errorf("%s: SizeBytes = %d, want %d", n.Name, n.SizeBytes, want[n.Name])
+		}
+	}
+}
+
+func TestSnapshotCommitCancelAbortsBlockJob(t *testing.T) {
+	const overlay = "/var/lib/libvirt/images/web.vda.s1.qcow2"
+	pool, host := newFakeHost(t, func(cmd string) fakeReply {
+		switch {
+		case strings.HasPrefix(cmd, "virsh snapshot-list --name"):
+			return fakeReply{output: "@@SNAPSHOT s1\nName: s1\nLocation: external\n@@XML\n" +
+				"<domainsnapshot><name>s1</name><disks><disk name='vda' snapshot='external'><source file='" + overlay + "'/></disk></disks></domainsnapshot>\n"}
+		case strings.HasPrefix(cmd, "virsh domstate"):
+			return fakeReply{output: "running\n"}
+		case strings.HasPrefix(cmd, "virsh dumpxml"):
+			return fakeReply{output: "<domain type='kvm'><name>web</name><devices><disk type='file' device='disk'>" +
+				"<driver name='qemu' type='qcow2'/><source file='" + overlay + "'/><target dev='vda' bus='virtio'/></disk></devices></domain>\n"}
+		case strings.HasPrefix(cmd, "qemu-img info"):
+			return fakeReply{output: `[{"filename":"` + overlay + `","format":"qcow2"},{"filename":"/var/lib/libvirt/images/web.qcow2","format":"qcow2"}]`}
+		case strings.HasPrefix(cmd, "virsh blockcommit"):
+			return fakeReply{block: true}
+		}
+		return fakeReply{}
+	}, "h1")
+	m := NewManager(pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.SnapshotCommit(ctx, "h1", "web", func(string) {})
+		done <- err
+	}()
+	host.waitFor(t, "virsh blockcommit ")
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want canceled", err)
+	}
+	if !host.ran("virsh blockjob 'web' 'vda' --abort") {
+		t.Error("canceled block commit was not aborted")
+	}
+}
@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -24,16 +25,16 @@ var (
 	cpuCacheMap = make(map[string]*cpuSample) // key: hostID/vmName
 )
 
-// VMStats 获取 VM 实时资源统计
-func (m *Manager) VMStats(hostID, vmName string) (*VMResourceStats, error) {
+// VMStats 获取 VM 实时资源统计，ctx 可携带命令优先级
+func (m *Manager) VMStats(ctx context.Context, hostID, vmName string) (*VMResourceStats, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := client.Execute(fmt.Sprintf("virsh domstats %s --raw", internalssh.ShellQuote(vmName)))
+	output, err := client.Run(ctx, internalssh.OpStats, fmt.Sprintf("virsh domstats %s --raw", internalssh.ShellQuote(vmName)))
 	if err != nil {
-		return nil, fmt.Errorf("domstats: %w", err)
+		return nil, virshErr(output, fmt.Errorf("domstats: %w", err))
 	}
 
 	stats := parseDomstats(output)
@@ -104,31 +105,65 @@ func parseDomstats(output string) *VMResourceStats {
 	stats.MemActual = parseUint64(kv["balloon.current"]) * 1024 // KiB -> bytes
 	stats.MemRSS = parseUint64(kv["balloon.rss"]) * 1024
 
-	// 聚合所有网卡
-	for i := 0; ; i++ {
-		rxKey := fmt.Sprintf("net.%d.rx.bytes", i)
-		txKey := fmt.Sprintf("net.%d.tx.bytes", i)
-		if _, ok := kv[rxKey]; !ok {
-			break
+	// 各网卡明细及合计；计数器对单个设备是可选的，没有计数器的设备跳过
+	for i := 0; i < int(parseUint64(kv["net.count"])); i++ {
+		prefix := fmt.Sprintf("net.%d.", i)
+		if !hasAny(kv, prefix+"rx.bytes", prefix+"tx.bytes") {
+			continue
+		}
+		nic := InterfaceStats{
+			Name:    kv[prefix+"name"],
+			RxBytes: parseUint64(kv[prefix+"rx.bytes"]),
+			RxPkts:  parseUint64(kv[prefix+"rx.pkts"]),
+			RxDrop:  parseUint64(kv[prefix+"rx.drop"]),
+			TxBytes: parseUint64(kv[prefix+"tx.bytes"]),
+			TxPkts:  parseUint64(kv[prefix+"tx.pkts"]),
+			TxDrop:  parseUint64(kv[prefix+"tx.drop"]),
+		}
+		if nic.Name == "" {
+			nic.Name = fmt.Sprintf("net%d", i)
 		}
-		stats.NetRxBytes += parseUint64(kv[rxKey])
-		stats.NetTxBytes += parseUint64(kv[txKey])
+		stats.Interfaces = append(stats.Interfaces, nic)
+		stats.NetRxBytes += nic.RxBytes
+		stats.NetTxBytes += nic.TxBytes
 	}
 
-	// 聚合所有块设备
-	for i := 0; ; i++ {
-		rdKey := fmt.Sprintf("block.%d.rd.bytes", i)
-		wrKey := fmt.Sprintf("block.%d.wr.bytes", i)
-		if _, ok := kv[rdKey]; !ok {
-			break
+	// 各块设备明细及合计
+	for i := 0; i < int(parseUint64(kv["block.count"])); i++ {
+		prefix := fmt.Sprintf("block.%d.", i)
+		if !hasAny(kv, prefix+"rd.bytes", prefix+"wr.bytes") {
+			continue
+		}
+		disk := DiskStats{
+			Name:     kv[prefix+"name"],
+			RdBytes:  parseUint64(kv[prefix+"rd.bytes"]),
+			WrBytes:  parseUint64(kv[prefix+"wr.bytes"]),
+			RdReqs:   parseUint64(kv[prefix+"rd.reqs"]),
+			WrReqs:   parseUint64(kv[prefix+"wr.reqs"]),
+			RdTimeNs: parseUint64(kv[prefix+"rd.times"]),
+			WrTimeNs: parseUint64(kv[prefix+"wr.times"]),
 		}
-		stats.BlockRdBytes += parseUint64(kv[rdKey])
-		stats.BlockWrBytes += parseUint64(kv[wrKey])
+		if disk.Name == "" {
+			disk.Name = fmt.Sprintf("block%d", i)
+		}
+		stats.Disks = append(stats.Disks, disk)
+		stats.BlockRdBytes += disk.RdBytes
+		stats.BlockWrBytes += disk.WrBytes
 	}
 
 	return stats
 }
 
+// hasAny kv 中是否存在任一键
+func hasAny(kv map[string]string, keys ...string) bool {
+	for _, k := range keys {
+		if _, ok := kv[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func parseUint64(s string) uint64 {
 	v, _ := strconv.ParseUint(s, 10, 64)
 	return v
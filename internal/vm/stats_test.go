@@ -0,0 +1,55 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDomstats(t *testing.T) {
+	// 第一块网卡和 sda（空光驱）没有计数器，其后的设备仍须统计
+	output := `Domain: 'web01'
+  cpu.time=123456789
+  vcpu.current=2
+  balloon.current=2097152
+  balloon.rss=1048576
+  net.count=2
+  net.0.name=vnet0
+  net.1.name=vnet1
+  net.1.rx.bytes=1000
+  net.1.rx.pkts=10
+  net.1.rx.drop=1
+  net.1.tx.bytes=2000
+  net.1.tx.pkts=20
+  net.1.tx.drop=0
+  block.count=3
+  block.0.name=sda
+  block.1.name=vda
+  block.1.rd.bytes=4096
+  block.1.rd.reqs=4
+  block.1.rd.times=100
+  block.1.wr.bytes=8192
+  block.1.wr.reqs=8
+  block.1.wr.times=200
+  block.2.name=vdb
+  block.2.wr.bytes=512
+`
+	stats := parseDomstats(output)
+
+	if stats.CPUTime != 123456789 || stats.VCPUs != 2 || stats.MemActual != 2<<30 || stats.MemRSS != 1<<30 {
+		t.Errorf("cpu/memory = %d, %d, %d, %d", stats.CPUTime, stats.VCPUs, stats.MemActual, stats.MemRSS)
+	}
+	wantNICs := []InterfaceStats{{Name: "vnet1", RxBytes: 1000, RxPkts: 10, RxDrop: 1, TxBytes: 2000, TxPkts: 20}}
+	if !reflect.DeepEqual(stats.Interfaces, wantNICs) {
+		t.Errorf("interfaces = %+v, want %+v", stats.Interfaces, wantNICs)
+	}
+	wantDisks := []DiskStats{
+		{Name: "vda", RdBytes: 4096, WrBytes: 8192, RdReqs: 4, WrReqs: 8, RdTimeNs: 100, WrTimeNs: 200},
+		{Name: "vdb", WrBytes: 512},
+	}
+	if !reflect.DeepEqual(stats.Disks, wantDisks) {
+		t.Errorf("disks = %+v, want %+v", stats.Disks, wantDisks)
+	}
+	if stats.NetRxBytes != 1000 || stats.NetTxBytes != 2000 || stats.BlockRdBytes != 4096 || stats.BlockWrBytes != 8704 {
+		t.Errorf("totals = %d, %d, %d, %d", stats.NetRxBytes, stats.NetTxBytes, stats.BlockRdBytes, stats.BlockWrBytes)
+	}
+}
@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -8,25 +9,21 @@ import (
 )
 
 // PoolList 获取存储池列表
-func (m *Manager) PoolList(hostID string) ([]StoragePool, error) {
-	client, err := m.pool.Get(hostID)
+func (m *Manager) PoolList(ctx context.Context, hostID string) ([]StoragePool, error) {
+	b, err := m.backend(ctx, hostID)
 	if err != nil {
 		return nil, err
 	}
-	output, err := client.Execute("virsh pool-list --all --details")
-	if err != nil {
-		return nil, fmt.Errorf("pool-list: %w", err)
-	}
-	return parsePoolList(output), nil
+	return b.PoolList(ctx)
 }
 
 // VolList 获取存储池中的卷列表
-func (m *Manager) VolList(hostID, poolName string) ([]Volume, error) {
+func (m *Manager) VolList(ctx context.Context, hostID, poolName string) ([]Volume, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return nil, err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh vol-list %s --details", internalssh.ShellQuote(poolName)))
+	output, err := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh vol-list %s --details", internalssh.ShellQuote(poolName)))
 	if err != nil {
 		return nil, fmt.Errorf("vol-list: %w", err)
 	}
@@ -34,7 +31,7 @@ func (m *Manager) VolList(hostID, poolName string) ([]Volume, error) {
 }
 
 // DeleteVolume 删除存储卷
-func (m *Manager) DeleteVolume(hostID, poolName, volName string) error {
+func (m *Manager) DeleteVolume(ctx context.Context, hostID, poolName, volName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -42,7 +39,7 @@ func (m *Manager) DeleteVolume(hostID, poolName, volName string) error {
 
 	cmd := fmt.Sprintf("virsh vol-delete --pool %s %s",
 		internalssh.ShellQuote(poolName), internalssh.ShellQuote(volName))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
 	if err != nil {
 		return fmt.Errorf("vol-delete: %s", output)
 	}
@@ -50,7 +47,7 @@ func (m *Manager) DeleteVolume(hostID, poolName, volName string) error {
 }
 
 // CreateVolume 在存储池中创建新卷
-func (m *Manager) CreateVolume(hostID, poolName, volName string, sizeGB int, format string) (string, error) {
+func (m *Manager) CreateVolume(ctx context.Context, hostID, poolName, volName string, sizeGB int, format string) (string, error) {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return "", err
@@ -61,12 +58,12 @@ func (m *Manager) CreateVolume(hostID, poolName, volName string, sizeGB int, for
 	cmd := fmt.Sprintf("virsh vol-create-as %s %s %dG --format %s",
 		internalssh.ShellQuote(poolName), internalssh.ShellQuote(volName),
 		sizeGB, internalssh.ShellQuote(format))
-	output, err := client.Execute(cmd)
+	output, err := client.Run(ctx, internalssh.OpDisk, cmd)
 	if err != nil {
 		return "", fmt.Errorf("vol-create-as: %s", output)
 	}
 	// 获取卷路径
-	pathOutput, err := client.Execute(fmt.Sprintf("virsh vol-path %s --pool %s",
+	pathOutput, err := client.Run(ctx, internalssh.OpQuery, fmt.Sprintf("virsh vol-path %s --pool %s",
 		internalssh.ShellQuote(volName), internalssh.ShellQuote(poolName)))
 	if err != nil {
 		return "", nil
@@ -75,12 +72,12 @@ func (m *Manager) CreateVolume(hostID, poolName, volName string, sizeGB int, for
 }
 
 // PoolStart 启动存储池
-func (m *Manager) PoolStart(hostID, poolName string) error {
+func (m *Manager) PoolStart(ctx context.Context, hostID, poolName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh pool-start %s", internalssh.ShellQuote(poolName)))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh pool-start %s", internalssh.ShellQuote(poolName)))
 	if err != nil {
 		return fmt.Errorf("pool-start: %s", output)
 	}
@@ -88,12 +85,12 @@ func (m *Manager) PoolStart(hostID, poolName string) error {
 }
 
 // PoolStop 停止存储池
-func (m *Manager) PoolStop(hostID, poolName string) error {
+func (m *Manager) PoolStop(ctx context.Context, hostID, poolName string) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh pool-destroy %s", internalssh.ShellQuote(poolName)))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh pool-destroy %s", internalssh.ShellQuote(poolName)))
 	if err != nil {
 		return fmt.Errorf("pool-stop: %s", output)
 	}
@@ -101,7 +98,7 @@ func (m *Manager) PoolStop(hostID, poolName string) error {
 }
 
 // PoolAutostart 设置存储池自动启动
-func (m *Manager) PoolAutostart(hostID, poolName string, enabled bool) error {
+func (m *Manager) PoolAutostart(ctx context.Context, hostID, poolName string, enabled bool) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -110,13 +107,44 @@ func (m *Manager) PoolAutostart(hostID, poolName string, enabled bool) error {
 	if !enabled {
 		flag = "--no-autostart"
 	}
-	output, err := client.Execute(fmt.Sprintf("virsh pool-autostart %s %s", internalssh.ShellQuote(poolName), flag))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh pool-autostart %s %s", internalssh.ShellQuote(poolName), flag))
 	if err != nil {
 		return fmt.Errorf("pool-autostart: %s", output)
 	}
 	return nil
 }
 
+// PoolCreate 定义目录型存储池，创建目录后启动
+func (m *Manager) PoolCreate(ctx context.Context, hostID, poolName, path string) error {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return err
+	}
+	name := internalssh.ShellQuote(poolName)
+	cmd := fmt.Sprintf("virsh pool-define-as %s dir --target %s && virsh pool-build %s && virsh pool-start %s",
+		name, internalssh.ShellQuote(path), name, name)
+	output, err := client.Run(ctx, internalssh.OpConfig, cmd)
+	if err != nil {
+		return fmt.Errorf("pool-define: %s", output)
+	}
+	return nil
+}
+
+// PoolDelete 停止并删除存储池定义（不删除目录和其中的卷）
+func (m *Manager) PoolDelete(ctx context.Context, hostID, poolName string) error {
+	client, err := m.pool.Get(hostID)
+	if err != nil {
+		return err
+	}
+	// 未启动的存储池 pool-destroy 会失败，忽略
+	client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh pool-destroy %s", internalssh.ShellQuote(poolName)))
+	output, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("virsh pool-undefine %s", internalssh.ShellQuote(poolName)))
+	if err != nil {
+		return fmt.Errorf("pool-undefine: %s", output)
+	}
+	return nil
+}
+
 // parsePoolList 解析 virsh pool-list --all --details 输出
 func parsePoolList(output string) []StoragePool {
 	var pools []StoragePool
@@ -134,26 +162,38 @@ func parsePoolList(output string) []StoragePool {
 			Name:  fields[0],
 			State: fields[1],
 		}
-		if len(fields) >= 3 {
-			p.Autostart = fields[2]
-		}
-		if len(fields) >= 4 {
-			p.Persistent = fields[3]
+		p.Autostart = fields[2]
+		p.Persistent = fields[3]
+		// 容量列形如 "49.09 GiB"，非活动存储池为 "-"
+		sizes := sizeColumns(fields[4:])
+		if len(sizes) >= 1 {
+			p.Capacity = sizes[0]
 		}
-		if len(fields) >= 5 {
-			p.Capacity = fields[4]
+		if len(sizes) >= 2 {
+			p.Allocation = sizes[1]
 		}
-		if len(fields) >= 6 {
-			p.Allocation = fields[5]
-		}
-		if len(fields) >= 7 {
-			p.Available = fields[6]
+		if len(sizes) >= 3 {
+			p.Available = sizes[2]
 		}
 		pools = append(pools, p)
 	}
 	return pools
 }
 
+// sizeColumns 将 "49.09 GiB" 这类被空白拆开的容量字段重新组合
+func sizeColumns(fields []string) []string {
+	var cols []string
+	for i := 0; i < len(fields); i++ {
+		if fields[i] != "-" && i+1 < len(fields) {
+			cols = append(cols, fields[i]+" "+fields[i+1])
+			i++
+			continue
+		}
+		cols = append(cols, fields[i])
+	}
+	return cols
+}
+
 // parseVolList 解析 virsh vol-list --details 输出
 // 格式: Name  Path  Type  Capacity  Allocation
 func parseVolList(output string) []Volume {
@@ -1,8 +1,11 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"strings"
+
+	internalssh "vmcat/internal/ssh"
 )
 
 // TemplateCreateParams 模板创建 VM 的参数
@@ -23,11 +26,12 @@ type TemplateCreateParams struct {
 	// Cloud-init
 	RootPassword string `json:"rootPassword"`
 	SSHPubKey    string `json:"sshPubKey"`
+	UserData     string `json:"userData"` // 自定义 user-data，非空时替代按密码和公钥生成的内容
 }
 
 // CreateFromTemplate 基于模板创建 VM
 // 流程: mkdir -> qemu-img create -b -> virt-install --import
-func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams) error {
+func (m *Manager) CreateFromTemplate(ctx context.Context, hostID string, params *TemplateCreateParams) error {
 	client, err := m.pool.Get(hostID)
 	if err != nil {
 		return err
@@ -37,7 +41,7 @@ func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams
 	systemDisk := instDir + "/system.qcow2"
 
 	// 1. 创建 instance 目录
-	if _, err := client.Execute(fmt.Sprintf("mkdir -p %s/iso", instDir)); err != nil {
+	if _, err := client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("mkdir -p %s/iso", instDir)); err != nil {
 		return fmt.Errorf("mkdir: %w", err)
 	}
 
@@ -46,7 +50,7 @@ func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams
 		"qemu-img create -b %s -F qcow2 -f qcow2 %s %dG",
 		params.BasePath, systemDisk, params.DiskGB,
 	)
-	if output, err := client.Execute(qemuCmd); err != nil {
+	if output, err := client.Run(ctx, internalssh.OpDisk, qemuCmd); err != nil {
 		return fmt.Errorf("qemu-img create: %s", output)
 	}
 
@@ -75,7 +79,7 @@ func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams
 	}
 
 	// Cloud-init: 生成 seed ISO
-	if params.RootPassword != "" || params.SSHPubKey != "" {
+	if params.RootPassword != "" || params.SSHPubKey != "" || params.UserData != "" {
 		seedISO := instDir + "/iso/cloud-init.iso"
 		ciDir := instDir + "/iso/cidata"
 
@@ -99,11 +103,14 @@ func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams
 			ud = append(ud, "disable_root: false")
 		}
 		userData := strings.Join(ud, "\n")
+		if params.UserData != "" {
+			userData = params.UserData
+		}
 
 		// 写入文件
-		client.Execute(fmt.Sprintf("mkdir -p %s", ciDir))
-		client.Execute(fmt.Sprintf("cat > %s/meta-data << 'CIEOF'\n%s\nCIEOF", ciDir, metaData))
-		client.Execute(fmt.Sprintf("cat > %s/user-data << 'CIEOF'\n%s\nCIEOF", ciDir, userData))
+		client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("mkdir -p %s", ciDir))
+		client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("cat > %s/meta-data << 'CIEOF'\n%s\nCIEOF", ciDir, metaData))
+		client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("cat > %s/user-data << 'CIEOF'\n%s\nCIEOF", ciDir, userData))
 
 		// 生成 ISO (尝试 genisoimage, 回退 mkisofs, 再回退 xorriso)
 		genCmd := fmt.Sprintf(
@@ -114,7 +121,7 @@ func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams
 			seedISO, ciDir, ciDir,
 			seedISO, ciDir, ciDir,
 		)
-		if output, err := client.Execute(genCmd); err != nil {
+		if output, err := client.Run(ctx, internalssh.OpConfig, genCmd); err != nil {
 			// cloud-init ISO 生成失败不阻止创建，但记录警告
 			fmt.Printf("[warn] cloud-init ISO generation failed: %s\n", output)
 		} else {
@@ -126,16 +133,16 @@ func (m *Manager) CreateFromTemplate(hostID string, params *TemplateCreateParams
 	args = append(args, "--import", "--noautoconsole", "--graphics", "vnc,listen=0.0.0.0")
 
 	cmd := strings.Join(args, " ")
-	if output, err := client.Execute(cmd); err != nil {
+	if output, err := client.Run(ctx, internalssh.OpDisk, cmd); err != nil {
 		// 创建失败时清理
-		client.Execute(fmt.Sprintf("rm -rf %s", instDir))
+		client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("rm -rf %s", instDir))
 		return fmt.Errorf("virt-install: %s", output)
 	}
 
 	// 4. 写入元信息
 	metadata := fmt.Sprintf(`{"instanceId":%d,"vmName":"%s","flavorCpus":%d,"flavorMemMB":%d,"flavorDiskGB":%d,"basePath":"%s","osVariant":"%s"}`,
 		params.InstanceID, params.VMName, params.CPUs, params.MemoryMB, params.DiskGB, params.BasePath, params.OSVariant)
-	client.Execute(fmt.Sprintf("echo '%s' > %s/metadata.json", metadata, instDir))
+	client.Run(ctx, internalssh.OpConfig, fmt.Sprintf("echo '%s' > %s/metadata.json", metadata, instDir))
 
 	return nil
 }
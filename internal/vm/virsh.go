@@ -70,6 +70,20 @@ type DomainMemory struct {
 	Unit  string `xml:"unit,attr"`
 }
 
+// MB 内存转换为 MB
+func (m DomainMemory) MB() int {
+	switch m.Unit {
+	case "KiB":
+		return m.Value / 1024
+	case "GiB":
+		return m.Value * 1024
+	case "bytes":
+		return m.Value / 1024 / 1024
+	default: // MiB
+		return m.Value
+	}
+}
+
 type DomainDevices struct {
 	Disks      []DomainDisk      `xml:"disk"`
 	Interfaces []DomainInterface `xml:"interface"`
@@ -142,18 +156,7 @@ func domainToDetail(domain *DomainXML, hostID string) *VMDetail {
 		},
 	}
 
-	// 内存转换为 MB
-	mem := domain.Memory.Value
-	switch domain.Memory.Unit {
-	case "KiB":
-		detail.MemoryMB = mem / 1024
-	case "GiB":
-		detail.MemoryMB = mem * 1024
-	case "bytes":
-		detail.MemoryMB = mem / 1024 / 1024
-	default: // MiB
-		detail.MemoryMB = mem
-	}
+	detail.MemoryMB = domain.Memory.MB()
 
 	// 磁盘
 	for _, d := range domain.Devices.Disks {
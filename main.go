@@ -1,17 +1,20 @@
 package main
 
 import (
-	"crypto/rand"
+	"crypto/tls"
 	"embed"
-	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"vmcat/internal/api"
+	"vmcat/internal/metrics"
+	"vmcat/internal/store"
 )
 
 //go:embed all:frontend/dist
@@ -23,6 +26,23 @@ func main() {
 		runServer(os.Args[2:])
 		return
 	}
+	// ctl 子命令：通过 API 管理远程 vmcat serve
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(runCtl(os.Args[2:]))
+	}
+	// plan / apply：vmcat ctl spec plan|apply 的简写
+	if len(os.Args) > 1 && (os.Args[1] == "plan" || os.Args[1] == "apply") {
+		os.Exit(runCtl(append([]string{"spec", os.Args[1]}, os.Args[2:]...)))
+	}
+	// inventory：vmcat ctl inventory 的简写，可直接作为 Ansible 清单脚本
+	if len(os.Args) > 1 && os.Args[1] == "inventory" {
+		os.Exit(runCtl(os.Args[1:]))
+	}
+	// openapi 子命令：输出 API 文档（与 /v1/openapi.json 相同），供 pkg/client 代码生成
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		printOpenAPI()
+		return
+	}
 
 	// 桌面模式
 	runDesktop()
@@ -32,7 +52,12 @@ func main() {
 func runServer(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	port := fs.Int("port", 9600, "API server port")
-	apiKey := fs.String("api-key", "", "API key for authentication (auto-generated if empty)")
+	apiKey := fs.String("api-key", "", "built-in admin token (optional; users and tokens are managed via user.*/token.* actions)")
+	useTLS := fs.Bool("tls", false, "serve HTTPS/WSS (self-signed certificate under ~/.vmcat/tls is generated if none is given)")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file (PEM), implies --tls")
+	tlsKey := fs.String("tls-key", "", "TLS private key file (PEM), implies --tls")
+	tlsClientCA := fs.String("tls-client-ca", "", "CA bundle (PEM) for verifying client certificates; enables mutual TLS, implies --tls")
+	fenceScriptDir := fs.String("fence-script-dir", "", "directory of HA fence scripts; script fencing may only run files in it (default ~/.vmcat/fence)")
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: vmcat serve [options]\n\nOptions:\n")
 		fs.PrintDefaults()
@@ -48,30 +73,57 @@ func runServer(args []string) {
 	if envPort := os.Getenv("VMCAT_PORT"); envPort != "" {
 		fmt.Sscanf(envPort, "%d", port)
 	}
-
-	// 自动生成 API Key
-	if *apiKey == "" {
-		b := make([]byte, 24)
-		rand.Read(b)
-		*apiKey = "vmcat_sk_" + hex.EncodeToString(b)
-		log.Printf("Generated API Key: %s", *apiKey)
+	for flagVal, env := range map[*string]string{
+		tlsCert:        "VMCAT_TLS_CERT",
+		tlsKey:         "VMCAT_TLS_KEY",
+		tlsClientCA:    "VMCAT_TLS_CLIENT_CA",
+		fenceScriptDir: "VMCAT_FENCE_SCRIPT_DIR",
+	} {
+		if *flagVal == "" {
+			*flagVal = os.Getenv(env)
+		}
+	}
+	if *tlsCert != "" || *tlsKey != "" || *tlsClientCA != "" {
+		*useTLS = true
 	}
 
 	// 初始化 App（不启动 Wails）
 	app := NewApp()
+	if *fenceScriptDir != "" {
+		app.fencer.ScriptDir = *fenceScriptDir
+	}
 	if err := app.InitForServe(); err != nil {
 		log.Fatalf("init failed: %v", err)
 	}
 
+	// 首次启动且未指定 --api-key 时创建管理员令牌
+	if *apiKey == "" {
+		if err := bootstrapAdmin(app.store); err != nil {
+			log.Fatalf("bootstrap admin: %v", err)
+		}
+	}
+
 	// 创建 API 服务器
 	srv := api.NewServer(
-		app.dispatch,
-		app.termSrv.HandleTerminal,
-		app.termSrv.HandleVNC,
+		app.serveAPI,
+		app.guardWS("terminal.open", app.termSrv.HandleTerminal),
+		app.guardWS("vnc.open", app.termSrv.HandleVNC),
+		app.events,
 		*port,
-		*apiKey,
+		&authenticator{store: app.store, rootKey: *apiKey},
 		app.AppVersion(),
 	)
+	srv.SetEventFilter(app.eventVisible)
+	srv.SetRoutes(apiRoutes())
+	srv.SetMetrics(app.guardMetrics(metrics.Default.Handler()))
+
+	if *useTLS {
+		cfg, err := loadServerTLS(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			log.Fatalf("tls: %v", err)
+		}
+		srv.SetTLS(cfg)
+	}
 
 	// 优雅关闭
 	go func() {
@@ -88,3 +140,66 @@ func runServer(args []string) {
 		log.Fatalf("server failed: %v", err)
 	}
 }
+
+// loadServerTLS 加载 TLS 配置，未指定证书时使用 ~/.vmcat/tls 下的自签名证书（首次启动生成）
+func loadServerTLS(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		if certFile != "" || keyFile != "" {
+			return nil, fmt.Errorf("--tls-cert and --tls-key must be given together")
+		}
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		certFile = filepath.Join(homeDir, ".vmcat", "tls", "server.crt")
+		keyFile = filepath.Join(homeDir, ".vmcat", "tls", "server.key")
+	}
+
+	cfg, err := api.LoadTLSConfig(api.TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCA: clientCA})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("TLS certificate %s (SHA-256 %s)", certFile, api.CertFingerprint(cfg.Certificates[0]))
+	if clientCA != "" {
+		log.Printf("Client certificates required, verified against %s", clientCA)
+	}
+	return cfg, nil
+}
+
+// printOpenAPI 输出 OpenAPI 文档到标准输出
+func printOpenAPI() {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(api.BuildOpenAPI((&App{}).AppVersion(), apiRoutes())); err != nil {
+		log.Fatalf("openapi: %v", err)
+	}
+}
+
+// bootstrapAdmin 数据库中没有任何用户时创建 admin 用户及令牌
+// 令牌写入 ~/.vmcat/admin.token（仅所有者可读），不输出到日志
+func bootstrapAdmin(s *store.Store) error {
+	n, err := s.UserCount()
+	if err != nil || n > 0 {
+		return err
+	}
+
+	u := &store.User{Name: "admin", Role: api.RoleAdmin}
+	if err := s.UserAdd(u); err != nil {
+		return err
+	}
+	token, _, err := s.TokenCreate(u.ID, "bootstrap", "")
+	if err != nil {
+		return err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(homeDir, ".vmcat", "admin.token")
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return err
+	}
+	log.Printf("Created admin user, token saved to %s", path)
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package client VMCat 服务端模式 (vmcat serve) 的 Go 客户端
+//
+// 每个 action 对应一个类型化方法（见 zz_generated.go，由 OpenAPI 文档生成），
+// 也可通过 Call 按名称调用任意 action：
+//
+//	c := client.New("https://vmcat.example.com:9600", token)
+//	vms, err := c.VMList(ctx, client.HostParams{HostID: "..."})
+package client
+
+//go:generate sh -c "go run -tags headless ../.. openapi > openapi.json"
+//go:generate go run ./internal/gen -in openapi.json -out zz_generated.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client API 客户端，可并发使用
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义 http.Client（如自签名证书、客户端证书等 TLS 配置）
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// New 创建客户端，baseURL 如 http://127.0.0.1:9600，token 为空时不发送认证头
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error 服务端返回的错误
+type Error struct {
+	Action     string // action 名称
+	StatusCode int    // HTTP 状态码
+	Code       int    // 响应中的 code（401 / 403 / 1 等）
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Action, e.Message)
+}
+
+// IsForbidden 是否为权限不足
+func (e *Error) IsForbidden() bool { return e.StatusCode == http.StatusForbidden }
+
+// IsUnauthorized 是否为令牌无效或缺失
+func (e *Error) IsUnauthorized() bool { return e.StatusCode == http.StatusUnauthorized }
+
+type request struct {
+	Action string      `json:"action"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+type response struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Call 通过 /v1/api.json 调用 action，params 为 nil 表示无参数，out 为 nil 时忽略结果
+func (c *Client) Call(ctx context.Context, action string, params, out interface{}) error {
+	body, err := json.Marshal(request{Action: action, Data: params})
+	if err != nil {
+		return fmt.Errorf("%s: encode params: %w", action, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/api.json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: read response: %w", action, err)
+	}
+	var r response
+	if err := json.Unmarshal(data, &r); err != nil {
+		msg := strings.TrimSpace(string(data))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return &Error{Action: action, StatusCode: resp.StatusCode, Message: msg}
+	}
+	if resp.StatusCode != http.StatusOK || r.Code != 0 {
+		return &Error{Action: action, StatusCode: resp.StatusCode, Code: r.Code, Message: r.Msg}
+	}
+	if out == nil || len(r.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Data, out); err != nil {
+		return fmt.Errorf("%s: decode result: %w", action, err)
+	}
+	return nil
+}
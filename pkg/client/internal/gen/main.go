@@ -0,0 +1,241 @@
+// gen 由 OpenAPI 文档（vmcat openapi 的输出）生成 pkg/client 的类型和 action 方法
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"vmcat/internal/api"
+)
+
+// initialisms 方法名中按全大写输出的词
+var initialisms = map[string]string{
+	"api":       "API",
+	"ha":        "HA",
+	"id":        "ID",
+	"iso":       "ISO",
+	"json":      "JSON",
+	"nat":       "NAT",
+	"osvariant": "OSVariant",
+	"vm":        "VM",
+	"xml":       "XML",
+}
+
+const refPrefix = "#/components/schemas/"
+
+func main() {
+	in := flag.String("in", "openapi.json", "OpenAPI document")
+	out := flag.String("out", "zz_generated.go", "output file")
+	flag.Parse()
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc api.OpenAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatalf("parse %s: %v", *in, err)
+	}
+
+	src, err := generate(&doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type generator struct {
+	doc   *api.OpenAPIDoc
+	names map[string]string // components 名称 -> Go 类型名
+	used  map[string]bool   // 需要生成的 components
+	buf   bytes.Buffer
+
+	rawJSON bool // 是否用到 json.RawMessage
+}
+
+func generate(doc *api.OpenAPIDoc) ([]byte, error) {
+	g := &generator{doc: doc, names: make(map[string]string), used: make(map[string]bool)}
+
+	// 类型名去掉包名前缀（store.Host -> Host），重名时报错以免生成的代码含糊
+	owner := make(map[string]string)
+	for name := range doc.Components.Schemas {
+		short := name[strings.LastIndex(name, ".")+1:]
+		if prev, ok := owner[short]; ok {
+			return nil, fmt.Errorf("schemas %s and %s both map to type %s", prev, name, short)
+		}
+		owner[short] = name
+		g.names[name] = short
+	}
+
+	var methods bytes.Buffer
+	var inline []string
+	for _, a := range doc.Actions {
+		method := methodName(a.Name)
+		params := ""
+		if a.Params != nil {
+			if a.Params.Ref != "" {
+				params = g.typeOf(a.Params)
+			} else {
+				params = method + "Request"
+				inline = append(inline, fmt.Sprintf("// %s %s 的参数\ntype %s %s\n\n", params, a.Name, params, g.typeOf(a.Params)))
+			}
+		}
+
+		fmt.Fprintf(&methods, "// %s 调用 %s（需要 %s 角色", method, a.Name, a.Role)
+		if a.Method != "" {
+			fmt.Fprintf(&methods, "，REST: %s /v1%s", a.Method, a.Path)
+		}
+		methods.WriteString("）\n")
+
+		args, pass := "ctx context.Context", "nil"
+		if params != "" {
+			args, pass = "ctx context.Context, p "+params, "p"
+		}
+		if a.Result == nil {
+			fmt.Fprintf(&methods, "func (c *Client) %s(%s) error {\n\treturn c.Call(ctx, %q, %s, nil)\n}\n\n", method, args, a.Name, pass)
+			continue
+		}
+		result := g.typeOf(a.Result)
+		if a.Result.Ref != "" {
+			result = "*" + result
+		}
+		fmt.Fprintf(&methods, "func (c *Client) %s(%s) (%s, error) {\n\tvar out %s\n\terr := c.Call(ctx, %q, %s, &out)\n\treturn out, err\n}\n\n",
+			method, args, result, result, a.Name, pass)
+	}
+
+	g.buf.WriteString("// Code generated by vmcat/pkg/client/internal/gen from openapi.json. DO NOT EDIT.\n\n")
+	g.buf.WriteString("package client\n\nimport (\n\t\"context\"\n")
+	if g.rawJSON {
+		g.buf.WriteString("\t\"encoding/json\"\n")
+	}
+	g.buf.WriteString(")\n\n")
+	fmt.Fprintf(&g.buf, "// APIVersion 生成客户端时的服务端版本\nconst APIVersion = %q\n\n", doc.Info.Version)
+
+	// 方法生成过程中已收集全部引用到的类型
+	var types []string
+	for name := range g.used {
+		types = append(types, name)
+	}
+	sort.Slice(types, func(i, j int) bool { return g.names[types[i]] < g.names[types[j]] })
+	for _, name := range types {
+		fmt.Fprintf(&g.buf, "// %s 对应服务端 %s\ntype %s %s\n\n", g.names[name], name, g.names[name], g.typeOf(doc.Components.Schemas[name]))
+	}
+	for _, t := range inline {
+		g.buf.WriteString(t)
+	}
+	g.buf.Write(methods.Bytes())
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format: %w\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+// typeOf Schema 对应的 Go 类型表达式，引用的 components 记入 used
+func (g *generator) typeOf(s *api.Schema) string {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, refPrefix)
+		if !g.used[name] {
+			g.used[name] = true
+			// 递归收集该类型引用的其它类型
+			if def, ok := g.doc.Components.Schemas[name]; ok {
+				g.typeOf(def)
+			}
+		}
+		return g.names[name]
+	}
+
+	var t string
+	switch s.Type {
+	case "string":
+		t = "string"
+		if s.Format == "byte" {
+			t = "[]byte"
+		}
+	case "boolean":
+		t = "bool"
+	case "integer":
+		t = "int"
+		if s.Format != "" {
+			t = s.Format
+		}
+	case "number":
+		t = "float64"
+		if s.Format == "float" {
+			t = "float32"
+		}
+	case "array":
+		return "[]" + g.typeOf(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.typeOf(s.AdditionalProperties)
+		}
+		var b strings.Builder
+		b.WriteString("struct {\n")
+		for _, p := range s.Properties {
+			field := p.Schema.GoName
+			if field == "" {
+				field = exported(p.Name)
+			}
+			fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, g.typeOf(p.Schema), p.Name)
+		}
+		b.WriteString("}")
+		return b.String()
+	default:
+		g.rawJSON = true
+		return "json.RawMessage"
+	}
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// methodName action 名称转方法名：vm.getXML -> VMGetXML，osvariant.list -> OSVariantList
+func methodName(action string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(action, ".") {
+		for _, word := range splitWords(part) {
+			if s, ok := initialisms[strings.ToLower(word)]; ok {
+				b.WriteString(s)
+			} else {
+				b.WriteString(exported(word))
+			}
+		}
+	}
+	return b.String()
+}
+
+// splitWords 按驼峰拆分，连续大写视为一个词：getXML -> get, XML；isoList -> iso, List
+func splitWords(s string) []string {
+	var words []string
+	start := 0
+	runes := []rune(s)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}